@@ -216,7 +216,7 @@ run_subdomain_enumeration() {
         "${SCRIPT_DIR}/scanner/scanner" subdomain -d "$TARGET" \
             -c "$THREADS" \
             -passive \
-            -o "$json_output" 2>&1 | tee -a "$LOG_FILE"
+            -o "$json_output" 2>&1 | tee -a "$LOG_FILE" || scanner_status_ok "${PIPESTATUS[0]}"
         
         # Extract subdomains from JSON and append to txt
         if [[ -f "$json_output" ]]; then
@@ -289,7 +289,7 @@ run_port_scanning() {
         "${SCRIPT_DIR}/scanner/scanner" portscan -t "$input_file" \
             -p "1-1000" \
             -c "$THREADS" \
-            -o "$output_file" 2>&1 | tee -a "$LOG_FILE"
+            -o "$output_file" 2>&1 | tee -a "$LOG_FILE" || scanner_status_ok "${PIPESTATUS[0]}"
     fi
     
     log_success "Port scanning complete"
@@ -319,7 +319,7 @@ run_http_probing() {
         log_info "Running Go HTTP prober..."
         "${SCRIPT_DIR}/scanner/scanner" probe -l "$input_file" \
             -c "$THREADS" \
-            -o "$output_file" 2>&1 | tee -a "$LOG_FILE"
+            -o "$output_file" 2>&1 | tee -a "$LOG_FILE" || scanner_status_ok "${PIPESTATUS[0]}"
         
         jq -r '.[].url' "$output_file" 2>/dev/null > "$alive_urls" || true
     fi
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"io"
+
+	"github.com/recon-suite/scanner/api/scannerpb"
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client calls a remote scanner's gRPC service and converts the streamed
+// messages back to the modules' result types
+type Client struct {
+	Addr string
+
+	conn    *grpc.ClientConn
+	scanner scannerpb.ScannerClient
+}
+
+// Dial creates a client for the server at addr. Without options the
+// connection is plaintext, matching the serve command. Connecting is lazy,
+// so an unreachable server surfaces as an Unavailable error from the first
+// call.
+func Dial(addr string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Addr: addr, conn: conn, scanner: scannerpb.NewScannerClient(conn)}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Enumerate runs a remote enumeration, calling onResult for each subdomain
+func (c *Client) Enumerate(ctx context.Context, req *scannerpb.EnumerateRequest, onResult func(subdomain.Result)) error {
+	stream, err := c.scanner.Enumerate(ctx, req)
+	if err != nil {
+		return err
+	}
+	return receive(stream.Recv, func(m *scannerpb.Subdomain) {
+		onResult(subdomain.Result{
+			Subdomain: m.GetSubdomain(),
+			IPs:       m.GetIps(),
+			Source:    m.GetSource(),
+			Timestamp: m.GetTimestamp(),
+		})
+	})
+}
+
+// PortScan runs a remote port scan, calling onResult for each open port
+func (c *Client) PortScan(ctx context.Context, req *scannerpb.PortScanRequest, onResult func(portscan.Result)) error {
+	stream, err := c.scanner.PortScan(ctx, req)
+	if err != nil {
+		return err
+	}
+	return receive(stream.Recv, func(m *scannerpb.OpenPort) {
+		onResult(portscan.Result{
+			Host:      m.GetHost(),
+			Port:      int(m.GetPort()),
+			Open:      true,
+			Service:   m.GetService(),
+			Banner:    m.GetBanner(),
+			Timestamp: m.GetTimestamp(),
+		})
+	})
+}
+
+// Probe runs a remote probe, calling onResult for each live endpoint
+func (c *Client) Probe(ctx context.Context, req *scannerpb.ProbeRequest, onResult func(httpx.ProbeResult)) error {
+	stream, err := c.scanner.Probe(ctx, req)
+	if err != nil {
+		return err
+	}
+	return receive(stream.Recv, func(m *scannerpb.ProbeResult) {
+		onResult(httpx.ProbeResult{
+			URL:           m.GetUrl(),
+			StatusCode:    int(m.GetStatusCode()),
+			ContentLength: m.GetContentLength(),
+			ContentType:   m.GetContentType(),
+			Title:         m.GetTitle(),
+			Server:        m.GetServer(),
+			Technologies:  m.GetTechnologies(),
+			Headers:       m.GetHeaders(),
+			Redirected:    m.GetRedirected(),
+			FinalURL:      m.GetFinalUrl(),
+			ResponseTime:  m.GetResponseTimeMs(),
+			Timestamp:     m.GetTimestamp(),
+		})
+	})
+}
+
+// receive reads a server stream to the end
+func receive[T any](recv func() (T, error), handle func(T)) error {
+	for {
+		msg, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		handle(msg)
+	}
+}
@@ -0,0 +1,265 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: scanner.proto
+
+package scannerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Scanner_Enumerate_FullMethodName = "/recon.scanner.v1.Scanner/Enumerate"
+	Scanner_PortScan_FullMethodName  = "/recon.scanner.v1.Scanner/PortScan"
+	Scanner_Probe_FullMethodName     = "/recon.scanner.v1.Scanner/Probe"
+	Scanner_Pipeline_FullMethodName  = "/recon.scanner.v1.Scanner/Pipeline"
+)
+
+// ScannerClient is the client API for Scanner service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Scanner runs scans and streams each result as it is found. Streams are
+// flow controlled: a client that reads slowly slows the scan down instead
+// of the server buffering results. Cancelling the call stops the scan.
+type ScannerClient interface {
+	// Enumerate streams subdomains from passive sources and bruteforce
+	Enumerate(ctx context.Context, in *EnumerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Subdomain], error)
+	// PortScan streams open ports
+	PortScan(ctx context.Context, in *PortScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OpenPort], error)
+	// Probe streams live HTTP(S) endpoints
+	Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProbeResult], error)
+	// Pipeline streams stage transitions and every stage's results, ending
+	// with a summary
+	Pipeline(ctx context.Context, in *PipelineRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PipelineEvent], error)
+}
+
+type scannerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScannerClient(cc grpc.ClientConnInterface) ScannerClient {
+	return &scannerClient{cc}
+}
+
+func (c *scannerClient) Enumerate(ctx context.Context, in *EnumerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Subdomain], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scanner_ServiceDesc.Streams[0], Scanner_Enumerate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EnumerateRequest, Subdomain]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_EnumerateClient = grpc.ServerStreamingClient[Subdomain]
+
+func (c *scannerClient) PortScan(ctx context.Context, in *PortScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OpenPort], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scanner_ServiceDesc.Streams[1], Scanner_PortScan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PortScanRequest, OpenPort]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_PortScanClient = grpc.ServerStreamingClient[OpenPort]
+
+func (c *scannerClient) Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProbeResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scanner_ServiceDesc.Streams[2], Scanner_Probe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProbeRequest, ProbeResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_ProbeClient = grpc.ServerStreamingClient[ProbeResult]
+
+func (c *scannerClient) Pipeline(ctx context.Context, in *PipelineRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PipelineEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scanner_ServiceDesc.Streams[3], Scanner_Pipeline_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PipelineRequest, PipelineEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_PipelineClient = grpc.ServerStreamingClient[PipelineEvent]
+
+// ScannerServer is the server API for Scanner service.
+// All implementations must embed UnimplementedScannerServer
+// for forward compatibility.
+//
+// Scanner runs scans and streams each result as it is found. Streams are
+// flow controlled: a client that reads slowly slows the scan down instead
+// of the server buffering results. Cancelling the call stops the scan.
+type ScannerServer interface {
+	// Enumerate streams subdomains from passive sources and bruteforce
+	Enumerate(*EnumerateRequest, grpc.ServerStreamingServer[Subdomain]) error
+	// PortScan streams open ports
+	PortScan(*PortScanRequest, grpc.ServerStreamingServer[OpenPort]) error
+	// Probe streams live HTTP(S) endpoints
+	Probe(*ProbeRequest, grpc.ServerStreamingServer[ProbeResult]) error
+	// Pipeline streams stage transitions and every stage's results, ending
+	// with a summary
+	Pipeline(*PipelineRequest, grpc.ServerStreamingServer[PipelineEvent]) error
+	mustEmbedUnimplementedScannerServer()
+}
+
+// UnimplementedScannerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedScannerServer struct{}
+
+func (UnimplementedScannerServer) Enumerate(*EnumerateRequest, grpc.ServerStreamingServer[Subdomain]) error {
+	return status.Errorf(codes.Unimplemented, "method Enumerate not implemented")
+}
+func (UnimplementedScannerServer) PortScan(*PortScanRequest, grpc.ServerStreamingServer[OpenPort]) error {
+	return status.Errorf(codes.Unimplemented, "method PortScan not implemented")
+}
+func (UnimplementedScannerServer) Probe(*ProbeRequest, grpc.ServerStreamingServer[ProbeResult]) error {
+	return status.Errorf(codes.Unimplemented, "method Probe not implemented")
+}
+func (UnimplementedScannerServer) Pipeline(*PipelineRequest, grpc.ServerStreamingServer[PipelineEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Pipeline not implemented")
+}
+func (UnimplementedScannerServer) mustEmbedUnimplementedScannerServer() {}
+func (UnimplementedScannerServer) testEmbeddedByValue()                 {}
+
+// UnsafeScannerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScannerServer will
+// result in compilation errors.
+type UnsafeScannerServer interface {
+	mustEmbedUnimplementedScannerServer()
+}
+
+func RegisterScannerServer(s grpc.ServiceRegistrar, srv ScannerServer) {
+	// If the following call pancis, it indicates UnimplementedScannerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Scanner_ServiceDesc, srv)
+}
+
+func _Scanner_Enumerate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EnumerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScannerServer).Enumerate(m, &grpc.GenericServerStream[EnumerateRequest, Subdomain]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_EnumerateServer = grpc.ServerStreamingServer[Subdomain]
+
+func _Scanner_PortScan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PortScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScannerServer).PortScan(m, &grpc.GenericServerStream[PortScanRequest, OpenPort]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_PortScanServer = grpc.ServerStreamingServer[OpenPort]
+
+func _Scanner_Probe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProbeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScannerServer).Probe(m, &grpc.GenericServerStream[ProbeRequest, ProbeResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_ProbeServer = grpc.ServerStreamingServer[ProbeResult]
+
+func _Scanner_Pipeline_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PipelineRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScannerServer).Pipeline(m, &grpc.GenericServerStream[PipelineRequest, PipelineEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scanner_PipelineServer = grpc.ServerStreamingServer[PipelineEvent]
+
+// Scanner_ServiceDesc is the grpc.ServiceDesc for Scanner service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Scanner_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "recon.scanner.v1.Scanner",
+	HandlerType: (*ScannerServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Enumerate",
+			Handler:       _Scanner_Enumerate_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PortScan",
+			Handler:       _Scanner_PortScan_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Probe",
+			Handler:       _Scanner_Probe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Pipeline",
+			Handler:       _Scanner_Pipeline_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scanner.proto",
+}
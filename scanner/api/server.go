@@ -0,0 +1,449 @@
+// Package api serves the scanner over gRPC. The service is defined in
+// scanner.proto; scannerpb holds the generated Go stubs, and clients in
+// other languages can generate their own from the same file.
+package api
+
+//go:generate protoc --go_out=scannerpb --go_opt=paths=source_relative --go-grpc_out=scannerpb --go-grpc_opt=paths=source_relative scanner.proto
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/recon-suite/scanner/api/scannerpb"
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements scannerpb.ScannerServer. Each result is sent from the
+// scan's collector as it is found, so a slow client blocks the collector
+// and, through the modules' bounded queues, the workers behind it.
+type Server struct {
+	scannerpb.UnimplementedScannerServer
+}
+
+// NewServer creates a scanner gRPC service
+func NewServer() *Server {
+	return &Server{}
+}
+
+// NewGRPCServer returns a gRPC server with the scanner service registered.
+// Cancelling ctx stops every running scan, so each stream ends with the
+// results found so far and GracefulStop returns promptly.
+func NewGRPCServer(ctx context.Context, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		streamCtx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+		streamCtx = log.WithContext(streamCtx, log.FromContext(streamCtx).With("rpc", info.FullMethod))
+		return handler(srv, &boundStream{ServerStream: ss, ctx: streamCtx})
+	}))
+
+	server := grpc.NewServer(opts...)
+	scannerpb.RegisterScannerServer(server, NewServer())
+	return server
+}
+
+// boundStream overrides a stream's context
+type boundStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the overriding context
+func (b *boundStream) Context() context.Context {
+	return b.ctx
+}
+
+// Enumerate streams subdomains for one domain
+func (s *Server) Enumerate(req *scannerpb.EnumerateRequest, stream scannerpb.Scanner_EnumerateServer) error {
+	if req.GetDomain() == "" {
+		return status.Error(codes.InvalidArgument, "domain is required")
+	}
+	opts, err := parseOptions(req.GetOptions())
+	if err != nil {
+		return err
+	}
+
+	ctx, out := newSender(stream.Context())
+	scanner := subdomain.NewScanner(subdomain.Config{
+		Domain:     req.GetDomain(),
+		Wordlist:   req.GetWordlist(),
+		Workers:    opts.workers(100),
+		Timeout:    opts.timeout(30),
+		Passive:    req.Passive == nil || req.GetPassive(),
+		Bruteforce: req.GetBruteforce() && req.GetWordlist() != "",
+		Proxy:      opts.proxy,
+		Scope:      opts.scope,
+		Budget:     opts.budget,
+		OnResult: func(r subdomain.Result) {
+			out.send(func() error { return stream.Send(subdomainMessage(r)) })
+		},
+	})
+	_, err = scanner.EnumerateContext(ctx)
+	return out.finish(err)
+}
+
+// PortScan streams open ports across the targets
+func (s *Server) PortScan(req *scannerpb.PortScanRequest, stream scannerpb.Scanner_PortScanServer) error {
+	if len(req.GetTargets()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one target is required")
+	}
+	opts, err := parseOptions(req.GetOptions())
+	if err != nil {
+		return err
+	}
+
+	ports, err := parsePorts(req.GetPorts())
+	if err != nil {
+		return err
+	}
+	if len(ports) == 0 {
+		ports = pipeline.DefaultPorts
+	}
+
+	ctx, out := newSender(stream.Context())
+	scanner := portscan.NewScanner(portscan.Config{
+		Targets:       req.GetTargets(),
+		Ports:         ports,
+		Workers:       opts.workers(0),
+		Timeout:       opts.timeout(0),
+		RateLimit:     opts.rateLimit,
+		ServiceDetect: req.GetServiceDetect(),
+		Scope:         opts.scope,
+		Budget:        opts.budget,
+		OnResult: func(r portscan.Result) {
+			out.send(func() error { return stream.Send(portMessage(r)) })
+		},
+	})
+	_, err = scanner.ScanContext(ctx)
+	return out.finish(err)
+}
+
+// Probe streams live HTTP(S) endpoints
+func (s *Server) Probe(req *scannerpb.ProbeRequest, stream scannerpb.Scanner_ProbeServer) error {
+	if len(req.GetTargets()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one target is required")
+	}
+	opts, err := parseOptions(req.GetOptions())
+	if err != nil {
+		return err
+	}
+
+	ctx, out := newSender(stream.Context())
+	prober := httpx.NewProber(httpx.ProbeConfig{
+		Targets:        req.GetTargets(),
+		Workers:        opts.workers(0),
+		Timeout:        opts.timeout(0),
+		FollowRedirect: req.FollowRedirects == nil || req.GetFollowRedirects(),
+		Retries:        int(req.GetRetries()),
+		RateLimit:      opts.rateLimit,
+		UserAgent:      req.GetUserAgent(),
+		Headers:        req.GetHeaders(),
+		Proxy:          opts.proxy,
+		Scope:          opts.scope,
+		Budget:         opts.budget,
+		OnResult: func(r httpx.ProbeResult) {
+			out.send(func() error { return stream.Send(probeMessage(r)) })
+		},
+	})
+	_, err = prober.ProbeContext(ctx)
+	return out.finish(err)
+}
+
+// Pipeline streams a full pipeline run for one domain
+func (s *Server) Pipeline(req *scannerpb.PipelineRequest, stream scannerpb.Scanner_PipelineServer) error {
+	if req.GetDomain() == "" {
+		return status.Error(codes.InvalidArgument, "domain is required")
+	}
+	opts, err := parseOptions(req.GetOptions())
+	if err != nil {
+		return err
+	}
+
+	var stages []string
+	for _, stage := range req.GetStages() {
+		parsed, err := pipeline.ParseStages(stage)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		stages = append(stages, parsed...)
+	}
+
+	var rateSpecs []string
+	for stage, rps := range req.GetStageRates() {
+		rateSpecs = append(rateSpecs, stage+"="+strconv.Itoa(int(rps)))
+	}
+	rates, err := pipeline.ParseStageRates(strings.Join(rateSpecs, ","))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ports, err := parsePorts(req.GetPorts())
+	if err != nil {
+		return err
+	}
+
+	ctx, out := newSender(stream.Context())
+	p := pipeline.New(pipeline.Config{
+		Domain:     req.GetDomain(),
+		Wordlist:   req.GetWordlist(),
+		Passive:    req.Passive == nil || req.GetPassive(),
+		Bruteforce: req.GetBruteforce() && req.GetWordlist() != "",
+		Ports:      ports,
+		Workers:    opts.workers(0),
+		Timeout:    opts.timeout(0),
+		CrawlDepth: int(req.GetCrawlDepth()),
+		MaxURLs:    int(req.GetMaxUrls()),
+		Stages:     stages,
+		RateLimit:  opts.rateLimit,
+		StageRates: rates,
+		Proxy:      opts.proxy,
+		Scope:      opts.scope,
+		Budget:     opts.budget,
+		OnStage: func(event pipeline.StageEvent) {
+			out.send(func() error {
+				return stream.Send(&scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Stage{
+					Stage: &scannerpb.StageEvent{Stage: event.Stage, Done: event.Done},
+				}})
+			})
+		},
+		OnResult: func(stage string, result interface{}) {
+			if event := pipelineEvent(result); event != nil {
+				out.send(func() error { return stream.Send(event) })
+			}
+		},
+	})
+
+	report, err := p.Run(ctx)
+	if report != nil {
+		out.send(func() error {
+			return stream.Send(&scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Summary{
+				Summary: &scannerpb.PipelineSummary{
+					Domain:     report.Domain,
+					StartedAt:  report.StartedAt,
+					FinishedAt: report.FinishedAt,
+					Stages:     report.Stages,
+					Errors:     report.Errors,
+				},
+			}})
+		})
+	}
+	return out.finish(err)
+}
+
+// options are the parsed shared scan options
+type options struct {
+	scope     *scope.Scope
+	proxy     string
+	rateLimit int
+	budget    *utils.Budget
+	workerN   int
+	timeoutN  int
+}
+
+// parsePorts validates a request's ports, returning InvalidArgument for
+// any outside 1-65535
+func parsePorts(requested []int32) ([]int, error) {
+	ports := make([]int, 0, len(requested))
+	for _, port := range requested {
+		if port < 1 || port > 65535 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid port %d", port)
+		}
+		ports = append(ports, int(port))
+	}
+	return ports, nil
+}
+
+// parseOptions validates the shared options, returning InvalidArgument
+// errors for bad rules or proxies
+func parseOptions(msg *scannerpb.Options) (*options, error) {
+	opts := &options{
+		proxy:     msg.GetProxy(),
+		rateLimit: int(msg.GetRateLimit()),
+		workerN:   int(msg.GetWorkers()),
+		timeoutN:  int(msg.GetTimeoutSeconds()),
+	}
+	if opts.rateLimit < 0 || opts.workerN < 0 || opts.timeoutN < 0 {
+		return nil, status.Error(codes.InvalidArgument, "rate_limit, workers and timeout_seconds must not be negative")
+	}
+	if _, err := utils.ParseProxy(opts.proxy); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	opts.budget = utils.NewBudget(opts.rateLimit)
+
+	var err error
+	switch {
+	case len(msg.GetScope()) > 0:
+		if opts.scope, err = scope.Parse(msg.GetScope()); err == nil {
+			err = opts.scope.Exclude(msg.GetExclude())
+		}
+	case len(msg.GetExclude()) > 0:
+		opts.scope, err = scope.Exclusions(msg.GetExclude())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return opts, nil
+}
+
+// workers returns the requested worker count, or def if none was given
+func (o *options) workers(def int) int {
+	if o.workerN > 0 {
+		return o.workerN
+	}
+	return def
+}
+
+// timeout returns the requested timeout, or def if none was given
+func (o *options) timeout(def int) int {
+	if o.timeoutN > 0 {
+		return o.timeoutN
+	}
+	return def
+}
+
+// sender serializes sends on one stream. The first failed send cancels the
+// scan, since nobody is left to read its results.
+type sender struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	err    error
+}
+
+// newSender wraps the stream's context so a failed send stops the scan
+func newSender(parent context.Context) (context.Context, *sender) {
+	ctx, cancel := context.WithCancel(parent)
+	return ctx, &sender{ctx: ctx, cancel: cancel}
+}
+
+// send runs fn unless an earlier send failed
+func (s *sender) send(fn func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if err := fn(); err != nil {
+		s.err = err
+		s.cancel()
+	}
+}
+
+// finish returns the RPC status once the scan has returned scanErr
+func (s *sender) finish(scanErr error) error {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.err != nil:
+		return s.err
+	case scanErr != nil:
+		if st, ok := status.FromError(scanErr); ok && st.Code() != codes.Unknown {
+			return st.Err()
+		}
+		if ctxErr := status.FromContextError(scanErr); ctxErr.Code() != codes.Unknown {
+			return ctxErr.Err()
+		}
+		return status.Error(codes.Internal, scanErr.Error())
+	default:
+		return nil
+	}
+}
+
+// pipelineEvent converts a pipeline result to its stream message
+func pipelineEvent(result interface{}) *scannerpb.PipelineEvent {
+	switch r := result.(type) {
+	case subdomain.Result:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Subdomain{Subdomain: subdomainMessage(r)}}
+	case subdomain.ResolutionResult:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Resolution{Resolution: &scannerpb.Resolution{
+			Subdomain: r.Subdomain,
+			Ips:       r.IPs,
+			Alive:     r.Alive,
+			Error:     r.Error,
+		}}}
+	case portscan.Result:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Port{Port: portMessage(r)}}
+	case httpx.ProbeResult:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Probe{Probe: probeMessage(r)}}
+	case httpx.CrawlResult:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Crawl{Crawl: &scannerpb.CrawlResult{
+			Url:       r.URL,
+			Source:    r.Source,
+			Depth:     int32(r.Depth),
+			Type:      r.Type,
+			Method:    r.Method,
+			Params:    r.Params,
+			Timestamp: r.Timestamp,
+		}}}
+	case httpx.AnalysisResult:
+		return &scannerpb.PipelineEvent{Event: &scannerpb.PipelineEvent_Analysis{Analysis: &scannerpb.AnalysisResult{
+			Url:                    r.URL,
+			Title:                  r.Title,
+			Technologies:           r.Technologies,
+			Endpoints:              r.Endpoints,
+			Parameters:             r.Parameters,
+			Emails:                 r.Emails,
+			Interesting:            r.Interesting,
+			Takeover:               r.Takeover,
+			MissingSecurityHeaders: int32(r.SecurityHeaders.MissingCount),
+			Hash:                   r.Hash,
+		}}}
+	default:
+		return nil
+	}
+}
+
+// subdomainMessage converts an enumeration result
+func subdomainMessage(r subdomain.Result) *scannerpb.Subdomain {
+	return &scannerpb.Subdomain{
+		Subdomain: r.Subdomain,
+		Ips:       r.IPs,
+		Source:    r.Source,
+		Timestamp: r.Timestamp,
+	}
+}
+
+// portMessage converts an open port
+func portMessage(r portscan.Result) *scannerpb.OpenPort {
+	return &scannerpb.OpenPort{
+		Host:      r.Host,
+		Port:      int32(r.Port),
+		Service:   r.Service,
+		Banner:    r.Banner,
+		Timestamp: r.Timestamp,
+	}
+}
+
+// probeMessage converts a probe result
+func probeMessage(r httpx.ProbeResult) *scannerpb.ProbeResult {
+	return &scannerpb.ProbeResult{
+		Url:            r.URL,
+		StatusCode:     int32(r.StatusCode),
+		ContentLength:  r.ContentLength,
+		ContentType:    r.ContentType,
+		Title:          r.Title,
+		Server:         r.Server,
+		Technologies:   r.Technologies,
+		Headers:        r.Headers,
+		Redirected:     r.Redirected,
+		FinalUrl:       r.FinalURL,
+		ResponseTimeMs: r.ResponseTime,
+		Timestamp:      r.Timestamp,
+	}
+}
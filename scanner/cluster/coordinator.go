@@ -0,0 +1,308 @@
+// Package cluster spreads one scan across remote scanner workers. Workers
+// are ordinary `scanner serve` instances; the coordinator splits the
+// targets (and, for port scans, the port list) into shards, hands each
+// shard to an idle worker over gRPC, and merges the streamed results.
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/recon-suite/scanner/api"
+	"github.com/recon-suite/scanner/api/scannerpb"
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Config holds coordinator configuration
+type Config struct {
+	// Workers are the host:port addresses of the serve instances
+	Workers []string
+
+	// ShardSize is the number of targets per shard (default 32)
+	ShardSize int
+
+	// Retries is how many times a failed shard is handed to another
+	// worker before it is given up (default 2)
+	Retries int
+
+	// Options are sent with every shard: scope, exclusions, proxy, and each
+	// worker's own rate limit, concurrency and timeout
+	Options *scannerpb.Options
+
+	// Log, if set, receives a line per failed shard and dropped worker
+	Log io.Writer
+}
+
+// Coordinator dispatches shards to workers. A worker that cannot be reached
+// is dropped for the rest of the scan and its shard goes to another one.
+type Coordinator struct {
+	config  Config
+	clients []*api.Client
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// New creates a coordinator for the configured workers
+func New(config Config) (*Coordinator, error) {
+	if len(config.Workers) == 0 {
+		return nil, fmt.Errorf("no workers given")
+	}
+	if config.ShardSize <= 0 {
+		config.ShardSize = 32
+	}
+	if config.Retries == 0 {
+		config.Retries = 2
+	}
+
+	c := &Coordinator{config: config}
+	for _, addr := range config.Workers {
+		client, err := api.Dial(addr)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("worker %s: %w", addr, err)
+		}
+		c.clients = append(c.clients, client)
+	}
+	return c, nil
+}
+
+// Close closes every worker connection
+func (c *Coordinator) Close() error {
+	for _, client := range c.clients {
+		client.Close()
+	}
+	return nil
+}
+
+// Errors returns the shard failures of the last scan
+func (c *Coordinator) Errors() []string {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]string(nil), c.errors...)
+}
+
+// shard is one unit of work for a worker
+type shard struct {
+	targets  []string
+	ports    []int
+	attempts int
+}
+
+// attempt is a finished shard run
+type attempt struct {
+	worker int
+	shard  shard
+	err    error
+}
+
+// Enumerate enumerates each domain on a worker, one domain per shard
+func (c *Coordinator) Enumerate(ctx context.Context, domains []string, req *scannerpb.EnumerateRequest, onResult func(subdomain.Result)) ([]subdomain.Result, error) {
+	var shards []shard
+	for _, domain := range domains {
+		shards = append(shards, shard{targets: []string{domain}})
+	}
+
+	var results []subdomain.Result
+	m := newMerger(func(r subdomain.Result) string { return r.Subdomain }, onResult, &results)
+	err := c.dispatch(ctx, shards, func(ctx context.Context, client *api.Client, s shard) error {
+		shardReq := proto.Clone(req).(*scannerpb.EnumerateRequest)
+		shardReq.Domain = s.targets[0]
+		shardReq.Options = c.config.Options
+		return client.Enumerate(ctx, shardReq, m.add)
+	})
+	return results, err
+}
+
+// PortScan scans the targets' ports across the workers. When there are
+// fewer target shards than workers, the port list is split as well so
+// every worker gets a share of a small, wide scan.
+func (c *Coordinator) PortScan(ctx context.Context, targets []string, ports []int, serviceDetect bool, onResult func(portscan.Result)) ([]portscan.Result, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	targetShards := split(targets, c.config.ShardSize)
+	portChunks := 1
+	if len(targetShards) < len(c.clients) {
+		portChunks = max((len(c.clients)+len(targetShards)-1)/len(targetShards), 1)
+	}
+	portShards := split(ports, (len(ports)+portChunks-1)/portChunks)
+	if len(portShards) == 0 {
+		// An empty list lets the worker use its default ports
+		portShards = [][]int{nil}
+	}
+
+	var shards []shard
+	for _, t := range targetShards {
+		for _, p := range portShards {
+			shards = append(shards, shard{targets: t, ports: p})
+		}
+	}
+
+	var results []portscan.Result
+	m := newMerger(func(r portscan.Result) string { return net.JoinHostPort(r.Host, strconv.Itoa(r.Port)) }, onResult, &results)
+	err := c.dispatch(ctx, shards, func(ctx context.Context, client *api.Client, s shard) error {
+		req := &scannerpb.PortScanRequest{
+			Targets:       s.targets,
+			ServiceDetect: serviceDetect,
+			Options:       c.config.Options,
+		}
+		for _, port := range s.ports {
+			req.Ports = append(req.Ports, int32(port))
+		}
+		return client.PortScan(ctx, req, m.add)
+	})
+	return results, err
+}
+
+// Probe probes the targets across the workers
+func (c *Coordinator) Probe(ctx context.Context, targets []string, req *scannerpb.ProbeRequest, onResult func(httpx.ProbeResult)) ([]httpx.ProbeResult, error) {
+	var shards []shard
+	for _, t := range split(targets, c.config.ShardSize) {
+		shards = append(shards, shard{targets: t})
+	}
+
+	var results []httpx.ProbeResult
+	m := newMerger(func(r httpx.ProbeResult) string { return r.URL }, onResult, &results)
+	err := c.dispatch(ctx, shards, func(ctx context.Context, client *api.Client, s shard) error {
+		shardReq := proto.Clone(req).(*scannerpb.ProbeRequest)
+		shardReq.Targets = s.targets
+		shardReq.Options = c.config.Options
+		return client.Probe(ctx, shardReq, m.add)
+	})
+	return results, err
+}
+
+// dispatch runs every shard on an idle worker until all have finished,
+// failed their retries, or ctx is cancelled. It returns ctx's error if
+// cancelled, or an error counting the shards given up on.
+func (c *Coordinator) dispatch(ctx context.Context, shards []shard, run func(context.Context, *api.Client, shard) error) error {
+	c.errorsMu.Lock()
+	c.errors = nil
+	c.errorsMu.Unlock()
+
+	queue := shards
+	idle := make([]int, len(c.clients))
+	for i := range idle {
+		idle[i] = i
+	}
+	done := make(chan attempt)
+	running := 0
+	failed := 0
+
+	for len(queue) > 0 || running > 0 {
+		for len(queue) > 0 && len(idle) > 0 && ctx.Err() == nil {
+			s, worker := queue[0], idle[0]
+			queue, idle = queue[1:], idle[1:]
+			running++
+			go func() {
+				done <- attempt{worker: worker, shard: s, err: run(ctx, c.clients[worker], s)}
+			}()
+		}
+		if running == 0 {
+			// Cancelled, or every worker has been dropped
+			break
+		}
+
+		a := <-done
+		running--
+		if a.err == nil || ctx.Err() != nil {
+			idle = append(idle, a.worker)
+			continue
+		}
+
+		addr := c.clients[a.worker].Addr
+		if status.Code(a.err) == codes.Unavailable {
+			c.logf("worker %s dropped: %v", addr, a.err)
+		} else {
+			idle = append(idle, a.worker)
+		}
+
+		if a.shard.attempts < c.config.Retries {
+			a.shard.attempts++
+			queue = append(queue, a.shard)
+			continue
+		}
+		failed++
+		c.recordError(fmt.Sprintf("shard of %d targets failed on %s: %v", len(a.shard.targets), addr, a.err))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(queue) > 0 {
+		failed += len(queue)
+		c.recordError(fmt.Sprintf("%d shards not scanned: no workers left", len(queue)))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d shards failed", failed, len(shards))
+	}
+	return nil
+}
+
+// recordError logs and keeps a shard failure
+func (c *Coordinator) recordError(msg string) {
+	c.errorsMu.Lock()
+	c.errors = append(c.errors, msg)
+	c.errorsMu.Unlock()
+	c.logf("%s", msg)
+}
+
+// logf writes a line to the configured log
+func (c *Coordinator) logf(format string, args ...interface{}) {
+	if c.config.Log != nil {
+		fmt.Fprintf(c.config.Log, "[cluster] "+format+"\n", args...)
+	}
+}
+
+// merger collects results from concurrent shards, dropping duplicates a
+// retried shard streams again
+type merger[T any] struct {
+	mu       sync.Mutex
+	key      func(T) string
+	seen     map[string]bool
+	onResult func(T)
+	results  *[]T
+}
+
+// newMerger creates a merger appending to results
+func newMerger[T any](key func(T) string, onResult func(T), results *[]T) *merger[T] {
+	return &merger[T]{key: key, seen: make(map[string]bool), onResult: onResult, results: results}
+}
+
+// add merges one result
+func (m *merger[T]) add(r T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.key(r)
+	if m.seen[k] {
+		return
+	}
+	m.seen[k] = true
+	*m.results = append(*m.results, r)
+	if m.onResult != nil {
+		m.onResult(r)
+	}
+}
+
+// split cuts items into chunks of at most size
+func split[T any](items []T, size int) [][]T {
+	var chunks [][]T
+	for size > 0 && len(items) > 0 {
+		n := min(size, len(items))
+		chunks = append(chunks, items[:n])
+		items = items[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Handler serves the daemon's state as JSON:
+//
+//	GET /jobs             every job with its next and last run
+//	GET /runs?job=<name>  run history, newest first
+//	GET /metrics          scan counters in the Prometheus text format
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, d.Jobs())
+	})
+	mux.HandleFunc("/runs", func(w http.ResponseWriter, r *http.Request) {
+		runs, err := d.History(r.URL.Query().Get("job"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		newest := make([]Run, 0, len(runs))
+		for i := len(runs) - 1; i >= 0; i-- {
+			newest = append(newest, runs[i])
+		}
+		writeResponse(w, newest)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.Default.WritePrometheus(w)
+	})
+	return mux
+}
+
+// Serve exposes Handler on addr in the background until ctx is cancelled,
+// returning an error only if addr cannot be bound
+func (d *Daemon) Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           d.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	return nil
+}
+
+// writeResponse encodes v as the JSON response body
+func writeResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
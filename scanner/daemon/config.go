@@ -0,0 +1,225 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultResults is where run reports and history are kept when the config
+// does not name a directory
+const DefaultResults = "scanner-runs"
+
+// Config is a daemon jobs file:
+//
+//	results: ./recon-runs
+//	max_concurrent: 2
+//	rate_limit: 300
+//	jobs:
+//	  - name: example-nightly
+//	    schedule: "0 3 * * *"
+//	    domains: [example.com, example.org]
+//	    stages: subdomain,resolve,probe
+//	    rate_limit: 200
+//	    scope: scope.txt
+//	    watch_ct: true
+//	    incremental: true
+//
+// Jobs with watch_ct also scan new hostnames as soon as certificates for
+// them appear in certificate transparency logs; such a job may leave out
+// the schedule.
+type Config struct {
+	// Results is the directory for run reports and history
+	Results string `yaml:"results"`
+
+	// MaxConcurrent caps how many jobs scan at once; due jobs wait for a
+	// free slot. Defaults to 1.
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	// RateLimit caps the requests per second of all running jobs together,
+	// on top of each job's own rate_limit. Zero is unlimited.
+	RateLimit int `yaml:"rate_limit"`
+
+	// CertStream is the certstream feed watch_ct jobs follow (default: the
+	// public feed)
+	CertStream string `yaml:"certstream"`
+
+	// CertStreamBatch is how long new hostnames are collected before a
+	// watch_ct job scans them, as a Go duration. Defaults to 1m.
+	CertStreamBatch string `yaml:"certstream_batch"`
+
+	Jobs []*Job `yaml:"jobs"`
+
+	certStreamBatch time.Duration
+}
+
+// Job is one recurring pipeline scan. Options mirror the pipeline
+// command's flags.
+type Job struct {
+	Name       string   `yaml:"name"`
+	Schedule   string   `yaml:"schedule"`
+	Domains    []string `yaml:"domains"`
+	Stages     string   `yaml:"stages"`
+	Ports      []int    `yaml:"ports"`
+	Wordlist   string   `yaml:"wordlist"`
+	Passive    *bool    `yaml:"passive"`
+	Bruteforce bool     `yaml:"bruteforce"`
+	Workers    int      `yaml:"workers"`
+	Timeout    int      `yaml:"timeout"`
+	Depth      int      `yaml:"depth"`
+	MaxURLs    int      `yaml:"max_urls"`
+	RateLimit  int      `yaml:"rate_limit"`
+	StageRates string   `yaml:"stage_rates"`
+	Proxy      string   `yaml:"proxy"`
+	Scope      string   `yaml:"scope"`
+	Exclude    []string `yaml:"exclude"`
+	WatchCT    bool     `yaml:"watch_ct"`
+
+	// Incremental runs portscan, probe and crawl only on what the job's
+	// earlier runs did not scan or that has changed since, as recorded in
+	// <results>/<job>/known.json
+	Incremental bool `yaml:"incremental"`
+
+	schedule   Schedule
+	stages     []string
+	stageRates map[string]int
+	scope      *scope.Scope
+
+	// busy is held while the job runs, so scheduled and certstream runs
+	// never overlap
+	busy chan struct{}
+}
+
+// jobName keeps job names usable as directory names
+var jobName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// LoadConfig reads and validates a jobs file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading jobs file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// validate checks every job and fills in defaults
+func (c *Config) validate() error {
+	if c.Results == "" {
+		c.Results = DefaultResults
+	}
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 1
+	}
+	c.certStreamBatch = time.Minute
+	if c.CertStreamBatch != "" {
+		batch, err := time.ParseDuration(c.CertStreamBatch)
+		if err != nil || batch <= 0 {
+			return fmt.Errorf("invalid certstream_batch %q", c.CertStreamBatch)
+		}
+		c.certStreamBatch = batch
+	}
+	if len(c.Jobs) == 0 {
+		return fmt.Errorf("no jobs defined")
+	}
+
+	names := make(map[string]bool)
+	for i, job := range c.Jobs {
+		if !jobName.MatchString(job.Name) {
+			return fmt.Errorf("job %d: name %q must be letters, digits, '.', '_' or '-'", i+1, job.Name)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("job %s: duplicate name", job.Name)
+		}
+		names[job.Name] = true
+
+		if err := job.prepare(); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// prepare parses the job's schedule, stages, rates, proxy and scope
+func (j *Job) prepare() error {
+	if len(j.Domains) == 0 {
+		return fmt.Errorf("no domains")
+	}
+
+	if j.Schedule == "" && !j.WatchCT {
+		return fmt.Errorf("no schedule")
+	}
+	j.busy = make(chan struct{}, 1)
+
+	var err error
+	if j.Schedule != "" {
+		if j.schedule, err = ParseSchedule(j.Schedule); err != nil {
+			return err
+		}
+	}
+	if j.stages, err = pipeline.ParseStages(j.Stages); err != nil {
+		return err
+	}
+	if j.WatchCT && len(withoutStage(j.stages, pipeline.StageSubdomain)) == 0 {
+		return fmt.Errorf("watch_ct needs a stage to run on new hosts besides subdomain")
+	}
+	if j.stageRates, err = pipeline.ParseStageRates(j.StageRates); err != nil {
+		return err
+	}
+	if _, err := utils.ParseProxy(j.Proxy); err != nil {
+		return err
+	}
+
+	switch {
+	case j.Scope != "":
+		if j.scope, err = scope.Load(j.Scope); err != nil {
+			return err
+		}
+		if err := j.scope.Exclude(j.Exclude); err != nil {
+			return err
+		}
+	case len(j.Exclude) > 0:
+		if j.scope, err = scope.Exclusions(j.Exclude); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pipelineConfig builds the pipeline configuration for one domain
+func (j *Job) pipelineConfig(domain string) pipeline.Config {
+	passive := true
+	if j.Passive != nil {
+		passive = *j.Passive
+	}
+
+	return pipeline.Config{
+		Domain:     domain,
+		Wordlist:   j.Wordlist,
+		Passive:    passive,
+		Bruteforce: j.Bruteforce && j.Wordlist != "",
+		Ports:      j.Ports,
+		Workers:    j.Workers,
+		Timeout:    j.Timeout,
+		CrawlDepth: j.Depth,
+		MaxURLs:    j.MaxURLs,
+		Stages:     j.stages,
+		RateLimit:  j.RateLimit,
+		StageRates: j.stageRates,
+		Proxy:      j.Proxy,
+		Scope:      j.scope,
+	}
+}
@@ -0,0 +1,380 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Run statuses recorded in the history
+const (
+	StatusOK          = "ok"          // every domain finished without errors
+	StatusPartial     = "partial"     // some stages or domains errored
+	StatusInterrupted = "interrupted" // the daemon stopped mid-run
+)
+
+// Run triggers
+const (
+	TriggerSchedule   = "schedule"   // the job's schedule came due
+	TriggerCertStream = "certstream" // new hostnames appeared in CT logs
+)
+
+// historyFile is the append-only run log kept in the results directory
+const historyFile = "history.jsonl"
+
+// knownFile, in a job's directory, records what an incremental job has
+// scanned
+const knownFile = "known.json"
+
+// Run is one finished job run as recorded in the history
+type Run struct {
+	Job        string   `json:"job"`
+	ID         string   `json:"id"`
+	Trigger    string   `json:"trigger,omitempty"`
+	StartedAt  string   `json:"started_at"`
+	FinishedAt string   `json:"finished_at"`
+	Status     string   `json:"status"`
+	Domains    int      `json:"domains"`
+	Findings   int      `json:"findings"`
+	Errors     []string `json:"errors,omitempty"`
+	Report     string   `json:"report"`
+}
+
+// JobState is a job's live scheduling state
+type JobState struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	WatchCT  bool   `json:"watch_ct,omitempty"`
+	Running  bool   `json:"running"`
+	NextRun  string `json:"next_run,omitempty"`
+	LastRun  *Run   `json:"last_run,omitempty"`
+}
+
+// Daemon runs configured jobs on their schedules, writing each run's
+// reports to <results>/<job>/<run id>.json and appending a Run line to
+// <results>/history.jsonl
+type Daemon struct {
+	config *Config
+	log    io.Writer
+	slots  chan struct{}
+	budget *utils.Budget // shared by every running job
+
+	mu     sync.Mutex
+	states map[string]*JobState
+
+	historyMu sync.Mutex
+}
+
+// New creates a daemon for a validated config. Scheduling and scope
+// messages go to log.
+func New(config *Config, log io.Writer) *Daemon {
+	d := &Daemon{
+		config: config,
+		log:    log,
+		slots:  make(chan struct{}, config.MaxConcurrent),
+		budget: utils.NewBudget(config.RateLimit),
+		states: make(map[string]*JobState),
+	}
+
+	for _, job := range config.Jobs {
+		if job.scope != nil {
+			job.scope.Log = log
+		}
+		d.states[job.Name] = &JobState{Name: job.Name, Schedule: job.Schedule, WatchCT: job.WatchCT}
+	}
+
+	// Seed each job's last run from the history so restarts show it
+	if runs, err := d.History(""); err == nil {
+		for i := range runs {
+			if state, ok := d.states[runs[i].Job]; ok {
+				state.LastRun = &runs[i]
+			}
+		}
+	}
+	return d
+}
+
+// Run schedules every job until ctx is cancelled. A job never overlaps
+// itself: its next run is computed after the current one finishes. A run in
+// progress when ctx is cancelled is recorded as interrupted with its
+// partial results.
+func (d *Daemon) Run(ctx context.Context) error {
+	if err := os.MkdirAll(d.config.Results, 0755); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	var watched []*Job
+	for _, job := range d.config.Jobs {
+		if job.WatchCT {
+			watched = append(watched, job)
+		}
+		if job.schedule == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			d.schedule(ctx, job)
+		}(job)
+	}
+	if len(watched) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.watch(ctx, watched)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// schedule sleeps until each of the job's run times and runs it
+func (d *Daemon) schedule(ctx context.Context, job *Job) {
+	for {
+		next := job.schedule.Next(time.Now())
+		d.update(job.Name, func(s *JobState) { s.NextRun = next.Format(time.RFC3339) })
+		d.logf("%s: next run at %s", job.Name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Wait for the job's certstream run, if any, and a free slot; a
+		// run that starts late still counts as this scheduled run
+		select {
+		case <-ctx.Done():
+			return
+		case job.busy <- struct{}{}:
+		}
+		select {
+		case <-ctx.Done():
+			<-job.busy
+			return
+		case d.slots <- struct{}{}:
+		}
+		d.runJob(ctx, job, job.Domains, TriggerSchedule)
+		<-d.slots
+		<-job.busy
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runJob scans domains with the job's settings and records the run.
+// Certstream runs scan the new hostnames themselves, so they skip subdomain
+// enumeration.
+func (d *Daemon) runJob(ctx context.Context, job *Job, domains []string, trigger string) {
+	started := time.Now().UTC()
+	run := Run{
+		Job:       job.Name,
+		ID:        started.Format("20060102T150405Z"),
+		Trigger:   trigger,
+		StartedAt: started.Format(time.RFC3339),
+		Status:    StatusOK,
+	}
+	if trigger == TriggerCertStream {
+		run.ID += "-ct"
+	}
+	d.update(job.Name, func(s *JobState) { s.Running = true; s.NextRun = "" })
+	d.logf("%s: run %s started", job.Name, run.ID)
+
+	// Every module's debug lines say which job and run they belong to
+	ctx = log.WithContext(ctx, log.FromContext(ctx).With("job", job.Name, "run", run.ID))
+
+	var known *pipeline.Known
+	knownPath := filepath.Join(d.config.Results, job.Name, knownFile)
+	if job.Incremental {
+		var err error
+		if known, err = readKnown(knownPath); err != nil {
+			run.Errors = append(run.Errors, err.Error())
+		}
+	}
+
+	var reports []*pipeline.Report
+	for _, domain := range domains {
+		config := job.pipelineConfig(domain)
+		config.SharedBudget = d.budget
+		config.Known = known
+		if trigger == TriggerCertStream {
+			config.Stages = withoutStage(config.Stages, pipeline.StageSubdomain)
+		}
+		report, err := pipeline.New(config).Run(ctx)
+		if err != nil && report == nil {
+			run.Errors = append(run.Errors, fmt.Sprintf("%s: %v", domain, err))
+			continue
+		}
+
+		for _, stageErr := range report.Errors {
+			run.Errors = append(run.Errors, fmt.Sprintf("%s: %s", domain, stageErr))
+		}
+		run.Findings += len(report.Subdomains) + len(report.Ports) + len(report.Probes) + len(report.Screenshots) + len(report.Crawl) + len(report.Analysis)
+		reports = append(reports, report)
+		if job.Incremental {
+			if known == nil {
+				known = pipeline.NewKnown()
+			}
+			known.Add(report)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.Domains = len(reports)
+
+	switch {
+	case ctx.Err() != nil:
+		run.Status = StatusInterrupted
+	case len(run.Errors) > 0:
+		run.Status = StatusPartial
+	}
+
+	run.Report = filepath.Join(d.config.Results, job.Name, run.ID+".json")
+	if err := writeJSON(run.Report, reports); err != nil {
+		run.Errors = append(run.Errors, fmt.Sprintf("writing report: %v", err))
+		run.Status = StatusPartial
+		run.Report = ""
+	}
+	if known != nil {
+		if err := writeJSON(knownPath, known); err != nil {
+			run.Errors = append(run.Errors, fmt.Sprintf("writing known assets: %v", err))
+			run.Status = StatusPartial
+		}
+	}
+	run.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+
+	if err := d.record(run); err != nil {
+		d.logf("%s: recording run %s: %v", job.Name, run.ID, err)
+	}
+	d.update(job.Name, func(s *JobState) { s.Running = false; s.LastRun = &run })
+	metrics.Default.Counter("scanner_daemon_runs_total", "Daemon job runs finished, by job and status", "job", job.Name, "status", run.Status).Inc()
+	metrics.Default.Gauge("scanner_daemon_last_run_findings", "Findings of each job's last run", "job", job.Name).Set(float64(run.Findings))
+	d.logf("%s: run %s %s: %d domains, %d findings, %d errors", job.Name, run.ID, run.Status, run.Domains, run.Findings, len(run.Errors))
+}
+
+// Jobs returns every job's current state
+func (d *Daemon) Jobs() []JobState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states := make([]JobState, 0, len(d.config.Jobs))
+	for _, job := range d.config.Jobs {
+		states = append(states, *d.states[job.Name])
+	}
+	return states
+}
+
+// update changes a job's state under the lock
+func (d *Daemon) update(name string, fn func(*JobState)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.states[name])
+}
+
+// record appends a run to the history file
+func (d *Daemon) record(run Run) error {
+	d.historyMu.Lock()
+	defer d.historyMu.Unlock()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(d.config.Results, historyFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns recorded runs oldest first, for one job or all of them
+// if job is empty
+func (d *Daemon) History(job string) ([]Run, error) {
+	d.historyMu.Lock()
+	defer d.historyMu.Unlock()
+	return ReadHistory(d.config.Results, job)
+}
+
+// ReadHistory reads the run history from a results directory
+func ReadHistory(dir, job string) ([]Run, error) {
+	f, err := os.Open(filepath.Join(dir, historyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		var run Run
+		if err := decoder.Decode(&run); err != nil {
+			return runs, fmt.Errorf("parsing history: %w", err)
+		}
+		if job == "" || run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// logf writes a timestamped line to the daemon log
+func (d *Daemon) logf(format string, args ...interface{}) {
+	if d.log == nil {
+		return
+	}
+	fmt.Fprintf(d.log, "[%s] "+format+"\n", append([]interface{}{time.Now().Format(time.RFC3339)}, args...)...)
+}
+
+// writeJSON writes v as indented JSON, creating parent directories
+func writeJSON(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readKnown reads an incremental job's record of scanned assets, or
+// returns nil before its first run
+func readKnown(path string) (*pipeline.Known, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading known assets: %w", err)
+	}
+	known := pipeline.NewKnown()
+	if err := json.Unmarshal(data, known); err != nil {
+		return nil, fmt.Errorf("reading known assets: %w", err)
+	}
+	return known, nil
+}
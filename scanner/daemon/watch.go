@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils"
+)
+
+// watch follows the certstream feed for the jobs' domains and, every
+// batch interval, scans the new hostnames each job has collected. A job
+// that is still running keeps its hostnames for the next batch. Hostnames
+// are queued in the job's results directory until a run scans them, so
+// those not yet scanned when the daemon stops are scanned after a restart.
+func (d *Daemon) watch(ctx context.Context, jobs []*Job) {
+	var domains []string
+	pending := make(map[*Job]*utils.DiskQueue[string])
+	for _, job := range jobs {
+		domains = append(domains, job.Domains...)
+
+		queue, err := d.openPending(job)
+		if err != nil {
+			d.logf("%s: certstream: %v; new hosts will not survive a restart", job.Name, err)
+			queue, _ = utils.OpenDiskQueue[string]("")
+		} else if n := queue.Len(); n > 0 {
+			d.logf("%s: certstream: %d hosts pending from before the restart", job.Name, n)
+		}
+		defer queue.Close()
+		pending[job] = queue
+	}
+
+	go subdomain.WatchCertStream(ctx, subdomain.CertStreamConfig{
+		URL:     d.config.CertStream,
+		Domains: domains,
+		OnResult: func(r subdomain.Result) {
+			for _, job := range jobs {
+				if !coversHost(job, r.Subdomain) {
+					continue
+				}
+				d.logf("%s: certstream: new host %s", job.Name, r.Subdomain)
+				if err := pending[job].Push(r.Subdomain); err != nil {
+					d.logf("%s: certstream: queueing %s: %v", job.Name, r.Subdomain, err)
+				}
+			}
+		},
+		OnError: func(err error) {
+			d.logf("certstream: %v; reconnecting", err)
+		},
+	})
+	d.logf("certstream: watching %d domains", len(domains))
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(d.config.certStreamBatch)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, job := range jobs {
+			queue := pending[job]
+			if queue.Len() == 0 {
+				continue
+			}
+			select {
+			case job.busy <- struct{}{}:
+			default:
+				continue
+			}
+
+			items := queue.Pop(0)
+			hosts := make([]string, len(items))
+			ids := make([]uint64, len(items))
+			for i, item := range items {
+				hosts[i], ids[i] = item.Value, item.ID
+			}
+			wg.Add(1)
+			go func(job *Job) {
+				defer wg.Done()
+				defer func() { <-job.busy }()
+				select {
+				case <-ctx.Done():
+					return
+				case d.slots <- struct{}{}:
+				}
+				d.runJob(ctx, job, hosts, TriggerCertStream)
+				<-d.slots
+
+				// An interrupted run's hosts stay queued for the next start
+				if ctx.Err() == nil {
+					if err := queue.Ack(ids...); err != nil {
+						d.logf("%s: certstream: %v", job.Name, err)
+					}
+				}
+			}(job)
+		}
+	}
+}
+
+// openPending opens the queue of a job's certstream hosts not yet scanned
+func (d *Daemon) openPending(job *Job) (*utils.DiskQueue[string], error) {
+	dir := filepath.Join(d.config.Results, job.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return utils.OpenDiskQueue[string](filepath.Join(dir, "certstream-pending.jsonl"))
+}
+
+// coversHost reports whether a hostname is under one of the job's domains
+// and in its scope
+func coversHost(job *Job, host string) bool {
+	for _, domain := range job.Domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return job.scope.Allows(host)
+		}
+	}
+	return false
+}
+
+// withoutStage returns stages minus one
+func withoutStage(stages []string, stage string) []string {
+	var kept []string
+	for _, s := range stages {
+		if s != stage {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
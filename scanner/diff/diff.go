@@ -0,0 +1,261 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+)
+
+// Snapshot is the comparable state of one scan: which subdomains exist,
+// which ports are open, and what each live URL returned
+type Snapshot struct {
+	Subdomains map[string]bool
+	Ports      map[string]portscan.Result
+	Probes     map[string]httpx.ProbeResult
+}
+
+// NewSnapshot creates an empty snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Subdomains: make(map[string]bool),
+		Ports:      make(map[string]portscan.Result),
+		Probes:     make(map[string]httpx.ProbeResult),
+	}
+}
+
+// Add merges results into the snapshot. It accepts the result slices the
+// commands write and pipeline reports.
+func (s *Snapshot) Add(results interface{}) {
+	switch v := results.(type) {
+	case []subdomain.Result:
+		for _, r := range v {
+			s.Subdomains[r.Subdomain] = true
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			if r.Open {
+				s.Ports[portKey(r)] = r
+			}
+		}
+	case []httpx.ProbeResult:
+		for _, r := range v {
+			s.Probes[r.URL] = r
+		}
+	case *pipeline.Report:
+		s.Add(v.Subdomains)
+		s.Add(v.Ports)
+		s.Add(v.Probes)
+	}
+}
+
+// LoadFile reads a JSON or NDJSON results file written by any command
+func LoadFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := NewSnapshot()
+	decoder := json.NewDecoder(f)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		// A file holds one array, or one value per line
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			items = []json.RawMessage{raw}
+		}
+		for _, item := range items {
+			if err := s.addRaw(item); err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", path, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// addRaw decodes one result, telling the types apart by their fields
+func (s *Snapshot) addRaw(raw json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	has := func(key string) bool { _, ok := fields[key]; return ok }
+	switch {
+	case has("scan_id") && has("result"):
+		// An -envelope wrapper
+		return s.addRaw(fields["result"])
+	case has("domain") && has("stages"):
+		var report pipeline.Report
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return err
+		}
+		s.Add(&report)
+	case has("subdomain") && has("source"):
+		var r subdomain.Result
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		s.Add([]subdomain.Result{r})
+	case has("host") && has("port"):
+		var r portscan.Result
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		s.Add([]portscan.Result{r})
+	case has("url") && has("status_code"):
+		var r httpx.ProbeResult
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		s.Add([]httpx.ProbeResult{r})
+	}
+	return nil
+}
+
+// Result lists what changed between two snapshots
+type Result struct {
+	NewSubdomains     []string           `json:"new_subdomains,omitempty"`
+	RemovedSubdomains []string           `json:"removed_subdomains,omitempty"`
+	OpenedPorts       []portscan.Result  `json:"opened_ports,omitempty"`
+	ClosedPorts       []portscan.Result  `json:"closed_ports,omitempty"`
+	StatusChanges     []StatusChange     `json:"status_changes,omitempty"`
+	NewTechnologies   []TechnologyChange `json:"new_technologies,omitempty"`
+}
+
+// StatusChange is a URL whose status code differs between runs
+type StatusChange struct {
+	URL string `json:"url"`
+	Old int    `json:"old"`
+	New int    `json:"new"`
+}
+
+// TechnologyChange lists technologies newly detected on a URL
+type TechnologyChange struct {
+	URL          string   `json:"url"`
+	Technologies []string `json:"technologies"`
+}
+
+// Empty reports whether nothing changed
+func (r *Result) Empty() bool {
+	return len(r.NewSubdomains) == 0 && len(r.RemovedSubdomains) == 0 &&
+		len(r.OpenedPorts) == 0 && len(r.ClosedPorts) == 0 &&
+		len(r.StatusChanges) == 0 && len(r.NewTechnologies) == 0
+}
+
+// Compare reports what changed from old to new. URLs seen in only one
+// snapshot have no status change; technologies are compared per URL.
+func Compare(old, new *Snapshot) *Result {
+	r := &Result{}
+
+	for host := range new.Subdomains {
+		if !old.Subdomains[host] {
+			r.NewSubdomains = append(r.NewSubdomains, host)
+		}
+	}
+	for host := range old.Subdomains {
+		if !new.Subdomains[host] {
+			r.RemovedSubdomains = append(r.RemovedSubdomains, host)
+		}
+	}
+	sort.Strings(r.NewSubdomains)
+	sort.Strings(r.RemovedSubdomains)
+
+	for key, port := range new.Ports {
+		if _, ok := old.Ports[key]; !ok {
+			r.OpenedPorts = append(r.OpenedPorts, port)
+		}
+	}
+	for key, port := range old.Ports {
+		if _, ok := new.Ports[key]; !ok {
+			r.ClosedPorts = append(r.ClosedPorts, port)
+		}
+	}
+	sortPorts(r.OpenedPorts)
+	sortPorts(r.ClosedPorts)
+
+	for url, probe := range new.Probes {
+		before, ok := old.Probes[url]
+		if !ok {
+			continue
+		}
+		if before.StatusCode != probe.StatusCode {
+			r.StatusChanges = append(r.StatusChanges, StatusChange{URL: url, Old: before.StatusCode, New: probe.StatusCode})
+		}
+
+		known := make(map[string]bool)
+		for _, tech := range before.Technologies {
+			known[tech] = true
+		}
+		var added []string
+		for _, tech := range probe.Technologies {
+			if !known[tech] {
+				added = append(added, tech)
+			}
+		}
+		if len(added) > 0 {
+			sort.Strings(added)
+			r.NewTechnologies = append(r.NewTechnologies, TechnologyChange{URL: url, Technologies: added})
+		}
+	}
+	sort.Slice(r.StatusChanges, func(i, j int) bool { return r.StatusChanges[i].URL < r.StatusChanges[j].URL })
+	sort.Slice(r.NewTechnologies, func(i, j int) bool { return r.NewTechnologies[i].URL < r.NewTechnologies[j].URL })
+
+	return r
+}
+
+// Lines renders the result as +/- text lines
+func (r *Result) Lines() []string {
+	var lines []string
+	for _, host := range r.NewSubdomains {
+		lines = append(lines, "+ subdomain "+host)
+	}
+	for _, host := range r.RemovedSubdomains {
+		lines = append(lines, "- subdomain "+host)
+	}
+	for _, port := range r.OpenedPorts {
+		lines = append(lines, "+ port "+portKey(port)+" "+port.Service)
+	}
+	for _, port := range r.ClosedPorts {
+		lines = append(lines, "- port "+portKey(port)+" "+port.Service)
+	}
+	for _, change := range r.StatusChanges {
+		lines = append(lines, fmt.Sprintf("~ status %s %d -> %d", change.URL, change.Old, change.New))
+	}
+	for _, change := range r.NewTechnologies {
+		for _, tech := range change.Technologies {
+			lines = append(lines, "+ tech "+change.URL+" "+tech)
+		}
+	}
+	return lines
+}
+
+// portKey identifies an open port across runs
+func portKey(r portscan.Result) string {
+	return r.Host + ":" + strconv.Itoa(r.Port)
+}
+
+// sortPorts orders ports by host then port number
+func sortPorts(ports []portscan.Result) {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Host != ports[j].Host {
+			return ports[i].Host < ports[j].Host
+		}
+		return ports[i].Port < ports[j].Port
+	})
+}
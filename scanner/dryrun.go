@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/wordlist"
+)
+
+// Cost assumptions behind -dry-run estimates, for typical internet targets
+const (
+	planHTTPRequestBytes  = 600       // request line, headers and TLS overhead
+	planHTTPResponseBytes = 16 * 1024 // headers and body, unless bodies are read up to less
+	planHTTPLatency       = 500 * time.Millisecond
+	planDNSBytes          = 150 // query and answer
+	planDNSLatency        = 100 * time.Millisecond
+	planConnectBytes      = 240     // SYN, SYN-ACK, ACK and RST
+	planPassiveSources    = 3       // crtsh, hackertarget, threatcrowd
+	planPassiveBytes      = 1 << 20 // a source's answer for one domain
+	planPassiveLatency    = 5 * time.Second
+
+	// planHostsPerDomain and planURLsPerHost stand in for what the pipeline
+	// stages after subdomain will be given, which only a real run finds out
+	planHostsPerDomain = 100
+	planURLsPerHost    = 2
+)
+
+// planStage is one stage's estimated cost
+type planStage struct {
+	Stage    string  `json:"stage"`
+	Requests int64   `json:"requests"`
+	Bytes    int64   `json:"bytes"`
+	Seconds  float64 `json:"seconds"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// scanPlan is what a -dry-run would have sent: request counts, bandwidth
+// and duration per stage, from the expanded targets, ports and wordlists
+type scanPlan struct {
+	Command  string      `json:"command"`
+	Target   string      `json:"target"`
+	Targets  int         `json:"targets"`
+	Stages   []planStage `json:"stages"`
+	Requests int64       `json:"requests"`
+	Bytes    int64       `json:"bytes"`
+	Seconds  float64     `json:"seconds"`
+}
+
+func newPlan(command, target string, targets int) *scanPlan {
+	return &scanPlan{Command: command, Target: target, Targets: targets, Stages: []planStage{}}
+}
+
+// add appends a stage of requests moving bytesEach each. They go out at
+// rate per second, or as fast as workers each waiting latency per request
+// allow, whichever is slower; zero leaves either unbounded.
+func (p *scanPlan) add(stage string, requests, bytesEach int64, workers, rate int, latency time.Duration, note string) {
+	perSecond := float64(rate)
+	if workers > 0 && latency > 0 {
+		byWorkers := float64(workers) / latency.Seconds()
+		if perSecond == 0 || byWorkers < perSecond {
+			perSecond = byWorkers
+		}
+	}
+	var seconds float64
+	if perSecond > 0 {
+		seconds = float64(requests) / perSecond
+	}
+
+	p.Stages = append(p.Stages, planStage{
+		Stage:    stage,
+		Requests: requests,
+		Bytes:    requests * bytesEach,
+		Seconds:  seconds,
+		Note:     note,
+	})
+	p.Requests += requests
+	p.Bytes += requests * bytesEach
+	p.Seconds += seconds
+}
+
+// httpBytes estimates the bytes of one HTTP exchange reading at most
+// maxBody of the response
+func httpBytes(maxBody int64) int64 {
+	if maxBody <= 0 {
+		maxBody = planHTTPResponseBytes
+	}
+	return planHTTPRequestBytes + min(maxBody, planHTTPResponseBytes)
+}
+
+// probeURLs counts the URLs probing targets tries: a target without a
+// scheme is tried over https and then http
+func probeURLs(targets []string) int64 {
+	var n int64
+	for _, t := range targets {
+		if strings.HasPrefix(t, "http://") || strings.HasPrefix(t, "https://") {
+			n++
+		} else {
+			n += 2
+		}
+	}
+	return n
+}
+
+// countHosts counts the distinct hosts of URLs
+func countHosts(urls []string) int64 {
+	hosts := make(map[string]bool)
+	for _, raw := range urls {
+		if u, err := url.Parse(raw); err == nil {
+			hosts[u.Host] = true
+		}
+	}
+	return int64(len(hosts))
+}
+
+// countWords returns the entries of a wordlist, exiting if it cannot be read
+func countWords(path string) int64 {
+	words, err := wordlist.ReadWords(path)
+	if err != nil {
+		fatal(err)
+	}
+	return int64(len(words))
+}
+
+// formatPlan writes a plan as an aligned table with a total line
+func formatPlan(plan *scanPlan) []string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "STAGE\tREQUESTS\tBANDWIDTH\tDURATION\tNOTE\n")
+	for _, s := range plan.Stages {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", s.Stage, s.Requests, formatSize(s.Bytes), formatPlanDuration(s.Seconds), s.Note)
+	}
+	fmt.Fprintf(w, "total\t%d\t%s\t%s\t%d targets, nothing sent (dry run)\n",
+		plan.Requests, formatSize(plan.Bytes), formatPlanDuration(plan.Seconds), plan.Targets)
+	w.Flush()
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// formatPlanDuration rounds an estimate to what it can claim
+func formatPlanDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	switch {
+	case d < time.Second:
+		return "<1s"
+	case d < time.Hour:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Minute).String()
+	}
+}
+
+// planPipeline prints the plan of a pipeline run. Only the first stages'
+// inputs are known beforehand, so the later ones assume planHostsPerDomain
+// names per enumerated domain and planURLsPerHost web services per name.
+func planPipeline(domains, stages []string, rates map[string]int, rateLimit, workers int, ports []int,
+	wordlist string, passive, bruteforce bool, imported int, maxBody int64, maxURLs int, output string, format OutputFormat) int {
+	enabled := make(map[string]bool)
+	for _, stage := range stages {
+		enabled[stage] = true
+	}
+	if len(ports) == 0 {
+		ports = pipeline.DefaultPorts
+	}
+	if workers == 0 {
+		workers = 100
+	}
+	// A stage's own rate, or its module's default, under the shared one
+	rate := func(stage string, fallback int) int {
+		if r := rates[stage]; r > 0 {
+			fallback = r
+		}
+		if rateLimit > 0 && (fallback == 0 || rateLimit < fallback) {
+			return rateLimit
+		}
+		return fallback
+	}
+
+	plan := newPlan("pipeline", strings.Join(domains, ","), len(domains))
+	n := int64(len(domains))
+	hosts := n + int64(imported)
+	if enabled[pipeline.StageSubdomain] {
+		if passive {
+			plan.add(pipeline.StageSubdomain, n*planPassiveSources, planPassiveBytes, planPassiveSources, rateLimit, planPassiveLatency, "passive sources")
+		}
+		if bruteforce && wordlist != "" {
+			plan.add(pipeline.StageSubdomain, n*countWords(wordlist), planDNSBytes, workers, rateLimit, planDNSLatency, "bruteforce, one lookup per word")
+		}
+		hosts += n * planHostsPerDomain
+	}
+	hostNote := fmt.Sprintf("assumes %d names per domain", planHostsPerDomain)
+	if enabled[pipeline.StageResolve] {
+		plan.add(pipeline.StageResolve, hosts, planDNSBytes, workers, rateLimit, planDNSLatency, hostNote)
+	}
+	if enabled[pipeline.StagePortScan] {
+		plan.add(pipeline.StagePortScan, hosts*int64(len(ports)), planConnectBytes, workers, rate(pipeline.StagePortScan, portscan.DefaultRateLimit), planHTTPLatency,
+			fmt.Sprintf("%d ports; %s", len(ports), hostNote))
+	}
+	services := hosts * planURLsPerHost
+	serviceNote := fmt.Sprintf("assumes %d web services per name", planURLsPerHost)
+	if enabled[pipeline.StageProbe] {
+		plan.add(pipeline.StageProbe, services, httpBytes(maxBody), workers, rate(pipeline.StageProbe, httpx.DefaultProbeRateLimit), planHTTPLatency, serviceNote)
+	}
+	if enabled[pipeline.StageScreenshot] {
+		plan.add(pipeline.StageScreenshot, services, httpBytes(0), workers, rateLimit, planHTTPLatency, serviceNote+"; page loads fetch more")
+	}
+	crawled := int64(0)
+	if enabled[pipeline.StageCrawl] {
+		crawled = n * int64(maxURLs)
+		plan.add(pipeline.StageCrawl, crawled, httpBytes(0), workers, rate(pipeline.StageCrawl, httpx.DefaultCrawlRateLimit), planHTTPLatency, "at most -max-urls per domain")
+	}
+	if enabled[pipeline.StageAnalyze] {
+		plan.add(pipeline.StageAnalyze, crawled, httpBytes(0), workers, rateLimit, planHTTPLatency, "crawled URLs; probed responses are reused")
+	}
+
+	outputResults(plan, output, format)
+	return exitClean
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts every environment variable the scanner reads
+const envPrefix = "SCANNER_"
+
+// parseFlags parses a command's arguments, then fills each flag not given
+// on the command line from the environment. Precedence, highest first:
+//
+//	-flag on the command line
+//	SCANNER_<COMMAND>_<FLAG>, e.g. SCANNER_PORTSCAN_TIMEOUT
+//	SCANNER_<FLAG>, e.g. SCANNER_RATE_LIMIT, for the sharedFlags only
+//	the flag's default
+//
+// Names are upper-cased with - as _. An unusable value is a usage error.
+// The --profile preset then fills flags still unset, and under --project
+// the project's paths apply; see applyProfile and applyProject.
+func parseFlags(fs *flag.FlagSet) {
+	usage := fs.Usage
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintf(fs.Output(), "\nAny flag can also be set as %s%s_<FLAG>, and the shared options (-db, -rate-limit, -proxy...) as %s<FLAG> (upper case, - as _)\n",
+			envPrefix, strings.ToUpper(fs.Name()), envPrefix)
+	}
+	fs.Parse(os.Args[2:])
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		for _, name := range envNames(fs.Name(), f.Name) {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := fs.Set(f.Name, value); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s=%q: %v\n", name, value, err)
+				os.Exit(exitUsage)
+			}
+			set[f.Name] = true
+			return
+		}
+	})
+	applyProfile(fs, set)
+	applyProject(fs, set)
+	startEvents(fs)
+}
+
+// sharedFlags are the flags addCommonFlags registers. They mean the same in
+// every command, so only they can be set for all commands at once: a name
+// like -t is targets in one command and a timeout in another.
+var sharedFlags = func() map[string]bool {
+	fs := flag.NewFlagSet("shared", flag.ContinueOnError)
+	addCommonFlags(fs)
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	return names
+}()
+
+// envNames returns the variables that can set a flag, most specific first
+func envNames(command, flagName string) []string {
+	name := strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+	names := []string{envPrefix + strings.ToUpper(command) + "_" + name}
+	if sharedFlags[flagName] {
+		names = append(names, envPrefix+name)
+	}
+	return names
+}
+
+// takeGlobalOptions removes the options given before the command from
+// os.Args, so os.Args[1] is the command, and returns their values:
+// --project NAME and --profile NAME, with one dash or two, and the value
+// after a space or =. SCANNER_PROJECT stands in for --project.
+func takeGlobalOptions() (projectName, profileName string) {
+	projectName = os.Getenv(envPrefix + "PROJECT")
+	for len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "-") {
+		option, value, hasValue := strings.Cut(strings.TrimLeft(os.Args[1], "-"), "=")
+		if option != "project" && option != "profile" {
+			break
+		}
+		consumed := 1
+		if !hasValue {
+			if len(os.Args) < 3 {
+				fmt.Fprintf(os.Stderr, "Error: --%s needs a name\n", option)
+				os.Exit(exitUsage)
+			}
+			value, consumed = os.Args[2], 2
+		}
+		os.Args = append(os.Args[:1], os.Args[1+consumed:]...)
+
+		if option == "project" {
+			projectName = value
+		} else {
+			profileName = value
+		}
+	}
+	return projectName, profileName
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// Events -log-json writes, one JSON object per line with the name in "event"
+const (
+	eventRunStart    = "run_start"
+	eventRunFinish   = "run_finish"
+	eventStageStart  = "stage_start"
+	eventStageFinish = "stage_finish"
+	eventFinding     = "finding"
+	eventError       = "error"
+	eventNotice      = "notice" // what stderr would say otherwise: resumes, scope skips
+)
+
+// runEvents is the -log-json event log, or nil. While it is set, warnings
+// and notices go to it instead of stderr and progress bars are off, so
+// stderr holds nothing but JSON lines.
+var runEvents *slog.Logger
+
+// startEvents turns on the event log if the parsed flags ask for it
+func startEvents(fs *flag.FlagSet) {
+	f := fs.Lookup("log-json")
+	if f == nil || f.Value.String() != "true" {
+		return
+	}
+	runEvents = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch {
+			case len(groups) > 0:
+			case a.Key == slog.MessageKey:
+				a.Key = "event"
+			case a.Key == slog.LevelKey:
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	if fs.Lookup("progress") != nil {
+		fs.Set("progress", "false")
+	}
+}
+
+// emitEvent writes an event with its fields as key-value pairs, if -log-json
+// was given
+func emitEvent(event string, args ...any) {
+	if runEvents != nil {
+		runEvents.Info(event, args...)
+	}
+}
+
+// emitFindings writes a finding event for each result in a batch a command
+// saves. Pipeline reports are skipped: their stages report each result as
+// they find it.
+func emitFindings(command string, results interface{}) {
+	if runEvents == nil || results == nil {
+		return
+	}
+	switch results.(type) {
+	case *pipeline.Report, []*pipeline.Report:
+		return
+	}
+
+	v := reflect.ValueOf(results)
+	if v.Kind() != reflect.Slice {
+		emitEvent(eventFinding, "command", command, "result", results)
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		emitEvent(eventFinding, "command", command, "result", v.Index(i).Interface())
+	}
+}
+
+// emitStage writes a pipeline stage's start or finish, with its counts
+func emitStage(e pipeline.StageEvent) {
+	if !e.Done {
+		emitEvent(eventStageStart, "domain", e.Domain, "stage", e.Stage)
+		return
+	}
+	stats := e.Progress.Snapshot()
+	emitEvent(eventStageFinish, "domain", e.Domain, "stage", e.Stage,
+		"done", stats.Done, "total", stats.Total, "found", stats.Found)
+}
+
+// messages returns where notices meant for people go: stderr, or notice
+// events under -log-json
+func messages() io.Writer {
+	if runEvents != nil {
+		return eventWriter(eventNotice)
+	}
+	return os.Stderr
+}
+
+// eventWriter writes each line written to it as an event of its name, for
+// code that reports to an io.Writer
+type eventWriter string
+
+func (w eventWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			emitEvent(string(w), "message", line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes, matching utils/exit-codes.sh. When several apply, the most
+// severe wins: fatal, interrupted, partial, findings, clean.
+const (
+	exitClean       = 0   // ran clean, nothing found
+	exitFatal       = 1   // fatal error, no usable results
+	exitUsage       = 2   // invalid arguments
+	exitPartial     = 8   // finished, but some sources or targets errored
+	exitFindings    = 9   // ran clean and found results
+	exitInterrupted = 130 // stopped by SIGINT/SIGTERM, partial results written
+)
+
+// runStatus collects the outcome of a command run
+type runStatus struct {
+	findings bool
+	failures int
+
+	// results counts what the run found, and errors its warnings by
+	// errorClass, for the run summary
+	results int
+	errors  map[string]int
+
+	// out receives warnings; nil means stderr
+	out io.Writer
+}
+
+// found records that the run produced n results
+func (s *runStatus) found(n int) {
+	s.results += n
+	if n > 0 {
+		s.findings = true
+	}
+}
+
+// warn reports a non-fatal failure on stderr and marks the run partial
+func (s *runStatus) warn(format string, args ...interface{}) {
+	s.failures++
+	message := fmt.Sprintf(format, args...)
+	if s.errors == nil {
+		s.errors = make(map[string]int)
+	}
+	s.errors[errorClass(message)]++
+
+	out := s.out
+	if out == nil && runEvents != nil {
+		emitEvent(eventError, "message", message, "class", errorClass(message))
+		return
+	}
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Warning: %s\n", message)
+}
+
+// warnf reports a failure that leaves the results whole, such as a
+// checkpoint that could not be written, on stderr or as an error event
+func warnf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if runEvents != nil {
+		emitEvent(eventError, "message", message, "class", errorClass(message))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+}
+
+// code returns the exit code for the run
+func (s *runStatus) code(ctx context.Context) int {
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case s.failures > 0:
+		return exitPartial
+	case s.findings:
+		return exitFindings
+	default:
+		return exitClean
+	}
+}
+
+// fatal prints an error and exits with exitFatal
+func fatal(err error) {
+	if runEvents != nil {
+		emitEvent(eventError, "message", err.Error(), "fatal", true)
+		os.Exit(exitFatal)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitFatal)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// fuzzPlaceholder replaces every parameter value in fuzz output; ffuf
+// substitutes each occurrence, and sqlmap tests every parameter anyway
+const fuzzPlaceholder = "FUZZ"
+
+// fuzzTargets collects endpoints and the parameter names they take, one
+// per method, endpoint and parameter set, for the fuzz output format
+type fuzzTargets struct {
+	seen  map[string]bool
+	lines []string
+}
+
+// formatAsFuzz writes the parameterized endpoints in crawl, params,
+// analyze and pipeline results, one per line. GET endpoints are plain URLs
+// with each parameter set to FUZZ; others are "METHOD URL BODY" lines.
+func formatAsFuzz(results interface{}) ([]byte, error) {
+	t := &fuzzTargets{seen: make(map[string]bool)}
+	if !t.collect(results) {
+		return nil, fmt.Errorf("fuzz output is only supported for crawl, params, analyze and pipeline results")
+	}
+	return []byte(strings.Join(t.lines, "\n")), nil
+}
+
+// collect adds the endpoints in a batch of results, reporting false for
+// result types that carry no parameters
+func (t *fuzzTargets) collect(results interface{}) bool {
+	switch v := results.(type) {
+	case []httpx.CrawlResult:
+		for _, r := range v {
+			t.add(r.Method, r.URL, r.Params, false)
+		}
+	case []httpx.ParamResult:
+		for _, r := range v {
+			switch r.Method {
+			case httpx.ParamMethodJSON:
+				t.add("POST", r.URL, r.Params, true)
+			default:
+				t.add(r.Method, r.URL, r.Params, false)
+			}
+		}
+	case []httpx.AnalysisResult:
+		for _, r := range v {
+			base, err := url.Parse(r.URL)
+			if err != nil {
+				continue
+			}
+			// Names seen in the page, other than its form fields, are
+			// likely ones it accepts itself
+			fields := make(map[string]bool)
+			for _, form := range r.Forms {
+				for _, field := range form.Fields {
+					fields[field] = true
+				}
+			}
+			var pageParams []string
+			for _, name := range r.Parameters {
+				if !fields[name] {
+					pageParams = append(pageParams, name)
+				}
+			}
+			t.add("GET", r.URL, pageParams, false)
+
+			for _, form := range r.Forms {
+				action, err := base.Parse(form.Action)
+				if err != nil {
+					continue
+				}
+				t.add(form.Method, action.String(), form.Fields, false)
+			}
+			for _, endpoint := range r.Endpoints {
+				if !strings.Contains(endpoint, "?") {
+					continue
+				}
+				if resolved, err := base.Parse(endpoint); err == nil {
+					t.add("GET", resolved.String(), nil, false)
+				}
+			}
+		}
+	case *pipeline.Report:
+		t.collect(v.Crawl)
+		t.collect(v.Analysis)
+	case []*pipeline.Report:
+		for _, report := range v {
+			t.collect(report)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// add records an endpoint with the names in its query string and params.
+// GET parameters all go in the query string; otherwise params go in the
+// body, as a form or, if asJSON, a JSON object.
+func (t *fuzzTargets) add(method, raw string, params []string, asJSON bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = "GET"
+	}
+
+	query := make(map[string]bool)
+	for name := range u.Query() {
+		query[name] = true
+	}
+	body := make(map[string]bool)
+	for _, name := range params {
+		if name == "" {
+			continue
+		}
+		if method == "GET" {
+			query[name] = true
+		} else {
+			body[name] = true
+		}
+	}
+	if len(query) == 0 && len(body) == 0 {
+		return
+	}
+
+	u.Fragment = ""
+	u.RawQuery = fuzzQuery(query)
+	line := u.String()
+	if method != "GET" {
+		payload := fuzzQuery(body)
+		if asJSON {
+			payload = fuzzJSON(body)
+		}
+		line = strings.TrimSpace(method + " " + line + " " + payload)
+	}
+
+	if !t.seen[line] {
+		t.seen[line] = true
+		t.lines = append(t.lines, line)
+	}
+}
+
+// fuzzQuery returns names as a query string with every value FUZZ, in a
+// stable order so endpoints with the same parameters dedupe
+func fuzzQuery(names map[string]bool) string {
+	pairs := make([]string, 0, len(names))
+	for _, name := range sortedNames(names) {
+		pairs = append(pairs, url.QueryEscape(name)+"="+fuzzPlaceholder)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// fuzzJSON returns names as a JSON object with every value FUZZ
+func fuzzJSON(names map[string]bool) string {
+	fields := make([]string, 0, len(names))
+	for _, name := range sortedNames(names) {
+		fields = append(fields, fmt.Sprintf("%q:%q", name, fuzzPlaceholder))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+// sortedNames returns the keys of names in order
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -1,7 +1,29 @@
 module github.com/recon-suite/scanner
 
-go 1.23
+go 1.23.0
 
 require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/nats-io/nats.go v1.42.0
+	github.com/redis/go-redis/v9 v9.7.3
+	golang.org/x/crypto v0.37.0
+	golang.org/x/net v0.35.0
+	golang.org/x/term v0.31.0
 	golang.org/x/time v0.5.0
+	google.golang.org/grpc v1.72.2
+	google.golang.org/protobuf v1.36.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
 )
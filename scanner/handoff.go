@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+)
+
+// handoffList collects the live services in probe, portscan and pipeline
+// results for the urls and hostport output formats, normalized so other
+// tools (nuclei, httpx, ffuf) read them as they are
+type handoffList struct {
+	urls      map[string]bool
+	hostPorts map[string]bool
+}
+
+// formatAsHandoff writes one scheme://host:port URL (FormatURLs) or
+// host:port pair (FormatHostPort) per line, sorted and without duplicates.
+// Hosts are lower-cased, IPv6 addresses bracketed and the port always
+// given, defaults included.
+func formatAsHandoff(results interface{}, format OutputFormat) ([]byte, error) {
+	l := &handoffList{urls: make(map[string]bool), hostPorts: make(map[string]bool)}
+	if !l.collect(results) {
+		return nil, fmt.Errorf("%s output is only supported for probe, portscan and pipeline results", format)
+	}
+
+	set := l.urls
+	if format == FormatHostPort {
+		set = l.hostPorts
+	}
+	lines := make([]string, 0, len(set))
+	for line := range set {
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// collect adds the services in a batch of results, reporting false for
+// result types that name none
+func (l *handoffList) collect(results interface{}) bool {
+	switch v := results.(type) {
+	case []httpx.ProbeResult:
+		for _, r := range v {
+			if r.StatusCode > 0 {
+				l.addURL(r.URL)
+			}
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			if r.Open {
+				l.add("", r.Host, r.Port)
+			}
+		}
+		// Web ports are also URLs, with the scheme their service names
+		for _, target := range portscan.WebTargets(v) {
+			l.addURL(target)
+		}
+	case *pipeline.Report:
+		l.collect(v.Ports)
+		l.collect(v.Probes)
+	case []*pipeline.Report:
+		for _, report := range v {
+			l.collect(report)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// addURL adds a URL's origin, skipping anything other than http and https
+func (l *handoffList) addURL(raw string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	scheme := strings.ToLower(u.Scheme)
+	port, err := strconv.Atoi(u.Port())
+	switch {
+	case scheme != "http" && scheme != "https":
+		return
+	case err != nil && scheme == "https":
+		port = 443
+	case err != nil:
+		port = 80
+	}
+	l.add(scheme, u.Hostname(), port)
+}
+
+// add adds a host and port, and its URL if scheme is set
+func (l *handoffList) add(scheme, host string, port int) {
+	hostPort := net.JoinHostPort(strings.ToLower(strings.TrimSuffix(host, ".")), strconv.Itoa(port))
+	l.hostPorts[hostPort] = true
+	if scheme != "" {
+		l.urls[scheme+"://"+hostPort] = true
+	}
+}
@@ -0,0 +1,478 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+)
+
+// kinds are the result kinds merged, named and ordered as pipeline stages
+var kinds = []string{
+	pipeline.StageSubdomain,
+	pipeline.StageResolve,
+	pipeline.StagePortScan,
+	pipeline.StageProbe,
+	pipeline.StageScreenshot,
+	pipeline.StageCrawl,
+	pipeline.StageAnalyze,
+}
+
+// Dataset is the results of several files merged into one record per
+// asset: a subdomain, a host's port, a URL, or a crawled method and URL
+type Dataset struct {
+	records map[string]map[string]*record
+	read    int
+}
+
+// record is one asset's result as JSON fields, so fields of any version
+// of a result survive the merge
+type record struct {
+	fields  map[string]json.RawMessage
+	sortKey string
+
+	// seen is the result's timestamp, if it has one
+	seen time.Time
+}
+
+// New creates an empty dataset
+func New() *Dataset {
+	d := &Dataset{records: make(map[string]map[string]*record)}
+	for _, kind := range kinds {
+		d.records[kind] = make(map[string]*record)
+	}
+	return d
+}
+
+// Read returns how many results were read, before deduplication
+func (d *Dataset) Read() int {
+	return d.read
+}
+
+// Len returns how many results the dataset holds
+func (d *Dataset) Len() int {
+	n := 0
+	for _, records := range d.records {
+		n += len(records)
+	}
+	return n
+}
+
+// LoadFile reads a results file into the dataset: JSON or NDJSON from any
+// of the merged commands or the pipeline, enveloped or not, resolve's CSV,
+// or an nmap or masscan report
+func (d *Dataset) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case len(trimmed) == 0:
+		return nil
+	case bytes.HasPrefix(trimmed, []byte("name,type,value,wildcard")):
+		return d.loadCSV(path, data)
+	case trimmed[0] != '[' && trimmed[0] != '{':
+		// nmap's XML, or masscan's list output
+		return d.loadPortReport(path, data)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		// A file holds one array, or one value per line
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			items = []json.RawMessage{raw}
+		}
+		for _, item := range items {
+			if err := d.addRaw(item); err != nil {
+				return fmt.Errorf("parsing %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadPortReport reads the open ports of an nmap or masscan report
+func (d *Dataset) loadPortReport(path string, data []byte) error {
+	results, err := portscan.ParseReport(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, r := range results {
+		if err := d.addTyped(pipeline.StagePortScan, r); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// loadCSV reads resolve's CSV output, one record per row, back into a
+// result per name
+func (d *Dataset) loadCSV(path string, data []byte) error {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var names []string
+	byName := make(map[string]*subdomain.ResolutionResult)
+	for _, row := range rows[1:] {
+		if len(row) != 4 {
+			return fmt.Errorf("parsing %s: expected 4 columns, got %d", path, len(row))
+		}
+		name, recordType, value := row[0], row[1], row[2]
+		r := byName[name]
+		if r == nil {
+			r = &subdomain.ResolutionResult{Subdomain: name, Alive: true, Records: make(map[string][]string)}
+			byName[name] = r
+			names = append(names, name)
+		}
+		if recordType == "A" || recordType == "AAAA" {
+			r.IPs = append(r.IPs, value)
+		}
+		r.Records[recordType] = append(r.Records[recordType], value)
+		r.Wildcard = r.Wildcard || row[3] == "true"
+	}
+	for _, name := range names {
+		if err := d.addTyped(pipeline.StageResolve, byName[name]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Add merges results already decoded, such as a stored run's: a slice of
+// one command's results or a pipeline report
+func (d *Dataset) Add(results interface{}) error {
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		items = []json.RawMessage{raw}
+	}
+	for _, item := range items {
+		if err := d.addRaw(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTyped adds a result decoded by another reader
+func (d *Dataset) addTyped(kind string, result interface{}) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return d.add(kind, raw)
+}
+
+// addRaw adds one result, telling the types apart by their fields as diff
+// does
+func (d *Dataset) addRaw(raw json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	has := func(key string) bool { _, ok := fields[key]; return ok }
+	switch {
+	case has("scan_id") && has("result"):
+		// An -envelope wrapper
+		return d.addRaw(fields["result"])
+	case has("domain") && has("stages"):
+		return d.addReport(fields)
+	case has("url") && has("status_code"):
+		return d.add(pipeline.StageProbe, raw)
+	case has("url") && has("depth"):
+		return d.add(pipeline.StageCrawl, raw)
+	case has("url") && has("security_headers"):
+		return d.add(pipeline.StageAnalyze, raw)
+	case has("url") && (has("file") || has("error")):
+		return d.add(pipeline.StageScreenshot, raw)
+	case has("subdomain") && has("alive"):
+		return d.add(pipeline.StageResolve, raw)
+	case has("subdomain") && has("source"):
+		return d.add(pipeline.StageSubdomain, raw)
+	case has("host") && has("port"):
+		return d.add(pipeline.StagePortScan, raw)
+	}
+	return fmt.Errorf("unrecognized result %.80s (merge reads subdomain, resolve, portscan, probe, screenshot, crawl, analyze and pipeline output)", raw)
+}
+
+// addReport adds the results of each stage of a pipeline report
+func (d *Dataset) addReport(fields map[string]json.RawMessage) error {
+	stages := map[string]string{
+		"subdomains":  pipeline.StageSubdomain,
+		"resolved":    pipeline.StageResolve,
+		"ports":       pipeline.StagePortScan,
+		"probes":      pipeline.StageProbe,
+		"screenshots": pipeline.StageScreenshot,
+		"crawl":       pipeline.StageCrawl,
+		"analysis":    pipeline.StageAnalyze,
+	}
+	for field, kind := range stages {
+		var items []json.RawMessage
+		if raw, ok := fields[field]; ok {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return fmt.Errorf("report %s: %w", field, err)
+			}
+		}
+		for _, item := range items {
+			if err := d.add(kind, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add merges one result of a kind into the record for its asset
+func (d *Dataset) add(kind string, raw json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	key, sortKey := assetKey(kind, fields)
+	if key == "" {
+		return fmt.Errorf("%s result without an asset: %.80s", kind, raw)
+	}
+
+	d.read++
+	r := &record{fields: fields, sortKey: sortKey}
+	// Imported reports without times carry the Unix epoch
+	if t, err := time.Parse(time.RFC3339, text(fields, "timestamp")); err == nil && t.Unix() > 0 {
+		r.seen = t
+	}
+
+	if old, ok := d.records[kind][key]; ok {
+		r = reconcile(old, r)
+	}
+	d.records[kind][key] = r
+	return nil
+}
+
+// assetKey returns the key two results of a kind share when they are
+// about the same asset, and the key the merged results are sorted by
+func assetKey(kind string, fields map[string]json.RawMessage) (key, sortKey string) {
+	switch kind {
+	case pipeline.StageSubdomain, pipeline.StageResolve:
+		key = strings.ToLower(strings.TrimSuffix(text(fields, "subdomain"), "."))
+		return key, key
+	case pipeline.StagePortScan:
+		var port int
+		json.Unmarshal(fields["port"], &port)
+		host := strings.ToLower(text(fields, "host"))
+		if host == "" || port == 0 {
+			return "", ""
+		}
+		return net.JoinHostPort(host, strconv.Itoa(port)), fmt.Sprintf("%s %05d", host, port)
+	case pipeline.StageCrawl:
+		method := strings.ToUpper(text(fields, "method"))
+		if method == "" {
+			method = "GET"
+		}
+		u := text(fields, "url")
+		if u == "" {
+			return "", ""
+		}
+		return method + " " + u, u + " " + method
+	default:
+		key = text(fields, "url")
+		return key, key
+	}
+}
+
+// reconcile merges two results for the same asset. The newer one, by
+// timestamp or else by the order read, wins each field it sets; fields it
+// leaves empty keep the older value, and lists of strings are combined.
+func reconcile(a, b *record) *record {
+	older, newer := a, b
+	if !a.seen.IsZero() && !b.seen.IsZero() && a.seen.After(b.seen) {
+		older, newer = b, a
+	}
+
+	fields := make(map[string]json.RawMessage, len(older.fields))
+	for k, v := range older.fields {
+		fields[k] = v
+	}
+	for k, v := range newer.fields {
+		if empty(v) {
+			continue
+		}
+		if combined, ok := union(fields[k], v); ok {
+			v = combined
+		}
+		fields[k] = v
+	}
+	return &record{fields: fields, sortKey: newer.sortKey, seen: newer.seen}
+}
+
+// empty reports whether a JSON value holds nothing
+func empty(v json.RawMessage) bool {
+	switch string(bytes.TrimSpace(v)) {
+	case "", "null", `""`, "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// union combines two JSON lists of strings, older values first, reporting
+// false if either is not one
+func union(older, newer json.RawMessage) (json.RawMessage, bool) {
+	var a, b []string
+	if older == nil || json.Unmarshal(older, &a) != nil || json.Unmarshal(newer, &b) != nil {
+		return nil, false
+	}
+	seen := make(map[string]bool, len(a)+len(b))
+	var combined []string
+	for _, s := range append(a, b...) {
+		if !seen[s] {
+			seen[s] = true
+			combined = append(combined, s)
+		}
+	}
+	raw, err := json.Marshal(combined)
+	return raw, err == nil
+}
+
+// text returns a string field, or "" if it is missing or not a string
+func text(fields map[string]json.RawMessage, key string) string {
+	var s string
+	json.Unmarshal(fields[key], &s)
+	return s
+}
+
+// Kinds returns the kinds of result the dataset holds, in pipeline order
+func (d *Dataset) Kinds() []string {
+	var present []string
+	for _, kind := range kinds {
+		if len(d.records[kind]) > 0 {
+			present = append(present, kind)
+		}
+	}
+	return present
+}
+
+// Results returns the merged results: the commands' own result slice when
+// the dataset holds one kind, so the output feeds the next command as
+// theirs does, or else a pipeline report of them all for domain
+func (d *Dataset) Results(domain string) (interface{}, error) {
+	report := &pipeline.Report{Domain: domain, Stages: d.Kinds()}
+	targets := map[string]interface{}{
+		pipeline.StageSubdomain:  &report.Subdomains,
+		pipeline.StageResolve:    &report.Resolved,
+		pipeline.StagePortScan:   &report.Ports,
+		pipeline.StageProbe:      &report.Probes,
+		pipeline.StageScreenshot: &report.Screenshots,
+		pipeline.StageCrawl:      &report.Crawl,
+		pipeline.StageAnalyze:    &report.Analysis,
+	}
+	for _, kind := range report.Stages {
+		if err := d.decode(kind, targets[kind]); err != nil {
+			return nil, fmt.Errorf("%s results: %w", kind, err)
+		}
+	}
+
+	var first, last time.Time
+	for _, records := range d.records {
+		for _, r := range records {
+			if !r.seen.IsZero() && (first.IsZero() || r.seen.Before(first)) {
+				first = r.seen
+			}
+			if r.seen.After(last) {
+				last = r.seen
+			}
+		}
+	}
+	if !first.IsZero() {
+		report.StartedAt = first.UTC().Format(time.RFC3339)
+		report.FinishedAt = last.UTC().Format(time.RFC3339)
+	}
+
+	if len(report.Stages) != 1 {
+		return report, nil
+	}
+	switch report.Stages[0] {
+	case pipeline.StageSubdomain:
+		return report.Subdomains, nil
+	case pipeline.StageResolve:
+		return report.Resolved, nil
+	case pipeline.StagePortScan:
+		return report.Ports, nil
+	case pipeline.StageProbe:
+		return report.Probes, nil
+	case pipeline.StageScreenshot:
+		return report.Screenshots, nil
+	case pipeline.StageCrawl:
+		return report.Crawl, nil
+	default:
+		return report.Analysis, nil
+	}
+}
+
+// Items returns every merged result on its own, kind by kind, for
+// writing one per line
+func (d *Dataset) Items() ([]interface{}, error) {
+	results, err := d.Results("")
+	if err != nil {
+		return nil, err
+	}
+	lists := []interface{}{results}
+	if report, ok := results.(*pipeline.Report); ok {
+		lists = []interface{}{report.Subdomains, report.Resolved, report.Ports, report.Probes, report.Screenshots, report.Crawl, report.Analysis}
+	}
+
+	var items []interface{}
+	for _, list := range lists {
+		v := reflect.ValueOf(list)
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, v.Index(i).Interface())
+		}
+	}
+	return items, nil
+}
+
+// decode unmarshals the records of a kind, sorted, into a pointer to a
+// slice of their result type
+func (d *Dataset) decode(kind string, into interface{}) error {
+	records := make([]*record, 0, len(d.records[kind]))
+	for _, r := range d.records[kind] {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].sortKey < records[j].sortKey })
+
+	raws := make([]map[string]json.RawMessage, 0, len(records))
+	for _, r := range records {
+		raws = append(raws, r.fields)
+	}
+	data, err := json.Marshal(raws)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
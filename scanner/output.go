@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/recon-suite/scanner/diff"
+	"github.com/recon-suite/scanner/pkg/archive"
+	"github.com/recon-suite/scanner/pkg/asn"
+	"github.com/recon-suite/scanner/pkg/codesearch"
+	"github.com/recon-suite/scanner/pkg/credcheck"
+	"github.com/recon-suite/scanner/pkg/dnsaudit"
+	"github.com/recon-suite/scanner/pkg/hostintel"
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/reputation"
+	"github.com/recon-suite/scanner/pkg/screenshot"
+	"github.com/recon-suite/scanner/pkg/snmp"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/tlsscan"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/storage"
+)
+
+// Output formats
+type OutputFormat string
+
+const (
+	FormatJSON   OutputFormat = "json"
+	FormatTXT    OutputFormat = "txt"
+	FormatNDJSON OutputFormat = "ndjson"
+	FormatSARIF  OutputFormat = "sarif"
+	FormatCSV    OutputFormat = "csv"
+	FormatFuzz   OutputFormat = "fuzz"
+	FormatHTML   OutputFormat = "html"
+	FormatMD     OutputFormat = "md"
+
+	// FormatSitemap and FormatTree lay out crawled URLs: as a sitemap.xml,
+	// and as a tree of paths per site
+	FormatSitemap OutputFormat = "sitemap"
+	FormatTree    OutputFormat = "tree"
+
+	// FormatURLs and FormatHostPort list live services for other tools
+	FormatURLs     OutputFormat = "urls"
+	FormatHostPort OutputFormat = "hostport"
+)
+
+// runEnvelope holds the identifiers -envelope wraps JSON and ndjson
+// results with, or is nil
+var runEnvelope *envelope
+
+// envelope is the scan a run's results are wrapped as part of
+type envelope struct {
+	scanID string
+	runID  int64
+	module string
+}
+
+// wrap returns a batch of results, or a single one, in envelopes, or
+// unchanged without -envelope
+func (e *envelope) wrap(results interface{}) interface{} {
+	if e == nil {
+		return results
+	}
+	envelopes := storage.Envelopes(e.scanID, e.runID, e.module, results)
+	if reflect.ValueOf(results).Kind() != reflect.Slice {
+		return envelopes[0]
+	}
+	return envelopes
+}
+
+// outputResults writes results to file or stdout
+func outputResults(results interface{}, outputFile string, format OutputFormat) {
+	var output []byte
+	var err error
+
+	switch format {
+	case FormatJSON:
+		output, err = json.MarshalIndent(runEnvelope.wrap(results), "", "  ")
+	case FormatTXT:
+		output = formatAsText(results)
+	case FormatSARIF:
+		output, err = json.MarshalIndent(toSARIF(results), "", "  ")
+	case FormatCSV:
+		output, err = formatAsCSV(results)
+	case FormatFuzz:
+		output, err = formatAsFuzz(results)
+	case FormatHTML:
+		output, err = formatAsHTML(results)
+	case FormatMD:
+		output, err = formatAsMarkdown(results)
+	case FormatSitemap:
+		output, err = formatAsSitemap(results)
+	case FormatTree:
+		output, err = formatAsTree(results)
+	case FormatURLs, FormatHostPort:
+		output, err = formatAsHandoff(results, format)
+	default:
+		output, err = json.MarshalIndent(runEnvelope.wrap(results), "", "  ")
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(exitFatal)
+	}
+
+	if outputFile != "" {
+		err = os.WriteFile(outputFile, output, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+			os.Exit(exitFatal)
+		}
+	} else {
+		fmt.Println(string(output))
+	}
+}
+
+// formatAsText converts results to plain text format
+func formatAsText(results interface{}) []byte {
+	var lines []string
+
+	switch v := results.(type) {
+	case []string:
+		lines = v
+	case []subdomain.Result:
+		for _, r := range v {
+			lines = append(lines, r.Subdomain)
+		}
+	case []subdomain.ResolutionResult:
+		for _, r := range v {
+			if len(r.Records) == 0 {
+				lines = append(lines, strings.TrimSpace(r.Subdomain+" "+strings.Join(r.IPs, ",")))
+				continue
+			}
+			for _, recordType := range subdomain.RecordTypes {
+				for _, value := range r.Records[recordType] {
+					lines = append(lines, r.Subdomain+" "+recordType+" "+value)
+				}
+			}
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			line := fmt.Sprintf("%s:%d %s", r.Host, r.Port, r.Service)
+			if r.RDNS != "" && r.RDNS != r.Host {
+				line += " (" + r.RDNS + ")"
+			}
+			if r.FTP != nil {
+				line += " [" + r.FTP.Severity + "] anonymous"
+				if r.FTP.Writable {
+					line += " writable " + strings.Join(r.FTP.WritablePaths, ",")
+				}
+			}
+			if r.StartTLS != nil {
+				if r.StartTLS.Supported {
+					line += " starttls"
+					if r.StartTLS.TLSVersion != "" {
+						line += " " + r.StartTLS.TLSVersion
+					}
+				} else {
+					line += " no-starttls"
+				}
+			}
+			if r.SSH != nil {
+				if len(r.SSH.Weak) > 0 {
+					line += fmt.Sprintf(" weak-ssh(%d)", len(r.SSH.Weak))
+				}
+				for _, key := range r.SSH.HostKeys {
+					if len(key.SharedWith) > 0 {
+						line += " hostkey-shared-with " + strings.Join(key.SharedWith, ",")
+						break
+					}
+				}
+			}
+			if r.Path != nil {
+				if r.Path.FilteredAfter != "" {
+					line += " filtered-after " + r.Path.FilteredAfter
+				} else if r.Path.Upstream != "" {
+					line += " via " + r.Path.Upstream
+				}
+			}
+			if r.RDP != nil {
+				line += " " + strings.Join(r.RDP.SecurityLayers, ",")
+				if r.RDP.NLARequired {
+					line += " nla"
+				}
+				if r.RDP.NTLM != nil && r.RDP.NTLM.DNSComputer != "" {
+					line += " " + r.RDP.NTLM.DNSComputer
+				}
+			}
+			lines = append(lines, line)
+		}
+	case []httpx.ProbeResult:
+		for _, r := range v {
+			lines = append(lines, r.URL)
+		}
+	case []httpx.CrawlResult:
+		for _, r := range v {
+			lines = append(lines, r.URL)
+		}
+	case []httpx.FuzzResult:
+		for _, r := range v {
+			line := fmt.Sprintf("%d %d %s", r.StatusCode, r.ContentLength, r.URL)
+			if r.RedirectTo != "" {
+				line += " -> " + r.RedirectTo
+			}
+			lines = append(lines, line)
+		}
+	case []httpx.ParamResult:
+		for _, r := range v {
+			lines = append(lines, r.URL+" "+strings.Join(r.Params, ","))
+		}
+	case []httpx.JSResult:
+		for _, r := range v {
+			if r.Error != "" {
+				lines = append(lines, r.URL+" error: "+r.Error)
+			}
+			for _, e := range r.Endpoints {
+				lines = append(lines, r.URL+" endpoint "+e)
+			}
+			for _, u := range r.URLs {
+				lines = append(lines, r.URL+" url "+u)
+			}
+			for _, secret := range r.Secrets {
+				lines = append(lines, r.URL+" secret "+secret)
+			}
+			for _, sink := range r.Sinks {
+				lines = append(lines, fmt.Sprintf("%s sink %s %d: %s", r.URL, sink.Sink, sink.Line, sink.Code))
+			}
+		}
+	case []httpx.TemplateResult:
+		for _, r := range v {
+			line := fmt.Sprintf("[%s] %s %s", r.Severity, r.TemplateID, r.URL)
+			names := make([]string, 0, len(r.Extracted))
+			for name := range r.Extracted {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				line += " " + name + "=" + strings.Join(r.Extracted[name], ",")
+			}
+			lines = append(lines, line)
+		}
+	case []httpx.BypassResult:
+		for _, r := range v {
+			line := fmt.Sprintf("[%s] %d->%d %s %s %s", r.Severity, r.OriginalStatus, r.StatusCode, r.Technique, r.Method, r.RequestURL)
+			for name, value := range r.Headers {
+				line += " " + name + ": " + value
+			}
+			lines = append(lines, line)
+		}
+	case []httpx.MethodResult:
+		for _, r := range v {
+			line := fmt.Sprintf("[%s] %s accepted=%s", r.Severity, r.URL, strings.Join(r.Accepted, ","))
+			if len(r.Allow) > 0 {
+				line += " allow=" + strings.Join(r.Allow, ",")
+			}
+			if len(r.Risky) > 0 {
+				line += " risky=" + strings.Join(r.Risky, ",")
+			}
+			lines = append(lines, line)
+		}
+	case []httpx.SmuggleResult:
+		for _, r := range v {
+			lines = append(lines, fmt.Sprintf("[%s] %s %s control=%dms delay=%dms confirmations=%d",
+				r.Severity, r.Technique, r.URL, r.ControlMS, r.DelayMS, r.Confirmations))
+		}
+	case []screenshot.Result:
+		for _, r := range v {
+			if r.Error != "" {
+				lines = append(lines, r.URL+" error: "+r.Error)
+			} else {
+				lines = append(lines, r.URL+" "+r.File)
+			}
+		}
+	case []tlsscan.Result:
+		for _, r := range v {
+			endpoint := net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+			if len(r.Issues) == 0 {
+				lines = append(lines, endpoint+" ok "+strings.Join(r.Versions, ","))
+			}
+			for _, issue := range r.Issues {
+				lines = append(lines, fmt.Sprintf("%s %s %s %s", endpoint, issue.Severity, issue.ID, issue.Detail))
+			}
+		}
+	case []archive.Result:
+		for _, r := range v {
+			lines = append(lines, r.URL)
+		}
+	case []codesearch.Finding:
+		for _, f := range v {
+			lines = append(lines, fmt.Sprintf("%s %s %s/%s %s", f.Kind, f.Name, f.Repository, f.Path, f.Match))
+		}
+	case []hostintel.Host:
+		for _, h := range v {
+			var ports []string
+			for _, s := range h.Services {
+				ports = append(ports, strconv.Itoa(s.Port))
+			}
+			line := fmt.Sprintf("%s %s %s ports=%s", h.Host, h.IP, h.Source, strings.Join(ports, ","))
+			if len(h.Tags) > 0 {
+				line += " tags=" + strings.Join(h.Tags, ",")
+			}
+			if len(h.OnlyCached) > 0 {
+				line += " only-cached=" + joinInts(h.OnlyCached)
+			}
+			if len(h.OnlyScanned) > 0 {
+				line += " only-scanned=" + joinInts(h.OnlyScanned)
+			}
+			lines = append(lines, line)
+		}
+	case []reputation.Result:
+		for _, r := range v {
+			line := fmt.Sprintf("[%s] %s", r.Severity, r.IP)
+			if r.Host != r.IP {
+				line += " (" + r.Host + ")"
+			}
+			if r.Compromised {
+				line += " compromised"
+			}
+			if r.Sinkholed {
+				line += " sinkholed"
+			}
+			for _, l := range r.Listings {
+				line += " " + l.List + ":" + l.Category
+			}
+			lines = append(lines, line)
+		}
+	case []credcheck.Result:
+		for _, r := range v {
+			cred := r.Password
+			if r.Username != "" {
+				cred = r.Username + ":" + r.Password
+			}
+			lines = append(lines, r.Check+" "+r.URL+" "+cred)
+		}
+	case []dnsaudit.MailResult:
+		for _, r := range v {
+			for _, issue := range r.Issues {
+				lines = append(lines, fmt.Sprintf("%s [%s] %s: %s", r.Domain, issue.Severity, issue.Check, issue.Detail))
+			}
+		}
+	case []dnsaudit.DelegationResult:
+		for _, r := range v {
+			for _, issue := range r.Issues {
+				lines = append(lines, fmt.Sprintf("%s [%s] %s: %s", r.Zone, issue.Severity, issue.Check, issue.Detail))
+			}
+		}
+	case []dnsaudit.DanglingResult:
+		for _, r := range v {
+			for _, issue := range r.Issues {
+				lines = append(lines, fmt.Sprintf("%s [%s] %s: %s", r.Name, issue.Severity, issue.Check, issue.Detail))
+			}
+		}
+	case []snmp.Result:
+		for _, r := range v {
+			line := r.Host + " " + strings.Join(r.Communities, ",")
+			if r.SysName != "" {
+				line += " " + r.SysName
+			}
+			for _, a := range r.Addresses {
+				line += " " + a.IP
+			}
+			lines = append(lines, line)
+		}
+	case []asn.Result:
+		// One prefix per line, ready for portscan -t -
+		seen := make(map[string]bool)
+		for _, r := range v {
+			for _, prefix := range r.Prefixes {
+				if !seen[prefix] {
+					seen[prefix] = true
+					lines = append(lines, prefix)
+				}
+			}
+		}
+	case *diff.Result:
+		lines = v.Lines()
+	case *scanPlan:
+		lines = formatPlan(v)
+	case []storage.Asset:
+		for _, a := range v {
+			lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s %s %s %s", a.ID, a.Kind, a.Value, a.Detail)))
+		}
+	default:
+		data, _ := json.Marshal(results)
+		return data
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// joinInts joins numbers with commas
+func joinInts(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatAsCSV converts DNS results to one row per record value
+func formatAsCSV(results interface{}) ([]byte, error) {
+	v, ok := results.([]subdomain.ResolutionResult)
+	if !ok {
+		return nil, fmt.Errorf("csv output is only supported for DNS results")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"name", "type", "value", "wildcard"})
+	for _, r := range v {
+		wildcard := strconv.FormatBool(r.Wildcard)
+		if len(r.Records) == 0 {
+			// Address lookups only fill IPs
+			for _, ip := range r.IPs {
+				recordType := "A"
+				if net.ParseIP(ip).To4() == nil {
+					recordType = "AAAA"
+				}
+				w.Write([]string{r.Subdomain, recordType, ip, wildcard})
+			}
+			continue
+		}
+		for _, recordType := range subdomain.RecordTypes {
+			for _, value := range r.Records[recordType] {
+				w.Write([]string{r.Subdomain, recordType, value, wildcard})
+			}
+		}
+	}
+	w.Flush()
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), w.Error()
+}
+
+// resultStream writes one JSON object per line as results arrive
+type resultStream struct {
+	w    *bufio.Writer
+	file *os.File
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// newResultStream opens an NDJSON stream to the output file or stdout.
+// It returns nil if the format is not ndjson.
+func newResultStream(outputFile string, format OutputFormat) *resultStream {
+	if format != FormatNDJSON {
+		return nil
+	}
+
+	var dest io.Writer = os.Stdout
+	var file *os.File
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+			os.Exit(exitFatal)
+		}
+		dest, file = f, f
+	}
+
+	w := bufio.NewWriter(dest)
+	return &resultStream{
+		w:    w,
+		file: file,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+// Write encodes a single result and flushes it
+func (rs *resultStream) Write(result interface{}) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.enc.Encode(runEnvelope.wrap(result)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		return
+	}
+	rs.w.Flush()
+}
+
+// Close flushes and closes the stream
+func (rs *resultStream) Close() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.w.Flush()
+	if rs.file != nil {
+		rs.file.Close()
+	}
+}
+
+// stderrIsTerminal reports whether stderr is attached to a terminal, which
+// decides whether progress is shown by default
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// newProgress starts a stderr progress reporter, or returns nil if disabled
+func newProgress(enabled bool, label, noun string) *utils.Progress {
+	if !enabled {
+		return nil
+	}
+	progress := utils.NewProgress(label, noun, os.Stderr)
+	progress.Start()
+	return progress
+}
@@ -0,0 +1,18 @@
+// Package archive harvests historical URLs for a domain from web archives:
+// the Wayback Machine, Common Crawl and URLScan. Old URLs often point at
+// endpoints, parameters and files a crawl of the live site no longer
+// reaches.
+//
+//	harvester := archive.NewHarvester(archive.Config{
+//		Domains:    []string{"example.com"},
+//		Subdomains: true,
+//	})
+//	results, err := harvester.Run(ctx)
+//	for _, r := range results {
+//		fmt.Println(r.URL, r.Sources)
+//	}
+//
+// URLs are deduplicated across sources; set DedupPatterns to also collapse
+// URLs that differ only by IDs, dates or hashes. Whether a URL still
+// answers is left to the caller, usually with httpx.Prober.
+package archive
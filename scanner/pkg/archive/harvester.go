@@ -0,0 +1,288 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Source names
+const (
+	SourceWayback     = "wayback"
+	SourceCommonCrawl = "commoncrawl"
+	SourceURLScan     = "urlscan"
+)
+
+// AllSources lists every archive queried by default
+var AllSources = []string{SourceWayback, SourceCommonCrawl, SourceURLScan}
+
+// Default API endpoints
+const (
+	DefaultWaybackURL     = "https://web.archive.org/cdx/search/cdx"
+	DefaultCommonCrawlURL = "https://index.commoncrawl.org/collinfo.json"
+	DefaultURLScanURL     = "https://urlscan.io/api/v1/search/"
+)
+
+// Config holds archive harvester configuration
+type Config struct {
+	// Domains are the domains whose URLs are harvested
+	Domains []string
+
+	// Subdomains also harvests URLs on every subdomain of each domain
+	Subdomains bool
+
+	// Sources are the archives queried; empty means AllSources
+	Sources []string
+
+	// Limit caps the URLs taken from each source per domain; 0 is no limit
+	Limit int
+
+	// SkipExtensions drops URLs whose path ends in one of these extensions
+	// (without the dot), such as images and fonts
+	SkipExtensions []string
+
+	// DedupPatterns collapses URLs that differ only by IDs, UUIDs, dates or
+	// hashes, keeping the first seen
+	DedupPatterns bool
+
+	// Timeout is the per-request timeout in seconds
+	Timeout int
+
+	// Proxy routes archive queries through an http://, https:// or
+	// socks5:// proxy
+	Proxy string
+
+	// URLScanKey is an optional URLScan API key, raising its rate limits
+	URLScanKey string
+
+	// WaybackURL, CommonCrawlURL and URLScanURL override the API endpoints
+	WaybackURL     string
+	CommonCrawlURL string
+	URLScanURL     string
+
+	// Scope, if set, drops out-of-scope URLs
+	Scope *scope.Scope
+
+	// Budget, if set, limits archive queries to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts source queries and URLs found
+	Progress *utils.Progress
+
+	// OnResult is called for each URL the first time it is seen
+	OnResult func(Result)
+}
+
+// Result is one archived URL
+type Result struct {
+	URL        string   `json:"url"`
+	Host       string   `json:"host"`
+	Sources    []string `json:"sources"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Timestamp  string   `json:"timestamp"`
+}
+
+// Harvester collects archived URLs
+type Harvester struct {
+	config Config
+	client *http.Client
+
+	mu      sync.Mutex
+	results map[string]*Result
+	order   []string
+
+	ccOnce sync.Once
+	ccAPI  string
+	ccErr  error
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewHarvester creates a new archive harvester
+func NewHarvester(config Config) *Harvester {
+	if len(config.Sources) == 0 {
+		config.Sources = AllSources
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60
+	}
+	if config.WaybackURL == "" {
+		config.WaybackURL = DefaultWaybackURL
+	}
+	if config.CommonCrawlURL == "" {
+		config.CommonCrawlURL = DefaultCommonCrawlURL
+	}
+	if config.URLScanURL == "" {
+		config.URLScanURL = DefaultURLScanURL
+	}
+
+	return &Harvester{
+		config:  config,
+		results: make(map[string]*Result),
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "urls"),
+		},
+	}
+}
+
+// Errors returns the failed source queries of the last run
+func (h *Harvester) Errors() []string {
+	h.errorsMu.Lock()
+	defer h.errorsMu.Unlock()
+	return append([]string(nil), h.errors...)
+}
+
+// recordError notes a failed source query
+func (h *Harvester) recordError(source, domain string, err error) {
+	h.errorsMu.Lock()
+	defer h.errorsMu.Unlock()
+	h.errors = append(h.errors, fmt.Sprintf("%s %s: %v", source, domain, err))
+}
+
+// Run queries every source for every domain and returns the deduplicated
+// URLs. If ctx is cancelled, the URLs found so far are returned with ctx's
+// error.
+func (h *Harvester) Run(ctx context.Context) ([]Result, error) {
+	queries := map[string]func(context.Context, string) ([]string, error){
+		SourceWayback:     h.queryWayback,
+		SourceCommonCrawl: h.queryCommonCrawl,
+		SourceURLScan:     h.queryURLScan,
+	}
+	for _, source := range h.config.Sources {
+		if queries[source] == nil {
+			return nil, fmt.Errorf("unknown archive source %q (want %s)", source, strings.Join(AllSources, ", "))
+		}
+	}
+
+	h.config.Progress.AddTotal(len(h.config.Domains) * len(h.config.Sources))
+
+	ctx = log.WithModule(ctx, "urls")
+	var wg sync.WaitGroup
+	for _, domain := range h.config.Domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		for _, source := range h.config.Sources {
+			wg.Add(1)
+			go func(source, domain string) {
+				defer wg.Done()
+				defer h.config.Progress.Done()
+				if err := h.config.Budget.Wait(ctx); err != nil {
+					return
+				}
+				logger := log.FromContext(ctx).With("source", source, log.Target(domain))
+				start := time.Now()
+				urls, err := queries[source](ctx, domain)
+				switch {
+				case err != nil && ctx.Err() == nil:
+					logger.Warn("source failed", log.Err(err), log.Since(start))
+					h.recordError(source, domain, err)
+				case len(urls) == 0 && err == nil:
+					logger.Info("source returned no URLs", log.Since(start))
+				default:
+					logger.Debug("source answered", "urls", len(urls), log.Since(start))
+				}
+				for _, u := range urls {
+					h.add(u, domain, source)
+				}
+			}(source, domain)
+		}
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	results := make([]Result, 0, len(h.order))
+	for _, key := range h.order {
+		r := *h.results[key]
+		sort.Strings(r.Sources)
+		results = append(results, r)
+	}
+	return results, ctx.Err()
+}
+
+// add records a URL from a source, merging it with earlier sightings
+func (h *Harvester) add(raw, domain, source string) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	if port := u.Port(); (port == "80" && u.Scheme == "http") || (port == "443" && u.Scheme == "https") {
+		u.Host = u.Hostname()
+	}
+
+	host := u.Hostname()
+	if host != domain && !(h.config.Subdomains && strings.HasSuffix(host, "."+domain)) {
+		return
+	}
+	if h.skipped(u.Path) {
+		return
+	}
+	normalized := u.String()
+	if !h.config.Scope.Allows(normalized) {
+		return
+	}
+
+	key := normalized
+	if h.config.DedupPatterns {
+		key = httpx.URLPattern(u)
+	}
+
+	h.mu.Lock()
+	if r, ok := h.results[key]; ok {
+		if !contains(r.Sources, source) {
+			r.Sources = append(r.Sources, source)
+		}
+		h.mu.Unlock()
+		return
+	}
+	r := &Result{
+		URL:       normalized,
+		Host:      host,
+		Sources:   []string{source},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	h.results[key] = r
+	h.order = append(h.order, key)
+	h.mu.Unlock()
+
+	h.config.Progress.Found()
+	metrics.Findings("urls").Inc()
+	if h.config.OnResult != nil {
+		h.config.OnResult(*r)
+	}
+}
+
+// skipped reports whether a path has one of the skipped extensions
+func (h *Harvester) skipped(p string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(p)), ".")
+	return ext != "" && contains(h.config.SkipExtensions, ext)
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// errNoCaptures marks an index's "nothing archived" answer, which some
+// APIs send as an error status
+var errNoCaptures = errors.New("no captures")
+
+// queryWayback lists the Wayback Machine's captured URLs for a domain
+func (h *Harvester) queryWayback(ctx context.Context, domain string) ([]string, error) {
+	params := url.Values{}
+	params.Set("url", h.pattern(domain))
+	params.Set("fl", "original")
+	params.Set("collapse", "urlkey")
+	if h.config.Limit > 0 {
+		params.Set("limit", strconv.Itoa(h.config.Limit))
+	}
+
+	body, err := h.fetch(ctx, h.config.WaybackURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return readLines(body, func(line string) string { return line })
+}
+
+// queryCommonCrawl lists the URLs in the latest Common Crawl index for a
+// domain
+func (h *Harvester) queryCommonCrawl(ctx context.Context, domain string) ([]string, error) {
+	api, err := h.commonCrawlAPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("url", h.pattern(domain))
+	params.Set("output", "json")
+	params.Set("fl", "url")
+	if h.config.Limit > 0 {
+		params.Set("limit", strconv.Itoa(h.config.Limit))
+	}
+
+	body, err := h.fetch(ctx, api+"?"+params.Encode(), nil)
+	if errors.Is(err, errNoCaptures) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return readLines(body, func(line string) string {
+		var entry struct {
+			URL string `json:"url"`
+		}
+		json.Unmarshal([]byte(line), &entry)
+		return entry.URL
+	})
+}
+
+// commonCrawlAPI finds the CDX endpoint of the newest Common Crawl index,
+// looking it up once per harvester
+func (h *Harvester) commonCrawlAPI(ctx context.Context) (string, error) {
+	h.ccOnce.Do(func() {
+		body, err := h.fetch(ctx, h.config.CommonCrawlURL, nil)
+		if err != nil {
+			h.ccErr = fmt.Errorf("listing indexes: %w", err)
+			return
+		}
+		defer body.Close()
+
+		var indexes []struct {
+			ID     string `json:"id"`
+			CDXAPI string `json:"cdx-api"`
+		}
+		if err := json.NewDecoder(body).Decode(&indexes); err != nil {
+			h.ccErr = fmt.Errorf("listing indexes: %w", err)
+			return
+		}
+		if len(indexes) == 0 {
+			h.ccErr = errors.New("no indexes listed")
+			return
+		}
+		// Newest first
+		h.ccAPI = indexes[0].CDXAPI
+	})
+	return h.ccAPI, h.ccErr
+}
+
+// queryURLScan lists the page URLs of URLScan's public scans of a domain
+func (h *Harvester) queryURLScan(ctx context.Context, domain string) ([]string, error) {
+	query := "page.domain:" + domain
+	if h.config.Subdomains {
+		query = "domain:" + domain
+	}
+	size := 10000
+	if h.config.Limit > 0 && h.config.Limit < size {
+		size = h.config.Limit
+	}
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("size", strconv.Itoa(size))
+
+	var header http.Header
+	if h.config.URLScanKey != "" {
+		header = http.Header{"Api-Key": {h.config.URLScanKey}}
+	}
+	body, err := h.fetch(ctx, h.config.URLScanURL+"?"+params.Encode(), header)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Results []struct {
+			Page struct {
+				URL string `json:"url"`
+			} `json:"page"`
+			Task struct {
+				URL string `json:"url"`
+			} `json:"task"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	var urls []string
+	for _, r := range resp.Results {
+		urls = append(urls, r.Task.URL)
+		if r.Page.URL != r.Task.URL {
+			urls = append(urls, r.Page.URL)
+		}
+	}
+	return urls, nil
+}
+
+// pattern is the CDX URL match for a domain, with or without subdomains
+func (h *Harvester) pattern(domain string) string {
+	if h.config.Subdomains {
+		return "*." + domain + "/*"
+	}
+	return domain + "/*"
+}
+
+// fetch GETs a URL, returning the body of a 2xx response
+func (h *Harvester) fetch(ctx context.Context, url string, header http.Header) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errNoCaptures
+		}
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// readLines applies parse to each non-blank line of r, keeping non-empty
+// values
+func readLines(r io.Reader, parse func(string) string) ([]string, error) {
+	var values []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if v := parse(line); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,17 @@
+// Package asn maps organizations to the autonomous systems they run and
+// the IP prefixes those systems announce, turning a company name into
+// netblocks to scan.
+//
+//	lookup := asn.NewLookup(asn.Config{
+//		Orgs: []string{"Example Corp"},
+//	})
+//	results, err := lookup.Run(ctx)
+//	for _, r := range results {
+//		fmt.Println(r.ASN, r.Name, r.Prefixes)
+//	}
+//
+// Organization names are searched in RIPEstat and in the bgp.tools AS
+// list; announced prefixes come from RIPEstat's routing data. Name search
+// matches any AS whose holder name contains the organization, so review
+// the ASNs before scanning what they announce.
+package asn
@@ -0,0 +1,273 @@
+package asn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Default API endpoints
+const (
+	DefaultRIPEStatURL = "https://stat.ripe.net/data"
+	DefaultBGPToolsURL = "https://bgp.tools/asns.csv"
+)
+
+// Config holds ASN lookup configuration
+type Config struct {
+	// Orgs are organization names to search for, matched case-insensitively
+	// against AS holder names
+	Orgs []string
+
+	// ASNs are autonomous systems to expand directly, skipping the search
+	ASNs []int
+
+	// IPv4Only drops announced IPv6 prefixes
+	IPv4Only bool
+
+	// Workers is how many ASNs have their prefixes fetched at once
+	Workers int
+
+	// Timeout is the per-request timeout in seconds
+	Timeout int
+
+	// Proxy routes API requests through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// UserAgent identifies the client; bgp.tools refuses generic agents
+	UserAgent string
+
+	// RIPEStatURL and BGPToolsURL override the API endpoints
+	RIPEStatURL string
+	BGPToolsURL string
+
+	// Budget, if set, limits API requests to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts ASNs expanded and those announcing prefixes
+	Progress *utils.Progress
+
+	// OnResult is called for each AS once its prefixes are known
+	OnResult func(Result)
+}
+
+// Result is one autonomous system and the prefixes it announces
+type Result struct {
+	ASN       int      `json:"asn"`
+	Name      string   `json:"name,omitempty"`
+	Country   string   `json:"country,omitempty"`
+	Source    string   `json:"source"`
+	Prefixes  []string `json:"prefixes"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// Lookup resolves organizations to ASNs and ASNs to prefixes
+type Lookup struct {
+	config Config
+	client *http.Client
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewLookup creates a new ASN lookup
+func NewLookup(config Config) *Lookup {
+	if config.Workers == 0 {
+		config.Workers = 5
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "recon-scanner asn lookup (github.com/recon-suite/scanner)"
+	}
+	if config.RIPEStatURL == "" {
+		config.RIPEStatURL = DefaultRIPEStatURL
+	}
+	if config.BGPToolsURL == "" {
+		config.BGPToolsURL = DefaultBGPToolsURL
+	}
+
+	return &Lookup{
+		config: config,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "asn"),
+		},
+	}
+}
+
+// Errors returns the failed sources and ASNs of the last run
+func (l *Lookup) Errors() []string {
+	l.errorsMu.Lock()
+	defer l.errorsMu.Unlock()
+	return append([]string(nil), l.errors...)
+}
+
+// recordError notes a failed source or ASN
+func (l *Lookup) recordError(what string, err error) {
+	l.errorsMu.Lock()
+	defer l.errorsMu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Run searches the organizations, then fetches the prefixes of every ASN
+// found or given, returning the ASNs that announce at least one. If ctx is
+// cancelled, the ASNs expanded so far are returned with ctx's error.
+func (l *Lookup) Run(ctx context.Context) ([]Result, error) {
+	found := make(map[int]*Result)
+	var order []int
+	add := func(r Result) {
+		if existing, ok := found[r.ASN]; ok {
+			if existing.Name == "" {
+				existing.Name = r.Name
+			}
+			if existing.Country == "" {
+				existing.Country = r.Country
+			}
+			return
+		}
+		found[r.ASN] = &r
+		order = append(order, r.ASN)
+	}
+
+	for _, asn := range l.config.ASNs {
+		add(Result{ASN: asn, Source: "input"})
+	}
+	ctx = log.WithModule(ctx, "asn")
+	if len(l.config.Orgs) > 0 {
+		searches := []struct {
+			source string
+			search func(context.Context, []string) ([]Result, error)
+		}{
+			{"ripestat", l.searchRIPEStat},
+			{"bgp.tools", l.searchBGPTools},
+		}
+		for _, s := range searches {
+			logger := log.FromContext(ctx).With("source", s.source)
+			start := time.Now()
+			matches, err := s.search(ctx, l.config.Orgs)
+			switch {
+			case err != nil:
+				logger.Warn("organization search failed", log.Err(err), log.Since(start))
+				l.recordError(s.source, err)
+			case len(matches) == 0:
+				logger.Info("no ASNs matched", "orgs", l.config.Orgs, log.Since(start))
+			default:
+				logger.Debug("organization search answered", "asns", len(matches), log.Since(start))
+			}
+			for _, r := range matches {
+				add(r)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	l.config.Progress.AddTotal(len(order))
+	jobs := make(chan *Result)
+	done := make(chan *Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < l.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				l.expand(ctx, r)
+				done <- r
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, asn := range order {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- found[asn]:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var results []Result
+	for r := range done {
+		l.config.Progress.Done()
+		if len(r.Prefixes) == 0 {
+			continue
+		}
+		l.config.Progress.Found()
+		metrics.Findings("asn").Inc()
+		if l.config.OnResult != nil {
+			l.config.OnResult(*r)
+		}
+		results = append(results, *r)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ASN < results[j].ASN })
+	return results, ctx.Err()
+}
+
+// expand fills in an AS's announced prefixes, and its holder name if the
+// search did not supply one
+func (l *Lookup) expand(ctx context.Context, r *Result) {
+	if r.Name == "" {
+		name, err := l.holder(ctx, r.ASN)
+		if err != nil && ctx.Err() == nil {
+			log.FromContext(ctx).Warn("looking up holder", log.Target(fmt.Sprintf("AS%d", r.ASN)), log.Err(err))
+			l.recordError(fmt.Sprintf("AS%d", r.ASN), err)
+		}
+		r.Name = name
+	}
+
+	prefixes, err := l.announcedPrefixes(ctx, r.ASN)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.FromContext(ctx).Warn("looking up prefixes", log.Target(fmt.Sprintf("AS%d", r.ASN)), log.Err(err))
+			l.recordError(fmt.Sprintf("AS%d", r.ASN), err)
+		}
+		return
+	}
+	if len(prefixes) == 0 {
+		log.FromContext(ctx).Info("AS announces no prefixes", log.Target(fmt.Sprintf("AS%d", r.ASN)))
+	}
+	for _, prefix := range prefixes {
+		if l.config.IPv4Only && strings.Contains(prefix, ":") {
+			continue
+		}
+		r.Prefixes = append(r.Prefixes, prefix)
+	}
+	r.Timestamp = time.Now().UTC().Format(time.RFC3339)
+}
+
+// ParseASN parses an AS number, with or without its "AS" prefix
+func ParseASN(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ASN %q", s)
+	}
+	return int(asn), nil
+}
@@ -0,0 +1,178 @@
+package asn
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// searchRIPEStat finds the ASNs RIPEstat's search completion lists for
+// each organization
+func (l *Lookup) searchRIPEStat(ctx context.Context, orgs []string) ([]Result, error) {
+	var results []Result
+	for _, org := range orgs {
+		var resp struct {
+			Data struct {
+				Categories []struct {
+					Category    string `json:"category"`
+					Suggestions []struct {
+						Value       string `json:"value"`
+						Label       string `json:"label"`
+						Description string `json:"description"`
+					} `json:"suggestions"`
+				} `json:"categories"`
+			} `json:"data"`
+		}
+		if err := l.ripeStat(ctx, "searchcomplete", org, &resp); err != nil {
+			return results, err
+		}
+
+		for _, category := range resp.Data.Categories {
+			if category.Category != "ASNs" {
+				continue
+			}
+			for _, s := range category.Suggestions {
+				asn, err := ParseASN(s.Value)
+				if err != nil || !matchesOrg(s.Description+" "+s.Label, org) {
+					continue
+				}
+				results = append(results, Result{ASN: asn, Name: s.Description, Source: "ripestat"})
+			}
+		}
+	}
+	return results, nil
+}
+
+// holder returns an AS's holder name from RIPEstat
+func (l *Lookup) holder(ctx context.Context, asn int) (string, error) {
+	var resp struct {
+		Data struct {
+			Holder string `json:"holder"`
+		} `json:"data"`
+	}
+	if err := l.ripeStat(ctx, "as-overview", fmt.Sprintf("AS%d", asn), &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.Holder, nil
+}
+
+// announcedPrefixes returns the prefixes RIPEstat saw an AS announce
+func (l *Lookup) announcedPrefixes(ctx context.Context, asn int) ([]string, error) {
+	var resp struct {
+		Data struct {
+			Prefixes []struct {
+				Prefix string `json:"prefix"`
+			} `json:"prefixes"`
+		} `json:"data"`
+	}
+	if err := l.ripeStat(ctx, "announced-prefixes", fmt.Sprintf("AS%d", asn), &resp); err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, p := range resp.Data.Prefixes {
+		prefixes = append(prefixes, p.Prefix)
+	}
+	return prefixes, nil
+}
+
+// ripeStat queries one RIPEstat data call for a resource
+func (l *Lookup) ripeStat(ctx context.Context, call, resource string, v interface{}) error {
+	endpoint := fmt.Sprintf("%s/%s/data.json?resource=%s", strings.TrimSuffix(l.config.RIPEStatURL, "/"), call, url.QueryEscape(resource))
+	body, err := l.fetch(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", call, err)
+	}
+	return nil
+}
+
+// searchBGPTools scans the bgp.tools AS list for holders matching any
+// organization
+func (l *Lookup) searchBGPTools(ctx context.Context, orgs []string) ([]Result, error) {
+	body, err := l.fetch(ctx, l.config.BGPToolsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	// asn,name,class,cc
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	var results []Result
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, err
+		}
+		if len(record) < 2 {
+			continue
+		}
+		asn, err := ParseASN(record[0])
+		if err != nil {
+			continue // header
+		}
+		for _, org := range orgs {
+			if matchesOrg(record[1], org) {
+				r := Result{ASN: asn, Name: record[1], Source: "bgp.tools"}
+				if len(record) > 3 {
+					r.Country = record[3]
+				}
+				results = append(results, r)
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+// fetch GETs a URL within the budget, returning the body of a 2xx response
+func (l *Lookup) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if err := l.config.Budget.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", l.config.UserAgent)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// matchesOrg reports whether a holder name contains an organization name,
+// ignoring case and punctuation
+func matchesOrg(holder, org string) bool {
+	org = normalizeName(org)
+	return org != "" && strings.Contains(" "+normalizeName(holder)+" ", " "+org+" ")
+}
+
+// normalizeName lower-cases a name and reduces punctuation and runs of
+// spaces to single spaces
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(fields, " ")
+}
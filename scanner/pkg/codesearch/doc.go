@@ -0,0 +1,20 @@
+// Package codesearch searches GitHub and GitLab code for a target's domains
+// and reports what the matching files leak: endpoints and hostnames under
+// those domains, and credentials committed next to them.
+//
+//	searcher := codesearch.NewSearcher(codesearch.Config{
+//		Domains:     []string{"example.com"},
+//		Orgs:        []string{"example"},
+//		GitHubToken: os.Getenv("GITHUB_TOKEN"),
+//	})
+//	findings, err := searcher.Run(ctx)
+//	for _, f := range findings {
+//		fmt.Println(f.Kind, f.Repository, f.Path, f.Match)
+//	}
+//
+// Both platforms require a token for code search. GitHub searches all of
+// its public code, narrowed to Orgs when set; GitLab searches each group in
+// Orgs, or the whole instance when none are set (which gitlab.com only
+// allows with advanced search). Matches are read from the search
+// fragments unless FetchFiles is set, which downloads each matching file.
+package codesearch
@@ -0,0 +1,115 @@
+package codesearch
+
+import (
+	"regexp"
+	"strings"
+)
+
+// match is one thing extract found in a file
+type match struct {
+	kind  string
+	name  string
+	match string
+}
+
+// credentialPatterns find committed secrets, by name
+var credentialPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"AWS Key", regexp.MustCompile(`AKIA[0-9A-Z]{16}`)},
+	{"Private Key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)},
+	{"GitHub Token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"GitLab Token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"Slack Token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"Google API Key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Connection String", regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:@/"'<>]+:[^\s@/"'<>]+@[^\s/"'<>]+`)},
+	{"Password", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']([^"'\s]{6,})["']`)},
+}
+
+// internalLabels mark hostnames that look like they were never meant to
+// be public
+var internalLabels = []string{
+	"internal", "intranet", "corp", "dev", "development", "staging", "stage", "stg",
+	"test", "qa", "uat", "preprod", "admin", "vpn", "jenkins", "ci", "git", "gitlab",
+	"jira", "confluence", "wiki", "grafana", "kibana", "vault", "db", "sql", "ldap",
+}
+
+// extract finds the hostnames and URLs under domain in text, and any
+// credentials
+func extract(text, domain string) []match {
+	var matches []match
+	seen := make(map[string]bool)
+	add := func(kind, name, value string) {
+		value = truncate(value, 200)
+		if !seen[kind+value] {
+			seen[kind+value] = true
+			matches = append(matches, match{kind: kind, name: name, match: value})
+		}
+	}
+
+	quoted := regexp.QuoteMeta(domain)
+	urlRe := regexp.MustCompile(`(?i)\bhttps?://(?:[a-z0-9-]+\.)*` + quoted + `(?::[0-9]+)?/[^\s"'<>()\[\]{}\x60]+`)
+	for _, u := range urlRe.FindAllString(text, -1) {
+		add(KindEndpoint, "url", strings.TrimRight(u, ".,;:"))
+	}
+
+	hostRe := regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+` + quoted + `\b`)
+	for _, host := range hostRe.FindAllString(text, -1) {
+		host = strings.ToLower(host)
+		name := "subdomain"
+		if looksInternal(strings.TrimSuffix(host, "."+domain)) {
+			name = "internal"
+		}
+		add(KindHostname, name, host)
+	}
+
+	for _, p := range credentialPatterns {
+		for _, m := range p.pattern.FindAllStringSubmatch(text, -1) {
+			if len(m) > 1 && placeholder(m[1]) {
+				continue
+			}
+			add(KindCredential, p.name, m[0])
+		}
+	}
+	return matches
+}
+
+// looksInternal reports whether any label of a subdomain prefix is a
+// typical internal name
+func looksInternal(prefix string) bool {
+	for _, label := range strings.FieldsFunc(prefix, func(r rune) bool { return r == '.' || r == '-' }) {
+		for _, internal := range internalLabels {
+			if label == internal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// placeholder reports whether a secret value is a template or example
+// rather than a real secret
+func placeholder(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.Contains(value, "${") || strings.Contains(value, "{{") || strings.HasPrefix(value, "<") {
+		return true
+	}
+	if strings.Trim(lower, "x*.-_") == "" {
+		return true
+	}
+	for _, word := range []string{"changeme", "example", "password", "placeholder", "your"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package codesearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// searchGitHub runs a GitHub code search for a domain, in one org if set
+func (s *Searcher) searchGitHub(ctx context.Context, domain, org string) ([]file, error) {
+	query := `"` + domain + `"`
+	if org != "" {
+		query += " org:" + org
+	}
+	header := http.Header{
+		"Authorization":        {"Bearer " + s.config.GitHubToken},
+		"Accept":               {"application/vnd.github.text-match+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+
+	var files []file
+	perPage := min(s.config.MaxResults, 100)
+	for page := 1; len(files) < s.config.MaxResults; page++ {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("per_page", fmt.Sprint(perPage))
+		params.Set("page", fmt.Sprint(page))
+
+		body, err := s.get(ctx, s.config.GitHubURL+"/search/code?"+params.Encode(), header)
+		if err != nil {
+			return files, err
+		}
+		var resp struct {
+			Items []struct {
+				Path       string `json:"path"`
+				URL        string `json:"url"`
+				HTMLURL    string `json:"html_url"`
+				Repository struct {
+					FullName string `json:"full_name"`
+				} `json:"repository"`
+				TextMatches []struct {
+					Fragment string `json:"fragment"`
+				} `json:"text_matches"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return files, fmt.Errorf("decoding response: %w", err)
+		}
+
+		for _, item := range resp.Items {
+			var fragments []string
+			for _, m := range item.TextMatches {
+				fragments = append(fragments, m.Fragment)
+			}
+			contentsURL := item.URL
+			files = append(files, file{
+				platform:   PlatformGitHub,
+				repository: item.Repository.FullName,
+				path:       item.Path,
+				url:        item.HTMLURL,
+				domain:     domain,
+				text:       strings.Join(fragments, "\n"),
+				fetch: func(ctx context.Context) (string, error) {
+					raw, err := s.get(ctx, contentsURL, http.Header{
+						"Authorization": {"Bearer " + s.config.GitHubToken},
+						"Accept":        {"application/vnd.github.raw+json"},
+					})
+					return string(raw), err
+				},
+			})
+		}
+		if len(resp.Items) < perPage {
+			break
+		}
+	}
+	if len(files) > s.config.MaxResults {
+		files = files[:s.config.MaxResults]
+	}
+	return files, nil
+}
@@ -0,0 +1,105 @@
+package codesearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlabProject is the part of a GitLab project the search reports
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+}
+
+// searchGitLab runs a GitLab blob search for a domain, in one group if
+// set or across the instance
+func (s *Searcher) searchGitLab(ctx context.Context, domain, group string) ([]file, error) {
+	endpoint := s.config.GitLabURL + "/api/v4/search"
+	if group != "" {
+		endpoint = s.config.GitLabURL + "/api/v4/groups/" + url.PathEscape(group) + "/search"
+	}
+	header := http.Header{"Private-Token": {s.config.GitLabToken}}
+
+	projects := make(map[int]gitlabProject)
+	var files []file
+	perPage := min(s.config.MaxResults, 100)
+	for page := 1; len(files) < s.config.MaxResults; page++ {
+		params := url.Values{}
+		params.Set("scope", "blobs")
+		params.Set("search", domain)
+		params.Set("per_page", fmt.Sprint(perPage))
+		params.Set("page", fmt.Sprint(page))
+
+		body, err := s.get(ctx, endpoint+"?"+params.Encode(), header)
+		if err != nil {
+			return files, err
+		}
+		var blobs []struct {
+			Path      string `json:"path"`
+			Ref       string `json:"ref"`
+			Data      string `json:"data"`
+			ProjectID int    `json:"project_id"`
+		}
+		if err := json.Unmarshal(body, &blobs); err != nil {
+			return files, fmt.Errorf("decoding response: %w", err)
+		}
+
+		for _, blob := range blobs {
+			project, ok := projects[blob.ProjectID]
+			if !ok {
+				project, err = s.gitlabProject(ctx, blob.ProjectID)
+				if err != nil {
+					if ctx.Err() != nil {
+						return files, ctx.Err()
+					}
+					s.recordError(fmt.Sprintf("gitlab project %d", blob.ProjectID), err)
+					project = gitlabProject{PathWithNamespace: fmt.Sprint(blob.ProjectID)}
+				}
+				projects[blob.ProjectID] = project
+			}
+
+			f := file{
+				platform:   PlatformGitLab,
+				repository: project.PathWithNamespace,
+				path:       blob.Path,
+				domain:     domain,
+				text:       blob.Data,
+			}
+			if project.WebURL != "" {
+				f.url = project.WebURL + "/-/blob/" + blob.Ref + "/" + blob.Path
+			}
+			rawURL := fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw?ref=%s",
+				s.config.GitLabURL, blob.ProjectID, url.PathEscape(blob.Path), url.QueryEscape(blob.Ref))
+			f.fetch = func(ctx context.Context) (string, error) {
+				raw, err := s.get(ctx, rawURL, header)
+				return string(raw), err
+			}
+			files = append(files, f)
+		}
+		if len(blobs) < perPage {
+			break
+		}
+	}
+	if len(files) > s.config.MaxResults {
+		files = files[:s.config.MaxResults]
+	}
+	return files, nil
+}
+
+// gitlabProject looks up a project's path and web URL
+func (s *Searcher) gitlabProject(ctx context.Context, id int) (gitlabProject, error) {
+	var project gitlabProject
+	body, err := s.get(ctx, fmt.Sprintf("%s/api/v4/projects/%d", s.config.GitLabURL, id), http.Header{"Private-Token": {s.config.GitLabToken}})
+	if err != nil {
+		return project, err
+	}
+	if err := json.Unmarshal(body, &project); err != nil {
+		return project, fmt.Errorf("decoding project: %w", err)
+	}
+	project.WebURL = strings.TrimSuffix(project.WebURL, "/")
+	return project, nil
+}
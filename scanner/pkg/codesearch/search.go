@@ -0,0 +1,328 @@
+package codesearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Platform names
+const (
+	PlatformGitHub = "github"
+	PlatformGitLab = "gitlab"
+)
+
+// Default API endpoints
+const (
+	DefaultGitHubURL = "https://api.github.com"
+	DefaultGitLabURL = "https://gitlab.com"
+)
+
+// Finding kinds
+const (
+	KindEndpoint   = "endpoint"
+	KindHostname   = "hostname"
+	KindCredential = "credential"
+)
+
+// Config holds code search configuration
+type Config struct {
+	// Domains are searched for verbatim; findings are tied to them
+	Domains []string
+
+	// Orgs are GitHub organizations or users and GitLab groups the search
+	// is narrowed to
+	Orgs []string
+
+	// GitHubToken and GitLabToken authenticate each platform; a platform
+	// without a token is skipped
+	GitHubToken string
+	GitLabToken string
+
+	// GitHubURL and GitLabURL override the API endpoints, e.g. for GitHub
+	// Enterprise or a self-hosted GitLab
+	GitHubURL string
+	GitLabURL string
+
+	// MaxResults caps the files taken from each search; default 100
+	MaxResults int
+
+	// FetchFiles downloads each matching file instead of reading only the
+	// search fragments, at one extra request per file
+	FetchFiles bool
+
+	// Timeout is the per-request timeout in seconds
+	Timeout int
+
+	// Proxy routes API requests through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Budget, if set, limits API requests to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts searches run and findings made
+	Progress *utils.Progress
+
+	// OnResult is called for each finding as it is made
+	OnResult func(Finding)
+}
+
+// Finding is one leaked endpoint, hostname or credential in a file
+type Finding struct {
+	Platform   string `json:"platform"`
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	URL        string `json:"url"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Match      string `json:"match"`
+	Domain     string `json:"domain"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// file is one search hit: a file and the text known of it
+type file struct {
+	platform   string
+	repository string
+	path       string
+	url        string
+	domain     string
+	text       string
+	fetch      func(context.Context) (string, error)
+}
+
+// Searcher runs code searches
+type Searcher struct {
+	config Config
+	client *http.Client
+
+	mu   sync.Mutex
+	seen map[string]bool
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewSearcher creates a new code searcher
+func NewSearcher(config Config) *Searcher {
+	if config.GitHubURL == "" {
+		config.GitHubURL = DefaultGitHubURL
+	}
+	if config.GitLabURL == "" {
+		config.GitLabURL = DefaultGitLabURL
+	}
+	config.GitHubURL = strings.TrimSuffix(config.GitHubURL, "/")
+	config.GitLabURL = strings.TrimSuffix(config.GitLabURL, "/")
+	if config.MaxResults == 0 {
+		config.MaxResults = 100
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+
+	return &Searcher{
+		config: config,
+		seen:   make(map[string]bool),
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "code"),
+		},
+	}
+}
+
+// Errors returns the failed searches of the last run
+func (s *Searcher) Errors() []string {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	return append([]string(nil), s.errors...)
+}
+
+// recordError notes a failed search or file download
+func (s *Searcher) recordError(what string, err error) {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	s.errors = append(s.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Run searches every platform with a token for every domain and returns
+// the findings. If ctx is cancelled, the findings so far are returned with
+// ctx's error.
+func (s *Searcher) Run(ctx context.Context) ([]Finding, error) {
+	type search struct {
+		name string
+		run  func(context.Context, string, string) ([]file, error)
+	}
+	var searches []search
+	if s.config.GitHubToken != "" {
+		searches = append(searches, search{PlatformGitHub, s.searchGitHub})
+	}
+	if s.config.GitLabToken != "" {
+		searches = append(searches, search{PlatformGitLab, s.searchGitLab})
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("code search needs a GitHub or GitLab token")
+	}
+
+	orgs := s.config.Orgs
+	if len(orgs) == 0 {
+		orgs = []string{""}
+	}
+	s.config.Progress.AddTotal(len(searches) * len(s.config.Domains) * len(orgs))
+
+	ctx = log.WithModule(ctx, "code")
+	var findings []Finding
+	for _, search := range searches {
+		for _, domain := range s.config.Domains {
+			domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+			for _, org := range orgs {
+				if ctx.Err() != nil {
+					return findings, ctx.Err()
+				}
+				what := search.name + " " + strings.TrimSpace(domain+" "+org)
+				logger := log.FromContext(ctx).With("source", search.name, log.Target(domain))
+				if org != "" {
+					logger = logger.With("org", org)
+				}
+				start := time.Now()
+				files, err := search.run(ctx, domain, org)
+				if err != nil && ctx.Err() == nil {
+					logger.Warn("search failed", log.Err(err), log.Since(start))
+					s.recordError(what, err)
+				}
+				before := len(findings)
+				for _, f := range files {
+					findings = append(findings, s.inspect(ctx, f)...)
+				}
+				switch {
+				case len(files) == 0 && err == nil:
+					logger.Info("search matched no files", log.Since(start))
+				case len(findings) == before && len(files) > 0:
+					logger.Info("matched files held no findings", "files", len(files), log.Since(start))
+				default:
+					logger.Debug("search finished", "files", len(files), "findings", len(findings)-before, log.Since(start))
+				}
+				s.config.Progress.Done()
+			}
+		}
+	}
+	return findings, ctx.Err()
+}
+
+// inspect extracts findings from a file, downloading it first if
+// configured to
+func (s *Searcher) inspect(ctx context.Context, f file) []Finding {
+	text := f.text
+	if s.config.FetchFiles && f.fetch != nil {
+		content, err := f.fetch(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.FromContext(ctx).Warn("downloading file", log.Target(f.repository+"/"+f.path), log.Err(err))
+				s.recordError(f.platform+" "+f.repository+"/"+f.path, err)
+			}
+		} else {
+			text = content
+		}
+	}
+
+	var findings []Finding
+	for _, m := range extract(text, f.domain) {
+		key := strings.Join([]string{f.platform, f.repository, f.path, m.kind, m.match}, "\x00")
+		s.mu.Lock()
+		dup := s.seen[key]
+		s.seen[key] = true
+		s.mu.Unlock()
+		if dup {
+			continue
+		}
+
+		finding := Finding{
+			Platform:   f.platform,
+			Repository: f.repository,
+			Path:       f.path,
+			URL:        f.url,
+			Kind:       m.kind,
+			Name:       m.name,
+			Match:      m.match,
+			Domain:     f.domain,
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		}
+		s.config.Progress.Found()
+		metrics.Findings("code").Inc()
+		if s.config.OnResult != nil {
+			s.config.OnResult(finding)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// get sends an authenticated GET within the budget, waiting out rate
+// limits, and returns the body of a 2xx response
+func (s *Searcher) get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := s.config.Budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return body, nil
+		}
+		wait, limited := rateLimitWait(resp)
+		if !limited || attempt >= 3 {
+			return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(truncate(string(body), 200)))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitWait reports whether a response is a rate limit and how long
+// to wait before retrying, at most a minute
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	wait, advised := utils.RetryAfterDelay(resp.Header, time.Now())
+	if !advised {
+		// A plain 403 is a permission problem, not a rate limit
+		if resp.StatusCode == http.StatusForbidden {
+			return 0, false
+		}
+		wait = 10 * time.Second
+	}
+	return min(wait+time.Second, time.Minute), true
+}
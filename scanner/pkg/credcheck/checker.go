@@ -0,0 +1,407 @@
+package credcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Config holds default credential check configuration
+type Config struct {
+	// URLs are base URLs of web interfaces, usually live URLs from a probe
+	URLs []string
+
+	// SNMPHosts are hosts answering on UDP/161
+	SNMPHosts []string
+
+	// Checks are the checks run; empty means AllChecks
+	Checks []string
+
+	// MaxAttempts caps the credentials tried per interface; default 3
+	MaxAttempts int
+
+	// Delay is the pause between attempts on one host in seconds; default 3
+	Delay int
+
+	// Workers is how many hosts are checked at once; attempts on one host
+	// are never concurrent
+	Workers   int
+	Timeout   int
+	UserAgent string
+
+	// Proxy routes web logins through an http://, https:// or socks5://
+	// proxy; SNMP is UDP and always goes direct
+	Proxy string
+
+	// Scope, if set, skips out-of-scope URLs and hosts
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts interfaces checked and credentials found
+	Progress *utils.Progress
+
+	// OnResult is called for each working credential
+	OnResult func(Result)
+}
+
+// Result is a default credential that worked
+type Result struct {
+	Target    string `json:"target"`
+	Check     string `json:"check"`
+	Service   string `json:"service"`
+	URL       string `json:"url"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password"`
+	Evidence  string `json:"evidence,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Credential is one username and password pair; SNMP uses the password
+// as the community string
+type Credential struct {
+	Username string
+	Password string
+}
+
+// outcome is the verdict on one login attempt
+type outcome int
+
+const (
+	failed outcome = iota
+	succeeded
+	lockedOut
+)
+
+// unit is one interface to check on a host
+type unit struct {
+	target string
+	check  string
+}
+
+// errLockout stops every check on a host
+var errLockout = fmt.Errorf("response shows throttling or lockout; stopped checking this host")
+
+// Checker tries default credentials
+type Checker struct {
+	config Config
+	client *http.Client
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewChecker creates a new default credential checker
+func NewChecker(config Config) *Checker {
+	if len(config.Checks) == 0 {
+		config.Checks = AllChecks
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 3
+	}
+	if config.Delay == 0 {
+		config.Delay = 3
+	}
+	if config.Workers == 0 {
+		config.Workers = 5
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	}
+
+	return &Checker{
+		config: config,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy:           utils.ProxyFunc(config.Proxy),
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}, "creds"),
+			// Login redirects are the answer, not something to follow
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Errors returns the hosts abandoned and checks that failed in the last run
+func (c *Checker) Errors() []string {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]string(nil), c.errors...)
+}
+
+// recordError notes a failed check
+func (c *Checker) recordError(target string, err error) {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	c.errors = append(c.errors, fmt.Sprintf("%s: %v", target, err))
+}
+
+// Run checks every URL and SNMP host. If ctx is cancelled, the
+// credentials found so far are returned with ctx's error.
+func (c *Checker) Run(ctx context.Context) ([]Result, error) {
+	// Units are grouped by host so one host only ever sees one attempt at
+	// a time, however many of its ports or paths are listed
+	hosts := make(map[string][]unit)
+	var order []string
+	add := func(host string, u unit) {
+		if _, ok := hosts[host]; !ok {
+			order = append(order, host)
+		}
+		hosts[host] = append(hosts[host], u)
+	}
+	for _, target := range c.config.Scope.Filter(c.config.URLs) {
+		if !strings.Contains(target, "://") {
+			target = "https://" + target
+		}
+		target = strings.TrimSuffix(target, "/")
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		for _, check := range c.config.Checks {
+			if _, ok := httpChecks[check]; ok {
+				add(u.Hostname(), unit{target: target, check: check})
+			}
+		}
+	}
+	if c.selected(CheckSNMP) {
+		for _, host := range c.config.Scope.Filter(c.config.SNMPHosts) {
+			name := host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				name = h
+			}
+			add(name, unit{target: host, check: CheckSNMP})
+		}
+	}
+
+	total := 0
+	for _, units := range hosts {
+		total += len(units)
+	}
+	c.config.Progress.AddTotal(total)
+
+	ctx = log.WithModule(ctx, "creds")
+	jobs := make(chan []unit)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for units := range jobs {
+				c.checkHost(ctx, units, results)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, host := range order {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- hosts[host]:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []Result
+	for result := range results {
+		c.config.Progress.Found()
+		metrics.Findings("creds").Inc()
+		if c.config.OnResult != nil {
+			c.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	return found, ctx.Err()
+}
+
+// selected reports whether a check is enabled
+func (c *Checker) selected(check string) bool {
+	for _, name := range c.config.Checks {
+		if name == check {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost runs every unit on one host in turn, pacing attempts and
+// giving up on the host at the first sign of lockout
+func (c *Checker) checkHost(ctx context.Context, units []unit, results chan<- Result) {
+	var last time.Time
+	pace := func() error {
+		if !last.IsZero() {
+			wait := time.Until(last.Add(time.Duration(c.config.Delay) * time.Second))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		last = time.Now()
+		return nil
+	}
+
+	unreachable := make(map[string]bool)
+	for i, u := range units {
+		if ctx.Err() != nil {
+			return
+		}
+		if unreachable[u.target] {
+			c.config.Progress.Done()
+			continue
+		}
+		var err error
+		if u.check == CheckSNMP {
+			err = c.checkSNMP(ctx, u.target, pace, results)
+		} else {
+			err = c.checkHTTP(ctx, u.target, httpChecks[u.check], pace, results)
+		}
+		c.config.Progress.Done()
+
+		if err == errLockout {
+			log.FromContext(ctx).Warn("lockout suspected, skipping the host's other checks", log.Target(u.target), "check", u.check)
+			c.recordError(u.target, err)
+			for range units[i+1:] {
+				c.config.Progress.Done()
+			}
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			// One error is enough; the other checks on this URL would fail alike
+			log.FromContext(ctx).Warn("check failed, skipping the target's other checks", log.Target(u.target), "check", u.check, log.Err(err))
+			c.recordError(u.target, fmt.Errorf("%s: %w", u.check, err))
+			unreachable[u.target] = true
+		}
+	}
+}
+
+// checkHTTP detects one web interface and, if it is there, tries its
+// default credentials until one works
+func (c *Checker) checkHTTP(ctx context.Context, base string, check httpCheck, pace func() error, results chan<- Result) error {
+	loginURL, ok, err := check.detect(ctx, c, base)
+	if err != nil || !ok {
+		return err
+	}
+
+	for i, cred := range check.creds {
+		if i >= c.config.MaxAttempts {
+			break
+		}
+		if err := pace(); err != nil {
+			return err
+		}
+		result, evidence, err := check.login(ctx, c, loginURL, cred)
+		if err != nil {
+			return err
+		}
+		switch result {
+		case lockedOut:
+			return errLockout
+		case succeeded:
+			results <- Result{
+				Target:    base,
+				Check:     check.name,
+				Service:   check.service,
+				URL:       loginURL,
+				Username:  cred.Username,
+				Password:  cred.Password,
+				Evidence:  evidence,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// response is what a check reads from an HTTP response
+type response struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// do sends one request, with basic auth if cred is set
+func (c *Checker) do(ctx context.Context, method, target, body, contentType string, cred *Credential) (response, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return response{}, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if cred != nil {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+	c.config.Budget.Wait(ctx)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+	return response{status: resp.StatusCode, header: resp.Header, body: string(data)}, nil
+}
+
+// lockoutPhrases appear on pages that throttle or lock out logins
+var lockoutPhrases = []string{"locked", "too many", "try again later", "captcha", "temporarily blocked"}
+
+// throttled reports whether a failed login's response shows throttling or
+// lockout
+func throttled(r response) bool {
+	if r.status == http.StatusTooManyRequests || r.header.Get("Retry-After") != "" {
+		return true
+	}
+	body := strings.ToLower(r.body)
+	for _, phrase := range lockoutPhrases {
+		if strings.Contains(body, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// verdict turns a login response into an outcome
+func verdict(r response, ok bool) outcome {
+	switch {
+	case ok:
+		return succeeded
+	case throttled(r):
+		return lockedOut
+	}
+	return failed
+}
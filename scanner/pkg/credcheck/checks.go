@@ -0,0 +1,190 @@
+package credcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/snmp"
+)
+
+// Check names
+const (
+	CheckTomcat   = "tomcat"
+	CheckGrafana  = "grafana"
+	CheckJenkins  = "jenkins"
+	CheckRabbitMQ = "rabbitmq"
+	CheckSNMP     = "snmp"
+)
+
+// AllChecks lists every check, run by default
+var AllChecks = []string{CheckTomcat, CheckGrafana, CheckJenkins, CheckRabbitMQ, CheckSNMP}
+
+// httpCheck is the default credential test for one web interface
+type httpCheck struct {
+	name    string
+	service string
+
+	// creds are tried in order, most common first
+	creds []Credential
+
+	// detect reports whether base serves the interface, and the URL logins
+	// go to
+	detect func(ctx context.Context, c *Checker, base string) (string, bool, error)
+
+	// login tries one credential, returning evidence on success
+	login func(ctx context.Context, c *Checker, loginURL string, cred Credential) (outcome, string, error)
+}
+
+// httpChecks are the web interface checks by name
+var httpChecks = map[string]httpCheck{
+	CheckTomcat: {
+		name:    CheckTomcat,
+		service: "Tomcat Manager",
+		creds: []Credential{
+			{"tomcat", "tomcat"}, {"admin", "admin"}, {"tomcat", "s3cret"}, {"admin", "tomcat"}, {"manager", "manager"},
+		},
+		detect: func(ctx context.Context, c *Checker, base string) (string, bool, error) {
+			loginURL := base + "/manager/html"
+			r, err := c.do(ctx, http.MethodGet, loginURL, "", "", nil)
+			if err != nil {
+				return "", false, err
+			}
+			realm := r.header.Get("WWW-Authenticate")
+			return loginURL, r.status == http.StatusUnauthorized && strings.Contains(realm, "Tomcat Manager"), nil
+		},
+		login: func(ctx context.Context, c *Checker, loginURL string, cred Credential) (outcome, string, error) {
+			r, err := c.do(ctx, http.MethodGet, loginURL, "", "", &cred)
+			if err != nil {
+				return failed, "", err
+			}
+			ok := r.status == http.StatusOK && strings.Contains(r.body, "Tomcat Web Application Manager")
+			return verdict(r, ok), "manager application listing", nil
+		},
+	},
+
+	CheckGrafana: {
+		name:    CheckGrafana,
+		service: "Grafana",
+		creds: []Credential{
+			{"admin", "admin"}, {"admin", "prom-operator"}, {"admin", "grafana"},
+		},
+		detect: func(ctx context.Context, c *Checker, base string) (string, bool, error) {
+			r, err := c.do(ctx, http.MethodGet, base+"/login", "", "", nil)
+			if err != nil {
+				return "", false, err
+			}
+			return base + "/login", r.status == http.StatusOK && strings.Contains(strings.ToLower(r.body), "grafana"), nil
+		},
+		login: func(ctx context.Context, c *Checker, loginURL string, cred Credential) (outcome, string, error) {
+			body, _ := json.Marshal(map[string]string{"user": cred.Username, "password": cred.Password})
+			r, err := c.do(ctx, http.MethodPost, loginURL, string(body), "application/json", nil)
+			if err != nil {
+				return failed, "", err
+			}
+			ok := r.status == http.StatusOK && strings.Contains(r.body, "Logged in")
+			return verdict(r, ok), "login API accepted the credentials", nil
+		},
+	},
+
+	CheckJenkins: {
+		name:    CheckJenkins,
+		service: "Jenkins",
+		creds: []Credential{
+			{"admin", "admin"}, {"admin", "password"}, {"jenkins", "jenkins"},
+		},
+		detect: func(ctx context.Context, c *Checker, base string) (string, bool, error) {
+			r, err := c.do(ctx, http.MethodGet, base+"/login", "", "", nil)
+			if err != nil {
+				return "", false, err
+			}
+			ok := r.status == http.StatusOK && r.header.Get("X-Jenkins") != "" && strings.Contains(r.body, "j_username")
+			return base + "/j_spring_security_check", ok, nil
+		},
+		login: func(ctx context.Context, c *Checker, loginURL string, cred Credential) (outcome, string, error) {
+			form := url.Values{}
+			form.Set("j_username", cred.Username)
+			form.Set("j_password", cred.Password)
+			form.Set("from", "/")
+			form.Set("Submit", "Sign in")
+			r, err := c.do(ctx, http.MethodPost, loginURL, form.Encode(), "application/x-www-form-urlencoded", nil)
+			if err != nil {
+				return failed, "", err
+			}
+			// Both outcomes redirect; failures go back to the login page
+			location := r.header.Get("Location")
+			ok := r.status == http.StatusFound && location != "" && !strings.Contains(location, "loginError")
+			return verdict(r, ok), "login redirected to " + location, nil
+		},
+	},
+
+	CheckRabbitMQ: {
+		name:    CheckRabbitMQ,
+		service: "RabbitMQ Management",
+		creds: []Credential{
+			{"guest", "guest"}, {"admin", "admin"},
+		},
+		detect: func(ctx context.Context, c *Checker, base string) (string, bool, error) {
+			loginURL := base + "/api/whoami"
+			r, err := c.do(ctx, http.MethodGet, loginURL, "", "", nil)
+			if err != nil {
+				return "", false, err
+			}
+			realm := r.header.Get("WWW-Authenticate")
+			return loginURL, r.status == http.StatusUnauthorized && strings.Contains(realm, "RabbitMQ"), nil
+		},
+		login: func(ctx context.Context, c *Checker, loginURL string, cred Credential) (outcome, string, error) {
+			r, err := c.do(ctx, http.MethodGet, loginURL, "", "", &cred)
+			if err != nil {
+				return failed, "", err
+			}
+			var whoami struct {
+				Name string `json:"name"`
+				Tags any    `json:"tags"`
+			}
+			ok := r.status == http.StatusOK && json.Unmarshal([]byte(r.body), &whoami) == nil && whoami.Name != ""
+			return verdict(r, ok), fmt.Sprintf("logged in as %s with tags %v", whoami.Name, whoami.Tags), nil
+		},
+	},
+}
+
+// snmpCommunities are tried in order against SNMP agents
+var snmpCommunities = []string{"public", "private"}
+
+// checkSNMP tries the default community strings against one agent. A
+// wrong community gets no answer, so failures are silent.
+func (c *Checker) checkSNMP(ctx context.Context, host string, pace func() error, results chan<- Result) error {
+	for i, community := range snmpCommunities {
+		if i >= c.config.MaxAttempts {
+			break
+		}
+		if err := pace(); err != nil {
+			return err
+		}
+		client := snmp.Client{
+			Community: community,
+			Version:   snmp.Version2c,
+			Timeout:   time.Duration(c.config.Timeout) * time.Second,
+			Retries:   1,
+		}
+		vars, err := client.Get(ctx, host, snmp.OIDSysDescr)
+		if err != nil || len(vars) == 0 {
+			continue
+		}
+		results <- Result{
+			Target:    host,
+			Check:     CheckSNMP,
+			Service:   "SNMP",
+			URL:       "snmp://" + host,
+			Password:  community,
+			Evidence:  "sysDescr: " + vars[0].Value,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		return nil
+	}
+	return nil
+}
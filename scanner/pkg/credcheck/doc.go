@@ -0,0 +1,20 @@
+// Package credcheck tries a short, curated list of vendor default
+// credentials against admin interfaces and services found by earlier
+// scans: Tomcat Manager, Grafana, Jenkins, RabbitMQ management and SNMP
+// community strings.
+//
+//	checker := credcheck.NewChecker(credcheck.Config{
+//		URLs:      []string{"https://ci.example.com", "http://192.0.2.10:8080"},
+//		SNMPHosts: []string{"192.0.2.10"},
+//	})
+//	results, err := checker.Run(ctx)
+//	for _, r := range results {
+//		fmt.Println(r.Check, r.URL, r.Username, r.Password)
+//	}
+//
+// Logging in to systems you are not authorized to test is illegal in most
+// places; only run it within an agreed scope. To stay clear of account
+// lockouts, attempts on each host are made one at a time with a delay
+// between them, capped at MaxAttempts per interface, and a host is
+// abandoned as soon as a response shows throttling or lockout.
+package credcheck
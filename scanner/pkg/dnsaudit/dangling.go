@@ -0,0 +1,342 @@
+package dnsaudit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Registration states of the domain a record points under
+const (
+	RegistrationOK           = "registered"
+	RegistrationUnregistered = "unregistered"
+	RegistrationExpired      = "expired"
+	RegistrationExpiring     = "expiring"
+	RegistrationUnknown      = "unknown"
+)
+
+// DanglingConfig holds dangling-record check configuration
+type DanglingConfig struct {
+	// Domains are the names whose CNAME, MX and NS records are checked
+	Domains []string
+
+	// Resolvers are the recursive DNS servers queried, as IP:port;
+	// default Google's and Cloudflare's
+	Resolvers []string
+
+	Workers int
+	Timeout time.Duration
+
+	// ExpiryWindow is how close to its expiry date a domain is reported
+	// as expiring (default 30 days)
+	ExpiryWindow time.Duration
+
+	// BootstrapURL is the RDAP bootstrap registry mapping TLDs to their
+	// RDAP servers (default DefaultRDAPBootstrapURL)
+	BootstrapURL string
+
+	// Proxy routes RDAP queries through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope names
+	Scope *scope.Scope
+
+	// Budget, if set, limits queries to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts names checked and those with issues
+	Progress *utils.Progress
+
+	// OnResult is called for each name with records pointing at other
+	// domains
+	OnResult func(DanglingResult)
+}
+
+// DanglingResult is one name's records that point under another
+// registered domain, and whether those domains are still held
+type DanglingResult struct {
+	Name      string           `json:"name"`
+	Records   []DanglingRecord `json:"records"`
+	Issues    []Issue          `json:"issues,omitempty"`
+	Timestamp string           `json:"timestamp"`
+}
+
+// DanglingRecord is one CNAME, MX or NS record pointing at another
+// registered domain
+type DanglingRecord struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+
+	// Domain is the registered domain of Target
+	Domain       string `json:"domain"`
+	Registration string `json:"registration"`
+	Expires      string `json:"expires,omitempty"`
+}
+
+// DanglingChecker finds records pointing under domains that have lapsed
+// or are about to, which whoever registers the domain then controls.
+// Unlike the analyzer's takeover fingerprints, which spot unclaimed
+// resources at a hosting service, this needs no more than a registrar.
+type DanglingChecker struct {
+	config  DanglingConfig
+	lookups *lookups
+	rdap    *rdapClient
+
+	errors   []error
+	errorsMu sync.Mutex
+}
+
+// NewDanglingChecker creates a new dangling-record checker
+func NewDanglingChecker(config DanglingConfig) *DanglingChecker {
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = []string{"8.8.8.8:53", "1.1.1.1:53", "8.8.4.4:53"}
+	}
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.ExpiryWindow == 0 {
+		config.ExpiryWindow = 30 * 24 * time.Hour
+	}
+	if config.BootstrapURL == "" {
+		config.BootstrapURL = DefaultRDAPBootstrapURL
+	}
+	client := &http.Client{
+		Timeout: 3 * config.Timeout,
+		Transport: metrics.Transport(&http.Transport{
+			Proxy: utils.ProxyFunc(config.Proxy),
+		}, "rdap"),
+	}
+	return &DanglingChecker{
+		config:  config,
+		lookups: newLookups(config.Resolvers, config.Timeout, config.Budget),
+		rdap:    newRDAPClient(client, config.BootstrapURL, config.Budget),
+	}
+}
+
+// Run checks every name and returns those with records pointing at other
+// domains. If ctx is cancelled, the names checked so far are returned.
+func (c *DanglingChecker) Run(ctx context.Context) []DanglingResult {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range c.config.Scope.Filter(c.config.Domains) {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	c.config.Progress.AddTotal(len(names))
+
+	ctx = log.WithModule(ctx, "dangling")
+	jobs := make(chan string)
+	results := make(chan DanglingResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				start := time.Now()
+				result, err := c.check(ctx, name)
+				switch {
+				case err != nil && ctx.Err() == nil:
+					log.FromContext(ctx).Warn("checking records", log.Target(name), log.Err(err), log.Since(start))
+					c.recordError(fmt.Errorf("%s: %w", name, err))
+				case len(result.Records) > 0:
+					results <- result
+				default:
+					log.FromContext(ctx).Debug("no external records", log.Target(name), log.Since(start))
+				}
+				c.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, name := range names {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- name:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []DanglingResult
+	for result := range results {
+		if len(result.Issues) > 0 {
+			c.config.Progress.Found()
+			metrics.Findings("dangling").Inc()
+		}
+		if c.config.OnResult != nil {
+			c.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	return found
+}
+
+// Errors returns the names whose records could not be read, and domains
+// whose registration could not be looked up
+func (c *DanglingChecker) Errors() []error {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]error(nil), c.errors...)
+}
+
+// recordError keeps a lookup failure for Errors
+func (c *DanglingChecker) recordError(err error) {
+	c.errorsMu.Lock()
+	c.errors = append(c.errors, err)
+	c.errorsMu.Unlock()
+}
+
+// check reads the CNAME, MX and NS records of name and looks up the
+// registration of each target under another registered domain
+func (c *DanglingChecker) check(ctx context.Context, name string) (DanglingResult, error) {
+	result := DanglingResult{
+		Name:      name,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	targets, err := c.targets(ctx, name)
+	if err != nil {
+		return result, err
+	}
+
+	own := scope.RegisteredDomain(name)
+	for _, t := range targets {
+		domain := scope.RegisteredDomain(t.Target)
+		if domain == "" || domain == own {
+			continue
+		}
+		t.Domain = domain
+		c.registration(ctx, &t)
+		result.Records = append(result.Records, t)
+
+		switch t.Registration {
+		case RegistrationUnregistered:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityCritical,
+				Check:    "dangling-registrable",
+				Detail:   fmt.Sprintf("%s record points to %s, under %s, which is not registered; whoever registers it controls where %s leads", t.Type, t.Target, domain, name),
+			})
+		case RegistrationExpired:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityHigh,
+				Check:    "dangling-expired",
+				Detail:   fmt.Sprintf("%s record points to %s, under %s, which has expired%s and may soon be open to registration", t.Type, t.Target, domain, expiredOn(t.Expires)),
+			})
+		case RegistrationExpiring:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityMedium,
+				Check:    "dangling-expiring",
+				Detail:   fmt.Sprintf("%s record points to %s, under %s, which expires on %s", t.Type, t.Target, domain, t.Expires),
+			})
+		}
+	}
+	return result, nil
+}
+
+// targets returns name's CNAME target or, for a name without one, its
+// mail exchangers and nameservers
+func (c *DanglingChecker) targets(ctx context.Context, name string) ([]DanglingRecord, error) {
+	cname, err := c.lookups.cname(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if cname != "" {
+		return []DanglingRecord{{Type: "CNAME", Target: cname}}, nil
+	}
+
+	var records []DanglingRecord
+	hosts, err := c.lookups.mx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		// A null MX (".") says the domain takes no mail
+		if host != "" {
+			records = append(records, DanglingRecord{Type: "MX", Target: strings.ToLower(host)})
+		}
+	}
+	hosts, err = c.lookups.nameservers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		records = append(records, DanglingRecord{Type: "NS", Target: host})
+	}
+	return records, nil
+}
+
+// registration fills in whether the record's domain is still held. A
+// registry with no record of the domain is only believed when the domain
+// has no nameservers either; a TLD without RDAP is judged by the
+// nameservers alone.
+func (c *DanglingChecker) registration(ctx context.Context, record *DanglingRecord) {
+	record.Registration = RegistrationUnknown
+	reg, err := c.rdap.domain(ctx, record.Domain)
+	if err != nil && !errors.Is(err, errNoRDAP) {
+		if ctx.Err() == nil {
+			log.FromContext(ctx).Warn("RDAP lookup failed", log.Target(record.Domain), log.Err(err))
+			c.recordError(fmt.Errorf("%s: %w", record.Domain, err))
+		}
+		return
+	}
+
+	if reg.found {
+		switch now := time.Now(); {
+		case reg.dropping, !reg.expires.IsZero() && reg.expires.Before(now):
+			record.Registration = RegistrationExpired
+		case !reg.expires.IsZero() && reg.expires.Before(now.Add(c.config.ExpiryWindow)):
+			record.Registration = RegistrationExpiring
+		default:
+			record.Registration = RegistrationOK
+		}
+		if !reg.expires.IsZero() {
+			record.Expires = reg.expires.UTC().Format("2006-01-02")
+		}
+		return
+	}
+
+	exists, err := c.lookups.exists(ctx, record.Domain)
+	switch {
+	case err != nil:
+		if ctx.Err() == nil {
+			c.recordError(fmt.Errorf("%s: %w", record.Domain, err))
+		}
+	case exists:
+		record.Registration = RegistrationOK
+	default:
+		record.Registration = RegistrationUnregistered
+	}
+}
+
+// expiredOn describes an expiry date for an issue's detail
+func expiredOn(expires string) string {
+	if expires == "" {
+		return ""
+	}
+	return " on " + expires
+}
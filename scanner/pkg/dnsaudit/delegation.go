@@ -0,0 +1,405 @@
+package dnsaudit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Nameserver states
+const (
+	NSOK           = "ok"
+	NSUnregistered = "unregistered"
+	NSUnresolvable = "unresolvable"
+	NSLame         = "lame"
+	NSUnreachable  = "unreachable"
+)
+
+// claimableProviders are DNS hosts that let any account create a zone for
+// a domain it does not own. A delegation to one of them that the provider
+// no longer serves can be claimed by creating the zone there. Markers are
+// matched against ".<nameserver host>.".
+var claimableProviders = []struct{ marker, name string }{
+	{".awsdns-", "Amazon Route 53"},
+	{".azure-dns.", "Azure DNS"},
+	{".digitalocean.com.", "DigitalOcean"},
+	{".dnsimple.com.", "DNSimple"},
+	{".dnsmadeeasy.com.", "DNS Made Easy"},
+	{".googledomains.com.", "Google Cloud DNS"},
+	{".linode.com.", "Linode"},
+	{".nsone.net.", "NS1"},
+	{".vultr.com.", "Vultr"},
+	{".he.net.", "Hurricane Electric"},
+	{".domaincontrol.com.", "GoDaddy"},
+	{".bizland.com.", "Bizland"},
+	{".dreamhost.com.", "DreamHost"},
+	{".name.com.", "Name.com"},
+	{".yahoo.com.", "Yahoo Small Business"},
+	{".000domains.com.", "000Domains"},
+	{".reg.ru.", "Reg.ru"},
+	{".tierra.net.", "TierraNet"},
+	{".mydomain.com.", "MyDomain"},
+	{".cloudns.net.", "ClouDNS"},
+	{".registrar-servers.com.", "Namecheap"},
+}
+
+// DelegationConfig holds delegation check configuration
+type DelegationConfig struct {
+	// Domains are the names checked for a delegation: the target and any
+	// subdomains that may be separate zones
+	Domains []string
+
+	// Resolvers are the recursive DNS servers used to find parent zones
+	// and nameserver addresses, as IP:port; default Google's and
+	// Cloudflare's. Delegations themselves are read from the parent
+	// zone's nameservers.
+	Resolvers []string
+
+	Workers int
+	Timeout time.Duration
+
+	// Scope, if set, skips out-of-scope domains
+	Scope *scope.Scope
+
+	// Budget, if set, limits queries to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts names checked and zones with issues
+	Progress *utils.Progress
+
+	// OnResult is called for each delegated zone found
+	OnResult func(DelegationResult)
+}
+
+// DelegationResult is one delegated zone and the state of its nameservers
+type DelegationResult struct {
+	Zone        string       `json:"zone"`
+	Parent      string       `json:"parent"`
+	Nameservers []Nameserver `json:"nameservers"`
+	Issues      []Issue      `json:"issues,omitempty"`
+	Timestamp   string       `json:"timestamp"`
+}
+
+// Nameserver is one NS host a zone is delegated to
+type Nameserver struct {
+	Host     string   `json:"host"`
+	IPs      []string `json:"ips,omitempty"`
+	Status   string   `json:"status"`
+	Provider string   `json:"provider,omitempty"`
+}
+
+// DelegationChecker finds zones delegated to nameservers that are gone,
+// unregistered or no longer serve them
+type DelegationChecker struct {
+	config  DelegationConfig
+	lookups *lookups
+
+	errors   []error
+	errorsMu sync.Mutex
+}
+
+// NewDelegationChecker creates a new delegation checker
+func NewDelegationChecker(config DelegationConfig) *DelegationChecker {
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = []string{"8.8.8.8:53", "1.1.1.1:53", "8.8.4.4:53"}
+	}
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	return &DelegationChecker{
+		config:  config,
+		lookups: newLookups(config.Resolvers, config.Timeout, config.Budget),
+	}
+}
+
+// Run checks every name and returns the delegated zones among them. If
+// ctx is cancelled, the zones found so far are returned.
+func (c *DelegationChecker) Run(ctx context.Context) []DelegationResult {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range c.config.Scope.Filter(c.config.Domains) {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	c.config.Progress.AddTotal(len(names))
+
+	ctx = log.WithModule(ctx, "ns")
+	jobs := make(chan string)
+	results := make(chan DelegationResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				start := time.Now()
+				result, ok, err := c.check(ctx, name)
+				switch {
+				case err != nil && ctx.Err() == nil:
+					log.FromContext(ctx).Warn("checking delegation", log.Target(name), log.Err(err), log.Since(start))
+					c.recordError(fmt.Errorf("%s: %w", name, err))
+				case ok:
+					results <- result
+				default:
+					log.FromContext(ctx).Debug("not a delegated zone", log.Target(name), log.Since(start))
+				}
+				c.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, name := range names {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- name:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var zones []DelegationResult
+	for result := range results {
+		if len(result.Issues) > 0 {
+			c.config.Progress.Found()
+			metrics.Findings("ns").Inc()
+		}
+		if c.config.OnResult != nil {
+			c.config.OnResult(result)
+		}
+		zones = append(zones, result)
+	}
+	return zones
+}
+
+// Errors returns the names whose delegation could not be read
+func (c *DelegationChecker) Errors() []error {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]error(nil), c.errors...)
+}
+
+// recordError keeps a lookup failure for Errors
+func (c *DelegationChecker) recordError(err error) {
+	c.errorsMu.Lock()
+	c.errors = append(c.errors, err)
+	c.errorsMu.Unlock()
+}
+
+// check reads the delegation of name from its parent zone and tests each
+// nameserver. ok is false when name is not a zone of its own.
+func (c *DelegationChecker) check(ctx context.Context, name string) (DelegationResult, bool, error) {
+	result := DelegationResult{
+		Zone:      name,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	parent, parentServers, err := c.parentZone(ctx, name)
+	if err != nil || parent == "" {
+		return result, false, err
+	}
+	result.Parent = parent
+
+	hosts, err := c.delegation(ctx, name, parentServers)
+	if err != nil || len(hosts) == 0 {
+		return result, false, err
+	}
+
+	for _, host := range hosts {
+		ns := c.nameserver(ctx, name, host)
+		result.Nameservers = append(result.Nameservers, ns)
+
+		switch ns.Status {
+		case NSUnregistered:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityCritical,
+				Check:    "ns-unregistered",
+				Detail:   fmt.Sprintf("nameserver %s is under %s, which is not registered; whoever registers it controls %s", host, scope.RegisteredDomain(host), name),
+			})
+		case NSUnresolvable:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityMedium,
+				Check:    "ns-unresolvable",
+				Detail:   fmt.Sprintf("nameserver %s has no address", host),
+			})
+		case NSLame:
+			if ns.Provider != "" {
+				result.Issues = append(result.Issues, Issue{
+					Severity: SeverityHigh,
+					Check:    "ns-takeover",
+					Detail:   fmt.Sprintf("nameserver %s (%s) does not serve %s; the zone may be claimable by creating it at %s", host, ns.Provider, name, ns.Provider),
+				})
+			} else {
+				result.Issues = append(result.Issues, Issue{
+					Severity: SeverityMedium,
+					Check:    "ns-lame",
+					Detail:   fmt.Sprintf("nameserver %s does not serve %s (lame delegation)", host, name),
+				})
+			}
+		case NSUnreachable:
+			result.Issues = append(result.Issues, Issue{
+				Severity: SeverityLow,
+				Check:    "ns-unreachable",
+				Detail:   fmt.Sprintf("nameserver %s did not answer", host),
+			})
+		}
+	}
+	return result, true, nil
+}
+
+// parentZone finds the closest enclosing zone of name that has
+// nameservers, and their addresses
+func (c *DelegationChecker) parentZone(ctx context.Context, name string) (string, []string, error) {
+	labels := strings.Split(name, ".")
+	for i := 1; i < len(labels); i++ {
+		zone := strings.Join(labels[i:], ".")
+		hosts, err := c.lookups.nameservers(ctx, zone)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+
+		var servers []string
+		for _, host := range hosts {
+			addrs, err := c.lookups.addrs(ctx, host)
+			if err == nil {
+				servers = append(servers, addrs...)
+			}
+			if len(servers) >= 3 {
+				break
+			}
+		}
+		if len(servers) == 0 {
+			return "", nil, fmt.Errorf("no address for any nameserver of parent zone %s", zone)
+		}
+		return zone, servers, nil
+	}
+	return "", nil, nil
+}
+
+// delegation asks the parent zone's servers for name's NS records. A
+// zone cut shows as a referral (NS records for name in the authority
+// section) or, when the same servers host both zones, an authoritative
+// NS answer.
+func (c *DelegationChecker) delegation(ctx context.Context, name string, servers []string) ([]string, error) {
+	var lastErr error
+	for _, server := range servers {
+		reply, err := c.lookups.exchange(ctx, server, name, dnsmessage.TypeNS)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if reply.RCode != dnsmessage.RCodeSuccess {
+			return nil, nil
+		}
+
+		var hosts []string
+		for _, section := range [][]dnsmessage.Resource{reply.Answers, reply.Authorities} {
+			for _, rr := range section {
+				ns, ok := rr.Body.(*dnsmessage.NSResource)
+				if !ok || !sameName(rr.Header.Name.String(), name) {
+					continue
+				}
+				hosts = append(hosts, strings.ToLower(strings.TrimSuffix(ns.NS.String(), ".")))
+			}
+			if len(hosts) > 0 {
+				break
+			}
+		}
+		sort.Strings(hosts)
+		return hosts, nil
+	}
+	return nil, lastErr
+}
+
+// nameserver checks that one NS host exists and answers authoritatively
+// for zone
+func (c *DelegationChecker) nameserver(ctx context.Context, zone, host string) Nameserver {
+	ns := Nameserver{Host: host, Provider: claimableProvider(host)}
+
+	addrs, err := c.lookups.addrs(ctx, host)
+	if err != nil {
+		ns.Status = NSUnreachable
+		return ns
+	}
+	if len(addrs) == 0 {
+		exists, err := c.lookups.exists(ctx, host)
+		switch {
+		case err != nil:
+			ns.Status = NSUnreachable
+		case !exists:
+			ns.Status = NSUnregistered
+		default:
+			ns.Status = NSUnresolvable
+		}
+		return ns
+	}
+	ns.IPs = addrs
+
+	// One server answering for the zone is enough; a lame answer from
+	// every address is needed to call it lame
+	ns.Status = NSUnreachable
+	for _, addr := range addrs {
+		reply, err := c.lookups.exchange(ctx, addr, zone, dnsmessage.TypeSOA)
+		if err != nil {
+			continue
+		}
+		if reply.RCode == dnsmessage.RCodeSuccess && reply.Authoritative && hasSOA(reply, zone) {
+			ns.Status = NSOK
+			return ns
+		}
+		ns.Status = NSLame
+	}
+	return ns
+}
+
+// hasSOA reports whether a reply carries the SOA record of zone
+func hasSOA(reply *dnsmessage.Message, zone string) bool {
+	for _, rr := range reply.Answers {
+		if rr.Header.Type == dnsmessage.TypeSOA && sameName(rr.Header.Name.String(), zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimableProvider returns the provider an NS host belongs to, if it is
+// one where zones can be claimed
+func claimableProvider(host string) string {
+	host = "." + host + "."
+	for _, p := range claimableProviders {
+		if strings.Contains(host, p.marker) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// sameName compares DNS names ignoring case and the trailing dot
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,27 @@
+// Package dnsaudit checks DNS configuration for security weaknesses.
+//
+// MailChecker reviews a domain's email authentication: its SPF record
+// (qualifiers, lookup count, includes of domains that no longer exist),
+// its DMARC policy and the DKIM keys published under common selectors.
+//
+// DelegationChecker reads each zone's delegation from its parent and
+// tests the nameservers it names, flagging those under unregistered
+// domains and those that no longer serve the zone, which at some DNS
+// hosts lets anyone claim it.
+//
+// DanglingChecker follows each name's CNAME, MX and NS records to other
+// registered domains and asks their registry over RDAP whether they are
+// still held. A record under a domain nobody holds is a takeover anyone
+// can carry out at a registrar, reported apart from the service
+// takeovers the HTTP analyzer fingerprints; domains past or near their
+// expiry date are reported too.
+//
+//	checker := dnsaudit.NewMailChecker(dnsaudit.MailConfig{
+//		Domains: []string{"example.com"},
+//	})
+//	for _, r := range checker.Run(ctx) {
+//		for _, issue := range r.Issues {
+//			fmt.Println(r.Domain, issue.Severity, issue.Detail)
+//		}
+//	}
+package dnsaudit
@@ -0,0 +1,495 @@
+package dnsaudit
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// spfLookupLimit is the most DNS-querying SPF terms a check may take
+// (RFC 7208 section 4.6.4) before receivers return a permanent error
+const spfLookupLimit = 10
+
+// DefaultSelectors are the DKIM selectors tried when MailConfig.Selectors
+// is not set: generic names and those of common mail providers
+var DefaultSelectors = []string{
+	"default", "dkim", "mail", "email", "smtp", "k1", "k2", "k3", "s1", "s2",
+	"selector1", "selector2", "google", "mandrill", "mailjet", "mxvault",
+	"zoho", "zmail", "protonmail", "protonmail2", "protonmail3",
+	"fm1", "fm2", "fm3", "smtpapi", "everlytic", "sig1", "dkim1", "key1",
+}
+
+// MailConfig holds email security check configuration
+type MailConfig struct {
+	Domains []string
+
+	// Resolvers are the DNS servers queried, as IP:port; default Google's
+	// and Cloudflare's
+	Resolvers []string
+
+	// Selectors are the DKIM selectors tried; empty means DefaultSelectors
+	Selectors []string
+
+	Workers int
+	Timeout time.Duration
+
+	// Scope, if set, skips out-of-scope domains
+	Scope *scope.Scope
+
+	// Budget, if set, limits lookups to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts domains checked and those with issues
+	Progress *utils.Progress
+
+	// OnResult is called for each domain checked
+	OnResult func(MailResult)
+}
+
+// MailResult is the email authentication setup of one domain
+type MailResult struct {
+	Domain    string       `json:"domain"`
+	MX        []string     `json:"mx,omitempty"`
+	SPF       *SPFRecord   `json:"spf,omitempty"`
+	DMARC     *DMARCRecord `json:"dmarc,omitempty"`
+	DKIM      []DKIMKey    `json:"dkim,omitempty"`
+	Issues    []Issue      `json:"issues,omitempty"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// SPFRecord is a domain's SPF policy
+type SPFRecord struct {
+	Record string `json:"record"`
+
+	// All is the qualifier of the all mechanism (+, -, ~ or ?), or empty
+	// when the record has none
+	All string `json:"all,omitempty"`
+
+	// Lookups counts the DNS-querying terms, includes followed
+	Lookups  int      `json:"lookups"`
+	Includes []string `json:"includes,omitempty"`
+}
+
+// DMARCRecord is a domain's DMARC policy
+type DMARCRecord struct {
+	Record          string   `json:"record"`
+	Policy          string   `json:"policy"`
+	SubdomainPolicy string   `json:"subdomain_policy,omitempty"`
+	Percent         int      `json:"pct"`
+	ReportURIs      []string `json:"rua,omitempty"`
+
+	// Inherited is set when the record is the registered domain's, which
+	// receivers apply to subdomains without their own
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// DKIMKey is a public key published under a selector
+type DKIMKey struct {
+	Selector string `json:"selector"`
+	KeyType  string `json:"key_type"`
+	Bits     int    `json:"bits,omitempty"`
+
+	// Revoked is set when the record has an empty key
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// MailChecker checks the SPF, DMARC and DKIM records of domains
+type MailChecker struct {
+	config  MailConfig
+	lookups *lookups
+
+	errors   []error
+	errorsMu sync.Mutex
+}
+
+// NewMailChecker creates a new email security checker
+func NewMailChecker(config MailConfig) *MailChecker {
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = []string{"8.8.8.8:53", "1.1.1.1:53", "8.8.4.4:53"}
+	}
+	if len(config.Selectors) == 0 {
+		config.Selectors = DefaultSelectors
+	}
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	return &MailChecker{
+		config:  config,
+		lookups: newLookups(config.Resolvers, config.Timeout, config.Budget),
+	}
+}
+
+// Run checks every domain. If ctx is cancelled, the domains checked so
+// far are returned.
+func (c *MailChecker) Run(ctx context.Context) []MailResult {
+	var domains []string
+	for _, domain := range c.config.Scope.Filter(c.config.Domains) {
+		domains = append(domains, strings.TrimSuffix(strings.ToLower(domain), "."))
+	}
+	c.config.Progress.AddTotal(len(domains))
+
+	ctx = log.WithModule(ctx, "mail")
+	jobs := make(chan string)
+	results := make(chan MailResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				start := time.Now()
+				result, err := c.check(ctx, domain)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.FromContext(ctx).Warn("checking domain", log.Target(domain), log.Err(err), log.Since(start))
+						c.recordError(fmt.Errorf("%s: %w", domain, err))
+					}
+				} else {
+					log.FromContext(ctx).Debug("domain checked", log.Target(domain), log.Since(start))
+					results <- result
+				}
+				c.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, domain := range domains {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- domain:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var checked []MailResult
+	for result := range results {
+		if len(result.Issues) > 0 {
+			c.config.Progress.Found()
+			metrics.Findings("mail").Inc()
+		}
+		if c.config.OnResult != nil {
+			c.config.OnResult(result)
+		}
+		checked = append(checked, result)
+	}
+	return checked
+}
+
+// Errors returns the domains whose records could not be read, for
+// example because the resolvers timed out
+func (c *MailChecker) Errors() []error {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]error(nil), c.errors...)
+}
+
+// recordError keeps a lookup failure for Errors
+func (c *MailChecker) recordError(err error) {
+	c.errorsMu.Lock()
+	c.errors = append(c.errors, err)
+	c.errorsMu.Unlock()
+}
+
+// check reads and reviews one domain's records
+func (c *MailChecker) check(ctx context.Context, domain string) (MailResult, error) {
+	result := MailResult{
+		Domain:    domain,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	issue := func(severity, check, format string, args ...interface{}) {
+		result.Issues = append(result.Issues, Issue{Severity: severity, Check: check, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	mx, err := c.lookups.mx(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.MX = mx
+
+	if err := c.checkSPF(ctx, &result, issue); err != nil {
+		return result, err
+	}
+	if err := c.checkDMARC(ctx, &result, issue); err != nil {
+		return result, err
+	}
+	c.checkDKIM(ctx, &result, issue)
+	return result, nil
+}
+
+// checkSPF reviews the domain's SPF record and the includes it pulls in
+func (c *MailChecker) checkSPF(ctx context.Context, result *MailResult, issue func(string, string, string, ...interface{})) error {
+	records, err := c.lookups.txt(ctx, result.Domain)
+	if err != nil {
+		return err
+	}
+	spf := recordsWithPrefix(records, "v=spf1")
+	switch {
+	case len(spf) == 0 && len(result.MX) == 0:
+		issue(SeverityLow, "spf", "no SPF record; a domain that sends no mail should publish \"v=spf1 -all\" so it cannot be spoofed")
+		return nil
+	case len(spf) == 0:
+		issue(SeverityMedium, "spf", "no SPF record; any server can send mail as this domain")
+		return nil
+	case len(spf) > 1:
+		issue(SeverityHigh, "spf", "%d SPF records; receivers treat this as a permanent error and ignore them", len(spf))
+	}
+
+	record := &SPFRecord{Record: spf[0]}
+	result.SPF = record
+	redirect := ""
+	for _, term := range strings.Fields(spf[0])[1:] {
+		qualifier, mechanism, value := parseSPFTerm(term)
+		switch mechanism {
+		case "all":
+			record.All = qualifier
+		case "ptr":
+			issue(SeverityLow, "spf", "uses the deprecated ptr mechanism, which is slow and unreliable")
+		case "redirect":
+			redirect = value
+		case "include":
+			record.Includes = append(record.Includes, value)
+		}
+	}
+
+	visited := map[string]bool{result.Domain: true}
+	record.Lookups = c.spfLookups(ctx, spf[0], visited, 0, issue)
+	if record.Lookups > spfLookupLimit {
+		issue(SeverityMedium, "spf", "%d DNS lookups exceed the limit of %d; receivers may reject the record as a permanent error", record.Lookups, spfLookupLimit)
+	}
+
+	switch {
+	case record.All == "+":
+		issue(SeverityCritical, "spf", "\"+all\" authorizes every server on the internet to send as this domain")
+	case record.All == "?":
+		issue(SeverityMedium, "spf", "\"?all\" is neutral; unlisted senders are neither passed nor failed")
+	case record.All == "~":
+		issue(SeverityLow, "spf", "\"~all\" only soft-fails unlisted senders; rely on DMARC enforcement or use \"-all\"")
+	case record.All == "" && redirect == "":
+		issue(SeverityMedium, "spf", "no \"all\" mechanism; unlisted senders default to neutral")
+	}
+	return nil
+}
+
+// spfLookups counts the DNS-querying terms of a record, following
+// includes and redirects, and reports includes of domains without an SPF
+// record. Includes of unregistered domains are flagged as dangling: whoever
+// registers the domain can publish an SPF record authorizing their own
+// servers.
+func (c *MailChecker) spfLookups(ctx context.Context, record string, visited map[string]bool, depth int, issue func(string, string, string, ...interface{})) int {
+	count := 0
+	for _, term := range strings.Fields(record)[1:] {
+		_, mechanism, value := parseSPFTerm(term)
+		switch mechanism {
+		case "a", "mx", "ptr", "exists":
+			count++
+		case "include", "redirect":
+			count++
+			if depth >= spfLookupLimit || value == "" || strings.Contains(value, "%") || visited[value] {
+				continue
+			}
+			visited[value] = true
+
+			records, err := c.lookups.txt(ctx, value)
+			if err != nil {
+				continue
+			}
+			spf := recordsWithPrefix(records, "v=spf1")
+			if len(spf) == 0 {
+				exists, err := c.lookups.exists(ctx, value)
+				switch {
+				case err != nil:
+				case !exists:
+					issue(SeverityHigh, "spf-dangling", "%s %s is not registered; whoever registers %s can send mail as this domain", mechanism, value, scope.RegisteredDomain(value))
+				default:
+					issue(SeverityMedium, "spf", "%s %s has no SPF record; receivers treat this as a permanent error", mechanism, value)
+				}
+				continue
+			}
+			count += c.spfLookups(ctx, spf[0], visited, depth+1, issue)
+		}
+	}
+	return count
+}
+
+// parseSPFTerm splits an SPF term into its qualifier (default +),
+// mechanism or modifier name, and value
+func parseSPFTerm(term string) (qualifier, name, value string) {
+	qualifier = "+"
+	if strings.ContainsAny(term[:1], "+-~?") {
+		qualifier, term = term[:1], term[1:]
+	}
+	if name, value, ok := strings.Cut(term, "="); ok {
+		return qualifier, strings.ToLower(name), value
+	}
+	name, value, _ = strings.Cut(term, ":")
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	return qualifier, strings.ToLower(name), value
+}
+
+// checkDMARC reviews the domain's DMARC policy, falling back to the
+// registered domain's as receivers do
+func (c *MailChecker) checkDMARC(ctx context.Context, result *MailResult, issue func(string, string, string, ...interface{})) error {
+	records, err := c.lookups.txt(ctx, "_dmarc."+result.Domain)
+	if err != nil {
+		return err
+	}
+	dmarc := recordsWithPrefix(records, "v=dmarc1")
+	inherited := false
+	if registered := scope.RegisteredDomain(result.Domain); len(dmarc) == 0 && registered != "" && registered != result.Domain {
+		records, err := c.lookups.txt(ctx, "_dmarc."+registered)
+		if err != nil {
+			return err
+		}
+		dmarc, inherited = recordsWithPrefix(records, "v=dmarc1"), true
+	}
+	switch {
+	case len(dmarc) == 0:
+		issue(SeverityMedium, "dmarc", "no DMARC record; receivers apply no policy to mail failing SPF and DKIM")
+		return nil
+	case len(dmarc) > 1:
+		issue(SeverityHigh, "dmarc", "%d DMARC records; receivers ignore them all", len(dmarc))
+	}
+
+	record := &DMARCRecord{Record: dmarc[0], Percent: 100, Inherited: inherited}
+	result.DMARC = record
+	for _, tag := range strings.Split(dmarc[0], ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "p":
+			record.Policy = strings.ToLower(value)
+		case "sp":
+			record.SubdomainPolicy = strings.ToLower(value)
+		case "pct":
+			if pct, err := strconv.Atoi(value); err == nil {
+				record.Percent = pct
+			}
+		case "rua":
+			for _, uri := range strings.Split(value, ",") {
+				if uri = strings.TrimSpace(uri); uri != "" {
+					record.ReportURIs = append(record.ReportURIs, uri)
+				}
+			}
+		}
+	}
+
+	// An inherited record applies its subdomain policy
+	policy := record.Policy
+	if inherited && record.SubdomainPolicy != "" {
+		policy = record.SubdomainPolicy
+	}
+	switch policy {
+	case "reject", "quarantine":
+	case "none":
+		issue(SeverityMedium, "dmarc", "policy is \"none\"; failing mail is only reported, never quarantined or rejected")
+	default:
+		issue(SeverityHigh, "dmarc", "invalid policy %q; receivers ignore the record", record.Policy)
+	}
+	if !inherited && record.SubdomainPolicy == "none" && record.Policy != "none" {
+		issue(SeverityLow, "dmarc", "subdomain policy is \"none\"; subdomains can be spoofed")
+	}
+	if record.Percent < 100 {
+		issue(SeverityLow, "dmarc", "pct=%d applies the policy to only part of failing mail", record.Percent)
+	}
+	if len(record.ReportURIs) == 0 {
+		issue(SeverityInfo, "dmarc", "no rua address; spoofing attempts go unreported")
+	}
+	return nil
+}
+
+// checkDKIM looks for keys under the configured selectors. Selectors
+// cannot be listed, so finding none is only informational.
+func (c *MailChecker) checkDKIM(ctx context.Context, result *MailResult, issue func(string, string, string, ...interface{})) {
+	for _, selector := range c.config.Selectors {
+		if ctx.Err() != nil {
+			return
+		}
+		records, err := c.lookups.txt(ctx, selector+"._domainkey."+result.Domain)
+		if err != nil {
+			continue
+		}
+		for _, record := range records {
+			key, ok := parseDKIMKey(selector, record)
+			if !ok {
+				continue
+			}
+			result.DKIM = append(result.DKIM, key)
+			switch {
+			case key.Revoked:
+			case key.Bits > 0 && key.Bits < 1024:
+				issue(SeverityHigh, "dkim", "selector %s has a %d-bit RSA key, short enough to factor", selector, key.Bits)
+			case key.Bits > 0 && key.Bits < 2048:
+				issue(SeverityLow, "dkim", "selector %s has a %d-bit RSA key; 2048 bits is recommended", selector, key.Bits)
+			}
+			break
+		}
+	}
+	if len(result.DKIM) == 0 && len(result.MX) > 0 {
+		issue(SeverityInfo, "dkim", "no DKIM key under %d common selectors", len(c.config.Selectors))
+	}
+}
+
+// parseDKIMKey reads a DKIM key record (RFC 6376 section 3.6.1)
+func parseDKIMKey(selector, record string) (DKIMKey, bool) {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if ok {
+			tags[strings.ToLower(strings.TrimSpace(name))] = strings.Join(strings.Fields(value), "")
+		}
+	}
+	p, ok := tags["p"]
+	if !ok || (tags["v"] != "" && tags["v"] != "DKIM1") {
+		return DKIMKey{}, false
+	}
+
+	key := DKIMKey{Selector: selector, KeyType: "rsa"}
+	if k := tags["k"]; k != "" {
+		key.KeyType = strings.ToLower(k)
+	}
+	if p == "" {
+		key.Revoked = true
+		return key, true
+	}
+	if key.KeyType != "rsa" {
+		return key, true
+	}
+
+	der, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return key, true
+	}
+	if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+		if rsaKey, ok := pub.(*rsa.PublicKey); ok {
+			key.Bits = rsaKey.N.BitLen()
+		}
+	} else if rsaKey, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		key.Bits = rsaKey.N.BitLen()
+	}
+	return key, true
+}
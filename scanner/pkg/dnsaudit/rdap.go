@@ -0,0 +1,196 @@
+package dnsaudit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+)
+
+// DefaultRDAPBootstrapURL is IANA's registry of RDAP servers by TLD
+const DefaultRDAPBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// errNoRDAP means a TLD's registry publishes no RDAP service
+var errNoRDAP = errors.New("no RDAP service for TLD")
+
+// registration is what a registry's RDAP server says of a domain
+type registration struct {
+	// found is false when the registry has no record of the domain
+	found bool
+
+	expires time.Time
+
+	// dropping is set while the domain is in its redemption period or
+	// pending delete, on its way back to the open market
+	dropping bool
+}
+
+// rdapClient looks domains up at their registry's RDAP server, found
+// through the IANA bootstrap registry
+type rdapClient struct {
+	client    *http.Client
+	bootstrap string
+	budget    *utils.Budget
+
+	once    sync.Once
+	servers map[string]string
+	loadErr error
+
+	mu    sync.Mutex
+	cache map[string]*rdapEntry
+}
+
+// rdapEntry is one domain's lookup, shared by the records that name it
+type rdapEntry struct {
+	done chan struct{}
+	reg  registration
+	err  error
+}
+
+// newRDAPClient returns a client that reads the bootstrap registry at
+// bootstrap on first use
+func newRDAPClient(client *http.Client, bootstrap string, budget *utils.Budget) *rdapClient {
+	return &rdapClient{client: client, bootstrap: bootstrap, budget: budget, cache: make(map[string]*rdapEntry)}
+}
+
+// domain returns the registration of a registered domain, looking each
+// domain up once however many records point under it
+func (r *rdapClient) domain(ctx context.Context, domain string) (registration, error) {
+	r.mu.Lock()
+	entry, ok := r.cache[domain]
+	if !ok {
+		entry = &rdapEntry{done: make(chan struct{})}
+		r.cache[domain] = entry
+	}
+	r.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.reg, entry.err
+		case <-ctx.Done():
+			return registration{}, ctx.Err()
+		}
+	}
+	entry.reg, entry.err = r.lookup(ctx, domain)
+	close(entry.done)
+	return entry.reg, entry.err
+}
+
+// lookup asks the RDAP server of domain's TLD for its record
+func (r *rdapClient) lookup(ctx context.Context, domain string) (registration, error) {
+	r.once.Do(func() { r.servers, r.loadErr = r.loadBootstrap(ctx) })
+	if r.loadErr != nil {
+		return registration{}, fmt.Errorf("loading RDAP bootstrap: %w", r.loadErr)
+	}
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	base, ok := r.servers[tld]
+	if !ok {
+		return registration{}, errNoRDAP
+	}
+
+	body, status, err := r.get(ctx, base+"domain/"+domain)
+	if err != nil {
+		return registration{}, err
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return registration{}, nil
+	case status != http.StatusOK:
+		return registration{}, fmt.Errorf("RDAP lookup of %s: unexpected status %d", domain, status)
+	}
+
+	var record struct {
+		Status []string `json:"status"`
+		Events []struct {
+			Action string `json:"eventAction"`
+			Date   string `json:"eventDate"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return registration{}, fmt.Errorf("decoding RDAP record of %s: %w", domain, err)
+	}
+
+	reg := registration{found: true}
+	for _, event := range record.Events {
+		if event.Action == "expiration" {
+			reg.expires, _ = time.Parse(time.RFC3339, event.Date)
+		}
+	}
+	for _, s := range record.Status {
+		switch strings.ToLower(s) {
+		case "pending delete", "redemption period":
+			reg.dropping = true
+		}
+	}
+	return reg, nil
+}
+
+// loadBootstrap reads the bootstrap registry into a map of TLD to RDAP
+// base URL
+func (r *rdapClient) loadBootstrap(ctx context.Context) (map[string]string, error) {
+	body, status, err := r.get(ctx, r.bootstrap)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", status)
+	}
+
+	// Each service is a pair of lists: the TLDs, then their servers
+	var registry struct {
+		Services [][][]string `json:"services"`
+	}
+	if err := json.Unmarshal(body, &registry); err != nil {
+		return nil, fmt.Errorf("decoding: %w", err)
+	}
+	servers := make(map[string]string)
+	for _, service := range registry.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		// Prefer an https server when several are listed
+		base := service[1][0]
+		for _, u := range service[1] {
+			if strings.HasPrefix(u, "https://") {
+				base = u
+				break
+			}
+		}
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		for _, tld := range service[0] {
+			servers[strings.ToLower(tld)] = base
+		}
+	}
+	return servers, nil
+}
+
+// get fetches an RDAP URL within the budget, returning the body and
+// status
+func (r *rdapClient) get(ctx context.Context, endpoint string) ([]byte, int, error) {
+	if err := r.budget.Wait(ctx); err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	return body, resp.StatusCode, err
+}
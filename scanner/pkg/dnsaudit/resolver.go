@@ -0,0 +1,233 @@
+package dnsaudit
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Severities of an Issue, lowest first
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Issue is one weakness found in a domain's records
+type Issue struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Detail   string `json:"detail"`
+}
+
+// lookups makes the DNS queries of one audit, paced by a shared budget
+type lookups struct {
+	resolver  *net.Resolver
+	resolvers []string
+	next      atomic.Uint32
+	timeout   time.Duration
+	budget    *utils.Budget
+}
+
+// newLookups returns lookups sent to resolvers in turn
+func newLookups(resolvers []string, timeout time.Duration, budget *utils.Budget) *lookups {
+	l := &lookups{resolvers: resolvers, timeout: timeout, budget: budget}
+	l.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, "udp", l.nextResolver())
+		},
+	}
+	return l
+}
+
+// nextResolver returns the resolver whose turn it is
+func (l *lookups) nextResolver() string {
+	return l.resolvers[int(l.next.Add(1))%len(l.resolvers)]
+}
+
+// txt returns the TXT records of name. A name without any is not an
+// error.
+func (l *lookups) txt(ctx context.Context, name string) ([]string, error) {
+	l.budget.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	records, err := l.resolver.LookupTXT(ctx, name)
+	if notFound(err) {
+		return nil, nil
+	}
+	return records, err
+}
+
+// exists reports whether a domain is delegated, by asking for the NS
+// records of its registered domain
+func (l *lookups) exists(ctx context.Context, domain string) (bool, error) {
+	registered := scope.RegisteredDomain(domain)
+	if registered == "" {
+		return false, nil
+	}
+	l.budget.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	records, err := l.resolver.LookupNS(ctx, registered)
+	if notFound(err) {
+		return false, nil
+	}
+	return len(records) > 0, err
+}
+
+// notFound reports whether err is the resolver saying the name or record
+// does not exist, as opposed to a failed query
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// mx returns the mail exchangers of a domain, lowest preference first
+func (l *lookups) mx(ctx context.Context, domain string) ([]string, error) {
+	l.budget.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	records, err := l.resolver.LookupMX(ctx, domain)
+	if notFound(err) {
+		return nil, nil
+	}
+	var hosts []string
+	for _, mx := range records {
+		hosts = append(hosts, strings.TrimSuffix(mx.Host, "."))
+	}
+	return hosts, err
+}
+
+// addrs returns the addresses of a host. A host without any is not an
+// error.
+func (l *lookups) addrs(ctx context.Context, host string) ([]string, error) {
+	l.budget.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	ips, err := l.resolver.LookupIPAddr(ctx, host)
+	if notFound(err) {
+		return nil, nil
+	}
+	var addrs []string
+	for _, ip := range ips {
+		addrs = append(addrs, ip.IP.String())
+	}
+	return addrs, err
+}
+
+// nameservers returns the NS hosts of a zone. A name that is not a zone
+// has none.
+func (l *lookups) nameservers(ctx context.Context, zone string) ([]string, error) {
+	l.budget.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	records, err := l.resolver.LookupNS(ctx, zone)
+	if notFound(err) {
+		return nil, nil
+	}
+	var hosts []string
+	for _, ns := range records {
+		hosts = append(hosts, strings.ToLower(strings.TrimSuffix(ns.Host, ".")))
+	}
+	return hosts, err
+}
+
+// exchange sends one non-recursive query straight to a server, as a
+// resolver walking the delegation chain would
+func (l *lookups) exchange(ctx context.Context, server, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	return l.query(ctx, net.JoinHostPort(server, "53"), name, qtype, false)
+}
+
+// cname returns the target of name's CNAME record, read from the
+// resolvers' answer itself so a target that no longer resolves is still
+// seen. A name without one is not an error.
+func (l *lookups) cname(ctx context.Context, name string) (string, error) {
+	reply, err := l.query(ctx, l.nextResolver(), name, dnsmessage.TypeCNAME, true)
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range reply.Answers {
+		if c, ok := rr.Body.(*dnsmessage.CNAMEResource); ok && sameName(rr.Header.Name.String(), name) {
+			return strings.ToLower(strings.TrimSuffix(c.CNAME.String(), ".")), nil
+		}
+	}
+	return "", nil
+}
+
+// query sends one query to the server at address, asking it to recurse
+// if recursive is set
+func (l *lookups) query(ctx context.Context, address, name string, qtype dnsmessage.Type, recursive bool) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: recursive},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	l.budget.Wait(ctx)
+	start := time.Now()
+	reply, err := l.send(ctx, address, query.ID, packet)
+	metrics.Record(metrics.ModuleOf(ctx, "dns"), start, err)
+	return reply, err
+}
+
+// send sends a packed query to the server at address and waits for the
+// reply to it
+func (l *lookups) send(ctx context.Context, address string, id uint16, packet []byte) (*dnsmessage.Message, error) {
+	d := net.Dialer{Timeout: l.timeout}
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(l.timeout))
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var reply dnsmessage.Message
+		if err := reply.Unpack(buf[:n]); err != nil || reply.ID != id || !reply.Response {
+			continue
+		}
+		return &reply, nil
+	}
+}
+
+// recordsWithPrefix returns the records starting with a version tag such
+// as "v=spf1", compared case-insensitively
+func recordsWithPrefix(records []string, prefix string) []string {
+	var matched []string
+	for _, record := range records {
+		record = strings.TrimSpace(record)
+		lower := strings.ToLower(record)
+		if lower == prefix || strings.HasPrefix(lower, prefix+" ") || strings.HasPrefix(lower, prefix+";") {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
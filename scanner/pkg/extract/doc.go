@@ -0,0 +1,11 @@
+// Package extract finds links, API endpoints, parameters and forms in
+// page, script, stylesheet, XML and JSON bodies. The crawler, the response
+// analyzer and the script analyzer share it, so each finds what the others
+// do.
+//
+//	base, _ := url.Parse("https://example.com/app/")
+//	links := extract.Links(body, extract.Options{Base: base})
+//	endpoints := extract.Endpoints(script, extract.Options{})
+//
+// Without a Base, matches are returned as written in the body.
+package extract
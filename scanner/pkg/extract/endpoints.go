@@ -0,0 +1,48 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// endpointPatterns find API endpoints in pages and scripts: versioned,
+// REST and GraphQL paths, and the URLs handed to fetch, axios and
+// client configuration
+var endpointPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`["'](/api/[^"']+)["']`),
+	regexp.MustCompile(`["'](/v[0-9]+/[^"']+)["']`),
+	regexp.MustCompile(`["'](/rest/[a-zA-Z0-9/_-]+)["']`),
+	regexp.MustCompile(`["'](/graphql[^"']*)["']`),
+	regexp.MustCompile(`["'](https?://[^"'\s]+/api/[^"']*)["']`),
+	regexp.MustCompile("fetch\\(\\s*[\"'`]([^\"'`]+)[\"'`]"),
+	regexp.MustCompile(`axios\.[a-z]+\(\s*["']([^"']+)["']`),
+	regexp.MustCompile(`(?:url|endpoint|baseURL):\s*["']([^"']+)["']`),
+}
+
+// linkFinderPattern is LinkFinder's endpoint regex: quoted absolute URLs,
+// relative paths, and file names with well-known extensions
+var linkFinderPattern = regexp.MustCompile(`(?:"|')(((?:[a-zA-Z]{1,10}://|//)[^"'/]{1,}\.[a-zA-Z]{2,}[^"']{0,})|((?:/|\.\./|\./)[^"'><,;| *()(%$^/\\\[\]][^"'><,;|()]{1,})|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/.]{1,}\.(?:[a-zA-Z]{1,4}|action)(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/]{3,}(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-]{1,}\.(?:php|asp|aspx|jsp|json|action|html|js|txt|xml)(?:[\?|#][^"|']{0,}|)))(?:"|')`)
+
+// absoluteURLPattern finds unquoted absolute URLs, such as in comments
+var absoluteURLPattern = regexp.MustCompile(`\bhttps?://[a-zA-Z0-9.-]+(?::[0-9]+)?(?:/[^\s"'<>\x60()\\]*)?`)
+
+// Endpoints returns the API endpoints named in a page or script, and
+// with Options.LinkFinder every other quoted path and URL
+func Endpoints(body string, options Options) []string {
+	c := newCollector(options)
+	c.match(body, endpointPatterns)
+	if options.LinkFinder {
+		c.match(body, []*regexp.Regexp{linkFinderPattern})
+	}
+	return c.result(body)
+}
+
+// AbsoluteURLs returns the http and https URLs in body whether quoted or
+// not, less trailing punctuation
+func AbsoluteURLs(body string, options Options) []string {
+	c := newCollector(options)
+	for _, u := range absoluteURLPattern.FindAllString(body, -1) {
+		c.add(strings.TrimRight(u, ".,;:"))
+	}
+	return c.result(body)
+}
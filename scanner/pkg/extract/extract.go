@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Options tune an extraction
+type Options struct {
+	// Base, if set, resolves each match against it, dropping those that
+	// do not resolve to an http or https URL; otherwise matches are
+	// returned as written
+	Base *url.URL
+
+	// Patterns are matched alongside the function's own, each giving its
+	// first submatch, or its whole match if it has none
+	Patterns []*regexp.Regexp
+
+	// LinkFinder also matches Endpoints with LinkFinder's broad pattern:
+	// any quoted relative path, and file names with well-known extensions
+	LinkFinder bool
+
+	// Limit, if set, caps how many are returned
+	Limit int
+}
+
+// Resolve resolves href against base, returning "" for fragments,
+// javascript:, mailto: and data: links and anything that does not
+// resolve to an http or https URL
+func Resolve(href string, base *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") ||
+		strings.HasPrefix(href, "javascript:") ||
+		strings.HasPrefix(href, "mailto:") ||
+		strings.HasPrefix(href, "data:") {
+		return ""
+	}
+
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+	return parsed.String()
+}
+
+// IsAbsolute reports whether s names its host: a URL with a scheme, or a
+// protocol-relative //host path
+func IsAbsolute(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "//")
+}
+
+// LooksLikeURL reports whether a string value is an absolute URL or a
+// root-relative path
+func LooksLikeURL(s string) bool {
+	if strings.ContainsAny(s, " \t\n<>") || len(s) < 2 {
+		return false
+	}
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") ||
+		(s[0] == '/' && s[1] != '/')
+}
+
+// collector gathers matches in the order found, once each, resolving
+// them if Options.Base is set
+type collector struct {
+	options Options
+	seen    map[string]bool
+	found   []string
+}
+
+func newCollector(options Options) *collector {
+	return &collector{options: options, seen: make(map[string]bool)}
+}
+
+// full reports whether Limit has been reached
+func (c *collector) full() bool {
+	return c.options.Limit > 0 && len(c.found) >= c.options.Limit
+}
+
+// add records s, resolved if there is a Base
+func (c *collector) add(s string) {
+	if c.options.Base != nil {
+		s = Resolve(s, c.options.Base)
+	}
+	if s == "" || c.seen[s] || c.full() {
+		return
+	}
+	c.seen[s] = true
+	c.found = append(c.found, s)
+}
+
+// match adds the first submatch, or whole match, of each pattern's
+// matches in body
+func (c *collector) match(body string, patterns []*regexp.Regexp) {
+	for _, pattern := range patterns {
+		for _, m := range pattern.FindAllStringSubmatch(body, -1) {
+			if c.full() {
+				return
+			}
+			if len(m) > 1 {
+				c.add(m[1])
+			} else {
+				c.add(m[0])
+			}
+		}
+	}
+}
+
+// result adds the matches of Options.Patterns and returns all found
+func (c *collector) result(body string) []string {
+	c.match(body, c.options.Patterns)
+	return c.found
+}
@@ -0,0 +1,262 @@
+package extract
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// corpus reads a sample document from testdata
+func corpus(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// pageBase is the URL the samples are resolved against
+var pageBase = mustParse("https://example.com/dir/page.html")
+
+func mustParse(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestLinkExtractors(t *testing.T) {
+	tests := []struct {
+		name    string
+		extract func(string, Options) []string
+		file    string
+		options Options
+		want    []string
+	}{
+		{
+			name:    "html links as written",
+			extract: Links,
+			file:    "page.html",
+			want: []string{
+				"/static/site.css", "/about", "contact.html", "#top", "mailto:admin@example.com",
+				"javascript:void(0)", "/items?page=2&sort=asc", "https://cdn.example.net/lib.js", "/img/logo.png",
+			},
+		},
+		{
+			name:    "html links resolved",
+			extract: Links,
+			file:    "page.html",
+			options: Options{Base: pageBase},
+			want: []string{
+				"https://example.com/static/site.css", "https://example.com/about", "https://example.com/dir/contact.html",
+				"https://example.com/items?page=2&sort=asc", "https://cdn.example.net/lib.js", "https://example.com/img/logo.png",
+			},
+		},
+		{
+			name:    "html links limited",
+			extract: Links,
+			file:    "page.html",
+			options: Options{Limit: 2},
+			want:    []string{"/static/site.css", "/about"},
+		},
+		{
+			name:    "sitemap",
+			extract: XMLLinks,
+			file:    "sitemap.xml",
+			want:    []string{"https://example.com/", "https://example.com/blog", "https://example.com/about"},
+		},
+		{
+			name:    "css as written",
+			extract: CSSLinks,
+			file:    "site.css",
+			want: []string{
+				"/static/fonts.css", "/img/bg.png", "https://cdn.example.net/logo.svg", "data:image/png;base64,AAAA", "reset.css",
+			},
+		},
+		{
+			name:    "css resolved drops data URLs",
+			extract: CSSLinks,
+			file:    "site.css",
+			options: Options{Base: pageBase},
+			want: []string{
+				"https://example.com/static/fonts.css", "https://example.com/img/bg.png",
+				"https://cdn.example.net/logo.svg", "https://example.com/dir/reset.css",
+			},
+		},
+		{
+			name:    "script",
+			extract: ScriptLinks,
+			file:    "app.js",
+			want: []string{
+				"https://api.example.com/api/v1/users", "https://example.com/rest/items",
+				"/api/orders?status=open", "/v2/accounts", "/graphql", "/dashboard/home",
+			},
+		},
+		{
+			name:    "json in key order",
+			extract: JSONLinks,
+			file:    "data.json",
+			want: []string{
+				"/img/a.png", "https://docs.example.com/", "/api/items?page=2", "/api/related", "https://example.com/api/items",
+			},
+		},
+		{
+			name:    "json resolved",
+			extract: JSONLinks,
+			file:    "data.json",
+			options: Options{Base: pageBase},
+			want: []string{
+				"https://example.com/img/a.png", "https://docs.example.com/", "https://example.com/api/items?page=2",
+				"https://example.com/api/related", "https://example.com/api/items",
+			},
+		},
+		{
+			name:    "endpoints",
+			extract: Endpoints,
+			file:    "app.js",
+			want: []string{
+				"/api/orders?status=open", "/v2/accounts", "/graphql",
+				"https://api.example.com/api/v1/users", "https://example.com/rest/items",
+			},
+		},
+		{
+			name:    "endpoints with linkfinder",
+			extract: Endpoints,
+			file:    "app.js",
+			options: Options{LinkFinder: true},
+			want: []string{
+				"/api/orders?status=open", "/v2/accounts", "/graphql", "https://api.example.com/api/v1/users",
+				"https://example.com/rest/items", "/dashboard/home", "static/chunk.js",
+			},
+		},
+		{
+			name:    "endpoints with extra patterns",
+			extract: Endpoints,
+			file:    "app.js",
+			options: Options{Patterns: []*regexp.Regexp{regexp.MustCompile(`loadScript\("([^"]+)"\)`)}},
+			want: []string{
+				"/api/orders?status=open", "/v2/accounts", "/graphql",
+				"https://api.example.com/api/v1/users", "https://example.com/rest/items", "static/chunk.js",
+			},
+		},
+		{
+			name:    "absolute urls in script",
+			extract: AbsoluteURLs,
+			file:    "app.js",
+			want: []string{
+				"https://api.example.com/api/v1/users", "https://example.com/rest/items", "http://docs.example.com/guide",
+			},
+		},
+		{
+			name:    "absolute urls in html comments",
+			extract: AbsoluteURLs,
+			file:    "page.html",
+			want:    []string{"https://cdn.example.net/lib.js", "https://staging.example.com/login"},
+		},
+		{
+			name:    "parameters of a page",
+			extract: Parameters,
+			file:    "page.html",
+			options: Options{Base: pageBase},
+			want:    []string{"q", "lang", "user", "pass", "page", "sort"},
+		},
+		{
+			name:    "parameters of a script",
+			extract: Parameters,
+			file:    "app.js",
+			want:    []string{"status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.extract(corpus(t, tt.file), tt.options)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLinksFallsBackToScript(t *testing.T) {
+	got := JSONLinks(`{"next": "/api/items", `, Options{})
+	if want := []string{"/api/items"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONLinksStable(t *testing.T) {
+	body := corpus(t, "data.json")
+	first := JSONLinks(body, Options{})
+	for i := 0; i < 20; i++ {
+		if got := JSONLinks(body, Options{}); !reflect.DeepEqual(got, first) {
+			t.Fatalf("run %d: got %q, first run %q", i, got, first)
+		}
+	}
+}
+
+func TestForms(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		want    []Form
+	}{
+		{
+			name: "as written",
+			want: []Form{
+				{Action: "/search", Method: "GET", Fields: []string{"q", "lang"}},
+				{Action: "", Method: "POST", Fields: []string{"user", "pass"}},
+			},
+		},
+		{
+			name:    "resolved, submitting to the page without an action",
+			options: Options{Base: pageBase},
+			want: []Form{
+				{Action: "https://example.com/search", Method: "GET", Fields: []string{"q", "lang"}},
+				{Action: "https://example.com/dir/page.html", Method: "POST", Fields: []string{"user", "pass"}},
+			},
+		},
+		{
+			name:    "limited",
+			options: Options{Limit: 1},
+			want:    []Form{{Action: "/search", Method: "GET", Fields: []string{"q", "lang"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Forms(corpus(t, "page.html"), tt.options)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/a", "https://example.com/a"},
+		{"b?x=1", "https://example.com/dir/b?x=1"},
+		{"//cdn.example.net/c", "https://cdn.example.net/c"},
+		{"  /spaced  ", "https://example.com/spaced"},
+		{"#frag", ""},
+		{"javascript:alert(1)", ""},
+		{"mailto:a@example.com", ""},
+		{"data:text/plain,x", ""},
+		{"ftp://example.com/f", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Resolve(tt.href, pageBase); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
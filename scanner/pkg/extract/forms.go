@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Form is an HTML form: where it submits, how, and its fields' names
+type Form struct {
+	Action string
+	Method string
+	Fields []string
+}
+
+var (
+	formPattern       = regexp.MustCompile(`(?is)<form([^>]*)>(.*?)</form>`)
+	formActionPattern = regexp.MustCompile(`(?i)action=["']([^"']+)["']`)
+	formMethodPattern = regexp.MustCompile(`(?i)method=["']?([a-z]+)`)
+	fieldNamePattern  = regexp.MustCompile(`name=["']([^"']+)["']`)
+)
+
+// parameterPatterns find parameter names: form fields and the keys of
+// query strings written in pages and scripts
+var parameterPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`name=["']([a-zA-Z0-9_-]+)["']`),
+	regexp.MustCompile(`[?&]([a-zA-Z0-9_]+)=`),
+}
+
+// Forms returns a page's forms. The method defaults to GET, and the
+// action is as written, or resolved against Options.Base: a form without
+// one submits to the page itself.
+func Forms(body string, options Options) []Form {
+	var forms []Form
+	for _, m := range formPattern.FindAllStringSubmatch(body, -1) {
+		if options.Limit > 0 && len(forms) >= options.Limit {
+			break
+		}
+		attrs, content := m[1], m[2]
+
+		form := Form{Method: "GET"}
+		if action := formActionPattern.FindStringSubmatch(attrs); action != nil {
+			form.Action = action[1]
+		}
+		if options.Base != nil {
+			if form.Action = Resolve(form.Action, options.Base); form.Action == "" {
+				form.Action = options.Base.String()
+			}
+		}
+		if method := formMethodPattern.FindStringSubmatch(attrs); method != nil {
+			form.Method = strings.ToUpper(method[1])
+		}
+		for _, field := range fieldNamePattern.FindAllStringSubmatch(content, -1) {
+			form.Fields = append(form.Fields, field[1])
+		}
+		forms = append(forms, form)
+	}
+	return forms
+}
+
+// Parameters returns the parameter names in a page or script: its form
+// fields and the keys of the query strings in it. They are names, so
+// Options.Base is ignored.
+func Parameters(body string, options Options) []string {
+	options.Base = nil
+	c := newCollector(options)
+	c.match(body, parameterPatterns)
+	return c.result(body)
+}
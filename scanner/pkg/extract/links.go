@@ -0,0 +1,96 @@
+package extract
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+)
+
+// linkPatterns find links in HTML: href and src attributes
+var linkPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`href=["']([^"']+)["']`),
+	regexp.MustCompile(`src=["']([^"']+)["']`),
+}
+
+// xmlLinkPattern finds the URLs of sitemaps and feeds
+var xmlLinkPattern = regexp.MustCompile(`(?i)<(?:loc|link|url)>\s*([^<\s]+)\s*</(?:loc|link|url)>`)
+
+// cssLinkPatterns find url(...) and @import references in stylesheets
+var cssLinkPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`url\(\s*["']?([^"')\s]+)["']?\s*\)`),
+	regexp.MustCompile(`@import\s+["']([^"']+)["']`),
+}
+
+// scriptLinkPatterns find quoted absolute URLs and root-relative paths in
+// scripts
+var scriptLinkPatterns = []*regexp.Regexp{
+	regexp.MustCompile("[\"'`](https?://[^\"'`\\s<>]+)[\"'`]"),
+	regexp.MustCompile("[\"'`](/[a-zA-Z0-9_\\-][a-zA-Z0-9_\\-./]*(?:\\?[^\"'`\\s<>]*)?)[\"'`]"),
+}
+
+// Links returns the href and src links of an HTML page
+func Links(body string, options Options) []string {
+	c := newCollector(options)
+	c.match(body, linkPatterns)
+	return c.result(body)
+}
+
+// XMLLinks returns the links of an XML document such as a sitemap or
+// feed: its loc, link and url elements, and any href and src attributes
+func XMLLinks(body string, options Options) []string {
+	c := newCollector(options)
+	c.match(body, linkPatterns)
+	c.match(body, []*regexp.Regexp{xmlLinkPattern})
+	return c.result(body)
+}
+
+// CSSLinks returns a stylesheet's url(...) and @import references
+func CSSLinks(body string, options Options) []string {
+	c := newCollector(options)
+	c.match(body, cssLinkPatterns)
+	return c.result(body)
+}
+
+// ScriptLinks returns the quoted absolute URLs and root-relative paths in
+// a script
+func ScriptLinks(body string, options Options) []string {
+	c := newCollector(options)
+	c.match(body, scriptLinkPatterns)
+	return c.result(body)
+}
+
+// JSONLinks returns the URL-shaped string values of a JSON document, in
+// key order, or its ScriptLinks if it does not parse
+func JSONLinks(body string, options Options) []string {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return ScriptLinks(body, options)
+	}
+
+	c := newCollector(options)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			// Keys in order, so the links come out the same every time
+			keys := make([]string, 0, len(val))
+			for key := range val {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(val[key])
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case string:
+			if LooksLikeURL(val) {
+				c.add(val)
+			}
+		}
+	}
+	walk(doc)
+	return c.result(body)
+}
@@ -0,0 +1,8 @@
+const API = "https://api.example.com/api/v1/users";
+fetch("/api/orders?status=open");
+axios.get('/v2/accounts');
+const client = { baseURL: "https://example.com/rest/items" };
+query("/graphql");
+const page = '/dashboard/home';
+loadScript("static/chunk.js");
+// see http://docs.example.com/guide, for details
@@ -0,0 +1,178 @@
+package extract
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// webpackMarkers are names only a webpack runtime or its chunks contain,
+// minified or not
+var webpackMarkers = []string{"__webpack_require__", "webpackJsonp", "webpackChunk"}
+
+// Terms of the expression a webpack runtime builds chunk file names with,
+// such as "static/js/"+e+"."+{179:"a1b2c3"}[e]+".chunk.js" or, before
+// webpack 5, ({0:"vendors"}[chunkId]||chunkId)
+const (
+	webpackString = `"[^"\n]*"|'[^'\n]*'`
+	webpackLookup = `\{[^{}]*\}\s*\[\s*[\w$]+\s*\](?:\s*\|\|\s*[\w$]+)?`
+	webpackTerm   = `\(*\s*(?:` + webpackString + `|` + webpackLookup + `|[\w$.]+)\s*\)*`
+)
+
+// webpackExpression matches a concatenation of terms
+var webpackExpression = regexp.MustCompile(webpackTerm + `(?:\s*\+\s*` + webpackTerm + `)+`)
+
+// webpackTerms splits an expression into its string literals, map
+// lookups (the map, the key variable and any fallback) and identifiers
+var webpackTerms = regexp.MustCompile(`(` + webpackString + `)|\{([^{}]*)\}\s*\[\s*([\w$]+)\s*\](?:\s*\|\|\s*([\w$]+))?|([\w$.]+)`)
+
+// webpackMapEntry matches a chunk map's id: "value" entries
+var webpackMapEntry = regexp.MustCompile(`(?:"([^"]+)"|'([^']+)'|([\w$]+))\s*:\s*(?:"([^"]*)"|'([^']*)')`)
+
+// webpackPublicPath matches the runtime's public path assignment
+var webpackPublicPath = regexp.MustCompile(`[\w$]+\.p\s*=\s*["']([^"']*)["']`)
+
+// WebpackChunks returns the URLs of the lazily loaded chunks a webpack
+// runtime knows, by evaluating its chunk file name expression for each id
+// in its chunk maps. Single-page apps keep most of their routes and API
+// calls in chunks no page links to.
+//
+// Chunk paths are under the runtime's public path. Without one, or with
+// webpack 5's "auto", they are placed where Options.Base, the script's
+// URL, shows the chunk directory to be, or else beside the script.
+func WebpackChunks(body string, options Options) []string {
+	if !containsAny(body, webpackMarkers) {
+		return nil
+	}
+
+	var chunks []string
+	for _, expression := range webpackExpression.FindAllString(body, -1) {
+		chunks = append(chunks, webpackFileNames(expression)...)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	publicPath := ""
+	if m := webpackPublicPath.FindStringSubmatch(body); m != nil && m[1] != "auto" {
+		publicPath = m[1]
+	}
+
+	c := newCollector(options)
+	for _, chunk := range chunks {
+		if publicPath != "" {
+			c.add(strings.TrimSuffix(publicPath, "/") + "/" + chunk)
+		} else {
+			c.add(webpackRoot(chunk, options) + chunk)
+		}
+	}
+	return c.result(body)
+}
+
+// webpackFileNames evaluates a chunk file name expression for every chunk
+// id its maps name. Expressions that are not one, because they have no
+// map lookup, end in something other than .js or use variables other than
+// the chunk id and public path, give nothing.
+func webpackFileNames(expression string) []string {
+	type term struct {
+		literal  string
+		entries  map[string]string
+		fallback bool
+		ident    string
+	}
+
+	var terms []term
+	variable := ""
+	ids := make(map[string]bool)
+	for _, m := range webpackTerms.FindAllStringSubmatch(expression, -1) {
+		switch {
+		case m[1] != "":
+			terms = append(terms, term{literal: m[1][1 : len(m[1])-1]})
+		case m[3] != "":
+			if variable == "" {
+				variable = m[3]
+			}
+			if m[3] != variable || (m[4] != "" && m[4] != variable) {
+				return nil
+			}
+			entries := make(map[string]string)
+			for _, entry := range webpackMapEntry.FindAllStringSubmatch(m[2], -1) {
+				id := entry[1] + entry[2] + entry[3]
+				entries[id] = entry[4] + entry[5]
+				ids[id] = true
+			}
+			terms = append(terms, term{entries: entries, fallback: m[4] != ""})
+		case strings.HasSuffix(m[5], ".p"):
+			// The public path, added to every chunk below
+		default:
+			terms = append(terms, term{ident: m[5]})
+		}
+	}
+	if variable == "" || len(terms) == 0 || !strings.HasSuffix(terms[len(terms)-1].literal, ".js") {
+		return nil
+	}
+	for _, t := range terms {
+		if t.ident != "" && t.ident != variable {
+			return nil
+		}
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	var names []string
+next:
+	for _, id := range sorted {
+		var b strings.Builder
+		for _, t := range terms {
+			switch {
+			case t.ident != "":
+				b.WriteString(id)
+			case t.entries != nil:
+				value, ok := t.entries[id]
+				if !ok && !t.fallback {
+					// A map of some chunks, such as those with CSS
+					continue next
+				}
+				if !ok {
+					value = id
+				}
+				b.WriteString(value)
+			default:
+				b.WriteString(t.literal)
+			}
+		}
+		names = append(names, strings.TrimPrefix(b.String(), "/"))
+	}
+	return names
+}
+
+// webpackRoot guesses the public path of a chunk from the script's URL:
+// the part of its path before the chunk's directory, such as /app/ for
+// /app/static/js/main.js and static/js/12.chunk.js, or its own directory
+func webpackRoot(chunk string, options Options) string {
+	if options.Base == nil {
+		return ""
+	}
+	scriptPath := options.Base.Path
+	if dir := path.Dir(chunk); dir != "." {
+		if i := strings.Index(scriptPath, "/"+dir+"/"); i >= 0 {
+			return scriptPath[:i+1]
+		}
+	}
+	return scriptPath[:strings.LastIndex(scriptPath, "/")+1]
+}
+
+// containsAny reports whether s contains any of substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+// Package hostintel pulls what Shodan and Censys have cached about hosts —
+// open ports, banners, hostnames and tags — without sending the hosts
+// anything, and compares it with the ports an active scan found.
+//
+//	lookup := hostintel.NewLookup(hostintel.Config{
+//		Targets:   []string{"192.0.2.10"},
+//		Scanned:   map[string][]int{"192.0.2.10": {22, 443}},
+//		ShodanKey: os.Getenv("SHODAN_API_KEY"),
+//	})
+//	hosts, err := lookup.Run(ctx)
+//	for _, h := range hosts {
+//		fmt.Println(h.IP, h.Source, h.OnlyCached, h.OnlyScanned)
+//	}
+//
+// Each source needs its own credentials and is skipped without them.
+// A host a source knows nothing about yields no result from it. With
+// Scanned set, every cached service notes whether the scan found it too,
+// OnlyCached lists the ports a source saw open that the scan did not
+// (closed since, filtered from the scanner, or outside its port range),
+// and OnlyScanned the ports the scan found that the source has not seen.
+package hostintel
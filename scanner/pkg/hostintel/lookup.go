@@ -0,0 +1,358 @@
+package hostintel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Source names
+const (
+	SourceShodan = "shodan"
+	SourceCensys = "censys"
+)
+
+// Default API endpoints
+const (
+	DefaultShodanURL = "https://api.shodan.io"
+	DefaultCensysURL = "https://search.censys.io/api"
+)
+
+// maxBanner is how much of a cached banner a result keeps
+const maxBanner = 512
+
+// errNotFound is a source knowing nothing of a host
+var errNotFound = errors.New("host not found")
+
+// Config holds host enrichment configuration
+type Config struct {
+	// Targets are IP addresses, or hostnames looked up to theirs
+	Targets []string
+
+	// Scanned, if set, are the ports an active scan found open, keyed by
+	// target as given, for comparing with what the sources cached
+	Scanned map[string][]int
+
+	// ShodanKey authenticates Shodan, and CensysID and CensysSecret
+	// Censys; a source without credentials is skipped
+	ShodanKey    string
+	CensysID     string
+	CensysSecret string
+
+	// ShodanURL and CensysURL override the API endpoints
+	ShodanURL string
+	CensysURL string
+
+	// Workers is how many hosts are looked up at once
+	Workers int
+
+	// Timeout is the per-request timeout in seconds
+	Timeout int
+
+	// Proxy routes API requests through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Budget, if set, limits API requests to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts lookups made and hosts a source knew
+	Progress *utils.Progress
+
+	// OnResult is called for each host a source knew
+	OnResult func(Host)
+}
+
+// Service is one port a source saw open
+type Service struct {
+	Port      int    `json:"port"`
+	Transport string `json:"transport,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Product   string `json:"product,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Banner    string `json:"banner,omitempty"`
+	SeenAt    string `json:"seen_at,omitempty"`
+
+	// Scanned is whether the active scan found the port open too
+	Scanned bool `json:"scanned,omitempty"`
+}
+
+// Host is what one source has cached about a host
+type Host struct {
+	Host      string    `json:"host"`
+	IP        string    `json:"ip"`
+	Source    string    `json:"source"`
+	Services  []Service `json:"services"`
+	Hostnames []string  `json:"hostnames,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	OS        string    `json:"os,omitempty"`
+	Org       string    `json:"org,omitempty"`
+	LastSeen  string    `json:"last_seen,omitempty"`
+
+	// OnlyCached are the TCP ports the source saw open that the active
+	// scan did not find, and OnlyScanned those the scan found that the
+	// source has not seen; both are empty without a scan to compare
+	OnlyCached  []int `json:"only_cached,omitempty"`
+	OnlyScanned []int `json:"only_scanned,omitempty"`
+
+	Timestamp string `json:"timestamp"`
+}
+
+// source looks a single IP up
+type source struct {
+	name   string
+	lookup func(ctx context.Context, ip string) (Host, error)
+}
+
+// Lookup enriches hosts from Shodan and Censys
+type Lookup struct {
+	config Config
+	client *http.Client
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewLookup creates a new host enrichment lookup
+func NewLookup(config Config) *Lookup {
+	if config.ShodanURL == "" {
+		config.ShodanURL = DefaultShodanURL
+	}
+	if config.CensysURL == "" {
+		config.CensysURL = DefaultCensysURL
+	}
+	config.ShodanURL = strings.TrimSuffix(config.ShodanURL, "/")
+	config.CensysURL = strings.TrimSuffix(config.CensysURL, "/")
+	if config.Workers == 0 {
+		config.Workers = 5
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+
+	return &Lookup{
+		config: config,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "intel"),
+		},
+	}
+}
+
+// Errors returns the failed lookups of the last run
+func (l *Lookup) Errors() []string {
+	l.errorsMu.Lock()
+	defer l.errorsMu.Unlock()
+	return append([]string(nil), l.errors...)
+}
+
+// recordError notes a failed lookup
+func (l *Lookup) recordError(what string, err error) {
+	l.errorsMu.Lock()
+	defer l.errorsMu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Run looks every target up in every source with credentials. If ctx is
+// cancelled, the hosts so far are returned with ctx's error.
+func (l *Lookup) Run(ctx context.Context) ([]Host, error) {
+	var sources []source
+	if l.config.ShodanKey != "" {
+		sources = append(sources, source{SourceShodan, l.shodan})
+	}
+	if l.config.CensysID != "" && l.config.CensysSecret != "" {
+		sources = append(sources, source{SourceCensys, l.censys})
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("host enrichment needs a Shodan key or Censys API ID and secret")
+	}
+	l.config.Progress.AddTotal(len(sources) * len(l.config.Targets))
+
+	ctx = log.WithModule(ctx, "intel")
+	jobs := make(chan string)
+	results := make(chan Host)
+
+	var wg sync.WaitGroup
+	for i := 0; i < l.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				for _, host := range l.enrich(ctx, target, sources) {
+					results <- host
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range l.config.Targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var hosts []Host
+	for host := range results {
+		l.config.Progress.Found()
+		metrics.Findings("intel").Inc()
+		if l.config.OnResult != nil {
+			l.config.OnResult(host)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, ctx.Err()
+}
+
+// enrich looks a target up in each source and compares what they cached
+// with the scan
+func (l *Lookup) enrich(ctx context.Context, target string, sources []source) []Host {
+	logger := log.FromContext(ctx).With(log.Target(target))
+	ip := target
+	if net.ParseIP(target) == nil {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", target)
+		if err != nil || len(ips) == 0 {
+			if ctx.Err() == nil {
+				logger.Warn("cannot resolve", log.Err(err))
+				l.recordError(target, fmt.Errorf("cannot resolve: %w", err))
+			}
+			l.config.Progress.AddTotal(-len(sources))
+			return nil
+		}
+		ip = ips[0].String()
+	}
+
+	var hosts []Host
+	for _, s := range sources {
+		start := time.Now()
+		host, err := s.lookup(ctx, ip)
+		l.config.Progress.Done()
+		switch {
+		case errors.Is(err, errNotFound):
+			logger.Info("host unknown to source", "source", s.name, log.Since(start))
+			continue
+		case err != nil:
+			if ctx.Err() == nil {
+				logger.Warn("lookup failed", "source", s.name, log.Err(err), log.Since(start))
+				l.recordError(s.name+" "+target, err)
+			}
+			continue
+		}
+
+		host.Host, host.IP, host.Source = target, ip, s.name
+		host.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		if scanned, ok := l.config.Scanned[target]; ok {
+			compare(&host, scanned)
+		}
+		logger.Debug("host enriched", "source", s.name, "services", len(host.Services), log.Since(start))
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// compare marks the services the scan found too and lists the TCP ports
+// only one side saw
+func compare(host *Host, scanned []int) {
+	cached := make(map[int]bool)
+	for i, s := range host.Services {
+		if s.Transport != "" && s.Transport != "tcp" {
+			continue
+		}
+		cached[s.Port] = true
+		host.Services[i].Scanned = slices.Contains(scanned, s.Port)
+		if !host.Services[i].Scanned && !slices.Contains(host.OnlyCached, s.Port) {
+			host.OnlyCached = append(host.OnlyCached, s.Port)
+		}
+	}
+	for _, port := range scanned {
+		if !cached[port] && !slices.Contains(host.OnlyScanned, port) {
+			host.OnlyScanned = append(host.OnlyScanned, port)
+		}
+	}
+	sort.Ints(host.OnlyCached)
+	sort.Ints(host.OnlyScanned)
+}
+
+// get sends a GET within the budget and returns the body of a 2xx
+// response, or errNotFound for a 404. Errors leave the URL out, as Shodan's
+// carries the key.
+func (l *Lookup) get(ctx context.Context, endpoint string, auth func(*http.Request)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := l.config.Budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, errors.New("invalid API URL")
+		}
+		req.Header.Set("Accept", "application/json")
+		auth(req)
+
+		resp, err := l.client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, errNotFound
+		case resp.StatusCode != http.StatusTooManyRequests || attempt >= 3:
+			return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(truncate(string(body), 200)))
+		}
+
+		wait, advised := utils.RetryAfterDelay(resp.Header, time.Now())
+		if !advised {
+			wait = 10 * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(wait+time.Second, time.Minute)):
+		}
+	}
+}
+
+// truncate cuts s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
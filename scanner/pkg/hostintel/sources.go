@@ -0,0 +1,126 @@
+package hostintel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// shodan reads Shodan's host record: its open ports and the latest banner
+// on each
+func (l *Lookup) shodan(ctx context.Context, ip string) (Host, error) {
+	body, err := l.get(ctx, l.config.ShodanURL+"/shodan/host/"+url.PathEscape(ip)+"?key="+url.QueryEscape(l.config.ShodanKey),
+		func(*http.Request) {})
+	if err != nil {
+		return Host{}, err
+	}
+	var resp struct {
+		Hostnames  []string `json:"hostnames"`
+		Tags       []string `json:"tags"`
+		OS         string   `json:"os"`
+		Org        string   `json:"org"`
+		LastUpdate string   `json:"last_update"`
+		Data       []struct {
+			Port      int    `json:"port"`
+			Transport string `json:"transport"`
+			Product   string `json:"product"`
+			Version   string `json:"version"`
+			Data      string `json:"data"`
+			Timestamp string `json:"timestamp"`
+			Shodan    struct {
+				Module string `json:"module"`
+			} `json:"_shodan"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Host{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	host := Host{
+		Services:  []Service{},
+		Hostnames: resp.Hostnames,
+		Tags:      resp.Tags,
+		OS:        resp.OS,
+		Org:       resp.Org,
+		LastSeen:  resp.LastUpdate,
+	}
+	for _, d := range resp.Data {
+		host.Services = append(host.Services, Service{
+			Port:      d.Port,
+			Transport: d.Transport,
+			Name:      d.Shodan.Module,
+			Product:   d.Product,
+			Version:   d.Version,
+			Banner:    truncate(strings.TrimSpace(d.Data), maxBanner),
+			SeenAt:    d.Timestamp,
+		})
+	}
+	return host, nil
+}
+
+// censys reads Censys's host record: its services, with the software and
+// banner seen on each
+func (l *Lookup) censys(ctx context.Context, ip string) (Host, error) {
+	body, err := l.get(ctx, l.config.CensysURL+"/v2/hosts/"+url.PathEscape(ip), func(req *http.Request) {
+		req.SetBasicAuth(l.config.CensysID, l.config.CensysSecret)
+	})
+	if err != nil {
+		return Host{}, err
+	}
+	var resp struct {
+		Result struct {
+			Services []struct {
+				Port       int    `json:"port"`
+				Transport  string `json:"transport_protocol"`
+				Name       string `json:"service_name"`
+				Banner     string `json:"banner"`
+				ObservedAt string `json:"observed_at"`
+				Software   []struct {
+					Product string `json:"product"`
+					Version string `json:"version"`
+				} `json:"software"`
+			} `json:"services"`
+			DNS struct {
+				Names []string `json:"names"`
+			} `json:"dns"`
+			OperatingSystem struct {
+				Product string `json:"product"`
+			} `json:"operating_system"`
+			AutonomousSystem struct {
+				Name string `json:"name"`
+			} `json:"autonomous_system"`
+			Labels      []string `json:"labels"`
+			LastUpdated string   `json:"last_updated_at"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Host{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	r := resp.Result
+	host := Host{
+		Services:  []Service{},
+		Hostnames: r.DNS.Names,
+		Tags:      r.Labels,
+		OS:        r.OperatingSystem.Product,
+		Org:       r.AutonomousSystem.Name,
+		LastSeen:  r.LastUpdated,
+	}
+	for _, s := range r.Services {
+		service := Service{
+			Port:      s.Port,
+			Transport: strings.ToLower(s.Transport),
+			Name:      strings.ToLower(s.Name),
+			Banner:    truncate(strings.TrimSpace(s.Banner), maxBanner),
+			SeenAt:    s.ObservedAt,
+		}
+		if len(s.Software) > 0 {
+			service.Product, service.Version = s.Software[0].Product, s.Software[0].Version
+		}
+		host.Services = append(host.Services, service)
+	}
+	return host, nil
+}
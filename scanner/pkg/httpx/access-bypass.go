@@ -0,0 +1,449 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Bypass technique categories, for BypassConfig.Techniques
+const (
+	BypassPath   = "path"
+	BypassHeader = "header"
+	BypassMethod = "method"
+)
+
+// BypassCategories are the technique categories, in the order tried
+var BypassCategories = []string{BypassPath, BypassHeader, BypassMethod}
+
+// spoofedIPHeaders claim the request came from the server itself, which
+// proxies and apps that allow-list local clients may trust
+var spoofedIPHeaders = []string{
+	"X-Forwarded-For", "X-Real-IP", "X-Client-IP", "X-Originating-IP", "X-Remote-IP",
+	"X-Remote-Addr", "X-Custom-IP-Authorization", "True-Client-IP",
+}
+
+// BypassConfig holds 401/403 bypass testing configuration
+type BypassConfig struct {
+	// Targets are the URLs to test, usually those a probe or fuzz run
+	// found forbidden. Targets that do not answer 401 or 403 are skipped.
+	Targets []string
+
+	// Techniques are the categories tried: BypassPath (casing, trailing
+	// characters, dot segments), BypassHeader (X-Original-URL and
+	// spoofed client IPs) and BypassMethod (HEAD, POST, a lower-case
+	// GET). Default all. Methods that change state, such as PUT and DELETE,
+	// are never sent.
+	Techniques []string
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts targets tested and bypasses found
+	Progress *utils.Progress
+
+	// OnResult is called for each bypass
+	OnResult func(BypassResult)
+}
+
+// BypassResult is one request that got past a 401 or 403
+type BypassResult struct {
+	URL            string            `json:"url"`
+	Technique      string            `json:"technique"`
+	Category       string            `json:"category"`
+	Method         string            `json:"method"`
+	RequestURL     string            `json:"request_url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	OriginalStatus int               `json:"original_status"`
+	StatusCode     int               `json:"status_code"`
+	ContentLength  int               `json:"content_length"`
+	Location       string            `json:"location,omitempty"`
+
+	// Severity is high when the bypass got a 2xx, and low for a redirect
+	// elsewhere
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+// BypassScanner retries forbidden URLs with known access control bypass
+// techniques
+type BypassScanner struct {
+	config  BypassConfig
+	prober  *Prober
+	limiter *rate.Limiter
+}
+
+// bypassAttempt is one request variant, and the request it is compared
+// against: the same request without the trick, so a page answering
+// anything with 200 is not reported
+type bypassAttempt struct {
+	technique, category string
+	method, url         string
+	headers             map[string]string
+	control             *bypassAttempt // nil: compare with the original
+}
+
+// bypassResponse is what a bypass attempt got back
+type bypassResponse struct {
+	status   int
+	length   int
+	location string
+}
+
+// NewBypassScanner creates a new bypass scanner. Redirects are not
+// followed, so each technique's own response is judged.
+func NewBypassScanner(config BypassConfig) *BypassScanner {
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 50
+	}
+	if len(config.Techniques) == 0 {
+		config.Techniques = BypassCategories
+	}
+
+	return &BypassScanner{
+		config: config,
+		prober: NewProber(ProbeConfig{
+			Workers:   config.Workers,
+			Timeout:   config.Timeout,
+			UserAgent: config.UserAgent,
+			Headers:   config.Headers,
+			Cookies:   config.Cookies,
+			Proxy:     config.Proxy,
+			Scope:     config.Scope,
+			Budget:    config.Budget,
+		}),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// ScanContext tests every target. A target's attempts are sent one at a
+// time, so workers spread over hosts. If ctx is cancelled, the bypasses
+// found so far are returned with ctx's error.
+func (s *BypassScanner) ScanContext(ctx context.Context) ([]BypassResult, error) {
+	targets := s.config.Scope.Filter(s.config.Targets)
+	s.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "bypass")
+	jobs := make(chan string)
+	results := make(chan BypassResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				for _, result := range s.test(ctx, target) {
+					results <- result
+				}
+				s.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var bypasses []BypassResult
+	for result := range results {
+		s.config.Progress.Found()
+		metrics.Findings("bypass").Inc()
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		bypasses = append(bypasses, result)
+	}
+	return bypasses, ctx.Err()
+}
+
+// test tries each technique on a target that answers 401 or 403
+func (s *BypassScanner) test(ctx context.Context, target string) []BypassResult {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	original, ok := s.send(ctx, &bypassAttempt{method: "GET", url: target})
+	if !ok {
+		return nil
+	}
+	if original.status != 401 && original.status != 403 {
+		log.FromContext(ctx).Info("not forbidden, nothing to bypass", log.Target(target), "status", original.status)
+		return nil
+	}
+
+	controls := make(map[*bypassAttempt]bypassResponse)
+	var results []BypassResult
+	for _, attempt := range s.attempts(u) {
+		if ctx.Err() != nil {
+			break
+		}
+		resp, ok := s.send(ctx, attempt)
+		if !ok || resp.status == original.status || !bypassed(resp, u, attempt) {
+			continue
+		}
+
+		// The trick must be what changed the answer
+		if attempt.control != nil {
+			control, seen := controls[attempt.control]
+			if !seen {
+				control, _ = s.send(ctx, attempt.control)
+				controls[attempt.control] = control
+			}
+			if control.status == resp.status && similarLength(control.length, resp.length) {
+				continue
+			}
+		}
+
+		severity := "high"
+		if resp.status >= 300 {
+			severity = "low"
+		}
+		results = append(results, BypassResult{
+			URL:            target,
+			Technique:      attempt.technique,
+			Category:       attempt.category,
+			Method:         attempt.method,
+			RequestURL:     attempt.url,
+			Headers:        attempt.headers,
+			OriginalStatus: original.status,
+			StatusCode:     resp.status,
+			ContentLength:  resp.length,
+			Location:       resp.location,
+			Severity:       severity,
+			Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return results
+}
+
+// attempts returns the request variants for a forbidden URL, in the
+// configured categories
+func (s *BypassScanner) attempts(u *url.URL) []*bypassAttempt {
+	root := u.Scheme + "://" + u.Host
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := ""
+	if u.RawQuery != "" {
+		query = "?" + u.RawQuery
+	}
+	trimmed := strings.TrimSuffix(path, "/")
+	parent, segment := trimmed[:strings.LastIndex(trimmed, "/")+1], trimmed[strings.LastIndex(trimmed, "/")+1:]
+	rest := strings.TrimPrefix(path, "/")
+
+	var attempts []*bypassAttempt
+	for _, category := range s.config.Techniques {
+		switch category {
+		case BypassPath:
+			// A sibling that does not exist shows what the server says to
+			// any path, such as a catch-all 200
+			control := &bypassAttempt{method: "GET", url: root + parent + randomWord() + query}
+			variants := []struct{ technique, path string }{
+				{"uppercase", parent + strings.ToUpper(segment)},
+				{"capitalized", parent + capitalize(segment)},
+				{"trailing-slash", trimmed + "/"},
+				{"trailing-dot", trimmed + "/."},
+				{"trailing-dotdot-semicolon", trimmed + "..;/"},
+				{"trailing-semicolon", trimmed + ";"},
+				{"trailing-space", trimmed + "%20"},
+				{"trailing-tab", trimmed + "%09"},
+				{"trailing-encoded-hash", trimmed + "%23"},
+				{"trailing-question", trimmed + "?"},
+				{"json-extension", trimmed + ".json"},
+				{"double-slash", "//" + rest},
+				{"dot-segment", "/./" + rest},
+				{"encoded-dot-segment", "/%2e/" + rest},
+				{"semicolon-segment", "/;/" + rest},
+			}
+			seen := map[string]bool{path: true}
+			for _, v := range variants {
+				// The root has no segment to change, and a "?" would merge
+				// with an existing query
+				if segment == "" || seen[v.path] || strings.HasSuffix(v.path, "?") && query != "" {
+					continue
+				}
+				seen[v.path] = true
+				attempts = append(attempts, &bypassAttempt{
+					technique: v.technique, category: BypassPath,
+					method: "GET", url: root + v.path + query, control: control,
+				})
+			}
+
+		case BypassHeader:
+			// The front end checks the path requested, while the app may
+			// route on one of these headers
+			rootControl := &bypassAttempt{method: "GET", url: root + "/"}
+			for _, header := range []string{"X-Original-URL", "X-Rewrite-URL"} {
+				attempts = append(attempts, &bypassAttempt{
+					technique: strings.ToLower(header), category: BypassHeader,
+					method: "GET", url: root + "/", headers: map[string]string{header: path + query},
+					control: rootControl,
+				})
+			}
+			for _, header := range spoofedIPHeaders {
+				attempts = append(attempts, &bypassAttempt{
+					technique: strings.ToLower(header), category: BypassHeader,
+					method: "GET", url: u.String(), headers: map[string]string{header: "127.0.0.1"},
+				})
+			}
+			attempts = append(attempts, &bypassAttempt{
+				technique: "forwarded", category: BypassHeader,
+				method: "GET", url: u.String(), headers: map[string]string{"Forwarded": "for=127.0.0.1"},
+			})
+
+		case BypassMethod:
+			for _, method := range []string{"HEAD", "POST", "get"} {
+				technique := "method-" + strings.ToLower(method)
+				if method == "get" {
+					technique = "method-lowercase"
+				}
+				attempts = append(attempts, &bypassAttempt{
+					technique: technique, category: BypassMethod,
+					method: method, url: u.String(),
+				})
+			}
+		}
+	}
+	return attempts
+}
+
+// send makes one attempt
+func (s *BypassScanner) send(ctx context.Context, attempt *bypassAttempt) (bypassResponse, bool) {
+	if !s.config.Scope.Allows(attempt.url) {
+		return bypassResponse{}, false
+	}
+	s.limiter.Wait(ctx)
+
+	req, err := s.prober.newRequest(ctx, attempt.method, attempt.url)
+	if err != nil {
+		return bypassResponse{}, false
+	}
+	for name, value := range attempt.headers {
+		req.Header.Set(name, value)
+	}
+	if attempt.method == "POST" {
+		req.Body = io.NopCloser(strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	s.config.Budget.Wait(ctx)
+
+	resp, err := s.prober.client.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Debug("request failed", log.Target(attempt.url), "technique", attempt.technique, log.Err(err))
+		return bypassResponse{}, false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+
+	length := len(body)
+	if attempt.method == "HEAD" && resp.ContentLength > 0 {
+		length = int(resp.ContentLength)
+	}
+	return bypassResponse{status: resp.StatusCode, length: length, location: resp.Header.Get("Location")}, true
+}
+
+// bypassed reports whether a response got past the block: a 2xx, or a
+// redirect somewhere other than the forbidden path itself, which servers
+// send to add or drop a trailing slash
+func bypassed(resp bypassResponse, u *url.URL, attempt *bypassAttempt) bool {
+	switch {
+	case resp.status >= 200 && resp.status < 300:
+		return true
+	case resp.status >= 300 && resp.status < 400:
+		if resp.location == "" {
+			return false
+		}
+		from, err := url.Parse(attempt.url)
+		if err != nil {
+			return false
+		}
+		to, err := from.Parse(resp.location)
+		if err != nil {
+			return false
+		}
+		return to.Host != u.Host || strings.TrimSuffix(to.Path, "/") != strings.TrimSuffix(u.Path, "/")
+	default:
+		return false
+	}
+}
+
+// similarLength reports whether two body lengths are within 5% of each
+// other, as dynamic parts of one page make them
+func similarLength(a, b int) bool {
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= max(a, b)/20
+}
+
+// capitalize upper-cases the first letter of s
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// ParseBypassTechniques validates a list of technique categories
+func ParseBypassTechniques(names []string) ([]string, error) {
+	var categories []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case BypassPath, BypassHeader, BypassMethod:
+			categories = append(categories, name)
+		default:
+			return nil, fmt.Errorf("unknown bypass technique %q (want %s)", name, strings.Join(BypassCategories, ", "))
+		}
+	}
+	return categories, nil
+}
@@ -0,0 +1,310 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// specPaths are common locations for Swagger/OpenAPI documents
+var specPaths = []string{
+	"/swagger.json",
+	"/openapi.json",
+	"/v2/api-docs",
+	"/v3/api-docs",
+	"/api-docs",
+	"/api/swagger.json",
+	"/api/openapi.json",
+	"/swagger/v1/swagger.json",
+	"/swagger-ui",
+	"/swagger-ui.html",
+	"/swagger-ui/index.html",
+}
+
+// specOperationMethods are the path item keys that describe operations
+var specOperationMethods = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"}
+
+// APISpec is the subset of a Swagger 2.0 / OpenAPI 3.x document we use
+type APISpec struct {
+	Swagger  string   `json:"swagger"`
+	OpenAPI  string   `json:"openapi"`
+	Host     string   `json:"host"`
+	BasePath string   `json:"basePath"`
+	Schemes  []string `json:"schemes"`
+	Servers  []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// SpecParameter is a documented operation parameter
+type SpecParameter struct {
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+// specOperation is a single documented operation
+type specOperation struct {
+	Parameters  []SpecParameter `json:"parameters"`
+	RequestBody struct {
+		Content map[string]struct {
+			Schema struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// SpecEndpoint is an endpoint documented in an API spec
+type SpecEndpoint struct {
+	URL    string
+	Method string
+	Params []string
+}
+
+// discoverSpecs probes the job's host for API specs and emits documented endpoints
+func (c *Crawler) discoverSpecs(ctx context.Context, job CrawlJob) {
+	base, err := url.Parse(job.URL)
+	if err != nil || base.Host == "" {
+		return
+	}
+	origin := base.Scheme + "://" + base.Host
+	if !c.markSpecHost(origin) {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range specPaths {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		specURL := origin + path
+		body := c.fetchSpec(ctx, specURL, base)
+		if body == "" {
+			continue
+		}
+
+		// Swagger UI pages reference the real spec location
+		if !looksLikeJSON(body) {
+			ref := swaggerUISpecRef(body, specURL)
+			if ref == "" || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			specURL = ref
+			body = c.fetchSpec(ctx, specURL, base)
+		}
+
+		endpoints, ok := ParseAPISpec([]byte(body), specURL)
+		if !ok {
+			continue
+		}
+		c.graph.addEdge(job.URL, specURL, "spec")
+
+		for _, ep := range endpoints {
+			key := ep.Method + " " + ep.URL
+			if seen[key] || c.urlCount() >= c.config.MaxURLs {
+				continue
+			}
+			seen[key] = true
+
+			// A spec's host or servers may name any system, so its
+			// endpoints pass the gates links do; one the crawl already
+			// found is not reported again
+			if c.config.SameHost && !c.isSameHost(ep.URL, base.Host) {
+				c.assets.record(ep.URL, specURL)
+				continue
+			}
+			c.graph.addEdge(specURL, ep.URL, "spec")
+			if c.config.RespectRobots && !c.robotsAllowed(ctx, ep.URL) {
+				continue
+			}
+			if !c.admit(job, ep.URL) {
+				continue
+			}
+
+			c.results <- CrawlResult{
+				URL:       ep.URL,
+				Source:    "openapi",
+				Depth:     job.Depth,
+				Type:      "api",
+				Method:    ep.Method,
+				Params:    ep.Params,
+				Referrer:  specURL,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+		}
+	}
+}
+
+// fetchSpec fetches a candidate spec through the gates a crawled link
+// passes, returning "" if one stops it or the fetch fails
+func (c *Crawler) fetchSpec(ctx context.Context, specURL string, base *url.URL) string {
+	if c.config.SameHost && !c.isSameHost(specURL, base.Host) {
+		return ""
+	}
+	if c.config.RespectRobots {
+		if !c.robotsAllowed(ctx, specURL) {
+			return ""
+		}
+		c.waitCrawlDelay(ctx, specURL)
+	}
+
+	c.limiter.Wait(ctx)
+	c.waitHost(ctx, hostOf(specURL))
+	return c.fetchBody(ctx, specURL)
+}
+
+// markSpecHost records that a host was probed for specs, returns true if new
+func (c *Crawler) markSpecHost(origin string) bool {
+	c.specHostsMu.Lock()
+	defer c.specHostsMu.Unlock()
+
+	if c.specHosts[origin] {
+		return false
+	}
+	c.specHosts[origin] = true
+	return true
+}
+
+// ParseAPISpec extracts documented endpoints from a Swagger/OpenAPI JSON document
+func ParseAPISpec(data []byte, specURL string) ([]SpecEndpoint, bool) {
+	var spec APISpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, false
+	}
+	if (spec.Swagger == "" && spec.OpenAPI == "") || len(spec.Paths) == 0 {
+		return nil, false
+	}
+
+	base := specBaseURL(spec, specURL)
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var endpoints []SpecEndpoint
+	for _, path := range paths {
+		item := spec.Paths[path]
+
+		// Parameters declared on the path apply to every operation
+		var shared []SpecParameter
+		if raw, ok := item["parameters"]; ok {
+			json.Unmarshal(raw, &shared)
+		}
+
+		for _, method := range specOperationMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+
+			var op specOperation
+			json.Unmarshal(raw, &op)
+
+			endpoints = append(endpoints, SpecEndpoint{
+				URL:    strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/"),
+				Method: strings.ToUpper(method),
+				Params: operationParams(shared, op),
+			})
+		}
+	}
+
+	return endpoints, true
+}
+
+// specBaseURL works out the API base URL declared by the spec
+func specBaseURL(spec APISpec, specURL string) string {
+	ref, err := url.Parse(specURL)
+	if err != nil {
+		return ""
+	}
+
+	// OpenAPI 3.x servers may be relative to the spec location
+	if len(spec.Servers) > 0 && spec.Servers[0].URL != "" {
+		if server, err := url.Parse(spec.Servers[0].URL); err == nil {
+			return ref.ResolveReference(server).String()
+		}
+	}
+
+	// Swagger 2.0 host/basePath/schemes
+	scheme := ref.Scheme
+	if len(spec.Schemes) > 0 {
+		scheme = spec.Schemes[0]
+	}
+	host := ref.Host
+	if spec.Host != "" {
+		host = spec.Host
+	}
+	return scheme + "://" + host + spec.BasePath
+}
+
+// operationParams merges path-level and operation parameter names
+func operationParams(shared []SpecParameter, op specOperation) []string {
+	var params []string
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			params = append(params, name)
+		}
+	}
+
+	for _, p := range shared {
+		add(p.Name)
+	}
+	for _, p := range op.Parameters {
+		add(p.Name)
+	}
+
+	// OpenAPI 3.x request bodies
+	var bodyParams []string
+	for _, content := range op.RequestBody.Content {
+		for name := range content.Schema.Properties {
+			bodyParams = append(bodyParams, name)
+		}
+	}
+	sort.Strings(bodyParams)
+	for _, name := range bodyParams {
+		add(name)
+	}
+
+	return params
+}
+
+// swaggerUISpecPattern matches the spec URL in a Swagger UI page's config
+var swaggerUISpecPattern = regexp.MustCompile(`(?i)(?:url|configUrl)\s*[:=]\s*["']([^"']+\.(?:json)|[^"']*api-docs[^"']*)["']`)
+
+// swaggerUISpecRef finds the spec URL configured in a Swagger UI page
+func swaggerUISpecRef(body, pageURL string) string {
+	match := swaggerUISpecPattern.FindStringSubmatch(body)
+	if len(match) < 2 {
+		return ""
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(match[1])
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// looksLikeJSON reports whether the body appears to be a JSON document
+func looksLikeJSON(body string) bool {
+	trimmed := strings.TrimSpace(body)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
@@ -0,0 +1,123 @@
+package httpx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BrowserProfile is the User-Agent and matching headers one client sends.
+// Bot detection compares them, so a Chrome User-Agent without Chrome's
+// client hints, or with Firefox's Accept, gets a request blocked.
+//
+// Profiles leave out Accept-Encoding: the prober's transport asks for
+// gzip, deflate and brotli itself and decodes the responses.
+type BrowserProfile struct {
+	Name      string
+	UserAgent string
+	Headers   map[string]string
+}
+
+// Headers a browser sends when navigating to a page typed in the address
+// bar
+var navigationHeaders = map[string]string{
+	"Upgrade-Insecure-Requests": "1",
+	"Sec-Fetch-Dest":            "document",
+	"Sec-Fetch-Mode":            "navigate",
+	"Sec-Fetch-Site":            "none",
+	"Sec-Fetch-User":            "?1",
+}
+
+const (
+	chromeAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	chromeBrands  = `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`
+	firefoxAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+)
+
+// chromeProfile returns Chrome 120's headers on a platform, as named by
+// its sec-ch-ua-platform client hint
+func chromeProfile(name, userAgent, platform string, mobile bool) BrowserProfile {
+	headers := withNavigation(map[string]string{
+		"Accept":             chromeAccept,
+		"Accept-Language":    "en-US,en;q=0.9",
+		"sec-ch-ua":          chromeBrands,
+		"sec-ch-ua-mobile":   "?0",
+		"sec-ch-ua-platform": `"` + platform + `"`,
+	})
+	if mobile {
+		headers["sec-ch-ua-mobile"] = "?1"
+	}
+	return BrowserProfile{Name: name, UserAgent: userAgent, Headers: headers}
+}
+
+// firefoxProfile returns Firefox 121's headers; Firefox sends no client
+// hints
+func firefoxProfile(name, userAgent string) BrowserProfile {
+	return BrowserProfile{Name: name, UserAgent: userAgent, Headers: withNavigation(map[string]string{
+		"Accept":          firefoxAccept,
+		"Accept-Language": "en-US,en;q=0.5",
+	})}
+}
+
+// withNavigation adds navigationHeaders to headers
+func withNavigation(headers map[string]string) map[string]string {
+	for name, value := range navigationHeaders {
+		headers[name] = value
+	}
+	return headers
+}
+
+// browserProfiles are the preset profile sets, by name
+var browserProfiles = map[string][]BrowserProfile{
+	"chrome": {
+		chromeProfile("chrome-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Windows", false),
+		chromeProfile("chrome-macos", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "macOS", false),
+		chromeProfile("chrome-linux", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Linux", false),
+	},
+	"firefox": {
+		firefoxProfile("firefox-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"),
+		firefoxProfile("firefox-macos", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0"),
+		firefoxProfile("firefox-linux", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"),
+	},
+	"mobile": {
+		chromeProfile("chrome-android", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Android", true),
+		{
+			Name:      "safari-ios",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			Headers: map[string]string{
+				"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+				"Accept-Language": "en-US,en;q=0.9",
+				"Sec-Fetch-Dest":  "document",
+				"Sec-Fetch-Mode":  "navigate",
+				"Sec-Fetch-Site":  "none",
+			},
+		},
+	},
+	"curl": {
+		{Name: "curl", UserAgent: "curl/8.5.0", Headers: map[string]string{"Accept": "*/*"}},
+	},
+}
+
+// BrowserProfiles returns the profiles of the named presets (chrome,
+// firefox, mobile, curl), in order, for rotating between
+func BrowserProfiles(names ...string) ([]BrowserProfile, error) {
+	var profiles []BrowserProfile
+	for _, name := range names {
+		preset, ok := browserProfiles[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown browser profile %q (want %s)", name, strings.Join(BrowserProfileNames(), ", "))
+		}
+		profiles = append(profiles, preset...)
+	}
+	return profiles, nil
+}
+
+// BrowserProfileNames returns the names of the preset profiles
+func BrowserProfileNames() []string {
+	names := make([]string, 0, len(browserProfiles))
+	for name := range browserProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
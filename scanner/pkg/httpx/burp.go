@@ -0,0 +1,211 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// BurpItem is one request from a Burp Suite site map or proxy history
+// export, with its response if Burp had one
+type BurpItem struct {
+	URL      string
+	Method   string
+	Response []byte // raw HTTP response, status line and headers included
+}
+
+// burpItems is the layout of a Burp Suite "Save items" XML export
+type burpItems struct {
+	Items []struct {
+		URL      string `xml:"url"`
+		Method   string `xml:"method"`
+		Response struct {
+			Base64 bool   `xml:"base64,attr"`
+			Data   string `xml:",chardata"`
+		} `xml:"response"`
+	} `xml:"item"`
+}
+
+// burpJSONItem is one item of a JSON export, as written by Burp's REST
+// API and export extensions. Requests and responses are raw HTTP or
+// base64; the URL may be given whole or in parts.
+type burpJSONItem struct {
+	URL      string          `json:"url"`
+	Protocol string          `json:"protocol"`
+	Host     string          `json:"host"`
+	Port     json.RawMessage `json:"port"`
+	Path     string          `json:"path"`
+	Method   string          `json:"method"`
+	Response string          `json:"response"`
+}
+
+// LoadBurp reads a Burp Suite export: an XML "Save items" file of the site
+// map or proxy history, or a JSON array, object with an "items" array, or
+// one item per line
+func LoadBurp(path string) ([]BurpItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []BurpItem
+	if head := bytes.TrimSpace(data[:min(len(data), 4096)]); bytes.HasPrefix(head, []byte("<")) {
+		items, err = parseBurpXML(data)
+	} else {
+		items, err = parseBurpJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return items, nil
+}
+
+// parseBurpXML reads the items of an XML export
+func parseBurpXML(data []byte) ([]BurpItem, error) {
+	var export burpItems
+	if err := xml.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("parsing Burp export: %w", err)
+	}
+
+	items := make([]BurpItem, 0, len(export.Items))
+	for _, item := range export.Items {
+		raw := []byte(item.Response.Data)
+		if item.Response.Base64 && len(bytes.TrimSpace(raw)) > 0 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(item.Response.Data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: decoding response: %w", item.URL, err)
+			}
+			raw = decoded
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			raw = nil
+		}
+		items = append(items, BurpItem{URL: item.URL, Method: item.Method, Response: raw})
+	}
+	return items, nil
+}
+
+// parseBurpJSON reads the items of a JSON export
+func parseBurpJSON(data []byte) ([]BurpItem, error) {
+	var raw []burpJSONItem
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var wrapped struct {
+			Items []burpJSONItem `json:"items"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Items != nil {
+			raw = wrapped.Items
+		} else {
+			raw = nil
+			for _, line := range bytes.Split(data, []byte("\n")) {
+				if len(bytes.TrimSpace(line)) == 0 {
+					continue
+				}
+				var item burpJSONItem
+				if err := json.Unmarshal(line, &item); err != nil {
+					return nil, fmt.Errorf("parsing Burp export: %w", err)
+				}
+				raw = append(raw, item)
+			}
+		}
+	}
+
+	items := make([]BurpItem, 0, len(raw))
+	for _, item := range raw {
+		url := item.URL
+		if url == "" && item.Host != "" {
+			url = burpURL(item)
+		}
+		if url == "" {
+			return nil, fmt.Errorf("parsing Burp export: item without a URL")
+		}
+
+		response := []byte(item.Response)
+		if item.Response != "" && !strings.HasPrefix(item.Response, "HTTP/") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(item.Response))
+			if err != nil {
+				return nil, fmt.Errorf("%s: decoding response: %w", url, err)
+			}
+			response = decoded
+		}
+		if len(bytes.TrimSpace(response)) == 0 {
+			response = nil
+		}
+		items = append(items, BurpItem{URL: url, Method: item.Method, Response: response})
+	}
+	return items, nil
+}
+
+// burpURL joins an item's URL parts, leaving out the scheme's default port
+func burpURL(item burpJSONItem) string {
+	scheme := strings.ToLower(item.Protocol)
+	if scheme == "" {
+		scheme = "https"
+	}
+	host := item.Host
+	if port := strings.Trim(string(item.Port), `"`); port != "" && port != "null" &&
+		!(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	path := item.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// BurpURLs returns each URL of an export once, in export order
+func BurpURLs(items []BurpItem) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item.URL] {
+			seen[item.URL] = true
+			urls = append(urls, item.URL)
+		}
+	}
+	return urls
+}
+
+// BurpResponses returns the responses of an export as probe results, with
+// bodies, so a crawl can parse them without requesting the pages again.
+// Only responses to GET requests are kept, since the crawler would GET
+// the URL; responses that do not parse are skipped.
+func BurpResponses(items []BurpItem) []ProbeResult {
+	var results []ProbeResult
+	for _, item := range items {
+		if item.Response == nil || (item.Method != "" && !strings.EqualFold(item.Method, http.MethodGet)) {
+			continue
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(item.Response)), nil)
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSavedBody))
+		resp.Body.Close()
+
+		headers := make(map[string]string)
+		for key, values := range resp.Header {
+			headers[key] = strings.Join(values, ", ")
+		}
+		results = append(results, ProbeResult{
+			URL:           item.URL,
+			StatusCode:    resp.StatusCode,
+			ContentLength: int64(len(body)),
+			ContentType:   resp.Header.Get("Content-Type"),
+			Title:         extractTitle(string(body)),
+			Server:        resp.Header.Get("Server"),
+			Headers:       headers,
+			Body:          string(body),
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return results
+}
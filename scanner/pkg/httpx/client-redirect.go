@@ -0,0 +1,120 @@
+package httpx
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Client-side redirect kinds, as in ProbeResult.ClientRedirectType
+const (
+	RedirectMetaRefresh = "meta-refresh"
+	RedirectJavaScript  = "javascript"
+)
+
+// maxJSRedirectBody is the largest page a script's location assignment is
+// taken as a redirect on. Parked, interstitial and WAF pages are short;
+// on a full page the assignment is likely behind a click handler.
+const maxJSRedirectBody = 8 * 1024
+
+var (
+	metaTagPattern     = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	metaRefreshPattern = regexp.MustCompile(`(?i)http-equiv\s*=\s*["']?refresh\b`)
+	metaContentPattern = regexp.MustCompile(`(?is)\bcontent\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	refreshURLPattern  = regexp.MustCompile(`(?i)^\s*\d*(?:\.\d*)?\s*[;,]\s*(?:url\s*=\s*)?["']?([^"']+)`)
+	scriptPattern      = regexp.MustCompile(`(?is)<script\b[^>]*>(.*?)</script>`)
+	jsLocationPattern  = regexp.MustCompile(`(?:\b(?:window|document|top|self|parent)\.)?\blocation(?:\.href)?\s*=\s*["']([^"']+)["']|\blocation\.(?:replace|assign)\(\s*["']([^"']+)["']\s*\)`)
+)
+
+// clientRedirect returns where a page redirects with a meta refresh or a
+// script setting the location, resolved against base, and which of the two
+// it uses. It returns empty strings if the page does neither.
+func clientRedirect(base *url.URL, body string) (string, string) {
+	for _, tag := range metaTagPattern.FindAllString(body, -1) {
+		if !metaRefreshPattern.MatchString(tag) {
+			continue
+		}
+		content := metaContentPattern.FindStringSubmatch(tag)
+		if content == nil {
+			continue
+		}
+		value := content[1] + content[2] + content[3]
+		if m := refreshURLPattern.FindStringSubmatch(value); m != nil {
+			if target := resolveRedirect(base, m[1]); target != "" {
+				return target, RedirectMetaRefresh
+			}
+		}
+	}
+
+	if len(body) > maxJSRedirectBody {
+		return "", ""
+	}
+	for _, script := range scriptPattern.FindAllStringSubmatch(body, -1) {
+		for _, m := range jsLocationPattern.FindAllStringSubmatch(script[1], -1) {
+			if target := resolveRedirect(base, m[1]+m[2]); target != "" {
+				return target, RedirectJavaScript
+			}
+		}
+	}
+	return "", ""
+}
+
+// resolveRedirect resolves a redirect target against base, returning ""
+// for targets that lead nowhere new: fragments, javascript: URLs, other
+// schemes and the page itself
+func resolveRedirect(base *url.URL, target string) string {
+	target = strings.TrimSpace(target)
+	if target == "" || strings.HasPrefix(target, "#") {
+		return ""
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	resolved.Fragment = ""
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	if resolved.String() == base.String() {
+		return ""
+	}
+	return resolved.String()
+}
+
+// followClientRedirects follows the meta refresh and script redirects of
+// a probed page, up to MaxRedirects of them and within scope, returning
+// the last page reached under the original URL, with FinalURL set to
+// where it was found
+func (p *Prober) followClientRedirects(ctx context.Context, result ProbeResult) ProbeResult {
+	visited := map[string]bool{result.URL: true, result.FinalURL: true}
+	for hops := 0; hops < p.config.MaxRedirects && result.ClientRedirect != ""; hops++ {
+		target := result.ClientRedirect
+		if visited[target] || !p.config.Scope.Allows(target) {
+			break
+		}
+		visited[target] = true
+
+		p.wait(ctx)
+		next, _ := p.Fetch(ctx, target)
+		if next.StatusCode == 0 {
+			break
+		}
+		if next.FinalURL == "" {
+			next.FinalURL = target
+		}
+		visited[next.FinalURL] = true
+		from := result.URL
+		if result.FinalURL != "" {
+			from = result.FinalURL
+		}
+		hop := RedirectHop{URL: from, StatusCode: result.StatusCode, Location: target, Type: result.ClientRedirectType}
+		next.RedirectChain = append(append(result.RedirectChain, hop), next.RedirectChain...)
+		next.URL = result.URL
+		next.Redirected = true
+		next.ResponseTime += result.ResponseTime
+		result = next
+	}
+	return result
+}
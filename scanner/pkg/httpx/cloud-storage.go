@@ -0,0 +1,205 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// Cloud storage providers
+const (
+	StorageS3    = "s3"
+	StorageGCS   = "gcs"
+	StorageAzure = "azure"
+)
+
+// What an anonymous request finds at a bucket
+const (
+	// StorageListable is a bucket anyone can list
+	StorageListable = "listable"
+
+	// StoragePrivate is a bucket that exists but refuses anonymous listing
+	StoragePrivate = "private"
+
+	// StorageMissing is a bucket or account that does not exist, so
+	// anyone could create it and serve the pages that reference it
+	StorageMissing = "missing"
+)
+
+// CloudStorage is an S3, Google Cloud Storage or Azure Blob Storage
+// bucket a response references
+type CloudStorage struct {
+	Provider string `json:"provider"`
+	Bucket   string `json:"bucket"` // account/container for Azure
+	URL      string `json:"url"`
+
+	// Access is what Prober.CheckStorage found, when it was checked
+	Access string `json:"access,omitempty"`
+
+	// FoundOn are the pages referencing the bucket, in StorageBuckets
+	FoundOn []string `json:"found_on,omitempty"`
+}
+
+// storagePrefix keeps a pattern from starting inside a longer host name
+const storagePrefix = `(?:^|[^a-z0-9.-])`
+
+// S3 buckets, virtual-hosted (bucket.s3.region.amazonaws.com), path-style
+// (s3.region.amazonaws.com/bucket) or as an s3:// URI
+var s3Pattern = regexp.MustCompile(`(?i)` + storagePrefix + `(?:(?:([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])\.)?s3[a-z0-9.-]*?\.amazonaws\.com(?:/([a-z0-9][a-z0-9.-]{1,61}[a-z0-9]))?|s3://([a-z0-9][a-z0-9.-]{1,61}[a-z0-9]))`)
+
+// Google Cloud Storage buckets, by XML or JSON API host or path, the
+// console's authenticated URLs, Firebase Storage or as a gs:// URI
+var gcsPattern = regexp.MustCompile(`(?i)` + storagePrefix + `(?:([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])\.storage\.googleapis\.com|storage\.googleapis\.com/(?:(?:upload|download)/)?storage/v1/b/([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])|(?:storage\.googleapis|storage\.cloud\.google)\.com/([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])|firebasestorage\.googleapis\.com/v0/b/([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])|gs://([a-z0-9][a-z0-9._-]{1,220}[a-z0-9]))`)
+
+// Azure storage accounts, with the container when the URL names one
+var azurePattern = regexp.MustCompile(`(?i)` + storagePrefix + `([a-z0-9]{3,24})\.blob\.core\.windows\.net(?:/([a-z0-9](?:[a-z0-9-]{1,61}[a-z0-9])?|\$root|\$web)\b)?`)
+
+// gcsReserved are path segments of the storage host that are API
+// prefixes, not buckets
+var gcsReserved = map[string]bool{"storage": true, "upload": true, "download": true, "batch": true}
+
+// extractCloudStorage finds the storage buckets a body references, once each
+func extractCloudStorage(body string) []CloudStorage {
+	var buckets []CloudStorage
+	seen := make(map[string]bool)
+	add := func(provider, bucket string) {
+		bucket = strings.ToLower(bucket)
+		if bucket == "" || seen[provider+"/"+bucket] {
+			return
+		}
+		seen[provider+"/"+bucket] = true
+		buckets = append(buckets, CloudStorage{Provider: provider, Bucket: bucket, URL: storageURL(provider, bucket)})
+	}
+
+	for _, m := range s3Pattern.FindAllStringSubmatch(body, -1) {
+		add(StorageS3, firstNonEmpty(m[1], m[2], m[3]))
+	}
+	for _, m := range gcsPattern.FindAllStringSubmatch(body, -1) {
+		bucket := firstNonEmpty(m[1:]...)
+		if !gcsReserved[strings.ToLower(bucket)] {
+			add(StorageGCS, bucket)
+		}
+	}
+	for _, m := range azurePattern.FindAllStringSubmatch(body, -1) {
+		if m[2] != "" {
+			add(StorageAzure, m[1]+"/"+m[2])
+		} else {
+			add(StorageAzure, m[1])
+		}
+	}
+	return buckets
+}
+
+// storageURL is the address of a bucket's listing. S3 buckets with dots
+// are addressed path-style, as their virtual host fails TLS checks.
+func storageURL(provider, bucket string) string {
+	switch provider {
+	case StorageS3:
+		if strings.Contains(bucket, ".") {
+			return "https://s3.amazonaws.com/" + bucket + "/"
+		}
+		return "https://" + bucket + ".s3.amazonaws.com/"
+	case StorageGCS:
+		return "https://storage.googleapis.com/" + bucket + "/"
+	case StorageAzure:
+		account, container, _ := strings.Cut(bucket, "/")
+		if container == "" {
+			return "https://" + account + ".blob.core.windows.net/"
+		}
+		return "https://" + account + ".blob.core.windows.net/" + container + "?restype=container&comp=list"
+	}
+	return ""
+}
+
+// StorageBuckets merges the buckets of analyzed pages into one inventory,
+// sorted by provider and bucket, with the pages each was found on
+func StorageBuckets(results []AnalysisResult) []CloudStorage {
+	index := make(map[string]int)
+	var buckets []CloudStorage
+	for _, result := range results {
+		for _, bucket := range result.Storage {
+			key := bucket.Provider + "/" + bucket.Bucket
+			i, ok := index[key]
+			if !ok {
+				i = len(buckets)
+				index[key] = i
+				bucket.FoundOn = nil
+				buckets = append(buckets, bucket)
+			}
+			if buckets[i].Access == "" {
+				buckets[i].Access = bucket.Access
+			}
+			buckets[i].FoundOn = appendSample(buckets[i].FoundOn, result.URL)
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Provider != buckets[j].Provider {
+			return buckets[i].Provider < buckets[j].Provider
+		}
+		return buckets[i].Bucket < buckets[j].Bucket
+	})
+	return buckets
+}
+
+// firstNonEmpty returns the first of values that is set
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// storageErrorCode matches the error code of S3, GCS and Azure XML errors
+var storageErrorCode = regexp.MustCompile(`<Code>([A-Za-z]+)</Code>`)
+
+// CheckStorage asks, without credentials, for a bucket's listing and
+// returns whether it is listable, private or missing. An Azure account
+// without a container in the URL can only be found missing; otherwise its
+// access is empty.
+func (p *Prober) CheckStorage(ctx context.Context, bucket CloudStorage) (string, error) {
+	if bucket.Provider == StorageAzure {
+		account, _, _ := strings.Cut(bucket.Bucket, "/")
+		var dnsErr *net.DNSError
+		if _, err := net.DefaultResolver.LookupHost(ctx, account+".blob.core.windows.net"); errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return StorageMissing, nil
+		}
+		if !strings.Contains(bucket.Bucket, "/") {
+			return "", nil
+		}
+	}
+
+	result, body := p.Fetch(ctx, bucket.URL)
+	if result.StatusCode == 0 {
+		return "", fmt.Errorf("%s: no response", bucket.URL)
+	}
+	var code string
+	if m := storageErrorCode.FindStringSubmatch(body); m != nil {
+		code = m[1]
+	}
+
+	access := ""
+	switch {
+	case result.StatusCode == http.StatusOK && (strings.Contains(body, "<ListBucketResult") || strings.Contains(body, "<EnumerationResults")):
+		access = StorageListable
+	case code == "NoSuchBucket" || code == "ContainerNotFound":
+		access = StorageMissing
+	case result.StatusCode == http.StatusUnauthorized || result.StatusCode == http.StatusForbidden:
+		access = StoragePrivate
+	case bucket.Provider == StorageAzure && result.StatusCode == http.StatusNotFound:
+		// Azure hides private containers behind ResourceNotFound
+		access = StoragePrivate
+	default:
+		return "", fmt.Errorf("%s: unexpected %d %s", bucket.URL, result.StatusCode, code)
+	}
+	log.FromContext(ctx).Debug("storage checked", log.Target(bucket.URL), "access", access)
+	return access, nil
+}
@@ -0,0 +1,353 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// frontier is the unbounded job queue of a concurrent crawl. A job is
+// pending from when it is pushed until the worker that popped it calls
+// done, after it has pushed the links it found, so the crawl is over
+// exactly when nothing is pending.
+type frontier struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []CrawlJob
+	disk    *frontierFile // if set, holds the queue instead of queue
+	pending int
+	closed  bool
+	stopped bool // closed early, by close
+	err     error
+}
+
+// newFrontier creates a frontier holding one pending placeholder, so it
+// cannot finish before the seeds are pushed; release it with seeded
+func newFrontier() *frontier {
+	f := &frontier{pending: 1}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// openFrontier creates a frontier queued in the file at path, resuming the
+// jobs an earlier crawl left there. replay is called with every job the
+// file records, crawled or not, so they can be marked seen again. It
+// returns the number of jobs still queued.
+func openFrontier(path string, replay func(CrawlJob)) (*frontier, int, error) {
+	disk, queued, err := openFrontierFile(path, replay)
+	if err != nil {
+		return nil, 0, err
+	}
+	f := newFrontier()
+	f.disk = disk
+	f.pending += queued
+	return f, queued, nil
+}
+
+// push queues a job, reporting false once the frontier is closed or the
+// job could not be written to disk
+func (f *frontier) push(job CrawlJob) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	if f.disk != nil {
+		if err := f.disk.append(job); err != nil {
+			if f.err == nil {
+				f.err = err
+			}
+			return false
+		}
+	} else {
+		f.queue = append(f.queue, job)
+	}
+	f.pending++
+	f.cond.Signal()
+	return true
+}
+
+// empty reports whether no job is queued
+func (f *frontier) empty() bool {
+	if f.disk != nil {
+		return f.disk.empty()
+	}
+	return len(f.queue) == 0
+}
+
+// pop blocks until a job is queued or the frontier is closed, reporting
+// false in the latter case
+func (f *frontier) pop() (CrawlJob, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.empty() && !f.closed {
+		f.cond.Wait()
+	}
+	if f.closed {
+		return CrawlJob{}, false
+	}
+	if f.disk != nil {
+		job, err := f.disk.next()
+		if err != nil {
+			// The queue cannot be read past this point, so the crawl ends
+			// here and is resumed from the last committed job
+			if f.err == nil {
+				f.err = err
+			}
+			f.closed = true
+			f.cond.Broadcast()
+			return CrawlJob{}, false
+		}
+		return job, true
+	}
+	job := f.queue[0]
+	f.queue[0] = CrawlJob{}
+	f.queue = f.queue[1:]
+	return job, true
+}
+
+// done marks a popped job finished, closing the frontier when it was the
+// last pending one. Jobs finishing after the frontier was closed early may
+// have been cut short, so a disk frontier keeps them to be crawled again.
+func (f *frontier) done(job CrawlJob) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.disk != nil && !f.closed {
+		f.disk.done(job.offset)
+	}
+	f.release()
+}
+
+// seeded releases the placeholder once the seeds are pushed
+func (f *frontier) seeded() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.release()
+}
+
+// release drops one pending job; f.mu must be held
+func (f *frontier) release() {
+	f.pending--
+	if f.pending == 0 {
+		f.closed = true
+		f.cond.Broadcast()
+	}
+}
+
+// close stops the crawl early: queued jobs are dropped and workers waiting
+// in pop return
+func (f *frontier) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.stopped = true
+	f.queue = nil
+	f.cond.Broadcast()
+}
+
+// closeOnDone closes the frontier if ctx is cancelled before stop is
+// closed
+func (f *frontier) closeOnDone(ctx context.Context, stop <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+		f.close()
+	case <-stop:
+	}
+}
+
+// finish releases the frontier's file once every worker has returned,
+// removing it if the crawl ran out of jobs and keeping it to resume from
+// otherwise. It returns the first error writing or reading the queue.
+func (f *frontier) finish() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.disk != nil {
+		var err error
+		if f.pending == 0 && !f.stopped && f.err == nil {
+			err = f.disk.remove()
+		} else {
+			err = f.disk.close()
+		}
+		if f.err == nil {
+			f.err = err
+		}
+	}
+	return f.err
+}
+
+// frontierCommitEvery is how many finished jobs a disk frontier records
+// its position after; a crash crawls at most this many jobs (plus those
+// in flight) again
+const frontierCommitEvery = 64
+
+// frontierFile is a frontier queue kept in an append-only file of JSON
+// jobs, one per line, so memory stays flat however many URLs are queued.
+// The offset of the first job not yet finished is recorded in a
+// ".offset" file beside it, from which a crawl that crashed or was
+// interrupted resumes.
+type frontierFile struct {
+	path     string
+	writer   *os.File
+	reader   *os.File
+	buf      *bufio.Reader
+	size     int64              // bytes of whole jobs in the file
+	read     int64              // offset of the next job to pop
+	inFlight map[int64]struct{} // offsets of popped jobs not yet done
+	finished int                // jobs done since the last commit
+}
+
+// openFrontierFile opens or creates the queue file at path. Jobs before
+// the recorded offset were crawled; those after it are queued again. A job
+// torn by a crash while being written is dropped.
+func openFrontierFile(path string, replay func(CrawlJob)) (*frontierFile, int, error) {
+	writer, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+	q := &frontierFile{path: path, writer: writer, inFlight: make(map[int64]struct{})}
+
+	if data, err := os.ReadFile(q.offsetPath()); err == nil {
+		q.read, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	queued := 0
+	buf := bufio.NewReader(writer)
+	for {
+		line, err := buf.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writer.Close()
+			return nil, 0, err
+		}
+		var job CrawlJob
+		if json.Unmarshal(line, &job) == nil {
+			replay(job)
+		}
+		if q.size >= q.read {
+			queued++
+		}
+		q.size += int64(len(line))
+	}
+	if q.read > q.size {
+		q.read = q.size
+	}
+	if err := writer.Truncate(q.size); err != nil {
+		writer.Close()
+		return nil, 0, err
+	}
+	if _, err := writer.Seek(q.size, io.SeekStart); err != nil {
+		writer.Close()
+		return nil, 0, err
+	}
+
+	q.reader, err = os.Open(path)
+	if err == nil {
+		_, err = q.reader.Seek(q.read, io.SeekStart)
+	}
+	if err != nil {
+		writer.Close()
+		return nil, 0, err
+	}
+	q.buf = bufio.NewReader(q.reader)
+	return q, queued, nil
+}
+
+// offsetPath is where the position of the first unfinished job is kept
+func (q *frontierFile) offsetPath() string {
+	return q.path + ".offset"
+}
+
+// append writes a job to the end of the queue
+func (q *frontierFile) append(job CrawlJob) error {
+	line, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	n, err := q.writer.Write(line)
+	if err != nil {
+		// Cut off whatever part of the job was written
+		q.writer.Truncate(q.size)
+		q.writer.Seek(q.size, io.SeekStart)
+		return err
+	}
+	q.size += int64(n)
+	return nil
+}
+
+// empty reports whether every written job has been popped
+func (q *frontierFile) empty() bool {
+	return q.read >= q.size
+}
+
+// next reads the job at the front of the queue
+func (q *frontierFile) next() (CrawlJob, error) {
+	line, err := q.buf.ReadBytes('\n')
+	if err != nil {
+		return CrawlJob{}, err
+	}
+	var job CrawlJob
+	if err := json.Unmarshal(bytes.TrimSpace(line), &job); err != nil {
+		return CrawlJob{}, err
+	}
+	job.offset = q.read
+	q.inFlight[job.offset] = struct{}{}
+	q.read += int64(len(line))
+	return job, nil
+}
+
+// done marks the job at offset finished, committing the queue's position
+// every frontierCommitEvery jobs
+func (q *frontierFile) done(offset int64) {
+	delete(q.inFlight, offset)
+	q.finished++
+	if q.finished >= frontierCommitEvery {
+		q.commit()
+	}
+}
+
+// commit records the offset of the first job not yet finished: the
+// earliest one in flight, or else the next to pop
+func (q *frontierFile) commit() error {
+	q.finished = 0
+	offset := q.read
+	for o := range q.inFlight {
+		offset = min(offset, o)
+	}
+	tmp := q.offsetPath() + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.offsetPath())
+}
+
+// close commits the queue's position and closes the file, leaving it to
+// resume from
+func (q *frontierFile) close() error {
+	err := q.commit()
+	q.reader.Close()
+	if closeErr := q.writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// remove deletes a queue whose every job was crawled
+func (q *frontierFile) remove() error {
+	q.reader.Close()
+	q.writer.Close()
+	err := os.Remove(q.path)
+	if offsetErr := os.Remove(q.offsetPath()); !errors.Is(offsetErr, os.ErrNotExist) && err == nil {
+		err = offsetErr
+	}
+	return err
+}
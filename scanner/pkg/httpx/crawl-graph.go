@@ -0,0 +1,255 @@
+package httpx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Graph output formats
+const (
+	GraphFormatJSON    = "json"
+	GraphFormatDOT     = "dot"
+	GraphFormatGraphML = "graphml"
+)
+
+// CrawlEdge records a link from one page to another
+type CrawlEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"` // link, js, form, spec
+}
+
+// CrawlGraph holds crawled URLs and the links between them
+type CrawlGraph struct {
+	Nodes []CrawlResult `json:"nodes"`
+	Edges []CrawlEdge   `json:"edges"`
+
+	edgeSeen map[string]bool
+	mu       sync.Mutex
+}
+
+// newCrawlGraph creates an empty crawl graph
+func newCrawlGraph() *CrawlGraph {
+	return &CrawlGraph{
+		edgeSeen: make(map[string]bool),
+	}
+}
+
+// addEdge records a unique edge between two URLs
+func (g *CrawlGraph) addEdge(from, to, edgeType string) {
+	if from == "" || to == "" {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := from + "\x00" + to + "\x00" + edgeType
+	if g.edgeSeen[key] {
+		return
+	}
+	g.edgeSeen[key] = true
+	g.Edges = append(g.Edges, CrawlEdge{From: from, To: to, Type: edgeType})
+}
+
+// setNodes stores the crawl results as graph nodes
+func (g *CrawlGraph) setNodes(nodes []CrawlResult) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.Nodes = nodes
+}
+
+// Adjacency returns the graph as a map of URL to linked URLs
+func (g *CrawlGraph) Adjacency() map[string][]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.adjacency()
+}
+
+// adjacency builds Adjacency's map. The caller holds mu.
+func (g *CrawlGraph) adjacency() map[string][]string {
+	adjacency := make(map[string][]string)
+	for _, node := range g.Nodes {
+		if _, ok := adjacency[node.URL]; !ok {
+			adjacency[node.URL] = []string{}
+		}
+	}
+	for _, edge := range g.Edges {
+		adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+	}
+
+	return adjacency
+}
+
+// EntryPoints returns crawled URLs that no other crawled page links to
+func (g *CrawlGraph) EntryPoints() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.entryPoints()
+}
+
+// entryPoints lists EntryPoints' URLs. The caller holds mu.
+func (g *CrawlGraph) entryPoints() []string {
+	linked := make(map[string]bool)
+	for _, edge := range g.Edges {
+		if edge.From != edge.To {
+			linked[edge.To] = true
+		}
+	}
+
+	var entries []string
+	for _, node := range g.Nodes {
+		if !linked[node.URL] {
+			entries = append(entries, node.URL)
+		}
+	}
+	sort.Strings(entries)
+
+	return entries
+}
+
+// Export renders the graph in the given format (json, dot, graphml)
+func (g *CrawlGraph) Export(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case GraphFormatJSON, "":
+		// Snapshot the graph, which a running crawl may still be adding to
+		g.mu.Lock()
+		nodes := append([]CrawlResult(nil), g.Nodes...)
+		edges := append([]CrawlEdge(nil), g.Edges...)
+		adjacency, entryPoints := g.adjacency(), g.entryPoints()
+		g.mu.Unlock()
+
+		return json.MarshalIndent(struct {
+			Nodes       []CrawlResult       `json:"nodes"`
+			Edges       []CrawlEdge         `json:"edges"`
+			Adjacency   map[string][]string `json:"adjacency"`
+			EntryPoints []string            `json:"entry_points,omitempty"`
+		}{
+			Nodes:       nodes,
+			Edges:       edges,
+			Adjacency:   adjacency,
+			EntryPoints: entryPoints,
+		}, "", "  ")
+	case GraphFormatDOT:
+		return g.toDOT(), nil
+	case GraphFormatGraphML:
+		return g.toGraphML()
+	default:
+		return nil, fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// toDOT renders the graph in Graphviz DOT format
+func (g *CrawlGraph) toDOT() []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("digraph crawl {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		label := node.URL
+		if node.Type != "" {
+			label += "\n(" + node.Type + ")"
+		}
+		fmt.Fprintf(&sb, "  %q [label=%q];\n", node.URL, label)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&sb, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Type)
+	}
+
+	sb.WriteString("}\n")
+	return []byte(sb.String())
+}
+
+// graphML document structure
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// toGraphML renders the graph as GraphML XML
+func (g *CrawlGraph) toGraphML() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	doc := graphMLDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "depth", For: "node", AttrName: "depth", AttrType: "int"},
+			{ID: "kind", For: "edge", AttrName: "type", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+
+	// Edges may point at URLs that were never crawled, so declare those too
+	declared := make(map[string]bool)
+	for _, node := range g.Nodes {
+		declared[node.URL] = true
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.URL,
+			Data: []graphMLData{
+				{Key: "type", Value: node.Type},
+				{Key: "depth", Value: fmt.Sprintf("%d", node.Depth)},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		for _, id := range []string{edge.From, edge.To} {
+			if !declared[id] {
+				declared[id] = true
+				doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: id})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.From,
+			Target: edge.To,
+			Data:   []graphMLData{{Key: "kind", Value: edge.Type}},
+		})
+	}
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), output...), nil
+}
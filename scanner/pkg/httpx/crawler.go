@@ -0,0 +1,838 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/extract"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// CrawlConfig holds crawler configuration
+type CrawlConfig struct {
+	StartURLs []string
+	MaxDepth  int
+	MaxURLs   int
+	Workers   int
+	Timeout   int
+	RateLimit int
+	SameHost  bool
+	JSParse   bool
+	UserAgent string
+
+	// SpecDiscovery probes each crawled host for Swagger/OpenAPI documents
+	SpecDiscovery bool
+
+	// DedupPatterns collapses URLs that differ only by IDs, UUIDs, dates,
+	// hashes or page numbers, keeping PatternSamples URLs per pattern
+	DedupPatterns  bool
+	PatternSamples int
+
+	// MaxBodySize caps how many bytes of a response are read for parsing
+	MaxBodySize int64
+
+	// ContentTypes maps content-type substrings to an extraction mode
+	// (html, css, js, json, xml). Responses matching no entry are not parsed.
+	ContentTypes map[string]string
+
+	// Strategy selects crawl ordering: concurrent (default) or bfs
+	Strategy string
+
+	// SeedBudget limits depth and URLs discovered from each start URL so one
+	// large site cannot starve the others; SeedBudgets overrides it per start
+	// URL. Zero fields fall back to MaxDepth and MaxURLs.
+	SeedBudget  SeedBudget
+	SeedBudgets map[string]SeedBudget
+
+	// RespectRobots honors robots.txt Disallow rules and Crawl-delay per host
+	RespectRobots bool
+
+	// PerHostRateLimit (requests/sec) and PerHostConcurrency (in-flight
+	// requests) apply to each host on top of the global RateLimit. Zero
+	// disables the limit.
+	PerHostRateLimit   float64
+	PerHostBurst       int
+	PerHostConcurrency int
+
+	// PerHostAlgorithm is the utils.Rate* algorithm PerHostRateLimit uses
+	// (default token bucket); unknown names fall back to the default
+	PerHostAlgorithm string
+
+	// Headers and Cookies are sent with every request; UserAgents, if set,
+	// are rotated round-robin instead of using UserAgent, and Profiles in
+	// place of both
+	Headers    map[string]string
+	Cookies    map[string]string
+	UserAgents []string
+	Profiles   []BrowserProfile
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// OnResult is called for each result as it is discovered. Calls are
+	// serialized, so the callback does not need to be safe for concurrent use.
+	OnResult func(CrawlResult)
+
+	// Progress, if set, counts crawled URLs against those queued so far
+	// and results found
+	Progress *utils.Progress
+
+	// Budget, if set, is a request rate shared with other modules, applied on
+	// top of RateLimit
+	Budget *utils.Budget
+
+	// Scope, if set, keeps out-of-scope URLs from being fetched or recorded
+	Scope *scope.Scope
+
+	// Seen, if set, remembers which URLs were queued, in place of an exact
+	// set of every URL; a compact one caps memory on very large crawls
+	Seen utils.SeenSet
+
+	// TargetLatency, if set, makes RateLimit adaptive, backing off while
+	// responses are slower than it or failing; see ProbeConfig
+	TargetLatency time.Duration
+
+	// Throttle, if set, slows down and may pause hosts whose responses
+	// look like blocking; see ProbeConfig
+	Throttle *utils.HostThrottle
+
+	// Responses are pages already fetched with their bodies, such as probe
+	// results kept with ProbeConfig.KeepBody. A start URL among them is
+	// parsed from its response instead of being requested again.
+	Responses []ProbeResult
+
+	// FrontierFile, if set, keeps the queue of a concurrent crawl in this
+	// append-only file instead of memory, so memory stays flat however
+	// many URLs are queued. A crawl that crashed or was interrupted picks
+	// up the jobs left in it when run again with the same start URLs; the
+	// file is removed once the crawl runs out of jobs.
+	FrontierFile string
+}
+
+// Crawl strategies
+const (
+	StrategyConcurrent = "concurrent"
+	StrategyBFS        = "bfs"
+)
+
+// SeedBudget holds per-seed crawl limits
+type SeedBudget struct {
+	MaxDepth int
+	MaxURLs  int
+}
+
+// Extraction modes for CrawlConfig.ContentTypes
+const (
+	ExtractHTML = "html" // href/src links, forms and inline JS endpoints
+	ExtractJS   = "js"   // quoted URL strings and API endpoint patterns
+	ExtractJSON = "json" // URL-shaped string values and API endpoint patterns
+	ExtractXML  = "xml"  // href/src attributes and <loc>/<link> elements
+	ExtractCSS  = "css"  // url(...) references and @import rules
+)
+
+// DefaultContentTypes parses HTML, CSS, JavaScript and JSON responses
+func DefaultContentTypes() map[string]string {
+	return map[string]string{
+		"text/html":             ExtractHTML,
+		"application/xhtml+xml": ExtractHTML,
+		"text/css":              ExtractCSS,
+		"javascript":            ExtractJS,
+		"ecmascript":            ExtractJS,
+		"json":                  ExtractJSON,
+	}
+}
+
+// CrawlResult holds discovered URLs
+type CrawlResult struct {
+	URL       string   `json:"url"`
+	Source    string   `json:"source"`
+	Depth     int      `json:"depth"`
+	Type      string   `json:"type"` // page, form, api, js, css
+	Method    string   `json:"method,omitempty"`
+	Params    []string `json:"params,omitempty"`
+	Referrer  string   `json:"referrer,omitempty"` // the page or spec it was found in
+	Timestamp string   `json:"timestamp"`
+}
+
+// Crawler handles web crawling operations
+type Crawler struct {
+	config  CrawlConfig
+	prober  *Prober
+	limiter *rate.Limiter
+	seen    utils.SeenSet
+	seenMu  sync.Mutex
+	results chan CrawlResult
+	graph   *CrawlGraph
+
+	specHosts   map[string]bool
+	specHostsMu sync.Mutex
+
+	patternHits map[string]int
+	seedCounts  map[int]int
+
+	robots *robotsPolicy
+	assets *assetInventory
+
+	hostLimiter  *utils.PerHostRateLimiter
+	hostInFlight *utils.PerHostSemaphore
+
+	// responses are CrawlConfig.Responses by URL and final URL
+	responses map[string]ProbeResult
+}
+
+// DefaultCrawlRateLimit is the requests per second when CrawlConfig.RateLimit is unset
+const DefaultCrawlRateLimit = 50
+
+// NewCrawler creates a new web crawler
+func NewCrawler(config CrawlConfig) *Crawler {
+	if config.MaxDepth == 0 {
+		config.MaxDepth = 3
+	}
+	if config.MaxURLs == 0 {
+		config.MaxURLs = 1000
+	}
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = DefaultCrawlRateLimit
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "ReconCrawler/1.0"
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 1 * 1024 * 1024
+	}
+	if config.ContentTypes == nil {
+		config.ContentTypes = DefaultContentTypes()
+	}
+	if config.DedupPatterns && config.PatternSamples == 0 {
+		config.PatternSamples = 1
+	}
+	if config.Seen == nil {
+		config.Seen, _ = utils.NewSeenSet(utils.SeenExact, 0)
+	}
+
+	probeConfig := ProbeConfig{
+		Workers:        config.Workers,
+		Timeout:        config.Timeout,
+		FollowRedirect: true,
+		MaxRedirects:   3,
+		TLSVerify:      false,
+		UserAgent:      config.UserAgent,
+		UserAgents:     config.UserAgents,
+		Profiles:       config.Profiles,
+		Headers:        config.Headers,
+		Cookies:        config.Cookies,
+		Proxy:          config.Proxy,
+		Scope:          config.Scope,
+		Budget:         config.Budget,
+		MaxBodySize:    config.MaxBodySize,
+		RateLimit:      config.RateLimit,
+		TargetLatency:  config.TargetLatency,
+		Throttle:       config.Throttle,
+
+		// Pages on a host are fetched by at most PerHostConcurrency workers
+		MaxIdleConnsPerHost: config.PerHostConcurrency,
+	}
+
+	crawler := &Crawler{
+		config:  config,
+		prober:  NewProber(probeConfig),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+		seen:    config.Seen,
+		graph:   newCrawlGraph(),
+
+		specHosts:   make(map[string]bool),
+		patternHits: make(map[string]int),
+		seedCounts:  make(map[int]int),
+		robots:      newRobotsPolicy(),
+		assets:      newAssetInventory(),
+		responses:   make(map[string]ProbeResult),
+	}
+	for _, r := range config.Responses {
+		if r.StatusCode == 0 || r.Body == "" {
+			continue
+		}
+		crawler.responses[r.URL] = r
+		if r.FinalURL != "" {
+			crawler.responses[r.FinalURL] = r
+		}
+	}
+
+	if config.PerHostRateLimit > 0 {
+		burst := config.PerHostBurst
+		if burst <= 0 {
+			burst = 1
+		}
+		limiter, err := utils.NewPerHostRateLimiterAlgorithm(config.PerHostAlgorithm, config.PerHostRateLimit, burst)
+		if err != nil {
+			limiter = utils.NewPerHostRateLimiter(config.PerHostRateLimit, burst)
+		}
+		crawler.hostLimiter = limiter
+	}
+	if config.PerHostConcurrency > 0 {
+		crawler.hostInFlight = utils.NewPerHostSemaphore(config.PerHostConcurrency)
+	}
+
+	return crawler
+}
+
+// CrawlJob represents a URL to crawl
+type CrawlJob struct {
+	URL      string
+	Depth    int
+	Seed     int    // index of the start URL this job was discovered from
+	Referrer string // the page that linked to it
+
+	offset int64 // position in a disk frontier
+}
+
+// Crawl starts the crawling process
+func (c *Crawler) Crawl() ([]CrawlResult, error) {
+	return c.CrawlContext(context.Background())
+}
+
+// CrawlContext crawls until done or ctx is cancelled. On cancellation it
+// returns the results collected so far with ctx's error.
+func (c *Crawler) CrawlContext(parent context.Context) ([]CrawlResult, error) {
+	ctx, cancel := context.WithTimeout(log.WithModule(parent, "crawl"), 30*time.Minute)
+	defer cancel()
+	start := time.Now()
+
+	c.results = make(chan CrawlResult, c.config.MaxURLs)
+
+	// Collect results while workers run so emitters never block on a full buffer
+	var results []CrawlResult
+	collected := make(chan struct{})
+	go func() {
+		for result := range c.results {
+			c.config.Progress.Found()
+			metrics.Findings("crawl").Inc()
+			if c.config.OnResult != nil {
+				c.config.OnResult(result)
+			}
+			results = append(results, result)
+		}
+		close(collected)
+	}()
+
+	var err error
+	if c.config.Strategy == StrategyBFS {
+		c.crawlBFS(ctx)
+	} else {
+		err = c.crawlConcurrent(ctx)
+	}
+
+	close(c.results)
+	<-collected
+	c.graph.setNodes(results)
+
+	logger := log.FromContext(ctx)
+	if len(results) == 0 && ctx.Err() == nil {
+		logger.Info("crawl found no pages", "seeds", len(c.config.StartURLs), log.Since(start))
+	} else {
+		logger.Debug("crawl finished", "urls", len(results), log.Since(start))
+	}
+
+	if err != nil {
+		return results, err
+	}
+	return results, parent.Err()
+}
+
+// crawlConcurrent crawls with workers pulling from a shared frontier,
+// so ordering depends on which pages respond first. With FrontierFile it
+// resumes the jobs an earlier crawl left in the file.
+func (c *Crawler) crawlConcurrent(ctx context.Context) error {
+	f := newFrontier()
+	if c.config.FrontierFile != "" {
+		var queued int
+		var err error
+		f, queued, err = openFrontier(c.config.FrontierFile, func(job CrawlJob) {
+			if c.markSeen(job.URL) {
+				c.chargeSeed(job.Seed)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		c.config.Progress.AddTotal(queued)
+	}
+	enqueue := func(job CrawlJob) bool {
+		if !f.push(job) {
+			return false
+		}
+		c.config.Progress.AddTotal(1)
+		return true
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go f.closeOnDone(ctx, stop)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := f.pop()
+				if !ok {
+					return
+				}
+				c.crawlJob(ctx, job, enqueue)
+				f.done(job)
+			}
+		}()
+	}
+
+	// Seeds are counted by seedJobs, so they bypass enqueue
+	for _, job := range c.seedJobs() {
+		f.push(job)
+	}
+	f.seeded()
+
+	wg.Wait()
+	return f.finish()
+}
+
+// crawlBFS crawls one depth level at a time, finishing every URL at depth N
+// before any URL at depth N+1 is fetched
+func (c *Crawler) crawlBFS(ctx context.Context) {
+	level := c.seedJobs()
+
+	for len(level) > 0 {
+		var next []CrawlJob
+		var nextMu sync.Mutex
+		enqueue := func(job CrawlJob) bool {
+			nextMu.Lock()
+			defer nextMu.Unlock()
+			next = append(next, job)
+			c.config.Progress.AddTotal(1)
+			return true
+		}
+
+		pool := utils.NewPool(ctx, utils.PoolConfig{Workers: c.config.Workers}, func(ctx context.Context, job CrawlJob) (struct{}, error) {
+			c.crawlJob(ctx, job, enqueue)
+			return struct{}{}, nil
+		})
+		go func() {
+			defer pool.Close()
+			for _, job := range level {
+				if pool.Submit(ctx, job) != nil {
+					return
+				}
+			}
+		}()
+		pool.Wait()
+
+		if ctx.Err() != nil || c.urlCount() >= c.config.MaxURLs {
+			return
+		}
+		level = next
+	}
+}
+
+// seedJobs marks the start URLs as seen and returns their jobs
+func (c *Crawler) seedJobs() []CrawlJob {
+	var jobs []CrawlJob
+	for i, startURL := range c.config.StartURLs {
+		if c.config.Scope.Allows(startURL) && c.markSeen(startURL) {
+			c.chargeSeed(i)
+			jobs = append(jobs, CrawlJob{URL: startURL, Depth: 0, Seed: i})
+		}
+	}
+	c.config.Progress.AddTotal(len(jobs))
+	return jobs
+}
+
+// crawlJob crawls one job unless robots.txt disallows it, queueing the
+// links it finds. Jobs are only queued within the URL budget, so every one
+// is crawled.
+func (c *Crawler) crawlJob(ctx context.Context, job CrawlJob, enqueue func(CrawlJob) bool) {
+	defer c.config.Progress.Done()
+
+	if c.config.RespectRobots {
+		if !c.robotsAllowed(ctx, job.URL) {
+			return
+		}
+		c.waitCrawlDelay(ctx, job.URL)
+	}
+
+	c.wait(ctx)
+	c.crawlHost(ctx, job, enqueue)
+}
+
+// wait paces page fetches at the prober's adaptive rate if there is one,
+// or RateLimit
+func (c *Crawler) wait(ctx context.Context) {
+	if c.prober.adaptive != nil {
+		c.prober.adaptive.Wait(ctx)
+		return
+	}
+	c.limiter.Wait(ctx)
+}
+
+// crawlHost crawls a job while honoring the per-host rate and in-flight limits
+func (c *Crawler) crawlHost(ctx context.Context, job CrawlJob, enqueue func(CrawlJob) bool) {
+	host := hostOf(job.URL)
+
+	if c.hostInFlight != nil {
+		if err := c.hostInFlight.Acquire(ctx, host); err != nil {
+			return
+		}
+		defer c.hostInFlight.Release(host)
+	}
+	c.waitHost(ctx, host)
+
+	c.crawlURL(ctx, job, enqueue)
+}
+
+// waitHost blocks on the per-host rate limiter if one is configured
+func (c *Crawler) waitHost(ctx context.Context, host string) {
+	if c.hostLimiter != nil {
+		c.hostLimiter.Wait(ctx, host)
+	}
+}
+
+// hostOf returns the lowercased host (with port) of a URL
+func hostOf(urlStr string) string {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
+
+// seedBudget returns the depth and URL limits that apply to a seed
+func (c *Crawler) seedBudget(seed int) SeedBudget {
+	budget := c.config.SeedBudget
+	if seed >= 0 && seed < len(c.config.StartURLs) {
+		if override, ok := c.config.SeedBudgets[c.config.StartURLs[seed]]; ok {
+			budget = override
+		}
+	}
+	if budget.MaxDepth == 0 {
+		budget.MaxDepth = c.config.MaxDepth
+	}
+	if budget.MaxURLs == 0 {
+		budget.MaxURLs = c.config.MaxURLs
+	}
+	return budget
+}
+
+// chargeSeed counts a URL against a seed's budget
+func (c *Crawler) chargeSeed(seed int) {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+	c.seedCounts[seed]++
+}
+
+// admit marks a URL found from job's seed as seen, returns true if it is new,
+// in scope, and the seed still has URL budget left
+func (c *Crawler) admit(job CrawlJob, urlStr string) bool {
+	c.seenMu.Lock()
+	spent := c.seedCounts[job.Seed]
+	c.seenMu.Unlock()
+
+	if spent >= c.seedBudget(job.Seed).MaxURLs {
+		return false
+	}
+	if !c.config.Scope.Allows(urlStr) {
+		return false
+	}
+	if !c.markSeen(urlStr) {
+		return false
+	}
+	c.chargeSeed(job.Seed)
+	return true
+}
+
+// crawlURL fetches and parses a URL
+func (c *Crawler) crawlURL(ctx context.Context, job CrawlJob, enqueue func(CrawlJob) bool) {
+	logger := log.FromContext(ctx)
+	result, body := c.fetch(ctx, job.URL)
+	if result.StatusCode == 0 {
+		logger.Debug("page did not answer", log.Target(job.URL), "depth", job.Depth)
+		return
+	}
+
+	// Record this URL
+	c.results <- CrawlResult{
+		URL:       job.URL,
+		Source:    "crawl",
+		Depth:     job.Depth,
+		Type:      c.classifyURL(job.URL, result.ContentType),
+		Referrer:  job.Referrer,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Look for API specs once per host
+	if c.config.SpecDiscovery {
+		c.discoverSpecs(ctx, job)
+	}
+
+	// Don't crawl deeper if at max depth
+	if job.Depth >= c.seedBudget(job.Seed).MaxDepth {
+		return
+	}
+
+	// Only continue if the content type is one we parse
+	mode := c.extractionMode(result.ContentType)
+	if mode == "" {
+		logger.Debug("content type not parsed for links", log.Target(job.URL), "content_type", result.ContentType)
+		return
+	}
+
+	if body == "" {
+		return
+	}
+
+	baseURL, _ := url.Parse(job.URL)
+	options := extract.Options{Base: baseURL}
+
+	// Buckets are often named only in strings, never linked
+	for _, bucket := range extractCloudStorage(body) {
+		c.assets.record(bucket.URL, job.URL)
+	}
+
+	switch mode {
+	case ExtractHTML:
+		c.queueLinks(ctx, job, baseURL, extract.Links(body, options), enqueue)
+
+		// Extract JavaScript URLs if enabled, and the chunks of an inline
+		// webpack runtime
+		if c.config.JSParse {
+			c.emitEndpoints(job, extract.Endpoints(body, options))
+			c.queueLinks(ctx, job, baseURL, extract.WebpackChunks(body, options), enqueue)
+		}
+
+		// Extract form actions
+		forms := c.extractForms(body, baseURL)
+		for _, form := range forms {
+			c.graph.addEdge(job.URL, form.URL, "form")
+			if c.admit(job, form.URL) {
+				c.results <- CrawlResult{
+					URL:       form.URL,
+					Source:    "form",
+					Depth:     job.Depth,
+					Type:      "form",
+					Method:    form.Method,
+					Params:    form.Params,
+					Referrer:  job.URL,
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				}
+			}
+		}
+	case ExtractXML:
+		c.queueLinks(ctx, job, baseURL, extract.XMLLinks(body, options), enqueue)
+	case ExtractCSS:
+		c.queueLinks(ctx, job, baseURL, extract.CSSLinks(body, options), enqueue)
+	case ExtractJS:
+		c.queueLinks(ctx, job, baseURL, extract.ScriptLinks(body, options), enqueue)
+		c.queueLinks(ctx, job, baseURL, extract.WebpackChunks(body, options), enqueue)
+		c.emitEndpoints(job, extract.Endpoints(body, options))
+	case ExtractJSON:
+		c.queueLinks(ctx, job, baseURL, extract.JSONLinks(body, options), enqueue)
+		c.emitEndpoints(job, extract.Endpoints(body, options))
+	}
+}
+
+// queueLinks records and queues newly discovered links
+func (c *Crawler) queueLinks(ctx context.Context, job CrawlJob, baseURL *url.URL, links []string, enqueue func(CrawlJob) bool) {
+	for _, link := range links {
+		if c.urlCount() >= c.config.MaxURLs {
+			break
+		}
+
+		if c.config.SameHost && !c.isSameHost(link, baseURL.Host) {
+			c.assets.record(link, job.URL)
+			continue
+		}
+
+		c.graph.addEdge(job.URL, link, "link")
+
+		if c.config.RespectRobots && !c.robotsAllowed(ctx, link) {
+			log.FromContext(ctx).Debug("disallowed by robots.txt", log.Target(link))
+			continue
+		}
+
+		if c.admit(job, link) {
+			enqueue(CrawlJob{URL: link, Depth: job.Depth + 1, Seed: job.Seed, Referrer: job.URL})
+		}
+	}
+}
+
+// emitEndpoints records API endpoints found in script content
+func (c *Crawler) emitEndpoints(job CrawlJob, endpoints []string) {
+	for _, endpoint := range endpoints {
+		c.graph.addEdge(job.URL, endpoint, "js")
+		if c.admit(job, endpoint) {
+			c.results <- CrawlResult{
+				URL:       endpoint,
+				Source:    "js-parse",
+				Depth:     job.Depth,
+				Type:      "api",
+				Referrer:  job.URL,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+		}
+	}
+}
+
+// extractionMode returns how to parse a response of the given content type,
+// or "" if it should not be parsed. The longest matching pattern wins.
+func (c *Crawler) extractionMode(contentType string) string {
+	contentType = strings.ToLower(contentType)
+
+	mode, matched := "", 0
+	for pattern, m := range c.config.ContentTypes {
+		if len(pattern) > matched && strings.Contains(contentType, strings.ToLower(pattern)) {
+			mode, matched = m, len(pattern)
+		}
+	}
+	return mode
+}
+
+// Graph returns the link graph recorded during the last crawl
+func (c *Crawler) Graph() *CrawlGraph {
+	return c.graph
+}
+
+// fetch requests a URL once for both its headers and its body, or returns
+// the response given in CrawlConfig.Responses without a request
+func (c *Crawler) fetch(ctx context.Context, targetURL string) (ProbeResult, string) {
+	if result, ok := c.responses[targetURL]; ok {
+		return result, result.Body
+	}
+	return c.prober.Fetch(ctx, targetURL)
+}
+
+// fetchBody gets the body content of a URL, or "" if it is out of scope
+func (c *Crawler) fetchBody(ctx context.Context, targetURL string) string {
+	_, body := c.fetchStatus(ctx, targetURL)
+	return body
+}
+
+// fetchStatus gets the status code and body of a URL, or 0 and "" if it
+// is out of scope or cannot be fetched
+func (c *Crawler) fetchStatus(ctx context.Context, targetURL string) (int, string) {
+	if !c.config.Scope.Allows(targetURL) {
+		return 0, ""
+	}
+	req, err := c.prober.newRequest(ctx, "GET", targetURL)
+	if err != nil {
+		return 0, ""
+	}
+	if err := c.config.Throttle.Wait(ctx, hostOf(targetURL)); err != nil {
+		return 0, ""
+	}
+	c.config.Budget.Wait(ctx)
+
+	resp, err := c.prober.client.Do(req)
+	if err != nil {
+		c.prober.recordThrottle(ctx, targetURL, 0, err)
+		return 0, ""
+	}
+	c.prober.recordThrottle(ctx, targetURL, resp.StatusCode, nil)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxBodySize))
+	return resp.StatusCode, string(body)
+}
+
+// FormInfo holds form information
+type FormInfo struct {
+	URL    string
+	Method string
+	Params []string
+}
+
+// extractForms extracts form actions and parameters
+func (c *Crawler) extractForms(body string, base *url.URL) []FormInfo {
+	var forms []FormInfo
+	for _, form := range extract.Forms(body, extract.Options{Base: base}) {
+		forms = append(forms, FormInfo{URL: form.Action, Method: form.Method, Params: form.Fields})
+	}
+	return forms
+}
+
+// isSameHost checks if URL is on same host
+func (c *Crawler) isSameHost(urlStr, host string) bool {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+	return parsed.Host == host || strings.HasSuffix(parsed.Host, "."+host)
+}
+
+// markSeen marks URL as seen, returns true if new
+func (c *Crawler) markSeen(urlStr string) bool {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+
+	// Normalize URL
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+	normalized := parsed.Scheme + "://" + parsed.Host + parsed.Path
+
+	// Skip URLs whose pattern has already been sampled enough
+	var pattern string
+	if c.config.DedupPatterns {
+		pattern = URLPattern(parsed)
+		if c.patternHits[pattern] >= c.config.PatternSamples {
+			return false
+		}
+	}
+
+	if !c.seen.Add(normalized) {
+		return false
+	}
+	if c.config.DedupPatterns {
+		c.patternHits[pattern]++
+	}
+	return true
+}
+
+// urlCount returns number of seen URLs
+func (c *Crawler) urlCount() int {
+	return c.seen.Len()
+}
+
+// classifyURL determines the type of URL
+func (c *Crawler) classifyURL(urlStr, contentType string) string {
+	lower := strings.ToLower(urlStr)
+
+	if strings.Contains(lower, "/api/") || strings.Contains(lower, "/graphql") {
+		return "api"
+	}
+	if strings.HasSuffix(lower, ".js") {
+		return "js"
+	}
+	if strings.HasSuffix(lower, ".css") {
+		return "css"
+	}
+	if strings.Contains(contentType, "json") {
+		return "api"
+	}
+	if strings.Contains(contentType, "javascript") {
+		return "js"
+	}
+
+	return "page"
+}
@@ -0,0 +1,134 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is what requests ask for unless their headers already
+// name an encoding
+const acceptEncoding = "gzip, deflate, br"
+
+// decompressTransport asks for compressed responses and decodes their
+// bodies, so titles, technologies and secrets are read from the content
+// and not its encoding. Go's transport only does this for gzip, and only
+// when it set Accept-Encoding itself; this also covers deflate and brotli,
+// and servers that compress unasked. The transport it wraps should have
+// DisableCompression set.
+func decompressTransport(next http.RoundTripper) http.RoundTripper {
+	return &decompressor{next: next}
+}
+
+type decompressor struct {
+	next http.RoundTripper
+}
+
+func (d *decompressor) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp, err := d.next.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead {
+		return resp, err
+	}
+
+	encodings := contentEncodings(resp.Header.Get("Content-Encoding"))
+	if len(encodings) == 0 {
+		return resp, nil
+	}
+	resp.Body = &decodedBody{body: resp.Body, encodings: encodings}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// CloseIdleConnections passes on to the wrapped transport
+func (d *decompressor) CloseIdleConnections() {
+	if c, ok := d.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// contentEncodings returns the codings of a Content-Encoding header in
+// the order they were applied, or nil if any is one that cannot be decoded
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, coding := range strings.Split(header, ",") {
+		switch coding = strings.ToLower(strings.TrimSpace(coding)); coding {
+		case "", "identity":
+		case "gzip", "x-gzip", "deflate", "br":
+			encodings = append(encodings, coding)
+		default:
+			return nil
+		}
+	}
+	return encodings
+}
+
+// decodedBody decodes a response body on first read, so a body that
+// turns out not to be what its header says fails its reads rather than
+// the request
+type decodedBody struct {
+	body      io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	err       error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = decoder(b.body, b.encodings)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
+
+// decoder undoes encodings, the last applied first
+func decoder(r io.Reader, encodings []string) (io.Reader, error) {
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			r = gz
+		case "deflate":
+			inflated, err := inflate(r)
+			if err != nil {
+				return nil, err
+			}
+			r = inflated
+		case "br":
+			r = brotli.NewReader(r)
+		}
+	}
+	return r, nil
+}
+
+// inflate reads deflate content, which should be zlib-wrapped but some
+// servers send as a raw deflate stream
+func inflate(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
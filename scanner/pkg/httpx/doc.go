@@ -0,0 +1,16 @@
+// Package httpx probes hosts for live HTTP services, crawls them for URLs
+// and endpoints, and analyzes responses for secrets, takeovers and missing
+// security headers. It is named httpx so it does not shadow net/http in
+// callers.
+//
+//	prober := httpx.NewProber(httpx.ProbeConfig{
+//		Targets: []string{"example.com", "192.0.2.10:8080"},
+//	})
+//	live, err := prober.ProbeContext(ctx)
+//
+//	crawler := httpx.NewCrawler(httpx.CrawlConfig{
+//		StartURLs: []string{"https://example.com"},
+//		MaxDepth:  2,
+//	})
+//	urls, err := crawler.CrawlContext(ctx)
+package httpx
@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxAssetSamples caps how many URLs and referrers are kept per external host
+const maxAssetSamples = 20
+
+// ExternalAsset is an out-of-scope host referenced by crawled pages
+type ExternalAsset struct {
+	Host     string   `json:"host"`
+	Category string   `json:"category"`          // cdn, storage, tracking, saas, social, other
+	Service  string   `json:"service,omitempty"` // the known third-party service, if any
+	Hits     int      `json:"hits"`
+	URLs     []string `json:"urls"`
+	FoundOn  []string `json:"found_on"`
+}
+
+// externalCategories maps well-known domain suffixes to an asset category
+var externalCategories = map[string]string{
+	// CDNs and static hosting
+	"cloudfront.net":       "cdn",
+	"akamaihd.net":         "cdn",
+	"akamaized.net":        "cdn",
+	"fastly.net":           "cdn",
+	"cloudflare.com":       "cdn",
+	"cdnjs.cloudflare.com": "cdn",
+	"jsdelivr.net":         "cdn",
+	"unpkg.com":            "cdn",
+	"bootstrapcdn.com":     "cdn",
+	"gstatic.com":          "cdn",
+	"googleapis.com":       "cdn",
+	"fonts.googleapis.com": "cdn",
+	"azureedge.net":        "cdn",
+	"b-cdn.net":            "cdn",
+
+	// Cloud storage
+	"s3.amazonaws.com":       "storage",
+	"storage.googleapis.com": "storage",
+	"blob.core.windows.net":  "storage",
+
+	// Analytics, tags and ads
+	"google-analytics.com":  "tracking",
+	"googletagmanager.com":  "tracking",
+	"doubleclick.net":       "tracking",
+	"googlesyndication.com": "tracking",
+	"hotjar.com":            "tracking",
+	"segment.com":           "tracking",
+	"segment.io":            "tracking",
+	"mixpanel.com":          "tracking",
+	"newrelic.com":          "tracking",
+	"nr-data.net":           "tracking",
+	"clarity.ms":            "tracking",
+	"connect.facebook.net":  "tracking",
+	"amplitude.com":         "tracking",
+	"sentry.io":             "tracking",
+
+	// SaaS platforms
+	"intercom.io":     "saas",
+	"zendesk.com":     "saas",
+	"hubspot.com":     "saas",
+	"hs-scripts.com":  "saas",
+	"stripe.com":      "saas",
+	"paypal.com":      "saas",
+	"recaptcha.net":   "saas",
+	"hcaptcha.com":    "saas",
+	"auth0.com":       "saas",
+	"okta.com":        "saas",
+	"salesforce.com":  "saas",
+	"typeform.com":    "saas",
+	"shopify.com":     "saas",
+	"atlassian.net":   "saas",
+	"statuspage.io":   "saas",
+	"youtube.com":     "saas",
+	"vimeo.com":       "saas",
+	"cookielaw.org":   "saas",
+	"onetrust.com":    "saas",
+	"cookiebot.com":   "saas",
+	"maps.google.com": "saas",
+
+	// Social
+	"facebook.com":  "social",
+	"twitter.com":   "social",
+	"x.com":         "social",
+	"linkedin.com":  "social",
+	"instagram.com": "social",
+	"github.com":    "social",
+	"tiktok.com":    "social",
+}
+
+// assetInventory collects external assets seen during a crawl
+type assetInventory struct {
+	assets map[string]*ExternalAsset
+	mu     sync.Mutex
+}
+
+// newAssetInventory creates an empty inventory
+func newAssetInventory() *assetInventory {
+	return &assetInventory{
+		assets: make(map[string]*ExternalAsset),
+	}
+}
+
+// record adds an external URL referenced from a page
+func (inv *assetInventory) record(link, foundOn string) {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	asset, ok := inv.assets[host]
+	if !ok {
+		asset = &ExternalAsset{
+			Host:     host,
+			Category: CategorizeHost(host),
+		}
+		if service, ok := lookupService(host); ok {
+			asset.Service = service.name
+		}
+		inv.assets[host] = asset
+	}
+
+	asset.Hits++
+	asset.URLs = appendSample(asset.URLs, link)
+	asset.FoundOn = appendSample(asset.FoundOn, foundOn)
+}
+
+// list returns the inventory sorted by host
+func (inv *assetInventory) list() []ExternalAsset {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	assets := make([]ExternalAsset, 0, len(inv.assets))
+	for _, asset := range inv.assets {
+		assets = append(assets, *asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Host < assets[j].Host
+	})
+
+	return assets
+}
+
+// appendSample appends a unique value while under the sample cap
+func appendSample(values []string, value string) []string {
+	if len(values) >= maxAssetSamples {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// CategorizeHost classifies a host by its longest matching known suffix
+func CategorizeHost(host string) string {
+	host = strings.ToLower(host)
+
+	category, matched := "other", 0
+	for suffix, c := range externalCategories {
+		if len(suffix) > matched && (host == suffix || strings.HasSuffix(host, "."+suffix)) {
+			category, matched = c, len(suffix)
+		}
+	}
+	return category
+}
+
+// ExternalAssets returns out-of-scope hosts referenced during the last crawl
+func (c *Crawler) ExternalAssets() []ExternalAsset {
+	return c.assets.list()
+}
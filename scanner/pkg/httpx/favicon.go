@@ -0,0 +1,251 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// faviconProducts are the favicon hashes, as Shodan computes them, of
+// products whose login pages often hide their name
+var faviconProducts = map[string]string{
+	"81586312":   "Jenkins",
+	"1278323681": "GitLab",
+	"981867722":  "Atlassian Jira",
+	"-305179312": "Atlassian Confluence",
+	"945408572":  "Fortinet FortiGate",
+	"-335242539": "F5 BIG-IP",
+	"-631559155": "Palo Alto GlobalProtect",
+	"967636089":  "MobileIron",
+	"1768726119": "Microsoft Outlook Web App",
+	"116323821":  "Spring Boot",
+	"-297069493": "Apache Tomcat",
+}
+
+// FaviconDB maps favicon hashes to products. Keys are Shodan's mmh3
+// hashes (signed decimal) or MD5 hex digests, as in the OWASP favicon
+// database; a favicon matches on either.
+type FaviconDB struct {
+	products map[string]string
+}
+
+// DefaultFaviconDB returns the embedded fingerprints
+func DefaultFaviconDB() *FaviconDB {
+	db := &FaviconDB{products: make(map[string]string, len(faviconProducts))}
+	for hash, product := range faviconProducts {
+		db.products[hash] = product
+	}
+	return db
+}
+
+// LoadFaviconDB returns the embedded fingerprints extended with those in
+// a file of "hash,product" or "hash: product" lines. Hashes are mmh3
+// (signed decimal) or MD5 hex; # starts a comment.
+func LoadFaviconDB(path string) (*FaviconDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := DefaultFaviconDB()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexAny(line, ",:")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: want hash,product", path, n)
+		}
+		hash := strings.ToLower(strings.Trim(strings.TrimSpace(line[:i]), `"`))
+		product := strings.Trim(strings.TrimSpace(line[i+1:]), `"`)
+		if _, err := strconv.ParseInt(hash, 10, 32); err != nil && !isMD5(hash) {
+			// A CSV header line
+			if n == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: %q is neither an mmh3 nor an MD5 hash", path, n, hash)
+		}
+		if product != "" {
+			db.products[hash] = product
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Lookup returns the product of a favicon, or "" if it is not known. A
+// nil FaviconDB uses the embedded fingerprints.
+func (db *FaviconDB) Lookup(hash int32, md5sum string) string {
+	products := faviconProducts
+	if db != nil {
+		products = db.products
+	}
+	if product, ok := products[strconv.Itoa(int(hash))]; ok {
+		return product
+	}
+	return products[md5sum]
+}
+
+// isMD5 reports whether s is an MD5 hex digest
+func isMD5(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// FaviconHash returns Shodan's hash of a favicon: the 32-bit MurmurHash3
+// of its base64 encoding, wrapped at 76 characters with a trailing
+// newline as Python's base64.encodebytes produces
+func FaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var wrapped strings.Builder
+	for len(encoded) > 76 {
+		wrapped.WriteString(encoded[:76])
+		wrapped.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	wrapped.WriteString(encoded)
+	wrapped.WriteByte('\n')
+	return int32(murmur3([]byte(wrapped.String()), 0))
+}
+
+// murmur3 is MurmurHash3 x86 32-bit
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// iconLinkPattern matches <link> tags whose rel names an icon
+var iconLinkPattern = regexp.MustCompile(`(?is)<link\b[^>]*\brel\s*=\s*["']?[^"'>]*\bicon\b[^>]*>`)
+
+// hrefPattern extracts the href of a tag
+var hrefPattern = regexp.MustCompile(`(?is)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// faviconURL returns the icon a page links to, or /favicon.ico
+func faviconURL(pageURL, body string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	if tag := iconLinkPattern.FindString(body); tag != "" {
+		if m := hrefPattern.FindStringSubmatch(tag); m != nil {
+			href := strings.TrimSpace(m[1] + m[2] + m[3])
+			if strings.HasPrefix(href, "data:") {
+				return href
+			}
+			if ref, err := url.Parse(href); err == nil && href != "" {
+				return base.ResolveReference(ref).String()
+			}
+		}
+	}
+	return base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+}
+
+// fetchFavicon downloads a page's favicon and fills in its hashes and any
+// product they identify
+func (p *Prober) fetchFavicon(ctx context.Context, result *ProbeResult, pageURL, body string) {
+	iconURL := faviconURL(pageURL, body)
+	if iconURL == "" {
+		return
+	}
+
+	var data []byte
+	if strings.HasPrefix(iconURL, "data:") {
+		meta, payload, ok := strings.Cut(iconURL[len("data:"):], ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return
+		}
+		data = decoded
+	} else {
+		if !p.config.Scope.Allows(iconURL) {
+			return
+		}
+		req, err := p.newRequest(ctx, "GET", iconURL)
+		if err != nil {
+			return
+		}
+		p.config.Budget.Wait(ctx)
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+
+		// A missing icon often comes back as the site's HTML error page
+		if resp.StatusCode != 200 || strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+			return
+		}
+		data, _ = io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	sum := md5.Sum(data)
+	result.FaviconURL = iconURL
+	if strings.HasPrefix(iconURL, "data:") {
+		result.FaviconURL = "data:"
+	}
+	result.FaviconHash = FaviconHash(data)
+	result.FaviconMD5 = hex.EncodeToString(sum[:])
+	if product := p.config.Favicons.Lookup(result.FaviconHash, result.FaviconMD5); product != "" {
+		result.Technologies = append(result.Technologies, product)
+	}
+}
@@ -0,0 +1,421 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"github.com/recon-suite/scanner/pkg/wordlist"
+	"golang.org/x/time/rate"
+)
+
+// DefaultMatchCodes are the statuses reported when FuzzConfig.MatchCodes
+// is not set
+var DefaultMatchCodes = []int{200, 201, 202, 203, 204, 206, 301, 302, 307, 308, 401, 403, 405, 500}
+
+// FuzzConfig holds content discovery configuration
+type FuzzConfig struct {
+	// Targets are base URLs; words are appended to each as a path
+	Targets []string
+
+	// Wordlist is a file of paths, one per line
+	Wordlist string
+
+	// Extensions are also tried after each word, e.g. "php" or ".bak"
+	Extensions []string
+
+	// Recursion fuzzes inside each directory found, up to MaxDepth levels
+	// below the target
+	Recursion bool
+	MaxDepth  int
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// MatchCodes are the statuses reported; empty means DefaultMatchCodes
+	MatchCodes []int
+
+	// FilterCodes, FilterSizes, FilterWords and FilterLines drop responses
+	// with these statuses, body sizes, word counts or line counts
+	FilterCodes []int
+	FilterSizes []int
+	FilterWords []int
+	FilterLines []int
+
+	// AutoCalibrate requests random paths in each directory first and
+	// drops responses that look like them, filtering soft 404s
+	AutoCalibrate bool
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts requests made and paths found
+	Progress *utils.Progress
+
+	// OnResult is called for each path found
+	OnResult func(FuzzResult)
+}
+
+// FuzzResult is one path that answered
+type FuzzResult struct {
+	URL           string `json:"url"`
+	StatusCode    int    `json:"status_code"`
+	ContentLength int    `json:"content_length"`
+	Words         int    `json:"words"`
+	Lines         int    `json:"lines"`
+	ContentType   string `json:"content_type,omitempty"`
+	RedirectTo    string `json:"redirect_to,omitempty"`
+	Depth         int    `json:"depth"`
+	Timestamp     string `json:"timestamp"`
+}
+
+// fuzzResponse is the shape of a response, used to compare it with the
+// calibration baseline
+type fuzzResponse struct {
+	status int
+	size   int
+	words  int
+	lines  int
+}
+
+// Fuzzer discovers content by requesting wordlist paths
+type Fuzzer struct {
+	config  FuzzConfig
+	prober  *Prober
+	limiter *rate.Limiter
+	words   []string
+}
+
+// DefaultFuzzRateLimit is the requests per second when FuzzConfig.RateLimit is unset
+const DefaultFuzzRateLimit = 200
+
+// NewFuzzer creates a new content discovery fuzzer
+func NewFuzzer(config FuzzConfig) *Fuzzer {
+	if config.Workers == 0 {
+		config.Workers = 40
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = DefaultFuzzRateLimit
+	}
+	if config.Recursion && config.MaxDepth == 0 {
+		config.MaxDepth = 2
+	}
+	if len(config.MatchCodes) == 0 {
+		config.MatchCodes = DefaultMatchCodes
+	}
+	extensions := make([]string, 0, len(config.Extensions))
+	for _, ext := range config.Extensions {
+		extensions = append(extensions, "."+strings.TrimPrefix(ext, "."))
+	}
+	config.Extensions = extensions
+
+	probeConfig := ProbeConfig{
+		Workers:   config.Workers,
+		Timeout:   config.Timeout,
+		UserAgent: config.UserAgent,
+		Headers:   config.Headers,
+		Cookies:   config.Cookies,
+		Proxy:     config.Proxy,
+		Scope:     config.Scope,
+		Budget:    config.Budget,
+	}
+
+	return &Fuzzer{
+		config:  config,
+		prober:  NewProber(probeConfig),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// FuzzContext fuzzes every target, then every directory found when
+// recursing, one directory at a time. If ctx is cancelled, the paths found
+// so far are returned with ctx's error.
+func (f *Fuzzer) FuzzContext(ctx context.Context) ([]FuzzResult, error) {
+	words, err := loadWords(f.config.Wordlist)
+	if err != nil {
+		return nil, err
+	}
+	f.words = words
+	ctx = log.WithModule(ctx, "fuzz")
+
+	type dir struct {
+		url   string
+		depth int
+	}
+	var queue []dir
+	for _, target := range f.config.Scope.Filter(f.config.Targets) {
+		if !strings.Contains(target, "://") {
+			target = "https://" + target
+		}
+		queue = append(queue, dir{url: strings.TrimSuffix(target, "/") + "/"})
+	}
+
+	var results []FuzzResult
+	seen := make(map[string]bool)
+	for len(queue) > 0 && ctx.Err() == nil {
+		d := queue[0]
+		queue = queue[1:]
+		if seen[d.url] {
+			continue
+		}
+		seen[d.url] = true
+
+		start := time.Now()
+		found := f.fuzzDir(ctx, d.url, d.depth)
+		results = append(results, found...)
+		if len(found) == 0 && ctx.Err() == nil {
+			log.FromContext(ctx).Info("no paths matched", log.Target(d.url), "depth", d.depth, log.Since(start))
+		} else {
+			log.FromContext(ctx).Debug("directory fuzzed", log.Target(d.url), "depth", d.depth, "found", len(found), log.Since(start))
+		}
+		if !f.config.Recursion || d.depth >= f.config.MaxDepth {
+			continue
+		}
+		for _, r := range found {
+			if sub, ok := directory(r); ok {
+				queue = append(queue, dir{url: sub, depth: d.depth + 1})
+			}
+		}
+	}
+	return results, ctx.Err()
+}
+
+// fuzzDir requests every word (and word plus extension) under one
+// directory URL
+func (f *Fuzzer) fuzzDir(ctx context.Context, base string, depth int) []FuzzResult {
+	var baseline []fuzzResponse
+	if f.config.AutoCalibrate {
+		baseline = f.calibrate(ctx, base)
+	}
+
+	paths := make(chan string, f.config.Workers*2)
+	found := make(chan FuzzResult)
+	f.config.Progress.AddTotal(len(f.words) * (1 + len(f.config.Extensions)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < f.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				f.limiter.Wait(ctx)
+				result, resp, ok := f.request(ctx, base+path, depth)
+				f.config.Progress.Done()
+				if ok && f.keep(resp, baseline) {
+					found <- result
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(paths)
+		for _, word := range f.words {
+			candidates := []string{word}
+			if !strings.HasSuffix(word, "/") {
+				for _, ext := range f.config.Extensions {
+					candidates = append(candidates, word+ext)
+				}
+			}
+			for _, path := range candidates {
+				select {
+				case <-ctx.Done():
+					return
+				case paths <- path:
+				}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	var results []FuzzResult
+	for result := range found {
+		f.config.Progress.Found()
+		metrics.Findings("fuzz").Inc()
+		if f.config.OnResult != nil {
+			f.config.OnResult(result)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// calibrate records how a directory answers paths that cannot exist
+func (f *Fuzzer) calibrate(ctx context.Context, base string) []fuzzResponse {
+	word := randomWord()
+	probes := []string{word, word + "/"}
+	for _, ext := range f.config.Extensions {
+		probes = append(probes, word+ext)
+	}
+
+	var baseline []fuzzResponse
+	for _, path := range probes {
+		if _, resp, ok := f.request(ctx, base+path, 0); ok {
+			baseline = append(baseline, resp)
+		}
+	}
+	log.FromContext(ctx).Debug("calibrated", log.Target(base), "baseline", len(baseline))
+	return baseline
+}
+
+// request fetches one URL without following redirects
+func (f *Fuzzer) request(ctx context.Context, target string, depth int) (FuzzResult, fuzzResponse, bool) {
+	result := FuzzResult{
+		URL:       target,
+		Depth:     depth,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if !f.config.Scope.Allows(target) {
+		return result, fuzzResponse{}, false
+	}
+
+	req, err := f.prober.newRequest(ctx, "GET", target)
+	if err != nil {
+		return result, fuzzResponse{}, false
+	}
+	f.config.Budget.Wait(ctx)
+
+	resp, err := f.prober.client.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Debug("request failed", log.Target(target), log.Err(err))
+		return result, fuzzResponse{}, false
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 5*1024*1024))
+	result.StatusCode = resp.StatusCode
+	result.ContentLength = len(body)
+	result.Words = len(bytes.Fields(body))
+	result.Lines = bytes.Count(body, []byte("\n"))
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		result.Lines++
+	}
+	result.ContentType = resp.Header.Get("Content-Type")
+	if location, err := resp.Location(); err == nil {
+		result.RedirectTo = location.String()
+	}
+
+	return result, fuzzResponse{
+		status: result.StatusCode,
+		size:   result.ContentLength,
+		words:  result.Words,
+		lines:  result.Lines,
+	}, true
+}
+
+// keep applies the match and filter options and the calibration baseline
+func (f *Fuzzer) keep(resp fuzzResponse, baseline []fuzzResponse) bool {
+	if !containsInt(f.config.MatchCodes, resp.status) ||
+		containsInt(f.config.FilterCodes, resp.status) ||
+		containsInt(f.config.FilterSizes, resp.size) ||
+		containsInt(f.config.FilterWords, resp.words) ||
+		containsInt(f.config.FilterLines, resp.lines) {
+		return false
+	}
+	for _, b := range baseline {
+		// Soft 404s often echo the path, so sizes drift while the word and
+		// line counts hold
+		if resp.status == b.status && (resp.size == b.size || (resp.words == b.words && resp.lines == b.lines)) {
+			return false
+		}
+	}
+	return true
+}
+
+// directory reports whether a result is a directory worth recursing into,
+// returning its URL with a trailing slash
+func directory(r FuzzResult) (string, bool) {
+	if strings.HasSuffix(r.URL, "/") && (r.StatusCode < 400 || r.StatusCode == 403) {
+		return r.URL, true
+	}
+	switch r.StatusCode {
+	case 301, 302, 307, 308:
+		if r.RedirectTo == r.URL+"/" {
+			return r.RedirectTo, true
+		}
+		// Relative redirects resolve against the requested URL
+		if base, err := url.Parse(r.URL); err == nil {
+			if loc, err := base.Parse(r.RedirectTo); err == nil && loc.String() == r.URL+"/" {
+				return loc.String(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// loadWords reads a wordlist, skipping blanks and comments and dropping
+// leading slashes
+func loadWords(path string) ([]string, error) {
+	file, err := wordlist.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimLeft(strings.TrimSpace(scanner.Text()), "/")
+		if word == "" || strings.HasPrefix(word, "#") || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("wordlist %s is empty", path)
+	}
+	return words, nil
+}
+
+// randomWord returns a path segment that will not exist on any server
+func randomWord() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// containsInt reports whether list holds n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/extract"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// What shows a GraphQL endpoint, strongest first
+const (
+	// GraphQLError is a body shaped like a GraphQL error response, as
+	// servers answer a request without a query
+	GraphQLError = "error"
+
+	// GraphQLIDE is a GraphiQL, Playground or other GraphQL IDE page
+	GraphQLIDE = "ide"
+
+	// GraphQLHint is an endpoint named in a page or script, such as in a
+	// client's configuration
+	GraphQLHint = "hint"
+)
+
+// GraphQLEndpoint is a GraphQL endpoint found in a response
+type GraphQLEndpoint struct {
+	URL      string `json:"url"`
+	Evidence string `json:"evidence"`
+
+	// Schema is what introspection returned, when it was tried and the
+	// endpoint allows it; see Prober.IntrospectGraphQL
+	Schema *GraphQLSchema `json:"schema,omitempty"`
+}
+
+// GraphQLSchema summarizes an introspected schema: the fields of its root
+// types and the names of the types it defines
+type GraphQLSchema struct {
+	Queries       []string `json:"queries,omitempty"`
+	Mutations     []string `json:"mutations,omitempty"`
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	Types         []string `json:"types,omitempty"`
+}
+
+// graphQLMessage matches the error messages GraphQL servers give for a
+// request without a usable query
+var graphQLMessage = regexp.MustCompile(`(?i)graphql|must provide (?:a )?query|query (?:string )?(?:is )?missing|get query missing|syntax error|cannot query field|unknown (?:type|argument)|persistedquery`)
+
+// graphQLIDEPattern matches the pages of GraphQL IDEs
+var graphQLIDEPattern = regexp.MustCompile(`(?i)graphiql|graphql[- ]playground|apollo sandbox|altair graphql|graphql voyager`)
+
+// graphQLHintPatterns find endpoints in client configuration: Apollo's
+// uri, and the endpoint and URL settings of other clients
+var graphQLHintPatterns = []*regexp.Regexp{
+	regexp.MustCompile("(?i)(?:uri|endpoint|graphqlEndpoint|graphql_?url|apiUrl|url)\\s*[:=]\\s*[\"'`]([^\"'`\\s]*(?:graphql|/gql)[^\"'`\\s]*)[\"'`]"),
+	regexp.MustCompile("[\"'`]([^\"'`\\s]*/gql/?)[\"'`]"),
+}
+
+// detectGraphQL finds the GraphQL endpoints a response shows: the page
+// itself if it is a GraphQL error or IDE, and those its body names
+func detectGraphQL(pageURL, body string) []GraphQLEndpoint {
+	var endpoints []GraphQLEndpoint
+	seen := make(map[string]bool)
+	add := func(endpoint, evidence string) {
+		if endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, GraphQLEndpoint{URL: endpoint, Evidence: evidence})
+		}
+	}
+
+	if isGraphQLError(body) {
+		add(pageURL, GraphQLError)
+	} else if graphQLIDEPattern.MatchString(body) {
+		add(pageURL, GraphQLIDE)
+	}
+
+	base, _ := url.Parse(pageURL)
+	options := extract.Options{Patterns: graphQLHintPatterns}
+	if base != nil && base.Host != "" {
+		options.Base = base
+	}
+	for _, endpoint := range extract.Endpoints(body, options) {
+		lower := strings.ToLower(endpoint)
+		if strings.Contains(lower, "graphql") || strings.HasSuffix(strings.TrimSuffix(lower, "/"), "/gql") {
+			add(endpoint, GraphQLHint)
+		}
+	}
+	return endpoints
+}
+
+// isGraphQLError reports whether body is a GraphQL error response: an
+// errors list alongside data, locations or extensions, or with a message
+// only GraphQL servers give. Short plain-text bodies are checked for the
+// messages too.
+func isGraphQLError(body string) bool {
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		return len(body) < 256 && graphQLMessage.MatchString(body) && !strings.Contains(body, "<")
+	}
+
+	var response struct {
+		Data   *json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string          `json:"message"`
+			Locations  json.RawMessage `json:"locations"`
+			Extensions json.RawMessage `json:"extensions"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal([]byte(body), &response) != nil || len(response.Errors) == 0 {
+		return false
+	}
+	first := response.Errors[0]
+	if first.Message == "" {
+		return false
+	}
+	return response.Data != nil || first.Locations != nil || first.Extensions != nil || graphQLMessage.MatchString(first.Message)
+}
+
+// graphQLIntrospectionQuery asks for the root types and every type's name
+// and fields, which is enough to list operations without the full schema
+const graphQLIntrospectionQuery = `query IntrospectionQuery { __schema { queryType { name } mutationType { name } subscriptionType { name } types { name kind fields(includeDeprecated: true) { name } } } }`
+
+// maxIntrospectionSize caps how much of an introspection response is read
+const maxIntrospectionSize = 10 << 20
+
+// IntrospectGraphQL posts an introspection query to a GraphQL endpoint and
+// summarizes the schema it returns. Introspection left enabled in
+// production maps out every operation the API has; servers that disable
+// it answer with an error, returned here.
+func (p *Prober) IntrospectGraphQL(ctx context.Context, endpoint string) (*GraphQLSchema, error) {
+	if !p.config.Scope.Allows(endpoint) {
+		return nil, fmt.Errorf("%s: out of scope", endpoint)
+	}
+	req, err := p.newRequest(ctx, http.MethodPost, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	payload, _ := json.Marshal(map[string]string{"query": graphQLIntrospectionQuery})
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(payload)), nil }
+	req.ContentLength = int64(len(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	p.wait(ctx)
+	p.config.Budget.Wait(ctx)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Debug("graphql introspection failed", log.Target(endpoint), log.Err(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionSize))
+	if err != nil {
+		return nil, err
+	}
+
+	type typeRef struct {
+		Name string `json:"name"`
+	}
+	var response struct {
+		Data struct {
+			Schema *struct {
+				QueryType        *typeRef `json:"queryType"`
+				MutationType     *typeRef `json:"mutationType"`
+				SubscriptionType *typeRef `json:"subscriptionType"`
+				Types            []struct {
+					Name   string    `json:"name"`
+					Kind   string    `json:"kind"`
+					Fields []typeRef `json:"fields"`
+				} `json:"types"`
+			} `json:"__schema"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("%s: introspection answered %d, not GraphQL", endpoint, resp.StatusCode)
+	}
+	schema := response.Data.Schema
+	if schema == nil {
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("%s: introspection refused: %s", endpoint, response.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("%s: introspection returned no schema", endpoint)
+	}
+
+	fields := make(map[string][]string)
+	summary := &GraphQLSchema{}
+	for _, t := range schema.Types {
+		if strings.HasPrefix(t.Name, "__") || t.Kind == "SCALAR" {
+			continue
+		}
+		summary.Types = append(summary.Types, t.Name)
+		for _, f := range t.Fields {
+			fields[t.Name] = append(fields[t.Name], f.Name)
+		}
+	}
+	sort.Strings(summary.Types)
+	root := func(ref *typeRef) []string {
+		if ref == nil {
+			return nil
+		}
+		return fields[ref.Name]
+	}
+	summary.Queries = root(schema.QueryType)
+	summary.Mutations = root(schema.MutationType)
+	summary.Subscriptions = root(schema.SubscriptionType)
+
+	log.FromContext(ctx).Debug("graphql introspection", log.Target(endpoint), "types", len(summary.Types))
+	return summary, nil
+}
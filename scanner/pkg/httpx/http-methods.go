@@ -0,0 +1,404 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// probedMethods are sent to every endpoint after OPTIONS. None changes
+// state on a well-behaved server; POST is sent without a body.
+var probedMethods = []string{"GET", "HEAD", "POST", "PROPFIND"}
+
+// UnsafeMethods change state when a server accepts them. They are only
+// sent with MethodConfig.Unsafe, and then to a random path below each
+// endpoint rather than the endpoint itself.
+var UnsafeMethods = []string{"PUT", "DELETE", "PATCH"}
+
+// RiskyMethods are flagged when an endpoint allows or accepts them: they
+// write, delete or move content, or tunnel through the server
+var RiskyMethods = []string{"PUT", "DELETE", "PATCH", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "CONNECT"}
+
+// MethodConfig holds allowed HTTP methods discovery configuration
+type MethodConfig struct {
+	// Targets are the endpoints to test: probed hosts, and the forms and
+	// API endpoints a crawl found
+	Targets []string
+
+	// Unsafe also sends UnsafeMethods, each to a random path below the
+	// endpoint, and compares the answer with a GET of that path
+	Unsafe bool
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts endpoints tested and those allowing risky
+	// methods
+	Progress *utils.Progress
+
+	// OnResult is called for each endpoint that answered
+	OnResult func(MethodResult)
+}
+
+// MethodResult is the methods one endpoint allows
+type MethodResult struct {
+	URL string `json:"url"`
+
+	// OptionsStatus is what OPTIONS got, and Allow the methods its Allow
+	// and Public headers list
+	OptionsStatus int      `json:"options_status"`
+	Allow         []string `json:"allow,omitempty"`
+
+	// CORSMethods are the methods Access-Control-Allow-Methods lists
+	CORSMethods []string `json:"cors_methods,omitempty"`
+
+	// Statuses are what each probed method got, and Accepted the methods
+	// not answered 405 or 501. UnsafeURL is where UnsafeMethods were sent;
+	// those answered the same as a GET of it are left out.
+	Statuses  map[string]int `json:"statuses"`
+	Accepted  []string       `json:"accepted,omitempty"`
+	UnsafeURL string         `json:"unsafe_url,omitempty"`
+
+	// Risky are the RiskyMethods allowed or accepted. Severity is high
+	// when one of them got a 2xx, medium when one is allowed or routed
+	// (answered other than 405 or 501), and info otherwise.
+	Risky     []string `json:"risky,omitempty"`
+	Severity  string   `json:"severity"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// MethodScanner discovers the HTTP methods endpoints allow
+type MethodScanner struct {
+	config  MethodConfig
+	prober  *Prober
+	limiter *rate.Limiter
+}
+
+// NewMethodScanner creates a new method scanner. Redirects are not
+// followed, so each method's own answer is judged.
+func NewMethodScanner(config MethodConfig) *MethodScanner {
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 50
+	}
+
+	return &MethodScanner{
+		config: config,
+		prober: NewProber(ProbeConfig{
+			Workers:   config.Workers,
+			Timeout:   config.Timeout,
+			UserAgent: config.UserAgent,
+			Headers:   config.Headers,
+			Cookies:   config.Cookies,
+			Proxy:     config.Proxy,
+			Scope:     config.Scope,
+			Budget:    config.Budget,
+		}),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// ScanContext tests every target. A target's methods are sent one at a
+// time, so workers spread over endpoints. If ctx is cancelled, the results
+// so far are returned with ctx's error.
+func (s *MethodScanner) ScanContext(ctx context.Context) ([]MethodResult, error) {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range s.config.Scope.Filter(s.config.Targets) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	s.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "methods")
+	jobs := make(chan string)
+	results := make(chan MethodResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				if result, ok := s.test(ctx, target); ok {
+					results <- result
+				}
+				s.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []MethodResult
+	for result := range results {
+		if len(result.Risky) > 0 {
+			s.config.Progress.Found()
+			metrics.Findings("methods").Inc()
+		}
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].URL < found[j].URL })
+	return found, ctx.Err()
+}
+
+// test asks an endpoint for its methods with OPTIONS, then tries each
+func (s *MethodScanner) test(ctx context.Context, target string) (MethodResult, bool) {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return MethodResult{}, false
+	}
+
+	result := MethodResult{URL: target, Statuses: make(map[string]int)}
+	options, ok := s.send(ctx, "OPTIONS", target)
+	if !ok {
+		return MethodResult{}, false
+	}
+	result.OptionsStatus = options.StatusCode
+	result.Statuses["OPTIONS"] = options.StatusCode
+	if options.StatusCode < 300 {
+		result.Allow = methodList(options.Header.Values("Allow"), options.Header.Values("Public"))
+	}
+	result.CORSMethods = methodList(options.Header.Values("Access-Control-Allow-Methods"))
+
+	for _, method := range probedMethods {
+		if ctx.Err() != nil {
+			break
+		}
+		if resp, ok := s.send(ctx, method, target); ok {
+			result.Statuses[method] = resp.StatusCode
+		}
+	}
+
+	// An unsafe method counts only where it is answered unlike a GET of
+	// the same path, so catch-all pages are not taken for write access
+	success := make(map[string]bool)
+	if s.config.Unsafe && ctx.Err() == nil {
+		probe := u.Scheme + "://" + u.Host + strings.TrimSuffix(u.EscapedPath(), "/") + "/" + randomWord()
+		result.UnsafeURL = probe
+		if control, ok := s.send(ctx, "GET", probe); ok {
+			for _, method := range UnsafeMethods {
+				if ctx.Err() != nil {
+					break
+				}
+				resp, ok := s.send(ctx, method, probe)
+				if !ok || resp.StatusCode == control.StatusCode {
+					continue
+				}
+				result.Statuses[method] = resp.StatusCode
+				success[method] = resp.StatusCode >= 200 && resp.StatusCode < 300
+			}
+		}
+	}
+
+	for method, status := range result.Statuses {
+		if method != "OPTIONS" && status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+			result.Accepted = append(result.Accepted, method)
+		}
+	}
+	sort.Strings(result.Accepted)
+
+	result.Severity = "info"
+	for _, method := range RiskyMethods {
+		if !slices.Contains(result.Allow, method) && !slices.Contains(result.Accepted, method) {
+			continue
+		}
+		result.Risky = append(result.Risky, method)
+		if success[method] {
+			result.Severity = "high"
+		} else if result.Severity != "high" {
+			result.Severity = "medium"
+		}
+	}
+	if len(result.Risky) > 0 {
+		log.FromContext(ctx).Info("risky methods allowed", log.Target(target), "methods", result.Risky)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	return result, true
+}
+
+// send makes one request, reading and discarding the body
+func (s *MethodScanner) send(ctx context.Context, method, target string) (*http.Response, bool) {
+	if !s.config.Scope.Allows(target) {
+		return nil, false
+	}
+	s.limiter.Wait(ctx)
+
+	req, err := s.prober.newRequest(ctx, method, target)
+	if err != nil {
+		return nil, false
+	}
+	if method == "POST" || method == "PUT" || method == "PATCH" {
+		req.Body = io.NopCloser(strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	s.config.Budget.Wait(ctx)
+
+	resp, err := s.prober.client.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Debug("request failed", log.Target(target), "method", method, log.Err(err))
+		return nil, false
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1024*1024))
+	resp.Body.Close()
+	return resp, true
+}
+
+// methodList splits comma-separated method headers into one sorted,
+// upper-case list
+func methodList(headers ...[]string) []string {
+	var methods []string
+	for _, values := range headers {
+		for _, value := range values {
+			for _, method := range strings.Split(value, ",") {
+				method = strings.ToUpper(strings.TrimSpace(method))
+				if method != "" && method != "*" && !slices.Contains(methods, method) {
+					methods = append(methods, method)
+				}
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// MethodExposure is a dangerous method a server has enabled, as the
+// analyzer reports it
+type MethodExposure struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+
+	// Evidence is the response line that shows the method enabled: the
+	// request TRACE or TRACK echoed back, PROPFIND's status line or the
+	// Allow header naming MKCOL
+	Evidence string `json:"evidence"`
+	Severity string `json:"severity"`
+}
+
+// traceMarker is the header TRACE and TRACK requests carry, which a server
+// that has them enabled echoes in the body
+const traceMarker = "X-Scanner-Trace"
+
+// CheckMethods checks a URL's server for TRACE and TRACK, which reflect
+// requests back (cross-site tracing), and the WebDAV methods PROPFIND,
+// which lists content, and MKCOL, which creates collections. MKCOL is
+// never sent: it is found in what OPTIONS allows. Nothing is returned for
+// out-of-scope URLs.
+func (p *Prober) CheckMethods(ctx context.Context, target string) []MethodExposure {
+	if !p.config.Scope.Allows(target) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+	marker := randomWord()
+
+	var exposures []MethodExposure
+	for _, method := range []string{"TRACE", "TRACK", "PROPFIND", "OPTIONS"} {
+		if ctx.Err() != nil {
+			break
+		}
+		req, err := p.newRequest(ctx, method, target)
+		if err != nil {
+			return exposures
+		}
+		switch method {
+		case "TRACE", "TRACK":
+			req.Header.Set(traceMarker, marker)
+		case "PROPFIND":
+			req.Header.Set("Depth", "0")
+		}
+
+		p.wait(ctx)
+		p.config.Budget.Wait(ctx)
+		resp, err := p.client.Do(req)
+		if err != nil {
+			logger.Debug("method check failed", log.Target(target), "method", method, log.Err(err))
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+
+		exposure := MethodExposure{Method: method, URL: target, Status: resp.StatusCode}
+		switch method {
+		case "TRACE", "TRACK":
+			// Only an echo of this request proves the method is on; many
+			// servers answer anything with 200
+			if resp.StatusCode >= 300 || !strings.Contains(string(body), marker) {
+				continue
+			}
+			exposure.Evidence = strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0])
+			exposure.Severity = "low"
+		case "PROPFIND":
+			if resp.StatusCode != http.StatusMultiStatus {
+				continue
+			}
+			exposure.Evidence = resp.Proto + " " + resp.Status
+			exposure.Severity = "medium"
+		case "OPTIONS":
+			allow := append(resp.Header.Values("Allow"), resp.Header.Values("Public")...)
+			if resp.StatusCode >= 300 || !slices.Contains(methodList(allow), "MKCOL") {
+				continue
+			}
+			exposure.Method = "MKCOL"
+			exposure.Evidence = "Allow: " + strings.Join(allow, ", ")
+			exposure.Severity = "medium"
+		}
+		logger.Info("dangerous method enabled", log.Target(target), "method", exposure.Method)
+		exposures = append(exposures, exposure)
+	}
+	return exposures
+}
@@ -0,0 +1,525 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/extract"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// JSConfig holds JavaScript analysis configuration
+type JSConfig struct {
+	// Targets are script URLs
+	Targets []string
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// MaxSize caps how much of each script is downloaded; default 10 MB
+	MaxSize int64
+
+	// SaveDir, if set, receives a beautified copy of each script
+	SaveDir string
+
+	// FollowChunks also analyzes the lazily loaded chunks a webpack
+	// runtime among the scripts names; see extract.WebpackChunks
+	FollowChunks bool
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope scripts
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts scripts analyzed and those with findings
+	Progress *utils.Progress
+
+	// OnResult is called for each script as it is analyzed
+	OnResult func(JSResult)
+}
+
+// JSResult is what one script exposes
+type JSResult struct {
+	URL       string   `json:"url"`
+	Size      int      `json:"size"`
+	Endpoints []string `json:"endpoints,omitempty"`
+	URLs      []string `json:"urls,omitempty"`
+	Secrets   []string `json:"secrets,omitempty"`
+	Sinks     []JSSink `json:"sinks,omitempty"`
+	Chunks    []string `json:"chunks,omitempty"`
+	File      string   `json:"file,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// JSSink is a DOM XSS sink or code execution call in a script, located in
+// the beautified source
+type JSSink struct {
+	Sink string `json:"sink"`
+	Line int    `json:"line"`
+	Code string `json:"code"`
+}
+
+// secretPatternNames are the interestingPatterns that are secrets
+var secretPatternNames = []string{
+	"AWS Key", "Private Key", "API Key", "Password Field",
+	"GitHub Token", "Slack Token", "Google API Key", "Stripe Key",
+}
+
+// sinkPatterns find DOM XSS sinks and code execution, by name
+var sinkPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"innerHTML", regexp.MustCompile(`\.innerHTML\s*\+?=[^=]`)},
+	{"outerHTML", regexp.MustCompile(`\.outerHTML\s*\+?=[^=]`)},
+	{"document.write", regexp.MustCompile(`\bdocument\.write(?:ln)?\s*\(`)},
+	{"insertAdjacentHTML", regexp.MustCompile(`\.insertAdjacentHTML\s*\(`)},
+	{"srcdoc", regexp.MustCompile(`\.srcdoc\s*=[^=]`)},
+	{"eval", regexp.MustCompile(`(?:^|[^.\w$])eval\s*\(`)},
+	{"Function", regexp.MustCompile(`\bnew\s+Function\s*\(`)},
+	{"setTimeout string", regexp.MustCompile(`\bset(?:Timeout|Interval)\s*\(\s*["'\x60]`)},
+	{"location", regexp.MustCompile(`\blocation(?:\.href)?\s*=[^=]|\blocation\.(?:assign|replace)\s*\(`)},
+	{"jQuery html", regexp.MustCompile(`\.(?:html|append|prepend|after|before|replaceWith)\s*\(\s*[^)\s]`)},
+	{"jQuery globalEval", regexp.MustCompile(`\$\.globalEval\s*\(`)},
+	{"dangerouslySetInnerHTML", regexp.MustCompile(`\bdangerouslySetInnerHTML\b`)},
+	{"postMessage handler", regexp.MustCompile(`addEventListener\s*\(\s*["']message["']`)},
+}
+
+// JSAnalyzer downloads scripts and extracts what they expose
+type JSAnalyzer struct {
+	config  JSConfig
+	prober  *Prober
+	limiter *rate.Limiter
+}
+
+// NewJSAnalyzer creates a new JavaScript analyzer
+func NewJSAnalyzer(config JSConfig) *JSAnalyzer {
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 50
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = 10 * 1024 * 1024
+	}
+
+	probeConfig := ProbeConfig{
+		Workers:        config.Workers,
+		Timeout:        config.Timeout,
+		FollowRedirect: true,
+		UserAgent:      config.UserAgent,
+		Headers:        config.Headers,
+		Cookies:        config.Cookies,
+		Proxy:          config.Proxy,
+		Scope:          config.Scope,
+		Budget:         config.Budget,
+	}
+
+	return &JSAnalyzer{
+		config:  config,
+		prober:  NewProber(probeConfig),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// AnalyzeContext downloads and analyzes every script, and with
+// FollowChunks the chunks their runtimes name. If ctx is cancelled, the
+// scripts finished so far are returned with ctx's error.
+func (a *JSAnalyzer) AnalyzeContext(ctx context.Context) ([]JSResult, error) {
+	if a.config.SaveDir != "" {
+		if err := os.MkdirAll(a.config.SaveDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := a.config.Scope.Filter(a.config.Targets)
+	a.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "js")
+	jobs := make(chan string)
+	results := make(chan JSResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				a.limiter.Wait(ctx)
+				results <- a.analyze(ctx, target)
+			}
+		}()
+	}
+
+	// Chunks found along the way join the queue, each once
+	queue := append([]string(nil), targets...)
+	queued := make(map[string]bool)
+	for _, target := range targets {
+		queued[target] = true
+	}
+
+	var analyzed []JSResult
+	inFlight := 0
+	for inFlight > 0 || (len(queue) > 0 && ctx.Err() == nil) {
+		var next string
+		var send chan<- string
+		if len(queue) > 0 && ctx.Err() == nil {
+			next, send = queue[0], jobs
+		}
+
+		select {
+		case send <- next:
+			queue = queue[1:]
+			inFlight++
+		case result := <-results:
+			inFlight--
+			if a.config.FollowChunks {
+				for _, chunk := range a.config.Scope.Filter(result.Chunks) {
+					if !queued[chunk] {
+						queued[chunk] = true
+						queue = append(queue, chunk)
+						a.config.Progress.AddTotal(1)
+					}
+				}
+			}
+
+			a.config.Progress.Done()
+			if len(result.Endpoints)+len(result.URLs)+len(result.Secrets)+len(result.Sinks) > 0 {
+				a.config.Progress.Found()
+				metrics.Findings("js").Inc()
+			}
+			if a.config.OnResult != nil {
+				a.config.OnResult(result)
+			}
+			analyzed = append(analyzed, result)
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return analyzed, ctx.Err()
+}
+
+// analyze downloads, beautifies and analyzes one script
+func (a *JSAnalyzer) analyze(ctx context.Context, target string) JSResult {
+	result := JSResult{
+		URL:       target,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	logger := log.FromContext(ctx).With(log.Target(target))
+	start := time.Now()
+	source, err := a.download(ctx, target)
+	if err != nil {
+		logger.Warn("downloading script", log.Err(err), log.Since(start))
+		result.Error = err.Error()
+		return result
+	}
+	result.Size = len(source)
+
+	pretty := BeautifyJS(source)
+	analysis := AnalyzeJS(target, pretty)
+	base, _ := url.Parse(target)
+	analysis.Chunks = extract.WebpackChunks(source, extract.Options{Base: base})
+	logger.Debug("script analyzed", "size", result.Size, "endpoints", len(analysis.Endpoints),
+		"secrets", len(analysis.Secrets), "sinks", len(analysis.Sinks), "chunks", len(analysis.Chunks), log.Since(start))
+	analysis.Size = result.Size
+	analysis.Timestamp = result.Timestamp
+
+	if a.config.SaveDir != "" {
+		file := filepath.Join(a.config.SaveDir, scriptFileName(target))
+		if err := os.WriteFile(file, []byte(pretty), 0644); err != nil {
+			analysis.Error = err.Error()
+		} else {
+			analysis.File = file
+		}
+	}
+	return analysis
+}
+
+// download fetches a script body up to the size limit
+func (a *JSAnalyzer) download(ctx context.Context, target string) (string, error) {
+	if !a.config.Scope.Allows(target) {
+		return "", fmt.Errorf("out of scope")
+	}
+	req, err := a.prober.newRequest(ctx, "GET", target)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "*/*")
+	a.config.Budget.Wait(ctx)
+
+	resp, err := a.prober.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, a.config.MaxSize))
+	return string(body), err
+}
+
+// AnalyzeJS extracts endpoints, absolute URLs, secrets and DOM sinks from
+// script source. Sink line numbers refer to source as given, so beautify
+// minified code first.
+func AnalyzeJS(scriptURL, source string) JSResult {
+	result := JSResult{URL: scriptURL}
+
+	endpoints := make(map[string]bool)
+	urls := make(map[string]bool)
+	for _, endpoint := range extract.Endpoints(source, extract.Options{LinkFinder: true}) {
+		if extract.IsAbsolute(endpoint) {
+			urls[endpoint] = true
+		} else {
+			endpoints[endpoint] = true
+		}
+	}
+	for _, u := range extract.AbsoluteURLs(source, extract.Options{}) {
+		urls[u] = true
+	}
+	result.Endpoints = sortedKeys(endpoints)
+	result.URLs = sortedKeys(urls)
+
+	seen := make(map[string]bool)
+	for _, name := range secretPatternNames {
+		for _, match := range interestingPatterns[name].FindAllString(source, 5) {
+			secret := name + ": " + match
+			if len(match) < 200 && !seen[secret] {
+				seen[secret] = true
+				result.Secrets = append(result.Secrets, secret)
+			}
+		}
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+		for _, sink := range sinkPatterns {
+			if sink.pattern.MatchString(line) {
+				code := strings.TrimSpace(line)
+				if len(code) > 160 {
+					code = code[:160]
+				}
+				result.Sinks = append(result.Sinks, JSSink{Sink: sink.name, Line: i + 1, Code: code})
+			}
+		}
+	}
+	return result
+}
+
+// BeautifyJS reformats minified JavaScript with one statement per line and
+// indented blocks, leaving strings, comments and regex literals intact. It
+// is a formatter for reading and line-based matching, not a parser.
+func BeautifyJS(source string) string {
+	var b strings.Builder
+	rs := []rune(source)
+	indent, parens := 0, 0
+	lineStart := true
+	var prev rune
+
+	write := func(s string) {
+		if lineStart {
+			b.WriteString(strings.Repeat("  ", indent))
+			lineStart = false
+		}
+		b.WriteString(s)
+	}
+	newline := func() {
+		if !lineStart {
+			b.WriteByte('\n')
+			lineStart = true
+		}
+	}
+
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		next := rune(0)
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := skipString(rs, i)
+			write(string(rs[i:end]))
+			i = end - 1
+			prev = c
+		case c == '/' && next == '/':
+			end := i
+			for end < len(rs) && rs[end] != '\n' {
+				end++
+			}
+			write(string(rs[i:end]))
+			newline()
+			i = end
+		case c == '/' && next == '*':
+			end := i + 2
+			for end+1 < len(rs) && !(rs[end] == '*' && rs[end+1] == '/') {
+				end++
+			}
+			end = min(end+2, len(rs))
+			write(string(rs[i:end]))
+			i = end - 1
+		case c == '/' && (prev == 0 || strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", prev)):
+			end := skipRegex(rs, i)
+			write(string(rs[i:end]))
+			i = end - 1
+			prev = 'a'
+		case c == '{':
+			write("{")
+			indent++
+			newline()
+			prev = c
+		case c == '}':
+			newline()
+			if indent > 0 {
+				indent--
+			}
+			write("}")
+			prev = c
+			rest := strings.TrimLeft(string(rs[i+1:min(i+16, len(rs))]), " \t\r\n")
+			switch {
+			case rest == "", strings.ContainsRune(";,).", rune(rest[0])):
+			case strings.HasPrefix(rest, "else"), strings.HasPrefix(rest, "catch"),
+				strings.HasPrefix(rest, "finally"), strings.HasPrefix(rest, "while"):
+				write(" ")
+			default:
+				newline()
+			}
+		case c == '(':
+			parens++
+			write("(")
+			prev = c
+		case c == ')':
+			if parens > 0 {
+				parens--
+			}
+			write(")")
+			prev = c
+		case c == ';':
+			write(";")
+			prev = c
+			// for (;;) headers stay on one line
+			if parens == 0 {
+				newline()
+			}
+		case c == '\n' || c == '\r':
+			newline()
+		case c == ' ' || c == '\t':
+			if !lineStart && next != 0 && next != ' ' && next != '\t' && next != '\n' && next != '\r' {
+				write(" ")
+			}
+		default:
+			write(string(c))
+			prev = c
+		}
+	}
+	newline()
+	return b.String()
+}
+
+// skipString returns the index just past the string literal starting at
+// start
+func skipString(rs []rune, start int) int {
+	quote := rs[start]
+	for i := start + 1; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(rs)
+}
+
+// skipRegex returns the index just past the regex literal (and flags)
+// starting at start. A line break before the closing slash means it was
+// division after all, so only the slash is consumed.
+func skipRegex(rs []rune, start int) int {
+	inClass := false
+	for i := start + 1; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return start + 1
+		case '/':
+			if inClass {
+				continue
+			}
+			i++
+			for i < len(rs) && (rs[i] >= 'a' && rs[i] <= 'z') {
+				i++
+			}
+			return i
+		}
+	}
+	return start + 1
+}
+
+// scriptFileName turns a script URL into a unique, safe file name
+func scriptFileName(scriptURL string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(scriptURL, "https://"), "http://")
+	name, _, _ = strings.Cut(name, "?")
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	sum := sha1.Sum([]byte(scriptURL))
+	name = strings.TrimSuffix(name, ".js")
+	return name + "-" + hex.EncodeToString(sum[:4]) + ".js"
+}
+
+// sortedKeys returns a set's members in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,520 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/extract"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"github.com/recon-suite/scanner/pkg/wordlist"
+	"golang.org/x/time/rate"
+)
+
+// Parameter mining methods: query string, form body or JSON body
+const (
+	ParamMethodGET  = "GET"
+	ParamMethodPOST = "POST"
+	ParamMethodJSON = "JSON"
+)
+
+// DefaultParams are the candidate names tried when no wordlist is given
+var DefaultParams = []string{
+	"id", "user", "username", "email", "password", "pass", "token", "key", "api_key", "apikey",
+	"secret", "session", "auth", "access_token", "code", "callback", "redirect", "redirect_uri",
+	"return", "return_url", "returnUrl", "next", "url", "uri", "path", "file", "filename", "page",
+	"name", "action", "cmd", "command", "exec", "query", "search", "q", "s", "filter", "sort",
+	"order", "limit", "offset", "page_size", "per_page", "debug", "test", "admin", "config", "data",
+	"json", "xml", "format", "type", "category", "tag", "ref", "source", "target", "dest", "from",
+	"to", "webhook", "lang", "locale", "view", "template", "include", "load", "mode", "preview",
+	"show", "edit", "delete", "update", "upload", "download", "export", "import", "jsonp", "cb",
+	"utm_source", "v", "version", "dir", "folder", "host", "domain", "site", "ip", "port", "role",
+	"group", "account", "uid", "user_id", "account_id", "order_id", "item", "product", "price",
+}
+
+// ParamConfig holds hidden parameter discovery configuration
+type ParamConfig struct {
+	// Targets are the endpoint URLs to mine
+	Targets []string
+
+	// Wordlist is a file of candidate names, one per line; empty means
+	// DefaultParams. Names found in each endpoint's own page are always
+	// tried too.
+	Wordlist string
+
+	// Method is how parameters are sent: ParamMethodGET (query string,
+	// the default), ParamMethodPOST (form body) or ParamMethodJSON
+	Method string
+
+	// BatchSize is how many candidates are sent per request; default 128,
+	// further limited by MaxURLLength for query strings
+	BatchSize    int
+	MaxURLLength int
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts endpoints mined and those with parameters
+	Progress *utils.Progress
+
+	// OnResult is called for each endpoint with confirmed parameters
+	OnResult func(ParamResult)
+}
+
+// ParamResult lists the hidden parameters confirmed on one endpoint
+type ParamResult struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+
+	// Params are the confirmed names; Reasons says what each changed:
+	// reflected, status, headers, words or lines
+	Params    []string          `json:"params"`
+	Reasons   map[string]string `json:"reasons"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// paramResponse is the part of a response compared against the baseline
+type paramResponse struct {
+	status  int
+	headers string
+	words   int
+	lines   int
+	body    string
+}
+
+// paramBaseline is how an endpoint answers unknown parameters, and which
+// of its traits are too unstable to compare
+type paramBaseline struct {
+	response paramResponse
+	unstable map[string]bool
+}
+
+// ParamMiner finds hidden parameters by sending candidate names in
+// batches and narrowing down the batches that change the response
+type ParamMiner struct {
+	config     ParamConfig
+	prober     *Prober
+	limiter    *rate.Limiter
+	candidates []string
+}
+
+// NewParamMiner creates a new parameter miner
+func NewParamMiner(config ParamConfig) *ParamMiner {
+	if config.Method == "" {
+		config.Method = ParamMethodGET
+	}
+	config.Method = strings.ToUpper(config.Method)
+	if config.BatchSize == 0 {
+		config.BatchSize = 128
+	}
+	if config.MaxURLLength == 0 {
+		config.MaxURLLength = 4000
+	}
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 50
+	}
+
+	probeConfig := ProbeConfig{
+		Workers:   config.Workers,
+		Timeout:   config.Timeout,
+		UserAgent: config.UserAgent,
+		Headers:   config.Headers,
+		Cookies:   config.Cookies,
+		Proxy:     config.Proxy,
+		Scope:     config.Scope,
+		Budget:    config.Budget,
+	}
+
+	return &ParamMiner{
+		config:  config,
+		prober:  NewProber(probeConfig),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// MineContext mines every target and returns those with confirmed
+// parameters. If ctx is cancelled, the endpoints finished so far are
+// returned with ctx's error.
+func (m *ParamMiner) MineContext(ctx context.Context) ([]ParamResult, error) {
+	switch m.config.Method {
+	case ParamMethodGET, ParamMethodPOST, ParamMethodJSON:
+	default:
+		return nil, fmt.Errorf("unknown parameter method %q (want GET, POST or JSON)", m.config.Method)
+	}
+
+	m.candidates = DefaultParams
+	if m.config.Wordlist != "" {
+		names, err := loadParamNames(m.config.Wordlist)
+		if err != nil {
+			return nil, err
+		}
+		m.candidates = names
+	}
+
+	targets := m.config.Scope.Filter(m.config.Targets)
+	m.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "params")
+	jobs := make(chan string)
+	results := make(chan ParamResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < m.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				result, ok := m.mine(ctx, target)
+				m.config.Progress.Done()
+				if ok {
+					results <- result
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var mined []ParamResult
+	for result := range results {
+		m.config.Progress.Found()
+		metrics.Findings("params").Inc()
+		if m.config.OnResult != nil {
+			m.config.OnResult(result)
+		}
+		mined = append(mined, result)
+	}
+	return mined, ctx.Err()
+}
+
+// mine finds the hidden parameters of one endpoint
+func (m *ParamMiner) mine(ctx context.Context, target string) (ParamResult, bool) {
+	result := ParamResult{
+		URL:       target,
+		Method:    m.config.Method,
+		Reasons:   make(map[string]string),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	logger := log.FromContext(ctx).With(log.Target(target))
+	start := time.Now()
+	baseline, ok := m.baseline(ctx, target)
+	if !ok {
+		logger.Info("no baseline response, endpoint skipped", log.Since(start))
+		return result, false
+	}
+
+	// Names the page itself uses come first; they are the likeliest hits
+	var candidates []string
+	seen := make(map[string]bool)
+	existing := existingParams(target)
+	for _, list := range [][]string{extract.Parameters(baseline.response.body, extract.Options{}), m.candidates} {
+		for _, name := range list {
+			if !seen[name] && !existing[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+
+	for _, batch := range m.batches(target, candidates) {
+		m.narrow(ctx, target, batch, baseline, result.Reasons)
+	}
+	if ctx.Err() != nil {
+		return result, false
+	}
+	if len(result.Reasons) == 0 {
+		logger.Debug("no parameters changed the response", "candidates", len(candidates), log.Since(start))
+		return result, false
+	}
+
+	for name := range result.Reasons {
+		result.Params = append(result.Params, name)
+	}
+	sort.Strings(result.Params)
+	return result, true
+}
+
+// baseline requests an endpoint twice with different junk parameters, to
+// learn how it answers unknown names and which traits vary by themselves
+func (m *ParamMiner) baseline(ctx context.Context, target string) (paramBaseline, bool) {
+	first, firstValues := m.junk()
+	second, secondValues := m.junk()
+	a, ok := m.send(ctx, target, first)
+	if !ok {
+		return paramBaseline{}, false
+	}
+	b, ok := m.send(ctx, target, second)
+	if !ok {
+		return paramBaseline{}, false
+	}
+
+	unstable := map[string]bool{
+		"status":  a.status != b.status,
+		"headers": a.headers != b.headers,
+		"words":   a.words != b.words,
+		"lines":   a.lines != b.lines,
+
+		// A page echoing any value, such as its own URL, makes reflection
+		// meaningless
+		"reflected": reflects(a.body, firstValues) || reflects(b.body, secondValues),
+	}
+	return paramBaseline{response: a, unstable: unstable}, true
+}
+
+// narrow sends a batch and, if the response differs from the baseline,
+// splits it until the responsible names are found
+func (m *ParamMiner) narrow(ctx context.Context, target string, batch map[string]string, baseline paramBaseline, reasons map[string]string) {
+	if ctx.Err() != nil || len(batch) == 0 {
+		return
+	}
+	resp, ok := m.send(ctx, target, batch)
+	if !ok {
+		return
+	}
+
+	// Reflected values name their parameters directly
+	if !baseline.unstable["reflected"] {
+		reflected := false
+		for name, value := range batch {
+			if strings.Contains(resp.body, value) {
+				reasons[name] = "reflected"
+				reflected = true
+			}
+		}
+		if reflected {
+			rest := make(map[string]string)
+			for name, value := range batch {
+				if reasons[name] == "" {
+					rest[name] = value
+				}
+			}
+			m.narrow(ctx, target, rest, baseline, reasons)
+			return
+		}
+	}
+
+	reason := baseline.differs(resp)
+	if reason == "" {
+		return
+	}
+	if len(batch) == 1 {
+		for name := range batch {
+			reasons[name] = reason
+		}
+		return
+	}
+
+	names := make([]string, 0, len(batch))
+	for name := range batch {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	half := len(names) / 2
+	for _, part := range [][]string{names[:half], names[half:]} {
+		sub := make(map[string]string, len(part))
+		for _, name := range part {
+			sub[name] = batch[name]
+		}
+		m.narrow(ctx, target, sub, baseline, reasons)
+	}
+}
+
+// differs names the first stable trait a response changes, or ""
+func (b paramBaseline) differs(resp paramResponse) string {
+	switch {
+	case !b.unstable["status"] && resp.status != b.response.status:
+		return "status"
+	case !b.unstable["headers"] && resp.headers != b.response.headers:
+		return "headers"
+	case !b.unstable["words"] && resp.words != b.response.words:
+		return "words"
+	case !b.unstable["lines"] && resp.lines != b.response.lines:
+		return "lines"
+	}
+	return ""
+}
+
+// batches splits candidates into name-to-canary batches, keeping query
+// strings under the URL length limit
+func (m *ParamMiner) batches(target string, candidates []string) []map[string]string {
+	var batches []map[string]string
+	batch := make(map[string]string)
+	length := len(target)
+	for _, name := range candidates {
+		value := randomCanary()
+		size := len(url.QueryEscape(name)) + len(value) + 2
+		full := len(batch) >= m.config.BatchSize ||
+			(m.config.Method == ParamMethodGET && length+size > m.config.MaxURLLength)
+		if full && len(batch) > 0 {
+			batches = append(batches, batch)
+			batch = make(map[string]string)
+			length = len(target)
+		}
+		batch[name] = value
+		length += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// send requests an endpoint with extra parameters
+func (m *ParamMiner) send(ctx context.Context, target string, params map[string]string) (paramResponse, bool) {
+	m.limiter.Wait(ctx)
+	if !m.config.Scope.Allows(target) {
+		return paramResponse{}, false
+	}
+
+	method, body, contentType := "GET", "", ""
+	switch m.config.Method {
+	case ParamMethodGET:
+		u, err := url.Parse(target)
+		if err != nil {
+			return paramResponse{}, false
+		}
+		query := u.Query()
+		for name, value := range params {
+			query.Set(name, value)
+		}
+		u.RawQuery = query.Encode()
+		target = u.String()
+	case ParamMethodPOST:
+		form := url.Values{}
+		for name, value := range params {
+			form.Set(name, value)
+		}
+		method, body, contentType = "POST", form.Encode(), "application/x-www-form-urlencoded"
+	case ParamMethodJSON:
+		data, _ := json.Marshal(params)
+		method, body, contentType = "POST", string(data), "application/json"
+	}
+
+	req, err := m.prober.newRequest(ctx, method, target)
+	if err != nil {
+		return paramResponse{}, false
+	}
+	if body != "" {
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", contentType)
+	}
+	m.config.Budget.Wait(ctx)
+
+	resp, err := m.prober.client.Do(req)
+	if err != nil {
+		return paramResponse{}, false
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
+	var names []string
+	for name := range resp.Header {
+		// Varying headers say nothing about parameters
+		switch name {
+		case "Date", "Expires", "Age", "Set-Cookie", "Content-Length", "Etag", "Last-Modified":
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	text := string(data)
+	return paramResponse{
+		status:  resp.StatusCode,
+		headers: strings.Join(names, ","),
+		words:   len(strings.Fields(text)),
+		lines:   strings.Count(text, "\n"),
+		body:    text,
+	}, true
+}
+
+// junk returns a parameter no application knows, with its value
+func (m *ParamMiner) junk() (map[string]string, []string) {
+	value := randomCanary()
+	return map[string]string{randomCanary(): value}, []string{value}
+}
+
+// reflects reports whether body contains any of the values
+func reflects(body string, values []string) bool {
+	for _, v := range values {
+		if strings.Contains(body, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingParams returns the names already in a URL's query string
+func existingParams(target string) map[string]bool {
+	names := make(map[string]bool)
+	if u, err := url.Parse(target); err == nil {
+		for name := range u.Query() {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// randomCanary returns a short random lower-case value unlikely to occur
+// in any page
+func randomCanary() string {
+	return "x" + randomWord()[:9]
+}
+
+// loadParamNames reads a parameter wordlist, skipping blanks and comments
+func loadParamNames(path string) ([]string, error) {
+	names, err := wordlist.ReadWords(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("wordlist %s is empty", path)
+	}
+	return names, nil
+}
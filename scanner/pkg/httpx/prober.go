@@ -0,0 +1,885 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/tlsscan"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/dnscache"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"github.com/recon-suite/scanner/pkg/vulndb"
+	"golang.org/x/time/rate"
+)
+
+// ProbeConfig holds HTTP prober configuration
+type ProbeConfig struct {
+	Targets        []string
+	Workers        int
+	Timeout        int
+	FollowRedirect bool
+	MaxRedirects   int
+	TLSVerify      bool
+	Retries        int
+	RateLimit      int
+	UserAgent      string
+	Headers        map[string]string
+
+	// UserAgents, if set, are rotated round-robin instead of using UserAgent
+	UserAgents []string
+
+	// Profiles, if set, are rotated round-robin in place of UserAgent and
+	// UserAgents, each request carrying one profile's User-Agent and
+	// headers. Headers still override theirs.
+	Profiles []BrowserProfile
+
+	// Cookies are sent with every request
+	Cookies map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// OnResult is called for each live target as it is probed
+	OnResult func(ProbeResult)
+
+	// Discard keeps live results out of ProbeContext's return value, for
+	// runs whose results OnResult writes out as they arrive
+	Discard bool
+
+	// Feed, if set, supplies more targets as they arrive, after Targets.
+	// The probe then runs until Feed is closed or ctx is cancelled, with
+	// no time limit, and implies Discard, as it may never end.
+	Feed <-chan string
+
+	// OnTargetDone is called once per target after every URL form of it has
+	// been tried, with the live result or one with a zero StatusCode. It may
+	// be called concurrently.
+	OnTargetDone func(target string, result ProbeResult)
+
+	// Progress, if set, counts probed targets and live URLs found
+	Progress *utils.Progress
+
+	// Budget, if set, is a request rate shared with other modules, applied on
+	// top of RateLimit
+	Budget *utils.Budget
+
+	// Scope, if set, skips out-of-scope targets, stops redirects that
+	// would leave scope and refuses connections to excluded addresses
+	// hostnames resolve to
+	Scope *scope.Scope
+
+	// CVEs, if set, is checked for the product versions named in the Server
+	// and X-Powered-By headers
+	CVEs *vulndb.DB
+
+	// MaxBodySize caps how many bytes of a response are read; default 100KB
+	MaxBodySize int64
+
+	// BodyTypes, if set, are the content types whose bodies are read, as
+	// media types ("application/json") or whole types ("text/*"); other
+	// responses, such as PDFs and videos, are reported from their headers
+	// alone. A response without a Content-Type is read.
+	BodyTypes []string
+
+	// KeepBody keeps the body read in ProbeResult.Body so later stages can
+	// analyze or crawl it without fetching the URL again
+	KeepBody bool
+
+	// MaxIdleConns and MaxIdleConnsPerHost size the pool of kept-alive
+	// connections, and IdleConnTimeout how long an idle one is kept. Zero
+	// values are sized from the workload: with many distinct hosts, each
+	// probed once or twice, few idle connections are kept per host, and
+	// with few hosts each keeps one per worker that may share it.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// MaxConnsPerHost caps the connections open to one host, in use or
+	// idle; zero is unlimited
+	MaxConnsPerHost int
+
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool
+
+	// TargetLatency, if set, makes the rate adaptive: it starts at
+	// RateLimit, backs off toward a twentieth of it while the average
+	// response time is above TargetLatency or requests fail or are
+	// throttled, and climbs back as responses speed up
+	TargetLatency time.Duration
+
+	// Favicon fetches each live site's favicon and names the product it
+	// identifies in Technologies
+	Favicon bool
+
+	// Favicons, if set, is used instead of the embedded favicon
+	// fingerprints
+	Favicons *FaviconDB
+
+	// CircuitBreaker, if set, is the number of consecutive failed requests
+	// to a host (and port) after which its remaining URLs and retries are
+	// skipped for CircuitCooldown (default 30s); then one request is tried
+	// again, and the host resumes if it answers
+	CircuitBreaker  int
+	CircuitCooldown time.Duration
+
+	// Throttle, if set, slows down and may pause hosts whose responses
+	// look like blocking; it may be shared with other modules so a host
+	// blocking one stays slowed for the next
+	Throttle *utils.HostThrottle
+
+	// DNSCache, if set, resolves hostnames for every connection, reusing
+	// answers for their TTL instead of resolving per connection
+	DNSCache *dnscache.Cache
+
+	// FollowClientRedirects follows pages that redirect with a meta
+	// refresh or by setting the location in a script, as parked and WAF
+	// pages do, up to MaxRedirects of them. The result is the page reached,
+	// with FinalURL set to its URL.
+	FollowClientRedirects bool
+
+	// HeadFirst sends HEAD instead of GET, saving the bodies' traffic on
+	// large target lists: status, headers, redirects and header-based
+	// technologies are still reported, but not the title or what the body
+	// shows. GET is sent when HEAD is rejected (400, 405 or 501), and is
+	// always used when KeepBody, Favicon or FollowClientRedirects need the
+	// body.
+	HeadFirst bool
+}
+
+// ProbeResult holds the result of an HTTP probe
+type ProbeResult struct {
+	URL           string            `json:"url"`
+	StatusCode    int               `json:"status_code"`
+	ContentLength int64             `json:"content_length"`
+	ContentType   string            `json:"content_type,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Server        string            `json:"server,omitempty"`
+	Technologies  []string          `json:"technologies,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Redirected    bool              `json:"redirected,omitempty"`
+	FinalURL      string            `json:"final_url,omitempty"`
+
+	// RedirectChain lists the redirects followed to reach the page, in
+	// order
+	RedirectChain []RedirectHop `json:"redirect_chain,omitempty"`
+
+	// ClientRedirect is where the page redirects with a meta refresh or a
+	// script, and ClientRedirectType which of the two. When following
+	// them, it is only left set if the chain was cut short.
+	ClientRedirect     string `json:"client_redirect,omitempty"`
+	ClientRedirectType string `json:"client_redirect_type,omitempty"`
+
+	// Method is set to HEAD when the result was had without a GET, so
+	// has no title or body
+	Method string `json:"method,omitempty"`
+
+	ResponseTime int64  `json:"response_time_ms"`
+	Timestamp    string `json:"timestamp"`
+
+	// Products are the versioned software named in the response headers,
+	// and CVEs the known vulnerabilities listed for them
+	Products []vulndb.Product `json:"products,omitempty"`
+	CVEs     []vulndb.Match   `json:"cves,omitempty"`
+
+	// The favicon fetched with ProbeConfig.Favicon, its Shodan-style mmh3
+	// hash and its MD5 digest
+	FaviconURL  string `json:"favicon_url,omitempty"`
+	FaviconHash int32  `json:"favicon_hash,omitempty"`
+	FaviconMD5  string `json:"favicon_md5,omitempty"`
+
+	// CertNames are the hostnames the server's certificate names in its
+	// CN and SANs
+	CertNames []string `json:"cert_names,omitempty"`
+
+	// CertIssues are the problems with that certificate for the host
+	// probed: expired, not covering the host, self-signed or a device's
+	// factory default. They are found whether or not TLSVerify is set.
+	CertIssues []tlsscan.Issue `json:"cert_issues,omitempty"`
+
+	// Body is the response body, up to MaxBodySize, set when
+	// ProbeConfig.KeepBody is
+	Body string `json:"body,omitempty"`
+
+	// BodySkipped is set when the body was not read because its content
+	// type is not among ProbeConfig.BodyTypes
+	BodySkipped bool `json:"body_skipped,omitempty"`
+}
+
+// RedirectHop is one redirect followed while probing
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+
+	// Type is set for client-side redirects: RedirectMetaRefresh or
+	// RedirectJavaScript
+	Type string `json:"type,omitempty"`
+}
+
+// Prober handles HTTP probing operations
+type Prober struct {
+	config   ProbeConfig
+	client   *http.Client
+	limiter  *rate.Limiter
+	adaptive *utils.AdaptiveRateLimiter
+	breakers *utils.PerHostCircuitBreaker
+	uaIndex  atomic.Uint64
+}
+
+// DefaultProbeRateLimit is the requests per second when ProbeConfig.RateLimit is unset
+const DefaultProbeRateLimit = 500
+
+// defaultUserAgent is sent when no User-Agent is configured
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// NewProber creates a new HTTP prober
+func NewProber(config ProbeConfig) *Prober {
+	if config.Workers == 0 {
+		config.Workers = 100
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = 5
+	}
+	if config.Retries == 0 {
+		config.Retries = 2
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = DefaultProbeRateLimit
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 100 * 1024
+	}
+	if config.CircuitCooldown == 0 {
+		config.CircuitCooldown = 30 * time.Second
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+	sizePool(&config)
+
+	// Create transport with TLS config
+	transport := &http.Transport{
+		Proxy: utils.ProxyFunc(config.Proxy),
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: !config.TLSVerify,
+		},
+		DialContext: config.DNSCache.DialContext(&net.Dialer{
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   config.Scope.Control,
+		}),
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     config.MaxConnsPerHost,
+		IdleConnTimeout:     config.IdleConnTimeout,
+		DisableKeepAlives:   config.DisableKeepAlives,
+		DisableCompression:  true,
+	}
+
+	// Create client with redirect policy; bodies are counted as sent and
+	// decoded after
+	client := &http.Client{
+		Transport: decompressTransport(metrics.Transport(transport, "http")),
+		Timeout:   time.Duration(config.Timeout) * time.Second,
+	}
+
+	if !config.FollowRedirect {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	} else {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if !config.Scope.Allows(req.URL.String()) {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= config.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", config.MaxRedirects)
+			}
+			return nil
+		}
+	}
+
+	prober := &Prober{
+		config:  config,
+		client:  client,
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+	if config.TargetLatency > 0 {
+		maxRate := float64(config.RateLimit)
+		prober.adaptive = utils.NewAdaptiveRateLimiter(maxRate, max(1, maxRate/20), maxRate, config.TargetLatency)
+	}
+	if config.CircuitBreaker > 0 {
+		prober.breakers = utils.NewPerHostCircuitBreaker(config.CircuitBreaker, config.CircuitCooldown)
+	}
+	return prober
+}
+
+// sizePool fills in the connection pool settings left at zero from the
+// number of distinct hosts among the targets and the workers sharing them.
+// A prober without targets, as used by the crawler and analyzer, expects
+// many requests to few hosts.
+func sizePool(config *ProbeConfig) {
+	hosts := make(map[string]bool)
+	for _, target := range config.Targets {
+		if _, rest, ok := strings.Cut(target, "://"); ok {
+			target = rest
+		}
+		host, _, _ := strings.Cut(target, "/")
+		hosts[strings.ToLower(host)] = true
+	}
+
+	perHost := config.Workers
+	idleTimeout := 90 * time.Second
+	if len(hosts) > 0 {
+		// Each target is tried over https and http, so a host's connection
+		// is rarely reused for long once workers move on
+		perHost = max(2, (config.Workers+len(hosts)-1)/len(hosts))
+		if len(hosts) >= config.Workers {
+			idleTimeout = 15 * time.Second
+		}
+	}
+	if config.MaxConnsPerHost > 0 {
+		perHost = min(perHost, config.MaxConnsPerHost)
+	}
+
+	if config.MaxIdleConnsPerHost == 0 {
+		config.MaxIdleConnsPerHost = perHost
+	}
+	if config.MaxIdleConns == 0 {
+		// Enough for every worker to park a connection, without letting
+		// thousands of hosts each hold one open
+		config.MaxIdleConns = max(100, config.Workers*2)
+	}
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = idleTimeout
+	}
+}
+
+// Probe performs HTTP probing on all targets
+func (p *Prober) Probe() ([]ProbeResult, error) {
+	return p.ProbeContext(context.Background())
+}
+
+// ProbeContext probes all targets until done or ctx is cancelled. On
+// cancellation it returns the live results collected so far with ctx's error.
+func (p *Prober) ProbeContext(parent context.Context) ([]ProbeResult, error) {
+	ctx, cancel := context.WithCancel(log.WithModule(parent, "probe"))
+	defer cancel()
+	if p.config.Feed == nil {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, 30*time.Minute)
+		defer cancelTimeout()
+	}
+
+	targets := p.config.Scope.Filter(p.config.Targets)
+	p.config.Progress.AddTotal(len(targets))
+
+	pool := utils.NewPool(ctx, utils.PoolConfig{Workers: p.config.Workers}, p.probeTarget)
+	go func() {
+		defer pool.Close()
+		for _, target := range targets {
+			if pool.Submit(ctx, target) != nil {
+				return
+			}
+		}
+		for {
+			target, ok := utils.Next(ctx, p.config.Feed)
+			if !ok {
+				return
+			}
+			if !p.config.Scope.Allows(target) {
+				continue
+			}
+			p.config.Progress.AddTotal(1)
+			if pool.Submit(ctx, target) != nil {
+				return
+			}
+		}
+	}()
+
+	// Collect successful probes, keeping any found before cancellation
+	var probed []ProbeResult
+	for r := range pool.Results() {
+		if r.Value.StatusCode > 0 {
+			p.config.Progress.Found()
+			metrics.Findings("probe").Inc()
+			if p.config.OnResult != nil {
+				p.config.OnResult(r.Value)
+			}
+			if !p.config.Discard && p.config.Feed == nil {
+				probed = append(probed, r.Value)
+			}
+		}
+	}
+
+	return probed, parent.Err()
+}
+
+// probeTarget probes a target's URLs in turn, returning the first that
+// answers, or the last failure
+func (p *Prober) probeTarget(ctx context.Context, target string) (ProbeResult, error) {
+	p.wait(ctx)
+
+	start := time.Now()
+	var result ProbeResult
+	for _, url := range p.normalizeURL(target) {
+		result = p.probeWithRetry(ctx, url)
+		if result.StatusCode > 0 {
+			break // Found working URL, skip alternates
+		}
+	}
+	p.config.Progress.Done()
+
+	logger := log.FromContext(ctx)
+	if result.StatusCode > 0 {
+		logger.Debug("target answered", log.Target(target), "url", result.URL, "status", result.StatusCode, log.Since(start))
+	} else if ctx.Err() == nil {
+		logger.Info("target did not answer", log.Target(target), log.Since(start))
+	}
+
+	// An interrupted target was not fully tried, so is not done
+	if ctx.Err() == nil && p.config.OnTargetDone != nil {
+		p.config.OnTargetDone(target, result)
+	}
+	return result, nil
+}
+
+// normalizeURL ensures URL has scheme
+func (p *Prober) normalizeURL(target string) []string {
+	target = strings.TrimSpace(target)
+
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return []string{target}
+	}
+
+	// Try HTTPS first, then HTTP
+	return []string{
+		"https://" + target,
+		"http://" + target,
+	}
+}
+
+// probeWithRetry probes URL with retry logic
+func (p *Prober) probeWithRetry(ctx context.Context, url string) ProbeResult {
+	var lastResult ProbeResult
+
+	for attempt := 0; attempt <= p.config.Retries; attempt++ {
+		if attempt > 0 {
+			// A host whose circuit opened is not worth waiting on
+			if breaker := p.breaker(url); breaker != nil && breaker.State() == utils.CircuitOpen {
+				break
+			}
+			// Exponential backoff
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+
+		result := p.probe(ctx, url)
+		if result.StatusCode > 0 {
+			return result
+		}
+		lastResult = result
+	}
+
+	return lastResult
+}
+
+// breaker returns the circuit breaker of url's scheme and host, or nil if
+// CircuitBreaker is not set
+func (p *Prober) breaker(url string) *utils.CircuitBreaker {
+	if p.breakers == nil {
+		return nil
+	}
+	scheme, _, _ := strings.Cut(url, "://")
+	return p.breakers.Get(strings.ToLower(scheme) + "://" + hostOf(url))
+}
+
+// probe sends HTTP request and extracts information
+func (p *Prober) probe(ctx context.Context, url string) ProbeResult {
+	if p.headOnly() {
+		result, _ := p.fetch(ctx, http.MethodHead, url)
+		if !headRejected(result.StatusCode) {
+			return result
+		}
+		log.FromContext(ctx).Debug("HEAD rejected, sending GET", log.Target(url), "status", result.StatusCode)
+	}
+	result, _ := p.Fetch(ctx, url)
+	if p.config.FollowClientRedirects && result.ClientRedirect != "" {
+		result = p.followClientRedirects(ctx, result)
+	}
+	return result
+}
+
+// headOnly reports whether targets are probed with HEAD: HeadFirst is set
+// and nothing needs the body
+func (p *Prober) headOnly() bool {
+	return p.config.HeadFirst && !p.config.KeepBody && !p.config.Favicon && !p.config.FollowClientRedirects
+}
+
+// headRejected reports whether a HEAD request's status says the server
+// does not take HEAD, rather than how the URL answers
+func headRejected(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// Fetch probes a single URL and also returns the (size-limited) body
+func (p *Prober) Fetch(ctx context.Context, url string) (ProbeResult, string) {
+	return p.fetch(ctx, http.MethodGet, url)
+}
+
+// fetch probes url with a GET or HEAD request
+func (p *Prober) fetch(ctx context.Context, method, url string) (ProbeResult, string) {
+	result := ProbeResult{
+		URL:       url,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	logger := log.FromContext(ctx)
+	if !p.config.Scope.Allows(url) {
+		logger.Debug("out of scope, not requested", log.Target(url))
+		return result, ""
+	}
+
+	req, err := p.newRequest(ctx, method, url)
+	if err != nil {
+		logger.Debug("building request", log.Target(url), log.Err(err))
+		return result, ""
+	}
+	if method == http.MethodHead {
+		result.Method = method
+	}
+	if err := p.config.Throttle.Wait(ctx, hostOf(url)); err != nil {
+		return result, ""
+	}
+	breaker := p.breaker(url)
+	var call utils.CircuitCall
+	if breaker != nil {
+		var ok bool
+		if call, ok = breaker.Allow(); !ok {
+			logger.Debug("host's circuit is open, not requested", log.Target(url))
+			return result, ""
+		}
+	}
+	p.config.Budget.Wait(ctx)
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	elapsed := time.Since(start)
+	result.ResponseTime = elapsed.Milliseconds()
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	p.recordThrottle(ctx, url, status, err)
+
+	if err != nil {
+		logger.Debug("request failed", log.Target(url), log.Err(err), log.Duration(elapsed))
+		if ctx.Err() == nil {
+			p.adaptive.RecordError()
+			if breaker != nil {
+				breaker.Failure(call)
+			}
+		} else if breaker != nil {
+			// Cancelled, which says nothing about the host
+			breaker.Release(call)
+		}
+		return result, ""
+	}
+	if breaker != nil {
+		breaker.Success(call)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		p.adaptive.RecordError()
+	} else {
+		p.adaptive.RecordLatency(elapsed)
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.ContentLength = resp.ContentLength
+
+	// Extract headers
+	result.Headers = make(map[string]string)
+	for key, values := range resp.Header {
+		result.Headers[key] = strings.Join(values, ", ")
+	}
+
+	// Content-Type
+	result.ContentType = resp.Header.Get("Content-Type")
+
+	// Server header
+	result.Server = resp.Header.Get("Server")
+
+	// Versions for CVE correlation
+	result.Products = vulndb.Fingerprint(result.Server + "\n" + strings.Join(resp.Header.Values("X-Powered-By"), "\n"))
+	result.CVEs = p.config.CVEs.LookupAll(result.Products)
+
+	// Check if redirected
+	if resp.Request.URL.String() != url {
+		result.Redirected = true
+		result.FinalURL = resp.Request.URL.String()
+	}
+	result.RedirectChain = redirectChain(resp)
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		leaf := resp.TLS.PeerCertificates[0]
+		result.CertNames = tlsscan.Hostnames(leaf.Subject.CommonName, leaf.DNSNames)
+		host := resp.Request.URL.Hostname()
+		result.CertIssues = tlsscan.CertificateIssues(host, tlsscan.InspectCertificate(host, resp.TLS.PeerCertificates))
+	}
+
+	// Read body for title and tech detection
+	var body []byte
+	if readsBody(result.ContentType, p.config.BodyTypes) {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, p.config.MaxBodySize))
+	} else if method != http.MethodHead {
+		result.BodySkipped = true
+	}
+	bodyStr := string(body)
+	if p.config.KeepBody {
+		result.Body = bodyStr
+	}
+
+	// Extract title
+	result.Title = extractTitle(bodyStr)
+
+	if resp.StatusCode < 300 {
+		result.ClientRedirect, result.ClientRedirectType = clientRedirect(resp.Request.URL, bodyStr)
+	}
+
+	// Detect technologies
+	result.Technologies = detectTechnologies(resp.Header, bodyStr)
+
+	if p.config.Favicon && resp.StatusCode < 500 {
+		p.fetchFavicon(ctx, &result, resp.Request.URL.String(), bodyStr)
+	}
+
+	return result, bodyStr
+}
+
+// readsBody reports whether a response of contentType is read, given the
+// BodyTypes allowed
+func readsBody(contentType string, types []string) bool {
+	if len(types) == 0 || contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, allowed := range types {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType || allowed == major+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectChain returns the redirects the client followed to get resp,
+// walking back through the responses that caused each request
+func redirectChain(resp *http.Response) []RedirectHop {
+	var chain []RedirectHop
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hop := req.Response
+		chain = append(chain, RedirectHop{
+			URL:        hop.Request.URL.String(),
+			StatusCode: hop.StatusCode,
+			Location:   hop.Header.Get("Location"),
+		})
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// recordThrottle tells the throttle how url's host answered, logging when
+// that slows down or pauses the host. Cancelled requests say nothing about
+// the host and are not counted.
+func (p *Prober) recordThrottle(ctx context.Context, url string, status int, err error) {
+	if p.config.Throttle == nil || ctx.Err() != nil {
+		return
+	}
+	host := hostOf(url)
+	switch p.config.Throttle.Record(host, utils.BlockedResponse(status, err)) {
+	case utils.ThrottleSlowed:
+		log.FromContext(ctx).Info("host looks like it is blocking, slowing down", log.Target(host), "rate", p.config.Throttle.Rate(host))
+	case utils.ThrottlePaused:
+		log.FromContext(ctx).Warn("host still blocking at the slowest rate, pausing", log.Target(host), log.Duration(p.config.Throttle.Cooldown()))
+	}
+}
+
+// wait paces requests at the adaptive rate if there is one, or RateLimit
+func (p *Prober) wait(ctx context.Context) {
+	if p.adaptive != nil {
+		p.adaptive.Wait(ctx)
+		return
+	}
+	p.limiter.Wait(ctx)
+}
+
+// newRequest builds a request carrying the configured user agent, headers and cookies
+func (p *Prober) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers
+	if len(p.config.Profiles) > 0 {
+		profile := p.config.Profiles[(p.uaIndex.Add(1)-1)%uint64(len(p.config.Profiles))]
+		req.Header.Set("User-Agent", profile.UserAgent)
+		for key, value := range profile.Headers {
+			req.Header.Set(key, value)
+		}
+	} else {
+		req.Header.Set("User-Agent", p.userAgent())
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	}
+
+	for key, value := range p.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	for name, value := range p.config.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return req, nil
+}
+
+// userAgent returns the next user agent in the rotation
+func (p *Prober) userAgent() string {
+	if len(p.config.UserAgents) == 0 {
+		return p.config.UserAgent
+	}
+	idx := p.uaIndex.Add(1) - 1
+	return p.config.UserAgents[idx%uint64(len(p.config.UserAgents))]
+}
+
+// extractTitle extracts page title from HTML
+func extractTitle(body string) string {
+	// Case-insensitive title extraction
+	re := regexp.MustCompile(`(?i)<title[^>]*>([^<]+)</title>`)
+	matches := re.FindStringSubmatch(body)
+	if len(matches) > 1 {
+		title := strings.TrimSpace(matches[1])
+		// Truncate long titles
+		if len(title) > 100 {
+			title = title[:100] + "..."
+		}
+		return title
+	}
+	return ""
+}
+
+// detectTechnologies identifies technologies from response
+func detectTechnologies(headers http.Header, body string) []string {
+	var techs []string
+	seen := make(map[string]bool)
+
+	addTech := func(tech string) {
+		if !seen[tech] {
+			seen[tech] = true
+			techs = append(techs, tech)
+		}
+	}
+
+	// Header-based detection
+	server := headers.Get("Server")
+	if server != "" {
+		if strings.Contains(strings.ToLower(server), "nginx") {
+			addTech("Nginx")
+		}
+		if strings.Contains(strings.ToLower(server), "apache") {
+			addTech("Apache")
+		}
+		if strings.Contains(strings.ToLower(server), "cloudflare") {
+			addTech("Cloudflare")
+		}
+		if strings.Contains(strings.ToLower(server), "iis") {
+			addTech("IIS")
+		}
+	}
+
+	poweredBy := headers.Get("X-Powered-By")
+	if poweredBy != "" {
+		if strings.Contains(strings.ToLower(poweredBy), "php") {
+			addTech("PHP")
+		}
+		if strings.Contains(strings.ToLower(poweredBy), "asp.net") {
+			addTech("ASP.NET")
+		}
+		if strings.Contains(strings.ToLower(poweredBy), "express") {
+			addTech("Express.js")
+		}
+	}
+
+	// Body-based detection
+	bodyLower := strings.ToLower(body)
+
+	// JavaScript frameworks
+	if strings.Contains(bodyLower, "react") || strings.Contains(body, "__NEXT_DATA__") {
+		addTech("React")
+	}
+	if strings.Contains(body, "ng-") || strings.Contains(bodyLower, "angular") {
+		addTech("Angular")
+	}
+	if strings.Contains(bodyLower, "vue") {
+		addTech("Vue.js")
+	}
+	if strings.Contains(bodyLower, "jquery") {
+		addTech("jQuery")
+	}
+
+	// CMS
+	if strings.Contains(bodyLower, "wordpress") || strings.Contains(body, "wp-content") {
+		addTech("WordPress")
+	}
+	if strings.Contains(bodyLower, "drupal") {
+		addTech("Drupal")
+	}
+	if strings.Contains(bodyLower, "joomla") {
+		addTech("Joomla")
+	}
+
+	// Frameworks
+	if strings.Contains(bodyLower, "laravel") {
+		addTech("Laravel")
+	}
+	if strings.Contains(bodyLower, "django") {
+		addTech("Django")
+	}
+	if strings.Contains(bodyLower, "rails") || strings.Contains(body, "csrf-token") {
+		addTech("Ruby on Rails")
+	}
+
+	// Cloud/CDN
+	if headers.Get("CF-Ray") != "" {
+		addTech("Cloudflare")
+	}
+	if strings.Contains(headers.Get("Via"), "cloudfront") {
+		addTech("CloudFront")
+	}
+
+	return techs
+}
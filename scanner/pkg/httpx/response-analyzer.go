@@ -1,10 +1,12 @@
-package http
+package httpx
 
 import (
 	"crypto/md5"
 	"encoding/hex"
 	"regexp"
 	"strings"
+
+	"github.com/recon-suite/scanner/pkg/extract"
 )
 
 // AnalysisResult holds response analysis results
@@ -20,7 +22,25 @@ type AnalysisResult struct {
 	Emails          []string        `json:"emails,omitempty"`
 	SecurityHeaders SecurityHeaders `json:"security_headers"`
 	Interesting     []string        `json:"interesting,omitempty"`
+	Takeover        string          `json:"takeover,omitempty"`
 	Hash            string          `json:"hash"`
+
+	// GraphQL are the GraphQL endpoints the response is or names
+	GraphQL []GraphQLEndpoint `json:"graphql,omitempty"`
+
+	// Services are the third-party services the page loads or links to
+	Services []ThirdPartyService `json:"services,omitempty"`
+
+	// TrackingIDs are the analytics, advertising and site verification IDs
+	// in the page; see CorrelateTrackingIDs
+	TrackingIDs []TrackingID `json:"tracking_ids,omitempty"`
+
+	// Storage are the S3, GCS and Azure buckets the page references
+	Storage []CloudStorage `json:"storage,omitempty"`
+
+	// Methods are the dangerous methods the page's server has enabled,
+	// filled in by an active check; see Prober.CheckMethods
+	Methods []MethodExposure `json:"methods,omitempty"`
 }
 
 // FormDetails holds extracted form details
@@ -66,13 +86,15 @@ func (ra *ResponseAnalyzer) Analyze(url string, headers map[string]string, body
 	result.Technologies = ra.detectAllTechnologies(headers, body)
 
 	// Extract endpoints from JS
-	result.Endpoints = ra.extractEndpoints(body)
+	result.Endpoints = extract.Endpoints(body, extract.Options{})
 
 	// Extract parameters
-	result.Parameters = ra.extractParameters(body)
+	result.Parameters = extract.Parameters(body, extract.Options{})
 
 	// Extract forms
-	result.Forms = ra.extractFormDetails(body)
+	for _, form := range extract.Forms(body, extract.Options{}) {
+		result.Forms = append(result.Forms, FormDetails{Action: form.Action, Method: form.Method, Fields: form.Fields})
+	}
 
 	// Extract comments
 	result.Comments = ra.extractComments(body)
@@ -86,6 +108,21 @@ func (ra *ResponseAnalyzer) Analyze(url string, headers map[string]string, body
 	// Find interesting patterns
 	result.Interesting = ra.findInteresting(body)
 
+	// Check for unclaimed third-party service pages
+	result.Takeover = ra.detectTakeover(body)
+
+	// GraphQL endpoints, by error shape, IDE page or name
+	result.GraphQL = detectGraphQL(url, body)
+
+	// Analytics, chat, payment, CDN and login providers the page uses
+	result.Services = detectServices(url, body)
+
+	// Analytics and pixel IDs, which tie together sites with one owner
+	result.TrackingIDs = extractTrackingIDs(body)
+
+	// Cloud storage buckets, which may be listable or unclaimed
+	result.Storage = extractCloudStorage(body)
+
 	return result
 }
 
@@ -226,97 +263,6 @@ func (ra *ResponseAnalyzer) detectAllTechnologies(headers map[string]string, bod
 	return techs
 }
 
-// extractEndpoints finds API endpoints in body
-func (ra *ResponseAnalyzer) extractEndpoints(body string) []string {
-	var endpoints []string
-	seen := make(map[string]bool)
-
-	patterns := []*regexp.Regexp{
-		regexp.MustCompile(`["'](/api/v?[0-9]*/[a-zA-Z0-9/_-]+)["']`),
-		regexp.MustCompile(`["'](/graphql[^"']*)["']`),
-		regexp.MustCompile(`["'](/rest/[a-zA-Z0-9/_-]+)["']`),
-		regexp.MustCompile(`["']https?://[^"']+/api/[^"']+["']`),
-		regexp.MustCompile(`endpoint:\s*["']([^"']+)["']`),
-		regexp.MustCompile(`baseURL:\s*["']([^"']+)["']`),
-	}
-
-	for _, pattern := range patterns {
-		for _, match := range pattern.FindAllStringSubmatch(body, -1) {
-			if len(match) > 1 && !seen[match[1]] {
-				seen[match[1]] = true
-				endpoints = append(endpoints, match[1])
-			}
-		}
-	}
-
-	return endpoints
-}
-
-// extractParameters finds parameter names
-func (ra *ResponseAnalyzer) extractParameters(body string) []string {
-	var params []string
-	seen := make(map[string]bool)
-
-	// Form inputs
-	inputRe := regexp.MustCompile(`name=["']([a-zA-Z0-9_-]+)["']`)
-	for _, match := range inputRe.FindAllStringSubmatch(body, -1) {
-		if len(match) > 1 && !seen[match[1]] {
-			seen[match[1]] = true
-			params = append(params, match[1])
-		}
-	}
-
-	// URL parameters in JS
-	paramRe := regexp.MustCompile(`[?&]([a-zA-Z0-9_]+)=`)
-	for _, match := range paramRe.FindAllStringSubmatch(body, -1) {
-		if len(match) > 1 && !seen[match[1]] {
-			seen[match[1]] = true
-			params = append(params, match[1])
-		}
-	}
-
-	return params
-}
-
-// extractFormDetails extracts detailed form information
-func (ra *ResponseAnalyzer) extractFormDetails(body string) []FormDetails {
-	var forms []FormDetails
-
-	formRe := regexp.MustCompile(`(?is)<form([^>]*)>(.*?)</form>`)
-	actionRe := regexp.MustCompile(`action=["']([^"']+)["']`)
-	methodRe := regexp.MustCompile(`(?i)method=["']([^"']+)["']`)
-	inputRe := regexp.MustCompile(`name=["']([^"']+)["']`)
-
-	for _, match := range formRe.FindAllStringSubmatch(body, -1) {
-		if len(match) < 3 {
-			continue
-		}
-
-		attrs := match[1]
-		content := match[2]
-
-		form := FormDetails{Method: "GET"}
-
-		if actionMatch := actionRe.FindStringSubmatch(attrs); len(actionMatch) > 1 {
-			form.Action = actionMatch[1]
-		}
-
-		if methodMatch := methodRe.FindStringSubmatch(attrs); len(methodMatch) > 1 {
-			form.Method = strings.ToUpper(methodMatch[1])
-		}
-
-		for _, inputMatch := range inputRe.FindAllStringSubmatch(content, -1) {
-			if len(inputMatch) > 1 {
-				form.Fields = append(form.Fields, inputMatch[1])
-			}
-		}
-
-		forms = append(forms, form)
-	}
-
-	return forms
-}
-
 // extractComments finds HTML/JS comments
 func (ra *ResponseAnalyzer) extractComments(body string) []string {
 	var comments []string
@@ -398,24 +344,30 @@ func (ra *ResponseAnalyzer) analyzeSecurityHeaders(headers map[string]string) Se
 	return sh
 }
 
+// interestingPatterns are the secrets and leaks findInteresting reports,
+// by name
+var interestingPatterns = map[string]*regexp.Regexp{
+	"AWS Key":        regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	"Private Key":    regexp.MustCompile(`-----BEGIN (RSA |EC )?PRIVATE KEY-----`),
+	"API Key":        regexp.MustCompile(`(?i)api[_-]?key["']\s*[:=]\s*["'][a-zA-Z0-9_-]{20,}["']`),
+	"Password Field": regexp.MustCompile(`(?i)password["']\s*[:=]\s*["'][^"']+["']`),
+	"GitHub Token":   regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	"Slack Token":    regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+	"Google API Key": regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	"Stripe Key":     regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{20,}\b`),
+	"Internal IP":    regexp.MustCompile(`(?:10\.|172\.(?:1[6-9]|2[0-9]|3[01])\.|192\.168\.)[0-9]{1,3}\.[0-9]{1,3}`),
+	"Debug Enabled":  regexp.MustCompile(`(?i)debug\s*[:=]\s*true`),
+	"Admin Path":     regexp.MustCompile(`["'](/admin[^"']*|/dashboard[^"']*)["']`),
+	"File Path":      regexp.MustCompile(`(?:\/etc\/|\/var\/|C:\\\\|\/home\/)[^\s"'<>]+`),
+	"SQL Query":      regexp.MustCompile(`(?i)(?:SELECT|INSERT|UPDATE|DELETE|DROP|CREATE)\s+.+FROM`),
+	"Backup File":    regexp.MustCompile(`(?i)["'][^"']+\.(bak|backup|old|sql|tar|zip)["']`),
+}
+
 // findInteresting finds potentially interesting patterns
 func (ra *ResponseAnalyzer) findInteresting(body string) []string {
 	var interesting []string
 
-	patterns := map[string]*regexp.Regexp{
-		"AWS Key":        regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
-		"Private Key":    regexp.MustCompile(`-----BEGIN (RSA |EC )?PRIVATE KEY-----`),
-		"API Key":        regexp.MustCompile(`(?i)api[_-]?key["']\s*[:=]\s*["'][a-zA-Z0-9_-]{20,}["']`),
-		"Password Field": regexp.MustCompile(`(?i)password["']\s*[:=]\s*["'][^"']+["']`),
-		"Internal IP":    regexp.MustCompile(`(?:10\.|172\.(?:1[6-9]|2[0-9]|3[01])\.|192\.168\.)[0-9]{1,3}\.[0-9]{1,3}`),
-		"Debug Enabled":  regexp.MustCompile(`(?i)debug\s*[:=]\s*true`),
-		"Admin Path":     regexp.MustCompile(`["'](/admin[^"']*|/dashboard[^"']*)["']`),
-		"File Path":      regexp.MustCompile(`(?:\/etc\/|\/var\/|C:\\\\|\/home\/)[^\s"'<>]+`),
-		"SQL Query":      regexp.MustCompile(`(?i)(?:SELECT|INSERT|UPDATE|DELETE|DROP|CREATE)\s+.+FROM`),
-		"Backup File":    regexp.MustCompile(`(?i)["'][^"']+\.(bak|backup|old|sql|tar|zip)["']`),
-	}
-
-	for name, pattern := range patterns {
+	for name, pattern := range interestingPatterns {
 		matches := pattern.FindAllString(body, 3)
 		for _, match := range matches {
 			if len(match) < 200 {
@@ -426,3 +378,37 @@ func (ra *ResponseAnalyzer) findInteresting(body string) []string {
 
 	return interesting
 }
+
+// takeoverFingerprints are body snippets served by third-party platforms
+// when the hostname points at an unclaimed resource
+var takeoverFingerprints = map[string]string{
+	"AWS S3":       "NoSuchBucket",
+	"GitHub Pages": "There isn't a GitHub Pages site here.",
+	"Heroku":       "herokucdn.com/error-pages/no-such-app.html",
+	"Shopify":      "Sorry, this shop is currently unavailable.",
+	"Tumblr":       "Whatever you were looking for doesn't currently exist at this address",
+	"Fastly":       "Fastly error: unknown domain",
+	"Ghost":        "The thing you were looking for is no longer here, or never was",
+	"Pantheon":     "The gods are wise, but do not know of the site which you seek.",
+	"Azure":        "404 Web Site not found",
+	"Bitbucket":    "Repository not found",
+	"Zendesk":      "Help Center Closed",
+	"Surge.sh":     "project not found",
+	"ReadMe.io":    "Project doesnt exist... yet!",
+	"WordPress":    "Do you want to register *.wordpress.com?",
+	"Webflow":      "The page you are looking for doesn't exist or has been moved.",
+	"Help Scout":   "No settings were found for this company:",
+	"Strikingly":   "But if you're looking to build your own website,",
+	"Uberflip":     "Non-hub domain, The URL you've accessed does not provide a hub.",
+	"Agile CRM":    "Sorry, this page is no longer available.",
+}
+
+// detectTakeover returns the service whose unclaimed-resource page the body matches
+func (ra *ResponseAnalyzer) detectTakeover(body string) string {
+	for service, fingerprint := range takeoverFingerprints {
+		if strings.Contains(body, fingerprint) {
+			return service
+		}
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RobotsRules holds the robots.txt rules that apply to our user agent
+type RobotsRules struct {
+	allow      []robotsRule
+	disallow   []robotsRule
+	CrawlDelay time.Duration
+}
+
+// robotsRule is an Allow or Disallow path pattern, with the expression it
+// compiles to if it uses * or $
+type robotsRule struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsEntry caches the rules for one origin
+type robotsEntry struct {
+	once  sync.Once
+	rules *RobotsRules
+}
+
+// robotsPolicy fetches, caches and enforces robots.txt per origin
+type robotsPolicy struct {
+	entries   map[string]*robotsEntry
+	nextFetch map[string]time.Time
+	mu        sync.Mutex
+}
+
+// newRobotsPolicy creates an empty robots policy cache
+func newRobotsPolicy() *robotsPolicy {
+	return &robotsPolicy{
+		entries:   make(map[string]*robotsEntry),
+		nextFetch: make(map[string]time.Time),
+	}
+}
+
+// robotsRulesFor returns the cached rules for an origin, fetching them on first use
+func (c *Crawler) robotsRulesFor(ctx context.Context, origin string) *RobotsRules {
+	c.robots.mu.Lock()
+	entry, ok := c.robots.entries[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		c.robots.entries[origin] = entry
+	}
+	c.robots.mu.Unlock()
+
+	entry.once.Do(func() {
+		status, body := c.fetchStatus(ctx, origin+"/robots.txt")
+		entry.rules = robotsForStatus(status, body, c.config.UserAgent)
+	})
+	return entry.rules
+}
+
+// robotsForStatus returns the rules a robots.txt fetch implies, per RFC
+// 9309: a missing file (4xx) allows everything, and a server error (5xx)
+// or no answer at all disallows everything
+func robotsForStatus(status int, body, userAgent string) *RobotsRules {
+	switch {
+	case status >= 200 && status < 300:
+		return ParseRobots(body, userAgent)
+	case status >= 400 && status < 500:
+		return &RobotsRules{}
+	default:
+		return &RobotsRules{disallow: []robotsRule{newRobotsRule("/")}}
+	}
+}
+
+// robotsAllowed reports whether robots.txt permits fetching the URL
+func (c *Crawler) robotsAllowed(ctx context.Context, urlStr string) bool {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	rules := c.robotsRulesFor(ctx, parsed.Scheme+"://"+parsed.Host)
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	return rules.Allowed(path)
+}
+
+// waitCrawlDelay blocks until the host's Crawl-delay has elapsed since the
+// previous request to it
+func (c *Crawler) waitCrawlDelay(ctx context.Context, urlStr string) {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	rules := c.robotsRulesFor(ctx, origin)
+	if rules.CrawlDelay <= 0 {
+		return
+	}
+
+	c.robots.mu.Lock()
+	now := time.Now()
+	slot := c.robots.nextFetch[origin]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.robots.nextFetch[origin] = slot.Add(rules.CrawlDelay)
+	c.robots.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Until(slot)):
+	}
+}
+
+// ParseRobots parses a robots.txt body, keeping the group whose
+// User-agent is userAgent's product token, compared case-insensitively,
+// or else the "*" group
+func ParseRobots(body, userAgent string) *RobotsRules {
+	token := strings.ToLower(userAgent)
+	if idx := strings.IndexAny(token, "/ "); idx > 0 {
+		token = token[:idx]
+	}
+
+	var specific, wildcard *RobotsRules
+	var current []*RobotsRules
+	inRules := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			agent := strings.ToLower(value)
+			switch {
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = &RobotsRules{}
+				}
+				current = append(current, wildcard)
+			case agent != "" && agent == token:
+				if specific == nil {
+					specific = &RobotsRules{}
+				}
+				current = append(current, specific)
+			}
+		case "allow", "disallow", "crawl-delay":
+			inRules = true
+			for _, rules := range current {
+				switch key {
+				case "allow":
+					if value != "" {
+						rules.allow = append(rules.allow, newRobotsRule(value))
+					}
+				case "disallow":
+					if value != "" {
+						rules.disallow = append(rules.disallow, newRobotsRule(value))
+					}
+				case "crawl-delay":
+					if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+						rules.CrawlDelay = time.Duration(secs * float64(time.Second))
+					}
+				}
+			}
+		}
+	}
+
+	if specific != nil {
+		return specific
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &RobotsRules{}
+}
+
+// Allowed reports whether a path (with query) may be fetched. The longest
+// matching rule wins and Allow wins ties, per RFC 9309.
+func (r *RobotsRules) Allowed(path string) bool {
+	allowLen := longestRobotsMatch(r.allow, path)
+	disallowLen := longestRobotsMatch(r.disallow, path)
+	return disallowLen == -1 || allowLen >= disallowLen
+}
+
+// longestRobotsMatch returns the length of the longest rule matching
+// path, or -1 if none match
+func longestRobotsMatch(rules []robotsRule, path string) int {
+	longest := -1
+	for _, rule := range rules {
+		if len(rule.pattern) > longest && rule.matches(path) {
+			longest = len(rule.pattern)
+		}
+	}
+	return longest
+}
+
+// newRobotsRule compiles a robots.txt path pattern supporting * and $
+func newRobotsRule(pattern string) robotsRule {
+	rule := robotsRule{pattern: pattern}
+	if !strings.ContainsAny(pattern, "*$") {
+		return rule
+	}
+
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	// Quoted parts joined by .* always compile
+	rule.re = regexp.MustCompile(expr)
+	return rule
+}
+
+// matches reports whether the rule's pattern matches path
+func (r robotsRule) matches(path string) bool {
+	if r.re == nil {
+		return strings.HasPrefix(path, r.pattern)
+	}
+	return r.re.MatchString(path)
+}
@@ -0,0 +1,169 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SavedResponse is an HTTP response read from disk for offline analysis
+type SavedResponse struct {
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+// maxSavedBody caps how much of a saved body is analyzed, like live fetches
+const maxSavedBody = 10 * 1024 * 1024
+
+// LoadResponses reads saved responses from a file or, recursively, a
+// directory. A file may be a Burp Suite XML or JSON export (every item
+// with a response), a raw HTTP response with status line and headers, probe
+// output (json or ndjson) kept with bodies, or a bare body. Responses
+// without a known URL are named after their file.
+func LoadResponses(path string) ([]SavedResponse, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadResponseFile(path)
+	}
+
+	var responses []SavedResponse
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		loaded, err := loadResponseFile(file)
+		if err != nil {
+			return err
+		}
+		responses = append(responses, loaded...)
+		return nil
+	})
+	return responses, err
+}
+
+// loadResponseFile reads one file in any supported format
+func loadResponseFile(path string) ([]SavedResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	head := bytes.TrimSpace(data[:min(len(data), 4096)])
+	switch {
+	case bytes.HasPrefix(head, []byte("<?xml")) && bytes.Contains(head, []byte("<items")):
+		items, err := parseBurpXML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		responses, err := parseBurpExport(items)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return responses, nil
+	case bytes.HasPrefix(head, []byte("HTTP/")):
+		response, err := parseRawResponse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		response.URL = path
+		return []SavedResponse{response}, nil
+	case bytes.HasPrefix(head, []byte("[")) || bytes.HasPrefix(head, []byte("{")):
+		if responses, ok := parseProbeOutput(data); ok {
+			if len(responses) == 0 {
+				return nil, fmt.Errorf("%s: probe output has no response bodies", path)
+			}
+			return responses, nil
+		}
+		// A Burp JSON export, if any item has a response
+		if items, err := parseBurpJSON(data); err == nil {
+			if responses, err := parseBurpExport(items); err == nil && len(responses) > 0 {
+				return responses, nil
+			}
+		}
+		return []SavedResponse{{URL: path, Body: string(data[:min(len(data), maxSavedBody)])}}, nil
+	default:
+		return []SavedResponse{{URL: path, Body: string(data[:min(len(data), maxSavedBody)])}}, nil
+	}
+}
+
+// parseBurpExport reads the responses out of a Burp export
+func parseBurpExport(items []BurpItem) ([]SavedResponse, error) {
+	var responses []SavedResponse
+	for _, item := range items {
+		if item.Response == nil {
+			continue
+		}
+		response, err := parseRawResponse(item.Response)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.URL, err)
+		}
+		response.URL = item.URL
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// parseRawResponse splits a raw HTTP response into headers and body
+func parseRawResponse(data []byte) (SavedResponse, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return SavedResponse{}, fmt.Errorf("parsing response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Saved bodies are often cut short or re-encoded, so take what reads
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSavedBody))
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	return SavedResponse{Headers: headers, Body: string(body)}, nil
+}
+
+// parseProbeOutput reads the responses out of probe results kept with
+// their bodies. ok is false if data is other JSON, such as a saved API
+// response, which is then analyzed as a bare body.
+func parseProbeOutput(data []byte) ([]SavedResponse, bool) {
+	var probes []ProbeResult
+	if err := json.Unmarshal(data, &probes); err != nil {
+		probes = nil
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var probe ProbeResult
+			if err := json.Unmarshal(line, &probe); err != nil {
+				return nil, false
+			}
+			probes = append(probes, probe)
+		}
+	}
+
+	var responses []SavedResponse
+	for _, probe := range probes {
+		if probe.URL == "" || probe.StatusCode == 0 {
+			return nil, false
+		}
+		if probe.Body == "" {
+			continue
+		}
+		url := probe.URL
+		if probe.FinalURL != "" {
+			url = probe.FinalURL
+		}
+		responses = append(responses, SavedResponse{URL: url, Headers: probe.Headers, Body: probe.Body})
+	}
+	return responses, true
+}
@@ -0,0 +1,285 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Request smuggling desync techniques: which length header the front end
+// honors, then which the back end does
+const (
+	SmuggleCLTE = "CL.TE"
+	SmuggleTECL = "TE.CL"
+)
+
+// SmuggleConfig holds request smuggling indicator check configuration
+type SmuggleConfig struct {
+	// Targets are the URLs whose front end and back end are checked
+	Targets []string
+
+	// Timeout is how long, in seconds, a probe waits for an answer. A
+	// probe still unanswered then, where its well-formed control was
+	// answered at once, is the indicator.
+	Timeout int
+
+	// Confirmations is how many times a delay must repeat before it is
+	// reported (default 2), so one slow answer is not taken for a desync
+	Confirmations int
+
+	Workers   int
+	RateLimit int
+	UserAgent string
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts targets checked and indicators found
+	Progress *utils.Progress
+
+	// OnResult is called for each indicator
+	OnResult func(SmuggleResult)
+}
+
+// SmuggleResult is a host whose front end and back end disagreed about
+// where a request ends. It is an indicator for manual investigation, not
+// a confirmed vulnerability: nothing was smuggled.
+type SmuggleResult struct {
+	URL       string `json:"url"`
+	Technique string `json:"technique"`
+
+	// ControlMS is how long the well-formed control request took, and
+	// DelayMS how long the probe went unanswered
+	ControlMS     int64 `json:"control_ms"`
+	DelayMS       int64 `json:"delay_ms"`
+	Confirmations int   `json:"confirmations"`
+
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+// smuggleProbe is a request whose body is one length by Content-Length
+// and another by its chunks. The probe leaves the side honoring the
+// technique's second header waiting for bytes that never come; the control
+// is the same request made consistent, so both sides answer at once.
+type smuggleProbe struct {
+	technique     string
+	probe         string
+	probeLength   int
+	control       string
+	controlLength int
+}
+
+// smuggleProbes are tried in order. CL.TE goes first: a CL.TE front end
+// would forward the TE.CL probe's trailing byte to the back end as the
+// start of someone else's request, so TE.CL is only sent to hosts where
+// CL.TE showed nothing.
+var smuggleProbes = []smuggleProbe{
+	// The front end forwards the 4 bytes Content-Length gives, which end
+	// inside a chunk; a chunked back end waits for the rest of it
+	{technique: SmuggleCLTE, probe: "1\r\nA\r\nX", probeLength: 4, control: "0\r\n\r\n", controlLength: 5},
+	// The front end forwards the empty chunked body alone, holding back
+	// the X after it; a back end honoring Content-Length waits for it
+	{technique: SmuggleTECL, probe: "0\r\n\r\nX", probeLength: 6, control: "0\r\n\r\n", controlLength: 5},
+}
+
+// SmuggleScanner checks front-end/back-end pairs for request smuggling
+// with timing alone: every probe is a single request whose missing bytes
+// only delay its own answer, on a connection of its own that is closed
+// after it
+type SmuggleScanner struct {
+	config  SmuggleConfig
+	limiter *rate.Limiter
+}
+
+// NewSmuggleScanner creates a new request smuggling indicator scanner
+func NewSmuggleScanner(config SmuggleConfig) *SmuggleScanner {
+	if config.Timeout == 0 {
+		config.Timeout = 5
+	}
+	if config.Confirmations == 0 {
+		config.Confirmations = 2
+	}
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 10
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	return &SmuggleScanner{
+		config:  config,
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// ScanContext checks every target. If ctx is cancelled, the indicators
+// found so far are returned with ctx's error.
+func (s *SmuggleScanner) ScanContext(ctx context.Context) ([]SmuggleResult, error) {
+	targets := s.config.Scope.Filter(s.config.Targets)
+	s.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "smuggle")
+	jobs := make(chan string)
+	results := make(chan SmuggleResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				if result, ok := s.check(ctx, target); ok {
+					results <- result
+				}
+				s.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []SmuggleResult
+	for result := range results {
+		s.config.Progress.Found()
+		metrics.Findings("smuggle").Inc()
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	return found, ctx.Err()
+}
+
+// check tries each technique on a target until one shows a desync
+func (s *SmuggleScanner) check(ctx context.Context, target string) (SmuggleResult, bool) {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return SmuggleResult{}, false
+	}
+	logger := log.FromContext(ctx)
+	timeout := time.Duration(s.config.Timeout) * time.Second
+
+	for _, p := range smuggleProbes {
+		// A control that is slow itself says nothing about the probe
+		control, err := s.send(ctx, u, p.control, p.controlLength)
+		if err != nil || control >= timeout/2 {
+			logger.Debug("control not answered promptly", log.Target(target), "technique", p.technique, log.Duration(control), log.Err(err))
+			return SmuggleResult{}, false
+		}
+
+		var delay time.Duration
+		confirmed := 0
+		for confirmed < s.config.Confirmations && ctx.Err() == nil {
+			elapsed, err := s.send(ctx, u, p.probe, p.probeLength)
+			if !isTimeout(err) {
+				break
+			}
+			delay = elapsed
+			confirmed++
+		}
+		if confirmed < s.config.Confirmations {
+			continue
+		}
+
+		logger.Info("request smuggling indicator", log.Target(target), "technique", p.technique)
+		return SmuggleResult{
+			URL:           target,
+			Technique:     p.technique,
+			ControlMS:     control.Milliseconds(),
+			DelayMS:       delay.Milliseconds(),
+			Confirmations: confirmed,
+			Severity:      "medium",
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		}, true
+	}
+	return SmuggleResult{}, false
+}
+
+// send writes one POST with both length headers on a new connection and
+// times how long the status line takes to come back
+func (s *SmuggleScanner) send(ctx context.Context, u *url.URL, body string, contentLength int) (time.Duration, error) {
+	s.limiter.Wait(ctx)
+	s.config.Budget.Wait(ctx)
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	timeout := time.Duration(s.config.Timeout) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+	address := net.JoinHostPort(u.Hostname(), port)
+
+	var conn net.Conn
+	var err error
+	if u.Scheme == "http" {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// A cancelled run stops waiting on the connection
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	request := fmt.Sprintf("POST %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nContent-Type: application/x-www-form-urlencoded\r\n"+
+		"Content-Length: %d\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n%s",
+		u.RequestURI(), u.Host, s.config.UserAgent, contentLength, body)
+	start := time.Now()
+	conn.SetDeadline(start.Add(timeout))
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return 0, err
+	}
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	return time.Since(start), err
+}
+
+// isTimeout reports whether err is a read that ran out of time
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,38 @@
+package httpx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSmuggleProbeLengths(t *testing.T) {
+	for _, p := range smuggleProbes {
+		t.Run(p.technique, func(t *testing.T) {
+			// A server honoring Content-Length on both sides must get every
+			// byte it is promised, or it stalls like a desynced pair
+			if p.probeLength > len(p.probe) {
+				t.Errorf("probe sends %d bytes, declares %d", len(p.probe), p.probeLength)
+			}
+			if p.controlLength != len(p.control) {
+				t.Errorf("control sends %d bytes, declares %d", len(p.control), p.controlLength)
+			}
+			if !strings.HasPrefix(p.control, "0\r\n\r\n") {
+				t.Errorf("control %q is not an empty chunked body", p.control)
+			}
+		})
+	}
+}
+
+func TestSmuggleProbeTECL(t *testing.T) {
+	for _, p := range smuggleProbes {
+		if p.technique != SmuggleTECL {
+			continue
+		}
+		// The chunked body ends a byte short of Content-Length
+		if !strings.HasPrefix(p.probe, "0\r\n\r\n") || p.probeLength != len("0\r\n\r\n")+1 || len(p.probe) != p.probeLength {
+			t.Errorf("TE.CL probe %q with length %d", p.probe, p.probeLength)
+		}
+		return
+	}
+	t.Fatal("no TE.CL probe")
+}
@@ -0,0 +1,288 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"golang.org/x/time/rate"
+)
+
+// TemplateConfig holds template scan configuration
+type TemplateConfig struct {
+	// Targets are base URLs, usually live URLs from a probe
+	Targets []string
+
+	// Templates are the checks run against every target
+	Templates []*Template
+
+	Workers   int
+	Timeout   int
+	RateLimit int
+	UserAgent string
+	Headers   map[string]string
+	Cookies   map[string]string
+
+	// Proxy routes every request through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Progress, if set, counts template runs and matches
+	Progress *utils.Progress
+
+	// OnResult is called for each match
+	OnResult func(TemplateResult)
+}
+
+// TemplateResult is one template that matched a URL
+type TemplateResult struct {
+	TemplateID string              `json:"template_id"`
+	Name       string              `json:"name"`
+	Severity   string              `json:"severity"`
+	Tags       []string            `json:"tags,omitempty"`
+	Target     string              `json:"target"`
+	URL        string              `json:"url"`
+	Method     string              `json:"method"`
+	StatusCode int                 `json:"status_code"`
+	Extracted  map[string][]string `json:"extracted,omitempty"`
+	Timestamp  string              `json:"timestamp"`
+}
+
+// TemplateScanner runs templates against targets
+type TemplateScanner struct {
+	config  TemplateConfig
+	prober  *Prober
+	limiter *rate.Limiter
+}
+
+// NewTemplateScanner creates a new template scanner. Redirects are not
+// followed, so matchers see the response for the exact path requested.
+func NewTemplateScanner(config TemplateConfig) *TemplateScanner {
+	if config.Workers == 0 {
+		config.Workers = 25
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 150
+	}
+
+	probeConfig := ProbeConfig{
+		Workers:   config.Workers,
+		Timeout:   config.Timeout,
+		UserAgent: config.UserAgent,
+		Headers:   config.Headers,
+		Cookies:   config.Cookies,
+		Proxy:     config.Proxy,
+		Scope:     config.Scope,
+		Budget:    config.Budget,
+	}
+
+	return &TemplateScanner{
+		config:  config,
+		prober:  NewProber(probeConfig),
+		limiter: rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+	}
+}
+
+// ScanContext runs every template against every target. If ctx is
+// cancelled, the matches found so far are returned with ctx's error.
+func (s *TemplateScanner) ScanContext(ctx context.Context) ([]TemplateResult, error) {
+	type job struct {
+		target   string
+		template *Template
+	}
+
+	var targets []string
+	for _, target := range s.config.Scope.Filter(s.config.Targets) {
+		if !strings.Contains(target, "://") {
+			target = "https://" + target
+		}
+		targets = append(targets, strings.TrimSuffix(target, "/"))
+	}
+	s.config.Progress.AddTotal(len(targets) * len(s.config.Templates))
+
+	ctx = log.WithModule(ctx, "check")
+	jobs := make(chan job)
+	results := make(chan TemplateResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				for _, result := range s.run(ctx, j.target, j.template) {
+					results <- result
+				}
+				s.config.Progress.Done()
+			}
+		}()
+	}
+
+	// Templates vary fastest, so one host is not hit by every worker at once
+	go func() {
+		defer close(jobs)
+		for _, t := range s.config.Templates {
+			for _, target := range targets {
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- job{target: target, template: t}:
+				}
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var matches []TemplateResult
+	for result := range results {
+		s.config.Progress.Found()
+		metrics.Findings("check").Inc()
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		matches = append(matches, result)
+	}
+	return matches, ctx.Err()
+}
+
+// run sends one template's requests to a target and reports each path
+// that matched
+func (s *TemplateScanner) run(ctx context.Context, target string, t *Template) []TemplateResult {
+	vars := templateVars(target)
+	var results []TemplateResult
+	for i := range t.Requests {
+		r := &t.Requests[i]
+		for _, path := range r.Paths {
+			if ctx.Err() != nil {
+				return results
+			}
+			requestURL := expandVars(path, vars)
+			resp, ok := s.send(ctx, r, requestURL, vars)
+			if !ok || !r.match(resp) {
+				continue
+			}
+
+			result := TemplateResult{
+				TemplateID: t.ID,
+				Name:       t.Info.Name,
+				Severity:   t.Info.Severity,
+				Tags:       t.Info.Tags,
+				Target:     target,
+				URL:        requestURL,
+				Method:     r.Method,
+				StatusCode: resp.status,
+				Timestamp:  time.Now().UTC().Format(time.RFC3339),
+			}
+			for _, e := range r.Extractors {
+				if values := e.extract(resp); len(values) > 0 {
+					if result.Extracted == nil {
+						result.Extracted = make(map[string][]string)
+					}
+					result.Extracted[e.Name] = append(result.Extracted[e.Name], values...)
+				}
+			}
+			results = append(results, result)
+			if r.StopAtFirstMatch {
+				break
+			}
+		}
+	}
+	return results
+}
+
+// send makes one template request
+func (s *TemplateScanner) send(ctx context.Context, r *TemplateRequest, requestURL string, vars map[string]string) (templateResponse, bool) {
+	if !s.config.Scope.Allows(requestURL) {
+		return templateResponse{}, false
+	}
+	s.limiter.Wait(ctx)
+
+	req, err := s.prober.newRequest(ctx, r.Method, requestURL)
+	if err != nil {
+		return templateResponse{}, false
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, expandVars(value, vars))
+	}
+	if r.Body != "" {
+		body := expandVars(r.Body, vars)
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	s.config.Budget.Wait(ctx)
+
+	resp, err := s.prober.client.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Debug("request failed", log.Target(requestURL), log.Err(err))
+		return templateResponse{}, false
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
+
+	// Headers are matched as raw response lines, in a stable order
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var header strings.Builder
+	fmt.Fprintf(&header, "%s %s\r\n", resp.Proto, resp.Status)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&header, "%s: %s\r\n", name, value)
+		}
+	}
+
+	return templateResponse{
+		status: resp.StatusCode,
+		header: header.String(),
+		body:   string(body),
+		values: resp.Header,
+	}, true
+}
+
+// templateVars returns the variables a target defines for templates
+func templateVars(target string) map[string]string {
+	vars := map[string]string{"BaseURL": target}
+	if u, err := url.Parse(target); err == nil {
+		vars["RootURL"] = u.Scheme + "://" + u.Host
+		vars["Hostname"] = u.Host
+		vars["Host"] = u.Hostname()
+	}
+	return vars
+}
+
+// expandVars replaces {{Name}} with each variable's value
+func expandVars(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	for name, value := range vars {
+		s = strings.ReplaceAll(s, "{{"+name+"}}", value)
+	}
+	return s
+}
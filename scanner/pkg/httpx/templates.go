@@ -0,0 +1,434 @@
+package httpx
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities are the template severities, lowest first
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// Template is one check: the requests to send, the matchers that decide
+// whether a response is a hit, and the extractors that pull values out of
+// it
+type Template struct {
+	ID       string            `yaml:"id"`
+	Info     TemplateInfo      `yaml:"info"`
+	Requests []TemplateRequest `yaml:"requests"`
+
+	// File is where the template was loaded from
+	File string `yaml:"-"`
+}
+
+// TemplateInfo describes a template
+type TemplateInfo struct {
+	Name        string   `yaml:"name"`
+	Severity    string   `yaml:"severity"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+}
+
+// TemplateRequest is one request sent to every path, with the matchers and
+// extractors applied to each response. Paths, headers and body may use
+// {{BaseURL}}, {{RootURL}}, {{Hostname}} and {{Host}}.
+type TemplateRequest struct {
+	Method  string            `yaml:"method"`
+	Paths   []string          `yaml:"path"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+
+	// MatchersCondition is "and" (every matcher must match) or "or"; default or
+	MatchersCondition string              `yaml:"matchers-condition"`
+	Matchers          []TemplateMatcher   `yaml:"matchers"`
+	Extractors        []TemplateExtractor `yaml:"extractors"`
+
+	// StopAtFirstMatch skips the remaining paths once one matches
+	StopAtFirstMatch bool `yaml:"stop-at-first-match"`
+}
+
+// TemplateMatcher tests one part of a response
+type TemplateMatcher struct {
+	// Type is status, size, word or regex
+	Type string `yaml:"type"`
+
+	// Part is body, header or all (headers then body); default body
+	Part string `yaml:"part"`
+
+	Status []int    `yaml:"status"`
+	Size   []int    `yaml:"size"`
+	Words  []string `yaml:"words"`
+	Regex  []string `yaml:"regex"`
+
+	// Condition is "and" (every value must match) or "or"; default or
+	Condition string `yaml:"condition"`
+
+	// Negative inverts the match
+	Negative bool `yaml:"negative"`
+
+	// CaseInsensitive compares words ignoring case
+	CaseInsensitive bool `yaml:"case-insensitive"`
+
+	regexes []*regexp.Regexp
+}
+
+// TemplateExtractor pulls values out of a matched response
+type TemplateExtractor struct {
+	// Type is regex or header
+	Type string `yaml:"type"`
+
+	// Name keys the values in TemplateResult.Extracted; default the type
+	Name string `yaml:"name"`
+
+	// Part is body, header or all; default body. Header extractors ignore it.
+	Part string `yaml:"part"`
+
+	// Regex extractors report capture group Group (0 is the whole match)
+	Regex []string `yaml:"regex"`
+	Group int      `yaml:"group"`
+
+	// Header extractors report the values of these response headers
+	Headers []string `yaml:"headers"`
+
+	regexes []*regexp.Regexp
+}
+
+// LoadTemplates reads templates from YAML files and directories, which
+// are searched recursively for .yaml and .yml files
+func LoadTemplates(paths ...string) ([]*Template, error) {
+	var templates []*Template
+	seen := make(map[string]string)
+	for _, path := range paths {
+		files, err := templateFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			t, err := LoadTemplate(file)
+			if err != nil {
+				return nil, err
+			}
+			if other, ok := seen[t.ID]; ok {
+				return nil, fmt.Errorf("%s: template %s is also defined in %s", file, t.ID, other)
+			}
+			seen[t.ID] = file
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+// LoadTemplate reads and validates one template file
+func LoadTemplate(file string) (*Template, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	t.File = file
+	if err := t.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &t, nil
+}
+
+// templateFiles lists the template files at path, a file or a directory
+func templateFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			if !d.IsDir() {
+				files = append(files, file)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// compile validates a template, applies defaults and compiles its regexes
+func (t *Template) compile() error {
+	if t.ID == "" {
+		return fmt.Errorf("template has no id")
+	}
+	if t.Info.Name == "" {
+		t.Info.Name = t.ID
+	}
+	t.Info.Severity = strings.ToLower(t.Info.Severity)
+	if t.Info.Severity == "" {
+		t.Info.Severity = "info"
+	}
+	if SeverityRank(t.Info.Severity) < 0 {
+		return fmt.Errorf("template %s: unknown severity %q", t.ID, t.Info.Severity)
+	}
+	if len(t.Requests) == 0 {
+		return fmt.Errorf("template %s has no requests", t.ID)
+	}
+
+	for i := range t.Requests {
+		r := &t.Requests[i]
+		r.Method = strings.ToUpper(r.Method)
+		if r.Method == "" {
+			r.Method = http.MethodGet
+		}
+		if len(r.Paths) == 0 {
+			return fmt.Errorf("template %s: request %d has no path", t.ID, i+1)
+		}
+		if len(r.Matchers) == 0 {
+			return fmt.Errorf("template %s: request %d has no matchers", t.ID, i+1)
+		}
+		if err := checkCondition(r.MatchersCondition); err != nil {
+			return fmt.Errorf("template %s: request %d: %w", t.ID, i+1, err)
+		}
+		for j := range r.Matchers {
+			if err := r.Matchers[j].compile(); err != nil {
+				return fmt.Errorf("template %s: request %d matcher %d: %w", t.ID, i+1, j+1, err)
+			}
+		}
+		for j := range r.Extractors {
+			if err := r.Extractors[j].compile(); err != nil {
+				return fmt.Errorf("template %s: request %d extractor %d: %w", t.ID, i+1, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compile validates a matcher and compiles its regexes
+func (m *TemplateMatcher) compile() error {
+	if err := checkCondition(m.Condition); err != nil {
+		return err
+	}
+	if err := checkPart(m.Part); err != nil {
+		return err
+	}
+	switch m.Type {
+	case "status":
+		if len(m.Status) == 0 {
+			return fmt.Errorf("status matcher has no status")
+		}
+	case "size":
+		if len(m.Size) == 0 {
+			return fmt.Errorf("size matcher has no size")
+		}
+	case "word":
+		if len(m.Words) == 0 {
+			return fmt.Errorf("word matcher has no words")
+		}
+	case "regex":
+		if len(m.Regex) == 0 {
+			return fmt.Errorf("regex matcher has no regex")
+		}
+		for _, expr := range m.Regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return err
+			}
+			m.regexes = append(m.regexes, re)
+		}
+	default:
+		return fmt.Errorf("unknown matcher type %q", m.Type)
+	}
+	return nil
+}
+
+// compile validates an extractor and compiles its regexes
+func (e *TemplateExtractor) compile() error {
+	if err := checkPart(e.Part); err != nil {
+		return err
+	}
+	if e.Name == "" {
+		e.Name = e.Type
+	}
+	switch e.Type {
+	case "regex":
+		if len(e.Regex) == 0 {
+			return fmt.Errorf("regex extractor has no regex")
+		}
+		for _, expr := range e.Regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return err
+			}
+			if e.Group > re.NumSubexp() {
+				return fmt.Errorf("regex %q has no group %d", expr, e.Group)
+			}
+			e.regexes = append(e.regexes, re)
+		}
+	case "header":
+		if len(e.Headers) == 0 {
+			return fmt.Errorf("header extractor has no headers")
+		}
+	default:
+		return fmt.Errorf("unknown extractor type %q", e.Type)
+	}
+	return nil
+}
+
+// checkCondition validates a matchers-condition or condition
+func checkCondition(condition string) error {
+	switch condition {
+	case "", "and", "or":
+		return nil
+	}
+	return fmt.Errorf("unknown condition %q", condition)
+}
+
+// checkPart validates the response part a matcher or extractor reads
+func checkPart(part string) error {
+	switch part {
+	case "", "body", "header", "all":
+		return nil
+	}
+	return fmt.Errorf("unknown part %q", part)
+}
+
+// SeverityRank returns the position of severity in Severities, or -1
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasTag reports whether a template carries any of tags
+func (t *Template) HasTag(tags ...string) bool {
+	for _, tag := range tags {
+		for _, own := range t.Info.Tags {
+			if strings.EqualFold(own, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templateResponse is the part of a response matchers and extractors read
+type templateResponse struct {
+	status int
+	header string
+	body   string
+	values http.Header
+}
+
+// part returns the named part of a response as text
+func (r templateResponse) part(name string) string {
+	switch name {
+	case "header":
+		return r.header
+	case "all":
+		return r.header + "\r\n" + r.body
+	}
+	return r.body
+}
+
+// match applies the request's matchers to a response
+func (r *TemplateRequest) match(resp templateResponse) bool {
+	for _, m := range r.Matchers {
+		ok := m.match(resp)
+		if r.MatchersCondition == "and" && !ok {
+			return false
+		}
+		if r.MatchersCondition != "and" && ok {
+			return true
+		}
+	}
+	return r.MatchersCondition == "and"
+}
+
+// match applies one matcher to a response
+func (m *TemplateMatcher) match(resp templateResponse) bool {
+	var results []bool
+	switch m.Type {
+	case "status":
+		for _, status := range m.Status {
+			results = append(results, resp.status == status)
+		}
+	case "size":
+		for _, size := range m.Size {
+			results = append(results, len(resp.body) == size)
+		}
+	case "word":
+		text := resp.part(m.Part)
+		if m.CaseInsensitive {
+			text = strings.ToLower(text)
+		}
+		for _, word := range m.Words {
+			if m.CaseInsensitive {
+				word = strings.ToLower(word)
+			}
+			results = append(results, strings.Contains(text, word))
+		}
+	case "regex":
+		text := resp.part(m.Part)
+		for _, re := range m.regexes {
+			results = append(results, re.MatchString(text))
+		}
+	}
+
+	matched := m.Condition == "and"
+	for _, ok := range results {
+		if m.Condition == "and" && !ok {
+			matched = false
+			break
+		}
+		if m.Condition != "and" && ok {
+			matched = true
+			break
+		}
+	}
+	return matched != m.Negative
+}
+
+// extract applies one extractor to a response, returning unique values
+func (e *TemplateExtractor) extract(resp templateResponse) []string {
+	var values []string
+	seen := make(map[string]bool)
+	add := func(value string) {
+		value = strings.TrimSpace(value)
+		if value != "" && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	switch e.Type {
+	case "regex":
+		text := resp.part(e.Part)
+		for _, re := range e.regexes {
+			for _, m := range re.FindAllStringSubmatch(text, -1) {
+				add(m[e.Group])
+			}
+		}
+	case "header":
+		for _, name := range e.Headers {
+			for _, value := range resp.values.Values(name) {
+				add(value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,264 @@
+package httpx
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/extract"
+)
+
+// Third-party service categories
+const (
+	ServiceAnalytics   = "analytics"
+	ServiceAdvertising = "advertising"
+	ServiceChat        = "chat"
+	ServicePayments    = "payments"
+	ServiceCDN         = "cdn"
+	ServiceOAuth       = "oauth"
+	ServiceCaptcha     = "captcha"
+	ServiceMonitoring  = "monitoring"
+	ServiceConsent     = "consent"
+	ServiceMarketing   = "marketing"
+)
+
+// ThirdPartyService is an external service a page loads or hands users
+// to: an analytics tag, chat widget, payment form or login provider
+type ThirdPartyService struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+
+	// Hosts are the service's hosts the page references; a service known
+	// only by a marker in the page's code has none
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// HostServices is the third-party services found across one host's pages
+type HostServices struct {
+	Host     string              `json:"host"`
+	Services []ThirdPartyService `json:"services"`
+}
+
+// knownService is a third-party service, recognized by the hosts it
+// serves from (matching subdomains too) or by markers in page code
+type knownService struct {
+	name     string
+	category string
+	hosts    []string
+	markers  []string
+}
+
+// knownServices are the services detectServices recognizes
+var knownServices = []knownService{
+	// Analytics and session recording
+	{"Google Analytics", ServiceAnalytics, []string{"google-analytics.com", "analytics.google.com"}, []string{"gtag('config'", `gtag("config"`, "ga('create'"}},
+	{"Google Tag Manager", ServiceAnalytics, []string{"googletagmanager.com"}, nil},
+	{"Segment", ServiceAnalytics, []string{"segment.com", "segment.io"}, nil},
+	{"Mixpanel", ServiceAnalytics, []string{"mixpanel.com", "mxpnl.com"}, []string{"mixpanel.init("}},
+	{"Amplitude", ServiceAnalytics, []string{"amplitude.com"}, nil},
+	{"Heap", ServiceAnalytics, []string{"heapanalytics.com"}, nil},
+	{"Hotjar", ServiceAnalytics, []string{"hotjar.com", "hotjar.io"}, nil},
+	{"Microsoft Clarity", ServiceAnalytics, []string{"clarity.ms"}, nil},
+	{"FullStory", ServiceAnalytics, []string{"fullstory.com"}, nil},
+	{"Matomo", ServiceAnalytics, []string{"matomo.cloud"}, []string{"_paq.push("}},
+	{"Plausible", ServiceAnalytics, []string{"plausible.io"}, nil},
+	{"Adobe Analytics", ServiceAnalytics, []string{"omtrdc.net", "2o7.net", "adobedtm.com"}, nil},
+	{"Optimizely", ServiceAnalytics, []string{"optimizely.com"}, nil},
+
+	// Advertising pixels
+	{"Meta Pixel", ServiceAdvertising, []string{"connect.facebook.net"}, []string{"fbq('init'", `fbq("init"`}},
+	{"Google Ads", ServiceAdvertising, []string{"googleadservices.com", "googlesyndication.com", "doubleclick.net"}, nil},
+	{"LinkedIn Insight", ServiceAdvertising, []string{"snap.licdn.com", "px.ads.linkedin.com"}, nil},
+	{"TikTok Pixel", ServiceAdvertising, []string{"analytics.tiktok.com"}, nil},
+	{"X Ads", ServiceAdvertising, []string{"static.ads-twitter.com", "analytics.twitter.com"}, nil},
+
+	// Chat and support widgets
+	{"Intercom", ServiceChat, []string{"intercom.io", "intercomcdn.com"}, []string{"window.intercomSettings"}},
+	{"Drift", ServiceChat, []string{"drift.com", "driftt.com"}, nil},
+	{"Zendesk", ServiceChat, []string{"zendesk.com", "zdassets.com", "zopim.com"}, nil},
+	{"LiveChat", ServiceChat, []string{"livechatinc.com"}, nil},
+	{"Crisp", ServiceChat, []string{"crisp.chat"}, nil},
+	{"Tawk.to", ServiceChat, []string{"tawk.to"}, nil},
+	{"Olark", ServiceChat, []string{"olark.com"}, nil},
+	{"Freshchat", ServiceChat, []string{"freshchat.com"}, nil},
+
+	// Payments
+	{"Stripe", ServicePayments, []string{"stripe.com", "stripe.network"}, []string{"Stripe('pk_", `Stripe("pk_`}},
+	{"PayPal", ServicePayments, []string{"paypal.com", "paypalobjects.com"}, nil},
+	{"Braintree", ServicePayments, []string{"braintreegateway.com", "braintree-api.com"}, nil},
+	{"Adyen", ServicePayments, []string{"adyen.com"}, nil},
+	{"Square", ServicePayments, []string{"squareup.com", "squarecdn.com"}, nil},
+	{"Klarna", ServicePayments, []string{"klarna.com", "klarnacdn.net"}, nil},
+	{"Checkout.com", ServicePayments, []string{"checkout.com"}, nil},
+	{"Razorpay", ServicePayments, []string{"razorpay.com"}, nil},
+
+	// CDNs and hosted libraries
+	{"cdnjs", ServiceCDN, []string{"cdnjs.cloudflare.com"}, nil},
+	{"jsDelivr", ServiceCDN, []string{"jsdelivr.net"}, nil},
+	{"unpkg", ServiceCDN, []string{"unpkg.com"}, nil},
+	{"jQuery CDN", ServiceCDN, []string{"code.jquery.com"}, nil},
+	{"Google Hosted Libraries", ServiceCDN, []string{"ajax.googleapis.com"}, nil},
+	{"Google Fonts", ServiceCDN, []string{"fonts.googleapis.com", "fonts.gstatic.com"}, nil},
+	{"BootstrapCDN", ServiceCDN, []string{"bootstrapcdn.com"}, nil},
+	{"Font Awesome", ServiceCDN, []string{"fontawesome.com"}, nil},
+	{"Amazon CloudFront", ServiceCDN, []string{"cloudfront.net"}, nil},
+	{"Akamai", ServiceCDN, []string{"akamaihd.net", "akamaized.net"}, nil},
+	{"Fastly", ServiceCDN, []string{"fastly.net"}, nil},
+	{"Azure CDN", ServiceCDN, []string{"azureedge.net"}, nil},
+
+	// Login providers
+	{"Google Sign-In", ServiceOAuth, []string{"accounts.google.com"}, []string{"accounts.google.com/gsi/client"}},
+	{"Microsoft identity platform", ServiceOAuth, []string{"login.microsoftonline.com", "login.live.com"}, nil},
+	{"Sign in with Apple", ServiceOAuth, []string{"appleid.apple.com", "appleid.cdn-apple.com"}, nil},
+	{"Facebook Login", ServiceOAuth, nil, []string{"facebook.com/dialog/oauth", "FB.login("}},
+	{"GitHub OAuth", ServiceOAuth, nil, []string{"github.com/login/oauth"}},
+	{"Auth0", ServiceOAuth, []string{"auth0.com"}, nil},
+	{"Okta", ServiceOAuth, []string{"okta.com", "oktacdn.com"}, nil},
+	{"Amazon Cognito", ServiceOAuth, []string{"amazoncognito.com"}, nil},
+	{"Firebase Authentication", ServiceOAuth, []string{"identitytoolkit.googleapis.com"}, []string{"firebase.auth("}},
+	{"Keycloak", ServiceOAuth, nil, []string{"/protocol/openid-connect/"}},
+
+	// CAPTCHAs
+	{"reCAPTCHA", ServiceCaptcha, []string{"recaptcha.net"}, []string{"google.com/recaptcha", "grecaptcha."}},
+	{"hCaptcha", ServiceCaptcha, []string{"hcaptcha.com"}, nil},
+	{"Cloudflare Turnstile", ServiceCaptcha, []string{"challenges.cloudflare.com"}, nil},
+
+	// Error and performance monitoring
+	{"Sentry", ServiceMonitoring, []string{"sentry.io", "sentry-cdn.com"}, []string{"Sentry.init("}},
+	{"New Relic", ServiceMonitoring, []string{"newrelic.com", "nr-data.net"}, []string{"NREUM"}},
+	{"Datadog RUM", ServiceMonitoring, []string{"datadoghq-browser-agent.com", "browser-intake-datadoghq.com"}, []string{"DD_RUM"}},
+	{"Bugsnag", ServiceMonitoring, []string{"bugsnag.com"}, nil},
+	{"LogRocket", ServiceMonitoring, []string{"logrocket.com", "lr-ingest.io"}, nil},
+
+	// Cookie consent
+	{"OneTrust", ServiceConsent, []string{"onetrust.com", "cookielaw.org"}, nil},
+	{"Cookiebot", ServiceConsent, []string{"cookiebot.com"}, nil},
+	{"TrustArc", ServiceConsent, []string{"trustarc.com"}, nil},
+	{"Osano", ServiceConsent, []string{"osano.com"}, nil},
+
+	// Marketing automation and forms
+	{"HubSpot", ServiceMarketing, []string{"hubspot.com", "hs-scripts.com", "hsforms.net", "hs-analytics.net"}, nil},
+	{"Marketo", ServiceMarketing, []string{"marketo.net", "marketo.com"}, nil},
+	{"Mailchimp", ServiceMarketing, []string{"list-manage.com", "chimpstatic.com"}, nil},
+	{"Typeform", ServiceMarketing, []string{"typeform.com"}, nil},
+}
+
+// lookupService returns the known service a host belongs to, by its
+// longest matching host
+func lookupService(host string) (knownService, bool) {
+	host = strings.ToLower(host)
+	var found knownService
+	matched := 0
+	for _, service := range knownServices {
+		for _, suffix := range service.hosts {
+			if len(suffix) > matched && (host == suffix || strings.HasSuffix(host, "."+suffix)) {
+				found, matched = service, len(suffix)
+			}
+		}
+	}
+	return found, matched > 0
+}
+
+// detectServices finds the third-party services a page references, by the
+// hosts of its links and the URLs in its code, and by markers such as a
+// widget's setup call
+func detectServices(pageURL, body string) []ThirdPartyService {
+	base, _ := url.Parse(pageURL)
+	if base != nil && base.Host == "" {
+		base = nil
+	}
+	links := extract.Links(body, extract.Options{Base: base})
+	links = append(links, extract.AbsoluteURLs(body, extract.Options{})...)
+
+	found := make(map[string]*ThirdPartyService)
+	var order []string
+	add := func(service knownService) *ThirdPartyService {
+		if s, ok := found[service.name]; ok {
+			return s
+		}
+		s := &ThirdPartyService{Name: service.name, Category: service.category}
+		found[service.name] = s
+		order = append(order, service.name)
+		return s
+	}
+
+	for _, link := range links {
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if base != nil && strings.EqualFold(host, base.Hostname()) {
+			continue
+		}
+		if service, ok := lookupService(host); ok {
+			s := add(service)
+			if !slices.Contains(s.Hosts, host) {
+				s.Hosts = append(s.Hosts, host)
+			}
+		}
+	}
+	for _, service := range knownServices {
+		for _, marker := range service.markers {
+			if strings.Contains(body, marker) {
+				add(service)
+				break
+			}
+		}
+	}
+
+	services := make([]ThirdPartyService, 0, len(order))
+	for _, name := range order {
+		s := found[name]
+		sort.Strings(s.Hosts)
+		services = append(services, *s)
+	}
+	return services
+}
+
+// ServicesByHost merges the third-party services of analyzed pages into
+// one list per host, sorted by host, then by category and name
+func ServicesByHost(results []AnalysisResult) []HostServices {
+	byHost := make(map[string]map[string]*ThirdPartyService)
+	for _, result := range results {
+		if len(result.Services) == 0 {
+			continue
+		}
+		host := hostOf(result.URL)
+		if byHost[host] == nil {
+			byHost[host] = make(map[string]*ThirdPartyService)
+		}
+		for _, service := range result.Services {
+			merged, ok := byHost[host][service.Name]
+			if !ok {
+				merged = &ThirdPartyService{Name: service.Name, Category: service.Category}
+				byHost[host][service.Name] = merged
+			}
+			for _, h := range service.Hosts {
+				if !slices.Contains(merged.Hosts, h) {
+					merged.Hosts = append(merged.Hosts, h)
+				}
+			}
+		}
+	}
+
+	inventory := make([]HostServices, 0, len(byHost))
+	for host, services := range byHost {
+		entry := HostServices{Host: host}
+		for _, service := range services {
+			sort.Strings(service.Hosts)
+			entry.Services = append(entry.Services, *service)
+		}
+		sort.Slice(entry.Services, func(i, j int) bool {
+			a, b := entry.Services[i], entry.Services[j]
+			if a.Category != b.Category {
+				return a.Category < b.Category
+			}
+			return a.Name < b.Name
+		})
+		inventory = append(inventory, entry)
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Host < inventory[j].Host })
+	return inventory
+}
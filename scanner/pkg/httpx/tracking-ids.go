@@ -0,0 +1,104 @@
+package httpx
+
+import (
+	"regexp"
+	"sort"
+)
+
+// TrackingID is an analytics, advertising or site verification ID found
+// in a page. Sites sharing one are usually run by the same owner, however
+// unrelated their domains and hosting look.
+type TrackingID struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// TrackingGroup is a tracking ID and the hosts whose pages carry it
+type TrackingGroup struct {
+	Type  string   `json:"type"`
+	ID    string   `json:"id"`
+	Hosts []string `json:"hosts"`
+}
+
+// trackingPatterns find tracking IDs by type; the first submatch is the
+// ID. Patterns for IDs short or generic enough to appear by chance, such
+// as GA4's G- IDs, require the call or URL that loads them.
+var trackingPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"google-analytics", regexp.MustCompile(`\b(UA-[0-9]{4,10}-[0-9]{1,4})\b`)},
+	{"google-analytics-4", regexp.MustCompile(`(?:gtag/js\?id=|gtag\(\s*["']config["']\s*,\s*["'])(G-[A-Z0-9]{6,12})\b`)},
+	{"google-tag-manager", regexp.MustCompile(`\b(GTM-[A-Z0-9]{4,9})\b`)},
+	{"google-ads", regexp.MustCompile(`\b(AW-[0-9]{9,11})\b`)},
+	{"google-adsense", regexp.MustCompile(`\b(ca-pub-[0-9]{16})\b`)},
+	{"facebook-pixel", regexp.MustCompile(`(?:fbq\(\s*["']init["']\s*,\s*["']|facebook\.com/tr\?id=)([0-9]{15,16})\b`)},
+	{"linkedin-insight", regexp.MustCompile(`_linkedin_partner_id\s*=\s*["']?([0-9]{5,10})`)},
+	{"tiktok-pixel", regexp.MustCompile(`ttq\.load\(\s*["']([A-Z0-9]{20})["']`)},
+	{"hotjar", regexp.MustCompile(`\bhjid\s*:\s*([0-9]{5,9})\b`)},
+	{"microsoft-clarity", regexp.MustCompile(`clarity\.ms/tag/([a-z0-9]{8,12})\b`)},
+	{"yandex-metrica", regexp.MustCompile(`\bym\(\s*([0-9]{5,9})\s*,\s*["']init["']`)},
+	{"segment", regexp.MustCompile(`analytics\.load\(\s*["']([A-Za-z0-9]{20,40})["']`)},
+	{"mixpanel", regexp.MustCompile(`mixpanel\.init\(\s*["']([a-f0-9]{32})["']`)},
+	{"hubspot", regexp.MustCompile(`js\.hs-scripts\.com/([0-9]{5,10})\.js`)},
+	{"google-site-verification", regexp.MustCompile(`(?i)<meta[^>]+name=["']google-site-verification["'][^>]+content=["']([A-Za-z0-9_-]{20,60})["']`)},
+	{"facebook-domain-verification", regexp.MustCompile(`(?i)<meta[^>]+name=["']facebook-domain-verification["'][^>]+content=["']([a-z0-9]{20,40})["']`)},
+}
+
+// extractTrackingIDs finds the tracking IDs in a page, once each
+func extractTrackingIDs(body string) []TrackingID {
+	var ids []TrackingID
+	seen := make(map[TrackingID]bool)
+	for _, tracker := range trackingPatterns {
+		for _, match := range tracker.pattern.FindAllStringSubmatch(body, -1) {
+			id := TrackingID{Type: tracker.kind, ID: match[1]}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// CorrelateTrackingIDs groups the hosts of analyzed pages by the tracking
+// IDs they share, returning only IDs seen on more than one host, most
+// shared first
+func CorrelateTrackingIDs(results []AnalysisResult) []TrackingGroup {
+	hosts := make(map[TrackingID]map[string]bool)
+	for _, result := range results {
+		host := hostOf(result.URL)
+		if host == "" {
+			continue
+		}
+		for _, id := range result.TrackingIDs {
+			if hosts[id] == nil {
+				hosts[id] = make(map[string]bool)
+			}
+			hosts[id][host] = true
+		}
+	}
+
+	var groups []TrackingGroup
+	for id, set := range hosts {
+		if len(set) < 2 {
+			continue
+		}
+		group := TrackingGroup{Type: id.Type, ID: id.ID}
+		for host := range set {
+			group.Hosts = append(group.Hosts, host)
+		}
+		sort.Strings(group.Hosts)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Hosts) != len(groups[j].Hosts) {
+			return len(groups[i].Hosts) > len(groups[j].Hosts)
+		}
+		if groups[i].Type != groups[j].Type {
+			return groups[i].Type < groups[j].Type
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
@@ -0,0 +1,64 @@
+package httpx
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholders substituted for variable path segments
+const (
+	patternID   = "{id}"
+	patternUUID = "{uuid}"
+	patternDate = "{date}"
+	patternHash = "{hash}"
+)
+
+var (
+	numericSegmentRe = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegmentRe    = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	dateSegmentRe    = regexp.MustCompile(`^(?:19|20)[0-9]{2}-?(?:0[1-9]|1[0-2])-?(?:0[1-9]|[12][0-9]|3[01])$`)
+	hashSegmentRe    = regexp.MustCompile(`^(?i)[0-9a-f]{16,}$`)
+	slugIDSegmentRe  = regexp.MustCompile(`^([a-zA-Z][a-zA-Z_-]*[-_])[0-9]+$`)
+)
+
+// URLPattern reduces a URL to a pattern key by replacing variable path
+// segments (numeric IDs, UUIDs, dates, hashes, /page/N) with placeholders.
+// Query strings, including pagination params, are dropped entirely.
+func URLPattern(u *url.URL) string {
+	segments := strings.Split(u.Path, "/")
+	for i, seg := range segments {
+		if seg != "" {
+			segments[i] = patternSegment(seg)
+		}
+	}
+
+	return strings.ToLower(u.Scheme+"://"+u.Host) + strings.Join(segments, "/")
+}
+
+// patternSegment returns the placeholder for a single path segment
+func patternSegment(seg string) string {
+	// Keep the file extension so /img/1.png and /doc/1.pdf stay distinct
+	name, ext := seg, ""
+	if idx := strings.LastIndex(seg, "."); idx > 0 {
+		name, ext = seg[:idx], seg[idx:]
+	}
+
+	switch {
+	case dateSegmentRe.MatchString(name):
+		return patternDate + ext
+	case numericSegmentRe.MatchString(name):
+		return patternID + ext
+	case uuidSegmentRe.MatchString(name):
+		return patternUUID + ext
+	case hashSegmentRe.MatchString(name):
+		return patternHash + ext
+	}
+
+	// product-1234, item_42
+	if match := slugIDSegmentRe.FindStringSubmatch(name); len(match) > 1 {
+		return match[1] + patternID + ext
+	}
+
+	return seg
+}
@@ -0,0 +1,16 @@
+// Package pipeline chains the subdomain, resolve, portscan, probe, crawl and
+// analyze stages for one domain, each stage feeding the next, under one
+// shared rate budget. Hostnames under the domain that the portscan and
+// probe stages read from certificates join the subdomain results with
+// Source "tls-cert", and Imported adds those another tool enumerated.
+//
+//	p := pipeline.New(pipeline.Config{
+//		Domain:  "example.com",
+//		Passive: true,
+//		Stages:  []string{pipeline.StageSubdomain, pipeline.StageProbe},
+//		OnResult: func(stage string, result interface{}) {
+//			fmt.Println(stage, result)
+//		},
+//	})
+//	report, err := p.Run(ctx)
+package pipeline
@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// Known is what earlier runs scanned. Given one, a run is incremental:
+// portscan and probe leave out the hosts it holds that resolve to no new
+// address, and crawl the URLs that answer as they did before.
+type Known struct {
+	// HostIPs are the addresses each port-scanned or probed host has
+	// resolved to
+	HostIPs map[string][]string `json:"host_ips"`
+
+	// URLs are how each probed URL last answered
+	URLs map[string]KnownURL `json:"urls"`
+}
+
+// KnownURL is how a URL answered a probe
+type KnownURL struct {
+	StatusCode int    `json:"status_code"`
+	Title      string `json:"title,omitempty"`
+}
+
+// NewKnown creates an empty record of scanned assets
+func NewKnown() *Known {
+	return &Known{HostIPs: make(map[string][]string), URLs: make(map[string]KnownURL)}
+}
+
+// Add records the hosts a report port-scanned or probed, with the
+// addresses they resolved to, and how its URLs answered
+func (k *Known) Add(report *Report) {
+	ips := make(map[string][]string)
+	for _, r := range report.Subdomains {
+		ips[r.Subdomain] = append(ips[r.Subdomain], r.IPs...)
+	}
+	for _, r := range report.Resolved {
+		ips[r.Subdomain] = append(ips[r.Subdomain], r.IPs...)
+	}
+
+	for _, r := range report.Ports {
+		k.AddHost(r.Host, ips[r.Host])
+	}
+	for _, r := range report.Probes {
+		if u, err := url.Parse(r.URL); err == nil && u.Hostname() != "" {
+			k.AddHost(u.Hostname(), ips[u.Hostname()])
+		}
+		k.URLs[r.URL] = KnownURL{StatusCode: r.StatusCode, Title: r.Title}
+	}
+}
+
+// AddHost records a scanned host and addresses it resolved to
+func (k *Known) AddHost(host string, ips []string) {
+	host = strings.ToLower(host)
+	known := k.HostIPs[host]
+	for _, ip := range ips {
+		if !contains(known, ip) {
+			known = append(known, ip)
+		}
+	}
+	if known == nil {
+		known = []string{}
+	}
+	k.HostIPs[host] = known
+}
+
+// changedHosts returns the hosts not yet scanned, or that resolved this
+// run to an address they had not before. A known host this run did not
+// resolve shows no change.
+func (p *Pipeline) changedHosts(ctx context.Context, report *Report, stage string, hosts []string) []string {
+	if p.config.Known == nil {
+		return hosts
+	}
+
+	current := make(map[string][]string)
+	for _, r := range report.Subdomains {
+		current[r.Subdomain] = append(current[r.Subdomain], r.IPs...)
+	}
+	for _, r := range report.Resolved {
+		current[r.Subdomain] = append(current[r.Subdomain], r.IPs...)
+	}
+
+	var changed []string
+	for _, host := range hosts {
+		known, ok := p.config.Known.HostIPs[strings.ToLower(host)]
+		if !ok {
+			changed = append(changed, host)
+			continue
+		}
+		for _, ip := range current[host] {
+			if !contains(known, ip) {
+				changed = append(changed, host)
+				break
+			}
+		}
+	}
+	p.skipped(ctx, report, stage, len(hosts)-len(changed))
+	return changed
+}
+
+// changedURLs returns the live URLs not probed before, or whose probe
+// this run answered with another status or title
+func (p *Pipeline) changedURLs(ctx context.Context, report *Report, stage string, urls []string) []string {
+	if p.config.Known == nil {
+		return urls
+	}
+
+	probed := make(map[string]httpx.ProbeResult)
+	for _, r := range report.Probes {
+		probed[r.URL] = r
+		if r.FinalURL != "" {
+			probed[r.FinalURL] = r
+		}
+	}
+
+	var changed []string
+	for _, u := range urls {
+		r, ok := probed[u]
+		if !ok {
+			changed = append(changed, u)
+			continue
+		}
+		known, ok := p.config.Known.URLs[r.URL]
+		if !ok || known.StatusCode != r.StatusCode || known.Title != r.Title {
+			changed = append(changed, u)
+		}
+	}
+	p.skipped(ctx, report, stage, len(urls)-len(changed))
+	return changed
+}
+
+// skipped notes how many known, unchanged assets a stage left out
+func (p *Pipeline) skipped(ctx context.Context, report *Report, stage string, n int) {
+	if n == 0 {
+		return
+	}
+	if report.Skipped == nil {
+		report.Skipped = make(map[string]int)
+	}
+	report.Skipped[stage] += n
+	log.Module(ctx, "pipeline").Info("skipping unchanged assets", "stage", stage, log.Target(p.config.Domain), "skipped", n)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
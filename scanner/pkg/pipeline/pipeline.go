@@ -0,0 +1,762 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/screenshot"
+	"github.com/recon-suite/scanner/pkg/state"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/vulndb"
+)
+
+// Stage names
+const (
+	StageSubdomain  = "subdomain"
+	StageResolve    = "resolve"
+	StagePortScan   = "portscan"
+	StageProbe      = "probe"
+	StageScreenshot = "screenshot"
+	StageCrawl      = "crawl"
+	StageAnalyze    = "analyze"
+)
+
+// AllStages lists every stage in execution order
+var AllStages = []string{StageSubdomain, StageResolve, StagePortScan, StageProbe, StageScreenshot, StageCrawl, StageAnalyze}
+
+// DefaultStages are the stages "all" runs. Screenshots need Chrome, so
+// they only run when asked for by name.
+var DefaultStages = []string{StageSubdomain, StageResolve, StagePortScan, StageProbe, StageCrawl, StageAnalyze}
+
+// DefaultPorts are scanned when no port list is given
+var DefaultPorts = []int{
+	21, 22, 25, 80, 81, 110, 143, 443, 445, 591, 593, 832, 981, 1010, 1311,
+	2082, 2083, 2087, 2095, 2096, 3000, 3306, 3389, 4443, 5000, 5432, 5601,
+	5900, 6379, 7001, 8000, 8008, 8080, 8081, 8088, 8443, 8888, 9000, 9090,
+	9200, 9443, 10000, 27017,
+}
+
+// Config holds pipeline configuration
+type Config struct {
+	Domain     string
+	Wordlist   string
+	Passive    bool
+	Bruteforce bool
+	Ports      []int
+	Workers    int
+	Timeout    int
+	CrawlDepth int
+	MaxURLs    int
+	Stages     []string
+
+	// RateLimit is a requests-per-second budget shared by every stage;
+	// StageRates sets each module's own limit (portscan, probe, crawl) under
+	// that budget. Zero leaves the module default.
+	RateLimit  int
+	StageRates map[string]int
+
+	// TargetLatency, if set, makes the probe and crawl rates adaptive,
+	// backing off while responses are slower than it or failing
+	TargetLatency time.Duration
+
+	// Throttle, if set, slows down and may pause hosts whose probe or
+	// crawl responses look like blocking; both stages share it, so a host
+	// that blocked the probes is crawled slowly too
+	Throttle *utils.HostThrottle
+
+	// MaxBodySize and BodyTypes limit the probe stage's reads of response
+	// bodies, which the crawl and analyze stages then work from; see
+	// httpx.ProbeConfig
+	MaxBodySize int64
+	BodyTypes   []string
+
+	// Proxy routes all HTTP traffic (passive sources, probe, crawl, analyze)
+	// through an http://, https:// or socks5:// proxy
+	Proxy string
+
+	// ScreenshotDir is where the screenshot stage writes its images and
+	// gallery (default: screenshots)
+	ScreenshotDir string
+
+	// Progress, if set, receives a status line for each running stage
+	Progress io.Writer
+
+	// Imported are subdomains another tool enumerated, such as those
+	// subdomain.LoadImport reads. The ones under Domain join the subdomain
+	// results, and so the later stages, whether or not the subdomain stage
+	// runs; names enumeration also found keep its result.
+	Imported []subdomain.Result
+
+	// Checkpoint, if set, records each finished stage so an interrupted
+	// run picks up after the last completed stage
+	Checkpoint *state.Checkpoint
+
+	// BruteforceState and ResumeBruteforce keep the subdomain stage's place
+	// in the wordlist across interrupted runs; see subdomain.Config
+	BruteforceState  string
+	ResumeBruteforce bool
+
+	// Scope, if set, is enforced by every stage
+	Scope *scope.Scope
+
+	// CVEs, if set, is checked for the versions portscan and probe find
+	CVEs *vulndb.DB
+
+	// Known, if set, makes the run incremental: portscan, probe and crawl
+	// leave out what earlier runs scanned that has not changed since
+	Known *Known
+
+	// Budget, if set, replaces the budget built from RateLimit so the caller
+	// can pause or re-rate the run
+	Budget *utils.Budget
+
+	// SharedBudget, if set, is a ceiling shared with other scans running
+	// at the same time; the run draws from it on top of RateLimit
+	SharedBudget *utils.Budget
+
+	// OnStage is called when each enabled stage starts and finishes
+	OnStage func(StageEvent)
+
+	// OnResult is called with each result as a stage finds it: a
+	// subdomain.Result, subdomain.ResolutionResult, portscan.Result,
+	// httpx.ProbeResult, screenshot.Result, httpx.CrawlResult or
+	// httpx.AnalysisResult. Calls may be concurrent.
+	OnResult func(stage string, result interface{})
+}
+
+// StageEvent reports a stage starting or finishing
+type StageEvent struct {
+	Domain   string
+	Stage    string
+	Done     bool
+	Progress *utils.Progress
+
+	// Abort cancels only this stage; its partial results are kept and the
+	// following stages still run
+	Abort func()
+}
+
+// Report is the consolidated output for one target domain
+type Report struct {
+	Domain      string                       `json:"domain"`
+	StartedAt   string                       `json:"started_at"`
+	FinishedAt  string                       `json:"finished_at"`
+	Stages      []string                     `json:"stages"`
+	Subdomains  []subdomain.Result           `json:"subdomains,omitempty"`
+	Resolved    []subdomain.ResolutionResult `json:"resolved,omitempty"`
+	Ports       []portscan.Result            `json:"ports,omitempty"`
+	Probes      []httpx.ProbeResult          `json:"probes,omitempty"`
+	Crawl       []httpx.CrawlResult          `json:"crawl,omitempty"`
+	Screenshots []screenshot.Result          `json:"screenshots,omitempty"`
+	Analysis    []httpx.AnalysisResult       `json:"analysis,omitempty"`
+
+	// Skipped counts, by stage, the unchanged hosts and URLs an
+	// incremental run left out
+	Skipped map[string]int `json:"skipped,omitempty"`
+
+	Errors []string `json:"errors,omitempty"`
+}
+
+// stageState is the checkpoint saved for a domain after each finished stage
+type stageState struct {
+	Completed []string `json:"completed"`
+	Carry     []string `json:"carry"`
+	Report    *Report  `json:"report"`
+}
+
+// Pipeline chains the scanner modules into one recon workflow
+type Pipeline struct {
+	config Config
+	stages map[string]bool
+	budget *utils.Budget
+
+	// responses are the live probe results with their bodies, which the
+	// crawl and analyze stages read instead of fetching each URL again.
+	// They are not checkpointed; a resumed run fetches.
+	responses []httpx.ProbeResult
+}
+
+// New creates a new pipeline
+func New(config Config) *Pipeline {
+	if config.Workers == 0 {
+		config.Workers = 100
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	if config.CrawlDepth == 0 {
+		config.CrawlDepth = 2
+	}
+	if config.MaxURLs == 0 {
+		config.MaxURLs = 500
+	}
+	if len(config.Ports) == 0 {
+		config.Ports = DefaultPorts
+	}
+	if len(config.Stages) == 0 {
+		config.Stages = DefaultStages
+	}
+
+	stages := make(map[string]bool)
+	for _, stage := range config.Stages {
+		stages[strings.ToLower(strings.TrimSpace(stage))] = true
+	}
+
+	budget := config.Budget
+	if budget == nil {
+		budget = utils.NewSubBudget(config.RateLimit, config.SharedBudget)
+	}
+
+	return &Pipeline{
+		config: config,
+		stages: stages,
+		budget: budget,
+	}
+}
+
+// ParseStages parses a comma-separated stage list
+func ParseStages(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" || spec == "all" {
+		return DefaultStages, nil
+	}
+
+	valid := make(map[string]bool)
+	for _, stage := range AllStages {
+		valid[stage] = true
+	}
+
+	var stages []string
+	for _, part := range strings.Split(spec, ",") {
+		stage := strings.ToLower(strings.TrimSpace(part))
+		if stage == "" {
+			continue
+		}
+		if !valid[stage] {
+			return nil, fmt.Errorf("unknown stage: %s", stage)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// RateLimitedStages are the stages that accept a StageRates entry
+var RateLimitedStages = []string{StagePortScan, StageProbe, StageCrawl}
+
+// ParseStageRates parses per-stage rate limits like "portscan=1000,probe=200"
+func ParseStageRates(spec string) (map[string]int, error) {
+	rates := make(map[string]int)
+	if strings.TrimSpace(spec) == "" {
+		return rates, nil
+	}
+
+	valid := make(map[string]bool)
+	for _, stage := range RateLimitedStages {
+		valid[stage] = true
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		stage := strings.ToLower(strings.TrimSpace(name))
+		if !ok || !valid[stage] {
+			return nil, fmt.Errorf("invalid stage rate %q (want %s=N)", part, strings.Join(RateLimitedStages, "|"))
+		}
+		rps, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || rps < 0 {
+			return nil, fmt.Errorf("invalid stage rate %q: %q is not a non-negative number", part, value)
+		}
+		rates[stage] = rps
+	}
+	return rates, nil
+}
+
+// Enabled reports whether a stage is turned on
+func (p *Pipeline) Enabled(stage string) bool {
+	return p.stages[stage]
+}
+
+// Run executes the enabled stages in order and returns the report. If ctx
+// is cancelled, the partial report is returned with ctx's error and the
+// interrupted stage is not checkpointed.
+func (p *Pipeline) Run(ctx context.Context) (*Report, error) {
+	if p.config.Domain == "" {
+		return nil, fmt.Errorf("pipeline requires a domain")
+	}
+
+	report := &Report{
+		Domain:    p.config.Domain,
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, stage := range AllStages {
+		if p.Enabled(stage) {
+			report.Stages = append(report.Stages, stage)
+		}
+	}
+
+	// Pick up after the last stage a previous run finished
+	var saved stageState
+	if p.config.Checkpoint.Load(p.config.Domain, &saved) && saved.Report != nil {
+		report = saved.Report
+	}
+	completed := make(map[string]bool)
+	for _, stage := range saved.Completed {
+		completed[stage] = true
+	}
+	if len(saved.Completed) > 0 {
+		log.Module(ctx, "pipeline").Info("resuming from checkpoint", log.Target(p.config.Domain), "completed", saved.Completed)
+	}
+
+	// Each step takes the previous step's hosts/targets/URLs and returns its
+	// own; disabled steps pass their input through
+	type stepFunc func(context.Context, *utils.Progress, []string) []string
+	steps := []struct {
+		stage string
+		noun  string
+		run   stepFunc
+	}{
+		{StageSubdomain, "found", func(ctx context.Context, progress *utils.Progress, _ []string) []string {
+			return p.runSubdomain(ctx, progress, report)
+		}},
+		{StageResolve, "alive", func(ctx context.Context, progress *utils.Progress, hosts []string) []string {
+			return p.runResolve(ctx, progress, report, hosts)
+		}},
+		{StagePortScan, "open", func(ctx context.Context, progress *utils.Progress, hosts []string) []string {
+			return p.runPortScan(ctx, progress, report, hosts)
+		}},
+		{StageProbe, "live", func(ctx context.Context, progress *utils.Progress, targets []string) []string {
+			return p.runProbe(ctx, progress, report, targets)
+		}},
+		{StageScreenshot, "captured", func(ctx context.Context, progress *utils.Progress, urls []string) []string {
+			p.runScreenshot(ctx, progress, report, urls)
+			return urls
+		}},
+		{StageCrawl, "urls", func(ctx context.Context, progress *utils.Progress, urls []string) []string {
+			p.runCrawl(ctx, progress, report, urls)
+			return urls
+		}},
+		{StageAnalyze, "analyzed", func(ctx context.Context, progress *utils.Progress, urls []string) []string {
+			p.runAnalyze(ctx, progress, report, urls)
+			return urls
+		}},
+	}
+
+	carry := saved.Carry
+	for _, step := range steps {
+		if completed[step.stage] {
+			continue
+		}
+
+		if p.Enabled(step.stage) {
+			carry = p.runStage(ctx, report, step.stage, step.noun, step.run, carry)
+		} else {
+			carry = step.run(ctx, nil, carry)
+		}
+		if ctx.Err() != nil {
+			report.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+			return report, ctx.Err()
+		}
+
+		saved.Completed = append(saved.Completed, step.stage)
+		saved.Carry = carry
+		saved.Report = report
+		if err := p.config.Checkpoint.Save(p.config.Domain, saved); err != nil {
+			log.Module(ctx, "pipeline").Warn("saving checkpoint", log.Target(p.config.Domain), log.Err(err))
+			report.Errors = append(report.Errors, fmt.Sprintf("checkpoint: %v", err))
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	return report, nil
+}
+
+// runStage runs one enabled stage under its own cancellable context,
+// reporting its start and finish to OnStage
+func (p *Pipeline) runStage(ctx context.Context, report *Report, stage, noun string,
+	run func(context.Context, *utils.Progress, []string) []string, in []string) []string {
+	stageCtx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	progress := p.progress(stage, noun)
+	event := StageEvent{Domain: p.config.Domain, Stage: stage, Progress: progress, Abort: abort}
+	if p.config.OnStage != nil {
+		p.config.OnStage(event)
+	}
+
+	logger := log.Module(ctx, "pipeline").With("stage", stage, log.Target(p.config.Domain))
+	logger.Debug("stage started", "in", len(in))
+	start := time.Now()
+
+	out := run(stageCtx, progress, in)
+	progress.Stop()
+
+	switch {
+	case stageCtx.Err() != nil && ctx.Err() == nil:
+		logger.Warn("stage aborted", log.Since(start))
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: aborted", stage))
+	case len(out) == 0:
+		logger.Info("stage passed nothing on", "in", len(in), log.Since(start))
+	default:
+		logger.Debug("stage finished", "in", len(in), "out", len(out), log.Since(start))
+	}
+	if p.config.OnStage != nil {
+		event.Done = true
+		p.config.OnStage(event)
+	}
+	return out
+}
+
+// progress starts a stage progress reporter, or returns nil if nothing
+// consumes one
+func (p *Pipeline) progress(stage, noun string) *utils.Progress {
+	if p.config.Progress == nil && p.config.OnStage == nil {
+		return nil
+	}
+	progress := utils.NewProgress(p.config.Domain+" "+stage, noun, p.config.Progress)
+	progress.Start()
+	return progress
+}
+
+// emit passes a result to OnResult if set
+func (p *Pipeline) emit(stage string, result interface{}) {
+	if p.config.OnResult != nil {
+		p.config.OnResult(stage, result)
+	}
+}
+
+// runSubdomain enumerates subdomains and merges in the imported ones,
+// returning the hosts to carry forward. Imported names are carried even
+// when the stage is disabled.
+func (p *Pipeline) runSubdomain(ctx context.Context, progress *utils.Progress, report *Report) []string {
+	if p.Enabled(StageSubdomain) {
+		scanner := subdomain.NewScanner(subdomain.Config{
+			Domain:     p.config.Domain,
+			Wordlist:   p.config.Wordlist,
+			Workers:    p.config.Workers,
+			Timeout:    p.config.Timeout,
+			Passive:    p.config.Passive,
+			Bruteforce: p.config.Bruteforce,
+			Proxy:      p.config.Proxy,
+			Progress:   progress,
+			Scope:      p.config.Scope,
+			Budget:     p.budget,
+			OnResult:   func(r subdomain.Result) { p.emit(StageSubdomain, r) },
+
+			BruteforceState:  p.config.BruteforceState,
+			ResumeBruteforce: p.config.ResumeBruteforce,
+		})
+		results, err := scanner.EnumerateContext(ctx)
+		for _, sourceErr := range scanner.Errors() {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", StageSubdomain, sourceErr))
+		}
+		if err != nil && ctx.Err() == nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StageSubdomain, err))
+		} else {
+			report.Subdomains = results
+		}
+	}
+
+	// Merge in the names another tool found, tagged with its Source
+	seen := map[string]bool{strings.ToLower(p.config.Domain): true}
+	for _, r := range report.Subdomains {
+		seen[r.Subdomain] = true
+	}
+	imported := subdomain.FromImport(p.config.Domain, p.config.Imported, seen, p.config.Scope)
+	for _, r := range imported {
+		progress.Found()
+		p.emit(StageSubdomain, r)
+	}
+	report.Subdomains = append(report.Subdomains, imported...)
+
+	hosts := []string{p.config.Domain}
+	seen = map[string]bool{p.config.Domain: true}
+	for _, r := range report.Subdomains {
+		if !seen[r.Subdomain] {
+			seen[r.Subdomain] = true
+			hosts = append(hosts, r.Subdomain)
+		}
+	}
+	return hosts
+}
+
+// runResolve keeps only hosts that resolve
+func (p *Pipeline) runResolve(ctx context.Context, progress *utils.Progress, report *Report, hosts []string) []string {
+	if !p.Enabled(StageResolve) {
+		return hosts
+	}
+
+	resolver := subdomain.NewResolver(subdomain.ResolverConfig{
+		Workers:  p.config.Workers,
+		Scope:    p.config.Scope,
+		Budget:   p.budget,
+		Progress: progress,
+		OnResult: func(r subdomain.ResolutionResult) { p.emit(StageResolve, r) },
+	})
+	report.Resolved = resolver.Resolve(ctx, hosts)
+
+	alive := make([]string, 0, len(report.Resolved))
+	for _, r := range report.Resolved {
+		alive = append(alive, r.Subdomain)
+	}
+	return alive
+}
+
+// runPortScan scans hosts, returning host:port probe targets for open ports
+func (p *Pipeline) runPortScan(ctx context.Context, progress *utils.Progress, report *Report, hosts []string) []string {
+	if !p.Enabled(StagePortScan) {
+		return hosts
+	}
+	hosts = p.changedHosts(ctx, report, StagePortScan, hosts)
+	if len(hosts) == 0 {
+		return hosts
+	}
+
+	scanner := portscan.NewScanner(portscan.Config{
+		Targets:       hosts,
+		Ports:         p.config.Ports,
+		Workers:       p.config.Workers,
+		RateLimit:     p.config.StageRates[StagePortScan],
+		ServiceDetect: true,
+		Progress:      progress,
+		Scope:         p.config.Scope,
+		Budget:        p.budget,
+		CVEs:          p.config.CVEs,
+		ReverseDNS:    true,
+		OnResult:      func(r portscan.Result) { p.emit(StagePortScan, r) },
+	})
+	results, err := scanner.ScanContext(ctx)
+	if err != nil && ctx.Err() == nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StagePortScan, err))
+		return hosts
+	}
+	report.Ports = results
+
+	var targets, names []string
+	for _, r := range results {
+		targets = append(targets, probeTarget(r.Host, r.Port))
+		names = append(names, r.CertNames...)
+	}
+	p.harvestCertNames(ctx, report, StagePortScan, names)
+	return targets
+}
+
+// probeTarget builds a prober target for an open port
+func probeTarget(host string, port int) string {
+	switch port {
+	case 80:
+		return "http://" + host
+	case 443:
+		return "https://" + host
+	default:
+		// Let the prober try https then http
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+}
+
+// runProbe probes targets, returning live URLs
+func (p *Pipeline) runProbe(ctx context.Context, progress *utils.Progress, report *Report, targets []string) []string {
+	if !p.Enabled(StageProbe) {
+		return nil
+	}
+	if !p.Enabled(StagePortScan) {
+		targets = p.changedHosts(ctx, report, StageProbe, targets)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	prober := httpx.NewProber(httpx.ProbeConfig{
+		Targets:        targets,
+		Workers:        p.config.Workers,
+		Timeout:        p.config.Timeout,
+		FollowRedirect: true,
+		RateLimit:      p.config.StageRates[StageProbe],
+		Proxy:          p.config.Proxy,
+		Progress:       progress,
+		Scope:          p.config.Scope,
+		Budget:         p.budget,
+		CVEs:           p.config.CVEs,
+		MaxBodySize:    p.config.MaxBodySize,
+		BodyTypes:      p.config.BodyTypes,
+		KeepBody:       p.Enabled(StageCrawl) || p.Enabled(StageAnalyze),
+		TargetLatency:  p.config.TargetLatency,
+		Throttle:       p.config.Throttle,
+		OnResult: func(r httpx.ProbeResult) {
+			r.Body = ""
+			p.emit(StageProbe, r)
+		},
+	})
+	results, err := prober.ProbeContext(ctx)
+	if err != nil && ctx.Err() == nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StageProbe, err))
+		return nil
+	}
+
+	// Bodies are handed to later stages but kept out of the report
+	p.responses = results
+	report.Probes = make([]httpx.ProbeResult, len(results))
+	for i, r := range results {
+		r.Body = ""
+		report.Probes[i] = r
+	}
+
+	var urls, names []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		names = append(names, r.CertNames...)
+		url := r.URL
+		if r.FinalURL != "" {
+			url = r.FinalURL
+		}
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	p.harvestCertNames(ctx, report, StageProbe, names)
+	return urls
+}
+
+// harvestCertNames adds the in-scope hostnames under the domain that a
+// stage read from certificates to the subdomain results, with Source
+// "tls-cert", emitting each new one as a subdomain result
+func (p *Pipeline) harvestCertNames(ctx context.Context, report *Report, stage string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	seen := map[string]bool{strings.ToLower(p.config.Domain): true}
+	for _, r := range report.Subdomains {
+		seen[r.Subdomain] = true
+	}
+	found := subdomain.FromCertNames(p.config.Domain, names, seen, p.config.Scope)
+	for _, r := range found {
+		p.emit(StageSubdomain, r)
+	}
+	report.Subdomains = append(report.Subdomains, found...)
+	if len(found) > 0 {
+		log.Module(ctx, "pipeline").Debug("names harvested from certificates", "stage", stage, log.Target(p.config.Domain), "new", len(found))
+	}
+}
+
+// runScreenshot captures the live URLs and writes their gallery
+func (p *Pipeline) runScreenshot(ctx context.Context, progress *utils.Progress, report *Report, urls []string) {
+	if !p.Enabled(StageScreenshot) || len(urls) == 0 {
+		return
+	}
+
+	dir := p.config.ScreenshotDir
+	if dir == "" {
+		dir = "screenshots"
+	}
+	capturer := screenshot.New(screenshot.Config{
+		OutputDir: dir,
+		Proxy:     p.config.Proxy,
+		Progress:  progress,
+		Scope:     p.config.Scope,
+		Budget:    p.budget,
+		OnResult:  func(r screenshot.Result) { p.emit(StageScreenshot, r) },
+	})
+	results, err := capturer.Capture(ctx, urls)
+	if err != nil && ctx.Err() == nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StageScreenshot, err))
+		return
+	}
+	report.Screenshots = results
+	if err := screenshot.WriteGallery(dir, results); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StageScreenshot, err))
+	}
+}
+
+// runCrawl crawls the live URLs
+func (p *Pipeline) runCrawl(ctx context.Context, progress *utils.Progress, report *Report, urls []string) {
+	if !p.Enabled(StageCrawl) {
+		return
+	}
+	if urls = p.changedURLs(ctx, report, StageCrawl, urls); len(urls) == 0 {
+		return
+	}
+
+	crawler := httpx.NewCrawler(httpx.CrawlConfig{
+		StartURLs:     urls,
+		MaxDepth:      p.config.CrawlDepth,
+		MaxURLs:       p.config.MaxURLs,
+		Timeout:       p.config.Timeout,
+		RateLimit:     p.config.StageRates[StageCrawl],
+		SameHost:      true,
+		JSParse:       true,
+		Strategy:      httpx.StrategyBFS,
+		Proxy:         p.config.Proxy,
+		Progress:      progress,
+		Scope:         p.config.Scope,
+		Budget:        p.budget,
+		Responses:     p.responses,
+		TargetLatency: p.config.TargetLatency,
+		Throttle:      p.config.Throttle,
+		OnResult:      func(r httpx.CrawlResult) { p.emit(StageCrawl, r) },
+	})
+	results, err := crawler.CrawlContext(ctx)
+	if err != nil && ctx.Err() == nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", StageCrawl, err))
+		return
+	}
+	report.Crawl = results
+}
+
+// runAnalyze runs the response analyzer over each live URL, reading the
+// body the probe stage kept or fetching it
+func (p *Pipeline) runAnalyze(ctx context.Context, progress *utils.Progress, report *Report, urls []string) {
+	if !p.Enabled(StageAnalyze) || len(urls) == 0 {
+		return
+	}
+
+	prober := httpx.NewProber(httpx.ProbeConfig{
+		Timeout:        p.config.Timeout,
+		FollowRedirect: true,
+		Proxy:          p.config.Proxy,
+		Scope:          p.config.Scope,
+		Budget:         p.budget,
+	})
+	analyzer := httpx.NewResponseAnalyzer()
+	progress.AddTotal(len(urls))
+
+	probed := make(map[string]httpx.ProbeResult)
+	for _, r := range p.responses {
+		if r.Body == "" {
+			continue
+		}
+		probed[r.URL] = r
+		if r.FinalURL != "" {
+			probed[r.FinalURL] = r
+		}
+	}
+
+	for _, url := range urls {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		result, ok := probed[url]
+		body := result.Body
+		if !ok {
+			result, body = prober.Fetch(ctx, url)
+		}
+		progress.Done()
+		if result.StatusCode == 0 {
+			continue
+		}
+		analysis := analyzer.Analyze(url, result.Headers, body)
+		progress.Found()
+		p.emit(StageAnalyze, analysis)
+		report.Analysis = append(report.Analysis, analysis)
+	}
+}
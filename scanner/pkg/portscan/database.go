@@ -0,0 +1,348 @@
+package portscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// parseMySQLGreeting reads the product and version from the handshake
+// packet a MySQL or MariaDB server sends on connect. A server refusing
+// the client sends an error packet instead, which still names it MySQL.
+func parseMySQLGreeting(raw []byte) (ServiceInfo, bool) {
+	// 3-byte little-endian payload length, sequence number 0
+	if len(raw) < 6 || raw[3] != 0 {
+		return ServiceInfo{}, false
+	}
+	length := int(raw[0]) | int(raw[1])<<8 | int(raw[2])<<16
+	if length < 2 || length > 1024 {
+		return ServiceInfo{}, false
+	}
+	payload := raw[4:]
+	if len(payload) > length {
+		payload = payload[:length]
+	}
+
+	switch payload[0] {
+	case 0xff:
+		// Error: 2-byte code, then "Host '...' is not allowed to connect"
+		if len(payload) < 4 {
+			return ServiceInfo{}, false
+		}
+		return ServiceInfo{Name: "mysql", Product: "MySQL"}, true
+	case 10:
+		// Protocol 10, then the NUL-terminated server version
+		end := bytes.IndexByte(payload[1:], 0)
+		if end <= 0 {
+			return ServiceInfo{}, false
+		}
+		version := string(payload[1 : 1+end])
+		info := ServiceInfo{Name: "mysql", Product: "MySQL", Version: version}
+
+		// MariaDB prefixes "5.5.5-" for old clients' sake:
+		// "5.5.5-10.6.12-MariaDB-1:10.6.12+maria~ubu2004"
+		if i := strings.Index(version, "-MariaDB"); i >= 0 {
+			info.Product = "MariaDB"
+			info.Version = strings.TrimPrefix(version[:i], "5.5.5-")
+		} else if i := strings.IndexByte(version, '-'); i > 0 {
+			info.Version = version[:i]
+		}
+		return info, true
+	}
+	return ServiceInfo{}, false
+}
+
+// postgresStartup is a startup packet for protocol 9.0, which no server
+// speaks, so every version answers with an error naming the protocol
+// range it does
+var postgresStartup = []byte{0, 0, 0, 8, 0, 9, 0, 0}
+
+// postgresProtocolRange matches "server supports 3.0 to 3.0"
+var postgresProtocolRange = regexp.MustCompile(`(\d+\.\d+) to (\d+\.\d+)`)
+
+// postgresVersions are the server versions each supported protocol range
+// implies: version 2 was dropped in 14 and 3.2 added in 18
+var postgresVersions = map[string]string{
+	"1.0 to 3.0": "7.4-13",
+	"2.0 to 3.0": "7.4-13",
+	"3.0 to 3.0": "14-17",
+	"3.0 to 3.2": "18+",
+}
+
+// probePostgres sends an unsupportable startup packet and reads the
+// server's error. Postgres gives away no exact version before login, so
+// the version is the range its supported protocols imply.
+func (sd *ServiceDetector) probePostgres(ctx context.Context, host string, port int) (ServiceInfo, bool) {
+	reply, err := sd.exchange(ctx, host, port, postgresStartup, nil)
+	if err != nil || len(reply) < 2 || reply[0] != 'E' {
+		return ServiceInfo{}, false
+	}
+	info := ServiceInfo{Name: "postgresql", Product: "PostgreSQL"}
+
+	// Protocol 3 errors are 'E', a length and fields of a type byte and
+	// a NUL-terminated string; servers before 7.4 send one bare string
+	message := string(reply[1:])
+	if len(reply) >= 5 {
+		if n := binary.BigEndian.Uint32(reply[1:5]); n >= 4 && int(n) <= len(reply)-1 {
+			for _, field := range bytes.Split(reply[5:1+n], []byte{0}) {
+				if len(field) > 1 && field[0] == 'M' {
+					message = string(field[1:])
+				}
+			}
+		}
+	}
+	if m := postgresProtocolRange.FindStringSubmatch(message); m != nil {
+		info.Version = postgresVersions[m[1]+" to "+m[2]]
+	}
+	return info, true
+}
+
+// probeRedis asks a Redis server for its INFO server section. A server
+// requiring a password answers -NOAUTH, which names it all the same.
+func (sd *ServiceDetector) probeRedis(ctx context.Context, host string, port int) (ServiceInfo, bool) {
+	reply, err := sd.exchange(ctx, host, port, []byte("INFO server\r\n"), nil)
+	if err != nil || len(reply) == 0 {
+		return ServiceInfo{}, false
+	}
+	switch reply[0] {
+	case '-':
+		if bytes.HasPrefix(reply, []byte("-NOAUTH")) || bytes.HasPrefix(reply, []byte("-DENIED")) {
+			return ServiceInfo{Name: "redis", Product: "Redis"}, true
+		}
+		return ServiceInfo{}, false
+	case '$':
+	default:
+		return ServiceInfo{}, false
+	}
+
+	info := ServiceInfo{Name: "redis", Product: "Redis"}
+	for _, line := range strings.Split(string(reply), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "redis_version":
+			if info.Version == "" {
+				info.Version = value
+			}
+		case "valkey_version":
+			info.Product, info.Version = "Valkey", value
+		}
+	}
+	return info, true
+}
+
+// mongoWireVersions are the MongoDB releases that introduced each wire
+// version, for servers that do not answer buildInfo
+var mongoWireVersions = map[int32]string{
+	2: "2.6", 3: "3.0", 4: "3.2", 5: "3.4", 6: "3.6", 7: "4.0", 8: "4.2",
+	9: "4.4", 13: "5.0", 17: "6.0", 21: "7.0", 25: "8.0",
+}
+
+// probeMongo sends isMaster, which every MongoDB answers before login,
+// then buildInfo for the exact version
+func (sd *ServiceDetector) probeMongo(ctx context.Context, host string, port int) (ServiceInfo, bool) {
+	reply, err := sd.exchange(ctx, host, port, mongoQuery(bsonDocument(bsonInt32("isMaster", 1))), mongoComplete)
+	if err != nil {
+		return ServiceInfo{}, false
+	}
+	// OP_REPLY: header, flags, cursor ID, starting from, number returned
+	hello := mongoBody(reply, 1, 36)
+	if hello == nil {
+		return ServiceInfo{}, false
+	}
+	info := ServiceInfo{Name: "mongodb", Product: "MongoDB"}
+	wire, _ := hello["maxWireVersion"].(int32)
+	info.Version = mongoWireVersions[wire]
+
+	// OP_MSG, from 3.6 on, is the one op newer servers accept buildInfo in
+	if wire >= 6 {
+		request := bsonDocument(bsonInt32("buildInfo", 1), bsonString("$db", "admin"))
+		if reply, err := sd.exchange(ctx, host, port, mongoMsg(request), mongoComplete); err == nil {
+			// OP_MSG: header, flag bits, section kind 0
+			if build := mongoBody(reply, 2013, 21); build != nil {
+				if version, ok := build["version"].(string); ok && version != "" {
+					info.Version = version
+				}
+			}
+		}
+	}
+	return info, true
+}
+
+// exchange sends request on a fresh connection and reads the server's
+// answer within the detector's timeout: one read, or with complete set,
+// reads until it reports the answer whole
+func (sd *ServiceDetector) exchange(ctx context.Context, host string, port int, request []byte, complete func([]byte) bool) ([]byte, error) {
+	conn, err := sd.dial(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(sd.timeout))
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	var reply []byte
+	buffer := make([]byte, 4096)
+	for len(reply) < 64*1024 {
+		n, err := conn.Read(buffer)
+		reply = append(reply, buffer[:n]...)
+		if err != nil {
+			if len(reply) > 0 {
+				return reply, nil
+			}
+			return nil, err
+		}
+		if complete == nil || complete(reply) {
+			break
+		}
+	}
+	return reply, nil
+}
+
+// mongoComplete reports whether a MongoDB reply has reached the length
+// its header declares
+func mongoComplete(reply []byte) bool {
+	return len(reply) >= 4 && int(binary.LittleEndian.Uint32(reply)) <= len(reply)
+}
+
+// mongoQuery is an OP_QUERY of admin.$cmd
+func mongoQuery(query []byte) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, 0) // flags
+	body = append(body, "admin.$cmd\x00"...)
+	body = binary.LittleEndian.AppendUint32(body, 0)                      // skip
+	body = binary.LittleEndian.AppendUint32(body, uint32(math.MaxUint32)) // return -1
+	return mongoMessage(2004, append(body, query...))
+}
+
+// mongoMsg is an OP_MSG with one body section
+func mongoMsg(document []byte) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, 0) // flag bits
+	body = append(body, 0)                           // section kind: body
+	return mongoMessage(2013, append(body, document...))
+}
+
+// mongoMessage prefixes a message header
+func mongoMessage(opCode uint32, body []byte) []byte {
+	message := binary.LittleEndian.AppendUint32(nil, uint32(16+len(body)))
+	message = binary.LittleEndian.AppendUint32(message, 1) // request ID
+	message = binary.LittleEndian.AppendUint32(message, 0) // response to
+	message = binary.LittleEndian.AppendUint32(message, opCode)
+	return append(message, body...)
+}
+
+// mongoBody returns the first document of a reply with the op code
+// wanted, found at offset, or nil
+func mongoBody(reply []byte, opCode uint32, offset int) map[string]interface{} {
+	if len(reply) < offset+5 || binary.LittleEndian.Uint32(reply[12:16]) != opCode {
+		return nil
+	}
+	return parseBSON(reply[offset:])
+}
+
+// bsonDocument wraps encoded elements in a document
+func bsonDocument(elements ...[]byte) []byte {
+	body := bytes.Join(elements, nil)
+	document := binary.LittleEndian.AppendUint32(nil, uint32(4+len(body)+1))
+	document = append(document, body...)
+	return append(document, 0)
+}
+
+// bsonInt32 encodes an int32 element
+func bsonInt32(name string, value int32) []byte {
+	element := append([]byte{0x10}, name+"\x00"...)
+	return binary.LittleEndian.AppendUint32(element, uint32(value))
+}
+
+// bsonString encodes a string element
+func bsonString(name, value string) []byte {
+	element := append([]byte{0x02}, name+"\x00"...)
+	element = binary.LittleEndian.AppendUint32(element, uint32(len(value)+1))
+	element = append(element, value...)
+	return append(element, 0)
+}
+
+// parseBSON reads the top-level string, int, double and bool fields of a
+// document; nested documents and other types are skipped
+func parseBSON(b []byte) map[string]interface{} {
+	if len(b) < 5 {
+		return nil
+	}
+	length := int(binary.LittleEndian.Uint32(b))
+	if length < 5 || length > len(b) {
+		return nil
+	}
+	b = b[4 : length-1]
+
+	fields := make(map[string]interface{})
+	for len(b) > 0 {
+		kind := b[0]
+		end := bytes.IndexByte(b[1:], 0)
+		if end < 0 {
+			break
+		}
+		name := string(b[1 : 1+end])
+		b = b[2+end:]
+
+		size := 0
+		switch kind {
+		case 0x01: // double
+			if len(b) >= 8 {
+				fields[name] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			}
+			size = 8
+		case 0x02: // string
+			if len(b) >= 4 {
+				n := int(binary.LittleEndian.Uint32(b))
+				if n >= 1 && 4+n <= len(b) {
+					fields[name] = string(b[4 : 4+n-1])
+				}
+				size = 4 + n
+			}
+		case 0x03, 0x04: // document, array
+			if len(b) >= 4 {
+				size = int(binary.LittleEndian.Uint32(b))
+			}
+		case 0x05: // binary
+			if len(b) >= 4 {
+				size = 5 + int(binary.LittleEndian.Uint32(b))
+			}
+		case 0x07: // object ID
+			size = 12
+		case 0x08: // bool
+			if len(b) >= 1 {
+				fields[name] = b[0] != 0
+			}
+			size = 1
+		case 0x09, 0x11: // datetime, timestamp
+			size = 8
+		case 0x0a: // null
+		case 0x10: // int32
+			if len(b) >= 4 {
+				fields[name] = int32(binary.LittleEndian.Uint32(b))
+			}
+			size = 4
+		case 0x12: // int64
+			if len(b) >= 8 {
+				fields[name] = int64(binary.LittleEndian.Uint64(b))
+			}
+			size = 8
+		case 0x13: // decimal128
+			size = 16
+		default:
+			return fields
+		}
+		if size < 0 || size > len(b) {
+			break
+		}
+		b = b[size:]
+	}
+	return fields
+}
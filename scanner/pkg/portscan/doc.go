@@ -0,0 +1,42 @@
+// Package portscan finds open TCP ports with connect scans and identifies
+// the services behind them.
+//
+//	scanner := portscan.NewScanner(portscan.Config{
+//		Targets:       []string{"192.0.2.10"},
+//		Ports:         []int{22, 80, 443},
+//		ServiceDetect: true,
+//	})
+//	open, err := scanner.ScanContext(ctx)
+//
+// With FTPAnonymous, ports identified as FTP are also tried with an
+// anonymous login; see CheckAnonymousFTP. With STARTTLS, SMTP, IMAP, POP3,
+// FTP and LDAP ports are asked whether they offer STARTTLS, and with
+// STARTTLSUpgrade the upgrade is completed to record the certificate and
+// post-TLS banner; see CheckSTARTTLS. With SSH, SSH ports have their
+// algorithm offerings and host key fingerprints recorded; see
+// FingerprintSSH. With RDP, RDP ports have their security layers, NLA
+// requirement and NTLM names recorded; see FingerprintRDP. With
+// ReverseDNS, each host with open ports has its PTR name looked up once
+// and recorded in its results' RDNS.
+//
+// Throughput is otherwise whichever of Workers and RateLimit binds first.
+// ConnectRate sets connections per second outright, sizing the workers to
+// hold it, and MaxRTT backs that rate off while ports are slow to answer.
+//
+// Tracer maps the routes to hosts with UDP, ICMP or TCP traceroutes,
+// counting the hosts behind each router so paths can be grouped by their
+// upstream network, and noting where a path goes silent before its host:
+//
+//	paths, err := portscan.NewTracer(portscan.TraceConfig{Mode: portscan.TraceTCP}).TraceAll(ctx, map[string]int{"192.0.2.10": 443})
+//
+// ServiceDetector fingerprints a single port in more depth, asking
+// databases for their versions: MySQL's greeting, Postgres's startup
+// error, Redis's INFO and MongoDB's isMaster and buildInfo:
+//
+//	info := portscan.NewServiceDetector(5 * time.Second).DetectContext(ctx, "192.0.2.10", 22)
+//
+// ParseNmap and ParseMasscan read another scanner's report into results,
+// and ParseReport reads either; WebTargets turns the HTTP and HTTPS ports
+// among any results into prober targets. To detect the services behind a
+// report's open ports, scan just those with HostPorts.
+package portscan
@@ -0,0 +1,251 @@
+package portscan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFTPListing caps the directory entries kept from an anonymous listing
+const maxFTPListing = 50
+
+// FTPAccess is what an anonymous FTP login allowed. Severity is high when
+// the server reports that anonymous users may write, medium otherwise.
+type FTPAccess struct {
+	Anonymous bool     `json:"anonymous"`
+	Writable  bool     `json:"writable"`
+	Severity  string   `json:"severity"`
+	Listing   []string `json:"listing,omitempty"`
+
+	// WritablePaths are the paths the server reports as writable: "/"
+	// itself, or entries of the root directory
+	WritablePaths []string `json:"writable_paths,omitempty"`
+}
+
+// ftpConn is an FTP control connection
+type ftpConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	host    string
+	timeout time.Duration
+}
+
+// CheckAnonymousFTP logs in to an FTP server as anonymous and lists the
+// root directory. Nothing is written: writability is taken from the
+// permissions the server reports (MLST perm facts, or world-writable
+// modes in a LIST). A nil result means anonymous login was refused.
+func CheckAnonymousFTP(ctx context.Context, dial DialFunc, host string, port int, timeout time.Duration) (*FTPAccess, error) {
+	conn, err := dialOrDirect(dial, timeout)(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := &ftpConn{conn: conn, reader: bufio.NewReader(conn), host: host, timeout: timeout}
+	if code, _, err := c.response(); err != nil {
+		return nil, err
+	} else if code != 220 {
+		return nil, fmt.Errorf("ftp: unexpected greeting %d", code)
+	}
+
+	code, _, err := c.command("USER anonymous")
+	if err != nil {
+		return nil, err
+	}
+	if code == 331 {
+		code, _, err = c.command("PASS anonymous@example.com")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if code != 230 {
+		c.command("QUIT")
+		return nil, nil
+	}
+
+	access := &FTPAccess{Anonymous: true}
+
+	// MLST reports the logged-in user's permissions on the directory
+	// itself: c (create file), m (make directory), p (delete entry)
+	if code, lines, err := c.command("MLST /"); err == nil && code == 250 {
+		for _, line := range lines {
+			if perm, ok := mlsxFact(line, "perm"); ok && strings.ContainsAny(perm, "cmp") {
+				access.WritablePaths = append(access.WritablePaths, "/")
+				break
+			}
+		}
+	}
+
+	entries, machine, err := c.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, writable := parseFTPEntry(entry, machine)
+		if name == "" || name == "." || name == ".." {
+			continue
+		}
+		if len(access.Listing) < maxFTPListing {
+			access.Listing = append(access.Listing, name)
+		}
+		if writable && len(access.WritablePaths) < maxFTPListing {
+			access.WritablePaths = append(access.WritablePaths, "/"+name)
+		}
+	}
+	c.command("QUIT")
+
+	access.Writable = len(access.WritablePaths) > 0
+	access.Severity = "medium"
+	if access.Writable {
+		access.Severity = "high"
+	}
+	return access, nil
+}
+
+// list reads the root directory over a passive data connection, with
+// MLSD when the server has it (machine is then true) and LIST otherwise
+func (c *ftpConn) list() (entries []string, machine bool, err error) {
+	for _, command := range []string{"MLSD /", "LIST /"} {
+		data, err := c.passive()
+		if err != nil {
+			return nil, false, err
+		}
+		code, _, err := c.command(command)
+		if err != nil {
+			data.Close()
+			return nil, false, err
+		}
+		if code != 125 && code != 150 {
+			data.Close()
+			continue
+		}
+
+		data.SetDeadline(time.Now().Add(c.timeout))
+		body, _ := io.ReadAll(io.LimitReader(data, 1024*1024))
+		data.Close()
+		c.response()
+
+		for _, line := range strings.Split(string(body), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				entries = append(entries, line)
+			}
+		}
+		return entries, command == "MLSD /", nil
+	}
+	return nil, false, fmt.Errorf("ftp: server refused MLSD and LIST")
+}
+
+// passive opens a data connection. The address in the 227 reply is
+// ignored apart from its port, since servers behind NAT often report an
+// internal one and trusting it would let a server point the scanner at
+// a third party.
+func (c *ftpConn) passive() (net.Conn, error) {
+	code, lines, err := c.command("PASV")
+	if err != nil {
+		return nil, err
+	}
+	if code != 227 || len(lines) == 0 {
+		return nil, fmt.Errorf("ftp: PASV refused (%d)", code)
+	}
+	reply := lines[len(lines)-1]
+	start, end := strings.Index(reply, "("), strings.Index(reply, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("ftp: malformed PASV reply %q", reply)
+	}
+	fields := strings.Split(reply[start+1:end], ",")
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("ftp: malformed PASV reply %q", reply)
+	}
+	p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("ftp: malformed PASV reply %q", reply)
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(p1*256+p2)), c.timeout)
+}
+
+// command sends one command and reads its reply
+func (c *ftpConn) command(command string) (int, []string, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := io.WriteString(c.conn, command+"\r\n"); err != nil {
+		return 0, nil, err
+	}
+	return c.response()
+}
+
+// response reads a reply, following "123-" continuation lines to the
+// closing "123 " line
+func (c *ftpConn) response() (int, []string, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	var lines []string
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return 0, lines, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+		if len(lines) > 1000 {
+			return 0, lines, fmt.Errorf("ftp: reply too long")
+		}
+		if len(line) >= 4 && line[3] == ' ' {
+			if code, err := strconv.Atoi(line[:3]); err == nil {
+				if len(lines) == 1 || strings.HasPrefix(lines[0], line[:3]+"-") {
+					return code, lines, nil
+				}
+			}
+		}
+	}
+}
+
+// parseFTPEntry returns the name of a directory entry and whether the
+// server reports it as writable
+func parseFTPEntry(entry string, machine bool) (string, bool) {
+	if machine {
+		// "type=dir;perm=flcdmpe; incoming"
+		facts, name, ok := strings.Cut(entry, " ")
+		if !ok {
+			return "", false
+		}
+		if kind, _ := mlsxFact(facts, "type"); kind == "cdir" || kind == "pdir" {
+			return "", false
+		}
+		perm, _ := mlsxFact(facts, "perm")
+		return name, strings.ContainsAny(perm, "acmw")
+	}
+
+	// "drwxrwxrwx 2 ftp ftp 4096 Jan 01 00:00 incoming", with the name
+	// after the eighth field, or a DOS-style "01-01-24 12:00AM <DIR> name"
+	// that carries no permissions
+	fields := strings.Fields(entry)
+	if len(fields) >= 4 && fields[0][0] >= '0' && fields[0][0] <= '9' && strings.Count(fields[0], "-") == 2 {
+		return strings.Join(fields[3:], " "), false
+	}
+	if len(fields) < 9 || len(fields[0]) != 10 {
+		return "", false
+	}
+	name := strings.Join(fields[8:], " ")
+	if i := strings.Index(name, " -> "); i >= 0 {
+		name = name[:i]
+	}
+	return name, fields[0][8] == 'w'
+}
+
+// mlsxFact returns the value of a fact in an MLST or MLSD line
+func mlsxFact(line, name string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if facts, _, ok := strings.Cut(line, " "); ok {
+		line = facts
+	}
+	for _, fact := range strings.Split(line, ";") {
+		if key, value, ok := strings.Cut(fact, "="); ok && strings.EqualFold(key, name) {
+			return strings.ToLower(value), true
+		}
+	}
+	return "", false
+}
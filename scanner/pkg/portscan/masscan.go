@@ -0,0 +1,209 @@
+package portscan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// masscanHost is one object of masscan's JSON output (-oJ, or -oD with one
+// object per line)
+type masscanHost struct {
+	IP        string          `json:"ip"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Ports     []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service *struct {
+			Name   string `json:"name"`
+			Banner string `json:"banner"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// masscanTrailingComma matches the comma older masscan versions leave
+// before the closing bracket of -oJ output
+var masscanTrailingComma = regexp.MustCompile(`,\s*\]\s*$`)
+
+// ParseMasscan reads the open TCP ports of masscan's list (-oL) or JSON
+// (-oJ, -oD) output into results. Banners grabbed with --banners become
+// each port's service and banner. masscan's XML output (-oX) is nmap's
+// format; read it with ParseNmap.
+func ParseMasscan(r io.Reader) ([]Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMasscanMerger()
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		err = parseMasscanJSON(trimmed, m)
+	} else {
+		err = parseMasscanList(data, m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.results, nil
+}
+
+// parseMasscanJSON reads a JSON array of hosts or one host per line
+func parseMasscanJSON(data []byte, m *masscanMerger) error {
+	var hosts []masscanHost
+	if bytes.HasPrefix(data, []byte("[")) {
+		data = masscanTrailingComma.ReplaceAll(data, []byte("]"))
+		if err := json.Unmarshal(data, &hosts); err != nil {
+			return fmt.Errorf("parsing masscan JSON: %w", err)
+		}
+	} else {
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			line = bytes.TrimSuffix(bytes.TrimSpace(line), []byte(","))
+			if len(line) == 0 {
+				continue
+			}
+			var host masscanHost
+			if err := json.Unmarshal(line, &host); err != nil {
+				return fmt.Errorf("parsing masscan JSON: %w", err)
+			}
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, host := range hosts {
+		// The final {"finished": 1} entry has no address
+		if host.IP == "" {
+			continue
+		}
+		seen, _ := strconv.ParseInt(strings.Trim(string(host.Timestamp), `"`), 10, 64)
+		for _, port := range host.Ports {
+			if port.Proto != "tcp" {
+				continue
+			}
+			switch {
+			case port.Service != nil:
+				m.banner(host.IP, port.Port, seen, port.Service.Name, port.Service.Banner)
+			case port.Status == "open":
+				m.open(host.IP, port.Port, seen)
+			}
+		}
+	}
+	return nil
+}
+
+// parseMasscanList reads list output: "open tcp PORT IP TIMESTAMP" lines,
+// and "banner tcp PORT IP TIMESTAMP SERVICE BANNER" lines with --banners
+func parseMasscanList(data []byte, m *masscanMerger) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 7)
+		if len(fields) < 5 {
+			return fmt.Errorf("parsing masscan list: line %d: expected \"open tcp PORT IP TIMESTAMP\"", line)
+		}
+		if fields[1] != "tcp" {
+			continue
+		}
+		port, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("parsing masscan list: line %d: invalid port %q", line, fields[2])
+		}
+		seen, _ := strconv.ParseInt(fields[4], 10, 64)
+
+		switch fields[0] {
+		case "open":
+			m.open(fields[3], port, seen)
+		case "banner":
+			var service, banner string
+			if len(fields) > 5 {
+				service = fields[5]
+			}
+			if len(fields) > 6 {
+				banner = fields[6]
+			}
+			m.banner(fields[3], port, seen, service, banner)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("parsing masscan list: %w", err)
+	}
+	return nil
+}
+
+// masscanMerger collects one result per host and port, as masscan reports
+// a port's banners on entries of their own
+type masscanMerger struct {
+	results []Result
+	index   map[ScanJob]int
+}
+
+func newMasscanMerger() *masscanMerger {
+	return &masscanMerger{index: make(map[ScanJob]int)}
+}
+
+// get returns the result for a port, adding it if it is new
+func (m *masscanMerger) get(host string, port int, seen int64) *Result {
+	key := ScanJob{Host: host, Port: port}
+	if i, ok := m.index[key]; ok {
+		return &m.results[i]
+	}
+	m.index[key] = len(m.results)
+	m.results = append(m.results, Result{
+		Host:      host,
+		Port:      port,
+		Open:      true,
+		Timestamp: time.Unix(seen, 0).UTC().Format(time.RFC3339),
+	})
+	return &m.results[len(m.results)-1]
+}
+
+func (m *masscanMerger) open(host string, port int, seen int64) {
+	m.get(host, port, seen)
+}
+
+// banner records a grabbed banner. masscan reports several per port, such
+// as an HTTP server header and a title; the first of each port is kept.
+func (m *masscanMerger) banner(host string, port int, seen int64, service, banner string) {
+	r := m.get(host, port, seen)
+	if r.Service == "" {
+		r.Service = masscanService(service)
+	}
+	if r.Banner == "" {
+		r.Banner = strings.TrimSpace(banner)
+	}
+}
+
+// masscanService maps masscan's banner protocol names to service names
+func masscanService(name string) string {
+	switch name {
+	case "http.server", "title":
+		return "http"
+	case "X509", "ssl":
+		return "ssl"
+	}
+	return name
+}
+
+// ParseReport reads another scanner's report of open ports: nmap or
+// masscan XML, or masscan list or JSON output
+func ParseReport(r io.Reader) ([]Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return ParseNmap(bytes.NewReader(data))
+	}
+	return ParseMasscan(bytes.NewReader(data))
+}
@@ -0,0 +1,176 @@
+package portscan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/vulndb"
+)
+
+// nmapRun is the part of nmap's -oX output that describes open ports
+type nmapRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Start   int64      `xml:"start,attr"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	EndTime   int64 `xml:"endtime,attr"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []nmapPort `xml:"ports>port"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name      string `xml:"name,attr"`
+		Product   string `xml:"product,attr"`
+		Version   string `xml:"version,attr"`
+		ExtraInfo string `xml:"extrainfo,attr"`
+		Tunnel    string `xml:"tunnel,attr"`
+	} `xml:"service"`
+}
+
+// nmapProducts are the CPE products of the nmap service product names
+// that banner fingerprinting knows
+var nmapProducts = map[string]vulndb.Product{
+	"Apache httpd":        {Vendor: "apache", Name: "http_server"},
+	"nginx":               {Vendor: "f5", Name: "nginx"},
+	"Microsoft IIS httpd": {Vendor: "microsoft", Name: "internet_information_services"},
+	"lighttpd":            {Vendor: "lighttpd", Name: "lighttpd"},
+	"Jetty":               {Vendor: "eclipse", Name: "jetty"},
+	"Apache Tomcat":       {Vendor: "apache", Name: "tomcat"},
+	"OpenSSH":             {Vendor: "openbsd", Name: "openssh"},
+	"Dropbear sshd":       {Vendor: "dropbear_ssh_project", Name: "dropbear_ssh"},
+	"vsftpd":              {Vendor: "beasts", Name: "vsftpd"},
+	"ProFTPD":             {Vendor: "proftpd", Name: "proftpd"},
+	"Pure-FTPd":           {Vendor: "pureftpd", Name: "pure-ftpd"},
+	"Exim smtpd":          {Vendor: "exim", Name: "exim"},
+	"MariaDB":             {Vendor: "mariadb", Name: "mariadb"},
+}
+
+// ParseNmap reads the open TCP ports of an nmap XML report (-oX) into
+// results, as if this scanner had found them. Each port's host is the name
+// it was scanned by, when nmap was given one, so probes reach the right
+// virtual host; otherwise its address. Service versions from -sV become
+// the banner, and the products of nmapProducts among them are recorded for
+// CVE lookups.
+func ParseNmap(r io.Reader) ([]Result, error) {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, fmt.Errorf("parsing nmap XML: %w", err)
+	}
+
+	var results []Result
+	for _, host := range run.Hosts {
+		name := nmapHostName(host)
+		if name == "" {
+			continue
+		}
+		seen := host.EndTime
+		if seen == 0 {
+			seen = run.Start
+		}
+		timestamp := time.Unix(seen, 0).UTC().Format(time.RFC3339)
+
+		for _, port := range host.Ports {
+			if port.Protocol != "tcp" || port.State.State != "open" {
+				continue
+			}
+			service := port.Service.Name
+			if port.Service.Tunnel == "ssl" && service == "http" {
+				service = "https"
+			}
+			banner := strings.Join(strings.Fields(strings.Join([]string{
+				port.Service.Product, port.Service.Version, port.Service.ExtraInfo,
+			}, " ")), " ")
+
+			result := Result{
+				Host:      name,
+				Port:      port.PortID,
+				Open:      true,
+				Service:   service,
+				Banner:    banner,
+				Timestamp: timestamp,
+				RDNS:      nmapPTR(host),
+			}
+			if product, ok := nmapProducts[port.Service.Product]; ok && port.Service.Version != "" {
+				product.Version = strings.Fields(port.Service.Version)[0]
+				result.Products = []vulndb.Product{product}
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// nmapHostName returns the name a host was scanned by, or its IPv4 or IPv6
+// address
+func nmapHostName(host nmapHost) string {
+	for _, hostname := range host.Hostnames {
+		if hostname.Type == "user" && hostname.Name != "" {
+			return hostname.Name
+		}
+	}
+	for _, addr := range host.Addresses {
+		if addr.AddrType == "ipv4" || addr.AddrType == "ipv6" {
+			return addr.Addr
+		}
+	}
+	return ""
+}
+
+// nmapPTR returns the name nmap's reverse lookup found for a host, if any
+func nmapPTR(host nmapHost) string {
+	for _, hostname := range host.Hostnames {
+		if hostname.Type == "PTR" {
+			return hostname.Name
+		}
+	}
+	return ""
+}
+
+// WebTargets returns prober targets for the open ports whose service is
+// HTTP or HTTPS, such as http, https, http-proxy or https-alt. Standard
+// ports are left out of the URL.
+func WebTargets(results []Result) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if !r.Open || !strings.Contains(r.Service, "http") {
+			continue
+		}
+		scheme := "http"
+		if strings.HasPrefix(r.Service, "https") || strings.HasPrefix(r.Service, "ssl") {
+			scheme = "https"
+		}
+		host := r.Host
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := scheme + "://" + host
+		if !(scheme == "http" && r.Port == 80) && !(scheme == "https" && r.Port == 443) {
+			target += ":" + strconv.Itoa(r.Port)
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
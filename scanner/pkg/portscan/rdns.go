@@ -0,0 +1,76 @@
+package portscan
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils/dnscache"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// reverseDNS looks up the PTR name of each scanned host once, however many
+// of its ports are open. All methods are safe to call on a nil
+// *reverseDNS, which looks nothing up.
+type reverseDNS struct {
+	cache *dnscache.Cache
+
+	mu    sync.Mutex
+	names map[string]*ptrLookup
+}
+
+// ptrLookup is a host's PTR name, looked up by the first port to ask for
+// it while the others wait
+type ptrLookup struct {
+	once sync.Once
+	name string
+}
+
+// newReverseDNS returns a lookup that resolves hostname targets to an
+// address through cache, which may be nil
+func newReverseDNS(cache *dnscache.Cache) *reverseDNS {
+	return &reverseDNS{cache: cache, names: make(map[string]*ptrLookup)}
+}
+
+// lookup returns the PTR name of host's address, or "" if it has none
+func (r *reverseDNS) lookup(ctx context.Context, host string, timeout time.Duration) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	l, ok := r.names[host]
+	if !ok {
+		l = &ptrLookup{}
+		r.names[host] = l
+	}
+	r.mu.Unlock()
+
+	l.once.Do(func() {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		l.name = r.resolve(ctx, host)
+	})
+	return l.name
+}
+
+// resolve looks up the PTR name of host, or of the first address a
+// hostname resolves to
+func (r *reverseDNS) resolve(ctx context.Context, host string) string {
+	addr := host
+	if net.ParseIP(host) == nil {
+		addrs, err := r.cache.LookupHost(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return ""
+		}
+		addr = addrs[0]
+	}
+
+	names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+	if err != nil || len(names) == 0 {
+		log.FromContext(ctx).Debug("no reverse DNS", log.Target(host), "address", addr, log.Err(err))
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
@@ -0,0 +1,314 @@
+package portscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/recon-suite/scanner/pkg/tlsscan"
+)
+
+// RDP security protocols, as requested and selected in the connection
+// request's negotiation
+const (
+	rdpProtocolRDP     = 0x0 // standard RDP security, RC4 keyed by the server
+	rdpProtocolSSL     = 0x1 // TLS
+	rdpProtocolHybrid  = 0x2 // CredSSP: TLS with NLA
+	rdpProtocolHybridX = 0x8 // CredSSP with an early user authorization result
+)
+
+// rdpFailureHybridRequired is the negotiation failure code a server sends
+// when it only accepts CredSSP
+const rdpFailureHybridRequired = 5
+
+// RDPInfo is how an RDP server secures its connections, and what its
+// NTLM challenge says about it
+type RDPInfo struct {
+	// SecurityLayers are the layers the server accepted when offered
+	// alone: "rdp" (standard RDP security), "tls", "credssp" and
+	// "credssp-early-auth"
+	SecurityLayers []string `json:"security_layers"`
+
+	// NLARequired is set when the server refused every layer but CredSSP,
+	// so no login screen is shown before credentials are checked
+	NLARequired bool `json:"nla_required"`
+
+	Certificate *tlsscan.Certificate `json:"certificate,omitempty"`
+	NTLM        *NTLMInfo            `json:"ntlm,omitempty"`
+}
+
+// NTLMInfo is what a server names itself in an NTLM challenge
+type NTLMInfo struct {
+	NetBIOSComputer string `json:"netbios_computer,omitempty"`
+	NetBIOSDomain   string `json:"netbios_domain,omitempty"`
+	DNSComputer     string `json:"dns_computer,omitempty"`
+	DNSDomain       string `json:"dns_domain,omitempty"`
+	DNSTree         string `json:"dns_tree,omitempty"`
+	OSVersion       string `json:"os_version,omitempty"`
+}
+
+// FingerprintRDP negotiates with an RDP server once per security layer to
+// learn which it accepts and whether it requires NLA. Where CredSSP is
+// accepted, an NTLM negotiate message is sent over it and the server's
+// challenge read for its names and OS version; no credentials are sent.
+func FingerprintRDP(ctx context.Context, dial DialFunc, host string, port int, timeout time.Duration) (*RDPInfo, error) {
+	dial = dialOrDirect(dial, timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	// Offering everything shows what the server prefers, and is the
+	// connection the NTLM exchange runs over
+	conn, selected, failure, err := rdpNegotiate(ctx, dial, address, rdpProtocolSSL|rdpProtocolHybrid|rdpProtocolHybridX, timeout)
+	if err != nil {
+		return nil, err
+	}
+	info := &RDPInfo{}
+	if failure == 0 {
+		info.SecurityLayers = append(info.SecurityLayers, rdpLayer(selected))
+		if selected != rdpProtocolRDP {
+			info.NTLM, info.Certificate = rdpTLS(ctx, conn, host, selected, timeout)
+		}
+	}
+	conn.Close()
+
+	hybridRequired := false
+	for _, protocol := range []uint32{rdpProtocolRDP, rdpProtocolSSL} {
+		if protocol == selected && failure == 0 {
+			continue
+		}
+		conn, got, refused, err := rdpNegotiate(ctx, dial, address, protocol, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return info, ctx.Err()
+			}
+			continue
+		}
+		conn.Close()
+		if refused == rdpFailureHybridRequired {
+			hybridRequired = true
+		}
+		if refused == 0 && got == protocol {
+			info.SecurityLayers = append(info.SecurityLayers, rdpLayer(protocol))
+		}
+	}
+	info.NLARequired = hybridRequired && !contains(info.SecurityLayers, "rdp") && !contains(info.SecurityLayers, "tls")
+	return info, nil
+}
+
+// rdpNegotiate sends an X.224 connection request offering protocols and
+// returns the connection with the protocol the server selected, or the
+// failure code it refused with. A server too old to negotiate selects
+// standard RDP security.
+func rdpNegotiate(ctx context.Context, dial DialFunc, address string, protocols uint32, timeout time.Duration) (net.Conn, uint32, uint32, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// TPKT header, X.224 connection request, RDP_NEG_REQ
+	request := []byte{
+		0x03, 0x00, 0x00, 0x13,
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x08, 0x00, 0, 0, 0, 0,
+	}
+	binary.LittleEndian.PutUint32(request[15:], protocols)
+	if _, err := conn.Write(request); err != nil {
+		conn.Close()
+		return nil, 0, 0, err
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		conn.Close()
+		return nil, 0, 0, err
+	}
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	if header[0] != 0x03 || length < 11 || length > 1024 {
+		conn.Close()
+		return nil, 0, 0, fmt.Errorf("rdp: not a TPKT reply")
+	}
+	reply := make([]byte, length-4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, 0, 0, err
+	}
+	// X.224 connection confirm, then RDP_NEG_RSP (2) or RDP_NEG_FAILURE (3)
+	if reply[1] != 0xd0 {
+		conn.Close()
+		return nil, 0, 0, fmt.Errorf("rdp: not a connection confirm")
+	}
+	if len(reply) < 15 {
+		return conn, rdpProtocolRDP, 0, nil
+	}
+	value := binary.LittleEndian.Uint32(reply[11:15])
+	switch reply[7] {
+	case 0x02:
+		return conn, value, 0, nil
+	case 0x03:
+		return conn, 0, value, nil
+	}
+	return conn, rdpProtocolRDP, 0, nil
+}
+
+// rdpLayer names a security protocol
+func rdpLayer(protocol uint32) string {
+	switch protocol {
+	case rdpProtocolRDP:
+		return "rdp"
+	case rdpProtocolSSL:
+		return "tls"
+	case rdpProtocolHybrid:
+		return "credssp"
+	case rdpProtocolHybridX:
+		return "credssp-early-auth"
+	}
+	return fmt.Sprintf("0x%x", protocol)
+}
+
+// rdpTLS starts TLS on a negotiated connection for its certificate and,
+// under CredSSP, sends an NTLM negotiate message for the challenge
+func rdpTLS(ctx context.Context, conn net.Conn, host string, selected uint32, timeout time.Duration) (*NTLMInfo, *tlsscan.Certificate) {
+	config := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+	tlsConn := tls.Client(conn, config)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, nil
+	}
+	cert := tlsscan.InspectCertificate(host, tlsConn.ConnectionState().PeerCertificates)
+	if selected == rdpProtocolSSL {
+		return nil, cert
+	}
+
+	if _, err := tlsConn.Write(credSSPNegotiate()); err != nil {
+		return nil, cert
+	}
+	response := make([]byte, 0, 4096)
+	buffer := make([]byte, 4096)
+	for len(response) < 64*1024 {
+		n, err := tlsConn.Read(buffer)
+		response = append(response, buffer[:n]...)
+		if _, _, _, ok := berElement(response); ok || err != nil {
+			break
+		}
+	}
+	return parseNTLMChallenge(response), cert
+}
+
+// credSSPNegotiate is a CredSSP TSRequest carrying an NTLM negotiate
+// message that asks for the target info
+func credSSPNegotiate() []byte {
+	negotiate := []byte("NTLMSSP\x00")
+	negotiate = binary.LittleEndian.AppendUint32(negotiate, 1)
+	// Unicode, request target, NTLM, always sign, extended session
+	// security, target info, version, 128 and 56 bit
+	negotiate = binary.LittleEndian.AppendUint32(negotiate, 0xe20882b7)
+	negotiate = append(negotiate, make([]byte, 16)...) // domain, workstation
+	negotiate = append(negotiate, 0x0a, 0x00, 0x63, 0x45, 0x00, 0x00, 0x00, 0x0f)
+
+	// TSRequest ::= SEQUENCE { version [0] INTEGER, negoTokens [1]
+	// SEQUENCE OF SEQUENCE { negoToken [0] OCTET STRING } }
+	return der(0x30,
+		der(0xa0, der(0x02, []byte{0x02})),
+		der(0xa1, der(0x30, der(0x30, der(0xa0, der(0x04, negotiate))))),
+	)
+}
+
+// der encodes one DER element
+func der(tag byte, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+	out := []byte{tag}
+	switch n := len(body); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+// NTLM target info fields
+const (
+	ntlmAvEOL = iota
+	ntlmAvNbComputerName
+	ntlmAvNbDomainName
+	ntlmAvDNSComputerName
+	ntlmAvDNSDomainName
+	ntlmAvDNSTreeName
+)
+
+// parseNTLMChallenge finds an NTLM challenge message in data and reads
+// the names from its target info and the OS version, or returns nil
+func parseNTLMChallenge(data []byte) *NTLMInfo {
+	start := bytes.Index(data, []byte("NTLMSSP\x00\x02\x00\x00\x00"))
+	if start < 0 || len(data)-start < 48 {
+		return nil
+	}
+	message := data[start:]
+
+	info := &NTLMInfo{}
+	flags := binary.LittleEndian.Uint32(message[20:24])
+	if flags&0x02000000 != 0 && len(message) >= 56 {
+		build := binary.LittleEndian.Uint16(message[50:52])
+		info.OSVersion = fmt.Sprintf("%d.%d.%d", message[48], message[49], build)
+	}
+
+	length := int(binary.LittleEndian.Uint16(message[40:42]))
+	offset := int(binary.LittleEndian.Uint32(message[44:48]))
+	if offset < 0 || offset+length > len(message) {
+		return info
+	}
+	pairs := message[offset : offset+length]
+	for len(pairs) >= 4 {
+		id := binary.LittleEndian.Uint16(pairs[:2])
+		n := int(binary.LittleEndian.Uint16(pairs[2:4]))
+		if id == ntlmAvEOL || 4+n > len(pairs) {
+			break
+		}
+		value := utf16LE(pairs[4 : 4+n])
+		switch id {
+		case ntlmAvNbComputerName:
+			info.NetBIOSComputer = value
+		case ntlmAvNbDomainName:
+			info.NetBIOSDomain = value
+		case ntlmAvDNSComputerName:
+			info.DNSComputer = value
+		case ntlmAvDNSDomainName:
+			info.DNSDomain = value
+		case ntlmAvDNSTreeName:
+			info.DNSTree = value
+		}
+		pairs = pairs[4+n:]
+	}
+	return info
+}
+
+// utf16LE decodes a little-endian UTF-16 string
+func utf16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,675 @@
+package portscan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/tlsscan"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/dnscache"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"github.com/recon-suite/scanner/pkg/vulndb"
+	"golang.org/x/time/rate"
+)
+
+// Config holds port scanner configuration
+type Config struct {
+	// Targets are hostnames, addresses or CIDR prefixes
+	Targets       []string
+	Ports         []int
+	Workers       int
+	Timeout       int
+	RateLimit     int
+	ServiceDetect bool
+
+	// HostPorts, if set, lists the ports to scan on particular targets
+	// instead of Ports, such as the open ports another scanner found
+	HostPorts map[string][]int
+
+	// ConnectRate, if set, is the connections per second to hold to,
+	// whatever the worker count: it replaces RateLimit, and Workers is
+	// sized so the rate holds even while every connection waits out the
+	// timeout
+	ConnectRate int
+
+	// MaxRTT, if set, makes the connect rate adaptive: it starts at the
+	// rate, backs off toward a twentieth of it while answered connections
+	// average longer than MaxRTT or dials fail for want of resources, and
+	// climbs back as they speed up. Unanswered ports, which time out
+	// however fast the scan goes, are not counted against it.
+	MaxRTT time.Duration
+
+	// OnResult is called for each open port as it is found
+	OnResult func(Result)
+
+	// Feed, if set, supplies more targets as they arrive, after Targets.
+	// The scan then runs until Feed is closed or ctx is cancelled, with no
+	// time limit, and implies Discard, as it may never end.
+	Feed <-chan string
+
+	// OnHostDone is called with a host's open ports once every port on it
+	// has been scanned
+	OnHostDone func(host string, open []Result)
+
+	// Discard keeps open ports out of ScanContext's return value, for scans
+	// too large to hold in memory whose results OnResult or OnHostDone
+	// write out as they arrive
+	Discard bool
+
+	// Progress, if set, counts scanned ports and open ports found
+	Progress *utils.Progress
+
+	// Budget, if set, is a connection rate shared with other modules, applied
+	// on top of RateLimit
+	Budget *utils.Budget
+
+	// Scope, if set, drops out-of-scope targets before any port is dialed,
+	// and refuses connections to excluded addresses hostnames resolve to
+	Scope *scope.Scope
+
+	// CVEs, if set, is checked for the product versions service detection
+	// finds in banners
+	CVEs *vulndb.DB
+
+	// DNSCache, if set, resolves hostname targets once per TTL rather than
+	// once per port
+	DNSCache *dnscache.Cache
+
+	// FTPAnonymous tries an anonymous login on ports service detection
+	// identifies as FTP, and lists the root directory read-only
+	FTPAnonymous bool
+
+	// STARTTLS asks ports service detection identifies as SMTP, IMAP,
+	// POP3, FTP or LDAP whether they offer STARTTLS
+	STARTTLS bool
+
+	// STARTTLSUpgrade completes the upgrade where offered, recording the
+	// certificate and the banner the service presents over TLS
+	STARTTLSUpgrade bool
+
+	// SSH records the algorithms and host keys of ports service detection
+	// identifies as SSH, flagging weak ones and keys shared across hosts
+	SSH bool
+
+	// RawBannerSize keeps up to this many bytes of each banner as read, in
+	// Result.BannerRaw, for binary protocols that Banner's printable text
+	// mangles (0 = none)
+	RawBannerSize int
+
+	// RDP records the security layers, NLA requirement and NTLM names of
+	// ports service detection identifies as RDP
+	RDP bool
+
+	// ReverseDNS looks up the PTR name of each host with open ports, once
+	// per host, for Result.RDNS
+	ReverseDNS bool
+}
+
+// Result represents a port scan result
+type Result struct {
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Open      bool   `json:"open"`
+	Service   string `json:"service,omitempty"`
+	Banner    string `json:"banner,omitempty"`
+	Timestamp string `json:"timestamp"`
+
+	// RDNS is the PTR name of the host's address, if Config.ReverseDNS
+	// found one
+	RDNS string `json:"rdns,omitempty"`
+
+	// BannerRaw is the banner as read, up to Config.RawBannerSize bytes;
+	// JSON carries it base64-encoded
+	BannerRaw []byte `json:"banner_raw,omitempty"`
+
+	// Products are the versioned software named in the banner, and CVEs
+	// the known vulnerabilities listed for them
+	Products []vulndb.Product `json:"products,omitempty"`
+	CVEs     []vulndb.Match   `json:"cves,omitempty"`
+
+	// FTP is what an anonymous login allowed, if it was accepted
+	FTP *FTPAccess `json:"ftp,omitempty"`
+
+	// StartTLS is whether the service offers STARTTLS, if it was asked
+	StartTLS *STARTTLSInfo `json:"starttls,omitempty"`
+
+	// SSH is the server's algorithm offerings and host keys
+	SSH *SSHInfo `json:"ssh,omitempty"`
+
+	// RDP is how an RDP server secures connections and what it names itself
+	RDP *RDPInfo `json:"rdp,omitempty"`
+
+	// CertNames are the hostnames named by a certificate the port
+	// presented, in a STARTTLS upgrade or RDP's TLS
+	CertNames []string `json:"cert_names,omitempty"`
+
+	// Path is the route to the host, if it was traced; the host's ports
+	// share it
+	Path *Path `json:"path,omitempty"`
+}
+
+// Scanner handles port scanning operations
+type Scanner struct {
+	config   Config
+	limiter  *rate.Limiter
+	adaptive *utils.AdaptiveRateLimiter
+	rdns     *reverseDNS
+
+	// hostKeys are the hosts seen with each SSH host key fingerprint
+	mu       sync.Mutex
+	hostKeys map[string][]string
+}
+
+// DefaultRateLimit is the connections per second when Config.RateLimit is
+// unset
+const DefaultRateLimit = 1000
+
+// maxConnectWorkers caps the workers ConnectRate sizes, which each hold a
+// socket
+const maxConnectWorkers = 5000
+
+// NewScanner creates a new port scanner
+func NewScanner(config Config) *Scanner {
+	if config.Timeout == 0 {
+		config.Timeout = 3
+	}
+	if config.ConnectRate > 0 {
+		// A worker waits at most the timeout per port, so rate × timeout
+		// of them keep the rate up when nothing answers
+		config.RateLimit = config.ConnectRate
+		config.Workers = min(max(1, config.ConnectRate*config.Timeout), maxConnectWorkers)
+	}
+	if config.Workers == 0 {
+		config.Workers = 300
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = DefaultRateLimit
+	}
+
+	s := &Scanner{
+		config:   config,
+		limiter:  rate.NewLimiter(rate.Limit(config.RateLimit), config.RateLimit),
+		hostKeys: make(map[string][]string),
+	}
+	if config.MaxRTT > 0 {
+		maxRate := float64(config.RateLimit)
+		s.adaptive = utils.NewAdaptiveRateLimiter(maxRate, max(1, maxRate/20), maxRate, config.MaxRTT)
+	}
+	if config.ReverseDNS {
+		s.rdns = newReverseDNS(config.DNSCache)
+	}
+	return s
+}
+
+// ScanJob represents a single scan job
+type ScanJob struct {
+	Host string
+	Port int
+}
+
+// Scan performs the port scan
+func (s *Scanner) Scan() ([]Result, error) {
+	return s.ScanContext(context.Background())
+}
+
+// ScanContext scans until done or ctx is cancelled. On cancellation it
+// returns the open ports found so far with ctx's error.
+func (s *Scanner) ScanContext(parent context.Context) ([]Result, error) {
+	ctx, cancel := context.WithCancel(log.WithModule(parent, "portscan"))
+	defer cancel()
+	if s.config.Feed == nil {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, 30*time.Minute)
+		defer cancelTimeout()
+	}
+	logger := log.FromContext(ctx)
+
+	targets, err := ExpandTargets(s.config.Targets)
+	if err != nil {
+		return nil, err
+	}
+	targets = s.config.Scope.Filter(targets)
+
+	total := 0
+	for _, target := range targets {
+		total += len(s.portsOf(target))
+	}
+	s.config.Progress.AddTotal(total)
+
+	// Track ports left per host so finished hosts can be reported; a host's
+	// entries are dropped once it is. Fed hosts are added as they arrive,
+	// hence the lock.
+	var mu sync.Mutex
+	remaining := make(map[string]int)
+	hostOpen := make(map[string][]Result)
+	hostOpenCount := make(map[string]int)
+	for _, target := range targets {
+		remaining[target] += len(s.portsOf(target))
+	}
+	start := time.Now()
+
+	// The pool is bounded, so memory does not grow with the number of
+	// host/port pairs; the loop below drains results as workers fill it
+	pool := utils.NewPool(ctx, utils.PoolConfig{Workers: s.config.Workers}, s.scanJob)
+	submit := func(target string) bool {
+		for _, port := range s.portsOf(target) {
+			if pool.Submit(ctx, ScanJob{Host: target, Port: port}) != nil {
+				return false
+			}
+		}
+		return true
+	}
+	go func() {
+		defer pool.Close()
+		for _, target := range targets {
+			if !submit(target) {
+				return
+			}
+		}
+		for {
+			target, ok := utils.Next(ctx, s.config.Feed)
+			if !ok {
+				return
+			}
+			expanded, err := ExpandTargets([]string{target})
+			if err != nil {
+				logger.Warn("skipping target", log.Target(target), log.Err(err))
+				continue
+			}
+			for _, host := range s.config.Scope.Filter(expanded) {
+				ports := s.portsOf(host)
+				s.config.Progress.AddTotal(len(ports))
+				mu.Lock()
+				remaining[host] += len(ports)
+				mu.Unlock()
+				if !submit(host) {
+					return
+				}
+			}
+		}
+	}()
+
+	// Collect open ports only
+	var openPorts []Result
+	finished := 0
+	for r := range pool.Results() {
+		result := r.Value
+		s.config.Progress.Done()
+		if result.Open {
+			s.config.Progress.Found()
+			metrics.Findings("portscan").Inc()
+			if s.config.OnResult != nil {
+				s.config.OnResult(result)
+			}
+			if !s.config.Discard && s.config.Feed == nil {
+				openPorts = append(openPorts, result)
+			}
+			if s.config.OnHostDone != nil {
+				hostOpen[result.Host] = append(hostOpen[result.Host], result)
+			}
+			hostOpenCount[result.Host]++
+		}
+
+		mu.Lock()
+		remaining[result.Host]--
+		done := remaining[result.Host] == 0
+		if done {
+			delete(remaining, result.Host)
+		}
+		mu.Unlock()
+		if done {
+			finished++
+			if hostOpenCount[result.Host] == 0 {
+				logger.Info("no open ports", log.Target(result.Host), "ports", len(s.portsOf(result.Host)))
+			} else {
+				logger.Debug("host finished", log.Target(result.Host), "open", hostOpenCount[result.Host])
+			}
+			if s.config.OnHostDone != nil {
+				s.config.OnHostDone(result.Host, hostOpen[result.Host])
+			}
+			delete(hostOpen, result.Host)
+			delete(hostOpenCount, result.Host)
+		}
+	}
+	logger.Debug("scan finished", "hosts", finished, log.Since(start))
+
+	return openPorts, parent.Err()
+}
+
+// portsOf returns the ports to scan on a target
+func (s *Scanner) portsOf(target string) []int {
+	if ports, ok := s.config.HostPorts[target]; ok {
+		return ports
+	}
+	return s.config.Ports
+}
+
+// scanJob scans one port, detecting its service if enabled
+func (s *Scanner) scanJob(ctx context.Context, job ScanJob) (Result, error) {
+	timeout := time.Duration(s.config.Timeout) * time.Second
+
+	// Rate limiting
+	s.wait(ctx)
+	s.config.Budget.Wait(ctx)
+
+	result, conn := s.scanPort(ctx, job.Host, job.Port, timeout, s.config.ServiceDetect)
+	check := s.checkDialer(timeout)
+	if result.Open {
+		result.RDNS = s.rdns.lookup(ctx, job.Host, timeout)
+	}
+
+	// Service detection if enabled, on the connection the scan opened
+	if conn != nil {
+		banner := readBanner(conn, s.config.RawBannerSize)
+		metrics.BytesRead("portscan").Add(int64(len(banner)))
+		conn.Close()
+		result.Service = s.detectService(job.Port, banner)
+		result.Banner = cleanBanner(banner)
+		result.BannerRaw = rawBanner(banner, s.config.RawBannerSize)
+		result.Products = vulndb.Fingerprint(banner)
+		result.CVEs = s.config.CVEs.LookupAll(result.Products)
+		if result.Service == "ftp" && s.config.FTPAnonymous {
+			result.FTP, _ = CheckAnonymousFTP(ctx, check, job.Host, job.Port, timeout)
+		}
+		if protocol := STARTTLSProtocol(result.Service, job.Port); protocol != "" && s.config.STARTTLS {
+			var err error
+			result.StartTLS, err = CheckSTARTTLS(ctx, check, job.Host, job.Port, protocol, s.config.STARTTLSUpgrade, timeout)
+			if err != nil {
+				log.FromContext(ctx).Debug("starttls check failed", log.Target(job.Host), "port", job.Port, log.Err(err))
+			}
+		}
+		if result.Service == "ssh" && s.config.SSH {
+			var err error
+			if result.SSH, err = FingerprintSSH(ctx, check, job.Host, job.Port, timeout); err != nil {
+				log.FromContext(ctx).Debug("ssh fingerprinting failed", log.Target(job.Host), "port", job.Port, log.Err(err))
+			}
+			s.sharedHostKeys(job.Host, result.SSH)
+		}
+		if result.Service == "rdp" && s.config.RDP {
+			var err error
+			if result.RDP, err = FingerprintRDP(ctx, check, job.Host, job.Port, timeout); err != nil {
+				log.FromContext(ctx).Debug("rdp fingerprinting failed", log.Target(job.Host), "port", job.Port, log.Err(err))
+			}
+		}
+		result.CertNames = certNames(result)
+	}
+	return result, nil
+}
+
+// dialer returns the scan's dial function: through the DNS cache, and
+// refusing excluded addresses
+func (s *Scanner) dialer(timeout time.Duration) DialFunc {
+	return s.config.DNSCache.DialContext(&net.Dialer{Timeout: timeout, Control: s.config.Scope.Control})
+}
+
+// checkDialer returns the dial function for the checks run on an open
+// port, paced like the scan's own connections
+func (s *Scanner) checkDialer(timeout time.Duration) DialFunc {
+	dial := s.dialer(timeout)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		s.wait(ctx)
+		s.config.Budget.Wait(ctx)
+		return dial(ctx, network, address)
+	}
+}
+
+// wait paces connections at the adaptive rate if there is one, or
+// RateLimit
+func (s *Scanner) wait(ctx context.Context) {
+	if s.adaptive != nil {
+		s.adaptive.Wait(ctx)
+		return
+	}
+	s.limiter.Wait(ctx)
+}
+
+// record feeds a dial's outcome to the adaptive rate. Open and refused
+// ports are answers, timing the round trip; a timeout says nothing, as
+// filtered ports time out at any rate; anything else (no buffer space,
+// too many open files, unreachable) is the scan overrunning something.
+func (s *Scanner) record(err error, elapsed time.Duration) {
+	switch {
+	case s.adaptive == nil:
+	case err == nil || errors.Is(err, syscall.ECONNREFUSED):
+		s.adaptive.RecordLatency(elapsed)
+	case metrics.IsTimeout(err) || errors.Is(err, context.Canceled) || errors.Is(err, scope.ErrExcluded):
+	default:
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			s.adaptive.RecordError()
+		}
+	}
+}
+
+// certNames returns the hostnames of the certificates a port presented
+func certNames(result Result) []string {
+	var cert *tlsscan.Certificate
+	switch {
+	case result.StartTLS != nil && result.StartTLS.Certificate != nil:
+		cert = result.StartTLS.Certificate
+	case result.RDP != nil && result.RDP.Certificate != nil:
+		cert = result.RDP.Certificate
+	}
+	return cert.Hostnames()
+}
+
+// sharedHostKeys records a host's SSH host keys, filling in the other
+// hosts seen with each so far
+func (s *Scanner) sharedHostKeys(host string, info *SSHInfo) {
+	if info == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range info.HostKeys {
+		key := &info.HostKeys[i]
+		seen := false
+		for _, other := range s.hostKeys[key.Fingerprint] {
+			if other == host {
+				seen = true
+			} else {
+				key.SharedWith = append(key.SharedWith, other)
+			}
+		}
+		if !seen {
+			s.hostKeys[key.Fingerprint] = append(s.hostKeys[key.Fingerprint], host)
+		}
+	}
+}
+
+// scanPort checks if a port is open. If keep is set, an open port's
+// connection is returned for the caller to read a banner from and close;
+// otherwise it is closed and the connection is nil.
+func (s *Scanner) scanPort(ctx context.Context, host string, port int, timeout time.Duration, keep bool) (Result, net.Conn) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	dial := s.dialer(timeout)
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", address)
+	// A refused connection is a closed port, not a failed request
+	metrics.Requests("portscan").Inc()
+	metrics.Duration("portscan").Since(start)
+	if err != nil && metrics.IsTimeout(err) {
+		metrics.Timeouts("portscan").Inc()
+	}
+	s.record(err, time.Since(start))
+	if err != nil {
+		// Refused and timed out connections are closed and filtered ports;
+		// a name that does not resolve fails every port the same way
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			log.FromContext(ctx).Debug("resolving host", log.Target(address), log.Err(err))
+		}
+		return Result{
+			Host:      host,
+			Port:      port,
+			Open:      false,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}, nil
+	}
+	if !keep {
+		conn.Close()
+		conn = nil
+	}
+
+	return Result{
+		Host:      host,
+		Port:      port,
+		Open:      true,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}, conn
+}
+
+// detectService identifies the service from its port or banner
+func (s *Scanner) detectService(port int, banner string) string {
+	// Well-known ports
+	wellKnown := map[int]string{
+		21:    "ftp",
+		22:    "ssh",
+		23:    "telnet",
+		25:    "smtp",
+		53:    "dns",
+		80:    "http",
+		110:   "pop3",
+		143:   "imap",
+		443:   "https",
+		445:   "smb",
+		993:   "imaps",
+		995:   "pop3s",
+		1433:  "mssql",
+		1521:  "oracle",
+		3306:  "mysql",
+		3389:  "rdp",
+		5432:  "postgresql",
+		5900:  "vnc",
+		6379:  "redis",
+		8080:  "http-proxy",
+		8443:  "https-alt",
+		27017: "mongodb",
+	}
+
+	if service, ok := wellKnown[port]; ok {
+		return service
+	}
+
+	if banner != "" {
+		return s.identifyFromBanner(banner)
+	}
+
+	return "unknown"
+}
+
+// readBanner attempts to read a service banner from a fresh connection,
+// up to 1KB or size if larger
+func readBanner(conn net.Conn, size int) string {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	buffer := make([]byte, max(1024, size))
+	n, err := conn.Read(buffer)
+
+	// FTP and SMTP greetings may span lines ("220-..." up to "220 ...")
+	// sent in separate writes; the product is often on the last one
+	for err == nil && n < len(buffer) && continuedReply(string(buffer[:n])) {
+		var more int
+		more, err = conn.Read(buffer[n:])
+		n += more
+	}
+	if n == 0 {
+		// Try sending a simple probe, which HTTP servers answer with an
+		// error page carrying their Server header
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		conn.Write([]byte("\r\n"))
+		n, _ = conn.Read(buffer)
+	}
+
+	if n > 0 {
+		return string(buffer[:n])
+	}
+
+	return ""
+}
+
+// continuedReply reports whether a banner ends inside a multi-line
+// "NNN-" reply
+func continuedReply(banner string) bool {
+	if len(banner) < 4 || banner[3] != '-' {
+		return false
+	}
+	if !strings.HasSuffix(banner, "\n") {
+		return true
+	}
+	lines := strings.Split(strings.TrimRight(banner, "\r\n"), "\n")
+	last := lines[len(lines)-1]
+	return !(len(last) >= 4 && last[:3] == banner[:3] && last[3] == ' ')
+}
+
+// identifyFromBanner identifies service from banner
+func (s *Scanner) identifyFromBanner(banner string) string {
+	// POP3 and IMAP greetings rarely name the protocol
+	switch {
+	case strings.HasPrefix(banner, "+OK"):
+		return "pop3"
+	case strings.HasPrefix(banner, "* OK"), strings.HasPrefix(banner, "* PREAUTH"):
+		return "imap"
+	}
+
+	patterns := map[string]string{
+		"SSH":        "ssh",
+		"HTTP":       "http",
+		"SMTP":       "smtp",
+		"FTP":        "ftp",
+		"MySQL":      "mysql",
+		"PostgreSQL": "postgresql",
+		"MongoDB":    "mongodb",
+		"Redis":      "redis",
+		"nginx":      "nginx",
+		"Apache":     "apache",
+	}
+
+	for pattern, service := range patterns {
+		if containsIgnoreCase(banner, pattern) {
+			return service
+		}
+	}
+
+	return "unknown"
+}
+
+// containsIgnoreCase checks if s contains substr (case-insensitive)
+func containsIgnoreCase(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if equalFoldBytes(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalFoldBytes compares two strings case-insensitively
+func equalFoldBytes(s, t string) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		sr := s[i]
+		tr := t[i]
+		if sr >= 'A' && sr <= 'Z' {
+			sr = sr + 32
+		}
+		if tr >= 'A' && tr <= 'Z' {
+			tr = tr + 32
+		}
+		if sr != tr {
+			return false
+		}
+	}
+	return true
+}
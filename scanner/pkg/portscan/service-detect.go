@@ -1,14 +1,41 @@
 package portscan
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 )
 
 // ServiceDetector handles service fingerprinting
 type ServiceDetector struct {
 	timeout time.Duration
+
+	// RawBannerSize keeps up to this many bytes of the banner as read, in
+	// ServiceInfo.BannerRaw (0 = none)
+	RawBannerSize int
+
+	// UpgradeSTARTTLS completes the upgrade of services offering
+	// STARTTLS, recording what they present over TLS
+	UpgradeSTARTTLS bool
+
+	// Dial, if set, opens every connection the detector makes, in place
+	// of a plain dial within its timeout
+	Dial DialFunc
+}
+
+// DialFunc opens a connection, like net.Dialer.DialContext. The checks
+// take one so a scan's pacing, DNS cache and scope apply to their
+// connections too; nil dials directly.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dialOrDirect returns dial, or a plain dial within timeout if it is nil
+func dialOrDirect(dial DialFunc, timeout time.Duration) DialFunc {
+	if dial != nil {
+		return dial
+	}
+	return (&net.Dialer{Timeout: timeout}).DialContext
 }
 
 // NewServiceDetector creates a new service detector
@@ -25,10 +52,28 @@ type ServiceInfo struct {
 	Version string `json:"version,omitempty"`
 	Banner  string `json:"banner,omitempty"`
 	Product string `json:"product,omitempty"`
+
+	// BannerRaw is the banner as read, base64-encoded in JSON
+	BannerRaw []byte `json:"banner_raw,omitempty"`
+
+	// StartTLS is whether a mail, FTP or LDAP service offers STARTTLS
+	StartTLS *STARTTLSInfo `json:"starttls,omitempty"`
+
+	// SSH is an SSH server's algorithm offerings and host keys
+	SSH *SSHInfo `json:"ssh,omitempty"`
+
+	// RDP is an RDP server's security layers and NTLM names
+	RDP *RDPInfo `json:"rdp,omitempty"`
 }
 
 // Detect identifies the service running on a port
 func (sd *ServiceDetector) Detect(host string, port int) ServiceInfo {
+	return sd.DetectContext(context.Background(), host, port)
+}
+
+// DetectContext identifies the service running on a port, giving up on
+// the connection attempts when ctx is cancelled
+func (sd *ServiceDetector) DetectContext(ctx context.Context, host string, port int) ServiceInfo {
 	info := ServiceInfo{Name: "unknown"}
 
 	// First, try well-known ports
@@ -37,32 +82,67 @@ func (sd *ServiceDetector) Detect(host string, port int) ServiceInfo {
 	}
 
 	// Try to grab banner
-	banner := sd.grabBanner(host, port)
-	if banner != "" {
+	raw := sd.grabBanner(ctx, host, port)
+	info.BannerRaw = rawBanner(raw, sd.RawBannerSize)
+	if banner := cleanBanner(raw); banner != "" {
 		info.Banner = banner
-		parsed := sd.parseBanner(banner)
-		if parsed.Name != "" {
-			info.Name = parsed.Name
-		}
-		if parsed.Version != "" {
-			info.Version = parsed.Version
-		}
-		if parsed.Product != "" {
-			info.Product = parsed.Product
-		}
+		info.merge(sd.parseBanner(banner))
+	}
+
+	// Databases: MySQL's greeting is binary, and the others say nothing
+	// until asked
+	if greeting, ok := parseMySQLGreeting([]byte(raw)); ok {
+		info.merge(greeting)
+	}
+	var database ServiceInfo
+	var ok bool
+	switch info.Name {
+	case "postgresql":
+		database, ok = sd.probePostgres(ctx, host, port)
+	case "redis":
+		database, ok = sd.probeRedis(ctx, host, port)
+	case "mongodb":
+		database, ok = sd.probeMongo(ctx, host, port)
+	}
+	if ok {
+		info.merge(database)
 	}
 
 	// Try HTTP probe if port looks like HTTP
 	if sd.looksLikeHTTP(port) && info.Name == "unknown" {
-		httpInfo := sd.probeHTTP(host, port)
+		httpInfo := sd.probeHTTP(ctx, host, port)
 		if httpInfo.Name != "" {
 			return httpInfo
 		}
 	}
 
+	// Services that upgrade in-band are asked whether they do
+	if protocol := STARTTLSProtocol(info.Name, port); protocol != "" {
+		info.StartTLS, _ = CheckSTARTTLS(ctx, sd.Dial, host, port, protocol, sd.UpgradeSTARTTLS, sd.timeout)
+	}
+	if info.Name == "ssh" {
+		info.SSH, _ = FingerprintSSH(ctx, sd.Dial, host, port, sd.timeout)
+	}
+	if info.Name == "ms-wbt-server" {
+		info.RDP, _ = FingerprintRDP(ctx, sd.Dial, host, port, sd.timeout)
+	}
+
 	return info
 }
 
+// merge takes the fields another detection filled in
+func (info *ServiceInfo) merge(other ServiceInfo) {
+	if other.Name != "" {
+		info.Name = other.Name
+	}
+	if other.Version != "" {
+		info.Version = other.Version
+	}
+	if other.Product != "" {
+		info.Product = other.Product
+	}
+}
+
 // wellKnownPort returns service name for well-known ports
 func (sd *ServiceDetector) wellKnownPort(port int) string {
 	ports := map[int]string{
@@ -132,11 +212,10 @@ func (sd *ServiceDetector) wellKnownPort(port int) string {
 	return ports[port]
 }
 
-// grabBanner attempts to get service banner
-func (sd *ServiceDetector) grabBanner(host string, port int) string {
-	address := fmt.Sprintf("%s:%d", host, port)
-
-	conn, err := net.DialTimeout("tcp", address, sd.timeout)
+// grabBanner attempts to get service banner, as read: cleanBanner makes
+// it printable
+func (sd *ServiceDetector) grabBanner(ctx context.Context, host string, port int) string {
+	conn, err := sd.dial(ctx, host, port)
 	if err != nil {
 		return ""
 	}
@@ -146,7 +225,7 @@ func (sd *ServiceDetector) grabBanner(host string, port int) string {
 	conn.SetDeadline(time.Now().Add(sd.timeout))
 
 	// Read initial banner
-	buffer := make([]byte, 4096)
+	buffer := make([]byte, max(4096, sd.RawBannerSize))
 	n, err := conn.Read(buffer)
 
 	// If no immediate response, try sending probe
@@ -168,13 +247,28 @@ func (sd *ServiceDetector) grabBanner(host string, port int) string {
 	}
 
 	if n > 0 {
-		// Clean up non-printable characters
-		return cleanBanner(string(buffer[:n]))
+		return string(buffer[:n])
 	}
 
 	return ""
 }
 
+// dial connects to a port within the detector's timeout
+func (sd *ServiceDetector) dial(ctx context.Context, host string, port int) (net.Conn, error) {
+	return dialOrDirect(sd.Dial, sd.timeout)(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// rawBanner returns up to size bytes of a banner as read, or nil
+func rawBanner(banner string, size int) []byte {
+	if size <= 0 || banner == "" {
+		return nil
+	}
+	if len(banner) > size {
+		banner = banner[:size]
+	}
+	return []byte(banner)
+}
+
 // cleanBanner removes non-printable characters
 func cleanBanner(s string) string {
 	result := make([]byte, 0, len(s))
@@ -268,11 +362,10 @@ func (sd *ServiceDetector) looksLikeHTTP(port int) bool {
 }
 
 // probeHTTP sends HTTP request to detect web server
-func (sd *ServiceDetector) probeHTTP(host string, port int) ServiceInfo {
+func (sd *ServiceDetector) probeHTTP(ctx context.Context, host string, port int) ServiceInfo {
 	info := ServiceInfo{}
 
-	address := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", address, sd.timeout)
+	conn, err := sd.dial(ctx, host, port)
 	if err != nil {
 		return info
 	}
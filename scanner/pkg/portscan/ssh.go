@@ -0,0 +1,310 @@
+package portscan
+
+import (
+	"bufio"
+	"context"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHInfo is what an SSH server offers before authentication: the
+// algorithms of its key exchange init and its host keys
+type SSHInfo struct {
+	Version           string   `json:"version"`
+	KEX               []string `json:"kex"`
+	HostKeyAlgorithms []string `json:"host_key_algorithms"`
+	Ciphers           []string `json:"ciphers"`
+	MACs              []string `json:"macs"`
+	Compression       []string `json:"compression,omitempty"`
+
+	HostKeys []SSHHostKey `json:"host_keys,omitempty"`
+
+	// Weak lists the offered algorithms and host keys too weak to use,
+	// as "kex diffie-hellman-group1-sha1" or "hostkey ssh-rsa 1024 bits"
+	Weak []string `json:"weak,omitempty"`
+}
+
+// SSHHostKey is one of a server's host keys
+type SSHHostKey struct {
+	Type        string `json:"type"`
+	Bits        int    `json:"bits,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+
+	// SharedWith are the other hosts seen earlier in the scan presenting
+	// the same key: a cloned image or an appliance's default key
+	SharedWith []string `json:"shared_with,omitempty"`
+}
+
+// sshClientVersion is the identification the fingerprinting sends
+const sshClientVersion = "SSH-2.0-scanner"
+
+// errHostKeySeen stops a handshake once the host key is known
+var errHostKeySeen = errors.New("ssh: host key recorded")
+
+// Algorithms offered on the fingerprinting handshakes, every one the SSH
+// package can speak, so old servers still show their keys
+var (
+	sshKEX = []string{
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256", "diffie-hellman-group16-sha512",
+		"diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1",
+		"diffie-hellman-group-exchange-sha256", "diffie-hellman-group-exchange-sha1",
+	}
+	sshCiphers = []string{
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+		"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+		"chacha20-poly1305@openssh.com",
+		"arcfour256", "arcfour128", "arcfour",
+		"aes128-cbc", "3des-cbc",
+	}
+	sshMACs = []string{
+		"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com",
+		"hmac-sha2-256", "hmac-sha2-512", "hmac-sha1", "hmac-sha1-96",
+	}
+)
+
+// sshHostKeyTypes are the host key algorithms asked for in turn, one per
+// key: the RSA signature variants all present the same RSA key
+var sshHostKeyTypes = [][]string{
+	{ssh.KeyAlgoED25519},
+	{ssh.KeyAlgoECDSA256},
+	{ssh.KeyAlgoECDSA384},
+	{ssh.KeyAlgoECDSA521},
+	{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA},
+	{ssh.KeyAlgoDSA},
+}
+
+// weakSSHAlgorithms are algorithms broken or too short to trust, by the
+// name-list they appear in
+var weakSSHAlgorithms = map[string][]string{
+	"kex": {
+		"diffie-hellman-group1-sha1", "diffie-hellman-group14-sha1",
+		"diffie-hellman-group-exchange-sha1", "rsa1024-sha1",
+	},
+	"hostkey": {"ssh-dss", "ssh-rsa"},
+	"cipher": {
+		"none", "arcfour", "arcfour128", "arcfour256", "3des-cbc",
+		"blowfish-cbc", "cast128-cbc", "aes128-cbc", "aes192-cbc",
+		"aes256-cbc", "rijndael-cbc@lysator.liu.se",
+	},
+	"mac": {
+		"none", "hmac-md5", "hmac-md5-96", "hmac-md5-etm@openssh.com",
+		"hmac-md5-96-etm@openssh.com", "hmac-sha1-96",
+		"hmac-sha1-96-etm@openssh.com", "umac-64@openssh.com",
+		"umac-64-etm@openssh.com", "hmac-ripemd160",
+	},
+}
+
+// FingerprintSSH reads an SSH server's algorithm offerings from its key
+// exchange init, then completes one key exchange per host key type it
+// offers to record each key's fingerprint. Nothing is authenticated: each
+// handshake is dropped once the server has signed with its key.
+func FingerprintSSH(ctx context.Context, dial DialFunc, host string, port int, timeout time.Duration) (*SSHInfo, error) {
+	dial = dialOrDirect(dial, timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	info, err := sshKexInit(ctx, dial, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, algorithms := range sshHostKeyTypes {
+		if !offersAny(info.HostKeyAlgorithms, algorithms) {
+			continue
+		}
+		key, err := sshHostKey(ctx, dial, address, algorithms, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return info, ctx.Err()
+			}
+			continue
+		}
+		hostKey := SSHHostKey{Type: key.Type(), Fingerprint: ssh.FingerprintSHA256(key)}
+		if crypto, ok := key.(ssh.CryptoPublicKey); ok {
+			hostKey.Bits = publicKeyBits(crypto.CryptoPublicKey())
+		}
+		info.HostKeys = append(info.HostKeys, hostKey)
+	}
+	info.Weak = weakSSH(info)
+	return info, nil
+}
+
+// sshKexInit exchanges identifications and reads the server's key
+// exchange init, which is sent in the clear
+func sshKexInit(ctx context.Context, dial DialFunc, address string, timeout time.Duration) (*SSHInfo, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := io.WriteString(conn, sshClientVersion+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	// Servers may send other lines before their identification
+	reader := bufio.NewReader(conn)
+	info := &SSHInfo{}
+	for i := 0; ; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line = strings.TrimRight(line, "\r\n"); strings.HasPrefix(line, "SSH-") {
+			info.Version = line
+			break
+		}
+		if i >= 32 {
+			return nil, fmt.Errorf("ssh: no identification from %s", address)
+		}
+	}
+
+	// uint32 packet length, byte padding length, payload, padding
+	var header [5]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	padding := uint32(header[4])
+	if length > 35000 || length < padding+1 {
+		return nil, fmt.Errorf("ssh: bad packet length %d", length)
+	}
+	packet := make([]byte, length-1)
+	if _, err := io.ReadFull(reader, packet); err != nil {
+		return nil, err
+	}
+	payload := packet[:len(packet)-int(padding)]
+
+	// byte SSH_MSG_KEXINIT (20), 16 byte cookie, then ten name-lists
+	switch {
+	case len(payload) == 0:
+		return nil, fmt.Errorf("ssh: expected KEXINIT, got an empty packet")
+	case payload[0] != 20:
+		return nil, fmt.Errorf("ssh: expected KEXINIT, got message %d", payload[0])
+	case len(payload) < 17:
+		return nil, fmt.Errorf("ssh: truncated KEXINIT")
+	}
+	payload = payload[17:]
+	var lists [10][]string
+	for i := range lists {
+		if len(payload) < 4 {
+			return nil, fmt.Errorf("ssh: truncated KEXINIT")
+		}
+		n := binary.BigEndian.Uint32(payload[:4])
+		if uint32(len(payload)-4) < n {
+			return nil, fmt.Errorf("ssh: truncated KEXINIT")
+		}
+		if n > 0 {
+			lists[i] = strings.Split(string(payload[4:4+n]), ",")
+		}
+		payload = payload[4+n:]
+	}
+
+	// The client-to-server and server-to-client lists are nearly always
+	// the same; the former stands for both
+	info.KEX = lists[0]
+	info.HostKeyAlgorithms = lists[1]
+	info.Ciphers = lists[2]
+	info.MACs = lists[4]
+	info.Compression = lists[6]
+	return info, nil
+}
+
+// sshHostKey completes a key exchange offering only the given host key
+// algorithms and returns the key the server signed it with
+func sshHostKey(ctx context.Context, dial DialFunc, address string, algorithms []string, timeout time.Duration) (ssh.PublicKey, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		Config: ssh.Config{
+			KeyExchanges: sshKEX,
+			Ciphers:      sshCiphers,
+			MACs:         sshMACs,
+		},
+		User:              "scanner",
+		ClientVersion:     sshClientVersion,
+		HostKeyAlgorithms: algorithms,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeySeen
+		},
+		Timeout: timeout,
+	}
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+	if key != nil {
+		return key, nil
+	}
+	return nil, err
+}
+
+// weakSSH lists the weak algorithms a server offers and its short keys
+func weakSSH(info *SSHInfo) []string {
+	var weak []string
+	for _, list := range []struct {
+		kind    string
+		offered []string
+	}{
+		{"kex", info.KEX},
+		{"hostkey", info.HostKeyAlgorithms},
+		{"cipher", info.Ciphers},
+		{"mac", info.MACs},
+	} {
+		for _, name := range list.offered {
+			if offersAny(weakSSHAlgorithms[list.kind], []string{name}) {
+				weak = append(weak, list.kind+" "+name)
+			}
+		}
+	}
+	for _, key := range info.HostKeys {
+		if key.Bits > 0 && (key.Type == ssh.KeyAlgoRSA || key.Type == ssh.KeyAlgoDSA) && key.Bits < 2048 {
+			weak = append(weak, fmt.Sprintf("hostkey %s %d bits", key.Type, key.Bits))
+		}
+	}
+	return weak
+}
+
+// offersAny reports whether offered includes any of names
+func offersAny(offered, names []string) bool {
+	for _, o := range offered {
+		for _, name := range names {
+			if o == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKeyBits returns the size of a host key
+func publicKeyBits(key interface{}) int {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	case *dsa.PublicKey:
+		return k.P.BitLen()
+	}
+	return 0
+}
@@ -0,0 +1,412 @@
+package portscan
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/tlsscan"
+)
+
+// STARTTLS dialects
+const (
+	ProtocolSMTP = "smtp"
+	ProtocolIMAP = "imap"
+	ProtocolPOP3 = "pop3"
+	ProtocolFTP  = "ftp"
+	ProtocolLDAP = "ldap"
+)
+
+// STARTTLSInfo is whether a plaintext service offers an in-band upgrade
+// to TLS and, if the upgrade was completed, what it presented over TLS
+type STARTTLSInfo struct {
+	Protocol  string `json:"protocol"`
+	Supported bool   `json:"supported"`
+	Upgraded  bool   `json:"upgraded,omitempty"`
+
+	TLSVersion  string `json:"tls_version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+
+	// Banner is the service's first answer over TLS: its EHLO,
+	// CAPABILITY, CAPA or FEAT reply, which often lists more than the
+	// plaintext one (AUTH mechanisms held back until the link is secure)
+	Banner      string               `json:"banner,omitempty"`
+	Certificate *tlsscan.Certificate `json:"certificate,omitempty"`
+
+	// Error is why an offered upgrade could not be completed
+	Error string `json:"error,omitempty"`
+}
+
+// STARTTLSProtocol returns the STARTTLS dialect a service speaks, from its
+// detected name or else its port, or "" if it has none
+func STARTTLSProtocol(service string, port int) string {
+	switch service {
+	case "smtp", "submission":
+		return ProtocolSMTP
+	case "imap":
+		return ProtocolIMAP
+	case "pop3":
+		return ProtocolPOP3
+	case "ftp":
+		return ProtocolFTP
+	case "ldap":
+		return ProtocolLDAP
+	case "", "unknown":
+	default:
+		return ""
+	}
+	switch port {
+	case 25, 587, 2525:
+		return ProtocolSMTP
+	case 143:
+		return ProtocolIMAP
+	case 110:
+		return ProtocolPOP3
+	case 21:
+		return ProtocolFTP
+	case 389:
+		return ProtocolLDAP
+	}
+	return ""
+}
+
+// starttlsDialect is how one protocol greets, lists its capabilities and
+// starts TLS
+type starttlsDialect struct {
+	greet        func(*ftpConn) error
+	capabilities func(*ftpConn) ([]string, error)
+	start        func(*ftpConn) error
+
+	// keyword is the capability that advertises STARTTLS
+	keyword string
+}
+
+var starttlsDialects = map[string]starttlsDialect{
+	ProtocolSMTP: {
+		greet:        func(c *ftpConn) error { return expectCode(c, "", 220) },
+		capabilities: func(c *ftpConn) ([]string, error) { return numberedReply(c, "EHLO localhost", 250) },
+		start:        func(c *ftpConn) error { return expectCode(c, "STARTTLS", 220) },
+		keyword:      "STARTTLS",
+	},
+	ProtocolFTP: {
+		greet:        func(c *ftpConn) error { return expectCode(c, "", 220) },
+		capabilities: func(c *ftpConn) ([]string, error) { return numberedReply(c, "FEAT", 211) },
+		start:        func(c *ftpConn) error { return expectCode(c, "AUTH TLS", 234) },
+		keyword:      "AUTH TLS",
+	},
+	ProtocolIMAP: {
+		greet: func(c *ftpConn) error {
+			line, err := c.line()
+			if err == nil && !strings.HasPrefix(line, "* OK") && !strings.HasPrefix(line, "* PREAUTH") {
+				err = fmt.Errorf("imap: unexpected greeting %q", line)
+			}
+			return err
+		},
+		capabilities: func(c *ftpConn) ([]string, error) { return c.tagged("A1", "CAPABILITY") },
+		start: func(c *ftpConn) error {
+			_, err := c.tagged("A2", "STARTTLS")
+			return err
+		},
+		keyword: "STARTTLS",
+	},
+	ProtocolPOP3: {
+		greet: func(c *ftpConn) error {
+			line, err := c.line()
+			if err == nil && !strings.HasPrefix(line, "+OK") {
+				err = fmt.Errorf("pop3: unexpected greeting %q", line)
+			}
+			return err
+		},
+		capabilities: func(c *ftpConn) ([]string, error) { return c.dotted("CAPA") },
+		start: func(c *ftpConn) error {
+			if err := c.send("STLS"); err != nil {
+				return err
+			}
+			line, err := c.line()
+			if err == nil && !strings.HasPrefix(line, "+OK") {
+				err = fmt.Errorf("pop3: STLS refused: %q", line)
+			}
+			return err
+		},
+		keyword: "STLS",
+	},
+}
+
+// CheckSTARTTLS asks a plaintext service whether it offers STARTTLS and,
+// if upgrade is set, completes the handshake and records the certificate
+// and the capabilities the service lists over TLS. Certificates are not
+// verified; their trust is reported instead. An upgrade that fails after
+// being offered is recorded in the result's Error.
+func CheckSTARTTLS(ctx context.Context, dial DialFunc, host string, port int, protocol string, upgrade bool, timeout time.Duration) (*STARTTLSInfo, error) {
+	dialect, ok := starttlsDialects[protocol]
+	if !ok && protocol != ProtocolLDAP {
+		return nil, fmt.Errorf("starttls: unknown protocol %q", protocol)
+	}
+
+	conn, err := dialOrDirect(dial, timeout)(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { conn.Close() }()
+
+	info := &STARTTLSInfo{Protocol: protocol}
+	if protocol == ProtocolLDAP {
+		// LDAP has no capability list to read first: the extended
+		// operation is both the question and the start of the upgrade
+		conn.SetDeadline(time.Now().Add(timeout))
+		if info.Supported, err = ldapStartTLS(conn); err != nil || !info.Supported || !upgrade {
+			return info, err
+		}
+		tlsConn, err := info.handshake(ctx, conn, host, timeout)
+		if tlsConn != nil {
+			conn = tlsConn
+		}
+		if err != nil {
+			info.Error = err.Error()
+		}
+		return info, nil
+	}
+
+	c := &ftpConn{conn: conn, reader: bufio.NewReader(conn), host: host, timeout: timeout}
+	if err := dialect.greet(c); err != nil {
+		return nil, err
+	}
+	capabilities, err := dialect.capabilities(c)
+	if err != nil {
+		return nil, err
+	}
+	info.Supported = hasCapability(capabilities, dialect.keyword)
+	if !info.Supported || !upgrade {
+		return info, nil
+	}
+
+	if err := dialect.start(c); err != nil {
+		info.Error = err.Error()
+		return info, nil
+	}
+	tlsConn, err := info.handshake(ctx, conn, host, timeout)
+	if err != nil {
+		info.Error = err.Error()
+		return info, nil
+	}
+	conn = tlsConn
+	c.conn, c.reader = tlsConn, bufio.NewReader(tlsConn)
+
+	// The greeting is not repeated over TLS, so the capability list is
+	// the first thing the service says there
+	if capabilities, err = dialect.capabilities(c); err == nil {
+		info.Banner = cleanBanner(strings.Join(capabilities, "\n"))
+	}
+	return info, nil
+}
+
+// handshake runs a TLS client handshake on conn, recording the
+// negotiated version, suite and certificate
+func (info *STARTTLSInfo) handshake(ctx context.Context, conn net.Conn, host string, timeout time.Duration) (net.Conn, error) {
+	config := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+	tlsConn := tls.Client(conn, config)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+
+	state := tlsConn.ConnectionState()
+	info.Upgraded = true
+	info.TLSVersion = tls.VersionName(state.Version)
+	info.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	info.Certificate = tlsscan.InspectCertificate(host, state.PeerCertificates)
+	return tlsConn, nil
+}
+
+// hasCapability reports whether a capability list names keyword, which
+// may be several words ("AUTH TLS"). Reply codes and IMAP's
+// "* CAPABILITY" prefix are ignored.
+func hasCapability(lines []string, keyword string) bool {
+	for _, line := range lines {
+		if len(line) >= 4 && (line[3] == '-' || line[3] == ' ') {
+			if _, err := strconv.Atoi(line[:3]); err == nil {
+				line = line[4:]
+			}
+		}
+		line = " " + strings.Join(strings.Fields(strings.ToUpper(strings.ReplaceAll(line, ";", " "))), " ") + " "
+		if strings.Contains(line, " "+keyword+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// expectCode sends a command, or with none reads the greeting, and fails
+// unless the reply has the code wanted
+func expectCode(c *ftpConn, command string, want int) error {
+	reply, what := c.response, "greeting"
+	if command != "" {
+		reply = func() (int, []string, error) { return c.command(command) }
+		what = command
+	}
+	code, lines, err := reply()
+	if err != nil {
+		return err
+	}
+	if code != want {
+		return fmt.Errorf("%s refused: %s", what, strings.Join(lines, " "))
+	}
+	return nil
+}
+
+// numberedReply sends a command and returns its reply's lines if it has
+// the code wanted, or none if the server does not know the command
+func numberedReply(c *ftpConn, command string, want int) ([]string, error) {
+	code, lines, err := c.command(command)
+	if err != nil || code != want {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// send writes one command line
+func (c *ftpConn) send(command string) error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	_, err := io.WriteString(c.conn, command+"\r\n")
+	return err
+}
+
+// line reads one line, without its line ending
+func (c *ftpConn) line() (string, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	line, err := c.reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// tagged sends an IMAP command and reads the untagged lines up to its
+// tagged completion, failing unless that is OK
+func (c *ftpConn) tagged(tag, command string) ([]string, error) {
+	if err := c.send(tag + " " + command); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for len(lines) < 1000 {
+		line, err := c.line()
+		if err != nil {
+			return lines, err
+		}
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return lines, fmt.Errorf("imap: %s refused: %q", command, status)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+	return lines, fmt.Errorf("imap: reply too long")
+}
+
+// dotted sends a POP3 command and reads its multi-line answer up to the
+// closing ".", or nothing if the server refuses it
+func (c *ftpConn) dotted(command string) ([]string, error) {
+	if err := c.send(command); err != nil {
+		return nil, err
+	}
+	status, err := c.line()
+	if err != nil || !strings.HasPrefix(status, "+OK") {
+		return nil, err
+	}
+	var lines []string
+	for len(lines) < 1000 {
+		line, err := c.line()
+		if err != nil {
+			return lines, err
+		}
+		if line == "." {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+	return lines, fmt.Errorf("pop3: reply too long")
+}
+
+// ldapStartTLSRequest is an LDAP extended request, message ID 1, for the
+// StartTLS operation (OID 1.3.6.1.4.1.1466.20037)
+var ldapStartTLSRequest = append([]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16}, "1.3.6.1.4.1.1466.20037"...)
+
+// ldapStartTLS sends the StartTLS extended operation and reports whether
+// the server accepted it, after which it expects a TLS handshake
+func ldapStartTLS(conn net.Conn) (bool, error) {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return false, err
+	}
+
+	var response []byte
+	buffer := make([]byte, 4096)
+	for len(response) < 64*1024 {
+		n, err := conn.Read(buffer)
+		response = append(response, buffer[:n]...)
+		if tag, message, _, ok := berElement(response); ok {
+			if tag != 0x30 {
+				return false, fmt.Errorf("ldap: malformed response")
+			}
+			return ldapResultCode(message) == 0, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, fmt.Errorf("ldap: response too long")
+}
+
+// ldapResultCode returns the result code of an extended response
+// message, or -1 if it is not one
+func ldapResultCode(message []byte) int {
+	// messageID INTEGER, then [APPLICATION 24] ExtendedResponse whose
+	// first element is the resultCode ENUMERATED
+	_, _, rest, ok := berElement(message)
+	if !ok {
+		return -1
+	}
+	tag, op, _, ok := berElement(rest)
+	if !ok || tag != 0x78 {
+		return -1
+	}
+	tag, code, _, ok := berElement(op)
+	if !ok || tag != 0x0a || len(code) == 0 {
+		return -1
+	}
+	result := 0
+	for _, b := range code {
+		result = result<<8 | int(b)
+	}
+	return result
+}
+
+// berElement splits the first BER element off b: its tag, its content
+// and what follows. ok is false until b holds the whole element.
+func berElement(b []byte) (tag byte, content, rest []byte, ok bool) {
+	if len(b) < 2 {
+		return 0, nil, nil, false
+	}
+	tag, length, b := b[0], int(b[1]), b[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 || len(b) < n {
+			return 0, nil, nil, false
+		}
+		length = 0
+		for _, c := range b[:n] {
+			length = length<<8 | int(c)
+		}
+		b = b[n:]
+	}
+	if len(b) < length {
+		return 0, nil, nil, false
+	}
+	return tag, b[:length], b[length:], true
+}
@@ -0,0 +1,44 @@
+package portscan
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// MaxCIDRHosts is the most addresses a single CIDR target may expand to
+const MaxCIDRHosts = 1 << 20
+
+// ExpandTargets replaces each CIDR target with the addresses in it,
+// leaving hostnames and single addresses as they are. The network and
+// broadcast addresses of IPv4 prefixes shorter than /31 are skipped.
+func ExpandTargets(targets []string) ([]string, error) {
+	var expanded []string
+	for _, target := range targets {
+		if !strings.Contains(target, "/") {
+			expanded = append(expanded, target)
+			continue
+		}
+		prefix, err := netip.ParsePrefix(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR target %q: %w", target, err)
+		}
+		prefix = prefix.Masked()
+
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 20 {
+			return nil, fmt.Errorf("CIDR target %s has more than %d addresses; split it into smaller prefixes", prefix, MaxCIDRHosts)
+		}
+
+		skipEnds := prefix.Addr().Is4() && hostBits > 1
+		count := 1 << hostBits
+		addr := prefix.Addr()
+		for i := 0; i < count; i, addr = i+1, addr.Next() {
+			if skipEnds && (i == 0 || i == count-1) {
+				continue
+			}
+			expanded = append(expanded, addr.String())
+		}
+	}
+	return expanded, nil
+}
@@ -0,0 +1,387 @@
+package portscan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// Traceroute probe kinds
+const (
+	TraceUDP  = "udp"
+	TraceICMP = "icmp"
+	TraceTCP  = "tcp"
+)
+
+// traceBasePort is the first UDP port traceroute probes, one higher per hop
+const traceBasePort = 33434
+
+// TraceConfig holds traceroute configuration
+type TraceConfig struct {
+	// Mode is the probe sent: TraceUDP (the default), TraceICMP echo, or
+	// TraceTCP SYNs to a port the host has open, which firewalls that drop
+	// the others let through
+	Mode string
+
+	// MaxHops is the highest TTL tried (default 30)
+	MaxHops int
+
+	// Timeout is how long each hop has to answer (default 2s)
+	Timeout time.Duration
+
+	// Silent is how many hops in a row may go unanswered before the trace
+	// gives up and the path counts as filtered there (default 5)
+	Silent int
+
+	// Workers is how many hosts are traced at once (default 10)
+	Workers int
+}
+
+// Hop is one router on a path, or an unanswered TTL when Address is empty
+type Hop struct {
+	TTL     int     `json:"ttl"`
+	Address string  `json:"address,omitempty"`
+	RTT     float64 `json:"rtt_ms,omitempty"`
+
+	// Hosts is how many of the traced hosts' paths cross this hop; a hop
+	// most paths share is a chokepoint
+	Hosts int `json:"hosts,omitempty"`
+}
+
+// Path is the route to a host as traceroute found it
+type Path struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+	Mode string `json:"mode"`
+	Hops []Hop  `json:"hops"`
+
+	// Reached is whether the host itself answered
+	Reached bool `json:"reached"`
+
+	// Upstream is the last router answering before the host, which hosts
+	// on the same network share
+	Upstream string `json:"upstream,omitempty"`
+
+	// FilteredAfter, for a path that went silent before the host, is the
+	// last router answering: a firewall at or just past it drops the probes
+	FilteredAfter string `json:"filtered_after,omitempty"`
+}
+
+// Tracer maps the network paths to hosts
+type Tracer struct {
+	config TraceConfig
+}
+
+// NewTracer creates a tracer. Receiving the routers' ICMP answers needs a
+// raw socket, so tracing needs root or CAP_NET_RAW.
+func NewTracer(config TraceConfig) *Tracer {
+	if config.Mode == "" {
+		config.Mode = TraceUDP
+	}
+	if config.MaxHops == 0 {
+		config.MaxHops = 30
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 2 * time.Second
+	}
+	if config.Silent == 0 {
+		config.Silent = 5
+	}
+	if config.Workers == 0 {
+		config.Workers = 10
+	}
+	return &Tracer{config: config}
+}
+
+// TraceAll traces the path to each host, keyed by host, with a port each
+// has open for TraceTCP, and counts the hosts crossing every hop. Hosts
+// whose trace failed are left out; the first failure is returned with
+// the paths that succeeded.
+func (t *Tracer) TraceAll(ctx context.Context, hosts map[string]int) (map[string]*Path, error) {
+	ctx = log.WithModule(ctx, "traceroute")
+	paths := make(map[string]*Path)
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, t.config.Workers)
+	for host, port := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			path, err := t.Trace(ctx, host, port)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.FromContext(ctx).Debug("traceroute failed", log.Target(host), log.Err(err))
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			paths[host] = path
+		}()
+	}
+	wg.Wait()
+
+	countHops(paths)
+	return paths, firstErr
+}
+
+// countHops fills in how many paths cross each hop. A host's own address
+// is not counted as a router.
+func countHops(paths map[string]*Path) {
+	crossing := make(map[string]int)
+	for _, path := range paths {
+		for _, hop := range path.Hops {
+			if hop.Address != "" && hop.Address != path.IP {
+				crossing[hop.Address]++
+			}
+		}
+	}
+	for _, path := range paths {
+		for i, hop := range path.Hops {
+			if hop.Address != path.IP {
+				path.Hops[i].Hosts = crossing[hop.Address]
+			}
+		}
+	}
+}
+
+// Chokepoints returns the routers at least least of paths cross, most
+// crossed first
+func Chokepoints(paths map[string]*Path, least int) []Hop {
+	seen := make(map[string]Hop)
+	for _, path := range paths {
+		for _, hop := range path.Hops {
+			if hop.Address != "" && hop.Hosts >= least {
+				seen[hop.Address] = Hop{TTL: hop.TTL, Address: hop.Address, Hosts: hop.Hosts}
+			}
+		}
+	}
+	hops := make([]Hop, 0, len(seen))
+	for _, hop := range seen {
+		hops = append(hops, hop)
+	}
+	sort.Slice(hops, func(i, j int) bool {
+		if hops[i].Hosts != hops[j].Hosts {
+			return hops[i].Hosts > hops[j].Hosts
+		}
+		return hops[i].Address < hops[j].Address
+	})
+	return hops
+}
+
+// Trace sends probes with rising TTLs toward host until it answers, the
+// path goes silent or MaxHops is reached. port is the port TraceTCP
+// connects to.
+func (t *Tracer) Trace(ctx context.Context, host string, port int) (*Path, error) {
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no IPv4 address", host)
+	}
+	dst := ips[0].To4()
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("traceroute needs a raw ICMP socket (root or CAP_NET_RAW): %w", err)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	path := &Path{Host: host, IP: dst.String(), Mode: t.config.Mode, Hops: []Hop{}}
+	id := os.Getpid() & 0xffff
+	silent := 0
+	for ttl := 1; ttl <= t.config.MaxHops && ctx.Err() == nil; ttl++ {
+		hop, reached, err := t.probe(ctx, conn, dst, port, id, ttl)
+		if err != nil {
+			return nil, err
+		}
+		path.Hops = append(path.Hops, hop)
+		if reached {
+			path.Reached = true
+			break
+		}
+		if hop.Address == "" {
+			if silent++; silent >= t.config.Silent {
+				break
+			}
+		} else {
+			silent = 0
+		}
+	}
+
+	// Trailing silence is dropped but for the first unanswered hop, which
+	// marks where the path went dark
+	last := ""
+	for _, hop := range path.Hops {
+		if hop.Address != "" && hop.Address != path.IP {
+			last = hop.Address
+		}
+	}
+	for len(path.Hops) > 1 && path.Hops[len(path.Hops)-1].Address == "" && path.Hops[len(path.Hops)-2].Address == "" {
+		path.Hops = path.Hops[:len(path.Hops)-1]
+	}
+	path.Upstream = last
+	if !path.Reached {
+		path.FilteredAfter = last
+	}
+	return path, ctx.Err()
+}
+
+// probe sends one probe at ttl and waits for the router it expires at, or
+// the host, to answer
+func (t *Tracer) probe(ctx context.Context, conn *icmp.PacketConn, dst net.IP, port, id, ttl int) (Hop, bool, error) {
+	hop := Hop{TTL: ttl}
+	start := time.Now()
+	deadline := start.Add(t.config.Timeout)
+
+	// What the quoted probe in an ICMP error is matched on: the echo's id
+	// and sequence, or the destination port
+	var protocol, match int
+	// connected gets the round trip of a TCP connect that finished, refused
+	// or not: the host itself answered
+	var connected chan float64
+
+	switch t.config.Mode {
+	case TraceICMP:
+		protocol, match = 1, id<<16|ttl
+		conn.IPv4PacketConn().SetTTL(ttl)
+		msg := icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: id, Seq: ttl, Data: []byte("scanner traceroute")}}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return hop, false, err
+		}
+		if _, err := conn.WriteTo(b, &net.IPAddr{IP: dst}); err != nil {
+			return hop, false, err
+		}
+	case TraceTCP:
+		protocol, match = 6, port
+		connected = make(chan float64, 1)
+		dialer := &net.Dialer{
+			Timeout: t.config.Timeout,
+			Control: func(network, address string, c syscall.RawConn) error {
+				var err error
+				c.Control(func(fd uintptr) { err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl) })
+				return err
+			},
+		}
+		go func() {
+			c, err := dialer.DialContext(ctx, "tcp4", net.JoinHostPort(dst.String(), strconv.Itoa(port)))
+			if c != nil {
+				c.Close()
+			}
+			if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+				connected <- rtt(start)
+			}
+		}()
+	default:
+		protocol, match = 17, traceBasePort+ttl-1
+		udp, err := net.ListenPacket("udp4", ":0")
+		if err != nil {
+			return hop, false, err
+		}
+		defer udp.Close()
+		ipv4.NewPacketConn(udp).SetTTL(ttl)
+		if _, err := udp.WriteTo([]byte("scanner traceroute"), &net.UDPAddr{IP: dst, Port: match}); err != nil {
+			return hop, false, err
+		}
+	}
+
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		select {
+		case elapsed := <-connected:
+			hop.Address, hop.RTT = dst.String(), elapsed
+			return hop, true, nil
+		default:
+		}
+
+		// TCP answers come on the connection, so the socket is polled
+		// in short waits to notice them
+		wait := deadline
+		if poll := time.Now().Add(50 * time.Millisecond); connected != nil && poll.Before(deadline) {
+			wait = poll
+		}
+		conn.SetReadDeadline(wait)
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return hop, false, err
+		}
+		msg, err := icmp.ParseMessage(1, buf[:n])
+		if err != nil {
+			continue
+		}
+		addr := from.(*net.IPAddr).IP
+
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if msg.Type == ipv4.ICMPTypeEchoReply && protocol == 1 && addr.Equal(dst) && body.ID == id && body.Seq == ttl {
+				hop.Address, hop.RTT = addr.String(), rtt(start)
+				return hop, true, nil
+			}
+		case *icmp.TimeExceeded:
+			if quotes(body.Data, dst, protocol, match) {
+				hop.Address, hop.RTT = addr.String(), rtt(start)
+				return hop, false, nil
+			}
+		case *icmp.DstUnreach:
+			// The host refusing the UDP port is the host answering; a
+			// router saying it cannot deliver is the path's end
+			if quotes(body.Data, dst, protocol, match) {
+				hop.Address, hop.RTT = addr.String(), rtt(start)
+				return hop, addr.Equal(dst), nil
+			}
+		}
+	}
+	return hop, false, nil
+}
+
+// quotes reports whether an ICMP error's quoted packet is the probe: one
+// to dst of the protocol, with the echo id and sequence or destination
+// port match
+func quotes(data []byte, dst net.IP, protocol, match int) bool {
+	h, err := ipv4.ParseHeader(data)
+	if err != nil || !h.Dst.Equal(dst) || h.Protocol != protocol || len(data) < h.Len+8 {
+		return false
+	}
+	payload := data[h.Len:]
+	if protocol == 1 {
+		return int(binary.BigEndian.Uint16(payload[4:]))<<16|int(binary.BigEndian.Uint16(payload[6:])) == match
+	}
+	return int(binary.BigEndian.Uint16(payload[2:])) == match
+}
+
+// rtt returns the milliseconds since start
+func rtt(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,304 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Listing categories, most urgent first
+const (
+	CategoryCompromised = "compromised"
+	CategorySinkhole    = "sinkhole"
+	CategoryAbuse       = "abuse"
+	CategorySpam        = "spam"
+)
+
+// Config holds reputation check configuration
+type Config struct {
+	// Targets are IP addresses, or hostnames looked up to theirs
+	Targets []string
+
+	// Lists are the DNS blocklist zones queried (default DefaultLists)
+	Lists []string
+
+	// Feeds are files or http(s) URLs of address lists, each optionally
+	// given as name=path
+	Feeds []string
+
+	// Resolvers are the DNS servers as host:port, used in turn (default:
+	// the system's)
+	Resolvers []string
+
+	// Workers is how many addresses are checked at once
+	Workers int
+
+	// Timeout is the per-query and per-download timeout in seconds
+	Timeout int
+
+	// Proxy routes feed downloads through an http://, https:// or
+	// socks5:// proxy
+	Proxy string
+
+	// Budget, if set, limits DNS queries to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts addresses checked and those listed
+	Progress *utils.Progress
+
+	// OnResult is called for each listed address
+	OnResult func(Result)
+}
+
+// Listing is one list an address is on
+type Listing struct {
+	List     string `json:"list"`
+	Category string `json:"category"`
+
+	// Code is a blocklist's answer, which some lists vary by reason
+	Code string `json:"code,omitempty"`
+
+	// Match is the feed entry the address fell in
+	Match string `json:"match,omitempty"`
+}
+
+// Result is an address found on at least one list, or with a sinkhole's
+// reverse DNS name
+type Result struct {
+	Host     string    `json:"host"`
+	IP       string    `json:"ip"`
+	PTR      string    `json:"ptr,omitempty"`
+	Listings []Listing `json:"listings"`
+
+	// Compromised and Sinkholed summarize the listings: an address known
+	// to be running malware, or one a takedown has redirected
+	Compromised bool `json:"compromised"`
+	Sinkholed   bool `json:"sinkholed"`
+
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Checker checks addresses against blocklists and feeds
+type Checker struct {
+	config   Config
+	resolver *net.Resolver
+	client   *http.Client
+	feeds    []feed
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewChecker creates a new reputation checker
+func NewChecker(config Config) *Checker {
+	if config.Lists == nil {
+		config.Lists = DefaultLists
+	}
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+
+	resolver := net.DefaultResolver
+	if len(config.Resolvers) > 0 {
+		var next atomic.Uint32
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				addr := config.Resolvers[int(next.Add(1))%len(config.Resolvers)]
+				d := net.Dialer{Timeout: timeout}
+				return d.DialContext(ctx, "udp", addr)
+			},
+		}
+	}
+
+	return &Checker{
+		config:   config,
+		resolver: resolver,
+		client: &http.Client{
+			Timeout: 10 * timeout,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "reputation"),
+		},
+	}
+}
+
+// Errors returns the failed lookups and downloads of the last run
+func (c *Checker) Errors() []string {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	return append([]string(nil), c.errors...)
+}
+
+// recordError notes a failed lookup or download
+func (c *Checker) recordError(what string, err error) {
+	c.errorsMu.Lock()
+	defer c.errorsMu.Unlock()
+	c.errors = append(c.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Run loads the feeds, then checks every target and returns the listed
+// ones. A feed that cannot be loaded fails the run. If ctx is cancelled,
+// the results so far are returned with ctx's error.
+func (c *Checker) Run(ctx context.Context) ([]Result, error) {
+	ctx = log.WithModule(ctx, "reputation")
+	feeds, err := c.loadFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.feeds = feeds
+	c.config.Progress.AddTotal(len(c.config.Targets))
+
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				if result, ok := c.check(ctx, target); ok {
+					results <- result
+				}
+				c.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range c.config.Targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var listed []Result
+	for result := range results {
+		c.config.Progress.Found()
+		metrics.Findings("reputation").Inc()
+		if c.config.OnResult != nil {
+			c.config.OnResult(result)
+		}
+		listed = append(listed, result)
+	}
+	return listed, ctx.Err()
+}
+
+// check looks one target up in every feed and blocklist
+func (c *Checker) check(ctx context.Context, target string) (Result, bool) {
+	logger := log.FromContext(ctx).With(log.Target(target))
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		ips, err := c.lookup(ctx, func(ctx context.Context) ([]string, error) { return c.resolver.LookupHost(ctx, target) })
+		if err == nil {
+			for _, ip := range ips {
+				if a, parseErr := netip.ParseAddr(ip); parseErr == nil && a.Is4() {
+					addr = a
+					break
+				}
+			}
+		}
+		if !addr.IsValid() {
+			if ctx.Err() == nil {
+				logger.Warn("cannot resolve", log.Err(err))
+				c.recordError(target, fmt.Errorf("cannot resolve: %v", err))
+			}
+			return Result{}, false
+		}
+	}
+	addr = addr.Unmap()
+
+	result := Result{Host: target, IP: addr.String(), Listings: []Listing{}}
+	for _, f := range c.feeds {
+		if match, ok := f.contains(addr); ok {
+			result.Listings = append(result.Listings, Listing{List: f.name, Category: f.category, Match: match})
+		}
+	}
+	if addr.Is4() {
+		for _, list := range c.config.Lists {
+			listings, err := c.query(ctx, addr, list)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("blocklist lookup failed", "list", list, log.Err(err))
+					c.recordError(list+" "+result.IP, err)
+				}
+				continue
+			}
+			result.Listings = append(result.Listings, listings...)
+		}
+	}
+
+	names, _ := c.lookup(ctx, func(ctx context.Context) ([]string, error) { return c.resolver.LookupAddr(ctx, result.IP) })
+	if len(names) > 0 {
+		result.PTR = strings.TrimSuffix(names[0], ".")
+		if strings.Contains(strings.ToLower(result.PTR), "sinkhole") {
+			result.Listings = append(result.Listings, Listing{List: "ptr", Category: CategorySinkhole, Match: result.PTR})
+		}
+	}
+
+	if len(result.Listings) == 0 {
+		logger.Debug("not listed")
+		return Result{}, false
+	}
+	for _, l := range result.Listings {
+		result.Compromised = result.Compromised || l.Category == CategoryCompromised
+		result.Sinkholed = result.Sinkholed || l.Category == CategorySinkhole
+	}
+	switch {
+	case result.Compromised || result.Sinkholed:
+		result.Severity = "high"
+	case hasCategory(result.Listings, CategoryAbuse):
+		result.Severity = "medium"
+	default:
+		result.Severity = "low"
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	logger.Info("address listed", "listings", len(result.Listings), "severity", result.Severity)
+	return result, true
+}
+
+// lookup runs a DNS query within the budget and timeout
+func (c *Checker) lookup(ctx context.Context, query func(context.Context) ([]string, error)) ([]string, error) {
+	if err := c.config.Budget.Wait(ctx); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.Timeout)*time.Second)
+	defer cancel()
+	return query(ctx)
+}
+
+// hasCategory reports whether any listing is of category
+func hasCategory(listings []Listing, category string) bool {
+	for _, l := range listings {
+		if l.Category == category {
+			return true
+		}
+	}
+	return false
+}
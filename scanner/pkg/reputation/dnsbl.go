@@ -0,0 +1,63 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// DefaultLists are the DNS blocklists queried when none are configured
+var DefaultLists = []string{
+	"zen.spamhaus.org",
+	"bl.spamcop.net",
+	"b.barracudacentral.org",
+}
+
+// spamhausCodes are what Spamhaus ZEN's answers mean. Its PBL codes,
+// 127.0.0.10 and 127.0.0.11, are left out: they list dynamic and
+// end-user ranges by policy, which says nothing of the address itself.
+var spamhausCodes = map[string]string{
+	"127.0.0.2": CategorySpam,        // SBL: spam sources and operations
+	"127.0.0.3": CategorySpam,        // SBL CSS: low-reputation senders
+	"127.0.0.4": CategoryCompromised, // XBL: exploited and infected hosts
+	"127.0.0.5": CategoryCompromised,
+	"127.0.0.6": CategoryCompromised,
+	"127.0.0.7": CategoryCompromised,
+	"127.0.0.9": CategoryAbuse, // DROP: hijacked and criminal netblocks
+}
+
+// query asks a blocklist whether it lists addr, by looking the reversed
+// address up under the list's zone
+func (c *Checker) query(ctx context.Context, addr netip.Addr, list string) ([]Listing, error) {
+	b := addr.As4()
+	name := fmt.Sprintf("%d.%d.%d.%d.%s", b[3], b[2], b[1], b[0], list)
+	answers, err := c.lookup(ctx, func(ctx context.Context) ([]string, error) { return c.resolver.LookupHost(ctx, name) })
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var listings []Listing
+	for _, code := range answers {
+		// Answers outside 127.0.0.0/24 are the list refusing the query,
+		// such as Spamhaus turning away public resolvers, not a listing
+		if !strings.HasPrefix(code, "127.0.0.") {
+			return nil, fmt.Errorf("refused the query (answered %s); try another resolver", code)
+		}
+		category := CategorySpam
+		if strings.HasSuffix(list, "spamhaus.org") {
+			var ok bool
+			if category, ok = spamhausCodes[code]; !ok {
+				continue
+			}
+		}
+		listings = append(listings, Listing{List: list, Category: category, Code: code})
+	}
+	return listings, nil
+}
@@ -0,0 +1,29 @@
+// Package reputation checks IP addresses against DNS blocklists and
+// abuse feeds, flagging the ones already known as compromised or
+// sinkholed, which moves an asset up or out of the triage queue.
+//
+//	checker := reputation.NewChecker(reputation.Config{
+//		Targets: []string{"192.0.2.10"},
+//		Feeds:   []string{"https://iplists.firehol.org/files/firehol_level1.netset"},
+//	})
+//	results, err := checker.Run(ctx)
+//	for _, r := range results {
+//		fmt.Println(r.IP, r.Severity, r.Compromised, r.Sinkholed)
+//	}
+//
+// Blocklists are queried over DNS by reversed address. Spamhaus ZEN's
+// answer codes tell its lists apart: an XBL listing means the address is
+// sending from infected or exploited software, so it counts as
+// compromised. Spamhaus refuses queries from public resolvers, answering
+// with an error code that is reported as a failed lookup; use the local
+// resolver or one of Resolvers that Spamhaus accepts.
+//
+// Feeds are files or URLs listing one address or CIDR prefix per line,
+// with # or ; comments, as FireHOL, Spamhaus DROP and most abuse trackers
+// publish them. A feed is named after its file unless given as
+// name=path, and the name decides what a match means: one containing
+// "sinkhole" marks the address sinkholed, one containing "compromised",
+// "botnet", "c2" or "malware" marks it compromised, and any other is an
+// abuse listing. An address whose reverse DNS name contains "sinkhole" is
+// marked sinkholed too.
+package reputation
@@ -0,0 +1,113 @@
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// feed is an address list loaded from a file or URL
+type feed struct {
+	name     string
+	category string
+	prefixes []netip.Prefix
+}
+
+// contains returns the entry of the feed addr falls in
+func (f feed) contains(addr netip.Addr) (string, bool) {
+	for _, p := range f.prefixes {
+		if p.Contains(addr) {
+			if p.IsSingleIP() {
+				return p.Addr().String(), true
+			}
+			return p.String(), true
+		}
+	}
+	return "", false
+}
+
+// feedCategory returns what being on a feed means, from its name
+func feedCategory(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "sinkhole"):
+		return CategorySinkhole
+	case strings.Contains(name, "compromised"), strings.Contains(name, "botnet"),
+		strings.Contains(name, "c2"), strings.Contains(name, "malware"):
+		return CategoryCompromised
+	default:
+		return CategoryAbuse
+	}
+}
+
+// loadFeeds reads every configured feed
+func (c *Checker) loadFeeds(ctx context.Context) ([]feed, error) {
+	var feeds []feed
+	for _, spec := range c.config.Feeds {
+		name, location, ok := strings.Cut(spec, "=")
+		if !ok || strings.Contains(name, "/") {
+			name, location = strings.TrimSuffix(path.Base(spec), path.Ext(spec)), spec
+		}
+		start := time.Now()
+		f, err := c.loadFeed(ctx, name, location)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: %w", name, err)
+		}
+		log.FromContext(ctx).Debug("feed loaded", "feed", name, "entries", len(f.prefixes), log.Since(start))
+		feeds = append(feeds, f)
+	}
+	return feeds, nil
+}
+
+// loadFeed reads one feed from a file or an http(s) URL
+func (c *Checker) loadFeed(ctx context.Context, name, location string) (feed, error) {
+	var r io.Reader
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+		if err != nil {
+			return feed{}, err
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return feed{}, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return feed{}, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		file, err := os.Open(location)
+		if err != nil {
+			return feed{}, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	f := feed{name: name, category: feedCategory(name)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line, _, _ = strings.Cut(line, ";")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(fields[0]); err == nil {
+			f.prefixes = append(f.prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(fields[0]); err == nil {
+			f.prefixes = append(f.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return f, scanner.Err()
+}
@@ -0,0 +1,5 @@
+// Package scope parses include and exclude rules (hosts, wildcards, CIDRs
+// and regular expressions) that every scanner module uses to decide which
+// targets it may contact. A nil *Scope allows everything, so modules take
+// one unconditionally.
+package scope
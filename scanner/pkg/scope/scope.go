@@ -0,0 +1,312 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Scope decides which targets may be contacted. A scope file lists one rule
+// per line:
+//
+//	example.com        exact host
+//	*.example.com      any subdomain of example.com (not the apex)
+//	10.0.0.0/8         any IP in the block (single IPs are also accepted)
+//	re:^api[0-9]+\.    regular expression matched against the host
+//
+// A target is in scope if any include rule matches (or there are none) and
+// no exclude rule matches. Allows matches a hostname target by name alone;
+// Control, set on a dialer, also refuses the addresses excluded IPs and
+// networks cover whatever name they were reached by. All methods are safe
+// to call on a nil *Scope, which allows everything.
+type Scope struct {
+	include *ruleSet
+	exclude *ruleSet
+
+	// Log, if set, receives one line per out-of-scope or excluded host that
+	// is skipped
+	Log io.Writer
+
+	logged   map[string]bool
+	loggedMu sync.Mutex
+}
+
+// ErrExcluded is returned by Control for a connection to an excluded
+// address
+var ErrExcluded = errors.New("address excluded by scope")
+
+// ruleSet is a parsed list of host, wildcard, network and regex rules
+type ruleSet struct {
+	hosts     map[string]bool
+	wildcards []string
+	networks  []*net.IPNet
+	patterns  []*regexp.Regexp
+}
+
+// Load reads a scope file
+func Load(path string) (*Scope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scope file: %w", err)
+	}
+
+	s, err := Parse(strings.Split(string(data), "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse builds a scope from include rule lines, skipping blanks and
+// # comments
+func Parse(lines []string) (*Scope, error) {
+	include, err := parseRules(lines)
+	if err != nil {
+		return nil, err
+	}
+	if include == nil {
+		return nil, fmt.Errorf("scope has no rules")
+	}
+
+	return &Scope{
+		include: include,
+		logged:  make(map[string]bool),
+	}, nil
+}
+
+// Exclusions builds a scope that allows everything except targets matching
+// the given rules
+func Exclusions(lines []string) (*Scope, error) {
+	s := &Scope{logged: make(map[string]bool)}
+	if err := s.Exclude(lines); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Exclude adds blocklist rules; an excluded target is skipped even if an
+// include rule matches it
+func (s *Scope) Exclude(lines []string) error {
+	exclude, err := parseRules(lines)
+	if err != nil {
+		return fmt.Errorf("exclusions: %w", err)
+	}
+	if exclude == nil {
+		return nil
+	}
+
+	if s.exclude == nil {
+		s.exclude = exclude
+		return nil
+	}
+	for host := range exclude.hosts {
+		s.exclude.hosts[host] = true
+	}
+	s.exclude.wildcards = append(s.exclude.wildcards, exclude.wildcards...)
+	s.exclude.networks = append(s.exclude.networks, exclude.networks...)
+	s.exclude.patterns = append(s.exclude.patterns, exclude.patterns...)
+	return nil
+}
+
+// parseRules parses rule lines, returning nil if there are none
+func parseRules(lines []string) (*ruleSet, error) {
+	rs := &ruleSet{hosts: make(map[string]bool)}
+
+	rules := 0
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := rs.add(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rules++
+	}
+
+	if rules == 0 {
+		return nil, nil
+	}
+	return rs, nil
+}
+
+// add parses a single rule
+func (rs *ruleSet) add(rule string) error {
+	switch {
+	case strings.HasPrefix(rule, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(rule, "re:"))
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", rule, err)
+		}
+		rs.patterns = append(rs.patterns, re)
+	case strings.Contains(rule, "/"):
+		_, network, err := net.ParseCIDR(rule)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", rule, err)
+		}
+		rs.networks = append(rs.networks, network)
+	case strings.HasPrefix(rule, "*."):
+		rs.wildcards = append(rs.wildcards, strings.ToLower(strings.TrimPrefix(rule, "*")))
+	default:
+		if ip := net.ParseIP(rule); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			rs.networks = append(rs.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			return nil
+		}
+		rs.hosts[strings.ToLower(strings.TrimSuffix(rule, "."))] = true
+	}
+	return nil
+}
+
+// Allows reports whether a target (host, IP, host:port or URL) is in scope,
+// logging the first violation for each host
+func (s *Scope) Allows(target string) bool {
+	if s == nil {
+		return true
+	}
+
+	host := Host(target)
+	if s.exclude != nil && s.exclude.matches(host) {
+		s.logViolation(host, "excluded", target)
+		return false
+	}
+	if s.include != nil && !s.include.matches(host) {
+		s.logViolation(host, "out-of-scope", target)
+		return false
+	}
+	return true
+}
+
+// Control is a net.Dialer Control function that refuses connections to
+// excluded addresses, so that excluding an IP or network also excludes the
+// hostnames resolving into it. Include rules are not checked, as an
+// in-scope name may resolve anywhere. Through a proxy the address dialed
+// is the proxy's, so only Allows applies to the targets behind it.
+func (s *Scope) Control(_, address string, _ syscall.RawConn) error {
+	if s == nil || s.exclude == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if s.exclude.matches(host) {
+		s.logViolation(host, "excluded", address)
+		return fmt.Errorf("%s: %w", host, ErrExcluded)
+	}
+	return nil
+}
+
+// Filter returns the in-scope targets
+func (s *Scope) Filter(targets []string) []string {
+	if s == nil {
+		return targets
+	}
+
+	kept := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if s.Allows(target) {
+			kept = append(kept, target)
+		}
+	}
+	return kept
+}
+
+// matches checks a bare host against every rule in the set
+func (rs *ruleSet) matches(host string) bool {
+	if host == "" {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range rs.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	} else {
+		if rs.hosts[host] {
+			return true
+		}
+		for _, suffix := range rs.wildcards {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		}
+	}
+
+	for _, re := range rs.patterns {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// logViolation writes a skip notice once per host
+func (s *Scope) logViolation(host, reason, target string) {
+	if s.Log == nil {
+		return
+	}
+
+	s.loggedMu.Lock()
+	defer s.loggedMu.Unlock()
+
+	if s.logged[host] {
+		return
+	}
+	s.logged[host] = true
+	fmt.Fprintf(s.Log, "scope: skipping %s target %s\n", reason, target)
+}
+
+// Host extracts the lowercased host from a URL, host:port or bare host
+func Host(target string) string {
+	target = strings.TrimSpace(target)
+
+	if strings.Contains(target, "://") {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	}
+
+	// Drop any path left on a scheme-less target
+	if i := strings.IndexAny(target, "/?#"); i >= 0 {
+		target = target[:i]
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		target = host
+	}
+	target = strings.TrimPrefix(strings.TrimSuffix(target, "]"), "[")
+	return strings.ToLower(strings.TrimSuffix(target, "."))
+}
+
+// RegisteredDomain guesses the registered domain of a host: the last two
+// labels, or three under common second-level suffixes like co.uk
+func RegisteredDomain(host string) string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), "."), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+
+	n := 2
+	if len(labels) >= 3 && len(labels[len(labels)-1]) == 2 {
+		switch labels[len(labels)-2] {
+		case "co", "com", "net", "org", "gov", "ac", "edu":
+			n = 3
+		}
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
@@ -0,0 +1,274 @@
+package screenshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// chromeNames are the executables tried on PATH when no Chrome is given
+var chromeNames = []string{
+	"chromium", "chromium-browser", "google-chrome", "google-chrome-stable",
+	"chrome", "headless-shell", "msedge",
+}
+
+// findChrome returns the Chrome executable to run
+func findChrome(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("CHROME_PATH")
+	}
+	if path != "" {
+		return exec.LookPath(path)
+	}
+	for _, name := range chromeNames {
+		if found, err := exec.LookPath(name); err == nil {
+			return found, nil
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		const app = "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
+		if _, err := os.Stat(app); err == nil {
+			return app, nil
+		}
+	}
+	return "", errors.New("chrome not found: install Chromium or Chrome, or set CHROME_PATH")
+}
+
+// cdpCommand is a DevTools protocol command sent to Chrome
+type cdpCommand struct {
+	ID        int64       `json:"id"`
+	Method    string      `json:"method"`
+	Params    interface{} `json:"params,omitempty"`
+	SessionID string      `json:"sessionId,omitempty"`
+}
+
+// cdpMessage is a command response or an event received from Chrome
+type cdpMessage struct {
+	ID        int64           `json:"id"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+	SessionID string          `json:"sessionId"`
+	Result    json.RawMessage `json:"result"`
+	Error     *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// browser is a running headless Chrome and its DevTools connection
+type browser struct {
+	cmd     *exec.Cmd
+	conn    *websocket.Conn
+	dataDir string
+
+	nextID  atomic.Int64
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[int64]chan cdpMessage
+	sessions map[string]chan cdpMessage
+	closed   chan struct{}
+}
+
+// launchBrowser starts Chrome and connects to its DevTools endpoint
+func launchBrowser(ctx context.Context, chrome, proxy string) (*browser, error) {
+	dataDir, err := os.MkdirTemp("", "scanner-chrome-")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--headless=new",
+		"--remote-debugging-port=0",
+		"--user-data-dir=" + dataDir,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-gpu",
+		"--disable-extensions",
+		"--hide-scrollbars",
+		"--mute-audio",
+		"--ignore-certificate-errors",
+	}
+	// Chrome refuses to sandbox itself as root, e.g. in containers
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	if proxy != "" {
+		args = append(args, "--proxy-server="+proxy)
+	}
+	args = append(args, "about:blank")
+
+	cmd := exec.Command(chrome, args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		os.RemoveAll(dataDir)
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dataDir)
+		return nil, fmt.Errorf("starting chrome: %w", err)
+	}
+
+	// Chrome prints its DevTools address on stderr once it is listening
+	endpoint := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		sent := false
+		for scanner.Scan() {
+			if addr, ok := strings.CutPrefix(scanner.Text(), "DevTools listening on "); ok && !sent {
+				endpoint <- strings.TrimSpace(addr)
+				sent = true
+			}
+		}
+		if !sent {
+			close(endpoint)
+		}
+	}()
+
+	b := &browser{
+		cmd:      cmd,
+		dataDir:  dataDir,
+		pending:  make(map[int64]chan cdpMessage),
+		sessions: make(map[string]chan cdpMessage),
+		closed:   make(chan struct{}),
+	}
+
+	var addr string
+	select {
+	case addr = <-endpoint:
+	case <-time.After(30 * time.Second):
+	case <-ctx.Done():
+	}
+	if addr == "" {
+		b.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errors.New("chrome did not start a DevTools endpoint")
+	}
+
+	b.conn, err = websocket.Dial(addr, "", "http://127.0.0.1/")
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("connecting to chrome: %w", err)
+	}
+	// Full-page screenshots arrive as a single base64 message
+	b.conn.MaxPayloadBytes = 512 << 20
+
+	go b.read()
+	return b, nil
+}
+
+// read dispatches responses to their callers and events to their sessions
+func (b *browser) read() {
+	defer close(b.closed)
+	for {
+		var msg cdpMessage
+		if err := websocket.JSON.Receive(b.conn, &msg); err != nil {
+			return
+		}
+
+		b.mu.Lock()
+		if msg.ID != 0 {
+			if ch, ok := b.pending[msg.ID]; ok {
+				delete(b.pending, msg.ID)
+				ch <- msg
+			}
+		} else if ch, ok := b.sessions[msg.SessionID]; ok {
+			// Events nobody waits for are dropped rather than stalling reads
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// call sends a command, in a target session if sessionID is set, and
+// decodes its result into result if non-nil
+func (b *browser) call(ctx context.Context, sessionID, method string, params, result interface{}) error {
+	id := b.nextID.Add(1)
+	reply := make(chan cdpMessage, 1)
+	b.mu.Lock()
+	b.pending[id] = reply
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, id)
+		b.mu.Unlock()
+	}()
+
+	b.writeMu.Lock()
+	err := websocket.JSON.Send(b.conn, cdpCommand{ID: id, Method: method, Params: params, SessionID: sessionID})
+	b.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %s", method, msg.Error.Message)
+		}
+		if result != nil {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-b.closed:
+		return fmt.Errorf("%s: chrome connection closed", method)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// subscribe returns a channel receiving a session's events
+func (b *browser) subscribe(sessionID string) <-chan cdpMessage {
+	ch := make(chan cdpMessage, 64)
+	b.mu.Lock()
+	b.sessions[sessionID] = ch
+	b.mu.Unlock()
+	return ch
+}
+
+// unsubscribe stops delivering a session's events
+func (b *browser) unsubscribe(sessionID string) {
+	b.mu.Lock()
+	delete(b.sessions, sessionID)
+	b.mu.Unlock()
+}
+
+// Close shuts Chrome down and removes its profile
+func (b *browser) Close() {
+	if b.conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		b.call(ctx, "", "Browser.close", nil, nil)
+		cancel()
+		b.conn.Close()
+	} else {
+		b.cmd.Process.Kill()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		b.cmd.Process.Kill()
+		<-done
+	}
+	os.RemoveAll(b.dataDir)
+}
@@ -0,0 +1,14 @@
+// Package screenshot captures full-page screenshots of web pages in
+// headless Chrome, writes a thumbnail for each, and builds an HTML gallery
+// for eyeballing many hosts at once.
+//
+//	capturer := screenshot.New(screenshot.Config{
+//		OutputDir: "screenshots",
+//	})
+//	results, err := capturer.Capture(ctx, []string{"https://example.com"})
+//	err = screenshot.WriteGallery("screenshots", results)
+//
+// Chrome is driven over the DevTools protocol; it is found on PATH under
+// its usual names, or set Config.Chrome or the CHROME_PATH environment
+// variable.
+package screenshot
@@ -0,0 +1,82 @@
+package screenshot
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// GalleryFile is the gallery page written into the output directory
+const GalleryFile = "index.html"
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Screenshots</title>
+<style>
+body { font-family: sans-serif; margin: 1.5em; background: #f4f4f4; color: #222; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(330px, 1fr)); gap: 1em; }
+.card { background: #fff; border: 1px solid #ddd; padding: 5px; overflow: hidden; }
+.card img { display: block; width: 100%; border: 1px solid #eee; }
+.card a { color: #0645ad; text-decoration: none; word-break: break-all; font-size: 0.9em; }
+.title { color: #555; font-size: 0.85em; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+.failed li { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Screenshots</h1>
+<p>{{len .Captured}} captured, {{len .Failed}} failed &middot; {{.Generated}}</p>
+<div class="grid">
+{{- range .Captured}}
+<div class="card">
+<a href="{{.File}}"><img src="{{if .Thumbnail}}{{.Thumbnail}}{{else}}{{.File}}{{end}}" loading="lazy" alt=""></a>
+<a href="{{.URL}}">{{.URL}}</a>
+<div class="title">{{.Title}}</div>
+</div>
+{{- end}}
+</div>
+{{- if .Failed}}
+<h2>Failed</h2>
+<ul class="failed">
+{{- range .Failed}}
+<li><a href="{{.URL}}">{{.URL}}</a>: {{.Error}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteGallery writes an HTML page into dir showing every capture's
+// thumbnail, linked to its full screenshot, followed by the failures
+func WriteGallery(dir string, results []Result) error {
+	sorted := append([]Result(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].URL < sorted[j].URL })
+
+	var page struct {
+		Captured  []Result
+		Failed    []Result
+		Generated string
+	}
+	for _, r := range sorted {
+		if r.Error != "" {
+			page.Failed = append(page.Failed, r)
+		} else {
+			page.Captured = append(page.Captured, r)
+		}
+	}
+	page.Generated = time.Now().UTC().Format(time.RFC3339)
+
+	f, err := os.Create(filepath.Join(dir, GalleryFile))
+	if err != nil {
+		return err
+	}
+	if err := galleryTemplate.Execute(f, page); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
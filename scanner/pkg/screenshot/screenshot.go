@@ -0,0 +1,336 @@
+package screenshot
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// ThumbnailDir is the subdirectory of OutputDir holding thumbnails
+const ThumbnailDir = "thumbs"
+
+// Config holds screenshot capture configuration
+type Config struct {
+	// OutputDir receives a PNG per page and a thumbnail under ThumbnailDir
+	OutputDir string
+
+	// Workers is how many pages load at once, each in its own tab
+	Workers int
+
+	// Timeout is the per-page load timeout in seconds. A page still loading
+	// when it expires is captured as far as it rendered.
+	Timeout int
+
+	// Width and Height are the viewport size; the screenshot covers the
+	// whole page height up to MaxHeight
+	Width     int
+	Height    int
+	MaxHeight int
+
+	// ThumbnailWidth is the thumbnail width in pixels; thumbnails show the
+	// top of the page at the viewport's aspect ratio
+	ThumbnailWidth int
+
+	// Delay is an extra wait after the page loads, for scripts that render
+	// after the load event
+	Delay time.Duration
+
+	// Chrome is the browser executable (default: CHROME_PATH or PATH lookup)
+	Chrome string
+
+	// Proxy routes the browser through an http://, https:// or socks5://
+	// proxy
+	Proxy string
+
+	// Scope, if set, skips out-of-scope URLs
+	Scope *scope.Scope
+
+	// Budget, if set, limits page loads to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts pages loaded and those captured
+	Progress *utils.Progress
+
+	// OnResult is called for each page as it is captured or fails
+	OnResult func(Result)
+}
+
+// Result is one captured page. File and Thumbnail are relative to
+// OutputDir.
+type Result struct {
+	URL       string `json:"url"`
+	Title     string `json:"title,omitempty"`
+	File      string `json:"file,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Capturer takes screenshots in a shared headless Chrome
+type Capturer struct {
+	config Config
+}
+
+// New creates a capturer
+func New(config Config) *Capturer {
+	if config.OutputDir == "" {
+		config.OutputDir = "screenshots"
+	}
+	if config.Workers == 0 {
+		config.Workers = 4
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+	if config.Width == 0 {
+		config.Width = 1366
+	}
+	if config.Height == 0 {
+		config.Height = 768
+	}
+	if config.MaxHeight == 0 {
+		config.MaxHeight = 16384
+	}
+	if config.ThumbnailWidth == 0 {
+		config.ThumbnailWidth = 320
+	}
+	return &Capturer{config: config}
+}
+
+// Capture screenshots each URL. Pages that fail to load are returned with
+// Error set; the error is only for failures to start the browser.
+func (c *Capturer) Capture(ctx context.Context, urls []string) ([]Result, error) {
+	urls = c.config.Scope.Filter(urls)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	chrome, err := findChrome(c.config.Chrome)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(c.config.OutputDir, ThumbnailDir), 0755); err != nil {
+		return nil, err
+	}
+	b, err := launchBrowser(ctx, chrome, c.config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	c.config.Progress.AddTotal(len(urls))
+
+	ctx = log.WithModule(ctx, "screenshot")
+	var results []Result
+	var mu sync.Mutex
+	slots := make(chan struct{}, c.config.Workers)
+	var wg sync.WaitGroup
+
+	for _, url := range urls {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			result := c.capture(ctx, b, url)
+			if ctx.Err() != nil {
+				return
+			}
+			if result.Error != "" {
+				log.FromContext(ctx).Warn("capture failed", log.Target(url), log.KeyError, result.Error, log.Since(start))
+			} else {
+				log.FromContext(ctx).Debug("captured", log.Target(url), "file", result.File, log.Since(start))
+			}
+			c.config.Progress.Done()
+			if result.Error == "" {
+				c.config.Progress.Found()
+				metrics.Findings("screenshot").Inc()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if c.config.OnResult != nil {
+				c.config.OnResult(result)
+			}
+			results = append(results, result)
+		}()
+	}
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// capture loads one URL in a new tab and saves its screenshot
+func (c *Capturer) capture(ctx context.Context, b *browser, url string) Result {
+	result := Result{URL: url, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+	data, title, err := c.render(ctx, b, url)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Title = title
+
+	name := fileName(url)
+	result.File = name + ".png"
+	if err := os.WriteFile(filepath.Join(c.config.OutputDir, result.File), data, 0644); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	width, height, thumb, err := thumbnail(data, c.config.ThumbnailWidth, c.config.Width, c.config.Height)
+	result.Width, result.Height = width, height
+	if err != nil {
+		// The full screenshot is still usable
+		return result
+	}
+	result.Thumbnail = ThumbnailDir + "/" + name + ".jpg"
+	if err := os.WriteFile(filepath.Join(c.config.OutputDir, filepath.FromSlash(result.Thumbnail)), thumb, 0644); err != nil {
+		result.Thumbnail = ""
+	}
+	return result
+}
+
+// render loads a page and returns its full-page PNG and title
+func (c *Capturer) render(ctx context.Context, b *browser, url string) ([]byte, string, error) {
+	c.config.Budget.Wait(ctx)
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := b.call(ctx, "", "Target.createTarget", map[string]interface{}{"url": "about:blank"}, &target); err != nil {
+		return nil, "", err
+	}
+	defer b.call(context.WithoutCancel(ctx), "", "Target.closeTarget", map[string]interface{}{"targetId": target.TargetID}, nil)
+
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	err := b.call(ctx, "", "Target.attachToTarget", map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &attached)
+	if err != nil {
+		return nil, "", err
+	}
+	session := attached.SessionID
+	events := b.subscribe(session)
+	defer b.unsubscribe(session)
+
+	if err := b.call(ctx, session, "Page.enable", nil, nil); err != nil {
+		return nil, "", err
+	}
+	err = b.call(ctx, session, "Emulation.setDeviceMetricsOverride", map[string]interface{}{
+		"width":             c.config.Width,
+		"height":            c.config.Height,
+		"deviceScaleFactor": 1,
+		"mobile":            false,
+	}, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, time.Duration(c.config.Timeout)*time.Second)
+	defer cancel()
+
+	var navigated struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := b.call(loadCtx, session, "Page.navigate", map[string]interface{}{"url": url}, &navigated); err != nil {
+		return nil, "", err
+	}
+	if navigated.ErrorText != "" {
+		return nil, "", fmt.Errorf("loading page: %s", navigated.ErrorText)
+	}
+
+	// A page whose load never finishes is captured as it stands
+wait:
+	for {
+		select {
+		case event := <-events:
+			if event.Method == "Page.loadEventFired" {
+				break wait
+			}
+		case <-loadCtx.Done():
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
+			}
+			break wait
+		}
+	}
+	if c.config.Delay > 0 {
+		select {
+		case <-time.After(c.config.Delay):
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+
+	var title struct {
+		Result struct {
+			Value interface{} `json:"value"`
+		} `json:"result"`
+	}
+	b.call(ctx, session, "Runtime.evaluate", map[string]interface{}{"expression": "document.title", "returnByValue": true}, &title)
+	pageTitle, _ := title.Result.Value.(string)
+
+	var metrics struct {
+		CSSContentSize struct {
+			Height float64 `json:"height"`
+		} `json:"cssContentSize"`
+	}
+	if err := b.call(ctx, session, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+		return nil, "", err
+	}
+	height := min(max(int(metrics.CSSContentSize.Height), c.config.Height), c.config.MaxHeight)
+
+	var shot struct {
+		Data string `json:"data"`
+	}
+	err = b.call(ctx, session, "Page.captureScreenshot", map[string]interface{}{
+		"format":                "png",
+		"captureBeyondViewport": true,
+		"clip": map[string]interface{}{
+			"x": 0, "y": 0, "width": c.config.Width, "height": height, "scale": 1,
+		},
+	}, &shot)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(shot.Data)
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding screenshot: %w", err)
+	}
+	return data, pageTitle, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// fileName derives a readable, unique file name from a URL
+func fileName(url string) string {
+	name := unsafeChars.ReplaceAllString(url, "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	sum := sha1.Sum([]byte(url))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
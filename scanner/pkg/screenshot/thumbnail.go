@@ -0,0 +1,48 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+)
+
+// thumbnail decodes a PNG screenshot and returns its size and a JPEG of its
+// top viewport-shaped area scaled to width pixels wide
+func thumbnail(data []byte, width, viewportWidth, viewportHeight int) (int, int, []byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	cropH := min(srcH, srcW*viewportHeight/viewportWidth)
+	width = min(width, srcW)
+	height := max(cropH*width/srcW, 1)
+
+	// Box filter: each thumbnail pixel averages the source pixels it covers
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*cropH/height, max((y+1)*cropH/height, y*cropH/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, max((x+1)*srcW/width, x*srcW/width+1)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), n+1
+				}
+			}
+			thumb.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return srcW, srcH, nil, err
+	}
+	return srcW, srcH, buf.Bytes(), nil
+}
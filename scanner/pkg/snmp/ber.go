@@ -0,0 +1,272 @@
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagIPAddress   = 0x40
+	tagCounter32   = 0x41
+	tagGauge32     = 0x42
+	tagTimeTicks   = 0x43
+	tagCounter64   = 0x46
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	pduGetRequest     = 0xa0
+	pduGetNextRequest = 0xa1
+	pduResponse       = 0xa2
+)
+
+// response is the part of a response message callers use
+type response struct {
+	requestID   int
+	errorStatus int
+	vars        []Variable
+}
+
+// encodeMessage builds a request message asking for oids
+func encodeMessage(version int, community string, pduType byte, requestID int, oids []string) ([]byte, error) {
+	var bindings []byte
+	for _, oid := range oids {
+		encoded, err := encodeOID(oid)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, tlv(tagSequence, append(tlv(tagOID, encoded), tagNull, 0))...)
+	}
+
+	pdu := encodeInt(requestID)
+	pdu = append(pdu, encodeInt(0)...)
+	pdu = append(pdu, encodeInt(0)...)
+	pdu = append(pdu, tlv(tagSequence, bindings)...)
+
+	msg := encodeInt(version)
+	msg = append(msg, tlv(tagOctetString, []byte(community))...)
+	msg = append(msg, tlv(pduType, pdu)...)
+	return tlv(tagSequence, msg), nil
+}
+
+// tlv encodes one tag, length and value
+func tlv(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	n := len(value)
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// encodeInt encodes a non-negative INTEGER
+func encodeInt(n int) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return tlv(tagInteger, b)
+}
+
+// encodeOID encodes a dotted OID's value bytes
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	ids := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		ids[i] = n
+	}
+
+	out := base128(ids[0]*40 + ids[1])
+	for _, id := range ids[2:] {
+		out = append(out, base128(id)...)
+	}
+	return out, nil
+}
+
+// base128 encodes one OID arc
+func base128(n uint64) []byte {
+	out := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		out = append([]byte{byte(n&0x7f) | 0x80}, out...)
+	}
+	return out
+}
+
+// readTLV splits the first element off data
+func readTLV(data []byte) (tag byte, value, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated element")
+	}
+	tag = data[0]
+	n := int(data[1])
+	data = data[2:]
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 3 || len(data) < size {
+			return 0, nil, nil, fmt.Errorf("bad length")
+		}
+		n = 0
+		for _, b := range data[:size] {
+			n = n<<8 | int(b)
+		}
+		data = data[size:]
+	}
+	if len(data) < n {
+		return 0, nil, nil, fmt.Errorf("truncated element")
+	}
+	return tag, data[:n], data[n:], nil
+}
+
+// decodeMessage parses a response message
+func decodeMessage(data []byte) (response, error) {
+	var resp response
+	tag, msg, _, err := readTLV(data)
+	if err != nil || tag != tagSequence {
+		return resp, fmt.Errorf("not an SNMP message")
+	}
+	// version, community
+	for i := 0; i < 2; i++ {
+		if _, _, msg, err = readTLV(msg); err != nil {
+			return resp, err
+		}
+	}
+	tag, pdu, _, err := readTLV(msg)
+	if err != nil || tag != pduResponse {
+		return resp, fmt.Errorf("not a response")
+	}
+
+	var fields [3]int
+	for i := range fields {
+		var value []byte
+		if tag, value, pdu, err = readTLV(pdu); err != nil || tag != tagInteger {
+			return resp, fmt.Errorf("bad PDU header")
+		}
+		fields[i] = int(decodeInt(value))
+	}
+	resp.requestID, resp.errorStatus = fields[0], fields[1]
+
+	tag, bindings, _, err := readTLV(pdu)
+	if err != nil || tag != tagSequence {
+		return resp, fmt.Errorf("bad variable bindings")
+	}
+	for len(bindings) > 0 {
+		var binding, oid, value []byte
+		if _, binding, bindings, err = readTLV(bindings); err != nil {
+			return resp, err
+		}
+		if _, oid, binding, err = readTLV(binding); err != nil {
+			return resp, err
+		}
+		valueTag, value, _, err := readTLV(binding)
+		if err != nil {
+			return resp, err
+		}
+		switch valueTag {
+		case tagNull, tagNoSuchObject, tagNoSuchInstance, tagEndOfMibView:
+			continue
+		}
+		resp.vars = append(resp.vars, Variable{OID: decodeOID(oid), Value: decodeValue(valueTag, value)})
+	}
+	return resp, nil
+}
+
+// decodeInt reads a two's complement INTEGER
+func decodeInt(b []byte) int64 {
+	var n int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(c)
+	}
+	return n
+}
+
+// decodeOID renders OID value bytes in dotted form
+func decodeOID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var arcs []string
+	var n uint64
+	for _, c := range b {
+		n = n<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if len(arcs) == 0 {
+			// The first byte packs the first two arcs
+			first := min(n/40, 2)
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(n-first*40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(n, 10))
+		}
+		n = 0
+	}
+	return strings.Join(arcs, ".")
+}
+
+// decodeValue renders a value as text
+func decodeValue(tag byte, b []byte) string {
+	switch tag {
+	case tagInteger:
+		return strconv.FormatInt(decodeInt(b), 10)
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return strconv.FormatUint(n, 10)
+	case tagOID:
+		return decodeOID(b)
+	case tagIPAddress:
+		if len(b) == 4 {
+			return fmt.Sprintf("%d.%d.%d.%d", b[0], b[1], b[2], b[3])
+		}
+	case tagOctetString:
+		// Some agents NUL-terminate their strings
+		if text := strings.TrimRight(string(b), "\x00"); printable(text) {
+			return text
+		}
+		// Binary strings such as MAC addresses
+		parts := make([]string, len(b))
+		for i, c := range b {
+			parts[i] = fmt.Sprintf("%02x", c)
+		}
+		return strings.Join(parts, ":")
+	}
+	return string(b)
+}
+
+// printable reports whether s is text
+func printable(s string) bool {
+	for _, c := range []byte(s) {
+		if (c < 0x20 || c > 0x7e) && c != '\n' && c != '\r' && c != '\t' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package snmp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Protocol versions, as sent on the wire
+const (
+	Version1  = 0
+	Version2c = 1
+)
+
+// System group OIDs
+const (
+	OIDSysDescr    = "1.3.6.1.2.1.1.1.0"
+	OIDSysObjectID = "1.3.6.1.2.1.1.2.0"
+	OIDSysContact  = "1.3.6.1.2.1.1.4.0"
+	OIDSysName     = "1.3.6.1.2.1.1.5.0"
+	OIDSysLocation = "1.3.6.1.2.1.1.6.0"
+)
+
+// Variable is one OID and its value, rendered as text
+type Variable struct {
+	OID   string `json:"oid"`
+	Value string `json:"value"`
+}
+
+// Client sends SNMP requests with one community string
+type Client struct {
+	Community string
+
+	// Version is Version1 or Version2c (the zero value is v1)
+	Version int
+
+	// Timeout is how long to wait for each answer; default 3 seconds
+	Timeout time.Duration
+
+	// Retries resends an unanswered request; UDP drops packets
+	Retries int
+}
+
+// Get reads the given OIDs from host (port 161 unless host has one)
+func (c *Client) Get(ctx context.Context, host string, oids ...string) ([]Variable, error) {
+	return c.request(ctx, host, pduGetRequest, oids)
+}
+
+// Walk reads every variable under root with GetNext requests, stopping
+// after max variables (0 is no limit)
+func (c *Client) Walk(ctx context.Context, host, root string, max int) ([]Variable, error) {
+	root = strings.TrimPrefix(root, ".")
+	var vars []Variable
+	next := root
+	for max == 0 || len(vars) < max {
+		resp, err := c.request(ctx, host, pduGetNextRequest, []string{next})
+		if err != nil {
+			return vars, err
+		}
+		// An empty answer is endOfMibView: nothing follows
+		if len(resp) == 0 || !strings.HasPrefix(resp[0].OID, root+".") || resp[0].OID == next {
+			break
+		}
+		vars = append(vars, resp[0])
+		next = resp[0].OID
+	}
+	return vars, nil
+}
+
+// request sends one PDU and waits for its response
+func (c *Client) request(ctx context.Context, host string, pduType byte, oids []string) ([]Variable, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "161")
+	}
+
+	var id [4]byte
+	rand.Read(id[:])
+	requestID := int(binary.BigEndian.Uint32(id[:]) & 0x7fffffff)
+	packet, err := encodeMessage(c.Version, c.Community, pduType, requestID, oids)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 65535)
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if _, err := conn.Write(packet); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			resp, err := decodeMessage(buf[:n])
+			if err != nil || resp.requestID != requestID {
+				// Stray or malformed datagrams are ignored
+				continue
+			}
+			if resp.errorStatus != 0 {
+				return resp.vars, fmt.Errorf("agent returned error status %d", resp.errorStatus)
+			}
+			return resp.vars, nil
+		}
+	}
+	return nil, fmt.Errorf("no answer from %s", host)
+}
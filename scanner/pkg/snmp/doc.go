@@ -0,0 +1,15 @@
+// Package snmp is a small SNMP v1/v2c client for reconnaissance: testing
+// community strings and reading system information.
+//
+//	client := snmp.Client{Community: "public"}
+//	vars, err := client.Get(ctx, "192.0.2.10", snmp.OIDSysDescr, snmp.OIDSysName)
+//	for _, v := range vars {
+//		fmt.Println(v.OID, v.Value)
+//	}
+//
+// Enumerator tries community strings on many hosts and, with Walk, reads
+// the interface and IP address tables of each agent that answers.
+//
+// A wrong community string gets no answer at all, so it looks the same as
+// a filtered port: a timeout.
+package snmp
@@ -0,0 +1,305 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Interface and IP address table columns
+const (
+	oidIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	oidIfPhysAddress = "1.3.6.1.2.1.2.2.1.6"
+	oidIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	oidIPAdEntAddr   = "1.3.6.1.2.1.4.20.1.1"
+	oidIPAdEntIfIdx  = "1.3.6.1.2.1.4.20.1.2"
+	oidIPAdEntMask   = "1.3.6.1.2.1.4.20.1.3"
+)
+
+// DefaultCommunities are tried when Config.Communities is not set
+var DefaultCommunities = []string{"public", "private"}
+
+// Config holds SNMP enumeration configuration
+type Config struct {
+	// Targets are hosts or addresses, optionally with a port
+	Targets []string
+
+	// Communities are the community strings tried on each host; empty
+	// means DefaultCommunities
+	Communities []string
+
+	// Versions are tried in order for each community: "2c" and "1";
+	// default both
+	Versions []string
+
+	// Walk reads the interface and IP address tables, not only the system
+	// group
+	Walk bool
+
+	// MaxRows caps the variables read per table walk; default 500
+	MaxRows int
+
+	Workers int
+	Timeout int
+	Retries int
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, is a request rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts hosts tried and agents found
+	Progress *utils.Progress
+
+	// OnResult is called for each agent that answered
+	OnResult func(Result)
+}
+
+// Result is what one agent disclosed
+type Result struct {
+	Host        string      `json:"host"`
+	Port        int         `json:"port"`
+	Version     string      `json:"version"`
+	Communities []string    `json:"communities"`
+	SysDescr    string      `json:"sys_descr,omitempty"`
+	SysName     string      `json:"sys_name,omitempty"`
+	SysContact  string      `json:"sys_contact,omitempty"`
+	SysLocation string      `json:"sys_location,omitempty"`
+	Interfaces  []Interface `json:"interfaces,omitempty"`
+	Addresses   []Address   `json:"addresses,omitempty"`
+	Timestamp   string      `json:"timestamp"`
+}
+
+// Interface is one row of the interface table
+type Interface struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	MAC    string `json:"mac,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Address is one row of the IP address table
+type Address struct {
+	IP        string `json:"ip"`
+	Netmask   string `json:"netmask,omitempty"`
+	Interface int    `json:"interface,omitempty"`
+}
+
+// Enumerator finds SNMP agents that accept common community strings and
+// reads what they disclose
+type Enumerator struct {
+	config Config
+}
+
+// NewEnumerator creates a new SNMP enumerator
+func NewEnumerator(config Config) *Enumerator {
+	if len(config.Communities) == 0 {
+		config.Communities = DefaultCommunities
+	}
+	if len(config.Versions) == 0 {
+		config.Versions = []string{"2c", "1"}
+	}
+	if config.MaxRows == 0 {
+		config.MaxRows = 500
+	}
+	if config.Workers == 0 {
+		config.Workers = 50
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 2
+	}
+	return &Enumerator{config: config}
+}
+
+// EnumerateContext tries every target. If ctx is cancelled, the agents
+// found so far are returned with ctx's error.
+func (e *Enumerator) EnumerateContext(ctx context.Context) ([]Result, error) {
+	targets := e.config.Scope.Filter(e.config.Targets)
+	e.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "snmp")
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				start := time.Now()
+				if result, ok := e.enumerate(ctx, target); ok {
+					log.FromContext(ctx).Debug("community accepted", log.Target(target), "communities", result.Communities, log.Since(start))
+					results <- result
+				} else if ctx.Err() == nil {
+					log.FromContext(ctx).Info("no community accepted", log.Target(target), "tried", len(e.config.Communities), log.Since(start))
+				}
+				e.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []Result
+	for result := range results {
+		e.config.Progress.Found()
+		metrics.Findings("snmp").Inc()
+		if e.config.OnResult != nil {
+			e.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	return found, ctx.Err()
+}
+
+// enumerate tests the community strings on one host and, with the first
+// that works, reads the system group and optionally walks its tables
+func (e *Enumerator) enumerate(ctx context.Context, target string) (Result, bool) {
+	host, port := target, 161
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		host = h
+		port, _ = strconv.Atoi(p)
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	result := Result{
+		Host:      host,
+		Port:      port,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var client *Client
+	var system []Variable
+	for _, community := range e.config.Communities {
+		for _, version := range e.config.Versions {
+			c := &Client{
+				Community: community,
+				Version:   Version2c,
+				Timeout:   time.Duration(e.config.Timeout) * time.Second,
+				Retries:   e.config.Retries,
+			}
+			if version == "1" {
+				c.Version = Version1
+			}
+			e.config.Budget.Wait(ctx)
+			start := time.Now()
+			vars, err := c.Get(ctx, address, OIDSysDescr, OIDSysName, OIDSysContact, OIDSysLocation)
+			metrics.Record("snmp", start, err)
+			if ctx.Err() != nil {
+				return result, false
+			}
+			if err != nil && len(vars) == 0 {
+				log.FromContext(ctx).Debug("community rejected", log.Target(address), "version", version, log.Err(err))
+				continue
+			}
+			result.Communities = append(result.Communities, community)
+			if client == nil {
+				client, system = c, vars
+				result.Version = version
+			}
+			// One working version is enough to know the community works
+			break
+		}
+	}
+	if client == nil {
+		return result, false
+	}
+
+	for _, v := range system {
+		switch v.OID {
+		case OIDSysDescr:
+			result.SysDescr = v.Value
+		case OIDSysName:
+			result.SysName = v.Value
+		case OIDSysContact:
+			result.SysContact = v.Value
+		case OIDSysLocation:
+			result.SysLocation = v.Value
+		}
+	}
+	if e.config.Walk {
+		result.Interfaces = e.interfaces(ctx, client, address)
+		result.Addresses = e.addresses(ctx, client, address)
+	}
+	return result, true
+}
+
+// walk reads one table column, keyed by the OID suffix after the column
+func (e *Enumerator) walk(ctx context.Context, client *Client, address, column string) map[string]string {
+	e.config.Budget.Wait(ctx)
+	vars, _ := client.Walk(ctx, address, column, e.config.MaxRows)
+	rows := make(map[string]string, len(vars))
+	for _, v := range vars {
+		rows[strings.TrimPrefix(v.OID, column+".")] = v.Value
+	}
+	return rows
+}
+
+// interfaces reads the interface table
+func (e *Enumerator) interfaces(ctx context.Context, client *Client, address string) []Interface {
+	names := e.walk(ctx, client, address, oidIfDescr)
+	if len(names) == 0 {
+		return nil
+	}
+	macs := e.walk(ctx, client, address, oidIfPhysAddress)
+	statuses := e.walk(ctx, client, address, oidIfOperStatus)
+
+	var interfaces []Interface
+	for index, name := range names {
+		i := Interface{Name: name, MAC: macs[index]}
+		i.Index, _ = strconv.Atoi(index)
+		switch statuses[index] {
+		case "1":
+			i.Status = "up"
+		case "2":
+			i.Status = "down"
+		}
+		interfaces = append(interfaces, i)
+	}
+	sort.Slice(interfaces, func(a, b int) bool { return interfaces[a].Index < interfaces[b].Index })
+	return interfaces
+}
+
+// addresses reads the IP address table, which lists every address the
+// device holds, internal ones included
+func (e *Enumerator) addresses(ctx context.Context, client *Client, address string) []Address {
+	ips := e.walk(ctx, client, address, oidIPAdEntAddr)
+	if len(ips) == 0 {
+		return nil
+	}
+	ifIndexes := e.walk(ctx, client, address, oidIPAdEntIfIdx)
+	masks := e.walk(ctx, client, address, oidIPAdEntMask)
+
+	var addresses []Address
+	for key, ip := range ips {
+		a := Address{IP: ip, Netmask: masks[key]}
+		a.Interface, _ = strconv.Atoi(ifIndexes[key])
+		addresses = append(addresses, a)
+	}
+	sort.Slice(addresses, func(a, b int) bool { return addresses[a].IP < addresses[b].IP })
+	return addresses
+}
@@ -0,0 +1,151 @@
+package state
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+)
+
+// DefaultWorkspace is where checkpoints are kept when no directory is given
+const DefaultWorkspace = ".scanner-state"
+
+// Checkpoint records finished units of work (targets, batches, pipeline
+// stages) for one command run so an interrupted run can be resumed. Each
+// finished unit is appended to a utils.DiskQueue, so saving one costs a
+// line however many came before, and a crash loses at most the line being
+// written. The units are only ever pushed: the queue holds them all until
+// the run is finished.
+// All methods are safe to call on a nil *Checkpoint, which records nothing.
+type Checkpoint struct {
+	path  string
+	queue *utils.DiskQueue[checkpointUnit]
+
+	mu   sync.Mutex
+	done map[string]json.RawMessage
+}
+
+// checkpointUnit is a finished unit and its saved value, one per line of
+// the checkpoint file
+type checkpointUnit struct {
+	Unit  string          `json:"unit"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Open returns the checkpoint for a command run identified by key (usually
+// the target spec). With resume, previously saved work is loaded; otherwise
+// any stale checkpoint is discarded and the run starts fresh.
+func Open(dir, command, key string, resume bool) (*Checkpoint, error) {
+	if dir == "" {
+		dir = DefaultWorkspace
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating workspace: %w", err)
+	}
+
+	c := &Checkpoint{
+		path: strings.TrimSuffix(Path(dir, command, key), ".json") + ".jsonl",
+		done: make(map[string]json.RawMessage),
+	}
+	if !resume {
+		os.Remove(c.path)
+	}
+
+	queue, err := utils.OpenDiskQueue[checkpointUnit](c.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", c.path, err)
+	}
+	c.queue = queue
+
+	// Later saves of a unit replace earlier ones; the queue is never
+	// popped, so its items stay put
+	for _, item := range queue.Items() {
+		c.done[item.Value.Unit] = item.Value.Value
+	}
+	return c, nil
+}
+
+// Path returns where the state of a command run identified by key is kept
+// in a workspace, for modules that save their own progress alongside the
+// checkpoint
+func Path(dir, command, key string) string {
+	if dir == "" {
+		dir = DefaultWorkspace
+	}
+	sum := sha1.Sum([]byte(command + "\x00" + key))
+	return filepath.Join(dir, command+"-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// Path returns the checkpoint file location
+func (c *Checkpoint) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Resumed returns the number of units loaded from a previous run
+func (c *Checkpoint) Resumed() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// Load decodes the saved value of a finished unit into v, returning false
+// if the unit has not been finished
+func (c *Checkpoint) Load(unit string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	raw, ok := c.done[unit]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// Save marks a unit finished with its value and writes the checkpoint
+func (c *Checkpoint) Save(unit string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := c.queue.Push(checkpointUnit{Unit: unit, Value: raw}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.done[unit] = raw
+	c.mu.Unlock()
+	return nil
+}
+
+// Finish removes the checkpoint once the run has completed
+func (c *Checkpoint) Finish() error {
+	if c == nil {
+		return nil
+	}
+
+	c.queue.Close()
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package state keeps checkpoints of finished work so an interrupted run
+// can be resumed without redoing it.
+package state
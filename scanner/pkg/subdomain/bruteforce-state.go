@@ -0,0 +1,157 @@
+package subdomain
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bruteforceSaveEvery is how often a bruteforce saves its place and logs
+// its progress
+const bruteforceSaveEvery = 5 * time.Second
+
+// bruteforceState is the saved place of a bruteforce in its wordlist
+type bruteforceState struct {
+	Domain   string `json:"domain"`
+	Wordlist string `json:"wordlist"`
+
+	// Offset is how many bytes of the wordlist have been resolved, and
+	// Words how many words they held
+	Offset int64 `json:"offset"`
+	Words  int64 `json:"words"`
+
+	// Found are the names the bruteforce has found so far
+	Found []string `json:"found"`
+
+	UpdatedAt string `json:"updated_at"`
+}
+
+// bruteforceTracker follows which words have been resolved, so the offset
+// it saves never passes a word still in flight. All methods are safe to
+// call on a nil *bruteforceTracker, which saves nothing.
+type bruteforceTracker struct {
+	path  string
+	mu    sync.Mutex
+	state bruteforceState
+
+	// next is the sequence number of the first word not yet resolved, and
+	// pending the end offsets of words resolved ahead of it
+	next    uint64
+	pending map[uint64]int64
+}
+
+// openBruteforceTracker returns a tracker saving to path, holding the
+// place saved there if resume is set and it is of the same domain and
+// wordlist; otherwise any saved place is discarded
+func openBruteforceTracker(path, domain, wordlist string, resume bool) *bruteforceTracker {
+	t := &bruteforceTracker{
+		path:    path,
+		state:   bruteforceState{Domain: domain, Wordlist: wordlist},
+		pending: make(map[uint64]int64),
+	}
+	if !resume {
+		os.Remove(path)
+		return t
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t
+	}
+	var saved bruteforceState
+	if json.Unmarshal(data, &saved) != nil || saved.Domain != domain || saved.Wordlist != wordlist {
+		return t
+	}
+	t.state = saved
+	return t
+}
+
+// resumed returns the place to continue from and the names found before it
+func (t *bruteforceTracker) resumed() (offset, words int64, found []string) {
+	if t == nil {
+		return 0, 0, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.Offset, t.state.Words, append([]string(nil), t.state.Found...)
+}
+
+// done marks word seq, which ends at byte end of the wordlist, resolved,
+// and records the name if it was found
+func (t *bruteforceTracker) done(seq uint64, end int64, found string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if found != "" {
+		t.state.Found = append(t.state.Found, found)
+	}
+	t.pending[seq] = end
+	for {
+		end, ok := t.pending[t.next]
+		if !ok {
+			break
+		}
+		delete(t.pending, t.next)
+		t.next++
+		t.state.Offset = end
+		t.state.Words++
+	}
+}
+
+// save writes the place reached
+func (t *bruteforceTracker) save() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	t.state.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(t.state)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a truncated file
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// finish removes the saved place once the wordlist is done
+func (t *bruteforceTracker) finish() {
+	if t == nil {
+		return
+	}
+	os.Remove(t.path)
+}
+
+// wordlistEntry returns the word on a wordlist line, or "" for blank lines
+// and comments
+func wordlistEntry(line string) string {
+	word := strings.TrimSpace(line)
+	if strings.HasPrefix(word, "#") {
+		return ""
+	}
+	return word
+}
+
+// countWords counts the words left in a wordlist from its current offset
+func countWords(r io.Reader) int64 {
+	var n int64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if wordlistEntry(scanner.Text()) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package subdomain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+)
+
+// SourceTLSCert is the Source of names read from the certificates that
+// services presented to the portscan and probe modules
+const SourceTLSCert = "tls-cert"
+
+// FromCertNames returns the names under domain, as results with Source
+// "tls-cert", skipping those out of scope or already in seen. The names
+// returned are added to seen.
+func FromCertNames(domain string, names []string, seen map[string]bool, sc *scope.Scope) []Result {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var results []Result
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if seen[name] || (name != domain && !strings.HasSuffix(name, "."+domain)) {
+			continue
+		}
+		if !sc.Allows(name) {
+			continue
+		}
+		seen[name] = true
+		results = append(results, Result{
+			Subdomain: name,
+			Source:    SourceTLSCert,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return results
+}
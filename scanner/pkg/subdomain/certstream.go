@@ -0,0 +1,154 @@
+package subdomain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultCertStreamURL is the public certstream feed of newly logged
+// certificates
+const DefaultCertStreamURL = "wss://certstream.calidog.io/"
+
+// CertStreamConfig configures a live certificate transparency watch
+type CertStreamConfig struct {
+	// URL is a certstream-compatible websocket feed: the full feed
+	// (certificate_update messages) or a domains-only one (dns_entries)
+	URL string
+
+	// Domains are the domains whose names are reported, subdomains included
+	Domains []string
+
+	// Scope, if set, drops out-of-scope names
+	Scope *scope.Scope
+
+	// OnResult is called once for each new name, with Source "certstream"
+	OnResult func(Result)
+
+	// OnError, if set, is told about each dropped connection before the
+	// watch reconnects
+	OnError func(error)
+}
+
+// certStreamMessage is the part of a certstream message the watch reads
+type certStreamMessage struct {
+	MessageType string          `json:"message_type"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// WatchCertStream follows a certstream feed until ctx is cancelled,
+// reporting each name under the configured domains the first time a
+// certificate for it is logged. Dropped connections are retried with
+// backoff, so it only returns ctx's error.
+func WatchCertStream(ctx context.Context, config CertStreamConfig) error {
+	if config.URL == "" {
+		config.URL = DefaultCertStreamURL
+	}
+	domains := make([]string, 0, len(config.Domains))
+	for _, d := range config.Domains {
+		domains = append(domains, strings.ToLower(strings.TrimSuffix(d, ".")))
+	}
+
+	seen := make(map[string]bool)
+	report := func(name string) {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."))
+		if seen[name] || !underDomains(name, domains) {
+			return
+		}
+		seen[name] = true
+		if !config.Scope.Allows(name) {
+			return
+		}
+		if config.OnResult != nil {
+			config.OnResult(Result{
+				Subdomain: name,
+				Source:    "certstream",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		connected := time.Now()
+		err := readCertStream(ctx, config.URL, report)
+		if ctx.Err() != nil {
+			break
+		}
+		if config.OnError != nil {
+			config.OnError(err)
+		}
+
+		// A connection that held for a while starts the backoff over
+		if time.Since(connected) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 2*time.Minute)
+	}
+	return ctx.Err()
+}
+
+// readCertStream reads one connection, passing every logged name to
+// report, until it fails or ctx is cancelled
+func readCertStream(ctx context.Context, url string, report func(string)) error {
+	wsConfig, err := websocket.NewConfig(url, "http://localhost/")
+	if err != nil {
+		return fmt.Errorf("invalid certstream URL: %w", err)
+	}
+	conn, err := wsConfig.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to certstream: %w", err)
+	}
+	defer conn.Close()
+
+	// Closing the connection is the only way to interrupt a blocked read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		var msg certStreamMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return fmt.Errorf("reading certstream: %w", err)
+		}
+
+		switch msg.MessageType {
+		case "certificate_update":
+			var update struct {
+				LeafCert struct {
+					AllDomains []string `json:"all_domains"`
+				} `json:"leaf_cert"`
+			}
+			if json.Unmarshal(msg.Data, &update) == nil {
+				for _, name := range update.LeafCert.AllDomains {
+					report(name)
+				}
+			}
+		case "dns_entries":
+			var names []string
+			if json.Unmarshal(msg.Data, &names) == nil {
+				for _, name := range names {
+					report(name)
+				}
+			}
+		}
+	}
+}
+
+// underDomains reports whether name is one of domains or a subdomain of one
+func underDomains(name string, domains []string) bool {
+	for _, d := range domains {
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package subdomain
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Bruteforce query tuning
+const (
+	muxSocketsPerResolver = 4
+	muxDialTimeout        = 5 * time.Second
+	muxQueryTimeout       = 2 * time.Second
+	muxAttempts           = 3
+
+	// A socket's reader backs off muxReadBackoff, doubling, after each
+	// error in a row, and the socket is retired after muxMaxErrors reads
+	// and writes in a row fail
+	muxReadBackoff = 10 * time.Millisecond
+	muxMaxErrors   = 10
+)
+
+var (
+	errMuxFull = errors.New("too many outstanding queries on one socket")
+	errMuxDead = errors.New("every resolver socket failed")
+)
+
+// dnsMux sends A queries over a few long-lived UDP sockets per resolver and
+// matches replies to queries by ID, so thousands of lookups can be
+// outstanding at once without a socket or goroutine-bound exchange each
+type dnsMux struct {
+	conns []*muxConn
+	next  atomic.Uint32
+}
+
+// muxConn is one socket and the queries waiting on it
+type muxConn struct {
+	conn net.Conn
+
+	// failures counts reads and writes failed in a row, and dead is set
+	// once there are too many and the socket is retired
+	failures atomic.Int32
+	dead     atomic.Bool
+
+	mu      sync.Mutex
+	pending map[uint16]*muxQuery
+	nextID  uint16
+}
+
+// muxQuery is one outstanding query
+type muxQuery struct {
+	name  string
+	reply chan muxReply
+}
+
+// muxReply is what a query's reply said
+type muxReply struct {
+	rcode dnsmessage.RCode
+	found bool
+}
+
+// newDNSMux opens the sockets for each resolver (IP:port) and starts
+// reading replies. Resolvers that cannot be dialed are skipped.
+func newDNSMux(resolvers []string) (*dnsMux, error) {
+	m := &dnsMux{}
+	dialer := &net.Dialer{Timeout: muxDialTimeout}
+	var lastErr error
+	for _, addr := range resolvers {
+		for i := 0; i < muxSocketsPerResolver; i++ {
+			conn, err := dialer.Dial("udp", addr)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			c := &muxConn{
+				conn:    conn,
+				pending: make(map[uint16]*muxQuery),
+				nextID:  uint16(rand.Intn(1 << 16)),
+			}
+			m.conns = append(m.conns, c)
+			go c.read()
+		}
+	}
+	if len(m.conns) == 0 {
+		return nil, lastErr
+	}
+	return m, nil
+}
+
+// close closes every socket, ending their readers
+func (m *dnsMux) close() {
+	for _, c := range m.conns {
+		c.conn.Close()
+	}
+}
+
+// exists reports whether name has an A record or a CNAME, even one whose
+// target does not resolve. Each attempt goes out on the next live socket,
+// so a retry usually reaches another resolver.
+func (m *dnsMux) exists(ctx context.Context, name string) (bool, error) {
+	var lastErr error
+	for attempt := 0; attempt < muxAttempts; attempt++ {
+		c := m.live()
+		if c == nil {
+			return false, errMuxDead
+		}
+		found, rcode, err := c.query(ctx, name)
+		switch {
+		case err != nil:
+			lastErr = err
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+		case rcode == dnsmessage.RCodeSuccess || rcode == dnsmessage.RCodeNameError:
+			return found, nil
+		default:
+			// SERVFAIL or REFUSED from one resolver; another may answer
+			lastErr = errors.New(strings.ToLower(rcode.String()))
+		}
+	}
+	return false, lastErr
+}
+
+// live returns the next socket not retired, or nil if all are
+func (m *dnsMux) live() *muxConn {
+	for range m.conns {
+		c := m.conns[int(m.next.Add(1))%len(m.conns)]
+		if !c.dead.Load() {
+			return c
+		}
+	}
+	return nil
+}
+
+// query sends one A query and waits for its reply
+func (c *muxConn) query(ctx context.Context, name string) (bool, dnsmessage.RCode, error) {
+	q := &muxQuery{
+		name:  strings.ToLower(name),
+		reply: make(chan muxReply, 1),
+	}
+	id, err := c.register(q)
+	if err != nil {
+		return false, 0, err
+	}
+	defer c.unregister(id)
+
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return false, 0, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		return false, 0, err
+	}
+	if _, err := c.conn.Write(packet); err != nil {
+		c.failed()
+		return false, 0, err
+	}
+
+	timer := time.NewTimer(muxQueryTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false, 0, ctx.Err()
+	case <-timer.C:
+		return false, 0, errors.New("timeout")
+	case reply := <-q.reply:
+		return reply.found, reply.rcode, nil
+	}
+}
+
+// register assigns a free query ID to q
+func (c *muxConn) register(q *muxQuery) (uint16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < 1<<16; i++ {
+		c.nextID++
+		if _, used := c.pending[c.nextID]; !used {
+			c.pending[c.nextID] = q
+			return c.nextID, nil
+		}
+	}
+	return 0, errMuxFull
+}
+
+// unregister frees a query ID; a late reply to it is dropped
+func (c *muxConn) unregister(id uint16) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// read delivers replies to the queries waiting for them until the socket
+// is closed. Replies whose ID or question does not match an outstanding
+// query are dropped. A socket whose reads keep failing, as when its
+// resolver answers every query with port unreachable, is retired.
+func (c *muxConn) read() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			failures := c.failed()
+			if c.dead.Load() {
+				return
+			}
+			time.Sleep(muxReadBackoff << failures)
+			continue
+		}
+		c.failures.Store(0)
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil || !header.Response {
+			continue
+		}
+		question, err := p.Question()
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		q := c.pending[header.ID]
+		if q != nil && strings.TrimSuffix(strings.ToLower(question.Name.String()), ".") == q.name {
+			delete(c.pending, header.ID)
+		} else {
+			q = nil
+		}
+		c.mu.Unlock()
+		if q == nil {
+			continue
+		}
+
+		q.reply <- muxReply{rcode: header.RCode, found: hasAnswer(&p)}
+	}
+}
+
+// failed counts a failed read or write, retiring the socket once too many
+// fail in a row, and returns how many have
+func (c *muxConn) failed() int32 {
+	n := c.failures.Add(1)
+	if n >= muxMaxErrors && !c.dead.Swap(true) {
+		c.conn.Close()
+	}
+	return n
+}
+
+// hasAnswer reports whether the rest of a reply holds an A or CNAME record
+func hasAnswer(p *dnsmessage.Parser) bool {
+	if err := p.SkipAllQuestions(); err != nil {
+		return false
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			return false
+		}
+		if h.Type == dnsmessage.TypeA || h.Type == dnsmessage.TypeCNAME {
+			return true
+		}
+		if err := p.SkipAnswer(); err != nil {
+			return false
+		}
+	}
+}
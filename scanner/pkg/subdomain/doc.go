@@ -0,0 +1,24 @@
+// Package subdomain enumerates a domain's subdomains from passive sources
+// (certificate transparency and public datasets), the live certstream feed,
+// wordlist bruteforce and permutations of the names found, and resolves
+// hostnames to their IPs.
+//
+//	scanner := subdomain.NewScanner(subdomain.Config{
+//		Domain:  "example.com",
+//		Passive: true,
+//	})
+//	results, err := scanner.EnumerateContext(ctx)
+//
+// Resolver resolves an existing host list:
+//
+//	resolver := subdomain.NewResolver(subdomain.ResolverConfig{})
+//	for _, r := range resolver.Resolve(ctx, hosts) {
+//		fmt.Println(r.Subdomain, r.Alive, r.IPs)
+//	}
+//
+// ReverseIP asks passive DNS sources which other names have been seen on
+// resolved addresses, reporting the new ones under the target's domains:
+//
+//	lookup := subdomain.NewReverseIP(subdomain.ReverseIPConfig{Domains: []string{"example.com"}})
+//	cohosted := lookup.Lookup(ctx, ips)
+package subdomain
@@ -0,0 +1,613 @@
+package subdomain
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+	"github.com/recon-suite/scanner/pkg/wordlist"
+)
+
+// Config holds subdomain scanner configuration
+type Config struct {
+	Domain     string
+	Wordlist   string
+	Workers    int
+	Timeout    int
+	Passive    bool
+	Bruteforce bool
+
+	// Proxy routes passive source queries through an http://, https:// or
+	// socks5:// proxy
+	Proxy string
+
+	// OnResult is called for each subdomain as it is discovered
+	OnResult func(Result)
+
+	// Progress, if set, counts queried sources and wordlist entries
+	// resolved, and subdomains found
+	Progress *utils.Progress
+
+	// Budget, if set, limits bruteforce lookups to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Scope, if set, keeps out-of-scope names from being resolved or reported
+	Scope *scope.Scope
+
+	// Resolvers are the DNS servers bruteforce queries, as IP:port; default
+	// Google's and Cloudflare's. Workers is the number of queries
+	// outstanding across them.
+	Resolvers []string
+
+	// Seen, if set, remembers which names were reported, in place of an
+	// exact set of every name; a compact one caps memory on huge
+	// bruteforces
+	Seen utils.SeenSet
+
+	// CertStream, if set, is a certstream feed (DefaultCertStreamURL for
+	// the public one) followed while the other sources run, reporting
+	// names newly logged for Domain with Source "certstream"
+	CertStream string
+
+	// Monitor keeps following CertStream after the other sources finish,
+	// past Timeout, until the enumeration's context is cancelled
+	Monitor bool
+
+	// ScrapeRecords, once the passive and bruteforce sources finish, looks
+	// up the NS, MX and TXT records of Domain and every name found and
+	// reports the hostnames under Domain they name (mail and name servers,
+	// SPF includes, DMARC report addresses) with Source "dns-records".
+	// Names found this way are scraped in turn.
+	ScrapeRecords bool
+
+	// Permutations, once the other sources (and ScrapeRecords) finish,
+	// splits the names found into words and numbers and recombines them
+	// into new candidates (dev-api from dev and api, web2 from web1), which
+	// are resolved like bruteforce words and reported with Source
+	// "permutations". Candidates under a wildcard are skipped.
+	Permutations bool
+
+	// MaxPermutations caps the candidates Permutations resolves; default
+	// 100000
+	MaxPermutations int
+
+	// BruteforceState, if set, is a file in which bruteforce keeps its
+	// place in the wordlist and the names it has found, so an interrupted
+	// run over a huge wordlist can pick up where it stopped. It is removed
+	// once the wordlist is done.
+	BruteforceState string
+
+	// ResumeBruteforce continues from BruteforceState if it holds the
+	// place of the same domain and wordlist; otherwise the file is
+	// discarded and the wordlist read from the start
+	ResumeBruteforce bool
+}
+
+// Result represents a discovered subdomain
+type Result struct {
+	Subdomain string   `json:"subdomain"`
+	IPs       []string `json:"ips,omitempty"`
+	Source    string   `json:"source"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// Scanner handles subdomain enumeration
+type Scanner struct {
+	config  Config
+	results chan Result
+	seen    utils.SeenSet
+	client  *http.Client
+
+	errors   []string
+	errorsMu sync.Mutex
+
+	// found holds the names reported, for ScrapeRecords and Permutations
+	found   []string
+	foundMu sync.Mutex
+}
+
+// NewScanner creates a new subdomain scanner
+func NewScanner(config Config) *Scanner {
+	if config.Workers == 0 {
+		config.Workers = 100
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+	if config.Seen == nil {
+		config.Seen, _ = utils.NewSeenSet(utils.SeenExact, 0)
+	}
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = []string{"8.8.8.8:53", "1.1.1.1:53", "8.8.4.4:53"}
+	}
+	if config.MaxPermutations == 0 {
+		config.MaxPermutations = 100000
+	}
+
+	return &Scanner{
+		config: config,
+		seen:   config.Seen,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "subdomain"),
+		},
+	}
+}
+
+// Enumerate performs subdomain enumeration
+func (s *Scanner) Enumerate() ([]Result, error) {
+	return s.EnumerateContext(context.Background())
+}
+
+// EnumerateContext enumerates until done or ctx is cancelled. On
+// cancellation it returns the subdomains found so far with ctx's error.
+func (s *Scanner) EnumerateContext(parent context.Context) ([]Result, error) {
+	s.results = make(chan Result, 10000)
+	var wg sync.WaitGroup
+
+	parent = log.WithModule(parent, "subdomain")
+	ctx, cancel := context.WithTimeout(parent, time.Duration(s.config.Timeout)*time.Minute)
+	defer cancel()
+
+	// Passive enumeration
+	if s.config.Passive {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.passiveEnumerate(ctx)
+		}()
+	}
+
+	// Bruteforce enumeration
+	if s.config.Bruteforce && s.config.Wordlist != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.bruteforceEnumerate(ctx)
+		}()
+	}
+
+	// The live CT feed runs alongside the other sources, or until Timeout
+	// if there are none, and in monitor mode until cancelled
+	var stream sync.WaitGroup
+	if s.config.CertStream != "" {
+		streamParent := ctx
+		if s.config.Monitor {
+			streamParent = parent
+		}
+		streamCtx, stopStream := context.WithCancel(streamParent)
+		defer stopStream()
+
+		stream.Add(1)
+		go func() {
+			defer stream.Done()
+			s.watchCertStream(streamCtx)
+		}()
+		if !s.config.Monitor && (s.config.Passive || s.config.Bruteforce && s.config.Wordlist != "") {
+			go func() {
+				wg.Wait()
+				stopStream()
+			}()
+		}
+	}
+
+	// Collect results
+	go func() {
+		wg.Wait()
+		if s.config.ScrapeRecords {
+			s.scrapeRecords(ctx)
+		}
+		if s.config.Permutations {
+			s.permute(ctx)
+		}
+		stream.Wait()
+		close(s.results)
+	}()
+
+	var results []Result
+	for result := range s.results {
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		results = append(results, result)
+	}
+
+	return results, parent.Err()
+}
+
+// Errors returns the passive sources and wordlist that failed during the
+// last enumeration, as "source: error"
+func (s *Scanner) Errors() []string {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	return append([]string(nil), s.errors...)
+}
+
+// recordError notes a failed source
+func (s *Scanner) recordError(source string, err error) {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	s.errors = append(s.errors, fmt.Sprintf("%s: %v", source, err))
+}
+
+// watchCertStream reports names for the domain logged on the certstream
+// feed until ctx is cancelled
+func (s *Scanner) watchCertStream(ctx context.Context) {
+	WatchCertStream(ctx, CertStreamConfig{
+		URL:     s.config.CertStream,
+		Domains: []string{s.config.Domain},
+		OnResult: func(r Result) {
+			s.addResult(r.Subdomain, r.Source)
+		},
+		OnError: func(err error) {
+			log.FromContext(ctx).Warn("certstream feed failed", log.Target(s.config.Domain), log.Err(err))
+			s.recordError("certstream", err)
+		},
+	})
+}
+
+// passiveEnumerate uses passive sources
+func (s *Scanner) passiveEnumerate(ctx context.Context) {
+	sources := []struct {
+		name string
+		fn   func(context.Context, string) ([]string, error)
+	}{
+		{"crtsh", s.queryCrtSh},
+		{"hackertarget", s.queryHackerTarget},
+		{"threatcrowd", s.queryThreatCrowd},
+	}
+
+	s.config.Progress.AddTotal(len(sources))
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(name string, fn func(context.Context, string) ([]string, error)) {
+			defer wg.Done()
+			logger := log.FromContext(ctx).With("source", name)
+			start := time.Now()
+			subdomains, err := fn(ctx, s.config.Domain)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				logger.Warn("source failed", log.Target(s.config.Domain), log.Err(err), log.Since(start))
+				s.recordError(name, err)
+			case len(subdomains) == 0 && err == nil:
+				logger.Info("source returned no names", log.Target(s.config.Domain), log.Since(start))
+			default:
+				logger.Debug("source answered", log.Target(s.config.Domain), "names", len(subdomains), log.Since(start))
+			}
+			for _, sub := range subdomains {
+				s.addResult(sub, name)
+			}
+			s.config.Progress.Done()
+		}(source.name, source.fn)
+	}
+	wg.Wait()
+}
+
+// queryCrtSh queries Certificate Transparency logs
+func (s *Scanner) queryCrtSh(ctx context.Context, domain string) ([]string, error) {
+	url := fmt.Sprintf("https://crt.sh/?q=%%25.%s&output=json", domain)
+
+	body, err := s.fetchSource(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	var subdomains []string
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		names := strings.Split(entry.NameValue, "\n")
+		for _, name := range names {
+			name = strings.TrimPrefix(name, "*.")
+			name = strings.TrimSpace(name)
+			if name != "" && !seen[name] && strings.HasSuffix(name, domain) {
+				seen[name] = true
+				subdomains = append(subdomains, name)
+			}
+		}
+	}
+
+	return subdomains, nil
+}
+
+// queryHackerTarget queries HackerTarget API
+func (s *Scanner) queryHackerTarget(ctx context.Context, domain string) ([]string, error) {
+	url := fmt.Sprintf("https://api.hackertarget.com/hostsearch/?q=%s", domain)
+
+	body, err := s.fetchSource(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failures come back as a plain-text message with a 200 status
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "error") || strings.HasPrefix(text, "API count exceeded") {
+		return nil, errors.New(text)
+	}
+
+	var subdomains []string
+	lines := strings.Split(text, "\n")
+
+	for _, line := range lines {
+		parts := strings.Split(line, ",")
+		if len(parts) >= 1 {
+			sub := strings.TrimSpace(parts[0])
+			if sub != "" && strings.HasSuffix(sub, domain) {
+				subdomains = append(subdomains, sub)
+			}
+		}
+	}
+
+	return subdomains, nil
+}
+
+// queryThreatCrowd queries ThreatCrowd API
+func (s *Scanner) queryThreatCrowd(ctx context.Context, domain string) ([]string, error) {
+	url := fmt.Sprintf("https://www.threatcrowd.org/searchApi/v2/domain/report/?domain=%s", domain)
+
+	body, err := s.fetchSource(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Subdomains []string `json:"subdomains"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return result.Subdomains, nil
+}
+
+// fetchSource GETs a passive source URL and returns the body, treating
+// non-2xx responses as errors
+func (s *Scanner) fetchSource(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// bruteforceEnumerate performs DNS bruteforce
+func (s *Scanner) bruteforceEnumerate(ctx context.Context) {
+	logger := log.FromContext(ctx).With("source", "bruteforce")
+	file, err := wordlist.Open(s.config.Wordlist)
+	if err != nil {
+		logger.Warn("opening wordlist", log.Err(err))
+		s.recordError("bruteforce", err)
+		return
+	}
+	defer file.Close()
+
+	mux, err := newDNSMux(s.config.Resolvers)
+	if err != nil {
+		logger.Warn("opening resolver sockets", log.Err(err))
+		s.recordError("bruteforce", err)
+		return
+	}
+	start := time.Now()
+	defer func() { logger.Debug("bruteforce finished", log.Target(s.config.Domain), log.Since(start)) }()
+	defer mux.close()
+
+	// A regular file can be counted up front for a true percentage, and
+	// resumed from an offset
+	var tracker *bruteforceTracker
+	var offset, skipped, total int64
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		if s.config.BruteforceState != "" {
+			tracker = openBruteforceTracker(s.config.BruteforceState, s.config.Domain, s.config.Wordlist, s.config.ResumeBruteforce)
+		}
+		var words int64
+		var found []string
+		offset, words, found = tracker.resumed()
+		if offset > info.Size() {
+			offset, words, found = 0, 0, nil
+			tracker = openBruteforceTracker(s.config.BruteforceState, s.config.Domain, s.config.Wordlist, false)
+		}
+		if offset > 0 {
+			logger.Info("resuming bruteforce", log.Target(s.config.Domain), "words_done", words, "found", len(found))
+		}
+		for _, name := range found {
+			s.addResult(name, "bruteforce")
+		}
+
+		file.Seek(offset, io.SeekStart)
+		total = countWords(file)
+		file.Seek(offset, io.SeekStart)
+		skipped = words
+		s.config.Progress.AddTotal(int(total))
+		total += skipped
+	}
+
+	// Create worker pool
+	jobs := make(chan bruteforceJob, s.config.Workers*2)
+	var wg sync.WaitGroup
+	var resolved atomic.Int64
+
+	// Start workers
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				found := ""
+				if s.resolve(ctx, mux, job.name, "bruteforce") {
+					found = job.name
+				}
+				if ctx.Err() == nil {
+					tracker.done(job.seq, job.end, found)
+					resolved.Add(1)
+				}
+			}
+		}()
+	}
+
+	// Save the place reached, and say how far along it is, until the
+	// wordlist is fed and resolved
+	stopSaving := make(chan struct{})
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		ticker := time.NewTicker(bruteforceSaveEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopSaving:
+				return
+			case <-ticker.C:
+				if err := tracker.save(); err != nil {
+					logger.Warn("saving bruteforce state", log.Err(err))
+				}
+				if total > 0 {
+					done := skipped + resolved.Load()
+					logger.Info("bruteforce progress", log.Target(s.config.Domain), "words", done, "total", total,
+						"percent", fmt.Sprintf("%.1f", float64(done)/float64(total)*100))
+				}
+			}
+		}
+	}()
+
+	// Feed jobs, stopping early on cancellation but still draining workers
+	scanner := bufio.NewScanner(file)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		offset += int64(advance)
+		return advance, token, err
+	})
+	var seq uint64
+feed:
+	for scanner.Scan() {
+		word := wordlistEntry(scanner.Text())
+		if word == "" {
+			continue
+		}
+		if total == 0 {
+			// A wordlist that could not be counted up front is counted as read
+			s.config.Progress.AddTotal(1)
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- bruteforceJob{name: word + "." + s.config.Domain, seq: seq, end: offset}:
+			seq++
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(stopSaving)
+	<-saved
+
+	// An interrupted run keeps its place for the next
+	if ctx.Err() != nil {
+		if err := tracker.save(); err != nil {
+			logger.Warn("saving bruteforce state", log.Err(err))
+		}
+		return
+	}
+	tracker.finish()
+}
+
+// bruteforceJob is a candidate name from a wordlist, with its word's
+// sequence number and the offset its line ends at
+type bruteforceJob struct {
+	name string
+	seq  uint64
+	end  int64
+}
+
+// resolveWorker resolves candidate subdomains, reporting those that exist
+// with source. Workers share the multiplexed sockets, so each is one
+// outstanding query.
+func (s *Scanner) resolveWorker(ctx context.Context, mux *dnsMux, jobs <-chan string, source string) {
+	for subdomain := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.resolve(ctx, mux, subdomain, source)
+	}
+}
+
+// resolve looks up a candidate subdomain, reporting it with source if it
+// exists, and returns whether it does
+func (s *Scanner) resolve(ctx context.Context, mux *dnsMux, subdomain, source string) bool {
+	defer s.config.Progress.Done()
+	if !s.config.Scope.Allows(subdomain) {
+		return false
+	}
+	s.config.Budget.Wait(ctx)
+	start := time.Now()
+	found, err := mux.exists(ctx, subdomain)
+	metrics.Record("subdomain", start, err)
+	if found {
+		s.addResult(subdomain, source)
+	} else if err != nil && ctx.Err() == nil {
+		log.FromContext(ctx).Debug("lookup failed", "source", source, log.Target(subdomain), log.Err(err))
+	}
+	return found
+}
+
+// addResult adds a unique result, reporting whether it was new and in scope
+func (s *Scanner) addResult(subdomain, source string) bool {
+	if !s.seen.Add(subdomain) {
+		return false
+	}
+	if !s.config.Scope.Allows(subdomain) {
+		return false
+	}
+	s.config.Progress.Found()
+	metrics.Findings("subdomain").Inc()
+	if s.config.ScrapeRecords || s.config.Permutations {
+		s.foundMu.Lock()
+		s.found = append(s.found, subdomain)
+		s.foundMu.Unlock()
+	}
+
+	s.results <- Result{
+		Subdomain: subdomain,
+		Source:    source,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	return true
+}
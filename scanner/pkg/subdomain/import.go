@@ -0,0 +1,154 @@
+package subdomain
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+)
+
+// Sources of imported names: amass and subfinder JSON output are tagged with
+// the tool, anything else with SourceImport
+const (
+	SourceImport    = "import"
+	SourceAmass     = "amass"
+	SourceSubfinder = "subfinder"
+)
+
+// importLine is one line of amass (-json) or subfinder (-oJ) JSON output
+type importLine struct {
+	// amass
+	Name      string `json:"name"`
+	Addresses []struct {
+		IP string `json:"ip"`
+	} `json:"addresses"`
+
+	// subfinder
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+}
+
+// LoadImport reads subdomains another tool enumerated, one result per name
+// with the addresses the tool resolved, if any. It reads:
+//
+//   - plain lists, one name per line, optionally followed by its addresses
+//     (amass -ip) or by an address and source (subfinder -oI)
+//   - amass -json and subfinder -oJ output, one JSON object per line
+//   - amass v4 graph lines, such as "www.example.com (FQDN) --> a_record
+//     --> 192.0.2.1 (IPAddress)"
+//
+// Names are lower-cased and deduplicated, merging their addresses.
+func LoadImport(path string) ([]Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []Result
+	index := make(map[string]int)
+	add := func(name, source string, ips []string) {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(name), "*."), "."))
+		if name == "" {
+			return
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(results)
+			index[name] = i
+			results = append(results, Result{
+				Subdomain: name,
+				Source:    source,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) != nil && !slices.Contains(results[i].IPs, ip) {
+				results[i].IPs = append(results[i].IPs, ip)
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, "{"):
+			var entry importLine
+			if err := json.Unmarshal([]byte(text), &entry); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", path, line, err)
+			}
+			if entry.Name != "" {
+				var ips []string
+				for _, addr := range entry.Addresses {
+					ips = append(ips, addr.IP)
+				}
+				add(entry.Name, SourceAmass, ips)
+			} else {
+				add(entry.Host, SourceSubfinder, []string{entry.IP})
+			}
+		case strings.Contains(text, " --> "):
+			parseAmassGraphLine(text, add)
+		default:
+			fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+			add(fields[0], SourceImport, fields[1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
+
+// parseAmassGraphLine adds the names of an amass v4 graph line, with the
+// address it resolves to when the line is an address record
+func parseAmassGraphLine(text string, add func(name, source string, ips []string)) {
+	parts := strings.Split(text, " --> ")
+	var names, ips []string
+	for _, part := range parts {
+		value, kind, ok := strings.Cut(strings.TrimSpace(part), " (")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSuffix(kind, ")") {
+		case "FQDN":
+			names = append(names, value)
+		case "IPAddress":
+			ips = append(ips, value)
+		}
+	}
+	for i, name := range names {
+		// Only the record's owner resolves to its address
+		if i == 0 {
+			add(name, SourceAmass, ips)
+		} else {
+			add(name, SourceAmass, nil)
+		}
+	}
+}
+
+// FromImport returns the imported results under domain, skipping those
+// out of scope or already in seen. The names returned are added to seen.
+func FromImport(domain string, imported []Result, seen map[string]bool, sc *scope.Scope) []Result {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var results []Result
+	for _, r := range imported {
+		if seen[r.Subdomain] || (r.Subdomain != domain && !strings.HasSuffix(r.Subdomain, "."+domain)) {
+			continue
+		}
+		if !sc.Allows(r.Subdomain) {
+			continue
+		}
+		seen[r.Subdomain] = true
+		results = append(results, r)
+	}
+	return results
+}
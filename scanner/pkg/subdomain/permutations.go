@@ -0,0 +1,290 @@
+package subdomain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// permute resolves candidates recombined from the words and numbers of the
+// names found so far, reporting those that exist with Source
+// "permutations"
+func (s *Scanner) permute(ctx context.Context) {
+	s.foundMu.Lock()
+	names := append([]string(nil), s.found...)
+	s.foundMu.Unlock()
+	if len(names) == 0 || ctx.Err() != nil {
+		return
+	}
+
+	candidates, capped := permutations(names, s.config.Domain, s.config.MaxPermutations)
+	if capped {
+		s.recordError("permutations", fmt.Errorf("stopped at %d candidates", s.config.MaxPermutations))
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	mux, err := newDNSMux(s.config.Resolvers)
+	if err != nil {
+		s.recordError("permutations", err)
+		return
+	}
+	defer mux.close()
+
+	// A wildcard would make every candidate under it resolve
+	wildcards := s.wildcardZones(ctx, mux, names)
+
+	jobs := make(chan string, s.config.Workers*2)
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.resolveWorker(ctx, mux, jobs, "permutations")
+		}()
+	}
+
+feed:
+	for _, candidate := range candidates {
+		if underWildcard(candidate, s.config.Domain, wildcards) {
+			continue
+		}
+		s.config.Progress.AddTotal(1)
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- candidate:
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+}
+
+// wildcardZones returns which of the domain, the names and the names'
+// parents answer for a random label below them
+func (s *Scanner) wildcardZones(ctx context.Context, mux *dnsMux, names []string) map[string]bool {
+	zones := map[string]bool{s.config.Domain: false}
+	for _, name := range names {
+		for zone := name; zone != s.config.Domain && strings.HasSuffix(zone, "."+s.config.Domain); {
+			zones[zone] = false
+			zone = zone[strings.IndexByte(zone, '.')+1:]
+		}
+	}
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < min(s.config.Workers, len(zones)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for zone := range jobs {
+				s.config.Budget.Wait(ctx)
+				if found, _ := mux.exists(ctx, randomLabel()+"."+zone); found {
+					mu.Lock()
+					zones[zone] = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for zone := range zones {
+		jobs <- zone
+	}
+	close(jobs)
+	wg.Wait()
+	return zones
+}
+
+// underWildcard reports whether one of name's parents, up to domain, is a
+// wildcard
+func underWildcard(name, domain string, wildcards map[string]bool) bool {
+	for zone := name; zone != domain; {
+		i := strings.IndexByte(zone, '.')
+		if i < 0 {
+			return false
+		}
+		zone = zone[i+1:]
+		if wildcards[zone] {
+			return true
+		}
+	}
+	return false
+}
+
+// permutations returns up to limit new names under domain recombined from
+// the words and numbers in names, cheapest guesses first: numbers moved
+// up and down, a word swapped for another, a word joined to the first
+// label, then a word inserted as a label of its own. It reports whether
+// limit cut the list short.
+func permutations(names []string, domain string, limit int) ([]string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	var prefixes [][]string
+	wordSet := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		known[name] = true
+		prefix := strings.TrimSuffix(name, "."+domain)
+		if prefix == name || prefix == "" {
+			continue
+		}
+		labels := strings.Split(prefix, ".")
+		prefixes = append(prefixes, labels)
+		for _, label := range labels {
+			for _, word := range labelWords(label) {
+				wordSet[word] = true
+			}
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return strings.Join(prefixes[i], ".") < strings.Join(prefixes[j], ".")
+	})
+	words := make([]string, 0, len(wordSet))
+	for word := range wordSet {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	var candidates []string
+	add := func(labels []string) bool {
+		if len(candidates) >= limit {
+			return false
+		}
+		for _, label := range labels {
+			if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+				return true
+			}
+		}
+		name := strings.Join(labels, ".") + "." + domain
+		if !known[name] {
+			known[name] = true
+			candidates = append(candidates, name)
+		}
+		return true
+	}
+	with := func(labels []string, i int, label string) []string {
+		out := append([]string(nil), labels...)
+		out[i] = label
+		return out
+	}
+
+	// Numbers moved up and down: web1 gives web0, web2, web3 and web4
+	for _, labels := range prefixes {
+		for i, label := range labels {
+			for _, variant := range numberVariants(label) {
+				if !add(with(labels, i, variant)) {
+					return candidates, true
+				}
+			}
+		}
+	}
+
+	// A word swapped for another: api-dev gives api-staging
+	for _, labels := range prefixes {
+		for i, label := range labels {
+			parts := strings.Split(label, "-")
+			for j, part := range parts {
+				if !isWord(part) {
+					continue
+				}
+				for _, word := range words {
+					swapped := append([]string(nil), parts...)
+					swapped[j] = word
+					if !add(with(labels, i, strings.Join(swapped, "-"))) {
+						return candidates, true
+					}
+				}
+			}
+		}
+	}
+
+	// A word joined to the first label: api gives dev-api, api-dev and devapi
+	for _, labels := range prefixes {
+		for _, word := range words {
+			for _, joined := range []string{word + "-" + labels[0], labels[0] + "-" + word, word + labels[0], labels[0] + word} {
+				if !add(with(labels, 0, joined)) {
+					return candidates, true
+				}
+			}
+		}
+	}
+
+	// A word inserted as a label: api.eu gives dev.api.eu and api.dev.eu
+	for _, labels := range prefixes {
+		for _, word := range words {
+			for i := 0; i <= len(labels); i++ {
+				inserted := append(append(append([]string(nil), labels[:i]...), word), labels[i:]...)
+				if !add(inserted) {
+					return candidates, true
+				}
+			}
+		}
+	}
+
+	return candidates, false
+}
+
+// labelWords splits a label into its words, dropping digits and dashes:
+// api-v2-eu gives api and eu. Single letters are dropped as too vague.
+func labelWords(label string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(label, func(r rune) bool {
+		return r == '-' || unicode.IsDigit(r)
+	}) {
+		if len(word) > 1 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// isWord reports whether a dash-separated part of a label is a word, with
+// no digits, that another word can replace
+func isWord(part string) bool {
+	if len(part) < 2 {
+		return false
+	}
+	for _, r := range part {
+		if unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// numberVariants returns label with each run of digits moved from -1 to
+// +3, keeping any zero padding: node09 gives node08, node10, node11 and
+// node12
+func numberVariants(label string) []string {
+	var variants []string
+	for start := 0; start < len(label); {
+		if !unicode.IsDigit(rune(label[start])) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(label) && unicode.IsDigit(rune(label[end])) {
+			end++
+		}
+		digits := label[start:end]
+		if n, err := strconv.Atoi(digits); err == nil {
+			for _, delta := range []int{-1, 1, 2, 3} {
+				if n+delta < 0 {
+					continue
+				}
+				number := fmt.Sprintf("%0*d", len(digits), n+delta)
+				variants = append(variants, label[:start]+number+label[end:])
+			}
+		}
+		start = end
+	}
+	return variants
+}
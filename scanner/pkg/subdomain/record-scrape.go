@@ -0,0 +1,61 @@
+package subdomain
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// scrapedRecordTypes are the record types whose values name other hosts
+var scrapedRecordTypes = []string{"NS", "MX", "TXT"}
+
+// hostnamePattern matches hostnames inside record values, such as SPF
+// include: and a: targets or the domain of a DMARC rua=mailto: address
+var hostnamePattern = regexp.MustCompile(`(?i)[a-z0-9_](?:[a-z0-9_-]{0,61}[a-z0-9])?(?:\.[a-z0-9_](?:[a-z0-9_-]{0,61}[a-z0-9])?)+`)
+
+// scrapeRecords looks up the NS, MX and TXT records of the domain and the
+// names found so far, reporting the new hostnames under the domain they
+// name, then does the same for those until no new ones turn up
+func (s *Scanner) scrapeRecords(ctx context.Context) {
+	resolver := NewResolver(ResolverConfig{
+		Resolvers:   s.config.Resolvers,
+		Workers:     s.config.Workers,
+		RecordTypes: scrapedRecordTypes,
+		Scope:       s.config.Scope,
+		Budget:      s.config.Budget,
+	})
+
+	s.foundMu.Lock()
+	batch := append([]string{s.config.Domain}, s.found...)
+	s.foundMu.Unlock()
+
+	for len(batch) > 0 && ctx.Err() == nil {
+		var next []string
+		for _, result := range resolver.Resolve(ctx, batch) {
+			for _, values := range result.Records {
+				for _, value := range values {
+					for _, name := range recordHostnames(value, s.config.Domain) {
+						if s.addResult(name, "dns-records") {
+							next = append(next, name)
+						}
+					}
+				}
+			}
+		}
+		batch = next
+	}
+}
+
+// recordHostnames returns the hostnames below domain named in a record
+// value
+func recordHostnames(value, domain string) []string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var names []string
+	for _, match := range hostnamePattern.FindAllString(value, -1) {
+		name := strings.ToLower(strings.TrimSuffix(match, "."))
+		if name != domain && strings.HasSuffix(name, "."+domain) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
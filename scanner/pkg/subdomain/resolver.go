@@ -0,0 +1,347 @@
+package subdomain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// ResolverConfig holds DNS resolver configuration
+type ResolverConfig struct {
+	Resolvers []string
+	Timeout   time.Duration
+	Retries   int
+	Workers   int
+
+	// RecordTypes, if set, are the DNS record types to look up (A, AAAA,
+	// CNAME, MX, NS, TXT) and report in Records. Empty looks up A and AAAA
+	// addresses only.
+	RecordTypes []string
+
+	// Wildcards sets how names that only exist through a wildcard record
+	// are handled: WildcardsMark flags them, WildcardsDrop leaves them out,
+	// and WildcardsOff (the default) does not check
+	Wildcards string
+
+	// Scope, if set, skips out-of-scope names without querying them
+	Scope *scope.Scope
+
+	// Budget, if set, limits lookups to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts resolved names and those found alive
+	Progress *utils.Progress
+
+	// OnResult is called for each name that resolves
+	OnResult func(ResolutionResult)
+}
+
+// ResolutionResult holds DNS resolution results
+type ResolutionResult struct {
+	Subdomain string   `json:"subdomain"`
+	IPs       []string `json:"ips"`
+	Alive     bool     `json:"alive"`
+	Error     string   `json:"error,omitempty"`
+
+	// Records holds each requested record type's values
+	Records map[string][]string `json:"records,omitempty"`
+
+	// Wildcard is set when the answer matches the parent's wildcard record
+	Wildcard bool `json:"wildcard,omitempty"`
+}
+
+// RecordTypes lists the record types a Resolver can look up
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// ParseRecordTypes parses a comma-separated list of record types
+func ParseRecordTypes(spec string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(spec, ",") {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, rt := range RecordTypes {
+			known = known || t == rt
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown record type %q (want %s)", t, strings.Join(RecordTypes, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// Resolver handles concurrent DNS resolution
+type Resolver struct {
+	config    ResolverConfig
+	resolvers []*net.Resolver
+	wildcards sync.Map // parent domain -> *wildcardAnswer
+}
+
+// NewResolver creates a new DNS resolver
+func NewResolver(config ResolverConfig) *Resolver {
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = []string{"8.8.8.8:53", "1.1.1.1:53", "8.8.4.4:53"}
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Retries == 0 {
+		config.Retries = 2
+	}
+	if config.Workers == 0 {
+		config.Workers = 100
+	}
+
+	resolvers := make([]*net.Resolver, len(config.Resolvers))
+	for i, addr := range config.Resolvers {
+		resolvers[i] = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Timeout: config.Timeout}
+				return d.DialContext(ctx, "udp", addr)
+			},
+		}
+	}
+
+	return &Resolver{
+		config:    config,
+		resolvers: resolvers,
+	}
+}
+
+// Resolve resolves a list of subdomains concurrently
+func (r *Resolver) Resolve(ctx context.Context, subdomains []string) []ResolutionResult {
+	subdomains = r.config.Scope.Filter(subdomains)
+	r.config.Progress.AddTotal(len(subdomains))
+	ctx = log.WithModule(ctx, "resolve")
+
+	jobs := make(chan string, r.config.Workers*2)
+	results := make(chan ResolutionResult, len(subdomains))
+
+	var wg sync.WaitGroup
+
+	// Start workers
+	for i := 0; i < r.config.Workers; i++ {
+		wg.Add(1)
+		go func(resolverIdx int) {
+			defer wg.Done()
+			resolver := r.resolvers[resolverIdx%len(r.resolvers)]
+			r.worker(ctx, resolver, jobs, results)
+		}(i)
+	}
+
+	// Feed jobs
+	go func() {
+		defer close(jobs)
+		for _, sub := range subdomains {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- sub:
+			}
+		}
+	}()
+
+	// Wait and close results
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collect results
+	var resolved []ResolutionResult
+	for result := range results {
+		r.config.Progress.Done()
+		if result.Wildcard && r.config.Wildcards == WildcardsDrop {
+			continue
+		}
+		if result.Alive {
+			r.config.Progress.Found()
+			metrics.Findings("resolve").Inc()
+			if r.config.OnResult != nil {
+				r.config.OnResult(result)
+			}
+			resolved = append(resolved, result)
+		}
+	}
+
+	return resolved
+}
+
+// worker resolves subdomains from job channel
+func (r *Resolver) worker(ctx context.Context, resolver *net.Resolver, jobs <-chan string, results chan<- ResolutionResult) {
+	for subdomain := range jobs {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			start := time.Now()
+			result := r.resolveWithRetry(ctx, resolver, subdomain)
+			if result.Alive && r.config.Wildcards != WildcardsOff {
+				result.Wildcard = r.isWildcard(ctx, resolver, result)
+			}
+			logger := log.FromContext(ctx).With(log.Target(subdomain), log.Since(start))
+			switch {
+			case result.Wildcard:
+				logger.Debug("resolves only through a wildcard", "ips", result.IPs)
+			case result.Alive:
+				logger.Debug("resolved", "ips", result.IPs)
+			case ctx.Err() == nil:
+				logger.Debug("did not resolve", log.KeyError, result.Error)
+			}
+			results <- result
+		}
+	}
+}
+
+// resolveWithRetry attempts to resolve with retries
+func (r *Resolver) resolveWithRetry(ctx context.Context, resolver *net.Resolver, subdomain string) ResolutionResult {
+	var lastErr error
+
+	for attempt := 0; attempt <= r.config.Retries; attempt++ {
+		r.config.Budget.Wait(ctx)
+		resolveCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+		start := time.Now()
+		result, err := r.lookup(resolveCtx, resolver, subdomain)
+		cancel()
+		// A name that does not exist is an answer, not a failed query
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			metrics.Record("resolve", start, nil)
+		} else {
+			metrics.Record("resolve", start, err)
+		}
+
+		if err == nil && (len(result.IPs) > 0 || len(result.Records) > 0) {
+			result.Alive = true
+			return result
+		}
+		lastErr = err
+
+		// Exponential backoff
+		if attempt < r.config.Retries {
+			time.Sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+		}
+	}
+
+	errMsg := ""
+	if lastErr != nil {
+		errMsg = lastErr.Error()
+	}
+
+	return ResolutionResult{
+		Subdomain: subdomain,
+		Alive:     false,
+		Error:     errMsg,
+	}
+}
+
+// lookup queries one name for the configured record types
+func (r *Resolver) lookup(ctx context.Context, resolver *net.Resolver, name string) (ResolutionResult, error) {
+	result := ResolutionResult{Subdomain: name}
+	if len(r.config.RecordTypes) == 0 {
+		ips, err := resolver.LookupIPAddr(ctx, name)
+		for _, ip := range ips {
+			result.IPs = append(result.IPs, ip.IP.String())
+		}
+		return result, err
+	}
+
+	// A name is alive if any type has records; the last error is kept in
+	// case none does
+	var lastErr error
+	for _, recordType := range r.config.RecordTypes {
+		values, err := lookupRecords(ctx, resolver, name, recordType)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if result.Records == nil {
+			result.Records = make(map[string][]string)
+		}
+		result.Records[recordType] = values
+		if recordType == "A" || recordType == "AAAA" {
+			result.IPs = append(result.IPs, values...)
+		}
+	}
+	if len(result.Records) > 0 {
+		lastErr = nil
+	}
+	return result, lastErr
+}
+
+// lookupRecords returns the values of one record type
+func lookupRecords(ctx context.Context, resolver *net.Resolver, name, recordType string) ([]string, error) {
+	var values []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// A name without a CNAME reports itself
+		if cname = strings.TrimSuffix(cname, "."); !strings.EqualFold(cname, strings.TrimSuffix(name, ".")) {
+			values = append(values, cname)
+		}
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			values = append(values, fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range records {
+			values = append(values, strings.TrimSuffix(ns.Host, "."))
+		}
+	case "TXT":
+		return resolver.LookupTXT(ctx, name)
+	}
+	return values, nil
+}
+
+// FilterAlive filters a list of subdomains to only alive ones
+func (r *Resolver) FilterAlive(ctx context.Context, subdomains []string) []string {
+	results := r.Resolve(ctx, subdomains)
+
+	alive := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Alive {
+			alive = append(alive, result.Subdomain)
+		}
+	}
+
+	return alive
+}
@@ -0,0 +1,318 @@
+package subdomain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// SourceReverseIP is the Source of names found co-hosted on an address
+const SourceReverseIP = "reverse-ip"
+
+// Default reverse-IP endpoints
+const (
+	DefaultHackerTargetURL = "https://api.hackertarget.com"
+	DefaultOTXURL          = "https://otx.alienvault.com"
+)
+
+// ReverseIPConfig holds reverse-IP lookup configuration
+type ReverseIPConfig struct {
+	// Domains are the target's domains: co-hosted names under them are
+	// reported. Empty leaves the choice to Scope.
+	Domains []string
+
+	// Scope, if set, must also allow a name for it to be reported
+	Scope *scope.Scope
+
+	// Known are names already found, which are not reported again
+	Known []string
+
+	// Workers is how many addresses are looked up at once
+	Workers int
+
+	// Timeout is the per-request timeout in seconds
+	Timeout int
+
+	// Proxy routes source queries through an http://, https:// or
+	// socks5:// proxy
+	Proxy string
+
+	// HackerTargetURL and OTXURL override the source endpoints
+	HackerTargetURL string
+	OTXURL          string
+
+	// Budget, if set, limits source queries to a rate shared with other
+	// modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts addresses looked up and names found
+	Progress *utils.Progress
+
+	// OnResult is called for each co-hosted name as it is found
+	OnResult func(Result)
+}
+
+// ReverseIP finds the other hostnames passive DNS has seen on addresses,
+// for assets that only show up through shared hosting
+type ReverseIP struct {
+	config ReverseIPConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	seen map[string]bool
+
+	errors   []string
+	errorsMu sync.Mutex
+}
+
+// NewReverseIP creates a new reverse-IP lookup
+func NewReverseIP(config ReverseIPConfig) *ReverseIP {
+	if config.Workers == 0 {
+		config.Workers = 5
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30
+	}
+	if config.HackerTargetURL == "" {
+		config.HackerTargetURL = DefaultHackerTargetURL
+	}
+	if config.OTXURL == "" {
+		config.OTXURL = DefaultOTXURL
+	}
+	config.HackerTargetURL = strings.TrimSuffix(config.HackerTargetURL, "/")
+	config.OTXURL = strings.TrimSuffix(config.OTXURL, "/")
+
+	seen := make(map[string]bool)
+	for _, name := range config.Known {
+		seen[normalizeName(name)] = true
+	}
+	return &ReverseIP{
+		config: config,
+		seen:   seen,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: metrics.Transport(&http.Transport{
+				Proxy: utils.ProxyFunc(config.Proxy),
+			}, "reverse-ip"),
+		},
+	}
+}
+
+// Errors returns the failed source queries of the last lookup
+func (r *ReverseIP) Errors() []string {
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	return append([]string(nil), r.errors...)
+}
+
+// recordError notes a failed source query
+func (r *ReverseIP) recordError(what string, err error) {
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Lookup asks each source for the names seen on every address and
+// returns the new in-scope ones, with the address they share in IPs
+func (r *ReverseIP) Lookup(ctx context.Context, ips []string) []Result {
+	ctx = log.WithModule(ctx, "reverse-ip")
+	r.config.Progress.AddTotal(len(ips))
+
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				for _, result := range r.lookup(ctx, ip) {
+					results <- result
+				}
+				r.config.Progress.Done()
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, ip := range ips {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- ip:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var found []Result
+	for result := range results {
+		r.config.Progress.Found()
+		metrics.Findings("reverse-ip").Inc()
+		if r.config.OnResult != nil {
+			r.config.OnResult(result)
+		}
+		found = append(found, result)
+	}
+	return found
+}
+
+// lookup queries every source for one address
+func (r *ReverseIP) lookup(ctx context.Context, ip string) []Result {
+	sources := []struct {
+		name string
+		fn   func(context.Context, string) ([]string, error)
+	}{
+		{"hackertarget", r.queryHackerTarget},
+		{"otx", r.queryOTX},
+	}
+
+	var results []Result
+	for _, source := range sources {
+		logger := log.FromContext(ctx).With("source", source.name, log.Target(ip))
+		start := time.Now()
+		names, err := source.fn(ctx, ip)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("source failed", log.Err(err), log.Since(start))
+				r.recordError(source.name+" "+ip, err)
+			}
+			continue
+		}
+		logger.Debug("source answered", "names", len(names), log.Since(start))
+
+		for _, name := range names {
+			name = normalizeName(name)
+			if !r.wanted(name) {
+				continue
+			}
+			results = append(results, Result{
+				Subdomain: name,
+				IPs:       []string{ip},
+				Source:    SourceReverseIP,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Subdomain < results[j].Subdomain })
+	return results
+}
+
+// wanted reports whether a name is in scope and not yet reported, marking
+// it reported
+func (r *ReverseIP) wanted(name string) bool {
+	if name == "" || strings.Contains(name, "*") {
+		return false
+	}
+	if len(r.config.Domains) > 0 {
+		under := false
+		for _, domain := range r.config.Domains {
+			domain = normalizeName(domain)
+			under = under || name == domain || strings.HasSuffix(name, "."+domain)
+		}
+		if !under {
+			return false
+		}
+	}
+	if !r.config.Scope.Allows(name) {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[name] {
+		return false
+	}
+	r.seen[name] = true
+	return true
+}
+
+// queryHackerTarget asks HackerTarget's reverse-IP lookup, which answers
+// one name per line
+func (r *ReverseIP) queryHackerTarget(ctx context.Context, ip string) ([]string, error) {
+	body, err := r.fetch(ctx, r.config.HackerTargetURL+"/reverseiplookup/?q="+url.QueryEscape(ip))
+	if err != nil {
+		return nil, err
+	}
+
+	// Failures and empty answers come back as plain-text messages with a
+	// 200 status
+	text := strings.TrimSpace(string(body))
+	switch {
+	case strings.HasPrefix(text, "No DNS A records found"):
+		return nil, nil
+	case strings.HasPrefix(text, "error") || strings.HasPrefix(text, "API count exceeded"):
+		return nil, errors.New(text)
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// queryOTX asks AlienVault OTX for the passive DNS names seen on an
+// address
+func (r *ReverseIP) queryOTX(ctx context.Context, ip string) ([]string, error) {
+	body, err := r.fetch(ctx, r.config.OTXURL+"/api/v1/indicators/IPv4/"+url.PathEscape(ip)+"/passive_dns")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		PassiveDNS []struct {
+			Hostname string `json:"hostname"`
+		} `json:"passive_dns"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	var names []string
+	for _, record := range resp.PassiveDNS {
+		names = append(names, record.Hostname)
+	}
+	return names, nil
+}
+
+// fetch GETs a source URL within the budget and returns the body,
+// treating non-2xx responses as errors
+func (r *ReverseIP) fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	if err := r.config.Budget.Wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// normalizeName lower-cases a hostname and drops its trailing dot
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
@@ -0,0 +1,73 @@
+package subdomain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Wildcard handling modes for ResolverConfig.Wildcards
+const (
+	WildcardsOff  = ""
+	WildcardsMark = "mark"
+	WildcardsDrop = "drop"
+)
+
+// wildcardAnswer is what a random name under a parent domain resolves to.
+// An empty answer means the parent has no wildcard.
+type wildcardAnswer struct {
+	once   sync.Once
+	values map[string]bool
+}
+
+// isWildcard reports whether a resolved name's answer is the same one a
+// random name under its parent gets, i.e. it only exists through a
+// wildcard record
+func (r *Resolver) isWildcard(ctx context.Context, resolver *net.Resolver, result ResolutionResult) bool {
+	_, parent, ok := strings.Cut(strings.TrimSuffix(result.Subdomain, "."), ".")
+	if !ok || !strings.Contains(parent, ".") {
+		return false
+	}
+
+	entry, _ := r.wildcards.LoadOrStore(parent, &wildcardAnswer{})
+	answer := entry.(*wildcardAnswer)
+	answer.once.Do(func() {
+		r.config.Budget.Wait(ctx)
+		probeCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+		defer cancel()
+		probe, err := r.lookup(probeCtx, resolver, randomLabel()+"."+parent)
+		if err != nil {
+			return
+		}
+		answer.values = make(map[string]bool)
+		for _, value := range answerValues(probe) {
+			answer.values[value] = true
+		}
+	})
+
+	values := answerValues(result)
+	if len(answer.values) == 0 || len(values) == 0 {
+		return false
+	}
+	for _, value := range values {
+		if !answer.values[value] {
+			return false
+		}
+	}
+	return true
+}
+
+// answerValues returns the addresses and CNAME targets of a result
+func answerValues(result ResolutionResult) []string {
+	return append(append([]string(nil), result.IPs...), result.Records["CNAME"]...)
+}
+
+// randomLabel returns a label no real host is likely to have
+func randomLabel() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "wc-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,250 @@
+package tlsscan
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Certificate summarizes the leaf certificate an endpoint presents
+type Certificate struct {
+	Subject            string   `json:"subject"`
+	CommonName         string   `json:"common_name,omitempty"`
+	Issuer             string   `json:"issuer"`
+	DNSNames           []string `json:"dns_names,omitempty"`
+	IPAddresses        []string `json:"ip_addresses,omitempty"`
+	NotBefore          string   `json:"not_before"`
+	NotAfter           string   `json:"not_after"`
+	DaysLeft           int      `json:"days_left"`
+	KeyType            string   `json:"key_type"`
+	KeyBits            int      `json:"key_bits"`
+	SignatureAlgorithm string   `json:"signature_algorithm"`
+	ChainLength        int      `json:"chain_length"`
+	Expired            bool     `json:"expired"`
+	NotYetValid        bool     `json:"not_yet_valid,omitempty"`
+	SelfSigned         bool     `json:"self_signed"`
+	HostnameMismatch   bool     `json:"hostname_mismatch"`
+	Trusted            bool     `json:"trusted"`
+	TrustError         string   `json:"trust_error,omitempty"`
+
+	// Default names the product whose factory certificate this is: one
+	// shipped with an appliance or server rather than issued for the host
+	Default string `json:"default,omitempty"`
+}
+
+// defaultCertificates are markers of the certificates appliances and
+// servers generate for themselves out of the box, matched against the
+// lower-cased subject and issuer with a comma after each attribute
+var defaultCertificates = []struct{ marker, product string }{
+	{"kubernetes ingress controller fake certificate", "ingress-nginx"},
+	{"traefik default cert", "Traefik"},
+	{"cn=plesk,", "Plesk"},
+	{"o=fortinet", "FortiGate"},
+	{"o=sonicwall", "SonicWall"},
+	{"o=ubiquiti", "Ubiquiti"},
+	{"cn=ubnt,", "Ubiquiti"},
+	{"o=synology", "Synology DSM"},
+	{"o=qnap", "QNAP"},
+	{"cn=router.asus.com,", "ASUS router"},
+	{"o=mikrotik", "MikroTik"},
+	{"pfsense", "pfSense"},
+	{"o=opnsense", "OPNsense"},
+	{"o=palo alto networks", "Palo Alto Networks"},
+	{"o=vmware", "VMware"},
+	{"o=cisco", "Cisco"},
+	{"o=hewlett packard enterprise", "HPE iLO"},
+	{"o=dell inc.", "Dell iDRAC"},
+	{"cn=localhost.localdomain,", "Linux distribution"},
+	{"o=internet widgits pty ltd", "OpenSSL"},
+	{"o=acme co", "Go generate_cert"},
+	{"o=default company ltd", "OpenSSL"},
+	{"cn=localhost,", "localhost"},
+}
+
+// InspectCertificate checks the leaf of a presented chain against host
+func InspectCertificate(host string, chain []*x509.Certificate) *Certificate {
+	if len(chain) == 0 {
+		return nil
+	}
+	leaf := chain[0]
+	now := time.Now()
+
+	cert := &Certificate{
+		Subject:            leaf.Subject.String(),
+		CommonName:         leaf.Subject.CommonName,
+		Issuer:             leaf.Issuer.String(),
+		DNSNames:           leaf.DNSNames,
+		NotBefore:          leaf.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:           leaf.NotAfter.UTC().Format(time.RFC3339),
+		DaysLeft:           int(leaf.NotAfter.Sub(now).Hours() / 24),
+		SignatureAlgorithm: leaf.SignatureAlgorithm.String(),
+		ChainLength:        len(chain),
+		Expired:            now.After(leaf.NotAfter),
+		NotYetValid:        now.Before(leaf.NotBefore),
+		HostnameMismatch:   leaf.VerifyHostname(host) != nil,
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.IPAddresses = append(cert.IPAddresses, ip.String())
+	}
+	cert.KeyType, cert.KeyBits = keyInfo(leaf)
+
+	// Self-signed: issued by itself and verifiable with its own key
+	cert.SelfSigned = bytes.Equal(leaf.RawIssuer, leaf.RawSubject) && leaf.CheckSignatureFrom(leaf) == nil
+
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	cert.Trusted = err == nil
+	if err != nil {
+		cert.TrustError = err.Error()
+	}
+	// Vendors' own publicly trusted certificates share these names, so
+	// only untrusted ones are taken for factory defaults
+	if !cert.Trusted {
+		cert.Default = defaultProduct(leaf)
+	}
+	return cert
+}
+
+// defaultProduct returns the product a certificate is the factory default
+// of, or ""
+func defaultProduct(leaf *x509.Certificate) string {
+	names := strings.ToLower(leaf.Subject.String() + "," + leaf.Issuer.String() + ",")
+	for _, d := range defaultCertificates {
+		if strings.Contains(names, d.marker) {
+			return d.product
+		}
+	}
+	return ""
+}
+
+// Hostnames returns the hostnames a certificate names in its CN and SANs,
+// lower-cased and with wildcard labels removed
+func (c *Certificate) Hostnames() []string {
+	if c == nil {
+		return nil
+	}
+	return Hostnames(c.CommonName, c.DNSNames)
+}
+
+// Hostnames returns the distinct hostnames among a certificate's common
+// name and DNS SANs: "*.example.com" gives example.com, and common names
+// that are not hostnames ("Plesk", an IP address) are left out
+func Hostnames(commonName string, dnsNames []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{commonName}, dnsNames...) {
+		name = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "*."), ".")
+		if name == "" || seen[name] || !strings.Contains(name, ".") || net.ParseIP(name) != nil ||
+			strings.ContainsAny(name, " */:@") {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// keyInfo returns a certificate's public key algorithm and size
+func keyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	case *dsa.PublicKey:
+		return "DSA", key.P.BitLen()
+	default:
+		return cert.PublicKeyAlgorithm.String(), 0
+	}
+}
+
+// audit lists the issues in a scanned endpoint
+func audit(r Result) []Issue {
+	var issues []Issue
+	add := func(id, severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{ID: id, Severity: severity, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	for _, v := range r.Versions {
+		if v == "TLS1.0" || v == "TLS1.1" {
+			add("tls/deprecated-version", SeverityMedium, "%s is enabled", v)
+		}
+	}
+
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+	weak := make(map[string]bool)
+	for _, names := range r.Ciphers {
+		for _, name := range names {
+			if insecure[name] && !weak[name] {
+				weak[name] = true
+				add("tls/weak-cipher", SeverityMedium, "weak cipher suite %s is accepted", name)
+			}
+		}
+	}
+
+	issues = append(issues, CertificateIssues(r.Host, r.Certificate)...)
+	if r.Certificate != nil && !r.OCSPStapled && !r.Certificate.SelfSigned {
+		add("tls/no-ocsp-stapling", SeverityLow, "no OCSP response is stapled")
+	}
+	return issues
+}
+
+// CertificateIssues lists the problems with a certificate presented for
+// host: expiry, trust, hostname match, factory defaults and weak keys or
+// signatures
+func CertificateIssues(host string, cert *Certificate) []Issue {
+	var issues []Issue
+	add := func(id, severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{ID: id, Severity: severity, Detail: fmt.Sprintf(format, args...)})
+	}
+	if cert == nil {
+		return nil
+	}
+
+	switch {
+	case cert.Expired:
+		add("cert/expired", SeverityHigh, "certificate expired on %s", cert.NotAfter)
+	case cert.NotYetValid:
+		add("cert/not-yet-valid", SeverityHigh, "certificate is not valid until %s", cert.NotBefore)
+	case cert.DaysLeft < 30:
+		add("cert/expiring", SeverityLow, "certificate expires in %d days", cert.DaysLeft)
+	}
+	if cert.SelfSigned {
+		add("cert/self-signed", SeverityHigh, "certificate is self-signed")
+	} else if !cert.Trusted && !cert.Expired && !cert.HostnameMismatch {
+		add("cert/untrusted", SeverityHigh, "certificate chain is not trusted: %s", cert.TrustError)
+	}
+	if cert.HostnameMismatch {
+		add("cert/hostname-mismatch", SeverityHigh, "certificate does not cover %s", host)
+	}
+	if cert.Default != "" {
+		add("cert/default", SeverityMedium, "certificate is a default %s certificate", cert.Default)
+	}
+
+	switch {
+	case cert.KeyType == "RSA" && cert.KeyBits < 2048,
+		cert.KeyType == "ECDSA" && cert.KeyBits < 256,
+		cert.KeyType == "DSA":
+		add("cert/weak-key", SeverityHigh, "%s key is %d bits", cert.KeyType, cert.KeyBits)
+	}
+	if alg := strings.ToUpper(cert.SignatureAlgorithm); strings.Contains(alg, "MD5") || strings.Contains(alg, "SHA1") {
+		add("cert/weak-signature", SeverityMedium, "certificate is signed with %s", cert.SignatureAlgorithm)
+	}
+	return issues
+}
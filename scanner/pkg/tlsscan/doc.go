@@ -0,0 +1,21 @@
+// Package tlsscan audits TLS endpoints: the protocol versions and cipher
+// suites each host:port accepts, and its certificate's validity, trust,
+// hostname match, key strength and OCSP stapling. Certificates appliances
+// and servers generate for themselves out of the box are told apart as
+// factory defaults; CertificateIssues checks a certificate on its own, as
+// the HTTP prober does for every HTTPS response.
+//
+//	scanner := tlsscan.NewScanner(tlsscan.Config{
+//		Targets: []string{"example.com", "192.0.2.10:8443"},
+//	})
+//	results, err := scanner.ScanContext(ctx)
+//	for _, r := range results {
+//		for _, issue := range r.Issues {
+//			fmt.Println(r.Target, issue.Severity, issue.Detail)
+//		}
+//	}
+//
+// Handshakes use Go's TLS stack, which speaks TLS 1.0 through 1.3; SSLv3 and
+// SSLv2 cannot be detected. TLS 1.3 cipher suites are not configurable in
+// Go, so only the negotiated one is reported.
+package tlsscan
@@ -0,0 +1,304 @@
+package tlsscan
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// Config holds TLS scanner configuration
+type Config struct {
+	// Targets are host or host:port; the port defaults to 443
+	Targets []string
+
+	// Workers is how many targets are scanned at once
+	Workers int
+
+	// Timeout is the per-handshake timeout in seconds
+	Timeout int
+
+	// SkipCiphers only checks protocol versions and the certificate,
+	// skipping the one-handshake-per-suite cipher enumeration
+	SkipCiphers bool
+
+	// Scope, if set, skips out-of-scope targets
+	Scope *scope.Scope
+
+	// Budget, if set, limits handshakes to a rate shared with other modules
+	Budget *utils.Budget
+
+	// Progress, if set, counts targets scanned and those speaking TLS
+	Progress *utils.Progress
+
+	// OnResult is called for each target that completes a handshake
+	OnResult func(Result)
+}
+
+// Result is the TLS audit of one host:port
+type Result struct {
+	Target      string              `json:"target"`
+	Host        string              `json:"host"`
+	Port        int                 `json:"port"`
+	Versions    []string            `json:"versions"`
+	Ciphers     map[string][]string `json:"ciphers,omitempty"`
+	Certificate *Certificate        `json:"certificate,omitempty"`
+	OCSPStapled bool                `json:"ocsp_stapled"`
+	Issues      []Issue             `json:"issues,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+// Issue severities
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Issue is one problem found on an endpoint
+type Issue struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Detail   string `json:"detail"`
+}
+
+// versions are the protocol versions probed, oldest first
+var versions = []struct {
+	id   uint16
+	name string
+}{
+	{tls.VersionTLS10, "TLS1.0"},
+	{tls.VersionTLS11, "TLS1.1"},
+	{tls.VersionTLS12, "TLS1.2"},
+	{tls.VersionTLS13, "TLS1.3"},
+}
+
+// Scanner audits TLS endpoints
+type Scanner struct {
+	config Config
+}
+
+// NewScanner creates a new TLS scanner
+func NewScanner(config Config) *Scanner {
+	if config.Workers == 0 {
+		config.Workers = 20
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	return &Scanner{config: config}
+}
+
+// ScanContext scans every target and returns those that completed a TLS
+// handshake. If ctx is cancelled, the targets finished so far are returned
+// with ctx's error.
+func (s *Scanner) ScanContext(ctx context.Context) ([]Result, error) {
+	targets := s.config.Scope.Filter(s.config.Targets)
+	s.config.Progress.AddTotal(len(targets))
+
+	ctx = log.WithModule(ctx, "tls")
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				start := time.Now()
+				result := s.scanTarget(ctx, target)
+				if len(result.Versions) == 0 && ctx.Err() == nil {
+					log.FromContext(ctx).Info("no TLS handshake succeeded", log.Target(target), log.KeyError, result.Error, log.Since(start))
+				} else {
+					log.FromContext(ctx).Debug("target scanned", log.Target(target), "versions", result.Versions, log.Since(start))
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- target:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var scanned []Result
+	for result := range results {
+		s.config.Progress.Done()
+		if len(result.Versions) == 0 {
+			continue
+		}
+		s.config.Progress.Found()
+		metrics.Findings("tls").Inc()
+		if s.config.OnResult != nil {
+			s.config.OnResult(result)
+		}
+		scanned = append(scanned, result)
+	}
+	return scanned, ctx.Err()
+}
+
+// scanTarget probes one target's versions, ciphers and certificate
+func (s *Scanner) scanTarget(ctx context.Context, target string) Result {
+	host, port := splitTarget(target)
+	result := Result{
+		Target:    target,
+		Host:      host,
+		Port:      port,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// The newest version's handshake supplies the certificate
+	var state *tls.ConnectionState
+	var lastErr error
+	for _, v := range versions {
+		// Offer every suite so a version is not missed for want of one
+		// Go leaves out by default
+		var offered []uint16
+		for _, suite := range cipherSuites(v.id) {
+			offered = append(offered, suite.ID)
+		}
+		cs, err := s.handshake(ctx, host, port, v.id, offered)
+		if err != nil {
+			lastErr = err
+			// Nothing listening: no other version will fare better
+			var opErr *net.OpError
+			if errors.As(err, &opErr) && opErr.Op == "dial" {
+				break
+			}
+			continue
+		}
+		result.Versions = append(result.Versions, v.name)
+		state = cs
+
+		if s.config.SkipCiphers {
+			continue
+		}
+		if result.Ciphers == nil {
+			result.Ciphers = make(map[string][]string)
+		}
+		if v.id == tls.VersionTLS13 {
+			result.Ciphers[v.name] = []string{tls.CipherSuiteName(cs.CipherSuite)}
+		} else {
+			result.Ciphers[v.name] = s.ciphers(ctx, host, port, v.id)
+		}
+	}
+	if state == nil {
+		if lastErr != nil {
+			result.Error = lastErr.Error()
+		}
+		return result
+	}
+
+	result.OCSPStapled = len(state.OCSPResponse) > 0
+	result.Certificate = InspectCertificate(host, state.PeerCertificates)
+	result.Issues = audit(result)
+	return result
+}
+
+// ciphers returns the TLS 1.0-1.2 suites a server accepts for a version
+func (s *Scanner) ciphers(ctx context.Context, host string, port int, version uint16) []string {
+	var accepted []string
+	for _, suite := range cipherSuites(version) {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, err := s.handshake(ctx, host, port, version, []uint16{suite.ID}); err == nil {
+			accepted = append(accepted, suite.Name)
+		}
+	}
+	return accepted
+}
+
+// cipherSuites lists the suites Go can offer at a pre-1.3 version
+func cipherSuites(version uint16) []*tls.CipherSuite {
+	var suites []*tls.CipherSuite
+	for _, list := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range list {
+			for _, v := range suite.SupportedVersions {
+				if v == version && v != tls.VersionTLS13 {
+					suites = append(suites, suite)
+					break
+				}
+			}
+		}
+	}
+	return suites
+}
+
+// handshake connects with exactly one protocol version, and only the given
+// suites if set, returning the connection state
+func (s *Scanner) handshake(ctx context.Context, host string, port int, version uint16, suites []uint16) (*tls.ConnectionState, error) {
+	if err := s.config.Budget.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       suites,
+	}
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+
+	timeout := time.Duration(s.config.Timeout) * time.Second
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: config}
+	start := time.Now()
+	conn, err := dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	metrics.Record("tls", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	return &state, nil
+}
+
+// splitTarget splits host[:port], defaulting to port 443. URLs are reduced
+// to their host and port.
+func splitTarget(target string) (string, int) {
+	if rest, ok := strings.CutPrefix(target, "https://"); ok {
+		target = rest
+	} else if rest, ok := strings.CutPrefix(target, "http://"); ok {
+		target = rest
+	}
+	target, _, _ = strings.Cut(target, "/")
+
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return strings.Trim(target, "[]"), 443
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, 443
+	}
+	return host, port
+}
@@ -0,0 +1,484 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// PoolConfig configures a Pool
+type PoolConfig struct {
+	// Workers is how many jobs run at once (default 10)
+	Workers int
+
+	// Buffer is how many submitted jobs may wait for a worker before
+	// Submit blocks, and how many results may wait to be read (default
+	// twice Workers)
+	Buffer int
+
+	// Ordered delivers results in the order their jobs were submitted
+	// rather than as they finish. A slow job holds back the results after
+	// it, and once Workers+Buffer jobs are waiting on it, Submit blocks.
+	Ordered bool
+}
+
+// PoolResult is the outcome of one job run by a Pool
+type PoolResult[T, R any] struct {
+	Job   T
+	Value R
+	Err   error
+}
+
+// Pool runs fn over submitted jobs on a fixed number of workers, keeping
+// memory bounded however many jobs there are. Once its context is done,
+// jobs still queued are dropped without a result. Results must be read,
+// with Results or Wait, until they are closed.
+type Pool[T, R any] struct {
+	ctx     context.Context
+	fn      func(context.Context, T) (R, error)
+	ordered bool
+
+	jobs    chan poolJob[T]
+	done    chan poolDone[T, R]
+	results chan PoolResult[T, R]
+	window  chan struct{} // jobs submitted but not yet delivered, if ordered
+
+	submitMu sync.Mutex
+	next     int
+
+	errMu sync.Mutex
+	errs  []error
+}
+
+// poolJob is a submitted job and its place in submission order
+type poolJob[T any] struct {
+	index int
+	job   T
+}
+
+// poolDone is a finished or dropped job, on its way to Results
+type poolDone[T, R any] struct {
+	index   int
+	result  PoolResult[T, R]
+	dropped bool
+}
+
+// NewPool starts a pool running fn on config.Workers workers until ctx is
+// done or the pool is closed and drained
+func NewPool[T, R any](ctx context.Context, config PoolConfig, fn func(context.Context, T) (R, error)) *Pool[T, R] {
+	if config.Workers <= 0 {
+		config.Workers = 10
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = config.Workers * 2
+	}
+
+	p := &Pool[T, R]{
+		ctx:     ctx,
+		fn:      fn,
+		ordered: config.Ordered,
+		jobs:    make(chan poolJob[T], config.Buffer),
+		done:    make(chan poolDone[T, R], config.Workers),
+		results: make(chan PoolResult[T, R], config.Buffer),
+	}
+	if p.ordered {
+		p.window = make(chan struct{}, config.Workers+config.Buffer)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+	go p.deliver()
+	return p
+}
+
+// Submit queues a job, blocking while the buffer is full. It returns ctx's
+// error, without queueing the job, once the pool's or ctx's context is
+// done. It must not be called after Close.
+func (p *Pool[T, R]) Submit(ctx context.Context, job T) error {
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+
+	if p.window != nil {
+		select {
+		case p.window <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+
+	select {
+	case p.jobs <- poolJob[T]{index: p.next, job: job}:
+		p.next++
+		return nil
+	case <-ctx.Done():
+		err := ctx.Err()
+		p.release()
+		return err
+	case <-p.ctx.Done():
+		p.release()
+		return p.ctx.Err()
+	}
+}
+
+// Close marks the end of the jobs; Results is closed once every job
+// submitted has finished
+func (p *Pool[T, R]) Close() {
+	close(p.jobs)
+}
+
+// Results returns the finished jobs' results
+func (p *Pool[T, R]) Results() <-chan PoolResult[T, R] {
+	return p.results
+}
+
+// Wait reads and discards the remaining results, for pools run only for
+// their jobs' side effects, and returns Err
+func (p *Pool[T, R]) Wait() error {
+	for range p.results {
+	}
+	return p.Err()
+}
+
+// Err returns the errors of every job that failed so far, joined, or nil
+func (p *Pool[T, R]) Err() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return errors.Join(p.errs...)
+}
+
+// work runs jobs until the jobs channel is closed, dropping them once the
+// pool's context is done
+func (p *Pool[T, R]) work() {
+	for job := range p.jobs {
+		if p.ctx.Err() != nil {
+			p.done <- poolDone[T, R]{index: job.index, dropped: true}
+			continue
+		}
+
+		value, err := p.fn(p.ctx, job.job)
+		if err != nil {
+			p.errMu.Lock()
+			p.errs = append(p.errs, err)
+			p.errMu.Unlock()
+		}
+		p.done <- poolDone[T, R]{index: job.index, result: PoolResult[T, R]{Job: job.job, Value: value, Err: err}}
+	}
+}
+
+// deliver passes finished jobs to results, in submission order if the
+// pool is ordered, and closes results after the last
+func (p *Pool[T, R]) deliver() {
+	defer close(p.results)
+
+	if !p.ordered {
+		for done := range p.done {
+			if !done.dropped {
+				p.results <- done.result
+			}
+		}
+		return
+	}
+
+	pending := make(map[int]poolDone[T, R])
+	next := 0
+	for done := range p.done {
+		pending[done.index] = done
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !ready.dropped {
+				p.results <- ready.result
+			}
+			p.release()
+		}
+	}
+}
+
+// release frees a slot in the ordering window, if the pool is ordered
+func (p *Pool[T, R]) release() {
+	if p.window != nil {
+		<-p.window
+	}
+}
+
+// Next receives the next value from feed, reporting false once feed is
+// closed or ctx is done. A nil feed has no values.
+func Next[T any](ctx context.Context, feed <-chan T) (T, bool) {
+	var zero T
+	if feed == nil {
+		return zero, false
+	}
+	select {
+	case v, ok := <-feed:
+		return v, ok
+	case <-ctx.Done():
+		return zero, false
+	}
+}
+
+// Semaphore provides a simple semaphore for limiting concurrency
+type Semaphore struct {
+	ch chan struct{}
+}
+
+// NewSemaphore creates a new semaphore with n slots
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{
+		ch: make(chan struct{}, n),
+	}
+}
+
+// Acquire acquires a slot
+func (s *Semaphore) Acquire() {
+	s.ch <- struct{}{}
+}
+
+// Release releases a slot
+func (s *Semaphore) Release() {
+	<-s.ch
+}
+
+// TryAcquire tries to acquire a slot without blocking
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.ch <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// PerHostSemaphore limits concurrent operations per host
+type PerHostSemaphore struct {
+	slots map[string]chan struct{}
+	mu    sync.Mutex
+	n     int
+}
+
+// NewPerHostSemaphore creates a semaphore allowing n in-flight operations per host
+func NewPerHostSemaphore(n int) *PerHostSemaphore {
+	if n <= 0 {
+		n = 1
+	}
+	return &PerHostSemaphore{
+		slots: make(map[string]chan struct{}),
+		n:     n,
+	}
+}
+
+// Acquire blocks until a slot for host is free or ctx is done
+func (s *PerHostSemaphore) Acquire(ctx context.Context, host string) error {
+	select {
+	case s.getSlots(host) <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot for host
+func (s *PerHostSemaphore) Release(host string) {
+	<-s.getSlots(host)
+}
+
+// getSlots gets or creates the slot channel for host
+func (s *PerHostSemaphore) getSlots(host string) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.slots[host]
+	if !ok {
+		ch = make(chan struct{}, s.n)
+		s.slots[host] = ch
+	}
+	return ch
+}
+
+// WaitGroup is a wrapper around sync.WaitGroup with additional features
+type WaitGroup struct {
+	wg      sync.WaitGroup
+	count   int
+	countMu sync.Mutex
+}
+
+// Add adds to the wait group counter
+func (w *WaitGroup) Add(delta int) {
+	w.countMu.Lock()
+	w.count += delta
+	w.countMu.Unlock()
+	w.wg.Add(delta)
+}
+
+// Done decrements the wait group counter
+func (w *WaitGroup) Done() {
+	w.countMu.Lock()
+	w.count--
+	w.countMu.Unlock()
+	w.wg.Done()
+}
+
+// Wait waits for all goroutines to finish
+func (w *WaitGroup) Wait() {
+	w.wg.Wait()
+}
+
+// Count returns the current count
+func (w *WaitGroup) Count() int {
+	w.countMu.Lock()
+	defer w.countMu.Unlock()
+	return w.count
+}
+
+// FanOutConfig configures FanOutWith
+type FanOutConfig struct {
+	// Limit is how many functions run at once (0 = all of them)
+	Limit int
+
+	// FailFast cancels the context the functions share as soon as one
+	// fails, and skips those not yet started
+	FailFast bool
+
+	// JoinErrors returns every function's error, joined in the order the
+	// functions were given, instead of the first to fail. Errors of
+	// functions stopped by FailFast are left out.
+	JoinErrors bool
+}
+
+// FanOut executes functions concurrently and waits for all to complete,
+// returning the first error
+func FanOut(ctx context.Context, fns ...func(context.Context) error) error {
+	return FanOutWith(ctx, FanOutConfig{}, fns...)
+}
+
+// FanOutWith executes functions concurrently, as config says, and waits
+// for all that started to return
+func FanOutWith(ctx context.Context, config FanOutConfig, fns ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := config.Limit
+	if limit <= 0 || limit > len(fns) {
+		limit = len(fns)
+	}
+	slots := make(chan struct{}, max(limit, 1))
+	stopped := make(chan struct{}) // closed when FailFast stops the rest
+
+	var (
+		mu    sync.Mutex
+		first error
+		errs  = make([]error, len(fns))
+		wg    sync.WaitGroup
+	)
+
+start:
+	for i, fn := range fns {
+		select {
+		case slots <- struct{}{}:
+		case <-stopped:
+			break start
+		}
+		select {
+		case <-stopped:
+			break start
+		default:
+		}
+
+		wg.Add(1)
+		go func(i int, f func(context.Context) error) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := f(ctx)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			select {
+			case <-stopped:
+				// Cut short by a sibling's failure, so not a failure itself
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+			default:
+			}
+			errs[i] = err
+			if first == nil {
+				first = err
+				if config.FailFast {
+					close(stopped)
+					cancel()
+				}
+			}
+		}(i, fn)
+	}
+	wg.Wait()
+
+	if config.JoinErrors {
+		return errors.Join(errs...)
+	}
+	return first
+}
+
+// ParallelMap applies fn to each item in parallel
+func ParallelMap[T any, R any](ctx context.Context, items []T, workers int, fn func(context.Context, T) (R, error)) ([]R, error) {
+	if workers <= 0 {
+		workers = len(items)
+	}
+
+	results := make([]R, len(items))
+	errs := make(chan error, 1)
+	sem := NewSemaphore(workers)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+		go func(idx int, it T) {
+			defer wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			sem.Acquire()
+			defer sem.Release()
+
+			result, err := fn(ctx, it)
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+				return
+			}
+			results[idx] = result
+		}(i, item)
+	}
+
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+		return results, nil
+	}
+}
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// diskQueueCompactAt is how many acknowledged items a queue file collects
+// before it is rewritten with only the pending ones
+const diskQueueCompactAt = 1000
+
+// DiskQueue is a FIFO queue of JSON-encoded values kept in an append-only
+// file, so pending work survives a restart. Delivery is at least once: an
+// item popped but not acknowledged when the process stops is queued again
+// the next time the file is opened. Items are held in memory as well; the
+// file is only read on open. It is safe for concurrent use.
+type DiskQueue[T any] struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File // nil for a queue kept only in memory
+	nextID   uint64
+	queued   []QueueItem[T]
+	inFlight map[uint64]QueueItem[T]
+	acked    int // acknowledgements in the file since it was last compacted
+}
+
+// QueueItem is a value popped from a DiskQueue, with the ID to
+// acknowledge it by
+type QueueItem[T any] struct {
+	ID    uint64
+	Value T
+}
+
+// queueRecord is one line of a queue file: an item pushed, or the ID of
+// one acknowledged
+type queueRecord struct {
+	ID    uint64          `json:"id"`
+	Ack   bool            `json:"ack,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// OpenDiskQueue opens or creates the queue file at path, queueing again
+// every item it holds that was never acknowledged. A record torn by a
+// crash while being written is dropped. With an empty path the queue is
+// kept only in memory.
+func OpenDiskQueue[T any](path string) (*DiskQueue[T], error) {
+	q := &DiskQueue[T]{path: path, inFlight: make(map[uint64]QueueItem[T])}
+	if path == "" {
+		return q, nil
+	}
+
+	pending := make(map[uint64]QueueItem[T])
+	if f, err := os.Open(path); err == nil {
+		buf := bufio.NewReader(f)
+		for {
+			line, err := buf.ReadBytes('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			var record queueRecord
+			if json.Unmarshal(line, &record) != nil {
+				continue
+			}
+			q.nextID = max(q.nextID, record.ID+1)
+			if record.Ack {
+				delete(pending, record.ID)
+				continue
+			}
+			var value T
+			if json.Unmarshal(record.Value, &value) == nil {
+				pending[record.ID] = QueueItem[T]{ID: record.ID, Value: value}
+			}
+		}
+		f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, item := range pending {
+		q.queued = append(q.queued, item)
+	}
+	sort.Slice(q.queued, func(i, j int) bool { return q.queued[i].ID < q.queued[j].ID })
+
+	// Start from a file holding only the pending items
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Push appends values to the queue, returning once they are on disk
+func (q *DiskQueue[T]) Push(values ...T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var lines []byte
+	items := make([]QueueItem[T], 0, len(values))
+	for _, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		item := QueueItem[T]{ID: q.nextID, Value: value}
+		line, _ := json.Marshal(queueRecord{ID: item.ID, Value: raw})
+		lines = append(append(lines, line...), '\n')
+		items = append(items, item)
+		q.nextID++
+	}
+	if err := q.write(lines); err != nil {
+		return err
+	}
+	q.queued = append(q.queued, items...)
+	return nil
+}
+
+// Pop removes and returns up to n items from the front of the queue, or
+// every item if n <= 0. Each must be acknowledged with Ack once handled,
+// or it is delivered again after a restart.
+func (q *DiskQueue[T]) Pop(n int) []QueueItem[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n <= 0 || n > len(q.queued) {
+		n = len(q.queued)
+	}
+	items := append([]QueueItem[T](nil), q.queued[:n]...)
+	q.queued = q.queued[n:]
+	for _, item := range items {
+		q.inFlight[item.ID] = item
+	}
+	return items
+}
+
+// Ack marks popped items handled, so they are not delivered again
+func (q *DiskQueue[T]) Ack(ids ...uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var lines []byte
+	for _, id := range ids {
+		if _, ok := q.inFlight[id]; !ok {
+			continue
+		}
+		line, _ := json.Marshal(queueRecord{ID: id, Ack: true})
+		lines = append(append(lines, line...), '\n')
+	}
+	if err := q.write(lines); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := q.inFlight[id]; ok {
+			delete(q.inFlight, id)
+			q.acked++
+		}
+	}
+
+	if q.acked >= diskQueueCompactAt {
+		return q.compact()
+	}
+	return nil
+}
+
+// Requeue puts popped items that were not handled back at the front of
+// the queue
+func (q *DiskQueue[T]) Requeue(ids ...uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []QueueItem[T]
+	for _, id := range ids {
+		if item, ok := q.inFlight[id]; ok {
+			delete(q.inFlight, id)
+			items = append(items, item)
+		}
+	}
+	q.queued = append(items, q.queued...)
+}
+
+// Items returns the queued items, oldest first, without popping them
+func (q *DiskQueue[T]) Items() []QueueItem[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]QueueItem[T](nil), q.queued...)
+}
+
+// Len returns how many items are queued, not counting those popped but
+// not yet acknowledged
+func (q *DiskQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// Close closes the queue's file; unacknowledged items stay in it
+func (q *DiskQueue[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// write appends records to the file and syncs it. The caller holds mu.
+func (q *DiskQueue[T]) write(lines []byte) error {
+	if q.file == nil || len(lines) == 0 {
+		return nil
+	}
+	if _, err := q.file.Write(lines); err != nil {
+		// Rewrite the file so a torn record cannot swallow the next one
+		q.compact()
+		return err
+	}
+	return q.file.Sync()
+}
+
+// compact rewrites the file with only the items not yet acknowledged,
+// popped or not, and reopens it for appending. The caller holds mu, or
+// the queue is not yet shared.
+func (q *DiskQueue[T]) compact() error {
+	if q.path == "" {
+		return nil
+	}
+
+	live := append([]QueueItem[T](nil), q.queued...)
+	for _, item := range q.inFlight {
+		live = append(live, item)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+
+	var lines []byte
+	for _, item := range live {
+		raw, err := json.Marshal(item.Value)
+		if err != nil {
+			return err
+		}
+		line, _ := json.Marshal(queueRecord{ID: item.ID, Value: raw})
+		lines = append(append(lines, line...), '\n')
+	}
+
+	// Write then rename so a crash never leaves a truncated queue
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, lines, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if q.file != nil {
+		q.file.Close()
+	}
+	q.file = file
+	q.acked = 0
+	return nil
+}
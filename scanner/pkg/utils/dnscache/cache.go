@@ -0,0 +1,387 @@
+package dnscache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Config configures a Cache
+type Config struct {
+	// Resolvers are the recursive DNS servers asked, in turn, as IP:port;
+	// default: the nameservers in /etc/resolv.conf, else public resolvers
+	Resolvers []string
+
+	// Timeout bounds each query (default 5s)
+	Timeout time.Duration
+
+	// MinTTL and MaxTTL clamp how long answers are kept, whatever their
+	// records say (defaults 5s and 1h)
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	// NegativeTTL is how long a name without addresses is remembered when
+	// the zone's SOA record does not say (default 30s)
+	NegativeTTL time.Duration
+}
+
+// Cache resolves hostnames to addresses, keeping each answer until its
+// TTL runs out. It is safe for concurrent use, and a nil *Cache resolves
+// every name with the system resolver instead.
+type Cache struct {
+	config Config
+	next   atomic.Uint32
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	inflight map[string]*call
+}
+
+// entry is a cached answer, or the error a name resolved to
+type entry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// call is a lookup in progress that other callers wait on
+type call struct {
+	done  chan struct{}
+	addrs []string
+	err   error
+}
+
+// sweepEvery is how many new entries are cached between sweeps of the
+// expired ones
+const sweepEvery = 1024
+
+// New creates a DNS cache
+func New(config Config) *Cache {
+	if len(config.Resolvers) == 0 {
+		config.Resolvers = systemResolvers()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.MinTTL == 0 {
+		config.MinTTL = 5 * time.Second
+	}
+	if config.MaxTTL == 0 {
+		config.MaxTTL = time.Hour
+	}
+	if config.NegativeTTL == 0 {
+		config.NegativeTTL = 30 * time.Second
+	}
+	return &Cache{
+		config:   config,
+		entries:  make(map[string]entry),
+		inflight: make(map[string]*call),
+	}
+}
+
+// systemResolvers returns the nameservers in /etc/resolv.conf, or public
+// resolvers if there are none
+func systemResolvers() []string {
+	var servers []string
+	if f, err := os.Open("/etc/resolv.conf"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+		f.Close()
+	}
+	if len(servers) == 0 {
+		servers = []string{"8.8.8.8:53", "1.1.1.1:53"}
+	}
+	return servers
+}
+
+// LookupHost returns the addresses of host, IPv4 first. Concurrent
+// lookups of one name wait on a single query, and its answer is reused
+// until it expires.
+func (c *Cache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if c == nil {
+		return net.DefaultResolver.LookupHost(ctx, host)
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	c.mu.Lock()
+	if e, ok := c.entries[name]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.addrs, e.err
+	}
+	if pending, ok := c.inflight[name]; ok {
+		c.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.addrs, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	lookup := &call{done: make(chan struct{})}
+	c.inflight[name] = lookup
+	c.mu.Unlock()
+
+	// Others share the answer, so one caller giving up must not cut the
+	// query short
+	addrs, ttl, err := c.resolve(context.WithoutCancel(ctx), name)
+
+	c.mu.Lock()
+	delete(c.inflight, name)
+	if ttl > 0 {
+		c.entries[name] = entry{addrs: addrs, err: err, expires: time.Now().Add(ttl)}
+		if len(c.entries)%sweepEvery == 0 {
+			c.sweep()
+		}
+	}
+	c.mu.Unlock()
+
+	lookup.addrs, lookup.err = addrs, err
+	close(lookup.done)
+	return addrs, err
+}
+
+// sweep drops expired entries; c.mu must be held
+func (c *Cache) sweep() {
+	now := time.Now()
+	for name, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// DialContext returns a dial function, for http.Transport.DialContext and
+// the like, that resolves names through the cache and dials each address
+// with dialer in turn until one connects
+func (c *Cache) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if c == nil || err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+		var firstErr error
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if (strings.HasSuffix(network, "4") && ip.To4() == nil) || (strings.HasSuffix(network, "6") && ip.To4() != nil) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if firstErr == nil {
+			firstErr = &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+		}
+		return nil, firstErr
+	}
+}
+
+// answer is what one query for a name's A or AAAA records returned
+type answer struct {
+	addrs []string
+	ttl   time.Duration // of the answer, or of the SOA if there is none
+	err   error
+}
+
+// resolve queries a name's A and AAAA records together, returning the
+// addresses and how long to cache them. Names without addresses are
+// cached as a not-found error; failed queries are not cached.
+func (c *Cache) resolve(ctx context.Context, name string) ([]string, time.Duration, error) {
+	var v4, v6 answer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); v4 = c.query(ctx, name, dnsmessage.TypeA) }()
+	go func() { defer wg.Done(); v6 = c.query(ctx, name, dnsmessage.TypeAAAA) }()
+	wg.Wait()
+
+	addrs := append(v4.addrs, v6.addrs...)
+	if len(addrs) > 0 {
+		ttl := c.config.MaxTTL
+		for _, a := range []answer{v4, v6} {
+			if len(a.addrs) > 0 {
+				ttl = min(ttl, a.ttl)
+			}
+		}
+		return addrs, c.clamp(ttl), nil
+	}
+
+	for _, a := range []answer{v4, v6} {
+		if a.err != nil {
+			return nil, 0, &net.DNSError{Err: a.err.Error(), Name: name, IsTemporary: true}
+		}
+	}
+	ttl := c.config.NegativeTTL
+	if v4.ttl > 0 || v6.ttl > 0 {
+		ttl = max(v4.ttl, v6.ttl)
+	}
+	return nil, c.clamp(ttl), &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// clamp keeps a TTL between MinTTL and MaxTTL
+func (c *Cache) clamp(ttl time.Duration) time.Duration {
+	return min(max(ttl, c.config.MinTTL), c.config.MaxTTL)
+}
+
+// query asks the resolvers in turn for one type of a name's records,
+// trying another resolver if one fails
+func (c *Cache) query(ctx context.Context, name string, qtype dnsmessage.Type) answer {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return answer{err: err}
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		return answer{err: err}
+	}
+
+	var a answer
+	for attempt := 0; attempt < min(2, len(c.config.Resolvers)); attempt++ {
+		server := c.config.Resolvers[int(c.next.Add(1))%len(c.config.Resolvers)]
+		reply, err := c.exchange(ctx, "udp", server, msg.ID, packet)
+		if err == nil && reply.Truncated {
+			reply, err = c.exchange(ctx, "tcp", server, msg.ID, packet)
+		}
+		if err != nil {
+			a = answer{err: err}
+			continue
+		}
+		if a = parseAnswer(reply); a.err == nil {
+			return a
+		}
+	}
+	return a
+}
+
+// parseAnswer reads the addresses of a reply and the TTL to cache them
+// for: the shortest in the answer section, or for a name without
+// addresses, the SOA's negative caching TTL
+func parseAnswer(reply *dnsmessage.Message) answer {
+	switch reply.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return answer{ttl: soaTTL(reply)}
+	default:
+		return answer{err: fmt.Errorf("server replied %s", reply.RCode)}
+	}
+
+	var a answer
+	for _, rr := range reply.Answers {
+		ttl := time.Duration(rr.Header.TTL) * time.Second
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			a.addrs = append(a.addrs, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			a.addrs = append(a.addrs, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+		default:
+			continue
+		}
+		// A CNAME in the chain expiring ends the answer too
+		if a.ttl == 0 || ttl < a.ttl {
+			a.ttl = ttl
+		}
+	}
+	if len(a.addrs) == 0 {
+		return answer{ttl: soaTTL(reply)}
+	}
+	return a
+}
+
+// soaTTL returns how long a reply saying a name has no records may be
+// cached, from the SOA in its authority section, or 0 if there is none
+func soaTTL(reply *dnsmessage.Message) time.Duration {
+	for _, rr := range reply.Authorities {
+		if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+			return time.Duration(min(rr.Header.TTL, soa.MinTTL)) * time.Second
+		}
+	}
+	return 0
+}
+
+// exchange sends a query to a server over UDP or TCP and waits for the
+// reply to it
+func (c *Cache) exchange(ctx context.Context, network, server string, id uint16, packet []byte) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(packet)))
+		if _, err := conn.Write(append(framed, packet...)); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+		var reply dnsmessage.Message
+		if err := reply.Unpack(buf); err != nil {
+			return nil, err
+		}
+		return &reply, nil
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var reply dnsmessage.Message
+		if err := reply.Unpack(buf[:n]); err != nil || reply.ID != id || !reply.Response {
+			continue
+		}
+		return &reply, nil
+	}
+}
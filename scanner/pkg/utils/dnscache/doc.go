@@ -0,0 +1,5 @@
+// Package dnscache resolves hostnames for the scanner's dialers and caches
+// the answers for as long as their records' TTLs allow, so thousands of
+// connections to one host cost one lookup. Names that do not resolve are
+// cached too, and concurrent lookups of one name share a single query.
+package dnscache
@@ -0,0 +1,4 @@
+// Package utils holds the helpers the scanner modules share: rate limiters
+// and cross-module budgets, progress counters, retries, worker pools, a
+// disk-backed job queue and proxy-aware HTTP transports.
+package utils
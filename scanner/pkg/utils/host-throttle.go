@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Host throttle actions, as returned by HostThrottle.Record
+const (
+	ThrottleSlowed = "slowed"
+	ThrottlePaused = "paused"
+)
+
+// ThrottleConfig configures a HostThrottle
+type ThrottleConfig struct {
+	// Window is how many of a host's latest responses are weighed (default
+	// 20); a host is judged once half of them are in
+	Window int
+
+	// Threshold is the share of those responses that must look blocked for
+	// the host to be slowed down (default 0.5)
+	Threshold float64
+
+	// Rate is the requests per second a host is cut to when it first looks
+	// blocked (default 5); each further burst halves it, down to MinRate
+	// (default 0.5)
+	Rate    float64
+	MinRate float64
+
+	// Pause stops requests to a host for Cooldown when it still looks
+	// blocked at MinRate
+	Pause bool
+
+	// Cooldown is how long a slowed host must go without another burst
+	// before its rate doubles back toward unlimited, and how long a pause
+	// lasts (default 30s)
+	Cooldown time.Duration
+}
+
+// HostThrottle slows down hosts that look like they are blocking: when
+// enough of a host's recent responses are 429s, 403s, 5xx or connection
+// resets, its rate is cut, and halved again on each further burst, and at
+// the slowest it may be paused. After a cooldown without a burst the rate
+// doubles back until the host is unthrottled. Hosts start unthrottled.
+// All methods are safe to call on a nil *HostThrottle, which never waits.
+type HostThrottle struct {
+	config ThrottleConfig
+	mu     sync.Mutex
+	hosts  map[string]*hostThrottle
+}
+
+// hostThrottle is one host's recent responses and rate
+type hostThrottle struct {
+	outcomes    []bool        // latest responses, true if blocked
+	limiter     *rate.Limiter // nil while unthrottled
+	changed     time.Time     // when the rate last moved
+	pausedUntil time.Time
+}
+
+// NewHostThrottle creates a throttle that starts every host unthrottled
+func NewHostThrottle(config ThrottleConfig) *HostThrottle {
+	if config.Window <= 0 {
+		config.Window = 20
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = 0.5
+	}
+	if config.Rate <= 0 {
+		config.Rate = 5
+	}
+	if config.MinRate <= 0 {
+		config.MinRate = 0.5
+	}
+	if config.MinRate > config.Rate {
+		config.MinRate = config.Rate
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	return &HostThrottle{config: config, hosts: make(map[string]*hostThrottle)}
+}
+
+// Cooldown returns how long a host stays slowed or paused without a burst
+func (t *HostThrottle) Cooldown() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.config.Cooldown
+}
+
+// Wait blocks while host is paused, then until its rate allows one more
+// request or ctx is done
+func (t *HostThrottle) Wait(ctx context.Context, host string) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	var limiter *rate.Limiter
+	var pause time.Duration
+	if h := t.hosts[host]; h != nil {
+		t.recover(h, time.Now())
+		limiter = h.limiter
+		pause = time.Until(h.pausedUntil)
+	}
+	t.mu.Unlock()
+
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// Record notes whether a response from host looked blocked, and returns
+// ThrottleSlowed or ThrottlePaused if that burst slowed or paused the
+// host, or "" if its rate stands
+func (t *HostThrottle) Record(host string, blocked bool) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.hosts[host]
+	if h == nil {
+		h = &hostThrottle{}
+		t.hosts[host] = h
+	}
+	h.outcomes = append(h.outcomes, blocked)
+	if len(h.outcomes) > t.config.Window {
+		h.outcomes = h.outcomes[1:]
+	}
+	if len(h.outcomes) < max(1, t.config.Window/2) {
+		return ""
+	}
+	n := 0
+	for _, b := range h.outcomes {
+		if b {
+			n++
+		}
+	}
+	if float64(n) < t.config.Threshold*float64(len(h.outcomes)) {
+		return ""
+	}
+
+	// Responses to requests sent before this burst are not held against
+	// the new rate
+	now := time.Now()
+	h.outcomes = nil
+	h.changed = now
+	switch {
+	case h.limiter == nil:
+		h.limiter = rate.NewLimiter(rate.Limit(t.config.Rate), 1)
+		return ThrottleSlowed
+	case float64(h.limiter.Limit()) > t.config.MinRate:
+		h.limiter.SetLimit(rate.Limit(max(t.config.MinRate, float64(h.limiter.Limit())/2)))
+		return ThrottleSlowed
+	case t.config.Pause:
+		h.pausedUntil = now.Add(t.config.Cooldown)
+		h.changed = h.pausedUntil
+		return ThrottlePaused
+	}
+	return ""
+}
+
+// Rate returns host's requests per second, or 0 if it is unthrottled
+func (t *HostThrottle) Rate(host string) float64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if h := t.hosts[host]; h != nil && h.limiter != nil {
+		return float64(h.limiter.Limit())
+	}
+	return 0
+}
+
+// recover doubles a slowed host's rate once a cooldown has passed since it
+// last moved, lifting it altogether above Rate. The caller holds mu.
+func (t *HostThrottle) recover(h *hostThrottle, now time.Time) {
+	if h.limiter == nil || now.Sub(h.changed) < t.config.Cooldown {
+		return
+	}
+	h.changed = now
+	next := float64(h.limiter.Limit()) * 2
+	if next > t.config.Rate {
+		h.limiter = nil
+		return
+	}
+	h.limiter.SetLimit(rate.Limit(next))
+}
+
+// BlockedResponse reports whether a response's status, or the error
+// instead of one, looks like a host refusing or shedding requests: 429,
+// 403, a 5xx or a connection reset
+func BlockedResponse(status int, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET) || strings.Contains(err.Error(), "connection reset")
+	}
+	return status == http.StatusTooManyRequests || status == http.StatusForbidden || status >= 500
+}
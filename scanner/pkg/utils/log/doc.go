@@ -0,0 +1,6 @@
+// Package log is the scanner's structured debug log, built on log/slog.
+// Modules take their logger from the context they run under, tagged with
+// the module's name, and log why a source or host produced nothing: the
+// errors they otherwise swallow, empty answers and how long each took.
+// Until a command turns it on the log discards everything.
+package log
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LevelOff is above every level, so a logger at it logs nothing
+const LevelOff = slog.Level(math.MaxInt32)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Field names the modules share, so one filter finds a module's or a
+// target's lines whichever module wrote them
+const (
+	KeyModule   = "module"
+	KeyTarget   = "target"
+	KeyDuration = "duration"
+	KeyError    = "error"
+)
+
+// New returns a logger writing lines at level and above to w as text
+// (key=value) or JSON
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
+
+// ParseLevel reads a level name: debug, info, warn, error or off
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "off", "none", "":
+		return LevelOff, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, error or off)", name)
+}
+
+// discard is the logger used until SetDefault is called
+var discard = slog.New(discardHandler{})
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// SetDefault sets the logger FromContext returns for contexts without one
+func SetDefault(l *slog.Logger) {
+	defaultLogger.Store(l)
+}
+
+// Default returns the logger set by SetDefault, or one that discards
+// everything
+func Default() *slog.Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	return discard
+}
+
+type (
+	contextKey struct{}
+	moduleKey  struct{}
+)
+
+// WithContext returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger ctx carries, or the default
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return Default()
+}
+
+// Module returns ctx's logger tagged with a module's name
+func Module(ctx context.Context, name string) *slog.Logger {
+	return FromContext(ctx).With(KeyModule, name)
+}
+
+// WithModule returns a copy of ctx whose logger is tagged with a module's
+// name, for a module's entry point to pass to the code it calls
+func WithModule(ctx context.Context, name string) context.Context {
+	return context.WithValue(WithContext(ctx, Module(ctx, name)), moduleKey{}, name)
+}
+
+// ModuleOf returns the module name WithModule gave ctx, or ""
+func ModuleOf(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(moduleKey{}).(string)
+	return name
+}
+
+// Target is the host, URL or domain a line is about
+func Target(target string) slog.Attr {
+	return slog.String(KeyTarget, target)
+}
+
+// Duration is how long the work a line reports took, to the millisecond
+func Duration(d time.Duration) slog.Attr {
+	return slog.Duration(KeyDuration, d.Round(time.Millisecond))
+}
+
+// Since is the Duration since start
+func Since(start time.Time) slog.Attr {
+	return Duration(time.Since(start))
+}
+
+// Err is the error a line reports
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// discardHandler drops every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
@@ -0,0 +1,6 @@
+// Package metrics counts what the scanner modules do: requests sent,
+// timeouts and other errors, bytes read, findings and how long requests
+// took. Every module records into one process-wide registry, labelled
+// with its name, which the run summary and the daemon's /metrics endpoint
+// (in the Prometheus text format) render.
+package metrics
@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metric kinds, as the Prometheus TYPE line names them
+const (
+	KindCounter   = "counter"
+	KindGauge     = "gauge"
+	KindHistogram = "histogram"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the request
+// duration histogram
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry holds named metrics, each a family of series told apart by
+// their labels. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is one metric name: its kind, help text and series by labels
+type family struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	series  map[string]interface{} // *Counter, *Gauge or *Histogram
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Default is the registry every module records into
+var Default = NewRegistry()
+
+// Counter returns the counter called name with the given label pairs
+// ("module", "probe"), creating it on first use. A name already used for
+// another kind of metric returns nil, which counts nothing.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c, _ := r.get(name, help, KindCounter, nil, labels, func(*family) interface{} { return &Counter{} }).(*Counter)
+	return c
+}
+
+// Gauge returns the gauge called name with the given label pairs
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g, _ := r.get(name, help, KindGauge, nil, labels, func(*family) interface{} { return &Gauge{} }).(*Gauge)
+	return g
+}
+
+// Histogram returns the histogram called name with the given label
+// pairs. The buckets of its first use apply to every series of the name.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h, _ := r.get(name, help, KindHistogram, buckets, labels, func(f *family) interface{} { return newHistogram(f.buckets) }).(*Histogram)
+	return h
+}
+
+// get returns the series of a family, creating either as needed
+func (r *Registry) get(name, help, kind string, buckets []float64, labels []string, create func(*family) interface{}) interface{} {
+	if r == nil {
+		return nil
+	}
+	key := labelString(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		if kind == KindHistogram && len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+		f = &family{name: name, help: help, kind: kind, buckets: buckets, series: make(map[string]interface{})}
+		r.families[name] = f
+	}
+	if f.kind != kind {
+		return nil
+	}
+	s, ok := f.series[key]
+	if !ok {
+		s = create(f)
+		f.series[key] = s
+	}
+	return s
+}
+
+// labelString renders label pairs as Prometheus does, {a="x",b="y"}, or
+// "" if there are none
+func labelString(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a count that only goes up. A nil *Counter counts nothing.
+type Counter struct {
+	value atomic.Int64
+}
+
+// Inc adds one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds n, which must not be negative
+func (c *Counter) Add(n int64) {
+	if c == nil || n < 0 {
+		return
+	}
+	c.value.Add(n)
+}
+
+// Value returns the count
+func (c *Counter) Value() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.value.Load()
+}
+
+// Gauge is a value that goes up and down, such as requests in flight. A
+// nil *Gauge records nothing.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the value
+func (g *Gauge) Set(v float64) {
+	if g == nil {
+		return
+	}
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Add adds delta, which may be negative
+func (g *Gauge) Add(delta float64) {
+	if g == nil {
+		return
+	}
+	for {
+		old := g.bits.Load()
+		if g.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Value returns the value
+func (g *Gauge) Value() float64 {
+	if g == nil {
+		return 0
+	}
+	return math.Float64frombits(g.bits.Load())
+}
+
+// Histogram counts observations into buckets by upper bound, and keeps
+// their count and sum. A nil *Histogram records nothing.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64 // per bucket, not cumulative
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{buckets: sorted, counts: make([]uint64, len(sorted))}
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64) {
+	if h == nil {
+		return
+	}
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// ObserveDuration records a duration in seconds
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Since records the seconds since start
+func (h *Histogram) Since(start time.Time) {
+	h.ObserveDuration(time.Since(start))
+}
+
+// snapshot returns the cumulative bucket counts, count and sum
+func (h *Histogram) snapshot() ([]uint64, uint64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i, n := range h.counts {
+		total += n
+		cumulative[i] = total
+	}
+	return cumulative, h.count, h.sum
+}
+
+// sortedFamilies returns the families in name order
+func (r *Registry) sortedFamilies() []*family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	return families
+}
+
+// keys returns a family's label sets in order, and the series of each
+func (r *Registry) keys(f *family) ([]string, []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]interface{}, len(keys))
+	for i, key := range keys {
+		series[i] = f.series[key]
+	}
+	return keys, series
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition
+// format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+	var b strings.Builder
+	for _, f := range r.sortedFamilies() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, strings.ReplaceAll(f.help, "\n", " "), f.name, f.kind)
+		keys, series := r.keys(f)
+		for i, key := range keys {
+			switch s := series[i].(type) {
+			case *Counter:
+				fmt.Fprintf(&b, "%s%s %d\n", f.name, key, s.Value())
+			case *Gauge:
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, key, formatFloat(s.Value()))
+			case *Histogram:
+				cumulative, count, sum := s.snapshot()
+				for j, bound := range s.buckets {
+					fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, withLabel(key, "le", formatFloat(bound)), cumulative[j])
+				}
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, withLabel(key, "le", "+Inf"), count)
+				fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, key, formatFloat(sum))
+				fmt.Fprintf(&b, "%s_count%s %d\n", f.name, key, count)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Snapshot returns every series' current value by name and labels, as
+// WritePrometheus names them. Histograms give their _count and _sum, and
+// series still at zero are left out.
+func (r *Registry) Snapshot() map[string]float64 {
+	if r == nil {
+		return nil
+	}
+	values := make(map[string]float64)
+	for _, f := range r.sortedFamilies() {
+		keys, series := r.keys(f)
+		for i, key := range keys {
+			switch s := series[i].(type) {
+			case *Counter:
+				if v := s.Value(); v != 0 {
+					values[f.name+key] = float64(v)
+				}
+			case *Gauge:
+				if v := s.Value(); v != 0 {
+					values[f.name+key] = v
+				}
+			case *Histogram:
+				if _, count, sum := s.snapshot(); count > 0 {
+					values[f.name+"_count"+key] = float64(count)
+					values[f.name+"_sum"+key] = sum
+				}
+			}
+		}
+	}
+	return values
+}
+
+// withLabel adds a label to a rendered label set
+func withLabel(key, name, value string) string {
+	label := name + "=" + strconv.Quote(value)
+	if key == "" {
+		return "{" + label + "}"
+	}
+	return strings.TrimSuffix(key, "}") + "," + label + "}"
+}
+
+// formatFloat renders a value as Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// Names of the metrics every module records, labelled by module
+const (
+	NameRequests  = "scanner_requests_total"
+	NameErrors    = "scanner_request_errors_total"
+	NameTimeouts  = "scanner_timeouts_total"
+	NameBytesRead = "scanner_bytes_read_total"
+	NameFindings  = "scanner_findings_total"
+	NameDuration  = "scanner_request_duration_seconds"
+	NameInFlight  = "scanner_requests_in_flight"
+)
+
+// Requests counts a module's requests: HTTP requests, DNS queries, SNMP
+// gets, TLS handshakes and connection attempts
+func Requests(module string) *Counter {
+	return Default.Counter(NameRequests, "Requests sent, by module", "module", module)
+}
+
+// Errors counts a module's requests that failed for any reason
+func Errors(module string) *Counter {
+	return Default.Counter(NameErrors, "Requests that failed, timeouts included, by module", "module", module)
+}
+
+// Timeouts counts a module's requests that timed out
+func Timeouts(module string) *Counter {
+	return Default.Counter(NameTimeouts, "Requests that timed out, by module", "module", module)
+}
+
+// BytesRead counts the response bytes a module read
+func BytesRead(module string) *Counter {
+	return Default.Counter(NameBytesRead, "Response bytes read, by module", "module", module)
+}
+
+// Findings counts the results a module reported
+func Findings(module string) *Counter {
+	return Default.Counter(NameFindings, "Results reported, by module", "module", module)
+}
+
+// Duration is how long a module's requests took to be answered
+func Duration(module string) *Histogram {
+	return Default.Histogram(NameDuration, "Time to a response, by module", DefaultBuckets, "module", module)
+}
+
+// InFlight is how many of a module's requests are waiting on an answer
+func InFlight(module string) *Gauge {
+	return Default.Gauge(NameInFlight, "Requests waiting on a response, by module", "module", module)
+}
+
+// Record counts one finished request of a module: its duration and, if
+// it failed, the error and whether it was a timeout
+func Record(module string, start time.Time, err error) {
+	Requests(module).Inc()
+	Duration(module).Since(start)
+	if err != nil {
+		Errors(module).Inc()
+		if IsTimeout(err) {
+			Timeouts(module).Inc()
+		}
+	}
+}
+
+// IsTimeout reports whether err is a deadline or network timeout
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ModuleOf returns the module a request's context is tagged with, or
+// fallback
+func ModuleOf(ctx context.Context, fallback string) string {
+	if module := log.ModuleOf(ctx); module != "" {
+		return module
+	}
+	return fallback
+}
+
+// Transport wraps an HTTP transport to record every request under the
+// module its context is tagged with (see log.WithModule), or fallback
+func Transport(next http.RoundTripper, fallback string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, fallback: fallback}
+}
+
+type transport struct {
+	next     http.RoundTripper
+	fallback string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	module := ModuleOf(req.Context(), t.fallback)
+	inFlight := InFlight(module)
+	inFlight.Add(1)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	inFlight.Add(-1)
+	Record(module, start, err)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, read: BytesRead(module)}
+	return resp, nil
+}
+
+// CloseIdleConnections passes on to the wrapped transport, so
+// http.Client.CloseIdleConnections still works
+func (t *transport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// countingBody counts the bytes read from a response body
+type countingBody struct {
+	io.ReadCloser
+	read *Counter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress periodically writes a one-line status (done/total, findings,
+// throughput and ETA) for a long-running scan. All methods are safe to call
+// on a nil *Progress, so modules can report unconditionally.
+type Progress struct {
+	label    string
+	noun     string
+	w        io.Writer
+	interval time.Duration
+
+	total atomic.Int64
+	done  atomic.Int64
+	found atomic.Int64
+
+	start time.Time
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewProgress creates a progress reporter; noun names what Found counts
+// (e.g. "open", "live"). With a nil writer it only counts, for callers that
+// read Snapshot themselves.
+func NewProgress(label, noun string, w io.Writer) *Progress {
+	return &Progress{
+		label:    label,
+		noun:     noun,
+		w:        w,
+		interval: time.Second,
+	}
+}
+
+// Start begins periodic reporting
+func (p *Progress) Start() {
+	if p == nil {
+		return
+	}
+
+	p.start = time.Now()
+	p.stop = make(chan struct{})
+	if p.w == nil {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				fmt.Fprintf(p.w, "\r\033[K%s", p.line())
+			}
+		}
+	}()
+}
+
+// Stop halts reporting and writes the final status line
+func (p *Progress) Stop() {
+	if p == nil || p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	p.wg.Wait()
+	if p.w != nil {
+		fmt.Fprintf(p.w, "\r\033[K%s\n", p.line())
+	}
+}
+
+// AddTotal grows the amount of expected work
+func (p *Progress) AddTotal(n int) {
+	if p == nil {
+		return
+	}
+	p.total.Add(int64(n))
+}
+
+// Done records one finished unit of work
+func (p *Progress) Done() {
+	if p == nil {
+		return
+	}
+	p.done.Add(1)
+}
+
+// Found records one finding
+func (p *Progress) Found() {
+	if p == nil {
+		return
+	}
+	p.found.Add(1)
+}
+
+// ProgressStats is a point-in-time view of a Progress
+type ProgressStats struct {
+	Label   string
+	Noun    string
+	Done    int64
+	Total   int64
+	Found   int64
+	Percent float64
+	Rate    float64 // units per second
+	ETA     string
+}
+
+// Snapshot returns the current counters, rate and ETA
+func (p *Progress) Snapshot() ProgressStats {
+	if p == nil {
+		return ProgressStats{}
+	}
+
+	stats := ProgressStats{
+		Label: p.label,
+		Noun:  p.noun,
+		Done:  p.done.Load(),
+		Total: p.total.Load(),
+		Found: p.found.Load(),
+		ETA:   "--",
+	}
+
+	if stats.Total > 0 {
+		stats.Percent = float64(stats.Done) / float64(stats.Total) * 100
+	}
+	if elapsed := time.Since(p.start); elapsed > 0 {
+		stats.Rate = float64(stats.Done) / elapsed.Seconds()
+	}
+
+	if stats.Rate > 0 && stats.Total > stats.Done {
+		remaining := time.Duration(float64(stats.Total-stats.Done) / stats.Rate * float64(time.Second))
+		stats.ETA = remaining.Round(time.Second).String()
+	} else if stats.Total > 0 && stats.Done >= stats.Total {
+		stats.ETA = "0s"
+	}
+	return stats
+}
+
+// line renders the current status
+func (p *Progress) line() string {
+	s := p.Snapshot()
+	return fmt.Sprintf("[%s] %d/%d (%.1f%%) | %s: %d | %.1f req/s | ETA %s",
+		s.Label, s.Done, s.Total, s.Percent, s.Noun, s.Found, s.Rate, s.ETA)
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ParseProxy validates an HTTP, HTTPS or SOCKS5 proxy URL. An empty string
+// returns nil, meaning connect directly.
+func ParseProxy(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https, socks5 or socks5h", raw)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+	return proxyURL, nil
+}
+
+// ProxyFunc returns a Transport.Proxy function routing every request through
+// the proxy, or nil for direct connections. Invalid proxies are treated as
+// direct; callers should validate with ParseProxy first.
+func ProxyFunc(raw string) func(*http.Request) (*url.URL, error) {
+	proxyURL, err := ParseProxy(raw)
+	if err != nil || proxyURL == nil {
+		return nil
+	}
+	return http.ProxyURL(proxyURL)
+}
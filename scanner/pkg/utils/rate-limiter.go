@@ -0,0 +1,580 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limiting algorithms for NewRateLimiterAlgorithm
+const (
+	// RateTokenBucket refills up to burst tokens at the rate, so after an
+	// idle spell burst requests go out back to back
+	RateTokenBucket = "token-bucket"
+
+	// RateSlidingWindow keeps a log of when requests were sent and allows
+	// at most burst in any window of burst/rate seconds. A token bucket
+	// lets nearly twice that through a window straddling its refill.
+	RateSlidingWindow = "sliding-window"
+
+	// RateLeakyBucket spaces requests evenly at the rate and never bursts,
+	// for WAFs that count requests arriving back to back; burst is ignored
+	RateLeakyBucket = "leaky-bucket"
+)
+
+// RateLimiter limits requests to a rate, by default with a token bucket
+type RateLimiter struct {
+	algorithm  string
+	rate       float64 // tokens per second
+	burst      int     // max burst size
+	tokens     float64
+	lastUpdate time.Time
+	sent       []time.Time // sliding window log, in time order
+	next       time.Time   // earliest time the leaky bucket lets one out
+	mu         sync.Mutex
+}
+
+// NewRateLimiter creates a new token bucket rate limiter
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	if ratePerSecond <= 0 {
+		ratePerSecond = 10
+	}
+	if burst <= 0 {
+		burst = int(ratePerSecond)
+	}
+
+	return &RateLimiter{
+		algorithm:  RateTokenBucket,
+		rate:       ratePerSecond,
+		burst:      max(1, burst),
+		tokens:     float64(burst),
+		lastUpdate: time.Now(),
+	}
+}
+
+// NewRateLimiterAlgorithm creates a rate limiter using one of the
+// Rate* algorithms (default token bucket)
+func NewRateLimiterAlgorithm(algorithm string, ratePerSecond float64, burst int) (*RateLimiter, error) {
+	if err := checkRateAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	rl := NewRateLimiter(ratePerSecond, burst)
+	if algorithm != "" {
+		rl.algorithm = algorithm
+	}
+	return rl, nil
+}
+
+// checkRateAlgorithm returns an error for names that are not a Rate*
+// algorithm
+func checkRateAlgorithm(algorithm string) error {
+	switch algorithm {
+	case "", RateTokenBucket, RateSlidingWindow, RateLeakyBucket:
+		return nil
+	default:
+		return fmt.Errorf("unknown rate algorithm %q (want %s, %s or %s)", algorithm, RateTokenBucket, RateSlidingWindow, RateLeakyBucket)
+	}
+}
+
+// Wait blocks until a token is available
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		rl.mu.Lock()
+		ok, waitTime := rl.take(time.Now(), 1)
+		rl.mu.Unlock()
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitTime):
+		}
+	}
+}
+
+// Allow checks if a request is allowed
+func (rl *RateLimiter) Allow() bool {
+	return rl.AllowN(1)
+}
+
+// AllowN checks if n requests are allowed
+func (rl *RateLimiter) AllowN(n int) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	ok, _ := rl.take(time.Now(), n)
+	return ok
+}
+
+// take lets n requests through if the algorithm allows them now, or else
+// returns how long until it might. The caller holds mu.
+func (rl *RateLimiter) take(now time.Time, n int) (bool, time.Duration) {
+	interval := time.Duration(float64(time.Second) / rl.rate)
+
+	switch rl.algorithm {
+	case RateSlidingWindow:
+		window := time.Duration(float64(rl.burst) * float64(time.Second) / rl.rate)
+		rl.prune(now, window)
+		if n > rl.burst {
+			return false, window
+		}
+		if over := len(rl.sent) + n - rl.burst; over > 0 {
+			// Wait for enough of the oldest to leave the window
+			return false, rl.sent[over-1].Add(window).Sub(now)
+		}
+		rl.log(now, n)
+		return true, 0
+
+	case RateLeakyBucket:
+		if now.Before(rl.next) {
+			return false, rl.next.Sub(now)
+		}
+		rl.next = now.Add(time.Duration(n) * interval)
+		return true, 0
+
+	default:
+		rl.refill(now)
+		if rl.tokens >= float64(n) {
+			rl.tokens -= float64(n)
+			return true, 0
+		}
+		return false, time.Duration((float64(n) - rl.tokens) / rl.rate * float64(time.Second))
+	}
+}
+
+// refill adds the token bucket's tokens earned since the last update
+func (rl *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(rl.lastUpdate).Seconds()
+	rl.tokens += elapsed * rl.rate
+	if rl.tokens > float64(rl.burst) {
+		rl.tokens = float64(rl.burst)
+	}
+	rl.lastUpdate = now
+}
+
+// prune drops the sliding window log's requests older than window
+func (rl *RateLimiter) prune(now time.Time, window time.Duration) {
+	i := 0
+	for i < len(rl.sent) && !rl.sent[i].After(now.Add(-window)) {
+		i++
+	}
+	rl.sent = rl.sent[i:]
+}
+
+// log adds n requests sent at at to the sliding window log, keeping it in
+// time order: Reserve logs requests ahead of those let through now
+func (rl *RateLimiter) log(at time.Time, n int) {
+	i := sort.Search(len(rl.sent), func(i int) bool { return rl.sent[i].After(at) })
+	rl.sent = slices.Insert(rl.sent, i, slices.Repeat([]time.Time{at}, n)...)
+}
+
+// Reserve reserves a token and returns wait duration
+func (rl *RateLimiter) Reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	switch rl.algorithm {
+	case RateSlidingWindow:
+		// The request goes in the log at the time it will be sent
+		window := time.Duration(float64(rl.burst) * float64(time.Second) / rl.rate)
+		rl.prune(now, window)
+		at := now
+		if over := len(rl.sent) + 1 - rl.burst; over > 0 {
+			at = rl.sent[over-1].Add(window)
+		}
+		rl.log(at, 1)
+		return at.Sub(now)
+
+	case RateLeakyBucket:
+		at := now
+		if rl.next.After(now) {
+			at = rl.next
+		}
+		rl.next = at.Add(time.Duration(float64(time.Second) / rl.rate))
+		return at.Sub(now)
+	}
+
+	rl.refill(now)
+
+	// If we have a token, no wait needed
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return 0
+	}
+
+	// Calculate wait time for next token
+	waitTime := time.Duration((1.0 - rl.tokens) / rl.rate * float64(time.Second))
+	rl.tokens = 0
+	return waitTime
+}
+
+// SetRate updates the rate limit
+func (rl *RateLimiter) SetRate(ratePerSecond float64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rate = ratePerSecond
+}
+
+// SetBurst updates the burst limit
+func (rl *RateLimiter) SetBurst(burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.burst = max(1, burst)
+	if rl.tokens > float64(rl.burst) {
+		rl.tokens = float64(rl.burst)
+	}
+}
+
+// AdaptiveRateLimiter adjusts rate based on response times and errors.
+// The rate is reconsidered once per adjustEvery requests recorded, so one
+// slow stretch lowers it a step at a time instead of all at once. A nil
+// AdaptiveRateLimiter does nothing.
+type AdaptiveRateLimiter struct {
+	limiter       *RateLimiter
+	minRate       float64
+	maxRate       float64
+	targetLatency time.Duration
+	samples       []time.Duration
+	samplesMu     sync.Mutex
+	maxSamples    int
+
+	// errors and recorded count requests since the last adjustment
+	errors   int
+	recorded int
+}
+
+// adjustEvery is how many requests are recorded between rate adjustments
+const adjustEvery = 10
+
+// NewAdaptiveRateLimiter creates an adaptive rate limiter
+func NewAdaptiveRateLimiter(initialRate, minRate, maxRate float64, targetLatency time.Duration) *AdaptiveRateLimiter {
+	return &AdaptiveRateLimiter{
+		limiter:       NewRateLimiter(initialRate, int(initialRate)),
+		minRate:       minRate,
+		maxRate:       maxRate,
+		targetLatency: targetLatency,
+		maxSamples:    100,
+	}
+}
+
+// Wait blocks until allowed
+func (arl *AdaptiveRateLimiter) Wait(ctx context.Context) error {
+	if arl == nil {
+		return nil
+	}
+	return arl.limiter.Wait(ctx)
+}
+
+// Rate returns the current requests per second
+func (arl *AdaptiveRateLimiter) Rate() float64 {
+	if arl == nil {
+		return 0
+	}
+	arl.limiter.mu.Lock()
+	defer arl.limiter.mu.Unlock()
+	return arl.limiter.rate
+}
+
+// RecordLatency records a response latency for adaptation
+func (arl *AdaptiveRateLimiter) RecordLatency(latency time.Duration) {
+	if arl == nil {
+		return
+	}
+	arl.samplesMu.Lock()
+	defer arl.samplesMu.Unlock()
+
+	arl.samples = append(arl.samples, latency)
+	if len(arl.samples) > arl.maxSamples {
+		arl.samples = arl.samples[1:]
+	}
+	arl.record()
+}
+
+// RecordError records a failed or throttled request (a timeout, a reset,
+// a 429 or 503) for adaptation
+func (arl *AdaptiveRateLimiter) RecordError() {
+	if arl == nil {
+		return
+	}
+	arl.samplesMu.Lock()
+	defer arl.samplesMu.Unlock()
+
+	arl.errors++
+	arl.record()
+}
+
+// record counts a request and adjusts the rate once enough have been
+// recorded. The caller holds samplesMu.
+func (arl *AdaptiveRateLimiter) record() {
+	arl.recorded++
+	if arl.recorded < adjustEvery {
+		return
+	}
+
+	// Errors are the clearest sign of overload, so they back off hardest
+	if arl.errors*5 >= arl.recorded {
+		arl.setRate(arl.Rate() * 0.5)
+	} else if len(arl.samples) >= adjustEvery {
+		arl.adjustRate(arl.averageLatency())
+	}
+	arl.errors, arl.recorded = 0, 0
+}
+
+// averageLatency calculates average of recent samples
+func (arl *AdaptiveRateLimiter) averageLatency() time.Duration {
+	if len(arl.samples) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, s := range arl.samples {
+		total += s
+	}
+	return total / time.Duration(len(arl.samples))
+}
+
+// adjustRate adjusts rate based on latency
+func (arl *AdaptiveRateLimiter) adjustRate(avgLatency time.Duration) {
+	currentRate := arl.Rate()
+
+	if avgLatency > arl.targetLatency*2 {
+		// High latency - slow down significantly
+		arl.setRate(currentRate * 0.5)
+	} else if avgLatency > arl.targetLatency {
+		// Above target - slow down slightly
+		arl.setRate(currentRate * 0.8)
+	} else if avgLatency < arl.targetLatency/2 {
+		// Very low latency - speed up
+		arl.setRate(currentRate * 1.2)
+	}
+}
+
+// setRate applies a new rate clamped to the configured range, with a
+// burst of one second's worth so a backed-off limiter cannot burst at the
+// old rate
+func (arl *AdaptiveRateLimiter) setRate(rate float64) {
+	if rate < arl.minRate {
+		rate = arl.minRate
+	}
+	if rate > arl.maxRate {
+		rate = arl.maxRate
+	}
+	arl.limiter.SetRate(rate)
+	arl.limiter.SetBurst(max(1, int(rate)))
+}
+
+// PerHostRateLimiter manages rate limits per host
+type PerHostRateLimiter struct {
+	limiters  map[string]*RateLimiter
+	mu        sync.RWMutex
+	algorithm string
+	rate      float64
+	burst     int
+}
+
+// NewPerHostRateLimiter creates a per-host rate limiter
+func NewPerHostRateLimiter(ratePerHost float64, burstPerHost int) *PerHostRateLimiter {
+	return &PerHostRateLimiter{
+		limiters: make(map[string]*RateLimiter),
+		rate:     ratePerHost,
+		burst:    burstPerHost,
+	}
+}
+
+// NewPerHostRateLimiterAlgorithm creates a per-host rate limiter using
+// one of the Rate* algorithms for each host
+func NewPerHostRateLimiterAlgorithm(algorithm string, ratePerHost float64, burstPerHost int) (*PerHostRateLimiter, error) {
+	if err := checkRateAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	phrl := NewPerHostRateLimiter(ratePerHost, burstPerHost)
+	phrl.algorithm = algorithm
+	return phrl, nil
+}
+
+// Wait waits for rate limit on specific host
+func (phrl *PerHostRateLimiter) Wait(ctx context.Context, host string) error {
+	limiter := phrl.getLimiter(host)
+	return limiter.Wait(ctx)
+}
+
+// Allow checks if request to host is allowed
+func (phrl *PerHostRateLimiter) Allow(host string) bool {
+	limiter := phrl.getLimiter(host)
+	return limiter.Allow()
+}
+
+// getLimiter gets or creates limiter for host
+func (phrl *PerHostRateLimiter) getLimiter(host string) *RateLimiter {
+	phrl.mu.RLock()
+	limiter, exists := phrl.limiters[host]
+	phrl.mu.RUnlock()
+
+	if exists {
+		return limiter
+	}
+
+	phrl.mu.Lock()
+	defer phrl.mu.Unlock()
+
+	// Double check after acquiring write lock
+	if limiter, exists = phrl.limiters[host]; exists {
+		return limiter
+	}
+
+	limiter, _ = NewRateLimiterAlgorithm(phrl.algorithm, phrl.rate, phrl.burst)
+	phrl.limiters[host] = limiter
+	return limiter
+}
+
+// Budget is a requests-per-second allowance shared by several modules, so
+// concurrently running stages together stay under one global rate. It can
+// be paused and re-rated while a scan runs. All methods are safe to call on
+// a nil *Budget, which never blocks.
+type Budget struct {
+	limiter  *rate.Limiter
+	requests atomic.Int64
+
+	mu     sync.Mutex
+	paused chan struct{} // closed on resume; nil while running
+
+	// parent, if set, is a wider budget every request also draws from
+	parent *Budget
+}
+
+// NewBudget creates a shared budget of rps requests per second, or returns
+// nil if rps is not positive
+func NewBudget(rps int) *Budget {
+	if rps <= 0 {
+		return nil
+	}
+	return &Budget{limiter: rate.NewLimiter(rate.Limit(rps), rps)}
+}
+
+// NewControlBudget creates a budget that can be paused and re-rated even
+// when no limit is set; rps <= 0 starts unlimited
+func NewControlBudget(rps int) *Budget {
+	if rps <= 0 {
+		return &Budget{limiter: rate.NewLimiter(rate.Inf, 1)}
+	}
+	return NewBudget(rps)
+}
+
+// NewSubBudget creates a budget of rps requests per second that also
+// draws every request from parent, so scans running side by side each keep
+// their own rate and together stay under the parent's. It returns parent
+// if rps is not positive, and a plain budget if parent is nil.
+func NewSubBudget(rps int, parent *Budget) *Budget {
+	if parent == nil {
+		return NewBudget(rps)
+	}
+	sub := NewBudget(rps)
+	if sub == nil {
+		return parent
+	}
+	sub.parent = parent
+	return sub
+}
+
+// Wait blocks while the budget is paused, then until it, and any parent,
+// allows one more request or ctx is done
+func (b *Budget) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.requests.Add(1)
+
+	b.mu.Lock()
+	paused := b.paused
+	b.mu.Unlock()
+	if paused != nil {
+		select {
+		case <-paused:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := b.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return b.parent.Wait(ctx)
+}
+
+// Pause stops Wait from returning until Resume
+func (b *Budget) Pause() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused == nil {
+		b.paused = make(chan struct{})
+	}
+}
+
+// Resume releases callers blocked by Pause
+func (b *Budget) Resume() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused != nil {
+		close(b.paused)
+		b.paused = nil
+	}
+}
+
+// Paused reports whether the budget is paused
+func (b *Budget) Paused() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused != nil
+}
+
+// Rate returns the current requests per second, or 0 if unlimited
+func (b *Budget) Rate() int {
+	if b == nil || b.limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return int(b.limiter.Limit())
+}
+
+// Requests returns how many requests have waited on the budget
+func (b *Budget) Requests() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.requests.Load()
+}
+
+// SetRate changes the requests per second; rps <= 0 removes the limit
+func (b *Budget) SetRate(rps int) {
+	if b == nil {
+		return
+	}
+	if rps <= 0 {
+		b.limiter.SetLimit(rate.Inf)
+		return
+	}
+	b.limiter.SetBurst(rps)
+	b.limiter.SetLimit(rate.Limit(rps))
+}
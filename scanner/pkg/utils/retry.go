@@ -0,0 +1,497 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryConfig holds retry configuration
+type RetryConfig struct {
+	MaxRetries      int
+	InitialDelay    time.Duration
+	MaxDelay        time.Duration
+	BackoffFactor   float64
+	Jitter          bool
+	RetryableErrors []error
+
+	// MaxRetryAfter is the longest server-advised delay, from a
+	// RetryAfterError, that is waited out; an error asking for longer is
+	// returned without retrying. Defaults to 1 minute.
+	MaxRetryAfter time.Duration
+}
+
+// RetryAfterError is an error from a server that said how long to wait
+// before trying again. RetryWithBackoff waits exactly Delay before the next
+// attempt instead of its own backoff.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.Err, e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter wraps err with the delay a response's Retry-After or
+// rate-limit headers ask for, or returns err unchanged if they ask for none
+func RetryAfter(err error, header http.Header) error {
+	delay, ok := RetryAfterDelay(header, time.Now())
+	if !ok || err == nil {
+		return err
+	}
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+// RetryAfterDelay returns how long a response's headers ask the client to
+// wait, as of now: Retry-After in seconds or as a date, else the reset of
+// an exhausted X-RateLimit or RateLimit window. Reset values above a
+// billion are Unix times, smaller ones seconds from now.
+func RetryAfterDelay(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if secs, err := strconv.ParseInt(value, 10, 64); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if remaining := header.Get(prefix + "Remaining"); remaining != "" && remaining != "0" {
+			continue
+		}
+		reset, err := strconv.ParseFloat(strings.TrimSpace(header.Get(prefix+"Reset")), 64)
+		if err != nil || reset < 0 {
+			continue
+		}
+		if reset > 1e9 {
+			return max(time.Unix(int64(reset), 0).Sub(now), 0), true
+		}
+		return time.Duration(reset * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// DefaultRetryConfig returns sensible defaults
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:    3,
+		InitialDelay:  100 * time.Millisecond,
+		MaxDelay:      10 * time.Second,
+		BackoffFactor: 2.0,
+		Jitter:        true,
+	}
+}
+
+// RetryableFunc is a function that can be retried
+type RetryableFunc func(ctx context.Context) error
+
+// RetryWithBackoff retries a function with exponential backoff
+func RetryWithBackoff(ctx context.Context, config RetryConfig, fn RetryableFunc) error {
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.InitialDelay <= 0 {
+		config.InitialDelay = 100 * time.Millisecond
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = 10 * time.Second
+	}
+	if config.BackoffFactor <= 0 {
+		config.BackoffFactor = 2.0
+	}
+	if config.MaxRetryAfter <= 0 {
+		config.MaxRetryAfter = time.Minute
+	}
+
+	var lastErr error
+	delay := config.InitialDelay
+
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		// Check context before attempt
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+
+		// Check if error is retryable
+		if !isRetryable(err, config.RetryableErrors) {
+			return err
+		}
+
+		// Don't sleep after last attempt
+		if attempt == config.MaxRetries {
+			break
+		}
+
+		// Calculate delay with optional jitter, unless the server said
+		// how long to wait
+		currentDelay := delay
+		var retryAfter *RetryAfterError
+		if errors.As(err, &retryAfter) {
+			if retryAfter.Delay > config.MaxRetryAfter {
+				return err
+			}
+			currentDelay = retryAfter.Delay
+		} else if config.Jitter {
+			jitterRange := float64(delay) * 0.3
+			jitter := rand.Float64()*jitterRange*2 - jitterRange
+			currentDelay = time.Duration(float64(delay) + jitter)
+		}
+
+		// Wait before retry
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(currentDelay):
+		}
+
+		// Increase delay for next attempt
+		delay = time.Duration(float64(delay) * config.BackoffFactor)
+		if delay > config.MaxDelay {
+			delay = config.MaxDelay
+		}
+	}
+
+	return fmt.Errorf("max retries (%d) exceeded: %w", config.MaxRetries, lastErr)
+}
+
+// isRetryable checks if an error should be retried
+func isRetryable(err error, retryableErrors []error) bool {
+	// If no specific retryable errors defined, retry all
+	if len(retryableErrors) == 0 {
+		return true
+	}
+
+	for _, retryableErr := range retryableErrors {
+		if errors.Is(err, retryableErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RetryResult wraps result with retry metadata
+type RetryResult struct {
+	Attempts int
+	Duration time.Duration
+	Error    error
+}
+
+// RetryWithResult retries and returns metadata
+func RetryWithResult(ctx context.Context, config RetryConfig, fn RetryableFunc) RetryResult {
+	start := time.Now()
+	attempts := 0
+
+	err := RetryWithBackoff(ctx, config, func(ctx context.Context) error {
+		attempts++
+		return fn(ctx)
+	})
+
+	return RetryResult{
+		Attempts: attempts,
+		Duration: time.Since(start),
+		Error:    err,
+	}
+}
+
+// ExponentialBackoff calculates delay for a given attempt
+func ExponentialBackoff(attempt int, baseDelay time.Duration, maxDelay time.Duration) time.Duration {
+	delay := baseDelay * time.Duration(math.Pow(2, float64(attempt)))
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// JitterDuration adds random jitter to a duration
+func JitterDuration(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 || factor > 1 {
+		factor = 0.3
+	}
+	jitterRange := float64(d) * factor
+	jitter := rand.Float64()*jitterRange*2 - jitterRange
+	return time.Duration(float64(d) + jitter)
+}
+
+// RetryPolicy defines custom retry behavior
+type RetryPolicy struct {
+	ShouldRetry func(error) bool
+	Delay       func(attempt int) time.Duration
+}
+
+// NewLinearRetryPolicy creates policy with linear backoff
+func NewLinearRetryPolicy(baseDelay time.Duration) RetryPolicy {
+	return RetryPolicy{
+		ShouldRetry: func(err error) bool { return true },
+		Delay: func(attempt int) time.Duration {
+			return baseDelay * time.Duration(attempt+1)
+		},
+	}
+}
+
+// NewExponentialRetryPolicy creates policy with exponential backoff
+func NewExponentialRetryPolicy(baseDelay, maxDelay time.Duration) RetryPolicy {
+	return RetryPolicy{
+		ShouldRetry: func(err error) bool { return true },
+		Delay: func(attempt int) time.Duration {
+			return ExponentialBackoff(attempt, baseDelay, maxDelay)
+		},
+	}
+}
+
+// ErrCircuitOpen is returned by CircuitBreaker.Execute while the breaker
+// is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Circuit breaker states, as returned by CircuitBreaker.State
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreakerConfig configures a CircuitBreaker
+type CircuitBreakerConfig struct {
+	// MaxFailures is how many consecutive failures open the breaker
+	MaxFailures int
+
+	// ResetTimeout is how long the breaker stays open before trial calls
+	// are let through
+	ResetTimeout time.Duration
+
+	// HalfOpenRequests is how many trial calls may be in flight at once
+	// while half-open (default 1)
+	HalfOpenRequests int
+
+	// SuccessThreshold is how many trial calls must succeed in a row to
+	// close the breaker (default 1); any failed trial reopens it
+	SuccessThreshold int
+
+	// OnStateChange, if set, is called with the old and new state on every
+	// transition. It runs after the breaker's lock is released, so it may
+	// call back into the breaker.
+	OnStateChange func(from, to string)
+}
+
+// CircuitBreaker implements circuit breaker pattern. After MaxFailures
+// consecutive failures it opens, refusing calls until ResetTimeout has
+// passed; then it is half-open, letting up to HalfOpenRequests trial calls
+// through at a time until SuccessThreshold of them succeed, which closes
+// it, or one fails, which reopens it. Outcomes count only in the state the
+// call was allowed in, so a call let through before the breaker opened
+// cannot close it by finishing late. It is safe for concurrent use.
+type CircuitBreaker struct {
+	mu              sync.Mutex
+	config          CircuitBreakerConfig
+	failures        int
+	lastFailureTime time.Time
+	state           string
+	generation      uint64 // bumped on every state change
+	trials          int    // half-open trial calls in flight
+	successes       int    // half-open trial calls succeeded in a row
+}
+
+// CircuitCall is a call a CircuitBreaker allowed, whose outcome is
+// reported with it
+type CircuitCall struct {
+	generation uint64
+	trial      bool
+}
+
+// NewCircuitBreaker creates a new circuit breaker with one trial call
+func NewCircuitBreaker(maxFailures int, resetTimeout time.Duration) *CircuitBreaker {
+	return NewCircuitBreakerConfig(CircuitBreakerConfig{MaxFailures: maxFailures, ResetTimeout: resetTimeout})
+}
+
+// NewCircuitBreakerConfig creates a circuit breaker from a config
+func NewCircuitBreakerConfig(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = 1
+	}
+	return &CircuitBreaker{config: config, state: CircuitClosed}
+}
+
+// Execute runs function through circuit breaker
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	call, ok := cb.Allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	if err != nil {
+		cb.Failure(call)
+		return err
+	}
+	cb.Success(call)
+	return nil
+}
+
+// Allow reports whether a call may go ahead. A caller that is allowed must
+// report the outcome with Success, Failure or Release, passing the call.
+func (cb *CircuitBreaker) Allow() (CircuitCall, bool) {
+	cb.mu.Lock()
+	from := cb.state
+	allowed := true
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.lastFailureTime) <= cb.config.ResetTimeout {
+			allowed = false
+			break
+		}
+		cb.setState(CircuitHalfOpen)
+		cb.trials = 1
+	case CircuitHalfOpen:
+		if cb.trials >= cb.config.HalfOpenRequests {
+			allowed = false
+			break
+		}
+		cb.trials++
+	}
+	call := CircuitCall{generation: cb.generation, trial: cb.state == CircuitHalfOpen}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.changed(from, to)
+	return call, allowed
+}
+
+// Success records a call that succeeded, closing a half-open breaker once
+// SuccessThreshold trials have succeeded
+func (cb *CircuitBreaker) Success(call CircuitCall) {
+	cb.mu.Lock()
+	from := cb.state
+	if call.generation == cb.generation {
+		cb.failures = 0
+		if call.trial {
+			cb.trials = max(cb.trials-1, 0)
+			cb.successes++
+			if cb.successes >= cb.config.SuccessThreshold {
+				cb.setState(CircuitClosed)
+			}
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.changed(from, to)
+}
+
+// Failure records a call that failed, opening the breaker after
+// MaxFailures in a row or a failed trial
+func (cb *CircuitBreaker) Failure(call CircuitCall) {
+	cb.mu.Lock()
+	from := cb.state
+	if call.generation == cb.generation {
+		cb.failures++
+		cb.lastFailureTime = time.Now()
+		if call.trial || cb.failures >= cb.config.MaxFailures {
+			cb.setState(CircuitOpen)
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.changed(from, to)
+}
+
+// Release records a call that ended without saying anything about the
+// service, such as one cancelled, freeing its trial slot if it had one
+func (cb *CircuitBreaker) Release(call CircuitCall) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if call.trial && call.generation == cb.generation {
+		cb.trials = max(cb.trials-1, 0)
+	}
+}
+
+// setState moves the breaker to state, starting a new generation with no
+// trials. The caller holds mu.
+func (cb *CircuitBreaker) setState(state string) {
+	cb.state = state
+	cb.generation++
+	cb.trials, cb.successes = 0, 0
+}
+
+// changed calls OnStateChange if the state moved. The caller must not
+// hold mu.
+func (cb *CircuitBreaker) changed(from, to string) {
+	if from != to && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, to)
+	}
+}
+
+// State returns current circuit breaker state
+func (cb *CircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// PerHostCircuitBreaker keeps a circuit breaker per host
+type PerHostCircuitBreaker struct {
+	breakers      map[string]*CircuitBreaker
+	mu            sync.Mutex
+	config        CircuitBreakerConfig
+	onStateChange func(host, from, to string)
+}
+
+// NewPerHostCircuitBreaker creates breakers that open for resetTimeout
+// after maxFailures consecutive failures to one host
+func NewPerHostCircuitBreaker(maxFailures int, resetTimeout time.Duration) *PerHostCircuitBreaker {
+	return NewPerHostCircuitBreakerConfig(CircuitBreakerConfig{MaxFailures: maxFailures, ResetTimeout: resetTimeout}, nil)
+}
+
+// NewPerHostCircuitBreakerConfig creates a breaker per host from config,
+// whose OnStateChange is ignored; onStateChange, if set, is called with
+// the host on every transition of its breaker instead
+func NewPerHostCircuitBreakerConfig(config CircuitBreakerConfig, onStateChange func(host, from, to string)) *PerHostCircuitBreaker {
+	config.OnStateChange = nil
+	return &PerHostCircuitBreaker{
+		breakers:      make(map[string]*CircuitBreaker),
+		config:        config,
+		onStateChange: onStateChange,
+	}
+}
+
+// Get returns host's breaker, creating it closed
+func (b *PerHostCircuitBreaker) Get(host string) *CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, ok := b.breakers[host]
+	if !ok {
+		config := b.config
+		if b.onStateChange != nil {
+			config.OnStateChange = func(from, to string) { b.onStateChange(host, from, to) }
+		}
+		cb = NewCircuitBreakerConfig(config)
+		b.breakers[host] = cb
+	}
+	return cb
+}
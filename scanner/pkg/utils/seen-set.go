@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"sync"
+)
+
+// Seen set kinds for NewSeenSet
+const (
+	// SeenExact keeps every key in full: exact, but memory grows with the
+	// length of the keys
+	SeenExact = "exact"
+
+	// SeenHash keeps a 64-bit hash of each key. Two keys colliding, and
+	// the second being treated as seen, is vanishingly rare below
+	// billions of keys.
+	SeenHash = "hash"
+
+	// SeenBloom keeps a bloom filter of fixed size, about two bytes per
+	// key of the expected capacity, that wrongly reports roughly one key
+	// in a thousand as already seen
+	SeenBloom = "bloom"
+)
+
+// SeenSet records which keys have been seen, for deduplicating URLs and
+// names in crawls and enumerations too large to hold them all. It is safe
+// for concurrent use.
+type SeenSet interface {
+	// Add records key, reporting whether it had not been seen before
+	Add(key string) bool
+
+	// Len returns how many keys Add reported as new
+	Len() int
+}
+
+// NewSeenSet creates a seen set of the given kind (default exact).
+// capacity is the number of keys a bloom filter is sized for; past it,
+// false positives grow. The other kinds ignore it.
+func NewSeenSet(kind string, capacity int) (SeenSet, error) {
+	switch kind {
+	case "", SeenExact:
+		return &exactSet{keys: make(map[string]struct{})}, nil
+	case SeenHash:
+		return &hashSet{seed: maphash.MakeSeed(), hashes: make(map[uint64]struct{})}, nil
+	case SeenBloom:
+		return newBloomSet(capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown seen set %q (want %s, %s or %s)", kind, SeenExact, SeenHash, SeenBloom)
+	}
+}
+
+// exactSet keeps keys in full
+type exactSet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func (s *exactSet) Add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	s.keys[key] = struct{}{}
+	return true
+}
+
+func (s *exactSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+// hashSet keeps a 64-bit hash of each key
+type hashSet struct {
+	mu     sync.Mutex
+	seed   maphash.Seed
+	hashes map[uint64]struct{}
+}
+
+func (s *hashSet) Add(key string) bool {
+	h := maphash.String(s.seed, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hashes[h]; ok {
+		return false
+	}
+	s.hashes[h] = struct{}{}
+	return true
+}
+
+func (s *hashSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hashes)
+}
+
+// bloomFalsePositive is the false positive rate a bloom set is sized for
+const bloomFalsePositive = 0.001
+
+// bloomSet is a bloom filter. Its k bit positions come from two
+// independent hashes combined as h1 + i*h2.
+type bloomSet struct {
+	mu     sync.Mutex
+	seeds  [2]maphash.Seed
+	bits   []uint64
+	m      uint64
+	k      int
+	length int
+}
+
+// newBloomSet sizes a bloom filter for capacity keys, default a million
+func newBloomSet(capacity int) *bloomSet {
+	if capacity <= 0 {
+		capacity = 1 << 20
+	}
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(bloomFalsePositive) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := int(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	return &bloomSet{
+		seeds: [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		bits:  make([]uint64, m/64),
+		m:     m,
+		k:     max(1, k),
+	}
+}
+
+func (s *bloomSet) Add(key string) bool {
+	h1 := maphash.String(s.seeds[0], key)
+	h2 := maphash.String(s.seeds[1], key) | 1
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := false
+	for i := 0; i < s.k; i++ {
+		bit := (h1 + uint64(i)*h2) % s.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if s.bits[word]&mask == 0 {
+			s.bits[word] |= mask
+			added = true
+		}
+	}
+	if added {
+		s.length++
+	}
+	return added
+}
+
+func (s *bloomSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.length
+}
@@ -0,0 +1,290 @@
+package vulndb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Match is a CVE listed for a detected product version
+type Match struct {
+	ID       string  `json:"id"`
+	CVSS     float64 `json:"cvss,omitempty"`
+	Severity string  `json:"severity,omitempty"`
+	Product  string  `json:"product"`
+	Version  string  `json:"version"`
+}
+
+// DB is an in-memory CVE dataset indexed by CPE product name
+type DB struct {
+	entries map[string][]entry
+	cves    int
+}
+
+// entry is one vulnerable CPE range of one CVE
+type entry struct {
+	id       string
+	cvss     float64
+	severity string
+
+	// version is an exact version, or "*" when the range fields apply
+	version        string
+	startIncluding string
+	startExcluding string
+	endIncluding   string
+	endExcluding   string
+}
+
+// nvdFeed is the part of an NVD CVE API 2.0 document the dataset uses
+type nvdFeed struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID      string `json:"id"`
+			Metrics struct {
+				V31 []nvdMetric `json:"cvssMetricV31"`
+				V30 []nvdMetric `json:"cvssMetricV30"`
+				V2  []nvdMetric `json:"cvssMetricV2"`
+			} `json:"metrics"`
+			Configurations []struct {
+				Nodes []struct {
+					CPEMatch []struct {
+						Vulnerable            bool   `json:"vulnerable"`
+						Criteria              string `json:"criteria"`
+						VersionStartIncluding string `json:"versionStartIncluding"`
+						VersionStartExcluding string `json:"versionStartExcluding"`
+						VersionEndIncluding   string `json:"versionEndIncluding"`
+						VersionEndExcluding   string `json:"versionEndExcluding"`
+					} `json:"cpeMatch"`
+				} `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// nvdMetric is one CVSS score; v2 puts the severity outside cvssData
+type nvdMetric struct {
+	Type     string `json:"type"`
+	CVSSData struct {
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"`
+}
+
+// Load reads NVD CVE API 2.0 JSON files (.json or .json.gz) and
+// directories of them
+func Load(paths ...string) (*DB, error) {
+	db := &DB{entries: make(map[string][]entry)}
+	for _, path := range paths {
+		files, err := datasetFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := db.loadFile(file); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	if db.cves == 0 {
+		return nil, fmt.Errorf("no CVEs found in %s", strings.Join(paths, ", "))
+	}
+	return db, nil
+}
+
+// datasetFiles lists the dataset files at path, a file or a directory
+func datasetFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (strings.HasSuffix(file, ".json") || strings.HasSuffix(file, ".json.gz")) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// loadFile adds one feed file to the dataset
+func (db *DB) loadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var feed nvdFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return fmt.Errorf("not an NVD 2.0 feed: %w", err)
+	}
+
+	for _, v := range feed.Vulnerabilities {
+		cve := v.CVE
+		score, severity := cvss(cve.Metrics.V31, cve.Metrics.V30, cve.Metrics.V2)
+		added := false
+		for _, config := range cve.Configurations {
+			for _, node := range config.Nodes {
+				for _, m := range node.CPEMatch {
+					if !m.Vulnerable {
+						continue
+					}
+					product, version, ok := parseCPE(m.Criteria)
+					if !ok {
+						continue
+					}
+					db.entries[product] = append(db.entries[product], entry{
+						id:             cve.ID,
+						cvss:           score,
+						severity:       severity,
+						version:        version,
+						startIncluding: m.VersionStartIncluding,
+						startExcluding: m.VersionStartExcluding,
+						endIncluding:   m.VersionEndIncluding,
+						endExcluding:   m.VersionEndExcluding,
+					})
+					added = true
+				}
+			}
+		}
+		if added {
+			db.cves++
+		}
+	}
+	return nil
+}
+
+// cvss picks the primary score of the newest CVSS version present
+func cvss(versions ...[]nvdMetric) (float64, string) {
+	for _, metrics := range versions {
+		if len(metrics) == 0 {
+			continue
+		}
+		best := metrics[0]
+		for _, m := range metrics {
+			if m.Type == "Primary" {
+				best = m
+				break
+			}
+		}
+		severity := best.CVSSData.BaseSeverity
+		if severity == "" {
+			severity = best.BaseSeverity
+		}
+		return best.CVSSData.BaseScore, strings.ToLower(severity)
+	}
+	return 0, ""
+}
+
+// parseCPE returns the product and version of an application CPE 2.3
+// name. An update such as p1 is appended to the version.
+func parseCPE(cpe string) (product, version string, ok bool) {
+	fields := strings.Split(cpe, ":")
+	if len(fields) < 7 || fields[0] != "cpe" || fields[2] != "a" {
+		return "", "", false
+	}
+	product, version = fields[4], fields[5]
+	if version == "-" {
+		return "", "", false
+	}
+	if update := fields[6]; version != "*" && update != "*" && update != "-" {
+		version += update
+	}
+	return product, version, true
+}
+
+// CVEs returns how many CVEs the dataset holds
+func (db *DB) CVEs() int {
+	if db == nil {
+		return 0
+	}
+	return db.cves
+}
+
+// Lookup returns the CVEs listed for a product version, highest CVSS
+// first. A nil DB has none.
+func (db *DB) Lookup(p Product) []Match {
+	if db == nil || p.Version == "" {
+		return nil
+	}
+	var matches []Match
+	seen := make(map[string]bool)
+	for _, e := range db.entries[p.Name] {
+		if seen[e.id] || !e.affects(p.Version) {
+			continue
+		}
+		seen[e.id] = true
+		matches = append(matches, Match{
+			ID:       e.id,
+			CVSS:     e.cvss,
+			Severity: e.severity,
+			Product:  p.Name,
+			Version:  p.Version,
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].CVSS != matches[j].CVSS {
+			return matches[i].CVSS > matches[j].CVSS
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+// LookupAll returns the CVEs listed for each product, highest CVSS first
+func (db *DB) LookupAll(products []Product) []Match {
+	if db == nil {
+		return nil
+	}
+	var matches []Match
+	for _, p := range products {
+		matches = append(matches, db.Lookup(p)...)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].CVSS > matches[j].CVSS })
+	return matches
+}
+
+// affects reports whether version falls in the entry's range
+func (e entry) affects(version string) bool {
+	if e.version != "*" {
+		return compareVersions(version, e.version) == 0
+	}
+	if e.startIncluding != "" && compareVersions(version, e.startIncluding) < 0 {
+		return false
+	}
+	if e.startExcluding != "" && compareVersions(version, e.startExcluding) <= 0 {
+		return false
+	}
+	if e.endIncluding != "" && compareVersions(version, e.endIncluding) > 0 {
+		return false
+	}
+	if e.endExcluding != "" && compareVersions(version, e.endExcluding) >= 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,17 @@
+// Package vulndb correlates detected software versions with known CVEs
+// from an offline copy of the NVD.
+//
+//	db, err := vulndb.Load("nvd/")
+//	products := vulndb.Fingerprint("Server: Apache/2.4.49 (Unix)")
+//	for _, m := range db.LookupAll(products) {
+//		fmt.Println(m.ID, m.CVSS, m.Severity)
+//	}
+//
+// Datasets are NVD CVE API 2.0 JSON: API responses or the yearly feed
+// files, optionally gzipped. Products are matched by CPE product name
+// alone, since vendors get renamed (nginx:nginx became f5:nginx), and
+// every vulnerable CPE in a CVE's configurations counts on its own, so a
+// match means "this version is listed", not "this host is exploitable".
+// Backported distribution patches do not change banner versions, so
+// review matches before reporting them.
+package vulndb
@@ -0,0 +1,56 @@
+package vulndb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Product is a piece of software and its version, named as in CPE
+type Product struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// version matches a dotted version with an optional suffix such as p1
+const version = `([0-9]+(?:\.[0-9]+)+[a-z0-9]*)`
+
+// fingerprints map banner and header text to CPE products
+var fingerprints = []struct {
+	vendor  string
+	product string
+	pattern *regexp.Regexp
+}{
+	{"apache", "http_server", regexp.MustCompile(`\bApache/` + version)},
+	{"f5", "nginx", regexp.MustCompile(`(?i)\bnginx/` + version)},
+	{"microsoft", "internet_information_services", regexp.MustCompile(`\bMicrosoft-IIS/` + version)},
+	{"lighttpd", "lighttpd", regexp.MustCompile(`\blighttpd/` + version)},
+	{"eclipse", "jetty", regexp.MustCompile(`\bJetty\(` + version)},
+	{"apache", "tomcat", regexp.MustCompile(`\bApache Tomcat/` + version)},
+	{"php", "php", regexp.MustCompile(`\bPHP/` + version)},
+	{"openssl", "openssl", regexp.MustCompile(`\bOpenSSL/` + version)},
+	{"openbsd", "openssh", regexp.MustCompile(`\bOpenSSH_` + version)},
+	{"dropbear_ssh_project", "dropbear_ssh", regexp.MustCompile(`\bdropbear_([0-9]{4}\.[0-9]+)`)},
+	{"beasts", "vsftpd", regexp.MustCompile(`\bvsFTPd ` + version)},
+	{"proftpd", "proftpd", regexp.MustCompile(`\bProFTPD ` + version)},
+	{"pureftpd", "pure-ftpd", regexp.MustCompile(`\bPure-FTPd ` + version)},
+	{"exim", "exim", regexp.MustCompile(`\bExim ` + version)},
+	{"mariadb", "mariadb", regexp.MustCompile(`\b` + version + `-MariaDB`)},
+}
+
+// Fingerprint finds the products and versions named in a banner or in
+// response headers such as Server and X-Powered-By
+func Fingerprint(text string) []Product {
+	var products []Product
+	seen := make(map[string]bool)
+	for _, f := range fingerprints {
+		for _, m := range f.pattern.FindAllStringSubmatch(text, -1) {
+			v := strings.ToLower(m[1])
+			if !seen[f.product+v] {
+				seen[f.product+v] = true
+				products = append(products, Product{Vendor: f.vendor, Name: f.product, Version: v})
+			}
+		}
+	}
+	return products
+}
@@ -0,0 +1,66 @@
+package vulndb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions orders two versions by their runs of digits and letters,
+// so 2.4.9 < 2.4.49 and 8.2 < 8.2p1. It returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	ta, tb := versionTokens(a), versionTokens(b)
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		na, errA := strconv.Atoi(ta[i])
+		nb, errB := strconv.Atoi(tb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			// A number sorts after a letter suffix: 1.0.1 > 1.0a
+			return 1
+		case errB == nil:
+			return -1
+		default:
+			if c := strings.Compare(ta[i], tb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(ta) - len(tb))
+}
+
+// versionTokens splits a version into runs of digits and runs of letters,
+// dropping separators
+func versionTokens(v string) []string {
+	var tokens []string
+	start := -1
+	digits := false
+	for i := 0; i <= len(v); i++ {
+		var c byte
+		if i < len(v) {
+			c = v[i]
+		}
+		isDigit := c >= '0' && c <= '9'
+		isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if start >= 0 && (!(isDigit || isLetter) || isDigit != digits) {
+			tokens = append(tokens, strings.ToLower(v[start:i]))
+			start = -1
+		}
+		if start < 0 && (isDigit || isLetter) {
+			start, digits = i, isDigit
+		}
+	}
+	return tokens
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+package wordlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+)
+
+// DirEnv overrides the directory downloaded wordlists are cached in
+const DirEnv = "SCANNER_WORDLISTS"
+
+// indexFile records the source of each list in the cache
+const indexFile = "index.json"
+
+// Source is a community wordlist that can be downloaded by name
+type Source struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+// seclists is where the SecLists discovery lists are served from
+const seclists = "https://raw.githubusercontent.com/danielmiessler/SecLists/master/Discovery/"
+
+// Sources are the larger community lists that can be downloaded by name
+var Sources = []Source{
+	{Name: "subdomains-20k", Kind: KindSubdomains, URL: seclists + "DNS/subdomains-top1million-20000.txt"},
+	{Name: "subdomains-110k", Kind: KindSubdomains, URL: seclists + "DNS/subdomains-top1million-110000.txt"},
+	{Name: "subdomains-jhaddix", Kind: KindSubdomains, URL: seclists + "DNS/dns-Jhaddix.txt"},
+	{Name: "paths-common", Kind: KindPaths, URL: seclists + "Web-Content/common.txt"},
+	{Name: "paths-raft-medium", Kind: KindPaths, URL: seclists + "Web-Content/raft-medium-directories.txt"},
+	{Name: "paths-raft-large-files", Kind: KindPaths, URL: seclists + "Web-Content/raft-large-files.txt"},
+	{Name: "params-burp", Kind: KindParams, URL: seclists + "Web-Content/burp-parameter-names.txt"},
+	{Name: "vhosts-namelist", Kind: KindVhosts, URL: seclists + "DNS/namelist.txt"},
+}
+
+// FindSource returns the downloadable list called name
+func FindSource(name string) (Source, bool) {
+	for _, source := range Sources {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+// validName is what a cached list may be called
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Dir returns the directory downloaded wordlists are cached in
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the wordlist cache (set %s): %w", DirEnv, err)
+	}
+	return filepath.Join(cache, "scanner", "wordlists"), nil
+}
+
+// Lists returns the embedded lists, then those downloaded into dir by name
+func Lists(dir string) ([]List, error) {
+	index, err := readIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := Embedded()
+	var cached []List
+	for _, list := range index {
+		cached = append(cached, list)
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].Name < cached[j].Name })
+	return append(result, cached...), nil
+}
+
+// Download fetches a list into dir under source's name, replacing any
+// earlier download of it, through proxy if set
+func Download(ctx context.Context, dir string, source Source, proxy string) (List, error) {
+	if !validName.MatchString(source.Name) {
+		return List{}, fmt.Errorf("bad wordlist name %q: use letters, digits, dots, dashes and underscores", source.Name)
+	}
+	for _, list := range embedded {
+		if list.Name == source.Name {
+			return List{}, fmt.Errorf("wordlist %s is embedded; download it under another name", source.Name)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return List{}, fmt.Errorf("creating wordlist cache: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return List{}, err
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: utils.ProxyFunc(proxy)}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return List{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return List{}, fmt.Errorf("downloading %s: %s", source.URL, resp.Status)
+	}
+
+	// Write then rename so a failed download leaves the old list in place
+	path := filepath.Join(dir, source.Name+".txt")
+	tmp, err := os.CreateTemp(dir, source.Name+".*.tmp")
+	if err != nil {
+		return List{}, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return List{}, fmt.Errorf("downloading %s: %w", source.URL, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return List{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return List{}, err
+	}
+	words := countWords(file)
+	file.Close()
+
+	updated := time.Now().UTC()
+	list := List{
+		Name:    source.Name,
+		Kind:    source.Kind,
+		Words:   words,
+		Source:  source.URL,
+		Path:    path,
+		Updated: &updated,
+	}
+	return list, addToIndex(dir, list)
+}
+
+// Update downloads each named list in dir again from where it came from,
+// or all of them if no names are given
+func Update(ctx context.Context, dir string, names []string, proxy string) ([]List, error) {
+	index, err := readIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		for name := range index {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var updated []List
+	for _, name := range names {
+		list, ok := index[name]
+		if !ok {
+			return updated, fmt.Errorf("no wordlist %s downloaded", name)
+		}
+		list, err = Download(ctx, dir, Source{Name: list.Name, Kind: list.Kind, URL: list.Source}, proxy)
+		if err != nil {
+			return updated, err
+		}
+		updated = append(updated, list)
+	}
+	return updated, nil
+}
+
+// readIndex reads the cache's lists by name; a missing index is empty
+func readIndex(dir string) (map[string]List, error) {
+	index := make(map[string]List)
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, indexFile), err)
+	}
+	return index, nil
+}
+
+// addToIndex records a downloaded list in the cache's index
+func addToIndex(dir string, list List) error {
+	index, err := readIndex(dir)
+	if err != nil {
+		return err
+	}
+	index[list.Name] = list
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, indexFile), data, 0644)
+}
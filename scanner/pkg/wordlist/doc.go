@@ -0,0 +1,11 @@
+// Package wordlist opens the wordlists modules bruteforce with: files, the
+// curated lists embedded in the binary, and community lists downloaded
+// into a local cache. Embedded and downloaded lists are named with @.
+//
+//	file, err := wordlist.Open("@subdomains-5k")
+//
+// Download fetches a list from Sources, or any URL, into the cache, and
+// Update fetches the cached lists again:
+//
+//	list, err := wordlist.Download(ctx, dir, wordlist.Sources[0], "")
+package wordlist
@@ -0,0 +1,178 @@
+package wordlist
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kinds of wordlist, by what their words are tried as
+const (
+	KindSubdomains = "subdomains"
+	KindPaths      = "paths"
+	KindParams     = "params"
+	KindVhosts     = "vhosts"
+)
+
+//go:embed lists/*.txt
+var lists embed.FS
+
+// List describes a wordlist that can be opened by name with @
+type List struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Words int    `json:"words"`
+
+	// Source is the URL a downloaded list was fetched from, or "embedded"
+	Source string `json:"source"`
+
+	// Path and Updated are where a downloaded list is cached and when it
+	// was fetched
+	Path    string     `json:"path,omitempty"`
+	Updated *time.Time `json:"updated,omitempty"`
+
+	// file and limit are an embedded list's file and how many of its
+	// words it takes (0 = all)
+	file  string
+	limit int
+}
+
+// SourceEmbedded is the Source of embedded lists
+const SourceEmbedded = "embedded"
+
+// embedded are the curated lists built into the binary. The shorter lists
+// are the head of the longer ones, which are ordered most common first.
+var embedded = []List{
+	{Name: "subdomains-1k", Kind: KindSubdomains, file: "subdomains.txt", limit: 1000},
+	{Name: "subdomains-5k", Kind: KindSubdomains, file: "subdomains.txt"},
+	{Name: "paths-1k", Kind: KindPaths, file: "paths.txt", limit: 1000},
+	{Name: "paths", Kind: KindPaths, file: "paths.txt"},
+	{Name: "params", Kind: KindParams, file: "params.txt"},
+	{Name: "vhosts", Kind: KindVhosts, file: "vhosts.txt"},
+}
+
+// Embedded returns the lists built into the binary
+func Embedded() []List {
+	var result []List
+	for _, list := range embedded {
+		data, err := embeddedData(list)
+		if err != nil {
+			continue
+		}
+		list.Source = SourceEmbedded
+		list.Words = countWords(bytes.NewReader(data))
+		result = append(result, list)
+	}
+	return result
+}
+
+// embeddedData returns the lines of an embedded list
+func embeddedData(list List) ([]byte, error) {
+	data, err := lists.ReadFile("lists/" + list.file)
+	if err != nil || list.limit == 0 {
+		return data, err
+	}
+	end := 0
+	for n := 0; n < list.limit && end < len(data); n++ {
+		i := bytes.IndexByte(data[end:], '\n')
+		if i < 0 {
+			return data, nil
+		}
+		end += i + 1
+	}
+	return data[:end], nil
+}
+
+// File is an open wordlist, a file on disk or an embedded list
+type File interface {
+	io.ReadSeekCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// Open opens a wordlist: a file, or with @ an embedded list
+// (@subdomains-5k) or one downloaded into the cache
+func Open(spec string) (File, error) {
+	name, ok := strings.CutPrefix(spec, "@")
+	if !ok {
+		return os.Open(spec)
+	}
+
+	for _, list := range embedded {
+		if list.Name == name {
+			data, err := embeddedData(list)
+			if err != nil {
+				return nil, err
+			}
+			return &embeddedFile{Reader: bytes.NewReader(data), name: name}, nil
+		}
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("bad wordlist name %q", spec)
+	}
+	file, err := os.Open(filepath.Join(dir, name+".txt"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no wordlist %s embedded or downloaded; scanner wordlist list shows them", spec)
+	}
+	return file, err
+}
+
+// ReadWords returns the words of a wordlist, skipping blank lines and #
+// comments
+func ReadWords(spec string) ([]string, error) {
+	file, err := Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// countWords counts the words in a wordlist
+func countWords(r io.Reader) int {
+	n := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" && !strings.HasPrefix(word, "#") {
+			n++
+		}
+	}
+	return n
+}
+
+// embeddedFile is an open embedded list
+type embeddedFile struct {
+	*bytes.Reader
+	name string
+}
+
+func (f *embeddedFile) Close() error { return nil }
+
+func (f *embeddedFile) Stat() (fs.FileInfo, error) { return f, nil }
+
+// embeddedFile is its own fs.FileInfo, that of a read-only regular file;
+// Size is the Reader's
+func (f *embeddedFile) Name() string       { return f.name }
+func (f *embeddedFile) Mode() fs.FileMode  { return 0444 }
+func (f *embeddedFile) ModTime() time.Time { return time.Time{} }
+func (f *embeddedFile) IsDir() bool        { return false }
+func (f *embeddedFile) Sys() interface{}   { return nil }
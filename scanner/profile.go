@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// scanProfile is a named set of flag defaults that configures every module
+// for one kind of engagement, selected with --profile
+type scanProfile struct {
+	// flags are the profile's values: "flag" for every command that has
+	// the flag, "command.flag" for one, which wins over the former. Flags
+	// given on the command line or in the environment win over both,
+	// unless the profile is strict.
+	flags map[string]string
+
+	// strict profiles refuse flags given another value than theirs
+	strict bool
+
+	// commands, if set, are the only commands the profile allows
+	commands []string
+}
+
+// scanProfiles are the --profile presets
+var scanProfiles = map[string]scanProfile{
+	"quick": {
+		flags: map[string]string{
+			"portscan.p":        "21,22,25,80,443,3306,3389,8080,8443",
+			"portscan.timeout":  "1",
+			"pipeline.p":        "80,443,8080,8443",
+			"pipeline.stages":   "subdomain,resolve,portscan,probe,analyze",
+			"pipeline.max-urls": "100",
+			"crawl.max-urls":    "200",
+			"retries":           "1",
+			"depth":             "1",
+		},
+	},
+	"full": {
+		flags: map[string]string{
+			"p":                 "1-65535",
+			"portscan.sV":       "true",
+			"bruteforce":        "true",
+			"records":           "true",
+			"permute":           "true",
+			"probe.favicon":     "true",
+			"crawl.js":          "true",
+			"crawl.depth":       "5",
+			"crawl.max-urls":    "5000",
+			"pipeline.depth":    "4",
+			"pipeline.max-urls": "2000",
+		},
+	},
+	"stealth": {
+		flags: map[string]string{
+			"rate-limit":             "10",
+			"c":                      "5",
+			"bruteforce":             "false",
+			"permute":                "false",
+			"retries":                "1",
+			"circuit":                "2",
+			"profile":                "chrome",
+			"probe.host-conns":       "1",
+			"crawl.robots":           "true",
+			"crawl.host-rate":        "1",
+			"crawl.host-concurrency": "1",
+			"crawl.depth":            "2",
+			"crawl.max-urls":         "200",
+			"pipeline.depth":         "1",
+			"pipeline.max-urls":      "100",
+			"pipeline.stage-rates":   "portscan=10,probe=5,crawl=1",
+		},
+	},
+	"passive-only": {
+		flags: map[string]string{
+			"passive":         "true",
+			"bruteforce":      "false",
+			"records":         "false",
+			"permute":         "false",
+			"urls.verify":     "false",
+			"pipeline.stages": "subdomain",
+		},
+		strict:   true,
+		commands: []string{"subdomain", "urls", "code", "asn", "intel", "reputation", "reverseip", "pipeline", "diff", "assets"},
+	},
+}
+
+// currentProfileName and currentProfile are the --profile preset, if any
+var (
+	currentProfileName string
+	currentProfile     *scanProfile
+)
+
+// selectProfile makes the named preset current, exiting if there is no
+// such profile or it does not allow the command
+func selectProfile(name, command string) {
+	profile, ok := scanProfiles[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown --profile %q: use %s\n", name, strings.Join(profileNames(), ", "))
+		os.Exit(exitUsage)
+	}
+	if profile.commands != nil && !slices.Contains(profile.commands, command) {
+		fmt.Fprintf(os.Stderr, "Error: --profile %s does not allow %s, which contacts the targets; it allows %s\n",
+			name, command, strings.Join(profile.commands, ", "))
+		os.Exit(exitUsage)
+	}
+	currentProfileName, currentProfile = name, &profile
+}
+
+// profileNames returns the presets' names, sorted
+func profileNames() []string {
+	names := make([]string, 0, len(scanProfiles))
+	for name := range scanProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile sets the current profile's values on a command's parsed
+// flags, skipping those in set, which were given on the command line or
+// in the environment; a strict profile exits if one of those differs
+func applyProfile(fs *flag.FlagSet, set map[string]bool) {
+	if currentProfile == nil {
+		return
+	}
+
+	// Every command's values first, so the command's own win
+	for _, own := range []bool{false, true} {
+		for key, value := range currentProfile.flags {
+			command, name, ok := strings.Cut(key, ".")
+			if !ok {
+				name = key
+			} else if command != fs.Name() {
+				continue
+			}
+			f := fs.Lookup(name)
+			if f == nil || ok != own {
+				continue
+			}
+
+			if set[name] {
+				if currentProfile.strict && f.Value.String() != value {
+					fmt.Fprintf(os.Stderr, "Error: --profile %s does not allow -%s=%s (only %s)\n", currentProfileName, name, f.Value, value)
+					os.Exit(exitUsage)
+				}
+				continue
+			}
+			if err := fs.Set(name, value); err != nil {
+				fatal(fmt.Errorf("--profile %s: -%s=%s: %w", currentProfileName, name, value, err))
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/recon-suite/scanner/project"
+	"github.com/recon-suite/scanner/storage"
+)
+
+// currentProject is the engagement named with --project or
+// SCANNER_PROJECT, or nil. It changes the defaults of the flags that say
+// where a run keeps its files; see applyProject.
+var currentProject *project.Project
+
+// openProject opens, creating if needed, the project a run belongs to
+func openProject(name string) *project.Project {
+	root, err := project.Root()
+	if err != nil {
+		fatal(err)
+	}
+	p, err := project.Open(root, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --project: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	return p
+}
+
+// applyProject points a command's flags at the current project, if any,
+// once parsed: -db, -workspace and the screenshot directories default to
+// the project's, a relative -o is written under its reports, and a relative
+// -i that names no file here is read from them. Flags in set were given on
+// the command line or in the environment and keep their value; diff's -db
+// is left alone, since it switches diff from files to stored runs.
+func applyProject(fs *flag.FlagSet, set map[string]bool) {
+	if currentProject == nil {
+		return
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		value := ""
+		switch {
+		case f.Name == "db" && !set[f.Name] && fs.Name() != "diff":
+			value = currentProject.DB()
+		case f.Name == "workspace" && !set[f.Name]:
+			value = currentProject.State()
+		case (f.Name == "screenshots" || (f.Name == "dir" && fs.Name() == "screenshot")) && !set[f.Name]:
+			value = currentProject.Screenshots()
+		case f.Name == "o" && set[f.Name]:
+			value = currentProject.Report(f.Value.String())
+			if err := os.MkdirAll(filepath.Dir(value), 0755); err != nil {
+				fatal(err)
+			}
+		case f.Name == "i" && set[f.Name]:
+			inputs := strings.Split(f.Value.String(), ",")
+			for i, input := range inputs {
+				inputs[i] = currentProject.Input(strings.TrimSpace(input))
+			}
+			value = strings.Join(inputs, ",")
+		default:
+			return
+		}
+		fs.Set(f.Name, value)
+	})
+}
+
+// projectDetail is what project show reports
+type projectDetail struct {
+	project.Info
+	Usage map[string]int64  `json:"usage_bytes"`
+	Runs  []storage.RunInfo `json:"runs"`
+}
+
+func runProject(name string) int {
+	fs := flag.NewFlagSet("project", flag.ExitOnError)
+	format := fs.String("f", "txt", "Output format: txt, json")
+	all := fs.Bool("all", false, "clean: delete the whole project, results database and reports included, instead of only its resume checkpoints")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: scanner project <list|show|clean> [options] [name]")
+		fmt.Fprintln(os.Stderr, "  list         projects, their size and last change")
+		fmt.Fprintln(os.Stderr, "  show NAME    a project's directories and stored runs")
+		fmt.Fprintln(os.Stderr, "  clean NAME   delete a project's resume checkpoints, or with -all the project")
+		fmt.Fprintln(os.Stderr, "  NAME defaults to --project's")
+		fs.PrintDefaults()
+	}
+	if len(os.Args) < 3 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	action := os.Args[2]
+	fs.Parse(os.Args[3:])
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	root, err := project.Root()
+	if err != nil {
+		fatal(err)
+	}
+
+	switch action {
+	case "list":
+		projects, err := project.List(root)
+		if err != nil {
+			fatal(err)
+		}
+		if OutputFormat(*format) == FormatJSON {
+			outputResults(projects, "", FormatJSON)
+			return exitClean
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tSIZE\tMODIFIED\tDIR")
+		for _, p := range projects {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, formatSize(p.Size), p.Modified.Format("2006-01-02 15:04"), p.Dir)
+		}
+		w.Flush()
+		return exitClean
+
+	case "show", "clean":
+		if name == "" {
+			fmt.Fprintf(os.Stderr, "Error: project %s needs a project name\n", action)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		p, err := project.Load(root, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if action == "clean" {
+			if err := p.Clean(*all); err != nil {
+				fatal(err)
+			}
+			return exitClean
+		}
+		detail, err := showProject(p)
+		if err != nil {
+			fatal(err)
+		}
+		if OutputFormat(*format) == FormatJSON {
+			outputResults(detail, "", FormatJSON)
+		} else {
+			printProject(detail)
+		}
+		return exitClean
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown project command: %s\n", action)
+		fs.Usage()
+		return exitUsage
+	}
+}
+
+// showProject gathers a project's sizes and the runs in its database
+func showProject(p *project.Project) (*projectDetail, error) {
+	info, err := p.Info()
+	if err != nil {
+		return nil, err
+	}
+	detail := &projectDetail{Info: info, Usage: make(map[string]int64)}
+	for _, name := range []string{project.DBFile, project.StateDir, project.ScreenshotsDir, project.ReportsDir} {
+		if detail.Usage[name], err = p.Usage(name); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := os.Stat(p.DB()); err == nil {
+		store, err := storage.Open(p.DB())
+		if err != nil {
+			return nil, err
+		}
+		defer store.Close()
+		if detail.Runs, err = store.Runs(); err != nil {
+			return nil, err
+		}
+	}
+	return detail, nil
+}
+
+// printProject writes project show's text output
+func printProject(detail *projectDetail) {
+	fmt.Printf("Project:  %s\nDir:      %s\nSize:     %s\n", detail.Name, detail.Dir, formatSize(detail.Size))
+	for _, name := range []string{project.DBFile, project.StateDir, project.ScreenshotsDir, project.ReportsDir} {
+		fmt.Printf("  %-12s  %s\n", name, formatSize(detail.Usage[name]))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tCOMMAND\tTARGET\tSTARTED\tSTATUS")
+	for _, run := range detail.Runs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", run.ID, run.Command, run.Target, run.StartedAt, run.Status)
+	}
+	w.Flush()
+}
+
+// formatSize writes a byte count in B, KB, MB or GB
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		size /= unit
+		if size < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", size, suffix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,244 @@
+// Package project keeps each engagement's results database, resume
+// checkpoints, screenshots and reports in a directory of its own, so one
+// client's scans never mix with another's:
+//
+//	<root>/acme/
+//		results.db    the -db results database
+//		state/        -resume checkpoints
+//		screenshots/  screenshot and pipeline galleries
+//		reports/      relative -o files: reports, and responses kept with -body
+//
+// The root is $SCANNER_PROJECTS, or ~/.scanner/projects.
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Names of the files and directories inside a project
+const (
+	DBFile         = "results.db"
+	StateDir       = "state"
+	ScreenshotsDir = "screenshots"
+	ReportsDir     = "reports"
+)
+
+// RootEnv overrides the directory projects are kept in
+const RootEnv = "SCANNER_PROJECTS"
+
+// validName keeps project names usable as directory names everywhere
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Project is one engagement's directory. All methods are safe to call on
+// a nil *Project, which stands for no project: paths are left as given.
+type Project struct {
+	Name string
+	Dir  string
+}
+
+// Info describes a project for listings
+type Info struct {
+	Name     string    `json:"name"`
+	Dir      string    `json:"dir"`
+	Size     int64     `json:"size_bytes"`
+	Modified time.Time `json:"modified"`
+}
+
+// Root returns the directory projects are kept in
+func Root() (string, error) {
+	if root := os.Getenv(RootEnv); root != "" {
+		return root, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the projects directory (set %s): %w", RootEnv, err)
+	}
+	return filepath.Join(home, ".scanner", "projects"), nil
+}
+
+// Open returns a project, creating its directories if it is new
+func Open(root, name string) (*Project, error) {
+	p, err := project(root, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{StateDir, ScreenshotsDir, ReportsDir} {
+		if err := os.MkdirAll(filepath.Join(p.Dir, dir), 0755); err != nil {
+			return nil, fmt.Errorf("creating project %s: %w", name, err)
+		}
+	}
+	return p, nil
+}
+
+// Load returns an existing project
+func Load(root, name string) (*Project, error) {
+	p, err := project(root, name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(p.Dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("no project named %q in %s", name, root)
+	}
+	return p, nil
+}
+
+// project validates a name and returns its project, without touching disk
+func project(root, name string) (*Project, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid project name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	return &Project{Name: name, Dir: filepath.Join(root, name)}, nil
+}
+
+// List returns the projects under root, by name
+func List(root string) ([]Info, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []Info
+	for _, entry := range entries {
+		if !entry.IsDir() || !validName.MatchString(entry.Name()) {
+			continue
+		}
+		p := &Project{Name: entry.Name(), Dir: filepath.Join(root, entry.Name())}
+		info, err := p.Info()
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, info)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects, nil
+}
+
+// Info sums the project's size and finds when it last changed
+func (p *Project) Info() (Info, error) {
+	info := Info{Name: p.Name, Dir: p.Dir}
+	size, modified, err := usage(p.Dir)
+	info.Size, info.Modified = size, modified
+	return info, err
+}
+
+// Usage returns the size of one of the project's files or directories,
+// such as StateDir; DBFile's includes SQLite's write-ahead log
+func (p *Project) Usage(name string) (int64, error) {
+	paths := []string{name}
+	if name == DBFile {
+		paths = append(paths, DBFile+"-wal", DBFile+"-shm")
+	}
+
+	var total int64
+	for _, path := range paths {
+		size, _, err := usage(filepath.Join(p.Dir, path))
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// usage walks a file or directory, summing sizes and finding the latest
+// modification; a missing path is empty
+func usage(path string) (int64, time.Time, error) {
+	var size int64
+	var modified time.Time
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		return nil
+	})
+	return size, modified, err
+}
+
+// DB returns the project's results database, or "" without a project
+func (p *Project) DB() string {
+	if p == nil {
+		return ""
+	}
+	return filepath.Join(p.Dir, DBFile)
+}
+
+// State returns the project's checkpoint directory, or "" without a
+// project
+func (p *Project) State() string {
+	if p == nil {
+		return ""
+	}
+	return filepath.Join(p.Dir, StateDir)
+}
+
+// Screenshots returns the project's screenshot directory, or "" without
+// a project
+func (p *Project) Screenshots() string {
+	if p == nil {
+		return ""
+	}
+	return filepath.Join(p.Dir, ScreenshotsDir)
+}
+
+// Report returns where a relative output path is written: under the
+// project's reports directory. Absolute paths, "-" and paths without a
+// project are returned as given.
+func (p *Project) Report(path string) string {
+	if p == nil || path == "" || path == "-" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.Dir, ReportsDir, path)
+}
+
+// Input returns the file a relative input path names: the path itself if
+// it exists, otherwise the report of that name if the project has one, so
+// a report written with -o can be read back by the same name
+func (p *Project) Input(path string) string {
+	if p == nil || path == "" || filepath.IsAbs(path) || fileExists(path) {
+		return path
+	}
+	if report := p.Report(path); fileExists(report) {
+		return report
+	}
+	return path
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Clean removes the project's resume checkpoints, which interrupted runs
+// leave behind, or with all the whole project
+func (p *Project) Clean(all bool) error {
+	if all {
+		return os.RemoveAll(p.Dir)
+	}
+	dir := filepath.Join(p.Dir, StateDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0755)
+}
@@ -0,0 +1,279 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// keywords are the words that are operators rather than field names
+var keywords = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"contains": "contains",
+	"in":       "in",
+	"matches":  "matches",
+}
+
+// lex splits an expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text := src[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(src[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("bad string at position %d: %w", i, err)
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			end := i + 1
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokNumber, src[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i + 1
+			for end < len(src) && (src[end] == '_' || src[end] == '.' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+			word := src[i:end]
+			if op, ok := keywords[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{tokOp, op, i})
+			} else {
+				tokens = append(tokens, token{tokIdent, word, i})
+			}
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "="} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			switch op {
+			case "":
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			case "=":
+				tokens = append(tokens, token{tokOp, "==", i})
+			case "=~":
+				tokens = append(tokens, token{tokOp, "matches", i})
+			default:
+				tokens = append(tokens, token{tokOp, op, i})
+			}
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// parser reads tokens into an expression tree by recursive descent:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | "(" or ")" | field [ op value | "in" "(" value { "," value } ")" ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, unexpected(closing, "\")\"")
+		}
+		return inner, nil
+	case tokIdent:
+		return p.parseComparison(t.text)
+	}
+	return nil, unexpected(t, "a field, \"!\" or \"(\"")
+}
+
+// parseComparison reads what follows a field: an operator and its value,
+// or nothing to test that the field is set
+func (p *parser) parseComparison(field string) (node, error) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return existsNode{field}, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=", "contains":
+		p.next()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{field: field, op: t.text, values: []value{v}}, nil
+	case "matches":
+		p.next()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(v.text)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern at position %d: %w", t.pos, err)
+		}
+		return compareNode{field: field, op: t.text, pattern: re}, nil
+	case "in":
+		p.next()
+		if open := p.next(); open.kind != tokLParen {
+			return nil, unexpected(open, "\"(\"")
+		}
+		var values []value
+		for {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if sep := p.next(); sep.kind == tokRParen {
+				break
+			} else if sep.kind != tokComma {
+				return nil, unexpected(sep, "\",\" or \")\"")
+			}
+		}
+		return compareNode{field: field, op: "==", values: values}, nil
+	}
+	return existsNode{field}, nil
+}
+
+// parseValue reads a literal: a quoted string, a number, true or false
+func (p *parser) parseValue() (value, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString:
+		return newValue(t.text), nil
+	case t.kind == tokNumber:
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			return value{}, fmt.Errorf("bad number %q at position %d", t.text, t.pos)
+		}
+		return newValue(t.text), nil
+	case t.kind == tokIdent && (strings.EqualFold(t.text, "true") || strings.EqualFold(t.text, "false")):
+		return newValue(strings.ToLower(t.text)), nil
+	}
+	return value{}, unexpected(t, "a quoted string, number, true or false")
+}
+
+// unexpected describes a token the grammar did not allow
+func unexpected(t token, want string) error {
+	if t.kind == tokEOF {
+		return fmt.Errorf("expected %s at end of query", want)
+	}
+	return fmt.Errorf("expected %s at position %d, got %q", want, t.pos, t.text)
+}
@@ -0,0 +1,341 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed filter expression over results, such as
+//
+//	status == 200 && tech contains "WordPress" && port in (80, 443)
+//
+// Fields name a result's JSON fields, with dots into nested objects and
+// lists (cves.id). A few short names stand for the fields results use:
+// status, tech, length, cve, ip, and host and port, which are also read
+// from a URL. A comparison holds if any of a list's values satisfies it,
+// strings compare without regard to case, and a field on its own holds if
+// it is set and not empty, zero or false.
+type Query struct {
+	source string
+	root   node
+}
+
+// Parse parses a query expression
+func Parse(src string) (*Query, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("query: %w", unexpected(t, "\"&&\", \"||\" or end of query"))
+	}
+	return &Query{source: src, root: root}, nil
+}
+
+// String returns the expression as given
+func (q *Query) String() string {
+	return q.source
+}
+
+// Match reports whether a JSON result satisfies the query
+func (q *Query) Match(result json.RawMessage) bool {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return false
+	}
+	return q.root.eval(fields)
+}
+
+// Filter returns the results that satisfy the query
+func (q *Query) Filter(results []json.RawMessage) []json.RawMessage {
+	var matched []json.RawMessage
+	for _, result := range results {
+		if q.Match(result) {
+			matched = append(matched, result)
+		}
+	}
+	return matched
+}
+
+// node is one part of an expression tree
+type node interface {
+	eval(fields map[string]interface{}) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) && n.right.eval(fields)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) || n.right.eval(fields)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(fields map[string]interface{}) bool {
+	return !n.operand.eval(fields)
+}
+
+// existsNode holds if a field has a value other than empty, zero or false
+type existsNode struct{ field string }
+
+func (n existsNode) eval(fields map[string]interface{}) bool {
+	for _, v := range lookup(fields, n.field) {
+		if v.text != "" && v.text != "false" && !(v.isNum && v.num == 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareNode compares a field's values with one literal, any of a list
+// (for in), or a pattern (for matches)
+type compareNode struct {
+	field   string
+	op      string
+	values  []value
+	pattern *regexp.Regexp
+}
+
+func (n compareNode) eval(fields map[string]interface{}) bool {
+	got := lookup(fields, n.field)
+	if n.op == "!=" {
+		// Holds only if no value is equal
+		equal := compareNode{field: n.field, op: "==", values: n.values}
+		return !equal.eval(fields)
+	}
+	for _, v := range got {
+		if n.pattern != nil {
+			if n.pattern.MatchString(v.text) {
+				return true
+			}
+			continue
+		}
+		for _, want := range n.values {
+			if compare(v, n.op, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// value is a literal or one of a result's values, as text and, if it is
+// one, as a number
+type value struct {
+	text  string
+	num   float64
+	isNum bool
+}
+
+func newValue(text string) value {
+	v := value{text: text}
+	if num, err := strconv.ParseFloat(text, 64); err == nil {
+		v.num, v.isNum = num, true
+	}
+	return v
+}
+
+// compare applies op to a result's value and a literal, numerically when
+// both are numbers
+func compare(got value, op string, want value) bool {
+	numeric := got.isNum && want.isNum
+	order := strings.Compare(strings.ToLower(got.text), strings.ToLower(want.text))
+	if numeric {
+		switch {
+		case got.num < want.num:
+			order = -1
+		case got.num > want.num:
+			order = 1
+		default:
+			order = 0
+		}
+	}
+
+	switch op {
+	case "==":
+		return order == 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	case "contains":
+		return strings.Contains(strings.ToLower(got.text), strings.ToLower(want.text))
+	}
+	return false
+}
+
+// aliases are the short field names and the fields they stand for, tried
+// in turn
+var aliases = map[string][]string{
+	"status": {"status_code"},
+	"tech":   {"technologies"},
+	"length": {"content_length"},
+	"cve":    {"cves.id"},
+	"ip":     {"ips"},
+	"host":   {"subdomain", "name"},
+}
+
+// lookup returns a field's values in a result, following aliases and
+// reading host and port from the result's URL when it has no such fields
+func lookup(fields map[string]interface{}, field string) []value {
+	if values := walk(fields, strings.Split(field, ".")); len(values) > 0 {
+		return values
+	}
+	for _, alias := range aliases[strings.ToLower(field)] {
+		if values := walk(fields, strings.Split(alias, ".")); len(values) > 0 {
+			return values
+		}
+	}
+
+	switch strings.ToLower(field) {
+	case "host", "port":
+		raw, _ := fields["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil
+		}
+		if strings.ToLower(field) == "host" {
+			return []value{newValue(u.Hostname())}
+		}
+		port := u.Port()
+		switch {
+		case port != "":
+		case u.Scheme == "https":
+			port = "443"
+		case u.Scheme == "http":
+			port = "80"
+		default:
+			return nil
+		}
+		return []value{newValue(port)}
+	}
+	return nil
+}
+
+// walk follows a field path through nested objects, and through each
+// element of lists, returning the values at its end
+func walk(v interface{}, path []string) []value {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return nil
+		}
+		child, ok := v[path[0]]
+		if !ok {
+			// Header names and the like differ in case
+			for k, c := range v {
+				if strings.EqualFold(k, path[0]) {
+					child, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil
+		}
+		return walk(child, path[1:])
+	case []interface{}:
+		var values []value
+		for _, elem := range v {
+			values = append(values, walk(elem, path)...)
+		}
+		return values
+	}
+
+	if len(path) > 0 {
+		return nil
+	}
+	switch v := v.(type) {
+	case string:
+		return []value{newValue(v)}
+	case float64:
+		return []value{{text: strconv.FormatFloat(v, 'f', -1, 64), num: v, isNum: true}}
+	case bool:
+		return []value{{text: strconv.FormatBool(v)}}
+	}
+	return nil
+}
+
+// reportLists are the fields of a pipeline report holding each stage's
+// results
+var reportLists = []string{"subdomains", "resolved", "ports", "probes", "screenshots", "crawl", "analysis"}
+
+// LoadFile reads a JSON or NDJSON results file written by any command.
+// Pipeline reports are read as the results of their stages, and -envelope
+// wrappers as the results they wrap.
+func LoadFile(path string) ([]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		// A file holds one array, or one value per line
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			items = []json.RawMessage{raw}
+		}
+		for _, item := range items {
+			results = append(results, expand(item)...)
+		}
+	}
+	return results, nil
+}
+
+// expand unwraps an enveloped result and splits a pipeline report into
+// its stages' results
+func expand(raw json.RawMessage) []json.RawMessage {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return []json.RawMessage{raw}
+	}
+	has := func(key string) bool { _, ok := fields[key]; return ok }
+
+	switch {
+	case has("scan_id") && has("result"):
+		return expand(fields["result"])
+	case has("domain") && has("stages"):
+		var results []json.RawMessage
+		for _, list := range reportLists {
+			var items []json.RawMessage
+			json.Unmarshal(fields[list], &items)
+			for _, item := range items {
+				results = append(results, expand(item)...)
+			}
+		}
+		return results
+	}
+	return []json.RawMessage{raw}
+}
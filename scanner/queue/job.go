@@ -0,0 +1,230 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils"
+)
+
+// Scans a job can ask for
+const (
+	ScanSubdomain = "subdomain"
+	ScanPortScan  = "portscan"
+	ScanProbe     = "probe"
+	ScanPipeline  = "pipeline"
+)
+
+// Job is one scan request read from the queue:
+//
+//	{"id": "nightly-42", "scan": "pipeline", "targets": ["example.com"],
+//	 "stages": "subdomain,probe", "rate_limit": 100, "scope": ["*.example.com"]}
+//
+// Options mirror the matching command's flags. Scope and exclusions are
+// inline rules, since a stateless worker has no scope file to read.
+type Job struct {
+	ID            string   `json:"id"`
+	Scan          string   `json:"scan"`
+	Targets       []string `json:"targets"`
+	Ports         []int    `json:"ports,omitempty"`
+	ServiceDetect bool     `json:"service_detect,omitempty"`
+	Stages        string   `json:"stages,omitempty"`
+	Wordlist      string   `json:"wordlist,omitempty"`
+	Passive       *bool    `json:"passive,omitempty"`
+	Bruteforce    bool     `json:"bruteforce,omitempty"`
+	Workers       int      `json:"workers,omitempty"`
+	Timeout       int      `json:"timeout,omitempty"`
+	Depth         int      `json:"depth,omitempty"`
+	MaxURLs       int      `json:"max_urls,omitempty"`
+	RateLimit     int      `json:"rate_limit,omitempty"`
+	StageRates    string   `json:"stage_rates,omitempty"`
+	Proxy         string   `json:"proxy,omitempty"`
+	Scope         []string `json:"scope,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+}
+
+// Message types published on the results queue
+const (
+	MessageResult = "result"
+	MessageDone   = "done"
+)
+
+// Job statuses reported in the done message
+const (
+	StatusOK          = "ok"
+	StatusPartial     = "partial"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+)
+
+// Message is published for each result a job finds and once when it ends
+type Message struct {
+	Job    string          `json:"job"`
+	Type   string          `json:"type"`
+	Scan   string          `json:"scan"`
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Set on the done message
+	Status  string   `json:"status,omitempty"`
+	Results int      `json:"results,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ParseJob decodes and validates a job
+func ParseJob(data []byte) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("parsing job: %w", err)
+	}
+	if job.ID == "" {
+		return nil, fmt.Errorf("job has no id")
+	}
+	if len(job.Targets) == 0 {
+		return &job, fmt.Errorf("job %s: no targets", job.ID)
+	}
+	switch job.Scan {
+	case ScanSubdomain, ScanPortScan, ScanProbe, ScanPipeline:
+	default:
+		return &job, fmt.Errorf("job %s: unknown scan %q", job.ID, job.Scan)
+	}
+	return &job, nil
+}
+
+// scope builds the job's scope from its inline rules, or nil if there are
+// none
+func (j *Job) scope() (*scope.Scope, error) {
+	switch {
+	case len(j.Scope) > 0:
+		s, err := scope.Parse(j.Scope)
+		if err != nil {
+			return nil, err
+		}
+		return s, s.Exclude(j.Exclude)
+	case len(j.Exclude) > 0:
+		return scope.Exclusions(j.Exclude)
+	default:
+		return nil, nil
+	}
+}
+
+// run executes the job, calling emit with each result as it is found and
+// drawing its requests from budget, shared with the worker's other jobs.
+// It returns the per-target errors that did not stop the job.
+func (j *Job) run(ctx context.Context, emit func(interface{}), budget *utils.Budget) ([]string, error) {
+	if _, err := utils.ParseProxy(j.Proxy); err != nil {
+		return nil, err
+	}
+	jobScope, err := j.scope()
+	if err != nil {
+		return nil, err
+	}
+	passive := j.Passive == nil || *j.Passive
+
+	switch j.Scan {
+	case ScanSubdomain:
+		var errs []string
+		for _, domain := range jobScope.Filter(j.Targets) {
+			scanner := subdomain.NewScanner(subdomain.Config{
+				Domain:     domain,
+				Wordlist:   j.Wordlist,
+				Workers:    j.Workers,
+				Timeout:    j.Timeout,
+				Passive:    passive,
+				Bruteforce: j.Bruteforce && j.Wordlist != "",
+				Proxy:      j.Proxy,
+				Scope:      jobScope,
+				Budget:     budget,
+				OnResult:   func(r subdomain.Result) { emit(r) },
+			})
+			if _, err := scanner.EnumerateContext(ctx); err != nil {
+				return errs, err
+			}
+			for _, sourceErr := range scanner.Errors() {
+				errs = append(errs, domain+": "+sourceErr)
+			}
+		}
+		return errs, nil
+
+	case ScanPortScan:
+		ports := j.Ports
+		if len(ports) == 0 {
+			ports = pipeline.DefaultPorts
+		}
+		scanner := portscan.NewScanner(portscan.Config{
+			Targets:       j.Targets,
+			Ports:         ports,
+			Workers:       j.Workers,
+			Timeout:       j.Timeout,
+			RateLimit:     j.RateLimit,
+			ServiceDetect: j.ServiceDetect,
+			Scope:         jobScope,
+			Budget:        budget,
+			OnResult:      func(r portscan.Result) { emit(r) },
+		})
+		_, err := scanner.ScanContext(ctx)
+		return nil, err
+
+	case ScanProbe:
+		prober := httpx.NewProber(httpx.ProbeConfig{
+			Targets:        j.Targets,
+			Workers:        j.Workers,
+			Timeout:        j.Timeout,
+			FollowRedirect: true,
+			RateLimit:      j.RateLimit,
+			Proxy:          j.Proxy,
+			Scope:          jobScope,
+			Budget:         budget,
+			OnResult:       func(r httpx.ProbeResult) { emit(r) },
+		})
+		_, err := prober.ProbeContext(ctx)
+		return nil, err
+
+	default:
+		stages, err := pipeline.ParseStages(j.Stages)
+		if err != nil {
+			return nil, err
+		}
+		rates, err := pipeline.ParseStageRates(j.StageRates)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []string
+		for _, domain := range jobScope.Filter(j.Targets) {
+			p := pipeline.New(pipeline.Config{
+				Domain:       domain,
+				Wordlist:     j.Wordlist,
+				Passive:      passive,
+				Bruteforce:   j.Bruteforce && j.Wordlist != "",
+				Ports:        j.Ports,
+				Workers:      j.Workers,
+				Timeout:      j.Timeout,
+				CrawlDepth:   j.Depth,
+				MaxURLs:      j.MaxURLs,
+				Stages:       stages,
+				RateLimit:    j.RateLimit,
+				StageRates:   rates,
+				Proxy:        j.Proxy,
+				Scope:        jobScope,
+				SharedBudget: budget,
+				OnResult:     func(stage string, result interface{}) { emit(result) },
+			})
+			report, err := p.Run(ctx)
+			if report != nil {
+				for _, stageErr := range report.Errors {
+					errs = append(errs, domain+": "+stageErr)
+				}
+			}
+			if err != nil {
+				return errs, err
+			}
+		}
+		return errs, nil
+	}
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsBroker reads jobs from a subject through a queue group, so each job
+// goes to one worker, and publishes messages to a results subject. Core
+// NATS delivers at most once: a job held by a worker that dies is lost, so
+// use Redis where jobs must survive crashes.
+type natsBroker struct {
+	conn    *nats.Conn
+	sub     *nats.Subscription
+	results string
+}
+
+// openNATS connects and joins the queue group
+func openNATS(rawURL string) (Broker, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	query := u.Query()
+	jobs := queryDefault(query, "jobs", "scanner.jobs")
+	group := queryDefault(query, "group", "scanner")
+	u.RawQuery = ""
+
+	conn, err := nats.Connect(u.String(), nats.Name(consumerName()))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	sub, err := conn.QueueSubscribeSync(jobs, group)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to %s: %w", jobs, err)
+	}
+
+	return &natsBroker{
+		conn:    conn,
+		sub:     sub,
+		results: queryDefault(query, "results", "scanner.results"),
+	}, nil
+}
+
+// Receive waits for the next job
+func (b *natsBroker) Receive(ctx context.Context) (*Delivery, error) {
+	msg, err := b.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Delivery{Data: msg.Data, Ack: func() error { return nil }}, nil
+}
+
+// Publish sends a message to the results subject
+func (b *natsBroker) Publish(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.conn.Publish(b.results, data)
+}
+
+// Close stops taking jobs and flushes pending results
+func (b *natsBroker) Close() error {
+	b.sub.Unsubscribe()
+	err := b.conn.FlushTimeout(10 * time.Second)
+	b.conn.Close()
+	return err
+}
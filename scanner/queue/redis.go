@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisClaimIdle is how long a job may sit unacknowledged with a consumer
+// before another worker takes it over, e.g. after the first one crashed
+const redisClaimIdle = 30 * time.Minute
+
+// redisBroker reads jobs from a stream through a consumer group, so each
+// job goes to one worker, and appends messages to a results stream. Each
+// job entry carries its JSON in a "job" field; each result entry carries a
+// Message in a "message" field.
+type redisBroker struct {
+	client   *redis.Client
+	jobs     string
+	results  string
+	group    string
+	consumer string
+}
+
+// openRedis connects and creates the consumer group if needed
+func openRedis(rawURL string) (Broker, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	query := u.Query()
+	b := &redisBroker{
+		jobs:     queryDefault(query, "jobs", "scanner:jobs"),
+		results:  queryDefault(query, "results", "scanner:results"),
+		group:    queryDefault(query, "group", "scanner"),
+		consumer: consumerName(),
+	}
+	u.RawQuery = ""
+
+	opts, err := redis.ParseURL(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	b.client = redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = b.client.XGroupCreateMkStream(ctx, b.jobs, b.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		b.client.Close()
+		return nil, fmt.Errorf("creating consumer group: %w", err)
+	}
+	return b, nil
+}
+
+// Receive returns a stale job abandoned by another consumer if there is
+// one, otherwise waits for a new job
+func (b *redisBroker) Receive(ctx context.Context) (*Delivery, error) {
+	for ctx.Err() == nil {
+		claimed, _, err := b.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   b.jobs,
+			Group:    b.group,
+			Consumer: b.consumer,
+			MinIdle:  redisClaimIdle,
+			Start:    "0-0",
+			Count:    1,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if len(claimed) > 0 {
+			return b.delivery(claimed[0]), nil
+		}
+
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    b.group,
+			Consumer: b.consumer,
+			Streams:  []string{b.jobs, ">"},
+			Count:    1,
+			Block:    5 * time.Second,
+		}).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		for _, stream := range streams {
+			if len(stream.Messages) > 0 {
+				return b.delivery(stream.Messages[0]), nil
+			}
+		}
+	}
+	return nil, ctx.Err()
+}
+
+// delivery wraps a stream entry
+func (b *redisBroker) delivery(msg redis.XMessage) *Delivery {
+	data, _ := msg.Values["job"].(string)
+	return &Delivery{
+		Data: []byte(data),
+		Ack: func() error {
+			return b.client.XAck(context.Background(), b.jobs, b.group, msg.ID).Err()
+		},
+	}
+}
+
+// Publish appends a message to the results stream
+func (b *redisBroker) Publish(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.results,
+		Values: map[string]interface{}{"message": string(data)},
+	}).Err()
+}
+
+// Close closes the connection
+func (b *redisBroker) Close() error {
+	return b.client.Close()
+}
+
+// queryDefault returns a query parameter or def if it is missing
+func queryDefault(query url.Values, key, def string) string {
+	if v := query.Get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// consumerName identifies this worker process to the broker
+func consumerName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "scanner"
+	}
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
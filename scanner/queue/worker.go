@@ -0,0 +1,183 @@
+// Package queue runs the scanner as a stateless worker behind a message
+// queue. Jobs are read from a Redis stream or NATS subject, and every
+// result, followed by a done message, is published to a results stream or
+// subject, so the scanner slots into existing automation pipelines.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/utils/log"
+)
+
+// Delivery is one job read from a broker. Ack tells the broker the job is
+// finished; a job that is never acknowledged may be redelivered.
+type Delivery struct {
+	Data []byte
+	Ack  func() error
+}
+
+// Broker is a job source and result sink
+type Broker interface {
+	// Receive blocks until a job arrives or ctx is cancelled
+	Receive(ctx context.Context) (*Delivery, error)
+
+	// Publish sends a message to the results queue
+	Publish(ctx context.Context, msg *Message) error
+
+	Close() error
+}
+
+// Open connects to the broker a URL names:
+//
+//	redis://[:password@]host:6379[/db]?jobs=scanner:jobs&results=scanner:results&group=scanner
+//	nats://host:4222?jobs=scanner.jobs&results=scanner.results&group=scanner
+//
+// Query parameters are optional; the defaults are shown.
+func Open(rawURL string) (Broker, error) {
+	switch {
+	case strings.HasPrefix(rawURL, "redis://"), strings.HasPrefix(rawURL, "rediss://"):
+		return openRedis(rawURL)
+	case strings.HasPrefix(rawURL, "nats://"), strings.HasPrefix(rawURL, "tls://"):
+		return openNATS(rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported queue URL %q: use redis:// or nats://", rawURL)
+	}
+}
+
+// Worker takes jobs from a broker and publishes their results
+type Worker struct {
+	broker Broker
+
+	// Concurrency is how many jobs run at once (default 1)
+	Concurrency int
+
+	// RateLimit caps the requests per second of all running jobs together,
+	// on top of each job's own rate limit. Zero is unlimited.
+	RateLimit int
+
+	// Log, if set, receives a line per job started and finished
+	Log io.Writer
+}
+
+// NewWorker creates a worker on a broker
+func NewWorker(broker Broker) *Worker {
+	return &Worker{broker: broker, Concurrency: 1}
+}
+
+// Run processes jobs until ctx is cancelled. A job interrupted by the
+// cancellation is reported as interrupted and left unacknowledged, so the
+// broker can hand it to another worker.
+func (w *Worker) Run(ctx context.Context) error {
+	slots := make(chan struct{}, max(w.Concurrency, 1))
+	budget := utils.NewBudget(w.RateLimit)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		delivery, err := w.broker.Receive(ctx)
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			w.process(ctx, delivery, budget)
+		}()
+	}
+}
+
+// process runs one job and publishes its results and outcome
+func (w *Worker) process(ctx context.Context, delivery *Delivery, budget *utils.Budget) {
+	job, err := ParseJob(delivery.Data)
+	if job == nil {
+		// Not even an ID to report against; drop it so it is not retried
+		w.logf("discarding job: %v", err)
+		w.ack(delivery)
+		return
+	}
+
+	done := &Message{Job: job.ID, Type: MessageDone, Scan: job.Scan, Status: StatusOK}
+	if err != nil {
+		done.Status = StatusFailed
+		done.Errors = []string{err.Error()}
+		w.publish(ctx, done)
+		w.ack(delivery)
+		return
+	}
+
+	w.logf("job %s: %s %s", job.ID, job.Scan, strings.Join(job.Targets, ","))
+	ctx = log.WithContext(ctx, log.FromContext(ctx).With("job", job.ID))
+
+	// Results are published in order, one at a time, from the scan's
+	// callbacks
+	var mu sync.Mutex
+	emit := func(result interface{}) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done.Results++
+		w.publish(ctx, &Message{Job: job.ID, Type: MessageResult, Scan: job.Scan, Result: data})
+	}
+
+	errs, err := job.run(ctx, emit, budget)
+	done.Errors = errs
+	switch {
+	case ctx.Err() != nil:
+		done.Status = StatusInterrupted
+	case err != nil:
+		done.Status = StatusFailed
+		done.Errors = append(done.Errors, err.Error())
+	case len(errs) > 0:
+		done.Status = StatusPartial
+	}
+
+	// The done message must go out even though ctx may be cancelled
+	w.publish(context.WithoutCancel(ctx), done)
+	w.logf("job %s: %s, %d results", job.ID, done.Status, done.Results)
+	if done.Status != StatusInterrupted {
+		w.ack(delivery)
+	}
+}
+
+// publish sends a message, logging failures
+func (w *Worker) publish(ctx context.Context, msg *Message) {
+	if err := w.broker.Publish(ctx, msg); err != nil {
+		w.logf("job %s: publishing %s: %v", msg.Job, msg.Type, err)
+	}
+}
+
+// ack acknowledges a delivery, logging failures
+func (w *Worker) ack(delivery *Delivery) {
+	if err := delivery.Ack(); err != nil {
+		w.logf("acknowledging job: %v", err)
+	}
+}
+
+// logf writes a line to the worker log
+func (w *Worker) logf(format string, args ...interface{}) {
+	if w.Log != nil {
+		fmt.Fprintf(w.Log, "[worker] "+format+"\n", args...)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// maxReportHosts caps the hosts listed in an executive summary
+const maxReportHosts = 10
+
+// riskKinds names the finding kinds in summaries, in the order listed
+var riskKinds = []struct{ kind, name string }{
+	{"takeover", "Takeover candidates"},
+	{"secret", "Exposed secrets"},
+	{"bucket", "Listable or unclaimed buckets"},
+	{"ftp", "Anonymous FTP"},
+	{"cve", "Known CVEs"},
+	{"panel", "Exposed panels"},
+	{"risky-port", "Risky open ports"},
+	{"graphql", "GraphQL introspection"},
+	{"method", "Dangerous HTTP methods"},
+	{"weak-ssh", "Weak SSH"},
+	{"missing-header", "Missing security headers"},
+}
+
+// reportDomain is one domain's report and its risk, as rendered
+type reportDomain struct {
+	Report *pipeline.Report
+	Risk   domainRisk
+	Top    []hostRisk
+	Kinds  []kindCount
+}
+
+// kindCount is how many findings of a kind a domain has
+type kindCount struct {
+	Name  string
+	Count int
+}
+
+// reportDomains scores pipeline reports, riskiest domain first, or
+// reports false for results that are not pipeline reports
+func reportDomains(results interface{}) ([]reportDomain, bool) {
+	var reports []*pipeline.Report
+	switch v := results.(type) {
+	case *pipeline.Report:
+		reports = []*pipeline.Report{v}
+	case []*pipeline.Report:
+		reports = v
+	default:
+		return nil, false
+	}
+
+	domains := make([]reportDomain, 0, len(reports))
+	for _, report := range reports {
+		risk := scoreReport(report)
+		domain := reportDomain{Report: report, Risk: risk, Top: risk.Hosts}
+		if len(domain.Top) > maxReportHosts {
+			domain.Top = domain.Top[:maxReportHosts]
+		}
+		for _, k := range riskKinds {
+			if n := risk.Counts[k.kind]; n > 0 {
+				domain.Kinds = append(domain.Kinds, kindCount{k.name, n})
+			}
+		}
+		domains = append(domains, domain)
+	}
+	sort.SliceStable(domains, func(i, j int) bool { return domains[i].Risk.Score > domains[j].Risk.Score })
+	return domains, true
+}
+
+// formatAsMarkdown renders pipeline reports as Markdown, opening with an
+// executive summary of each domain's risk
+func formatAsMarkdown(results interface{}) ([]byte, error) {
+	domains, ok := reportDomains(results)
+	if !ok {
+		return nil, fmt.Errorf("md output is only supported for pipeline reports")
+	}
+
+	var b strings.Builder
+	b.WriteString("# Recon report\n\n## Executive summary\n\n")
+	b.WriteString("| Domain | Risk | Score | Hosts with findings | Live URLs | Open ports |\n|---|---|---|---|---|---|\n")
+	for _, d := range domains {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %d |\n", mdCell(d.Report.Domain), strings.ToUpper(d.Risk.Level),
+			d.Risk.Score, len(d.Risk.Hosts), len(d.Report.Probes), len(d.Report.Ports))
+	}
+
+	for _, d := range domains {
+		r := d.Report
+		fmt.Fprintf(&b, "\n## %s\n\n", r.Domain)
+		fmt.Fprintf(&b, "**Risk: %s (%d/100)**. Stages %s, %s to %s.\n", strings.ToUpper(d.Risk.Level), d.Risk.Score,
+			strings.Join(r.Stages, ", "), r.StartedAt, r.FinishedAt)
+
+		if len(d.Kinds) > 0 {
+			b.WriteString("\n| Finding | Count |\n|---|---|\n")
+			for _, k := range d.Kinds {
+				fmt.Fprintf(&b, "| %s | %d |\n", k.Name, k.Count)
+			}
+		}
+		if len(d.Top) > 0 {
+			b.WriteString("\n### Riskiest hosts\n\n| Host | Risk | Score | Top finding |\n|---|---|---|---|\n")
+			for _, h := range d.Top {
+				fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", mdCell(h.Host), h.Level, h.Score, mdCell(h.Findings[0].Detail))
+			}
+
+			b.WriteString("\n### Findings by host\n")
+			for _, h := range d.Risk.Hosts {
+				fmt.Fprintf(&b, "\n#### %s (%s, %d)\n\n", h.Host, h.Level, h.Score)
+				for _, f := range h.Findings {
+					fmt.Fprintf(&b, "- **%s** (+%d) %s\n", f.Kind, f.Points, mdCell(f.Detail))
+				}
+			}
+		}
+
+		if len(r.Ports) > 0 {
+			b.WriteString("\n### Open ports\n\n| Host | Port | Service |\n|---|---|---|\n")
+			for _, p := range r.Ports {
+				fmt.Fprintf(&b, "| %s | %d | %s |\n", mdCell(p.Host), p.Port, mdCell(p.Service))
+			}
+		}
+		if len(r.Probes) > 0 {
+			b.WriteString("\n### Live URLs\n\n| URL | Status | Title | Technologies |\n|---|---|---|---|\n")
+			for _, p := range r.Probes {
+				fmt.Fprintf(&b, "| %s | %d | %s | %s |\n", mdCell(p.URL), p.StatusCode, mdCell(p.Title), mdCell(strings.Join(p.Technologies, ", ")))
+			}
+		}
+		if len(r.Errors) > 0 {
+			b.WriteString("\n### Errors\n\n")
+			for _, e := range r.Errors {
+				fmt.Fprintf(&b, "- %s\n", mdCell(e))
+			}
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// mdCell escapes a value for a Markdown table cell, keeping markup in
+// titles and banners from rendering
+func mdCell(s string) string {
+	s = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// formatAsHTML renders pipeline reports as a standalone HTML page,
+// opening with an executive summary of each domain's risk
+func formatAsHTML(results interface{}) ([]byte, error) {
+	domains, ok := reportDomains(results)
+	if !ok {
+		return nil, fmt.Errorf("html output is only supported for pipeline reports")
+	}
+	var b bytes.Buffer
+	if err := reportTemplate.Execute(&b, domains); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// reportTemplate lays out the domains of formatAsHTML
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Recon report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 1100px; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+.level { font-weight: bold; padding: 1px 6px; border-radius: 3px; color: #fff; }
+.critical { background: #8b0000; } .high { background: #d9480f; } .medium { background: #e0a800; }
+.low { background: #2b8a3e; } .none { background: #868e96; }
+details { margin: 0.3em 0; }
+</style>
+</head>
+<body>
+<h1>Recon report</h1>
+<h2>Executive summary</h2>
+<table>
+<tr><th>Domain</th><th>Risk</th><th>Score</th><th>Hosts with findings</th><th>Live URLs</th><th>Open ports</th></tr>
+{{range .}}<tr><td><a href="#{{.Report.Domain}}">{{.Report.Domain}}</a></td><td><span class="level {{.Risk.Level}}">{{upper .Risk.Level}}</span></td><td>{{.Risk.Score}}</td><td>{{len .Risk.Hosts}}</td><td>{{len .Report.Probes}}</td><td>{{len .Report.Ports}}</td></tr>
+{{end}}</table>
+{{range .}}
+<h2 id="{{.Report.Domain}}">{{.Report.Domain}} <span class="level {{.Risk.Level}}">{{upper .Risk.Level}} {{.Risk.Score}}/100</span></h2>
+<p>Stages {{join .Report.Stages ", "}}, {{.Report.StartedAt}} to {{.Report.FinishedAt}}.</p>
+{{if .Kinds}}<table>
+<tr><th>Finding</th><th>Count</th></tr>
+{{range .Kinds}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Top}}<h3>Riskiest hosts</h3>
+<table>
+<tr><th>Host</th><th>Risk</th><th>Score</th><th>Top finding</th></tr>
+{{range .Top}}<tr><td>{{.Host}}</td><td><span class="level {{.Level}}">{{upper .Level}}</span></td><td>{{.Score}}</td><td>{{(index .Findings 0).Detail}}</td></tr>
+{{end}}</table>
+<h3>Findings by host</h3>
+{{range .Risk.Hosts}}<details><summary>{{.Host}} <span class="level {{.Level}}">{{upper .Level}} {{.Score}}</span></summary>
+<ul>{{range .Findings}}<li><b>{{.Kind}}</b> (+{{.Points}}) {{.Detail}}</li>{{end}}</ul>
+</details>
+{{end}}{{end}}
+{{with .Report.Ports}}<h3>Open ports</h3>
+<table>
+<tr><th>Host</th><th>Port</th><th>Service</th></tr>
+{{range .}}<tr><td>{{.Host}}</td><td>{{.Port}}</td><td>{{.Service}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Report.Probes}}<h3>Live URLs</h3>
+<table>
+<tr><th>URL</th><th>Status</th><th>Title</th><th>Technologies</th></tr>
+{{range .}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.StatusCode}}</td><td>{{.Title}}</td><td>{{join .Technologies ", "}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Report.Errors}}<h3>Errors</h3>
+<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{end}}
+</body>
+</html>
+`))
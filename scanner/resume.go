@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/recon-suite/scanner/pkg/state"
+)
+
+// openCheckpoint opens the checkpoint for a command run, exiting on error
+func openCheckpoint(workspace, command, key string, resume bool) *state.Checkpoint {
+	checkpoint, err := state.Open(workspace, command, key, resume)
+	if err != nil {
+		fatal(err)
+	}
+
+	if n := checkpoint.Resumed(); n > 0 {
+		fmt.Fprintf(messages(), "Resuming from %s (%d units done)\n", checkpoint.Path(), n)
+	}
+	return checkpoint
+}
+
+// saveCheckpoint records a finished unit, warning instead of failing the scan
+func saveCheckpoint(checkpoint *state.Checkpoint, unit string, v interface{}) {
+	if err := checkpoint.Save(unit, v); err != nil {
+		warnf("saving checkpoint: %v", err)
+	}
+}
+
+// finishCheckpoint removes the checkpoint after a completed run
+func finishCheckpoint(checkpoint *state.Checkpoint) {
+	if err := checkpoint.Finish(); err != nil {
+		warnf("removing checkpoint: %v", err)
+	}
+}
+
+// finishRun removes the checkpoint after a completed run, or keeps it and
+// says how to continue if the run was interrupted
+func finishRun(ctx context.Context, checkpoint *state.Checkpoint) {
+	if ctx.Err() != nil {
+		fmt.Fprintf(messages(), "Partial results written; rerun with -resume to continue from %s\n", checkpoint.Path())
+		return
+	}
+	finishCheckpoint(checkpoint)
+}
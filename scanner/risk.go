@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+)
+
+// Risk levels, by score
+const (
+	riskCritical = "critical" // 70 and up
+	riskHigh     = "high"     // 40 and up
+	riskMedium   = "medium"   // 15 and up
+	riskLow      = "low"      // anything found
+	riskNone     = "none"
+)
+
+// maxRiskScore caps host and domain scores
+const maxRiskScore = 100
+
+// Points each kind of finding adds to its host's score. A host with one
+// takeover candidate or exposed secret rates high on that alone; missing
+// headers only tip the balance between otherwise equal hosts.
+const (
+	pointsTakeover       = 40
+	pointsSecret         = 30
+	pointsBucketListable = 30
+	pointsBucketMissing  = 30
+	pointsFTPWritable    = 30
+	pointsFTPAnonymous   = 15
+	pointsPanel          = 15
+	pointsRiskyPort      = 10
+	pointsIntrospection  = 10
+	pointsWebDAV         = 10
+	pointsTrace          = 5
+	pointsWeakSSH        = 5
+	pointsMissingHeader  = 1
+)
+
+// cvePoints are the points of a CVE by severity; one without a severity
+// counts as medium
+var cvePoints = map[string]int{"CRITICAL": 25, "HIGH": 15, "MEDIUM": 5, "LOW": 1}
+
+// riskyPorts are services that should rarely face the internet: remote
+// administration, file sharing, databases, caches and container APIs
+var riskyPorts = map[int]string{
+	23: "telnet", 135: "msrpc", 139: "netbios", 445: "smb", 1433: "mssql",
+	1521: "oracle", 2375: "docker", 3306: "mysql", 3389: "rdp", 5432: "postgresql",
+	5900: "vnc", 5984: "couchdb", 6379: "redis", 9200: "elasticsearch",
+	11211: "memcached", 27017: "mongodb",
+}
+
+// panelPattern matches the titles of login and administration panels
+var panelPattern = regexp.MustCompile(`(?i)\b(?:admin(?:istration|istrator)?|dashboard|control panel|cpanel|webmin|phpmyadmin|pgadmin|adminer|jenkins|grafana|kibana|portainer|rabbitmq management|tomcat (?:web application )?manager|sonarqube|gitlab|argo ?cd|prometheus|traefik|router login|management console)\b`)
+
+// riskFinding is one finding counted towards a host's score
+type riskFinding struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+	Points int    `json:"points"`
+}
+
+// hostRisk is a host's score and the findings behind it, worst first
+type hostRisk struct {
+	Host     string        `json:"host"`
+	Score    int           `json:"score"`
+	Level    string        `json:"level"`
+	Findings []riskFinding `json:"findings"`
+}
+
+// domainRisk is a pipeline report's score: its worst host's, plus five
+// for each other host rated high or critical
+type domainRisk struct {
+	Domain string         `json:"domain"`
+	Score  int            `json:"score"`
+	Level  string         `json:"level"`
+	Counts map[string]int `json:"counts"` // findings by kind
+	Hosts  []hostRisk     `json:"hosts"`  // hosts with findings, riskiest first
+}
+
+// riskLevel names the level of a score
+func riskLevel(score int) string {
+	switch {
+	case score >= 70:
+		return riskCritical
+	case score >= 40:
+		return riskHigh
+	case score >= 15:
+		return riskMedium
+	case score > 0:
+		return riskLow
+	}
+	return riskNone
+}
+
+// scoreReport weighs the findings of a pipeline report into per-host and
+// per-domain risk scores
+func scoreReport(report *pipeline.Report) domainRisk {
+	hosts := make(map[string]*hostRisk)
+	seen := make(map[string]bool)
+	add := func(host, kind, detail string, points int) {
+		if host == "" || seen[host+"\x00"+kind+"\x00"+detail] {
+			return
+		}
+		seen[host+"\x00"+kind+"\x00"+detail] = true
+		h := hosts[host]
+		if h == nil {
+			h = &hostRisk{Host: host}
+			hosts[host] = h
+		}
+		h.Findings = append(h.Findings, riskFinding{Kind: kind, Detail: detail, Points: points})
+	}
+
+	for _, port := range report.Ports {
+		scorePort(port, add)
+	}
+	for _, probe := range report.Probes {
+		host := urlHost(probe.URL)
+		if probe.StatusCode > 0 && panelPattern.MatchString(probe.Title) {
+			add(host, "panel", probe.Title+" at "+probe.URL, pointsPanel)
+		}
+		for _, cve := range probe.CVEs {
+			add(host, "cve", cve.ID+" in "+cve.Product+" "+cve.Version, cveRiskPoints(cve.Severity))
+		}
+	}
+	for _, analysis := range report.Analysis {
+		scoreAnalysis(analysis, add)
+	}
+
+	risk := domainRisk{Domain: report.Domain, Counts: make(map[string]int)}
+	for _, h := range hosts {
+		sort.SliceStable(h.Findings, func(i, j int) bool { return h.Findings[i].Points > h.Findings[j].Points })
+		for _, f := range h.Findings {
+			h.Score += f.Points
+			risk.Counts[f.Kind]++
+		}
+		if h.Score > maxRiskScore {
+			h.Score = maxRiskScore
+		}
+		h.Level = riskLevel(h.Score)
+		risk.Hosts = append(risk.Hosts, *h)
+	}
+	sort.Slice(risk.Hosts, func(i, j int) bool {
+		if risk.Hosts[i].Score != risk.Hosts[j].Score {
+			return risk.Hosts[i].Score > risk.Hosts[j].Score
+		}
+		return risk.Hosts[i].Host < risk.Hosts[j].Host
+	})
+
+	for i, h := range risk.Hosts {
+		if i == 0 {
+			risk.Score = h.Score
+		} else if h.Score >= 40 {
+			risk.Score += 5
+		}
+	}
+	if risk.Score > maxRiskScore {
+		risk.Score = maxRiskScore
+	}
+	risk.Level = riskLevel(risk.Score)
+	return risk
+}
+
+// scorePort adds the findings of an open port: risky services, anonymous
+// FTP, weak SSH and CVEs
+func scorePort(port portscan.Result, add func(host, kind, detail string, points int)) {
+	if !port.Open {
+		return
+	}
+	if name, ok := riskyPorts[port.Port]; ok {
+		add(port.Host, "risky-port", fmt.Sprintf("%d/%s open", port.Port, name), pointsRiskyPort)
+	}
+	if port.FTP != nil {
+		if port.FTP.Writable {
+			add(port.Host, "ftp", fmt.Sprintf("anonymous FTP writable on %d", port.Port), pointsFTPWritable)
+		} else {
+			add(port.Host, "ftp", fmt.Sprintf("anonymous FTP on %d", port.Port), pointsFTPAnonymous)
+		}
+	}
+	if port.SSH != nil && len(port.SSH.Weak) > 0 {
+		add(port.Host, "weak-ssh", fmt.Sprintf("%d weak SSH algorithms on %d", len(port.SSH.Weak), port.Port), pointsWeakSSH)
+	}
+	for _, cve := range port.CVEs {
+		add(port.Host, "cve", cve.ID+" in "+cve.Product+" "+cve.Version, cveRiskPoints(cve.Severity))
+	}
+}
+
+// scoreAnalysis adds the findings of an analyzed page: takeover
+// candidates, secrets, buckets, GraphQL introspection, dangerous methods
+// and missing headers
+func scoreAnalysis(a httpx.AnalysisResult, add func(host, kind, detail string, points int)) {
+	host := urlHost(a.URL)
+	if a.Takeover != "" {
+		add(host, "takeover", "unclaimed "+a.Takeover+" resource", pointsTakeover)
+	}
+
+	// Interesting entries are "<pattern name>: <match>"
+	for _, item := range a.Interesting {
+		name, match, ok := strings.Cut(item, ": ")
+		if _, secret := secretRules[name]; ok && secret {
+			if len(match) > 40 {
+				match = match[:40] + "..."
+			}
+			add(host, "secret", name+": "+match, pointsSecret)
+		}
+	}
+
+	for _, bucket := range a.Storage {
+		switch bucket.Access {
+		case httpx.StorageListable:
+			add(host, "bucket", "listable "+bucket.Provider+" bucket "+bucket.Bucket, pointsBucketListable)
+		case httpx.StorageMissing:
+			add(host, "bucket", "unclaimed "+bucket.Provider+" bucket "+bucket.Bucket, pointsBucketMissing)
+		}
+	}
+	for _, endpoint := range a.GraphQL {
+		if endpoint.Schema != nil {
+			add(host, "graphql", "introspection enabled at "+endpoint.URL, pointsIntrospection)
+		}
+	}
+	for _, m := range a.Methods {
+		points := pointsWebDAV
+		if m.Method == "TRACE" || m.Method == "TRACK" {
+			points = pointsTrace
+		}
+		add(host, "method", m.Method+" enabled: "+m.Evidence, points)
+	}
+
+	sh := a.SecurityHeaders
+	for _, header := range []struct{ name, value string }{
+		{"Content-Security-Policy", sh.CSP},
+		{"Strict-Transport-Security", sh.HSTS},
+		{"X-Frame-Options", sh.XFrameOptions},
+		{"X-Content-Type-Options", sh.XContentType},
+	} {
+		if header.value == "" {
+			add(host, "missing-header", header.name, pointsMissingHeader)
+		}
+	}
+}
+
+// cveRiskPoints returns the points of a CVE of severity
+func cveRiskPoints(severity string) int {
+	if points, ok := cvePoints[strings.ToUpper(severity)]; ok {
+		return points
+	}
+	return cvePoints["MEDIUM"]
+}
+
+// urlHost returns the host of a URL, without its port
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// SARIF 2.1.0 log structure, limited to the fields code scanning consumes
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+// sarifRules lists every rule the analyzer can report, in output order
+var sarifRules = []sarifRule{
+	{ID: "secret/aws-key", Name: "AWS Key", ShortDescription: sarifMessage{"AWS access key ID exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/private-key", Name: "Private Key", ShortDescription: sarifMessage{"Private key exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/api-key", Name: "API Key", ShortDescription: sarifMessage{"API key exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/password", Name: "Password Field", ShortDescription: sarifMessage{"Hardcoded password in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/github-token", Name: "GitHub Token", ShortDescription: sarifMessage{"GitHub token exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/slack-token", Name: "Slack Token", ShortDescription: sarifMessage{"Slack token exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/google-api-key", Name: "Google API Key", ShortDescription: sarifMessage{"Google API key exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "secret/stripe-key", Name: "Stripe Key", ShortDescription: sarifMessage{"Stripe live key exposed in response"}, DefaultConfig: sarifConfig{"error"}},
+	{ID: "headers/missing-csp", Name: "Content-Security-Policy", ShortDescription: sarifMessage{"Content-Security-Policy header missing"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "headers/missing-hsts", Name: "Strict-Transport-Security", ShortDescription: sarifMessage{"Strict-Transport-Security header missing"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "headers/missing-x-frame-options", Name: "X-Frame-Options", ShortDescription: sarifMessage{"X-Frame-Options header missing"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "headers/missing-x-content-type-options", Name: "X-Content-Type-Options", ShortDescription: sarifMessage{"X-Content-Type-Options header missing"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "takeover/candidate", Name: "Subdomain Takeover", ShortDescription: sarifMessage{"Host serves an unclaimed third-party service page"}, DefaultConfig: sarifConfig{"warning"}},
+	{ID: "graphql/endpoint", Name: "GraphQL Endpoint", ShortDescription: sarifMessage{"GraphQL endpoint found"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "graphql/introspection", Name: "GraphQL Introspection", ShortDescription: sarifMessage{"GraphQL endpoint allows schema introspection"}, DefaultConfig: sarifConfig{"warning"}},
+	{ID: "storage/bucket", Name: "Cloud Storage Bucket", ShortDescription: sarifMessage{"Cloud storage bucket referenced"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "storage/listable", Name: "Listable Bucket", ShortDescription: sarifMessage{"Cloud storage bucket allows anonymous listing"}, DefaultConfig: sarifConfig{"warning"}},
+	{ID: "storage/missing", Name: "Unclaimed Bucket", ShortDescription: sarifMessage{"Referenced cloud storage bucket does not exist"}, DefaultConfig: sarifConfig{"warning"}},
+	{ID: "method/trace", Name: "TRACE Enabled", ShortDescription: sarifMessage{"Server reflects TRACE requests (cross-site tracing)"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "method/track", Name: "TRACK Enabled", ShortDescription: sarifMessage{"Server reflects TRACK requests (cross-site tracing)"}, DefaultConfig: sarifConfig{"note"}},
+	{ID: "method/propfind", Name: "WebDAV PROPFIND", ShortDescription: sarifMessage{"Server answers WebDAV PROPFIND, listing content"}, DefaultConfig: sarifConfig{"warning"}},
+	{ID: "method/mkcol", Name: "WebDAV MKCOL", ShortDescription: sarifMessage{"Server allows WebDAV MKCOL, creating collections"}, DefaultConfig: sarifConfig{"warning"}},
+}
+
+// secretRules maps analyzer interesting-pattern names to secret rule IDs
+var secretRules = map[string]string{
+	"AWS Key":        "secret/aws-key",
+	"Private Key":    "secret/private-key",
+	"API Key":        "secret/api-key",
+	"Password Field": "secret/password",
+	"GitHub Token":   "secret/github-token",
+	"Slack Token":    "secret/slack-token",
+	"Google API Key": "secret/google-api-key",
+	"Stripe Key":     "secret/stripe-key",
+}
+
+// toSARIF converts analyzer findings in results to a SARIF log.
+// Results without analysis data produce a log with no findings.
+func toSARIF(results interface{}) sarifLog {
+	var analysis []httpx.AnalysisResult
+
+	switch v := results.(type) {
+	case []httpx.AnalysisResult:
+		analysis = v
+	case *pipeline.Report:
+		analysis = v.Analysis
+	case []*pipeline.Report:
+		for _, r := range v {
+			analysis = append(analysis, r.Analysis...)
+		}
+	}
+
+	findings := []sarifResult{}
+	for _, a := range analysis {
+		findings = append(findings, sarifFindings(a)...)
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:    "recon-scanner",
+				Version: version,
+				Rules:   sarifRules,
+			}},
+			Results: findings,
+		}},
+	}
+}
+
+// sarifFindings emits one SARIF result per secret, missing header,
+// takeover hit, GraphQL endpoint, storage bucket and dangerous method
+func sarifFindings(a httpx.AnalysisResult) []sarifResult {
+	var findings []sarifResult
+
+	add := func(ruleID, level, message string) {
+		findings = append(findings, sarifResult{
+			RuleID:  ruleID,
+			Level:   level,
+			Message: sarifMessage{Text: message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifact{URI: a.URL},
+				},
+			}},
+		})
+	}
+
+	// Interesting entries are "<pattern name>: <match>"
+	for _, item := range a.Interesting {
+		name, match, ok := strings.Cut(item, ": ")
+		if !ok {
+			continue
+		}
+		if ruleID, ok := secretRules[name]; ok {
+			add(ruleID, "error", name+" found: "+match)
+		}
+	}
+
+	sh := a.SecurityHeaders
+	if sh.CSP == "" {
+		add("headers/missing-csp", "note", "Content-Security-Policy header missing")
+	}
+	if sh.HSTS == "" {
+		add("headers/missing-hsts", "note", "Strict-Transport-Security header missing")
+	}
+	if sh.XFrameOptions == "" {
+		add("headers/missing-x-frame-options", "note", "X-Frame-Options header missing")
+	}
+	if sh.XContentType == "" {
+		add("headers/missing-x-content-type-options", "note", "X-Content-Type-Options header missing")
+	}
+
+	if a.Takeover != "" {
+		add("takeover/candidate", "warning", "Possible subdomain takeover: unclaimed "+a.Takeover+" resource")
+	}
+
+	for _, endpoint := range a.GraphQL {
+		if endpoint.Schema != nil {
+			add("graphql/introspection", "warning", fmt.Sprintf("GraphQL introspection enabled at %s: %d types", endpoint.URL, len(endpoint.Schema.Types)))
+		} else {
+			add("graphql/endpoint", "note", "GraphQL endpoint ("+endpoint.Evidence+"): "+endpoint.URL)
+		}
+	}
+
+	for _, bucket := range a.Storage {
+		switch bucket.Access {
+		case httpx.StorageListable:
+			add("storage/listable", "warning", "Anonymously listable "+bucket.Provider+" bucket: "+bucket.URL)
+		case httpx.StorageMissing:
+			add("storage/missing", "warning", "Referenced "+bucket.Provider+" bucket does not exist and could be claimed: "+bucket.Bucket)
+		default:
+			add("storage/bucket", "note", bucket.Provider+" bucket referenced: "+bucket.Bucket)
+		}
+	}
+
+	for _, m := range a.Methods {
+		level := "warning"
+		if m.Severity == "low" {
+			level = "note"
+		}
+		add("method/"+strings.ToLower(m.Method), level, m.Method+" enabled at "+m.URL+": "+m.Evidence)
+	}
+
+	return findings
+}
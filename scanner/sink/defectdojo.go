@@ -0,0 +1,397 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/reputation"
+	"github.com/recon-suite/scanner/pkg/vulndb"
+)
+
+// DefectDojo severities
+const (
+	ddCritical = "Critical"
+	ddHigh     = "High"
+	ddMedium   = "Medium"
+	ddLow      = "Low"
+	ddInfo     = "Info"
+)
+
+// ddSecretSeverity rates the secrets the analyzer finds; its other
+// interesting matches are informational
+var ddSecretSeverity = map[string]string{
+	"AWS Key": ddHigh, "Private Key": ddHigh, "GitHub Token": ddHigh, "Slack Token": ddHigh,
+	"Stripe Key": ddHigh, "API Key": ddMedium, "Password Field": ddMedium, "Google API Key": ddMedium,
+}
+
+// defectDojoSink imports findings into a DefectDojo test through the
+// reimport-scan API, as Generic Findings Import reports. The product,
+// engagement and test are created on first use, and each finding carries
+// a stable unique_id_from_tool, so findings already in the test are
+// updated rather than duplicated.
+type defectDojoSink struct {
+	endpoint    string
+	token       string
+	product     string
+	productType string
+	engagement  string
+	test        string
+	client      *http.Client
+}
+
+// ddFinding is one finding of a Generic Findings Import report
+type ddFinding struct {
+	Title            string       `json:"title"`
+	Description      string       `json:"description"`
+	Severity         string       `json:"severity"`
+	Date             string       `json:"date"`
+	UniqueID         string       `json:"unique_id_from_tool"`
+	VulnIDFromTool   string       `json:"vuln_id_from_tool"`
+	VulnerabilityIDs []ddVulnID   `json:"vulnerability_ids,omitempty"`
+	Component        string       `json:"component_name,omitempty"`
+	ComponentVersion string       `json:"component_version,omitempty"`
+	Endpoints        []ddEndpoint `json:"endpoints,omitempty"`
+	Active           bool         `json:"active"`
+	Verified         bool         `json:"verified"`
+	DynamicFinding   bool         `json:"dynamic_finding"`
+}
+
+type ddVulnID struct {
+	ID string `json:"vulnerability_id"`
+}
+
+type ddEndpoint struct {
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// openDefectDojo parses a defectdojo+http(s):// URL
+func openDefectDojo(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL: %w", err)
+	}
+	_, scheme, _ := strings.Cut(u.Scheme, "+")
+	if (scheme != "http" && scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: use defectdojo+https://host?token=KEY&product=NAME", rawURL)
+	}
+
+	query := u.Query()
+	s := &defectDojoSink{
+		token:       query.Get("token"),
+		product:     query.Get("product"),
+		productType: query.Get("product_type"),
+		engagement:  query.Get("engagement"),
+		test:        query.Get("test"),
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}
+	if s.token == "" || s.product == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: the API token and product are required (?token=KEY&product=NAME)", rawURL)
+	}
+	if s.engagement == "" {
+		s.engagement = "Recon"
+	}
+
+	u.Scheme, u.RawQuery = scheme, ""
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/reimport-scan/"
+	s.endpoint = u.String()
+	return s, nil
+}
+
+// Send imports the findings among results. Results with none, such as
+// subdomains or crawled URLs, send nothing.
+func (s *defectDojoSink) Send(ctx context.Context, meta Meta, results interface{}) error {
+	findings := defectDojoFindings(results, time.Now().UTC().Format("2006-01-02"))
+	if len(findings) == 0 {
+		return nil
+	}
+	report, err := json.Marshal(map[string][]ddFinding{"findings": findings})
+	if err != nil {
+		return err
+	}
+
+	// Each command gets its own test in the engagement unless one is named
+	test := s.test
+	if test == "" {
+		test = "recon-scanner " + meta.Command
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, field := range [][2]string{
+		{"scan_type", "Generic Findings Import"},
+		{"product_name", s.product},
+		{"product_type_name", s.productType},
+		{"engagement_name", s.engagement},
+		{"test_title", test},
+		{"auto_create_context", "true"},
+		{"deduplication_on_engagement", "true"},
+		{"close_old_findings", "false"},
+		{"minimum_severity", ddInfo},
+		{"scan_date", meta.Started.Format("2006-01-02")},
+		{"version", meta.Version},
+	} {
+		if field[1] != "" {
+			form.WriteField(field[0], field[1])
+		}
+	}
+	file, err := form.CreateFormFile("file", "scanner-findings.json")
+	if err != nil {
+		return err
+	}
+	file.Write(report)
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+s.token)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("importing %d findings into DefectDojo: %w", len(findings), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("importing %d findings into DefectDojo: %s: %s", len(findings), resp.Status, strings.TrimSpace(string(message)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// defectDojoFindings converts the findings among results: secrets,
+// takeovers, buckets, GraphQL endpoints, dangerous methods and missing
+// headers of analyzed pages, risky methods endpoints allow, access control bypasses, anonymous FTP, weak SSH, CVEs of
+// ports and probed URLs, and problems with probed URLs' certificates
+func defectDojoFindings(results interface{}, date string) []ddFinding {
+	var findings []ddFinding
+	add := func(kind, location, title, description, severity string, endpoint ddEndpoint) {
+		sum := sha256.Sum256([]byte(kind + "\x00" + location + "\x00" + title))
+		f := ddFinding{
+			Title:          title,
+			Description:    description,
+			Severity:       severity,
+			Date:           date,
+			UniqueID:       hex.EncodeToString(sum[:16]),
+			VulnIDFromTool: kind,
+			Active:         true,
+			DynamicFinding: true,
+		}
+		if endpoint.Host != "" {
+			f.Endpoints = []ddEndpoint{endpoint}
+		}
+		findings = append(findings, f)
+	}
+	addCVEs := func(location string, matches []vulndb.Match, endpoint ddEndpoint) {
+		for _, cve := range matches {
+			add("cve", location, cve.ID+" in "+cve.Product+" "+cve.Version,
+				fmt.Sprintf("%s %s at %s is affected by %s (CVSS %.1f), matched by its version.", cve.Product, cve.Version, location, cve.ID, cve.CVSS),
+				ddCVESeverity(cve.Severity), endpoint)
+			last := &findings[len(findings)-1]
+			last.VulnerabilityIDs = []ddVulnID{{cve.ID}}
+			last.Component, last.ComponentVersion = cve.Product, cve.Version
+		}
+	}
+
+	switch v := results.(type) {
+	case []httpx.AnalysisResult:
+		for _, a := range v {
+			endpoint := ddURLEndpoint(a.URL)
+			for _, item := range a.Interesting {
+				name, match, _ := strings.Cut(item, ": ")
+				severity, secret := ddSecretSeverity[name]
+				if !secret {
+					severity = ddInfo
+				}
+				add("interesting", a.URL+" "+match, name+" exposed at "+a.URL, "The response of "+a.URL+" contains a match for "+name+":\n\n    "+match, severity, endpoint)
+			}
+			if a.Takeover != "" {
+				add("takeover", a.URL, "Possible subdomain takeover of "+endpoint.Host,
+					endpoint.Host+" serves the page of an unclaimed "+a.Takeover+" resource; whoever claims it controls the host's content.", ddHigh, endpoint)
+			}
+			for _, bucket := range a.Storage {
+				switch bucket.Access {
+				case httpx.StorageListable:
+					add("bucket", bucket.URL, "Listable "+bucket.Provider+" bucket "+bucket.Bucket,
+						"The "+bucket.Provider+" bucket "+bucket.Bucket+", referenced by "+a.URL+", allows anyone to list its contents.", ddHigh, endpoint)
+				case httpx.StorageMissing:
+					add("bucket", bucket.URL, "Unclaimed "+bucket.Provider+" bucket "+bucket.Bucket,
+						"The "+bucket.Provider+" bucket "+bucket.Bucket+", referenced by "+a.URL+", does not exist and could be created by anyone.", ddHigh, endpoint)
+				}
+			}
+			for _, m := range a.Methods {
+				severity := ddMedium
+				if m.Severity == "low" {
+					severity = ddLow
+				}
+				add("method", m.URL+" "+m.Method, m.Method+" enabled on "+endpoint.Host,
+					"The server of "+a.URL+" has "+m.Method+" enabled; it answered:\n\n    "+m.Evidence, severity, ddURLEndpoint(m.URL))
+			}
+			for _, gql := range a.GraphQL {
+				if gql.Schema != nil {
+					add("graphql", gql.URL, "GraphQL introspection enabled at "+gql.URL,
+						fmt.Sprintf("The GraphQL endpoint %s answers introspection queries, disclosing its schema of %d types.", gql.URL, len(gql.Schema.Types)), ddLow, ddURLEndpoint(gql.URL))
+				}
+			}
+
+			var missing []string
+			sh := a.SecurityHeaders
+			for _, header := range []struct{ name, value string }{
+				{"Content-Security-Policy", sh.CSP},
+				{"Strict-Transport-Security", sh.HSTS},
+				{"X-Frame-Options", sh.XFrameOptions},
+				{"X-Content-Type-Options", sh.XContentType},
+			} {
+				if header.value == "" {
+					missing = append(missing, header.name)
+				}
+			}
+			if len(missing) > 0 {
+				add("missing-header", a.URL, "Missing security headers at "+a.URL,
+					a.URL+" does not send: "+strings.Join(missing, ", ")+".", ddInfo, endpoint)
+			}
+		}
+	case []httpx.BypassResult:
+		for _, b := range v {
+			severity := ddLow
+			if b.Severity == "high" {
+				severity = ddHigh
+			}
+			add("bypass", b.URL, "Access control bypass of "+b.URL+" ("+b.Technique+")",
+				fmt.Sprintf("%s returned %d, but %d to %s %s with the %s technique.", b.URL, b.OriginalStatus, b.StatusCode, b.Method, b.RequestURL, b.Technique),
+				severity, ddURLEndpoint(b.URL))
+		}
+	case []httpx.MethodResult:
+		for _, m := range v {
+			if len(m.Risky) == 0 {
+				continue
+			}
+			severity := ddMedium
+			if m.Severity == "high" {
+				severity = ddHigh
+			}
+			add("methods", m.URL, "Risky HTTP methods allowed on "+m.URL,
+				fmt.Sprintf("%s allows %s (accepted: %s; Allow: %s).", m.URL, strings.Join(m.Risky, ", "),
+					strings.Join(m.Accepted, ", "), strings.Join(m.Allow, ", ")),
+				severity, ddURLEndpoint(m.URL))
+		}
+	case []httpx.SmuggleResult:
+		for _, s := range v {
+			add("smuggle", s.URL+" "+s.Technique, "Possible "+s.Technique+" request smuggling on "+s.URL,
+				fmt.Sprintf("A %s timing probe to %s went unanswered for %dms, %d times, while a well-formed control was answered in %dms. "+
+					"The front end and back end may disagree on where requests end; nothing was smuggled, so confirm by hand.",
+					s.Technique, s.URL, s.DelayMS, s.Confirmations, s.ControlMS),
+				ddMedium, ddURLEndpoint(s.URL))
+		}
+	case []reputation.Result:
+		for _, r := range v {
+			severity, what := ddLow, "on a blocklist"
+			switch {
+			case r.Compromised:
+				severity, what = ddHigh, "known as compromised"
+			case r.Sinkholed:
+				severity, what = ddHigh, "sinkholed"
+			case r.Severity == "medium":
+				severity = ddMedium
+			}
+			var lists []string
+			for _, l := range r.Listings {
+				lists = append(lists, l.List+" ("+l.Category+")")
+			}
+			add("reputation", r.IP, r.IP+" is "+what,
+				fmt.Sprintf("%s (%s) is listed on %s.", r.IP, r.Host, strings.Join(lists, ", ")),
+				severity, ddEndpoint{Host: r.IP})
+		}
+	case []httpx.ProbeResult:
+		for _, p := range v {
+			endpoint := ddURLEndpoint(p.URL)
+			addCVEs(p.URL, p.CVEs, endpoint)
+
+			// A certificate belongs to the host and port, not each URL
+			// probed on it
+			endpoint.Path = ""
+			location := endpoint.Host
+			if u, err := url.Parse(p.URL); err == nil {
+				location = u.Host
+			}
+			for _, issue := range p.CertIssues {
+				add(issue.ID, location, "Certificate problem on "+location+": "+strings.TrimPrefix(issue.ID, "cert/"),
+					"The certificate "+location+" presents has a problem: "+issue.Detail+".", ddCVESeverity(issue.Severity), endpoint)
+			}
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			if !r.Open {
+				continue
+			}
+			location := r.Host + ":" + strconv.Itoa(r.Port)
+			endpoint := ddEndpoint{Protocol: r.Service, Host: r.Host, Port: r.Port}
+			if r.FTP != nil && r.FTP.Anonymous {
+				severity, title := ddMedium, "Anonymous FTP login on "+location
+				if r.FTP.Writable {
+					severity, title = ddHigh, "Anonymous FTP write access on "+location
+				}
+				add("ftp", location, title, "The FTP server on "+location+" accepts anonymous logins.", severity, endpoint)
+			}
+			if r.SSH != nil && len(r.SSH.Weak) > 0 {
+				add("weak-ssh", location, "Weak SSH algorithms on "+location,
+					"The SSH server on "+location+" offers: "+strings.Join(r.SSH.Weak, ", ")+".", ddLow, endpoint)
+			}
+			addCVEs(location, r.CVEs, endpoint)
+		}
+	case *pipeline.Report:
+		return defectDojoFindings([]*pipeline.Report{v}, date)
+	case []*pipeline.Report:
+		for _, report := range v {
+			for _, section := range []interface{}{report.Ports, report.Probes, report.Analysis} {
+				findings = append(findings, defectDojoFindings(section, date)...)
+			}
+		}
+	}
+	return findings
+}
+
+// ddURLEndpoint splits a URL into a DefectDojo endpoint
+func ddURLEndpoint(raw string) ddEndpoint {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return ddEndpoint{}
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return ddEndpoint{Protocol: u.Scheme, Host: u.Hostname(), Port: port, Path: strings.TrimPrefix(u.Path, "/")}
+}
+
+// ddCVESeverity maps an NVD severity to DefectDojo's; one without a
+// severity counts as medium
+func ddCVESeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL":
+		return ddCritical
+	case "HIGH":
+		return ddHigh
+	case "LOW":
+		return ddLow
+	}
+	return ddMedium
+}
@@ -0,0 +1,210 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// elasticBatch is how many documents go in one bulk request
+const elasticBatch = 500
+
+// elasticTemplate maps the documents of every index under a prefix. Run
+// fields and strings in results are keywords, so they can be filtered and
+// aggregated on in Kibana; strings also get a text subfield for full-text
+// search, and very long ones (bodies, banners) are kept out of the index.
+// Response headers are kept in the document but not mapped, since every
+// server's own header names would otherwise each add a field.
+const elasticTemplate = `{
+  "index_patterns": [%q],
+  "priority": 100,
+  "template": {
+    "mappings": {
+      "dynamic_templates": [
+        {"headers": {
+          "path_match": "result.headers",
+          "mapping": {"type": "object", "enabled": false}
+        }},
+        {"strings": {
+          "match_mapping_type": "string",
+          "mapping": {"type": "keyword", "ignore_above": 2048, "fields": {"text": {"type": "text"}}}
+        }}
+      ],
+      "properties": {
+        "@timestamp": {"type": "date"},
+        "kind": {"type": "keyword"},
+        "domain": {"type": "keyword"},
+        "run": {"properties": {
+          "id": {"type": "long"},
+          "command": {"type": "keyword"},
+          "target": {"type": "keyword"},
+          "started_at": {"type": "date"},
+          "scanner_version": {"type": "keyword"}
+        }},
+        "result": {"type": "object"}
+      }
+    }
+  }
+}`
+
+// elasticSink bulk-indexes documents into Elasticsearch or OpenSearch, one
+// index per result kind under a prefix (scanner-httpx-probe,
+// scanner-portscan), so each kind keeps its own field types
+type elasticSink struct {
+	endpoint string
+	index    string
+	username string
+	password string
+	apiKey   string
+	client   *http.Client
+
+	// The index template is installed before the first batch
+	once        sync.Once
+	templateErr error
+}
+
+// openElastic parses an elastic+http(s):// or opensearch+http(s):// URL
+func openElastic(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL: %w", err)
+	}
+	_, scheme, _ := strings.Cut(u.Scheme, "+")
+	if (scheme != "http" && scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: use elastic+https://host:9200", rawURL)
+	}
+
+	query := u.Query()
+	s := &elasticSink{
+		index:  strings.ToLower(query.Get("index")),
+		apiKey: query.Get("api_key"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.index == "" {
+		s.index = "scanner"
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+
+	u.Scheme, u.User, u.RawQuery = scheme, nil, ""
+	s.endpoint = strings.TrimSuffix(u.String(), "/")
+	return s, nil
+}
+
+// Send indexes results in batches of elasticBatch documents
+func (s *elasticSink) Send(ctx context.Context, meta Meta, results interface{}) error {
+	docs, err := Documents(meta, results)
+	if err != nil || len(docs) == 0 {
+		return err
+	}
+
+	s.once.Do(func() { s.templateErr = s.putTemplate(ctx) })
+	if s.templateErr != nil {
+		return s.templateErr
+	}
+
+	for start := 0; start < len(docs); start += elasticBatch {
+		if err := s.bulk(ctx, docs[start:min(start+elasticBatch, len(docs))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putTemplate installs or updates the index template for the prefix
+func (s *elasticSink) putTemplate(ctx context.Context) error {
+	body := fmt.Sprintf(elasticTemplate, s.index+"-*")
+	resp, err := s.do(ctx, http.MethodPut, "/_index_template/"+s.index, "application/json", strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("installing index template: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// bulk indexes one batch, reporting documents the cluster rejected
+func (s *elasticSink) bulk(ctx context.Context, docs []Document) error {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		action, _ := json.Marshal(map[string]map[string]string{"index": {"_index": s.index + "-" + doc.Kind}})
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(data)
+		body.WriteByte('\n')
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return fmt.Errorf("bulk indexing: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("bulk indexing: reading response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	rejected, reason := 0, ""
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status >= 300 {
+				rejected++
+				if reason == "" {
+					reason = outcome.Error.Type + ": " + outcome.Error.Reason
+				}
+			}
+		}
+	}
+	return fmt.Errorf("bulk indexing: %d of %d documents rejected (%s)", rejected, len(docs), reason)
+}
+
+// do sends an authenticated request, failing on error statuses
+func (s *elasticSink) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case s.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.apiKey)
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,143 @@
+// Package sink sends scan results to the log, search and vulnerability
+// management platforms teams already watch. Each result becomes one
+// document carrying the run it came from, so dashboards can filter and
+// compare scans without reading the scanner's own output files; DefectDojo
+// gets the findings among them instead.
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// Sink receives the results of a run, in one or more batches
+type Sink interface {
+	// Send delivers a batch of results: a slice of any result type, or
+	// pipeline reports, which are sent section by section. ctx bounds any
+	// retries.
+	Send(ctx context.Context, meta Meta, results interface{}) error
+}
+
+// Meta identifies the run results came from
+type Meta struct {
+	RunID   int64     `json:"id,omitempty"` // results database run ID, if -db was given
+	Command string    `json:"command"`
+	Target  string    `json:"target"`
+	Started time.Time `json:"started_at"`
+	Version string    `json:"scanner_version"`
+}
+
+// Open returns the sink a URL names:
+//
+//	elastic+https://[user:password@]host:9200?index=scanner&api_key=KEY
+//	opensearch+https://[user:password@]host:9200?index=scanner
+//	splunk+https://host:8088?token=TOKEN&index=main&sourcetype=scanner:result&source=scanner&insecure=false
+//	defectdojo+https://host?token=KEY&product=NAME&product_type=&engagement=Recon&test=
+//
+// Any scheme can use http instead of https. Query parameters other than
+// the Splunk HEC token and the DefectDojo API key and product are
+// optional; the defaults are shown, except that Splunk events go to the
+// token's default index unless one is given, and DefectDojo findings to a
+// test named after the command.
+func Open(rawURL string) (Sink, error) {
+	switch {
+	case strings.HasPrefix(rawURL, "elastic+"), strings.HasPrefix(rawURL, "opensearch+"):
+		return openElastic(rawURL)
+	case strings.HasPrefix(rawURL, "splunk+"):
+		return openSplunk(rawURL)
+	case strings.HasPrefix(rawURL, "defectdojo+"):
+		return openDefectDojo(rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported sink URL %q: use elastic+https://, opensearch+https://, splunk+https:// or defectdojo+https://", rawURL)
+	}
+}
+
+// Document is one result as sent to a sink
+type Document struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Run       Meta      `json:"run"`
+
+	// Kind names the result type, such as httpx-probe or portscan
+	Kind string `json:"kind"`
+
+	// Domain is the pipeline domain a report section belongs to
+	Domain string `json:"domain,omitempty"`
+
+	Result json.RawMessage `json:"result"`
+}
+
+// Documents flattens a batch of results into one document per result.
+// Anything that is not a slice is sent as a single document.
+func Documents(meta Meta, results interface{}) ([]Document, error) {
+	sent := time.Now().UTC()
+
+	var docs []Document
+	add := func(domain string, section interface{}) error {
+		v := reflect.ValueOf(section)
+		if v.Kind() != reflect.Slice {
+			v = reflect.ValueOf([]interface{}{section})
+		}
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i).Interface()
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, Document{Timestamp: sent, Run: meta, Kind: Kind(item), Domain: domain, Result: data})
+		}
+		return nil
+	}
+
+	var reports []*pipeline.Report
+	switch v := results.(type) {
+	case nil:
+		return nil, nil
+	case *pipeline.Report:
+		reports = []*pipeline.Report{v}
+	case []*pipeline.Report:
+		reports = v
+	default:
+		return docs, add("", results)
+	}
+
+	for _, report := range reports {
+		for _, section := range []interface{}{
+			report.Subdomains, report.Resolved, report.Ports, report.Probes, report.Crawl, report.Screenshots, report.Analysis,
+		} {
+			if err := add(report.Domain, section); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return docs, nil
+}
+
+// Kind names a result's type by its package and type name, without a
+// Result suffix: httpx.ProbeResult is httpx-probe, portscan.Result is
+// portscan
+func Kind(result interface{}) string {
+	t := reflect.TypeOf(result)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "result"
+	}
+
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	name := strings.ToLower(strings.TrimSuffix(t.Name(), "Result"))
+	switch {
+	case name == "" || name == pkg:
+		return pkg
+	case pkg == "":
+		return name
+	}
+	return pkg + "-" + name
+}
@@ -0,0 +1,184 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Splunk HEC batching and retry limits
+const (
+	splunkBatchEvents = 500
+	splunkBatchBytes  = 1 << 20
+	splunkRetries     = 3
+)
+
+// splunkSink sends documents as events to a Splunk HTTP Event Collector
+type splunkSink struct {
+	endpoint   string
+	token      string
+	index      string
+	sourcetype string
+	source     string
+	client     *http.Client
+
+	// retryWait is the wait before the first retry, doubled for each one
+	retryWait time.Duration
+}
+
+// splunkEvent is one HEC event envelope
+type splunkEvent struct {
+	Time       float64  `json:"time"`
+	Source     string   `json:"source"`
+	Sourcetype string   `json:"sourcetype"`
+	Index      string   `json:"index,omitempty"`
+	Event      Document `json:"event"`
+}
+
+// openSplunk parses a splunk+http(s):// URL
+func openSplunk(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL: %w", err)
+	}
+	_, scheme, _ := strings.Cut(u.Scheme, "+")
+	if (scheme != "http" && scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: use splunk+https://host:8088?token=TOKEN", rawURL)
+	}
+
+	query := u.Query()
+	s := &splunkSink{
+		token:      query.Get("token"),
+		index:      query.Get("index"),
+		sourcetype: query.Get("sourcetype"),
+		source:     query.Get("source"),
+		retryWait:  time.Second,
+	}
+	if s.token == "" && u.User != nil {
+		// Also accepted as splunk+https://TOKEN@host:8088
+		s.token = u.User.Username()
+	}
+	if s.token == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: the HEC token is required (?token=TOKEN)", rawURL)
+	}
+	if s.sourcetype == "" {
+		s.sourcetype = "scanner:result"
+	}
+	if s.source == "" {
+		s.source = "scanner"
+	}
+	insecure, _ := strconv.ParseBool(query.Get("insecure"))
+	s.client = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		},
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		path = "/services/collector/event"
+	}
+	u.Scheme, u.User, u.Path, u.RawPath, u.RawQuery = scheme, nil, path, "", ""
+	s.endpoint = u.String()
+	return s, nil
+}
+
+// Send posts results as events, several per request, up to
+// splunkBatchEvents events or splunkBatchBytes bytes
+func (s *splunkSink) Send(ctx context.Context, meta Meta, results interface{}) error {
+	docs, err := Documents(meta, results)
+	if err != nil || len(docs) == 0 {
+		return err
+	}
+
+	var batch bytes.Buffer
+	events := 0
+	for _, doc := range docs {
+		data, err := json.Marshal(splunkEvent{
+			Time:       float64(doc.Timestamp.UnixMilli()) / 1000,
+			Source:     s.source,
+			Sourcetype: s.sourcetype,
+			Index:      s.index,
+			Event:      doc,
+		})
+		if err != nil {
+			return err
+		}
+		if events > 0 && (events == splunkBatchEvents || batch.Len()+len(data) > splunkBatchBytes) {
+			if err := s.post(ctx, batch.Bytes(), events); err != nil {
+				return err
+			}
+			batch.Reset()
+			events = 0
+		}
+		batch.Write(data)
+		batch.WriteByte('\n')
+		events++
+	}
+	return s.post(ctx, batch.Bytes(), events)
+}
+
+// post sends one batch, retrying with backoff while the collector is
+// unreachable, busy or failing. Rejected events (bad token, unknown
+// index) are not retried.
+func (s *splunkSink) post(ctx context.Context, batch []byte, events int) error {
+	wait := s.retryWait
+	for attempt := 0; ; attempt++ {
+		retry, err := s.postOnce(ctx, batch)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == splunkRetries {
+			return fmt.Errorf("sending %d events to Splunk: %w", events, err)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// postOnce sends a batch, reporting whether a failure is worth retrying
+func (s *splunkSink) postOnce(ctx context.Context, batch []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(batch))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	// Errors carry {"text": "...", "code": N}
+	var reply struct {
+		Text string `json:"text"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &reply) == nil && reply.Text != "" {
+		message = reply.Text
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s: %s", resp.Status, message)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+)
+
+// sitemapLimit is the most URLs the sitemap protocol allows in one file
+const sitemapLimit = 50000
+
+// sitemapURLSet is a sitemap.xml document
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// crawledURLs returns the crawl results in crawl and pipeline results,
+// reporting false for other result types
+func crawledURLs(results interface{}) ([]httpx.CrawlResult, bool) {
+	switch v := results.(type) {
+	case []httpx.CrawlResult:
+		return v, true
+	case *pipeline.Report:
+		return v.Crawl, true
+	case []*pipeline.Report:
+		var all []httpx.CrawlResult
+		for _, report := range v {
+			all = append(all, report.Crawl...)
+		}
+		return all, true
+	}
+	return nil, false
+}
+
+// formatAsSitemap writes the pages in crawl and pipeline results as a
+// sitemap.xml, sorted and without fragments. Scripts, stylesheets and
+// form targets are left out, as is anything but http(s).
+func formatAsSitemap(results interface{}) ([]byte, error) {
+	crawled, ok := crawledURLs(results)
+	if !ok {
+		return nil, fmt.Errorf("sitemap output is only supported for crawl and pipeline results")
+	}
+
+	seen := make(map[string]bool)
+	var locs []string
+	for _, r := range crawled {
+		if r.Type != "page" && r.Type != "api" {
+			continue
+		}
+		if r.Method != "" && !strings.EqualFold(r.Method, "GET") {
+			continue
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		loc := u.String()
+		if !seen[loc] {
+			seen[loc] = true
+			locs = append(locs, loc)
+		}
+	}
+	if len(locs) > sitemapLimit {
+		return nil, fmt.Errorf("%d URLs is over the sitemap limit of %d; narrow the crawl with -scope", len(locs), sitemapLimit)
+	}
+	sort.Strings(locs)
+
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: []sitemapURL{}}
+	for _, loc := range locs {
+		set.URLs = append(set.URLs, sitemapURL{Loc: loc})
+	}
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// urlTree is one path segment of a site, and what was found at it
+type urlTree struct {
+	children map[string]*urlTree
+	types    map[string]bool
+	params   map[string]bool
+}
+
+func newURLTree() *urlTree {
+	return &urlTree{children: make(map[string]*urlTree), types: make(map[string]bool), params: make(map[string]bool)}
+}
+
+// formatAsTree writes the URLs in crawl and pipeline results as a tree
+// per site, one path segment per level. Entries other than pages are
+// marked with their type, and query and form parameters are listed after
+// the path that takes them.
+func formatAsTree(results interface{}) ([]byte, error) {
+	crawled, ok := crawledURLs(results)
+	if !ok {
+		return nil, fmt.Errorf("tree output is only supported for crawl and pipeline results")
+	}
+
+	sites := make(map[string]*urlTree)
+	for _, r := range crawled {
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		site := u.Scheme + "://" + u.Host
+		node := sites[site]
+		if node == nil {
+			node = newURLTree()
+			sites[site] = node
+		}
+		for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+			if segment == "" {
+				continue
+			}
+			child := node.children[segment]
+			if child == nil {
+				child = newURLTree()
+				node.children[segment] = child
+			}
+			node = child
+		}
+		if r.Type != "" && r.Type != "page" {
+			node.types[r.Type] = true
+		}
+		for name := range u.Query() {
+			node.params[name] = true
+		}
+		for _, name := range r.Params {
+			node.params[name] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i, site := range sortedKeys(sites) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(site + sites[site].label() + "\n")
+		sites[site].write(&sb, "")
+	}
+	return []byte(strings.TrimSuffix(sb.String(), "\n")), nil
+}
+
+// write draws the children of a node below it, each line prefixed by
+// the branches of its ancestors
+func (t *urlTree) write(sb *strings.Builder, prefix string) {
+	names := sortedKeys(t.children)
+	for i, name := range names {
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		child := t.children[name]
+		sb.WriteString(prefix + branch + name + child.label() + "\n")
+		child.write(sb, prefix+indent)
+	}
+}
+
+// label describes what was found at a node: its types other than page,
+// and the parameters it takes
+func (t *urlTree) label() string {
+	var label string
+	if len(t.types) > 0 {
+		label += " [" + strings.Join(sortedKeys(t.types), ",") + "]"
+	}
+	if len(t.params) > 0 {
+		label += " ?" + strings.Join(sortedKeys(t.params), ",")
+	}
+	return label
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
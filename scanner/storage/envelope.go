@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	neturl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/scope"
+)
+
+// EnvelopeSchema identifies the envelope format in its schema field
+const EnvelopeSchema = "recon-scanner.result/v1"
+
+// Envelope wraps one result in the identifiers that tie it to the scan
+// that found it and the assets it concerns. Asset IDs are AssetID's, the
+// same in every command's output and in the inventory, so a URL finding
+// joins the port, subdomain and IP it came from on IDs alone.
+type Envelope struct {
+	Schema string `json:"schema"`
+	ScanID string `json:"scan_id"`
+
+	// RunID is the results database run, if -db was given
+	RunID  int64  `json:"run_id,omitempty"`
+	Module string `json:"module"`
+
+	// TargetID is the ID of the domain, subdomain or IP the result is
+	// about, and Assets every asset it names, most specific first
+	TargetID string     `json:"target_id,omitempty"`
+	Assets   []AssetRef `json:"assets,omitempty"`
+
+	Result interface{} `json:"result"`
+}
+
+// AssetRef is one asset a result names
+type AssetRef struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Envelopes wraps each result of a batch, or a single result, for the
+// scan scanID of module
+func Envelopes(scanID string, runID int64, module string, results interface{}) []Envelope {
+	wrap := func(result interface{}) Envelope {
+		target, refs := References(result)
+		return Envelope{
+			Schema:   EnvelopeSchema,
+			ScanID:   scanID,
+			RunID:    runID,
+			Module:   module,
+			TargetID: target,
+			Assets:   refs,
+			Result:   result,
+		}
+	}
+
+	v := reflect.ValueOf(results)
+	if v.Kind() != reflect.Slice {
+		return []Envelope{wrap(results)}
+	}
+	envelopes := make([]Envelope, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		envelopes = append(envelopes, wrap(v.Index(i).Interface()))
+	}
+	return envelopes
+}
+
+// References returns the assets a result names, most specific first, and
+// the ID of its host among them. They are read from the fields results
+// share: URL, then Subdomain, Host, Zone or Domain for the host, Port,
+// and IP or IPs.
+func References(result interface{}) (string, []AssetRef) {
+	v := reflect.Indirect(reflect.ValueOf(result))
+	if v.Kind() != reflect.Struct {
+		return "", nil
+	}
+	text := func(name string) string {
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+			return strings.TrimSuffix(f.String(), ".")
+		}
+		return ""
+	}
+
+	var refs []AssetRef
+	seen := make(map[string]bool)
+	add := func(kind, value string) string {
+		id := AssetID(kind, value)
+		if !seen[id] {
+			seen[id] = true
+			refs = append(refs, AssetRef{ID: id, Kind: kind, Value: value})
+		}
+		return id
+	}
+
+	host, port := "", ""
+	if raw := text("URL"); raw != "" {
+		if u, err := neturl.Parse(raw); err == nil && u.Hostname() != "" {
+			add(KindURL, raw)
+			host, port = u.Hostname(), u.Port()
+			if port == "" {
+				port = "80"
+				if u.Scheme == "https" {
+					port = "443"
+				}
+			}
+		}
+	}
+	if host == "" {
+		for _, name := range []string{"Subdomain", "Host", "Zone", "Domain"} {
+			if host = text(name); host != "" {
+				break
+			}
+		}
+	}
+	if f := v.FieldByName("Port"); port == "" && f.IsValid() && f.Kind() == reflect.Int && f.Int() > 0 {
+		port = strconv.FormatInt(f.Int(), 10)
+	}
+
+	var target string
+	if host != "" {
+		if port != "" {
+			add(KindPort, net.JoinHostPort(host, port))
+		}
+		kind := hostKind(host)
+		target = add(kind, host)
+		if kind == KindSubdomain {
+			add(KindDomain, scope.RegisteredDomain(host))
+		}
+	}
+
+	if ip := text("IP"); ip != "" {
+		add(KindIP, ip)
+	}
+	if f := v.FieldByName("IPs"); f.IsValid() && f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String {
+		for i := 0; i < f.Len(); i++ {
+			add(KindIP, f.Index(i).String())
+		}
+	}
+	return target, refs
+}
+
+// hostKind tells an IP, a registered domain and a subdomain apart, as the
+// inventory files them
+func hostKind(host string) string {
+	registered := scope.RegisteredDomain(host)
+	switch {
+	case net.ParseIP(host) != nil:
+		return KindIP
+	case registered == "" || strings.EqualFold(host, registered):
+		return KindDomain
+	default:
+		return KindSubdomain
+	}
+}
+
+// Unwrap returns the results of enveloped JSON or NDJSON output as a JSON
+// array, so readers of one command's output take it either way. Data that
+// is not enveloped is returned unchanged.
+func Unwrap(data []byte) []byte {
+	type enveloped struct {
+		Schema string          `json:"schema"`
+		Result json.RawMessage `json:"result"`
+	}
+	var envelopes []enveloped
+	if err := json.Unmarshal(data, &envelopes); err != nil {
+		envelopes = nil
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for decoder.More() {
+			var e enveloped
+			if decoder.Decode(&e) != nil {
+				return data
+			}
+			envelopes = append(envelopes, e)
+		}
+	}
+	if len(envelopes) == 0 || envelopes[0].Schema != EnvelopeSchema {
+		return data
+	}
+
+	results := make([]json.RawMessage, 0, len(envelopes))
+	for _, e := range envelopes {
+		results = append(results, e.Result)
+	}
+	unwrapped, err := json.Marshal(results)
+	if err != nil {
+		return data
+	}
+	return unwrapped
+}
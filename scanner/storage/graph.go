@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Graph export formats; neo4j is a directory of CSV files, written by
+// WriteNeo4j
+const (
+	GraphFormatJSON    = "json"
+	GraphFormatDOT     = "dot"
+	GraphFormatGraphML = "graphml"
+	GraphFormatNeo4j   = "neo4j"
+)
+
+// Edge types, named by what the parent does to the child
+const (
+	EdgeHasSubdomain = "has-subdomain"
+	EdgeResolvesTo   = "resolves-to"
+	EdgeExposes      = "exposes"
+	EdgeServes       = "serves"
+	EdgeLinksTo      = "links-to"
+	EdgeRuns         = "runs"
+	EdgeHasFinding   = "has-finding"
+)
+
+// GraphEdge is a typed link between two assets
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// Graph is a set of assets and the typed links among them, for graph
+// tools to explore the attack surface
+type Graph struct {
+	Nodes []Asset     `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Graph returns the assets under a root, as for Query, and every link
+// between them, URL to URL links included
+func (inv *Inventory) Graph(under string) *Graph {
+	g := &Graph{Nodes: inv.Query("", under)}
+	included := make(map[string]*Asset, len(g.Nodes))
+	for i := range g.Nodes {
+		included[g.Nodes[i].ID] = &g.Nodes[i]
+	}
+
+	for _, node := range g.Nodes {
+		for _, child := range inv.children[node.ID] {
+			if c, ok := included[child]; ok {
+				g.Edges = append(g.Edges, GraphEdge{From: node.ID, To: child, Type: edgeType(node.Kind, c.Kind)})
+			}
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// edgeType names a link by the kinds it joins
+func edgeType(parent, child string) string {
+	switch child {
+	case KindSubdomain:
+		return EdgeHasSubdomain
+	case KindIP:
+		return EdgeResolvesTo
+	case KindPort:
+		return EdgeExposes
+	case KindURL:
+		if parent == KindURL {
+			return EdgeLinksTo
+		}
+		return EdgeServes
+	case KindTech:
+		return EdgeRuns
+	case KindFinding:
+		return EdgeHasFinding
+	}
+	return "has"
+}
+
+// Export renders the graph in the given format (json, dot, graphml)
+func (g *Graph) Export(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case GraphFormatJSON, "":
+		return json.MarshalIndent(g, "", "  ")
+	case GraphFormatDOT:
+		return g.toDOT(), nil
+	case GraphFormatGraphML:
+		return g.toGraphML()
+	default:
+		return nil, fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// toDOT renders the graph for Graphviz, one shape per kind
+func (g *Graph) toDOT() []byte {
+	shapes := map[string]string{
+		KindDomain: "doubleoctagon", KindSubdomain: "octagon", KindIP: "ellipse",
+		KindPort: "circle", KindURL: "box", KindTech: "component", KindFinding: "note",
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph assets {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&sb, "  %q [label=%q, kind=%q, shape=%s];\n", node.ID, node.Value, node.Kind, shapes[node.Kind])
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&sb, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Type)
+	}
+	sb.WriteString("}\n")
+	return []byte(sb.String())
+}
+
+// graphML document structure
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// toGraphML renders the graph as GraphML, for Gephi, yEd or Cytoscape
+func (g *Graph) toGraphML() ([]byte, error) {
+	doc := graphMLDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+			{ID: "value", For: "node", AttrName: "value", AttrType: "string"},
+			{ID: "detail", For: "node", AttrName: "detail", AttrType: "string"},
+			{ID: "first_seen", For: "node", AttrName: "first_seen", AttrType: "string"},
+			{ID: "last_seen", For: "node", AttrName: "last_seen", AttrType: "string"},
+			{ID: "type", For: "edge", AttrName: "type", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "assets", EdgeDefault: "directed"},
+	}
+
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "kind", Value: node.Kind},
+				{Key: "value", Value: node.Value},
+				{Key: "detail", Value: node.Detail},
+				{Key: "first_seen", Value: node.FirstSeen},
+				{Key: "last_seen", Value: node.LastSeen},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.From,
+			Target: edge.To,
+			Data:   []graphMLData{{Key: "type", Value: edge.Type}},
+		})
+	}
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), output...), nil
+}
+
+// neo4jLabels are the node labels of each kind
+var neo4jLabels = map[string]string{
+	KindDomain: "Domain", KindSubdomain: "Subdomain", KindIP: "IP", KindPort: "Port",
+	KindURL: "URL", KindTech: "Tech", KindFinding: "Finding",
+}
+
+// WriteNeo4j writes the graph to dir as nodes.csv and relationships.csv,
+// with the headers neo4j-admin database import expects:
+//
+//	neo4j-admin database import full --nodes=nodes.csv --relationships=relationships.csv
+func (g *Graph) WriteNeo4j(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	nodes := [][]string{{"id:ID", "value", "detail", "first_seen", "last_seen", ":LABEL"}}
+	for _, node := range g.Nodes {
+		nodes = append(nodes, []string{node.ID, node.Value, node.Detail, node.FirstSeen, node.LastSeen, "Asset;" + neo4jLabels[node.Kind]})
+	}
+	relationships := [][]string{{":START_ID", ":END_ID", ":TYPE"}}
+	for _, edge := range g.Edges {
+		relationships = append(relationships, []string{edge.From, edge.To, strings.ToUpper(strings.ReplaceAll(edge.Type, "-", "_"))})
+	}
+
+	for name, rows := range map[string][][]string{"nodes.csv": nodes, "relationships.csv": relationships} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		w.WriteAll(rows)
+		if err := w.Error(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,462 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/scope"
+	"github.com/recon-suite/scanner/pkg/snmp"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+)
+
+// Asset kinds, in hierarchy order: a domain has subdomains, a subdomain
+// resolves to IPs, an IP has open ports, a port serves URLs and a URL runs
+// technologies and has findings. URLs also link to the URLs crawled from
+// them.
+const (
+	KindDomain    = "domain"
+	KindSubdomain = "subdomain"
+	KindIP        = "ip"
+	KindPort      = "port"
+	KindURL       = "url"
+	KindTech      = "tech"
+	KindFinding   = "finding"
+)
+
+// inventorySchema holds every asset ever seen, with stable IDs, and the
+// parent → child links between them. Rows are upserted by each run rather
+// than duplicated.
+var inventorySchema = []string{
+	`CREATE TABLE IF NOT EXISTS inventory (
+		id         TEXT PRIMARY KEY,
+		kind       TEXT NOT NULL,
+		value      TEXT NOT NULL,
+		detail     TEXT NOT NULL DEFAULT '',
+		first_seen TEXT NOT NULL,
+		last_seen  TEXT NOT NULL,
+		first_run  BIGINT NOT NULL,
+		last_run   BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS inventory_links (
+		parent     TEXT NOT NULL,
+		child      TEXT NOT NULL,
+		first_seen TEXT NOT NULL,
+		last_seen  TEXT NOT NULL,
+		PRIMARY KEY (parent, child)
+	)`,
+	`CREATE INDEX IF NOT EXISTS inventory_kind ON inventory(kind, value)`,
+	`CREATE INDEX IF NOT EXISTS inventory_links_child ON inventory_links(child)`,
+}
+
+// Asset is one inventory entry
+type Asset struct {
+	ID        string   `json:"id"`
+	Kind      string   `json:"kind"`
+	Value     string   `json:"value"`
+	Detail    string   `json:"detail,omitempty"`
+	FirstSeen string   `json:"first_seen"`
+	LastSeen  string   `json:"last_seen"`
+	FirstRun  int64    `json:"first_run"`
+	LastRun   int64    `json:"last_run"`
+	Parents   []string `json:"parents,omitempty"`
+}
+
+// AssetID returns the stable ID of an asset, the same in every database
+func AssetID(kind, value string) string {
+	sum := sha1.Sum([]byte(kind + "\x00" + strings.ToLower(value)))
+	return kind + "-" + hex.EncodeToString(sum[:6])
+}
+
+// inventoryBatch collects the assets and links found in one Save
+type inventoryBatch struct {
+	assets map[string]*Asset
+	links  map[[2]string]bool
+
+	// hostIPs maps resolved hosts to their IPs so ports hang off the IPs
+	hostIPs map[string][]string
+}
+
+// add records an asset, returning its ID
+func (b *inventoryBatch) add(kind, value, detail string) string {
+	id := AssetID(kind, value)
+	if a, ok := b.assets[id]; ok {
+		if detail != "" {
+			a.Detail = detail
+		}
+		return id
+	}
+	b.assets[id] = &Asset{ID: id, Kind: kind, Value: value, Detail: detail}
+	return id
+}
+
+// link records a parent → child relationship
+func (b *inventoryBatch) link(parent, child string) {
+	b.links[[2]string{parent, child}] = true
+}
+
+// host adds a hostname or IP and returns its ID, linking a subdomain to
+// its domain
+func (b *inventoryBatch) host(host, domain string) string {
+	if net.ParseIP(host) != nil {
+		return b.add(KindIP, host, "")
+	}
+
+	if domain == "" || !strings.HasSuffix(host, "."+domain) {
+		domain = scope.RegisteredDomain(host)
+	}
+	if domain == "" || strings.EqualFold(host, domain) {
+		return b.add(KindDomain, host, "")
+	}
+
+	id := b.add(KindSubdomain, host, "")
+	b.link(b.add(KindDomain, domain, ""), id)
+	return id
+}
+
+// resolved adds a host's IPs under it
+func (b *inventoryBatch) resolved(host, domain string, ips []string) {
+	hostID := b.host(host, domain)
+	for _, ip := range ips {
+		b.link(hostID, b.add(KindIP, ip, ""))
+	}
+	if len(ips) > 0 {
+		b.hostIPs[host] = ips
+	}
+}
+
+// port adds an open port under the IPs its host resolved to, or under the
+// host itself if it was not resolved in this batch
+func (b *inventoryBatch) port(host string, port int, service, domain string) string {
+	id := b.add(KindPort, net.JoinHostPort(host, strconv.Itoa(port)), service)
+
+	parents := b.hostIPs[host]
+	if len(parents) == 0 {
+		b.link(b.host(host, domain), id)
+		return id
+	}
+	for _, ip := range parents {
+		b.link(b.add(KindIP, ip, ""), id)
+	}
+	return id
+}
+
+// url adds a URL under the port it is served on
+func (b *inventoryBatch) url(raw, detail, domain string) string {
+	id := b.add(KindURL, raw, detail)
+
+	parsed, err := neturl.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return id
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+	n, _ := strconv.Atoi(port)
+	b.link(b.port(parsed.Hostname(), n, "", domain), id)
+	return id
+}
+
+// collect walks a batch of results into assets and links
+func (b *inventoryBatch) collect(results interface{}, domain string) {
+	switch v := results.(type) {
+	case []subdomain.Result:
+		for _, r := range v {
+			b.resolved(r.Subdomain, domain, r.IPs)
+		}
+	case []subdomain.ResolutionResult:
+		for _, r := range v {
+			b.resolved(r.Subdomain, domain, r.IPs)
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			if !r.Open {
+				continue
+			}
+			portID := b.port(r.Host, r.Port, r.Service, domain)
+			if f, ok := ftpFinding(r); ok {
+				b.link(portID, b.add(KindFinding, ftpURL(r)+" "+f.kind+":"+f.name, f.detail))
+			}
+		}
+	case []snmp.Result:
+		for _, r := range v {
+			b.agent(r, domain)
+		}
+	case []httpx.ProbeResult:
+		for _, r := range v {
+			urlID := b.url(r.URL, strconv.Itoa(r.StatusCode)+" "+r.Title, domain)
+			for _, tech := range r.Technologies {
+				b.link(urlID, b.add(KindTech, tech, ""))
+			}
+			for _, f := range certFindings(r) {
+				b.link(urlID, b.add(KindFinding, r.URL+" "+f.kind+":"+f.name, f.detail))
+			}
+		}
+	case []httpx.CrawlResult:
+		for _, r := range v {
+			urlID := b.url(r.URL, r.Type, domain)
+			if r.Referrer != "" && r.Referrer != r.URL {
+				b.link(b.url(r.Referrer, "", domain), urlID)
+			}
+		}
+	case []httpx.AnalysisResult:
+		for _, r := range v {
+			urlID := b.url(r.URL, "", domain)
+			for _, f := range analysisFindings(r) {
+				value := r.URL + " " + f.kind + ":" + f.name
+				b.link(urlID, b.add(KindFinding, value, f.detail))
+			}
+		}
+	case []httpx.BypassResult:
+		for _, r := range v {
+			f := bypassFinding(r)
+			b.link(b.url(r.URL, "", domain), b.add(KindFinding, r.URL+" "+f.kind+":"+f.name, f.detail))
+		}
+	case []httpx.MethodResult:
+		for _, r := range v {
+			if f, ok := methodFinding(r); ok {
+				b.link(b.url(r.URL, "", domain), b.add(KindFinding, r.URL+" "+f.kind+":"+f.name, f.detail))
+			}
+		}
+	case []httpx.SmuggleResult:
+		for _, r := range v {
+			f := smuggleFinding(r)
+			b.link(b.url(r.URL, "", domain), b.add(KindFinding, r.URL+" "+f.kind+":"+f.name, f.detail))
+		}
+	case *pipeline.Report:
+		b.report(v)
+	case []*pipeline.Report:
+		for _, report := range v {
+			b.report(report)
+		}
+	}
+}
+
+// agent adds an SNMP agent's port, and the hostname and addresses the
+// device disclosed. A fully qualified sysName gets every address as a
+// resolution; otherwise the addresses hang off the scanned host.
+func (b *inventoryBatch) agent(r snmp.Result, domain string) {
+	var ips []string
+	if net.ParseIP(r.Host) != nil {
+		ips = append(ips, r.Host)
+	}
+	for _, a := range r.Addresses {
+		ip := net.ParseIP(a.IP)
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || a.IP == r.Host {
+			continue
+		}
+		ips = append(ips, a.IP)
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(r.SysName), ".")
+	if strings.Contains(name, ".") && net.ParseIP(name) == nil {
+		b.resolved(name, domain, ips)
+	} else {
+		hostID := b.host(r.Host, domain)
+		for _, ip := range ips {
+			if ip != r.Host {
+				b.link(hostID, b.add(KindIP, ip, ""))
+			}
+		}
+	}
+	b.port(r.Host, r.Port, "snmp", domain)
+}
+
+// report collects a pipeline report, resolutions first so ports find
+// their IPs
+func (b *inventoryBatch) report(report *pipeline.Report) {
+	for _, section := range []interface{}{
+		report.Subdomains, report.Resolved, report.Ports, report.Probes, report.Crawl, report.Analysis,
+	} {
+		b.collect(section, report.Domain)
+	}
+}
+
+// updateInventory upserts the batch's assets and links for this run
+func (r *Run) updateInventory(tx *sql.Tx, results interface{}) error {
+	b := &inventoryBatch{
+		assets:  make(map[string]*Asset),
+		links:   make(map[[2]string]bool),
+		hostIPs: make(map[string][]string),
+	}
+	b.collect(results, "")
+
+	// Rows are upserted in key order so that runs sharing a store lock them
+	// in the same order and cannot deadlock
+	ids := make([]string, 0, len(b.assets))
+	for id := range b.assets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	links := make([][2]string, 0, len(b.links))
+	for link := range b.links {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i][0] != links[j][0] {
+			return links[i][0] < links[j][0]
+		}
+		return links[i][1] < links[j][1]
+	})
+
+	seen := now()
+	for _, id := range ids {
+		a := b.assets[id]
+		if _, err := r.exec(tx,
+			`INSERT INTO inventory (id, kind, value, detail, first_seen, last_seen, first_run, last_run)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT (id) DO UPDATE SET
+			   last_seen = excluded.last_seen,
+			   last_run = excluded.last_run,
+			   detail = CASE WHEN excluded.detail = '' THEN inventory.detail ELSE excluded.detail END`,
+			a.ID, a.Kind, a.Value, a.Detail, seen, seen, r.ID, r.ID,
+		); err != nil {
+			return err
+		}
+	}
+	for _, link := range links {
+		if _, err := r.exec(tx,
+			`INSERT INTO inventory_links (parent, child, first_seen, last_seen) VALUES (?, ?, ?, ?)
+			 ON CONFLICT (parent, child) DO UPDATE SET last_seen = excluded.last_seen`,
+			link[0], link[1], seen, seen,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inventory is the loaded asset graph
+type Inventory struct {
+	assets   map[string]*Asset
+	children map[string][]string
+}
+
+// LoadInventory reads every asset and link
+func (s *Store) LoadInventory() (*Inventory, error) {
+	inv := &Inventory{
+		assets:   make(map[string]*Asset),
+		children: make(map[string][]string),
+	}
+
+	rows, err := s.db.Query(`SELECT id, kind, value, detail, first_seen, last_seen, first_run, last_run FROM inventory`)
+	if err != nil {
+		return nil, fmt.Errorf("loading inventory: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := &Asset{}
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Value, &a.Detail, &a.FirstSeen, &a.LastSeen, &a.FirstRun, &a.LastRun); err != nil {
+			return nil, fmt.Errorf("loading inventory: %w", err)
+		}
+		inv.assets[a.ID] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading inventory: %w", err)
+	}
+
+	links, err := s.db.Query(`SELECT parent, child FROM inventory_links`)
+	if err != nil {
+		return nil, fmt.Errorf("loading inventory links: %w", err)
+	}
+	defer links.Close()
+	for links.Next() {
+		var parent, child string
+		if err := links.Scan(&parent, &child); err != nil {
+			return nil, fmt.Errorf("loading inventory links: %w", err)
+		}
+		inv.children[parent] = append(inv.children[parent], child)
+		if a, ok := inv.assets[child]; ok {
+			a.Parents = append(a.Parents, parent)
+		}
+	}
+	return inv, links.Err()
+}
+
+// Query returns assets of a kind (any kind if empty) that sit under one of
+// the matching roots. under selects the roots: an asset ID, an exact value,
+// or a CIDR matching IP assets; empty means the whole inventory.
+func (inv *Inventory) Query(kind, under string) []Asset {
+	var roots []string
+	switch {
+	case under == "":
+		for id := range inv.assets {
+			roots = append(roots, id)
+		}
+	default:
+		_, network, cidrErr := net.ParseCIDR(under)
+		for id, a := range inv.assets {
+			switch {
+			case id == under || strings.EqualFold(a.Value, under):
+				roots = append(roots, id)
+			case cidrErr == nil && a.Kind == KindIP:
+				if ip := net.ParseIP(a.Value); ip != nil && network.Contains(ip) {
+					roots = append(roots, id)
+				}
+			}
+		}
+	}
+
+	// Everything down the hierarchy from the roots, roots included; links
+	// between URLs are not followed, or one page would reach all it links
+	reached := make(map[string]bool)
+	queue := roots
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if reached[id] {
+			continue
+		}
+		reached[id] = true
+		for _, child := range inv.children[id] {
+			if !inv.linksURLs(id, child) {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var assets []Asset
+	for id := range reached {
+		a, ok := inv.assets[id]
+		if !ok || (kind != "" && a.Kind != kind) {
+			continue
+		}
+		assets = append(assets, *a)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Kind != assets[j].Kind {
+			return kindOrder(assets[i].Kind) < kindOrder(assets[j].Kind)
+		}
+		return assets[i].Value < assets[j].Value
+	})
+	return assets
+}
+
+// linksURLs reports whether a link is from one URL to another
+func (inv *Inventory) linksURLs(parent, child string) bool {
+	p, c := inv.assets[parent], inv.assets[child]
+	return p != nil && c != nil && p.Kind == KindURL && c.Kind == KindURL
+}
+
+// kindOrder sorts kinds down the hierarchy
+func kindOrder(kind string) int {
+	for i, k := range []string{KindDomain, KindSubdomain, KindIP, KindPort, KindURL, KindTech, KindFinding} {
+		if k == kind {
+			return i
+		}
+	}
+	return len(kind)
+}
@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+)
+
+// RunResults is what one stored run found
+type RunResults struct {
+	Subdomains []subdomain.Result
+	Ports      []portscan.Result
+	Probes     []httpx.ProbeResult
+}
+
+// LoadRun reads back the assets, open ports and probed URLs of a run
+func (s *Store) LoadRun(id int64) (*RunResults, error) {
+	var exists int
+	err := s.db.QueryRow(s.rebind(`SELECT COUNT(*) FROM runs WHERE id = ?`), id).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("loading run %d: %w", id, err)
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("run %d not found", id)
+	}
+
+	results := &RunResults{}
+	if err := s.query(`SELECT host, ips, source FROM assets WHERE run_id = ?`, id, func(rows *sql.Rows) error {
+		var r subdomain.Result
+		var ips string
+		if err := rows.Scan(&r.Subdomain, &ips, &r.Source); err != nil {
+			return err
+		}
+		r.IPs = splitList(ips)
+		results.Subdomains = append(results.Subdomains, r)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("loading run %d assets: %w", id, err)
+	}
+
+	if err := s.query(`SELECT host, port, service, banner, seen_at FROM ports WHERE run_id = ?`, id, func(rows *sql.Rows) error {
+		r := portscan.Result{Open: true}
+		if err := rows.Scan(&r.Host, &r.Port, &r.Service, &r.Banner, &r.Timestamp); err != nil {
+			return err
+		}
+		results.Ports = append(results.Ports, r)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("loading run %d ports: %w", id, err)
+	}
+
+	if err := s.query(
+		`SELECT url, status_code, title, server, content_type, content_length, technologies, seen_at
+		 FROM urls WHERE run_id = ? AND source = 'probe'`, id, func(rows *sql.Rows) error {
+			var r httpx.ProbeResult
+			var technologies string
+			if err := rows.Scan(&r.URL, &r.StatusCode, &r.Title, &r.Server, &r.ContentType, &r.ContentLength, &technologies, &r.Timestamp); err != nil {
+				return err
+			}
+			r.Technologies = splitList(technologies)
+			results.Probes = append(results.Probes, r)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("loading run %d urls: %w", id, err)
+	}
+	return results, nil
+}
+
+// RunInfo is a stored run's row in runs
+type RunInfo struct {
+	ID         int64  `json:"id"`
+	Command    string `json:"command"`
+	Target     string `json:"target"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	Status     string `json:"status"`
+}
+
+// Runs lists the stored runs, oldest first
+func (s *Store) Runs() ([]RunInfo, error) {
+	rows, err := s.db.Query(`SELECT id, command, target, started_at, COALESCE(finished_at, ''), status FROM runs ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []RunInfo
+	for rows.Next() {
+		var r RunInfo
+		if err := rows.Scan(&r.ID, &r.Command, &r.Target, &r.StartedAt, &r.FinishedAt, &r.Status); err != nil {
+			return nil, fmt.Errorf("listing runs: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// LoadKnown reads what earlier runs port-scanned and probed, for an
+// incremental pipeline run: those hosts, with every address the inventory
+// has them resolving to, and how each URL last answered a probe
+func (s *Store) LoadKnown() (*pipeline.Known, error) {
+	ips := make(map[string][]string)
+	if err := s.query(
+		`SELECT h.value, a.value FROM inventory_links l
+		 JOIN inventory h ON h.id = l.parent
+		 JOIN inventory a ON a.id = l.child
+		 WHERE h.kind IN ('domain', 'subdomain') AND a.kind = ?`, KindIP, func(rows *sql.Rows) error {
+			var host, ip string
+			if err := rows.Scan(&host, &ip); err != nil {
+				return err
+			}
+			host = strings.ToLower(host)
+			ips[host] = append(ips[host], ip)
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("loading known hosts: %w", err)
+	}
+
+	known := pipeline.NewKnown()
+	if err := s.query(`SELECT value FROM inventory WHERE kind = ?`, KindPort, func(rows *sql.Rows) error {
+		var hostPort string
+		if err := rows.Scan(&hostPort); err != nil {
+			return err
+		}
+		if host, _, err := net.SplitHostPort(hostPort); err == nil {
+			known.AddHost(host, ips[strings.ToLower(host)])
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("loading known ports: %w", err)
+	}
+
+	// Later runs' rows come last, so the last answer wins
+	if err := s.query(`SELECT url, COALESCE(status_code, 0), title FROM urls WHERE source = ? ORDER BY id`, "probe", func(rows *sql.Rows) error {
+		var raw string
+		var answer pipeline.KnownURL
+		if err := rows.Scan(&raw, &answer.StatusCode, &answer.Title); err != nil {
+			return err
+		}
+		known.URLs[raw] = answer
+		if u, err := neturl.Parse(raw); err == nil && u.Hostname() != "" {
+			known.AddHost(u.Hostname(), ips[strings.ToLower(u.Hostname())])
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("loading known URLs: %w", err)
+	}
+	return known, nil
+}
+
+// Known reads what this and earlier runs scanned, as LoadKnown does
+func (r *Run) Known() (*pipeline.Known, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return r.store.LoadKnown()
+}
+
+// query runs a single-argument query and calls scan for each row
+func (s *Store) query(query string, arg interface{}, scan func(*sql.Rows) error) error {
+	rows, err := s.db.Query(s.rebind(query), arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// splitList reverses the comma joining used for list columns
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,553 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/hostintel"
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/reputation"
+	"github.com/recon-suite/scanner/pkg/snmp"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Run statuses
+const (
+	StatusRunning     = "running"
+	StatusOK          = "ok"
+	StatusPartial     = "partial"
+	StatusInterrupted = "interrupted"
+)
+
+// schema creates the results tables; {{autoincrement}} is the dialect's auto-increment
+// key. Every result row carries the run that found it, so the same asset
+// seen by several runs has one row per run.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS runs (
+		id          {{autoincrement}},
+		command     TEXT NOT NULL,
+		target      TEXT NOT NULL,
+		started_at  TEXT NOT NULL,
+		finished_at TEXT,
+		status      TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS assets (
+		id       {{autoincrement}},
+		run_id   BIGINT NOT NULL REFERENCES runs(id),
+		host     TEXT NOT NULL,
+		ips      TEXT NOT NULL DEFAULT '',
+		source   TEXT NOT NULL DEFAULT '',
+		alive    BOOLEAN,
+		seen_at  TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS ports (
+		id       {{autoincrement}},
+		run_id   BIGINT NOT NULL REFERENCES runs(id),
+		host     TEXT NOT NULL,
+		port     INTEGER NOT NULL,
+		service  TEXT NOT NULL DEFAULT '',
+		banner   TEXT NOT NULL DEFAULT '',
+		seen_at  TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS urls (
+		id             {{autoincrement}},
+		run_id         BIGINT NOT NULL REFERENCES runs(id),
+		url            TEXT NOT NULL,
+		source         TEXT NOT NULL,
+		status_code    INTEGER,
+		title          TEXT NOT NULL DEFAULT '',
+		server         TEXT NOT NULL DEFAULT '',
+		content_type   TEXT NOT NULL DEFAULT '',
+		content_length INTEGER,
+		technologies   TEXT NOT NULL DEFAULT '',
+		type           TEXT NOT NULL DEFAULT '',
+		seen_at        TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS findings (
+		id       {{autoincrement}},
+		run_id   BIGINT NOT NULL REFERENCES runs(id),
+		url      TEXT NOT NULL,
+		kind     TEXT NOT NULL,
+		name     TEXT NOT NULL,
+		detail   TEXT NOT NULL DEFAULT '',
+		seen_at  TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS assets_host ON assets(host)`,
+	`CREATE INDEX IF NOT EXISTS ports_host ON ports(host, port)`,
+	`CREATE INDEX IF NOT EXISTS urls_url ON urls(url)`,
+	`CREATE INDEX IF NOT EXISTS findings_kind ON findings(kind, name)`,
+}
+
+// dialect holds what differs between the supported databases
+type dialect struct {
+	driver        string
+	autoIncrement string
+
+	// numbered placeholders ($1, $2) instead of ?
+	numbered bool
+
+	// lock serializes schema creation across concurrent openers
+	lock string
+}
+
+var (
+	sqliteDialect = dialect{
+		driver:        "sqlite3",
+		autoIncrement: "INTEGER PRIMARY KEY AUTOINCREMENT",
+	}
+	postgresDialect = dialect{
+		driver:        "postgres",
+		autoIncrement: "BIGSERIAL PRIMARY KEY",
+		numbered:      true,
+		lock:          "SELECT pg_advisory_xact_lock(7254104)",
+	}
+)
+
+// Store is a results database. Each command run gets a row in runs, and
+// its subdomains, ports, URLs and findings are written against that run.
+type Store struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// Open opens a results database, creating the tables if needed. dsn is a
+// postgres:// (or postgresql://) URL for a shared PostgreSQL database, so
+// several scanners can write to one store at once; anything else is the
+// path of a local SQLite file.
+func Open(dsn string) (*Store, error) {
+	d := sqliteDialect
+	source := "file:" + dsn + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on"
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		d = postgresDialect
+		source = dsn
+	}
+
+	db, err := sql.Open(d.driver, source)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	s := &Store{db: db, dialect: d}
+	if err := s.createSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+	return s, nil
+}
+
+// createSchema creates any missing tables and indexes in one transaction
+func (s *Store) createSchema() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.dialect.lock != "" {
+		if _, err := tx.Exec(s.dialect.lock); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range append(schema, inventorySchema...) {
+		if _, err := tx.Exec(strings.ReplaceAll(stmt, "{{autoincrement}}", s.dialect.autoIncrement)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// rebind rewrites ? placeholders for the store's dialect
+func (s *Store) rebind(query string) string {
+	if !s.dialect.numbered {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&sb, "$%d", n)
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Run is one command run being recorded. All methods are safe to call on a
+// nil *Run, which records nothing.
+type Run struct {
+	ID    int64
+	store *Store
+}
+
+// BeginRun records the start of a command run against a target spec
+func (s *Store) BeginRun(command, target string) (*Run, error) {
+	var id int64
+	err := s.db.QueryRow(
+		s.rebind(`INSERT INTO runs (command, target, started_at, status) VALUES (?, ?, ?, ?) RETURNING id`),
+		command, target, now(), StatusRunning,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("recording run: %w", err)
+	}
+	return &Run{ID: id, store: s}, nil
+}
+
+// Finish marks the run finished with one of the Status values
+func (r *Run) Finish(status string) error {
+	if r == nil {
+		return nil
+	}
+
+	_, err := r.store.db.Exec(r.store.rebind(`UPDATE runs SET finished_at = ?, status = ? WHERE id = ?`), now(), status, r.ID)
+	return err
+}
+
+// Save writes a batch of results in one transaction and merges them into
+// the asset inventory. Accepted types are the slices the commands produce:
+// []subdomain.Result, []subdomain.ResolutionResult, []portscan.Result,
+// []httpx.ProbeResult, []httpx.CrawlResult, []httpx.AnalysisResult,
+// []snmp.Result, or pipeline reports.
+func (r *Run) Save(results interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := r.save(tx, results); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("saving results: %w", err)
+	}
+	if err := r.updateInventory(tx, results); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("updating inventory: %w", err)
+	}
+	return tx.Commit()
+}
+
+// save inserts each result type into its table
+func (r *Run) save(tx *sql.Tx, results interface{}) error {
+	seen := now()
+
+	switch v := results.(type) {
+	case []subdomain.Result:
+		for _, res := range v {
+			if _, err := r.exec(tx,
+				`INSERT INTO assets (run_id, host, ips, source, seen_at) VALUES (?, ?, ?, ?, ?)`,
+				r.ID, res.Subdomain, strings.Join(res.IPs, ","), res.Source, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []subdomain.ResolutionResult:
+		for _, res := range v {
+			if _, err := r.exec(tx,
+				`INSERT INTO assets (run_id, host, ips, source, alive, seen_at) VALUES (?, ?, ?, 'resolve', ?, ?)`,
+				r.ID, res.Subdomain, strings.Join(res.IPs, ","), res.Alive, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []portscan.Result:
+		for _, res := range v {
+			if !res.Open {
+				continue
+			}
+			if _, err := r.exec(tx,
+				`INSERT INTO ports (run_id, host, port, service, banner, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.ID, res.Host, res.Port, res.Service, res.Banner, seen,
+			); err != nil {
+				return err
+			}
+			if f, ok := ftpFinding(res); ok {
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, ftpURL(res), f.kind, f.name, f.detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case []snmp.Result:
+		for _, res := range v {
+			if _, err := r.exec(tx,
+				`INSERT INTO ports (run_id, host, port, service, banner, seen_at) VALUES (?, ?, ?, 'snmp', ?, ?)`,
+				r.ID, res.Host, res.Port, res.SysDescr, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []httpx.ProbeResult:
+		for _, res := range v {
+			if _, err := r.exec(tx,
+				`INSERT INTO urls (run_id, url, source, status_code, title, server, content_type, content_length, technologies, seen_at)
+				 VALUES (?, ?, 'probe', ?, ?, ?, ?, ?, ?, ?)`,
+				r.ID, res.URL, res.StatusCode, res.Title, res.Server, res.ContentType, res.ContentLength,
+				strings.Join(res.Technologies, ","), seen,
+			); err != nil {
+				return err
+			}
+			for _, f := range certFindings(res) {
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, res.URL, f.kind, f.name, f.detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case []httpx.CrawlResult:
+		for _, res := range v {
+			if _, err := r.exec(tx,
+				`INSERT INTO urls (run_id, url, source, type, seen_at) VALUES (?, ?, 'crawl', ?, ?)`,
+				r.ID, res.URL, res.Type, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []httpx.AnalysisResult:
+		for _, res := range v {
+			for _, f := range analysisFindings(res) {
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, res.URL, f.kind, f.name, f.detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case []httpx.BypassResult:
+		for _, res := range v {
+			f := bypassFinding(res)
+			if _, err := r.exec(tx,
+				`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.ID, res.URL, f.kind, f.name, f.detail, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []httpx.MethodResult:
+		for _, res := range v {
+			if f, ok := methodFinding(res); ok {
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, res.URL, f.kind, f.name, f.detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case []httpx.SmuggleResult:
+		for _, res := range v {
+			f := smuggleFinding(res)
+			if _, err := r.exec(tx,
+				`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.ID, res.URL, f.kind, f.name, f.detail, seen,
+			); err != nil {
+				return err
+			}
+		}
+	case []hostintel.Host:
+		for _, res := range v {
+			for _, f := range intelFindings(res) {
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, res.IP, f.kind, f.name, f.detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case []reputation.Result:
+		for _, res := range v {
+			for _, l := range res.Listings {
+				detail := fmt.Sprintf("%s %s %s%s", res.Severity, l.List, l.Code, l.Match)
+				if _, err := r.exec(tx,
+					`INSERT INTO findings (run_id, url, kind, name, detail, seen_at) VALUES (?, ?, ?, ?, ?, ?)`,
+					r.ID, res.IP, "reputation", l.Category, detail, seen,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	case *pipeline.Report:
+		return r.saveReport(tx, v)
+	case []*pipeline.Report:
+		for _, report := range v {
+			if err := r.saveReport(tx, report); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported result type %T", results)
+	}
+	return nil
+}
+
+// exec runs an insert in tx with the dialect's placeholders
+func (r *Run) exec(tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Exec(r.store.rebind(query), args...)
+}
+
+// saveReport writes every section of a pipeline report
+func (r *Run) saveReport(tx *sql.Tx, report *pipeline.Report) error {
+	for _, section := range []interface{}{
+		report.Subdomains, report.Resolved, report.Ports, report.Probes, report.Crawl, report.Analysis,
+	} {
+		if err := r.save(tx, section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finding is one row of the findings table
+type finding struct {
+	kind, name, detail string
+}
+
+// analysisFindings flattens an analysis result into secrets and other
+// interesting matches, takeover hits, GraphQL endpoints, buckets, emails
+// and missing security headers
+func analysisFindings(a httpx.AnalysisResult) []finding {
+	var findings []finding
+
+	// Interesting entries are "<pattern name>: <match>"
+	for _, item := range a.Interesting {
+		name, match, _ := strings.Cut(item, ": ")
+		findings = append(findings, finding{"interesting", name, match})
+	}
+	if a.Takeover != "" {
+		findings = append(findings, finding{"takeover", a.Takeover, ""})
+	}
+	for _, endpoint := range a.GraphQL {
+		detail := endpoint.Evidence
+		if endpoint.Schema != nil {
+			detail = "introspection"
+		}
+		findings = append(findings, finding{"graphql", endpoint.URL, detail})
+	}
+	for _, bucket := range a.Storage {
+		findings = append(findings, finding{"bucket", bucket.Provider + "/" + bucket.Bucket, bucket.Access})
+	}
+	for _, email := range a.Emails {
+		findings = append(findings, finding{"email", email, ""})
+	}
+	for _, m := range a.Methods {
+		findings = append(findings, finding{"method", m.Method, m.Evidence})
+	}
+
+	sh := a.SecurityHeaders
+	for _, header := range []struct{ name, value string }{
+		{"content-security-policy", sh.CSP},
+		{"strict-transport-security", sh.HSTS},
+		{"x-frame-options", sh.XFrameOptions},
+		{"x-content-type-options", sh.XContentType},
+	} {
+		if header.value == "" {
+			findings = append(findings, finding{"missing-header", header.name, ""})
+		}
+	}
+	return findings
+}
+
+// ftpFinding returns the finding for an FTP server that accepted an
+// anonymous login
+func ftpFinding(res portscan.Result) (finding, bool) {
+	if res.FTP == nil {
+		return finding{}, false
+	}
+	name := "anonymous-ftp"
+	detail := res.FTP.Severity
+	if res.FTP.Writable {
+		name = "anonymous-ftp-writable"
+		detail += " " + strings.Join(res.FTP.WritablePaths, ",")
+	}
+	return finding{"ftp", name, detail}, true
+}
+
+// bypassFinding returns the finding for a request that got past a 401 or
+// 403
+func bypassFinding(res httpx.BypassResult) finding {
+	detail := fmt.Sprintf("%s %d->%d %s %s", res.Severity, res.OriginalStatus, res.StatusCode, res.Method, res.RequestURL)
+	names := make([]string, 0, len(res.Headers))
+	for name := range res.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		detail += " " + name + ": " + res.Headers[name]
+	}
+	return finding{"bypass", res.Technique, detail}
+}
+
+// methodFinding returns the finding for an endpoint allowing risky HTTP
+// methods
+func methodFinding(res httpx.MethodResult) (finding, bool) {
+	if len(res.Risky) == 0 {
+		return finding{}, false
+	}
+	detail := fmt.Sprintf("%s %s accepted=%s allow=%s", res.Severity, strings.Join(res.Risky, ","),
+		strings.Join(res.Accepted, ","), strings.Join(res.Allow, ","))
+	return finding{"methods", "risky-methods", detail}, true
+}
+
+// certFindings returns the findings for the problems with the
+// certificate a probed URL presented
+func certFindings(res httpx.ProbeResult) []finding {
+	var findings []finding
+	for _, issue := range res.CertIssues {
+		findings = append(findings, finding{"cert", strings.TrimPrefix(issue.ID, "cert/"), issue.Severity + " " + issue.Detail})
+	}
+	return findings
+}
+
+// smuggleFinding returns the finding for a request smuggling indicator
+func smuggleFinding(res httpx.SmuggleResult) finding {
+	detail := fmt.Sprintf("%s control=%dms delay=%dms confirmations=%d", res.Severity, res.ControlMS, res.DelayMS, res.Confirmations)
+	return finding{"smuggle", res.Technique, detail}
+}
+
+// intelFindings returns the findings for the ports a source cached open
+// that the active scan did not find, and those it found that the source
+// has not seen
+func intelFindings(res hostintel.Host) []finding {
+	var findings []finding
+	for _, port := range res.OnlyCached {
+		findings = append(findings, finding{"intel", "only-cached", fmt.Sprintf("%s saw port %d open; the scan did not", res.Source, port)})
+	}
+	for _, port := range res.OnlyScanned {
+		findings = append(findings, finding{"intel", "only-scanned", fmt.Sprintf("the scan found port %d open; %s has not seen it", port, res.Source)})
+	}
+	return findings
+}
+
+// ftpURL returns the ftp:// URL of a scanned port
+func ftpURL(res portscan.Result) string {
+	return "ftp://" + net.JoinHostPort(res.Host, strconv.Itoa(res.Port)) + "/"
+}
+
+// now returns the current UTC time as stored in the database
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
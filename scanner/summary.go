@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils/metrics"
+)
+
+// runSummary describes how a run went, so runs can be compared and
+// throughput regressions spotted. It is appended as one JSON line to the
+// -summary file.
+type runSummary struct {
+	Command  string  `json:"command"`
+	Target   string  `json:"target"`
+	Started  string  `json:"started"`
+	Duration float64 `json:"duration_seconds"`
+	Status   string  `json:"status"`
+
+	Targets   int `json:"targets,omitempty"`
+	Results   int `json:"results"`
+	LiveHosts int `json:"live_hosts,omitempty"`
+	OpenPorts int `json:"open_ports,omitempty"`
+
+	// Requests counts those paced by the shared rate budget
+	Requests    int64   `json:"requests"`
+	RequestRate float64 `json:"requests_per_second"`
+
+	// ResponseTime holds percentiles of the response times results
+	// carry, such as probe results
+	ResponseTime *latencySummary `json:"response_time_ms,omitempty"`
+
+	// Errors counts the run's warnings by errorClass
+	Errors map[string]int `json:"errors,omitempty"`
+
+	// Metrics holds the counters the modules recorded (requests, timeouts,
+	// bytes read, findings) by name and labels, as /metrics renders them
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// latencySummary holds response time percentiles in milliseconds
+type latencySummary struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+	Max int64 `json:"max"`
+}
+
+// runTally accumulates what a run summary reports about results
+type runTally struct {
+	hosts     map[string]bool
+	openPorts int
+	latencies []int64
+}
+
+// add counts a batch of results: live hosts, open ports and response times
+func (t *runTally) add(results interface{}) {
+	if t.hosts == nil {
+		t.hosts = make(map[string]bool)
+	}
+
+	switch v := results.(type) {
+	case []subdomain.Result:
+		for _, r := range v {
+			if len(r.IPs) > 0 {
+				t.hosts[r.Subdomain] = true
+			}
+		}
+	case []subdomain.ResolutionResult:
+		for _, r := range v {
+			if len(r.IPs) > 0 {
+				t.hosts[r.Subdomain] = true
+			}
+		}
+	case []portscan.Result:
+		for _, r := range v {
+			if r.Open {
+				t.hosts[r.Host] = true
+				t.openPorts++
+			}
+		}
+	case []httpx.ProbeResult:
+		for _, r := range v {
+			if r.StatusCode > 0 {
+				if u, err := url.Parse(r.URL); err == nil {
+					t.hosts[u.Hostname()] = true
+				}
+				t.latencies = append(t.latencies, r.ResponseTime)
+			}
+		}
+	case *pipeline.Report:
+		t.add(v.Resolved)
+		t.add(v.Ports)
+		t.add(v.Probes)
+	}
+}
+
+// summarize builds the summary of a run that started at started
+func (t *runTally) summarize(started time.Time, requests int64, status *runStatus) runSummary {
+	elapsed := time.Since(started)
+	summary := runSummary{
+		Started:   started.UTC().Format(time.RFC3339),
+		Duration:  elapsed.Seconds(),
+		Results:   status.results,
+		LiveHosts: len(t.hosts),
+		OpenPorts: t.openPorts,
+		Requests:  requests,
+		Errors:    status.errors,
+		Metrics:   metrics.Default.Snapshot(),
+	}
+	if elapsed > 0 {
+		summary.RequestRate = float64(requests) / elapsed.Seconds()
+	}
+
+	if len(t.latencies) > 0 {
+		sort.Slice(t.latencies, func(i, j int) bool { return t.latencies[i] < t.latencies[j] })
+		percentile := func(p int) int64 {
+			return t.latencies[(len(t.latencies)-1)*p/100]
+		}
+		summary.ResponseTime = &latencySummary{
+			P50: percentile(50),
+			P90: percentile(90),
+			P99: percentile(99),
+			Max: t.latencies[len(t.latencies)-1],
+		}
+	}
+	return summary
+}
+
+// writeSummary appends a summary as one JSON line to path, or writes it to
+// stderr if path is -
+func writeSummary(path string, summary runSummary) error {
+	line, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if path == "-" {
+		_, err = os.Stderr.Write(line)
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// countTargets returns how many targets the first value of a run's target
+// spec names: the lines of a file, or one target. Targets read from stdin
+// are not counted.
+func countTargets(spec string) int {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || fields[0] == "-" {
+		return 0
+	}
+	if data, err := os.ReadFile(fields[0]); err == nil {
+		return len(parseTargetLines(string(data)))
+	}
+	return 1
+}
+
+// errorClass buckets a warning by its likely cause
+func errorClass(message string) string {
+	m := strings.ToLower(message)
+	switch {
+	case strings.Contains(m, "timeout") || strings.Contains(m, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(m, "connection refused"):
+		return "refused"
+	case strings.Contains(m, "connection reset") || strings.Contains(m, "broken pipe") || strings.Contains(m, "eof"):
+		return "reset"
+	case strings.Contains(m, "no such host") || strings.Contains(m, "server misbehaving") || strings.Contains(m, "nxdomain"):
+		return "dns"
+	case strings.Contains(m, "tls") || strings.Contains(m, "x509") || strings.Contains(m, "certificate"):
+		return "tls"
+	case strings.Contains(m, "429") || strings.Contains(m, "rate limit") || strings.Contains(m, "too many requests"):
+		return "rate_limited"
+	default:
+		return "other"
+	}
+}
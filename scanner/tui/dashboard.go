@@ -0,0 +1,414 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recon-suite/scanner/pkg/httpx"
+	"github.com/recon-suite/scanner/pkg/pipeline"
+	"github.com/recon-suite/scanner/pkg/portscan"
+	"github.com/recon-suite/scanner/pkg/screenshot"
+	"github.com/recon-suite/scanner/pkg/subdomain"
+	"github.com/recon-suite/scanner/pkg/utils"
+	"golang.org/x/term"
+)
+
+// maxFeed caps how many findings lines are kept for scrollback
+const maxFeed = 1000
+
+// Dashboard is a full-screen terminal view of a running pipeline: progress
+// per stage, a scrolling findings feed, and keys to pause, change the rate,
+// abort a stage, or quit
+type Dashboard struct {
+	budget *utils.Budget
+	quit   func()
+	in     *os.File
+	out    *os.File
+
+	mu       sync.Mutex
+	stages   []*stageView
+	selected int
+	feed     []string
+	notice   string
+
+	restore *term.State
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// stageView is one row of the stage table
+type stageView struct {
+	event pipeline.StageEvent
+	done  bool
+}
+
+// New creates a dashboard that reads keys from in and draws on out. budget
+// is paused and re-rated by key presses; quit is called to abort the run.
+func New(in, out *os.File, budget *utils.Budget, quit func()) *Dashboard {
+	return &Dashboard{
+		budget: budget,
+		quit:   quit,
+		in:     in,
+		out:    out,
+	}
+}
+
+// Supported reports whether in and out are both terminals
+func Supported(in, out *os.File) bool {
+	return term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd()))
+}
+
+// Start switches the terminal to raw mode on the alternate screen and
+// begins drawing and reading keys
+func (d *Dashboard) Start() error {
+	state, err := term.MakeRaw(int(d.in.Fd()))
+	if err != nil {
+		return fmt.Errorf("entering raw mode: %w", err)
+	}
+	d.restore = state
+	d.stop = make(chan struct{})
+
+	// Alternate screen, hidden cursor
+	fmt.Fprint(d.out, "\033[?1049h\033[?25l")
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			d.draw()
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	// The key reader blocks on stdin, so it is not waited for on Stop
+	go d.readKeys()
+	return nil
+}
+
+// Stop restores the terminal
+func (d *Dashboard) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	d.wg.Wait()
+
+	fmt.Fprint(d.out, "\033[?25h\033[?1049l")
+	term.Restore(int(d.in.Fd()), d.restore)
+}
+
+// OnStage tracks a pipeline stage starting or finishing
+func (d *Dashboard) OnStage(event pipeline.StageEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, view := range d.stages {
+		if view.event.Domain == event.Domain && view.event.Stage == event.Stage {
+			view.event = event
+			view.done = event.Done
+			return
+		}
+	}
+	d.stages = append(d.stages, &stageView{event: event, done: event.Done})
+
+	// Follow the newest stage unless the user moved the selection back
+	if d.selected == len(d.stages)-2 {
+		d.selected = len(d.stages) - 1
+	}
+}
+
+// OnResult adds a result to the findings feed
+func (d *Dashboard) OnResult(stage string, result interface{}) {
+	d.addFeed(fmt.Sprintf("[%s] %s", stage, describe(result)))
+}
+
+// Write adds log lines (such as scope violations) to the findings feed
+func (d *Dashboard) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		d.addFeed(line)
+	}
+	return len(p), nil
+}
+
+// addFeed appends one feed line, dropping the oldest past maxFeed
+func (d *Dashboard) addFeed(line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.feed = append(d.feed, line)
+	if len(d.feed) > maxFeed {
+		d.feed = d.feed[len(d.feed)-maxFeed:]
+	}
+}
+
+// readKeys handles key presses until Stop
+func (d *Dashboard) readKeys() {
+	r := bufio.NewReader(d.in)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		switch b {
+		case 'q', 3: // q or Ctrl-C
+			d.setNotice("quitting: finishing in-flight work")
+			d.quit()
+		case 'p', ' ':
+			d.togglePause()
+		case '+', '=':
+			d.changeRate(1.5)
+		case '-', '_':
+			d.changeRate(1 / 1.5)
+		case '0':
+			d.budget.SetRate(0)
+			d.setNotice("rate: unlimited")
+		case 'a':
+			d.abortSelected()
+		case 'k':
+			d.moveSelection(-1)
+		case 'j':
+			d.moveSelection(1)
+		case 0x1b: // arrow keys arrive as ESC [ A / ESC [ B
+			if next, _ := r.ReadByte(); next != '[' {
+				continue
+			}
+			switch dir, _ := r.ReadByte(); dir {
+			case 'A':
+				d.moveSelection(-1)
+			case 'B':
+				d.moveSelection(1)
+			}
+		}
+	}
+}
+
+// togglePause pauses or resumes every stage via the shared budget
+func (d *Dashboard) togglePause() {
+	if d.budget.Paused() {
+		d.budget.Resume()
+		d.setNotice("resumed")
+		return
+	}
+	d.budget.Pause()
+	d.setNotice("paused: in-flight requests will finish")
+}
+
+// changeRate scales the shared rate; lowering an unlimited rate starts from
+// the selected stage's observed throughput
+func (d *Dashboard) changeRate(factor float64) {
+	current := d.budget.Rate()
+	if current == 0 {
+		if factor > 1 {
+			return
+		}
+		current = int(d.selectedStats().Rate)
+		if current < 10 {
+			current = 10
+		}
+	}
+
+	next := int(float64(current) * factor)
+	if next < 1 {
+		next = 1
+	}
+	if next == current && factor > 1 {
+		next++
+	}
+	d.budget.SetRate(next)
+	d.setNotice(fmt.Sprintf("rate: %d req/s", next))
+}
+
+// abortSelected cancels the selected stage if it is still running
+func (d *Dashboard) abortSelected() {
+	d.mu.Lock()
+	var event pipeline.StageEvent
+	running := false
+	if d.selected >= 0 && d.selected < len(d.stages) {
+		event = d.stages[d.selected].event
+		running = !d.stages[d.selected].done
+	}
+	d.mu.Unlock()
+
+	if !running || event.Abort == nil {
+		return
+	}
+	event.Abort()
+	d.setNotice(fmt.Sprintf("aborting %s %s", event.Domain, event.Stage))
+}
+
+// moveSelection moves the stage cursor
+func (d *Dashboard) moveSelection(delta int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.selected += delta
+	if d.selected >= len(d.stages) {
+		d.selected = len(d.stages) - 1
+	}
+	if d.selected < 0 {
+		d.selected = 0
+	}
+}
+
+// selectedStats returns the selected stage's progress
+func (d *Dashboard) selectedStats() utils.ProgressStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.selected >= 0 && d.selected < len(d.stages) {
+		return d.stages[d.selected].event.Progress.Snapshot()
+	}
+	return utils.ProgressStats{}
+}
+
+// setNotice shows a one-line status message
+func (d *Dashboard) setNotice(msg string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notice = msg
+}
+
+// draw renders the whole screen
+func (d *Dashboard) draw() {
+	width, height, err := term.GetSize(int(d.out.Fd()))
+	if err != nil || width < 20 || height < 8 {
+		width, height = 80, 24
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var lines []string
+	rate := "unlimited"
+	if r := d.budget.Rate(); r > 0 {
+		rate = fmt.Sprintf("%d req/s", r)
+	}
+	header := "Recon Scanner | rate: " + rate
+	if d.budget.Paused() {
+		header += " | PAUSED"
+	}
+	lines = append(lines, header, strings.Repeat("─", width))
+
+	for i, view := range d.stages {
+		cursor := "  "
+		if i == d.selected {
+			cursor = "> "
+		}
+		lines = append(lines, cursor+stageLine(view))
+	}
+	lines = append(lines, strings.Repeat("─", width))
+
+	// Findings fill whatever height is left, newest at the bottom
+	footer := []string{
+		strings.Repeat("─", width),
+		d.notice,
+		"p pause/resume  +/- rate  0 unlimited  ↑/↓ select  a abort stage  q quit",
+	}
+	room := height - len(lines) - len(footer) - 1
+	feed := d.feed
+	if room < 0 {
+		room = 0
+	}
+	if len(feed) > room {
+		feed = feed[len(feed)-room:]
+	}
+	lines = append(lines, fmt.Sprintf("Findings (%d)", len(d.feed)))
+	lines = append(lines, feed...)
+	for len(lines) < height-len(footer) {
+		lines = append(lines, "")
+	}
+	lines = append(lines, footer...)
+
+	var sb strings.Builder
+	sb.WriteString("\033[H")
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > width {
+			line = string(runes[:width])
+		}
+		sb.WriteString(line)
+		sb.WriteString("\033[K")
+		if i < len(lines)-1 {
+			sb.WriteString("\r\n")
+		}
+	}
+	fmt.Fprint(d.out, sb.String())
+}
+
+// stageLine renders one stage row with a progress bar
+func stageLine(view *stageView) string {
+	s := view.event.Progress.Snapshot()
+
+	const barWidth = 20
+	filled := int(s.Percent / 100 * barWidth)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled)
+
+	state := "running"
+	if view.done {
+		state = "done"
+	}
+
+	return fmt.Sprintf("%-24s %-9s [%s] %5.1f%% %d/%d | %s: %d | %.1f/s | ETA %s | %s",
+		view.event.Domain, view.event.Stage, bar, s.Percent, s.Done, s.Total, s.Noun, s.Found, s.Rate, s.ETA, state)
+}
+
+// describe summarizes a pipeline result for the findings feed
+func describe(result interface{}) string {
+	switch r := result.(type) {
+	case subdomain.Result:
+		return fmt.Sprintf("%s (%s)", r.Subdomain, r.Source)
+	case subdomain.ResolutionResult:
+		return fmt.Sprintf("%s -> %s", r.Subdomain, strings.Join(r.IPs, ", "))
+	case portscan.Result:
+		if r.RDNS != "" && r.RDNS != r.Host {
+			return fmt.Sprintf("%s:%d (%s) open %s", r.Host, r.Port, r.RDNS, r.Service)
+		}
+		return fmt.Sprintf("%s:%d open %s", r.Host, r.Port, r.Service)
+	case httpx.ProbeResult:
+		summary := fmt.Sprintf("%s %d %s", r.URL, r.StatusCode, r.Title)
+		for _, issue := range r.CertIssues {
+			summary += ", " + issue.ID
+		}
+		return summary
+	case screenshot.Result:
+		if r.Error != "" {
+			return fmt.Sprintf("%s screenshot failed: %s", r.URL, r.Error)
+		}
+		return fmt.Sprintf("%s screenshot %s", r.URL, r.File)
+	case httpx.CrawlResult:
+		return fmt.Sprintf("%s %s", r.Type, r.URL)
+	case httpx.AnalysisResult:
+		summary := fmt.Sprintf("%s: %d interesting", r.URL, len(r.Interesting))
+		if r.Takeover != "" {
+			summary += ", possible " + r.Takeover + " takeover"
+		}
+		if len(r.GraphQL) > 0 {
+			summary += fmt.Sprintf(", %d graphql", len(r.GraphQL))
+		}
+		if len(r.Storage) > 0 {
+			summary += fmt.Sprintf(", %d buckets", len(r.Storage))
+		}
+		return summary
+	default:
+		return fmt.Sprintf("%v", r)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// watchPoll is how often a watched file is checked for appended lines
+const watchPoll = 500 * time.Millisecond
+
+// watchSeen is about how many targets a watch remembers to skip repeats of
+const watchSeen = 1 << 20
+
+// targetWatch follows a target file as other tools append to it, for
+// -watch. Each target is sent once: those already in the file, then those
+// written later. A named pipe is reopened when its writers close it, so
+// tools can take turns writing to it; stdin ("-") is read until it ends.
+//
+// Repeats are skipped by remembering the last watchSeen or so targets, in
+// two generations, so a watch that runs for good does not grow without
+// bound; a target last written over watchSeen/2 targets ago may be sent
+// again.
+type targetWatch struct {
+	path     string
+	targets  chan string
+	seen     map[string]bool
+	previous map[string]bool
+
+	mu  sync.Mutex
+	err error
+}
+
+// watchTargets starts following path. Targets is closed once stdin ends,
+// the watch fails or ctx is cancelled; a file or named pipe is otherwise
+// followed for good.
+func watchTargets(ctx context.Context, path string) *targetWatch {
+	w := &targetWatch{path: path, targets: make(chan string), seen: make(map[string]bool)}
+	go func() {
+		defer close(w.targets)
+		var err error
+		if path == "-" {
+			err = w.read(ctx, os.Stdin)
+		} else {
+			err = w.follow(ctx)
+		}
+		if ctx.Err() == nil {
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+		}
+	}()
+	return w
+}
+
+// Targets returns the targets as they are found
+func (w *targetWatch) Targets() <-chan string {
+	return w.targets
+}
+
+// Err returns why the watch stopped early, if it did
+func (w *targetWatch) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// follow reads path, opening it again whenever it is replaced, truncated
+// or, for a named pipe, closed by its writers
+func (w *targetWatch) follow(ctx context.Context) error {
+	for ctx.Err() == nil {
+		file, err := os.Open(w.path)
+		if err != nil {
+			return err
+		}
+		err = w.read(ctx, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read sends the targets of file's complete lines until it ends: for a
+// regular file, when it is replaced or truncated; otherwise at EOF, where
+// a last unterminated line counts too
+func (w *targetWatch) read(ctx context.Context, file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	regular := info.Mode().IsRegular() && file != os.Stdin
+
+	// Closing the file unblocks a read waiting on a pipe
+	stop := context.AfterFunc(ctx, func() { file.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(file)
+	var line []byte
+	var offset int64
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		offset += int64(len(chunk))
+		line = append(line, chunk...)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err == nil:
+			if !w.send(ctx, string(line)) {
+				return nil
+			}
+			line = line[:0]
+			continue
+		case !errors.Is(err, io.EOF):
+			return err
+		case !regular:
+			w.send(ctx, string(line))
+			return nil
+		}
+
+		// At the end of a regular file, wait for more to be written
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchPoll):
+		}
+		current, err := os.Stat(w.path)
+		if err != nil {
+			continue // being replaced, perhaps
+		}
+		if !os.SameFile(info, current) || current.Size() < offset {
+			return nil
+		}
+	}
+}
+
+// send passes on the targets of a line not seen before, reporting false
+// if ctx was cancelled first
+func (w *targetWatch) send(ctx context.Context, line string) bool {
+	for _, target := range parseTargetLines(line) {
+		if w.remember(target) {
+			continue
+		}
+		select {
+		case w.targets <- target:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// remember records target as seen, reporting whether it already was. Once
+// the current generation is full it becomes the previous one, whose
+// targets are carried forward as they recur.
+func (w *targetWatch) remember(target string) bool {
+	if w.seen[target] {
+		return true
+	}
+	if len(w.seen) >= watchSeen/2 {
+		w.previous, w.seen = w.seen, make(map[string]bool)
+	}
+	w.seen[target] = true
+	return w.previous[target]
+}
+
+// watchEnded returns the context a watch's run is finished with. A watch
+// runs until interrupted, so that is its normal end: the run is recorded
+// as finished and exits 0, 8 or 9 by what it found, not 130.
+func watchEnded(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// checkWatch exits if -watch cannot follow target as the command is run
+func checkWatch(flagName, target string, format OutputFormat, dryRun, resume bool) {
+	var problem string
+	switch {
+	case target == "":
+		problem = "-watch follows the " + flagName + " file, named pipe or - for stdin, so needs " + flagName
+	case format != FormatNDJSON:
+		problem = "-watch writes results as they are found and needs -f ndjson"
+	case dryRun:
+		problem = "-watch has no end to plan; -dry-run without it plans the file as it stands"
+	case resume:
+		problem = "-watch keeps no checkpoint to -resume; it runs until interrupted"
+	case target != "-":
+		if _, err := os.Stat(target); err != nil {
+			problem = "-watch: " + err.Error()
+			break
+		}
+		return
+	default:
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", problem)
+	os.Exit(exitUsage)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/recon-suite/scanner/pkg/utils"
+	"github.com/recon-suite/scanner/pkg/wordlist"
+)
+
+// wordlistCatalog is what wordlist list -f json reports
+type wordlistCatalog struct {
+	Lists        []wordlist.List   `json:"lists"`
+	Downloadable []wordlist.Source `json:"downloadable"`
+}
+
+func runWordlist(ctx context.Context) int {
+	fs := flag.NewFlagSet("wordlist", flag.ExitOnError)
+	format := fs.String("f", "txt", "Output format of list: txt, json")
+	name := fs.String("name", "", "download: name to keep a URL's list under (default: the URL's file name)")
+	kind := fs.String("kind", "", "download: what a URL's list holds: subdomains, paths, params or vhosts")
+	proxy := fs.String("proxy", "", "Route downloads through a proxy (http://, https://, socks5://host:port)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: scanner wordlist <list|show|download|update> [options] [name|URL]")
+		fmt.Fprintln(os.Stderr, "  list               embedded and downloaded lists, and those download fetches by name")
+		fmt.Fprintln(os.Stderr, "  show NAME          print a list's words, e.g. for other tools")
+		fmt.Fprintln(os.Stderr, "  download NAME|URL  fetch a community list, or any URL's, into the local cache")
+		fmt.Fprintln(os.Stderr, "  update [NAME...]   fetch downloaded lists again")
+		fmt.Fprintf(os.Stderr, "  Commands take lists as -w @NAME; %s moves the cache\n", wordlist.DirEnv)
+		fs.PrintDefaults()
+	}
+	if len(os.Args) < 3 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	action := os.Args[2]
+	fs.Parse(os.Args[3:])
+	if _, err := utils.ParseProxy(*proxy); err != nil {
+		fatal(err)
+	}
+
+	dir, err := wordlist.Dir()
+	if err != nil {
+		fatal(err)
+	}
+
+	switch action {
+	case "list":
+		lists, err := wordlist.Lists(dir)
+		if err != nil {
+			fatal(err)
+		}
+		catalog := wordlistCatalog{Lists: lists}
+		have := make(map[string]bool)
+		for _, list := range lists {
+			have[list.Name] = true
+		}
+		for _, source := range wordlist.Sources {
+			if !have[source.Name] {
+				catalog.Downloadable = append(catalog.Downloadable, source)
+			}
+		}
+		if OutputFormat(*format) == FormatJSON {
+			outputResults(catalog, "", FormatJSON)
+			return exitClean
+		}
+		printWordlists(catalog, dir)
+		return exitClean
+
+	case "show":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Error: wordlist show needs a list name")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		file, err := wordlist.Open("@" + strings.TrimPrefix(fs.Arg(0), "@"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		defer file.Close()
+		out := bufio.NewWriter(os.Stdout)
+		defer out.Flush()
+		if _, err := out.ReadFrom(file); err != nil {
+			fatal(err)
+		}
+		return exitClean
+
+	case "download":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Error: wordlist download needs a list name or URL")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		source, err := wordlistSource(fs.Arg(0), *name, *kind)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		fmt.Fprintf(messages(), "Downloading %s from %s\n", source.Name, source.URL)
+		list, err := wordlist.Download(ctx, dir, source, *proxy)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Fprintf(messages(), "Downloaded %s: %d words into %s; use it as -w @%s\n", list.Name, list.Words, list.Path, list.Name)
+		return exitClean
+
+	case "update":
+		var names []string
+		for _, arg := range fs.Args() {
+			names = append(names, strings.TrimPrefix(arg, "@"))
+		}
+		updated, err := wordlist.Update(ctx, dir, names, *proxy)
+		for _, list := range updated {
+			fmt.Fprintf(messages(), "Updated %s: %d words from %s\n", list.Name, list.Words, list.Source)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		if len(updated) == 0 {
+			fmt.Fprintln(messages(), "No downloaded wordlists to update")
+		}
+		return exitClean
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown wordlist command: %s\n", action)
+		fs.Usage()
+		return exitUsage
+	}
+}
+
+// wordlistSource returns what download fetches: a list of
+// wordlist.Sources by name, or a URL kept under name (by default its file
+// name) as a list of kind
+func wordlistSource(arg, name, kind string) (wordlist.Source, error) {
+	switch kind {
+	case "", wordlist.KindSubdomains, wordlist.KindPaths, wordlist.KindParams, wordlist.KindVhosts:
+	default:
+		return wordlist.Source{}, fmt.Errorf("unknown -kind %q (want subdomains, paths, params or vhosts)", kind)
+	}
+
+	if !strings.Contains(arg, "://") {
+		source, ok := wordlist.FindSource(strings.TrimPrefix(arg, "@"))
+		if !ok {
+			return source, fmt.Errorf("no downloadable wordlist %s; scanner wordlist list shows them", arg)
+		}
+		if name != "" {
+			source.Name = name
+		}
+		return source, nil
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return wordlist.Source{}, fmt.Errorf("bad wordlist URL %q", arg)
+	}
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	}
+	return wordlist.Source{Name: name, Kind: kind, URL: arg}, nil
+}
+
+// printWordlists writes the lists as a table, then those download fetches
+func printWordlists(catalog wordlistCatalog, dir string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tWORDS\tSOURCE\tUPDATED")
+	for _, list := range catalog.Lists {
+		kind, updated := list.Kind, "-"
+		if kind == "" {
+			kind = "-"
+		}
+		if list.Updated != nil {
+			updated = list.Updated.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "@%s\t%s\t%d\t%s\t%s\n", list.Name, kind, list.Words, list.Source, updated)
+	}
+	w.Flush()
+
+	if len(catalog.Downloadable) == 0 {
+		return
+	}
+	fmt.Printf("\nscanner wordlist download NAME fetches these into %s:\n", dir)
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, source := range catalog.Downloadable {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", source.Name, source.Kind, source.URL)
+	}
+	w.Flush()
+}
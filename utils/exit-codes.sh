@@ -13,6 +13,7 @@ readonly EXIT_TIMEOUT=5
 readonly EXIT_PERMISSION_ERROR=6
 readonly EXIT_CONFIG_ERROR=7
 readonly EXIT_PARTIAL_SUCCESS=8
+readonly EXIT_FINDINGS=9
 readonly EXIT_USER_INTERRUPT=130
 
 # Exit with code and optional message
@@ -42,7 +43,16 @@ describe_exit_code() {
         6) echo "Permission denied" ;;
         7) echo "Configuration error" ;;
         8) echo "Partial success (some stages failed)" ;;
+        9) echo "Success with findings" ;;
         130) echo "User interrupt (Ctrl+C)" ;;
         *) echo "Unknown error ($1)" ;;
     esac
 }
+
+# Treat scanner runs that finished (clean, partial or with findings) as success
+scanner_status_ok() {
+    case "$1" in
+        "$EXIT_SUCCESS"|"$EXIT_PARTIAL_SUCCESS"|"$EXIT_FINDINGS") return 0 ;;
+        *) return "$1" ;;
+    esac
+}